@@ -0,0 +1,224 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/yarlson/scr/internal/capture"
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// batchOwnFlags are the root command's flags that scr batch leaves out:
+// its own stand in for some, and the others pick what a single run
+// captures.
+var batchOwnFlags = map[string]bool{
+	"parallel":     true,
+	"fail-fast":    true,
+	"matrix":       true,
+	"json":         true,
+	"file":         true,
+	"actions-json": true,
+	"no-shell":     true,
+}
+
+// batchHiddenFlags are the root command's flags that scr batch keeps,
+// since a run reads them, but hides and refuses: every capture of a batch
+// runs its own command, and reports no progress.
+var batchHiddenFlags = []string{"attach-url", "tmux-layout", "progress-fd", "progress-file"}
+
+// newBatchCommand creates the `scr batch` command, which captures several
+// scripts in one go. It takes the capture flags of the root command's
+// flags, which must all be defined by then.
+func newBatchCommand(rootFlags *pflag.FlagSet) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch [flags] SCRIPT...",
+		Short: "Capture several script files, each into its own subdirectory of --out",
+		Long: `Capture each SCRIPT file, or each script of the --manifest YAML file, into a
+subdirectory of --out named after the script file, then print how each went
+and how long it took.
+
+The scripts run in --command, or with --manifest in the command each entry
+gives. A manifest is a list of entries such as:
+
+  - script: demos/ls.tape
+  - command: htop
+    script: demos/htop.tape
+    out: top
+
+Script paths in a manifest are relative to it. The captures open their
+pages in one Chrome, started once, instead of each starting its own; with
+--chrome-profile or --log each still starts its own. Every capture picks
+a free port of its own, and up to --parallel of them run at once. A failed capture doesn't stop the others unless --fail-fast
+is set. All other flags are those of scr and apply to every capture.`,
+		Example: `  scr batch -o ./demos/out ./demos/*.tape
+  scr batch --command "bash --norc" --parallel 4 ./demos/*.tape
+  scr batch --manifest demos.yaml`,
+		RunE: runBatch,
+	}
+
+	cmd.Flags().String("command", config.Shells[0], "Command the SCRIPT files run in")
+	cmd.Flags().String("manifest", "", "Capture the scripts of this YAML file, each with its command, instead of SCRIPT files")
+	cmd.Flags().Int("parallel", 1, "Run up to this many captures at once")
+	cmd.Flags().Bool("fail-fast", false, "Stop starting captures after the first one fails")
+	documentEnv(cmd.Flags())
+
+	// The root command's flags already name their environment variables
+	rootFlags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Deprecated != "" || batchOwnFlags[flag.Name] {
+			return
+		}
+		// A copy shares the value, but is changed and hidden on its own
+		clone := *flag
+		cmd.Flags().AddFlag(&clone)
+	})
+	for _, name := range batchHiddenFlags {
+		_ = cmd.Flags().MarkHidden(name)
+	}
+	cmd.Flags().SetNormalizeFunc(normalizeFlagName)
+
+	return cmd
+}
+
+// runBatch captures the scripts of a batch, up to --parallel at a time, in
+// a shared Chrome, and prints a summary of how each went.
+func runBatch(cmd *cobra.Command, args []string) error {
+	if err := loadConfigFile(cmd); err != nil {
+		return err
+	}
+	if err := applyEnv(cmd.Flags()); err != nil {
+		return classify(err, errUsage)
+	}
+
+	manifest, err := cmd.Flags().GetString("manifest")
+	if err != nil {
+		return fmt.Errorf("get manifest flag: %w", err)
+	}
+	command, err := cmd.Flags().GetString("command")
+	if err != nil {
+		return fmt.Errorf("get command flag: %w", err)
+	}
+	parallel, err := cmd.Flags().GetInt("parallel")
+	if err != nil {
+		return fmt.Errorf("get parallel flag: %w", err)
+	}
+	failFast, err := cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		return fmt.Errorf("get fail-fast flag: %w", err)
+	}
+	outputDir, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return fmt.Errorf("get out flag: %w", err)
+	}
+
+	for _, name := range []string{"attach-url", "tmux-layout"} {
+		if isSet(cmd.Flags(), name) {
+			return classify(fmt.Errorf("cannot use --%s with scr batch: each capture runs its own command", name), errUsage)
+		}
+	}
+	if err := checkParallel(cmd, parallel, "scr batch", "captures"); err != nil {
+		return err
+	}
+
+	entries, err := batchEntries(manifest, args)
+	if err != nil {
+		return classify(err, errUsage)
+	}
+	// Every script is read first, so that a missing one fails the batch
+	// before any capture starts
+	scripts := make([]string, len(entries))
+	for i, entry := range entries {
+		if scripts[i], err = script.ReadFile(entry.Script); err != nil {
+			return classify(err, errUsage)
+		}
+	}
+
+	browser := capture.NewSharedBrowser()
+	defer browser.Close()
+
+	runs := make([]matrixRun, len(entries))
+	for i, entry := range entries {
+		runs[i] = matrixRun{
+			entry: config.MatrixEntry{Out: entry.Out, Index: entry.Index},
+			dir:   filepath.Join(outputDir, entry.Out),
+		}
+	}
+	started := time.Now()
+	firstErr := runEntries(cmd, runs, parallel, failFast, func(run *matrixRun) error {
+		entry := entries[run.entry.Index]
+		fmt.Fprintf(cmd.ErrOrStderr(), "Capturing %s\n", entry.Script)
+		return runWithPositionalArgs(cmd, cmp.Or(entry.Command, command), nil, scripts[entry.Index], nil, &run.entry, browser)
+	})
+
+	failed := printBatch(cmd.OutOrStdout(), runs, entries, time.Since(started))
+	if err := interrupted(runs); err != nil {
+		return err
+	}
+	if failFast && firstErr != nil {
+		return firstErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d captures failed", failed, len(runs))
+	}
+	return nil
+}
+
+// batchEntries returns the captures of the --manifest file, or of the
+// script files given as arguments.
+func batchEntries(manifest string, args []string) ([]config.BatchEntry, error) {
+	switch {
+	case manifest != "" && len(args) > 0:
+		return nil, fmt.Errorf("cannot use both --manifest and SCRIPT arguments")
+	case manifest == "" && len(args) == 0:
+		return nil, fmt.Errorf("SCRIPT or --manifest is required (e.g., 'scr batch demos/*.tape')")
+	case manifest == "":
+		return config.ScriptBatch(args)
+	}
+
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("read --manifest: %w", err)
+	}
+	entries, err := config.ParseBatch(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse --manifest %s: %w", manifest, err)
+	}
+	for i, entry := range entries {
+		if !filepath.IsAbs(entry.Script) {
+			entries[i].Script = filepath.Join(filepath.Dir(manifest), entry.Script)
+		}
+	}
+	return entries, nil
+}
+
+// printBatch writes how each capture went and how long it took, and
+// returns the number that failed.
+func printBatch(w io.Writer, runs []matrixRun, entries []config.BatchEntry, took time.Duration) int {
+	var ok, failed int
+	for i, run := range runs {
+		status, detail := "ok", ""
+		switch {
+		case run.skipped:
+			status = "skipped"
+		case run.err != nil:
+			failed++
+			status, detail = "failed", fmt.Sprintf(": %v", run.err)
+		default:
+			ok++
+		}
+		duration := "-"
+		if !run.skipped {
+			duration = run.took.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%-8s  %-9s  %s -> %s%s\n", status, duration, entries[i].Script, run.dir, detail)
+	}
+	fmt.Fprintf(w, "Batch: %d of %d captures succeeded in %v\n", ok, len(runs), took.Round(time.Millisecond))
+	return failed
+}
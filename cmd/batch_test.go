@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "frames")
+	writeFile := func(t *testing.T, name, text string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+		return path
+	}
+	ls := writeFile(t, "demos/ls.tape", "Type 'ls' Enter")
+	top := writeFile(t, "demos/top.tape", "Sleep 1s Type 'q'")
+	bad := writeFile(t, "demos/bad.tape", "Jump")
+	manifest := writeFile(t, "demos.yaml", "- script: demos/ls.tape\n- command: htop\n  script: demos/top.tape\n  out: htop\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		wantErr  string
+		wantCode int
+	}{
+		{
+			name: "captures each script into its own directory",
+			args: []string{"--dry-run", "-o", out, ls, top},
+			want: []string{
+				"  1. Type 'ls'\n", "  1. Sleep 1s\n",
+				ls + " -> " + filepath.Join(out, "ls") + "\n",
+				top + " -> " + filepath.Join(out, "top") + "\n",
+				"Batch: 2 of 2 captures succeeded in ",
+			},
+		},
+		{
+			name: "manifest",
+			args: []string{"--storyboard", "-o", out, "--manifest", manifest},
+			want: []string{
+				"# Storyboard: `bash`", "# Storyboard: `htop`",
+				ls + " -> " + filepath.Join(out, "ls") + "\n",
+				top + " -> " + filepath.Join(out, "htop") + "\n",
+			},
+		},
+		{
+			name:     "a failed capture doesn't stop the others",
+			args:     []string{"--dry-run", "-o", out, bad, ls},
+			want:     []string{"failed  ", bad + " -> " + filepath.Join(out, "bad") + ": ", "  1. Type 'ls'\n", "Batch: 1 of 2 captures succeeded"},
+			wantErr:  "1 of 2 captures failed",
+			wantCode: exitFailure,
+		},
+		{
+			name:     "--fail-fast skips the rest",
+			args:     []string{"--dry-run", "--fail-fast", "-o", out, bad, ls},
+			want:     []string{"skipped   -          " + ls, "Batch: 0 of 2 captures succeeded"},
+			wantErr:  "parse script",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "missing script fails before any capture",
+			args:     []string{"--dry-run", ls, filepath.Join(dir, "missing.tape")},
+			wantErr:  "read script file",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "no scripts",
+			args:     []string{"--dry-run"},
+			wantErr:  "SCRIPT or --manifest is required",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "manifest and scripts",
+			args:     []string{"--manifest", manifest, ls},
+			wantErr:  "cannot use both --manifest and SCRIPT arguments",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "two scripts with the same name",
+			args:     []string{ls, writeFile(t, "other/ls.tape", "Enter")},
+			wantErr:  `entry 2: out "ls" is already used by entry 1`,
+			wantCode: exitUsage,
+		},
+		{
+			name:     "attach-url",
+			args:     []string{"--attach-url", "http://localhost:7681", ls},
+			wantErr:  "cannot use --attach-url with scr batch",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "parallel with a fixed port",
+			args:     []string{"--parallel", "2", "-p", "9000", ls},
+			wantErr:  "cannot use -p/--port with --parallel: each of the captures needs its own port",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "json is not a batch flag",
+			args:     []string{"--json", ls},
+			wantErr:  "unknown flag: --json",
+			wantCode: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(append([]string{"batch"}, tt.args...))
+			cmd.SetOut(&stdout)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, tt.wantCode, exitCode(err))
+			} else {
+				require.NoError(t, err)
+			}
+			for _, want := range tt.want {
+				assert.Contains(t, stdout.String(), want)
+			}
+		})
+	}
+}
+
+func TestBatchCommand_Parallel(t *testing.T) {
+	dir := t.TempDir()
+	var scripts []string
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(dir, name+".tape")
+		require.NoError(t, os.WriteFile(path, []byte("Enter"), 0o644))
+		scripts = append(scripts, path)
+	}
+
+	var stdout bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs(append([]string{"batch", "--parallel", "2", "--storyboard", "--command", "htop"}, scripts...))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(bytes.NewBuffer(nil))
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, 3, bytes.Count(stdout.Bytes(), []byte("# Storyboard: `htop`")))
+	assert.Contains(t, stdout.String(), "Batch: 3 of 3 captures succeeded")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+// newCleanupCommand creates the `scr cleanup` command, which stops the
+// processes and removes the Chrome profiles left behind by killed runs.
+func newCleanupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Stop ttyd and Chrome processes left behind by scr runs that were killed",
+		Long: `Find the ttyd and Chrome processes, and the throwaway Chrome profiles, of scr
+runs whose scr process is gone, such as one killed by a CI job timeout, and
+stop or remove them. Processes are told apart by the ` + capture.RunIDEnv + ` variable
+every run sets on them, so processes scr did not start, and those of runs
+still going, are never touched. Processes are only found on Linux; other
+systems get the profiles removed.`,
+		Args: cobra.NoArgs,
+		RunE: runCleanup,
+	}
+
+	cmd.Flags().Duration("older-than", 0, "Only clean up what is at least this old")
+	cmd.Flags().Bool("dry-run", false, "List what would be cleaned up without touching it")
+
+	return cmd
+}
+
+// runCleanup finds the leftovers of killed runs and, unless --dry-run,
+// stops and removes them.
+func runCleanup(cmd *cobra.Command, _ []string) error {
+	olderThan, err := cmd.Flags().GetDuration("older-than")
+	if err != nil {
+		return fmt.Errorf("get older-than flag: %w", err)
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("get dry-run flag: %w", err)
+	}
+
+	leftovers, err := capture.FindLeftovers(olderThan, time.Now())
+	if err != nil {
+		// Profiles are still found where processes cannot be searched
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+	}
+	printLeftovers(cmd.OutOrStdout(), leftovers, dryRun)
+	if dryRun {
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	if err := capture.Reap(leftovers); err != nil {
+		return fmt.Errorf("clean up: %w", err)
+	}
+	return nil
+}
+
+// printLeftovers prints a line for each process and profile found, saying
+// it was stopped or removed, or with dryRun that it would be.
+func printLeftovers(w io.Writer, l capture.Leftovers, dryRun bool) {
+	if len(l.Processes) == 0 && len(l.Dirs) == 0 {
+		fmt.Fprintln(w, "Nothing left behind by earlier runs")
+		return
+	}
+	stop, remove := "Stopping", "Removing"
+	if dryRun {
+		stop, remove = "Would stop", "Would remove"
+	}
+	for _, p := range l.Processes {
+		fmt.Fprintf(w, "%s process %d (run %s): %s\n", stop, p.PID, p.RunID, strings.Join(p.Args, " "))
+	}
+	for _, d := range l.Dirs {
+		fmt.Fprintf(w, "%s %s\n", remove, d.Path)
+	}
+}
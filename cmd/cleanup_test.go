@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupCommand(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+	stale := filepath.Join(tmp, "scr-chrome-"+strconv.Itoa(exited.Process.Pid)+"-1")
+	require.NoError(t, os.Mkdir(stale, 0o700))
+	other := filepath.Join(tmp, "scr-chrome-notarun")
+	require.NoError(t, os.Mkdir(other, 0o700))
+
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		cmd := NewRootCommand()
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(append([]string{"cleanup"}, args...))
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	assert.Contains(t, run("--older-than", "1h"), "Nothing left behind by earlier runs\n")
+	assert.Contains(t, run("--dry-run"), "Would remove "+stale+"\n")
+	assert.DirExists(t, stale)
+
+	assert.Contains(t, run(), "Removing "+stale+"\n")
+	assert.NoDirExists(t, stale)
+	assert.DirExists(t, other, "directories of no run are left alone")
+}
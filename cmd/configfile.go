@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read from the working directory when --config is not
+// given.
+const defaultConfigFile = ".scr.yaml"
+
+// commandLineOnly lists flags that cannot be set from a config file or the
+// environment: --no-shell changes how the positional arguments are read
+// before either is looked at.
+var commandLineOnly = map[string]bool{
+	"config":   true,
+	"help":     true,
+	"no-shell": true,
+}
+
+// configurable reports whether flag can be set from a config file or the
+// environment. Hidden flags are deprecated spellings of other flags.
+func configurable(flag *pflag.Flag) bool {
+	return !flag.Hidden && !commandLineOnly[flag.Name]
+}
+
+// setAnnotation marks, among a flag's annotations, a flag set from a config
+// file or the environment.
+const setAnnotation = "scr_set"
+
+// markSet records that flag was set from a config file or the environment,
+// which flag.Changed leaves out. The annotations are copied, since scr batch
+// shares them with the root command's flags.
+func markSet(flag *pflag.Flag) {
+	annotations := maps.Clone(flag.Annotations)
+	if annotations == nil {
+		annotations = map[string][]string{}
+	}
+	annotations[setAnnotation] = []string{"true"}
+	flag.Annotations = annotations
+}
+
+// isSet reports whether the flag name was set on the command line, in a
+// config file or in the environment, rather than left at its default.
+func isSet(flags *pflag.FlagSet, name string) bool {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		return false
+	}
+	_, ok := flag.Annotations[setAnnotation]
+	return flag.Changed || ok
+}
+
+// loadConfigFile applies the --config file ($SCR_CONFIG), or ./.scr.yaml
+// when it exists, to the flags not given on the command line. Values from
+// the file act as defaults: the flags stay unchanged, so explicit flags
+// still win, and are only marked as set; see isSet.
+func loadConfigFile(cmd *cobra.Command) error {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return fmt.Errorf("get config flag: %w", err)
+	}
+	if !cmd.Flags().Changed("config") {
+		path = cmp.Or(os.Getenv(envName("config")), path)
+	}
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return classify(fmt.Errorf("read config file: %w", err), errUsage)
+	}
+	if err := applyConfigFile(cmd.Flags(), path, data, cmd.ErrOrStderr()); err != nil {
+		return classify(err, errUsage)
+	}
+	return nil
+}
+
+// applyConfigFile sets each flag named in the YAML mapping data that was not
+// given on the command line. Unknown keys are reported to w and skipped.
+func applyConfigFile(flags *pflag.FlagSet, path string, data []byte, w io.Writer) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s: must be a mapping of flag names to values", path)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, node := root.Content[i], root.Content[i+1]
+		flag := flags.Lookup(key.Value)
+		if flag == nil || !configurable(flag) {
+			_, _ = fmt.Fprintf(w, "Warning: %s line %d: unknown key %q\n", path, key.Line, key.Value)
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+
+		values, err := configValues(flag, node)
+		if err == nil {
+			for _, v := range values {
+				if err = flag.Value.Set(v); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("config file %s line %d: %s: %w", path, node.Line, key.Value, err)
+		}
+		markSet(flag)
+	}
+	return nil
+}
+
+// configValues converts a YAML value to the strings pflag parses. Repeatable
+// flags also take a list.
+func configValues(flag *pflag.Flag, node *yaml.Node) ([]string, error) {
+	repeatable := strings.HasSuffix(flag.Value.Type(), "Array") || strings.HasSuffix(flag.Value.Type(), "Slice")
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		return nil, fmt.Errorf("missing value")
+	case node.Kind == yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case node.Kind == yaml.SequenceNode && repeatable:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("list items must be single values")
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	case node.Kind == yaml.SequenceNode:
+		return nil, fmt.Errorf("expected a single %s value, got a list", flag.Value.Type())
+	default:
+		return nil, fmt.Errorf("expected a %s value", flag.Value.Type())
+	}
+}
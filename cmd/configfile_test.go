@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		file     string
+		want     map[string]string
+		wantWarn string
+		wantErr  string
+	}{
+		{
+			name: "empty file keeps defaults",
+			file: "",
+			want: map[string]string{"interval": "500ms", "port": "0"},
+		},
+		{
+			name: "file overrides defaults",
+			file: "out: ./frames\ninterval: 250ms\nport: 9000\nwindow: true\ntheme: dracula\n",
+			want: map[string]string{"out": "./frames", "interval": "250ms", "port": "9000", "window": "true", "theme": "dracula"},
+		},
+		{
+			name: "flags override file",
+			args: []string{"-i", "1s", "--port", "8000"},
+			file: "interval: 250ms\nport: 9000\nwidth: 800\n",
+			want: map[string]string{"interval": "1s", "port": "8000", "width": "800"},
+		},
+		{
+			name: "repeatable flags take a list",
+			file: "env:\n  - FOO=1\n  - BAR=2\nsimulate-cvd: [protanopia]\n",
+			want: map[string]string{"env": "[FOO=1,BAR=2]", "simulate-cvd": "[protanopia]"},
+		},
+		{
+			name: "flag aliases are accepted",
+			file: "url: http://localhost:7681\n",
+			want: map[string]string{"attach-url": "http://localhost:7681"},
+		},
+		{
+			name:     "unknown keys warn",
+			file:     "intervall: 1s\nno-shell: true\ncommand: ls\nport: 9000\n",
+			want:     map[string]string{"port": "9000", "no-shell": "false", "command": ""},
+			wantWarn: "Warning: .scr.yaml line 1: unknown key \"intervall\"\nWarning: .scr.yaml line 2: unknown key \"no-shell\"\nWarning: .scr.yaml line 3: unknown key \"command\"\n",
+		},
+		{
+			name:    "type mismatch names the key",
+			file:    "width: 800\nport: abc\n",
+			wantErr: "config file .scr.yaml line 2: port: ",
+		},
+		{
+			name:    "duration without a unit",
+			file:    "interval: 500\n",
+			wantErr: "config file .scr.yaml line 1: interval: ",
+		},
+		{
+			name:    "list for a single value",
+			file:    "theme: [dracula, nord]\n",
+			wantErr: "config file .scr.yaml line 1: theme: expected a single string value, got a list",
+		},
+		{
+			name:    "missing value",
+			file:    "theme:\n",
+			wantErr: "config file .scr.yaml line 1: theme: missing value",
+		},
+		{
+			name:    "not a mapping",
+			file:    "- interval: 1s\n",
+			wantErr: "config file .scr.yaml: must be a mapping of flag names to values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			var warn bytes.Buffer
+			err := applyConfigFile(cmd.Flags(), ".scr.yaml", []byte(tt.file), &warn)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWarn, warn.String())
+			for name, want := range tt.want {
+				assert.Equal(t, want, cmd.Flags().Lookup(name).Value.String(), name)
+			}
+			// Values from the file don't count as given on the command line
+			if len(tt.args) == 0 {
+				for name := range tt.want {
+					assert.False(t, cmd.Flags().Changed(name), name)
+				}
+			}
+		})
+	}
+}
+
+func TestRootCommand_ConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile(defaultConfigFile, []byte("interval: 250ms\n"), 0o644))
+	other := filepath.Join(dir, "other.yaml")
+	require.NoError(t, os.WriteFile(other, []byte("interval: 2s\n"), 0o644))
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    time.Duration
+		wantErr string
+	}{
+		{name: "reads .scr.yaml", args: nil, want: 250 * time.Millisecond},
+		{name: "flag wins", args: []string{"-i", "1s"}, want: time.Second},
+		{name: "--config replaces .scr.yaml", args: []string{"--config", other}, want: 2 * time.Second},
+		{name: "missing --config file", args: []string{"--config", filepath.Join(dir, "missing.yaml")}, wantErr: "read config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(append(tt.args, "--storyboard", "bash", "Enter"))
+			cmd.SetOut(&out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, exitUsage, exitCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), "Interval: "+tt.want.String()+"\n")
+		})
+	}
+}
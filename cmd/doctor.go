@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/capture"
+	"github.com/yarlson/scr/internal/config"
+)
+
+// newDoctorCommand creates the `scr doctor` command, which checks that
+// everything a capture needs is in place.
+func newDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that ttyd, Chrome and the output directory are ready for captures",
+		Long: `Check what a capture needs: ttyd in PATH and new enough, a Chrome or Chromium
+executable, a free ttyd port and a writable output directory. With --smoke,
+also capture "echo ok" into a temporary directory.
+
+Each check prints pass, warn or fail, with a hint for anything to fix. The
+command fails when any check fails; warnings do not fail it.`,
+		Args: cobra.NoArgs,
+		RunE: runDoctor,
+	}
+
+	cmd.Flags().StringP("out", "o", "./screenshots", "Output directory to check")
+	cmd.Flags().IntP("port", "p", 7681, "ttyd port to check")
+	cmd.Flags().String("chrome-path", "", "Chrome or Chromium executable to check instead of searching the usual locations")
+	cmd.Flags().Bool("smoke", false, "Also run a short capture of \"echo ok\"")
+
+	return cmd
+}
+
+// runDoctor runs the checks and fails when any of them failed.
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	outputDir, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return fmt.Errorf("get out flag: %w", err)
+	}
+	port, err := cmd.Flags().GetInt("port")
+	if err != nil {
+		return fmt.Errorf("get port flag: %w", err)
+	}
+	chromePath, err := cmd.Flags().GetString("chrome-path")
+	if err != nil {
+		return fmt.Errorf("get chrome-path flag: %w", err)
+	}
+	smoke, err := cmd.Flags().GetBool("smoke")
+	if err != nil {
+		return fmt.Errorf("get smoke flag: %w", err)
+	}
+
+	// Failed checks are reported by runChecks, not by usage
+	cmd.SilenceUsage = true
+
+	cfg := &config.Config{OutputDir: outputDir, TTydPort: port, ChromePath: chromePath}
+	checks := capture.DoctorChecks(cfg, smoke)
+	if failed := runChecks(cmd.Context(), cmd.OutOrStdout(), checks); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// runChecks runs checks in order, prints a line for each with its hint
+// below it, and returns how many failed.
+func runChecks(ctx context.Context, w io.Writer, checks []capture.Check) int {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	width := 0
+	for _, check := range checks {
+		width = max(width, len(check.Name))
+	}
+
+	failed := 0
+	for _, check := range checks {
+		result := check.Run(ctx)
+		if result.Status == capture.CheckFail {
+			failed++
+		}
+		fmt.Fprintf(w, "%-4s  %-*s  %s\n", result.Status, width, check.Name, result.Detail)
+		if result.Hint != "" {
+			fmt.Fprintf(w, "%-4s  %-*s  hint: %s\n", "", width, "", result.Hint)
+		}
+	}
+	return failed
+}
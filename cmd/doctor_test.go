@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+func TestRunChecks(t *testing.T) {
+	result := func(r capture.CheckResult) func(context.Context) capture.CheckResult {
+		return func(context.Context) capture.CheckResult { return r }
+	}
+	checks := []capture.Check{
+		{Name: "ttyd", Run: result(capture.CheckResult{Status: capture.CheckPass, Detail: "ttyd 1.7.4"})},
+		{Name: "port", Run: result(capture.CheckResult{Status: capture.CheckWarn, Detail: "port 7681 is in use", Hint: "pass -p"})},
+		{Name: "Chrome", Run: result(capture.CheckResult{Status: capture.CheckFail, Detail: "not found", Hint: "install it"})},
+	}
+
+	var out bytes.Buffer
+	failed := runChecks(context.Background(), &out, checks)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, ""+
+		"pass  ttyd    ttyd 1.7.4\n"+
+		"warn  port    port 7681 is in use\n"+
+		"              hint: pass -p\n"+
+		"fail  Chrome  not found\n"+
+		"              hint: install it\n", out.String())
+}
+
+func TestDoctorCommand_FailsOnMissingDependencies(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	var out bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"doctor", "-o", t.TempDir(), "--chrome-path", filepath.Join(t.TempDir(), "chromium")})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 4 checks failed")
+	assert.Contains(t, out.String(), "fail  ttyd")
+	assert.Contains(t, out.String(), "fail  Chrome")
+	assert.Contains(t, out.String(), "is writable")
+	assert.NotContains(t, out.String(), "Usage:")
+}
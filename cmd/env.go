@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variable of every flag; see envName.
+const envPrefix = "SCR_"
+
+// envName returns the environment variable that sets the flag name, e.g.
+// SCR_FONT_SIZE for --font-size.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// documentEnv adds each flag's environment variable to its usage.
+func documentEnv(flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if configurable(flag) || flag.Name == "config" {
+			flag.Usage += fmt.Sprintf(" ($%s)", envName(flag.Name))
+		}
+	})
+}
+
+// applyEnv sets each flag not given on the command line from its non-empty
+// SCR_ environment variable, parsed as the flag would be. A variable replaces
+// the whole value of a repeatable flag, including values from a config file;
+// like config file values, the flags stay unchanged and are marked as set.
+func applyEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || !configurable(flag) {
+			return
+		}
+		name := envName(flag.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = slice.Replace(nil)
+		}
+		if err == nil {
+			err = flag.Value.Set(value)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+			return
+		}
+		markSet(flag)
+	})
+	return err
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "SCR_OUT", envName("out"))
+	assert.Equal(t, "SCR_FONT_SIZE", envName("font-size"))
+	assert.Equal(t, "SCR_SIMULATE_CVD", envName("simulate-cvd"))
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		file    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "env overrides defaults",
+			env:  map[string]string{"SCR_OUT": "/artifacts/screens", "SCR_TIMEOUT": "120s", "SCR_PORT": "9000", "SCR_WINDOW": "true"},
+			want: map[string]string{"out": "/artifacts/screens", "timeout": "2m0s", "port": "9000", "window": "true"},
+		},
+		{
+			name: "empty values are ignored",
+			env:  map[string]string{"SCR_OUT": ""},
+			want: map[string]string{"out": "./screenshots"},
+		},
+		{
+			name: "env overrides the config file",
+			env:  map[string]string{"SCR_INTERVAL": "1s", "SCR_ENV": "B=2"},
+			file: "interval: 250ms\nwidth: 800\nenv: [A=1]\n",
+			want: map[string]string{"interval": "1s", "width": "800", "env": "[B=2]"},
+		},
+		{
+			name: "flags override env",
+			env:  map[string]string{"SCR_INTERVAL": "1s", "SCR_FONT_SIZE": "18"},
+			args: []string{"-i", "2s"},
+			want: map[string]string{"interval": "2s", "font-size": "18"},
+		},
+		{
+			name: "comma-separated slices",
+			env:  map[string]string{"SCR_SIMULATE_CVD": "protanopia,tritanopia"},
+			want: map[string]string{"simulate-cvd": "[protanopia,tritanopia]"},
+		},
+		{
+			name: "command-line only flags are ignored",
+			env:  map[string]string{"SCR_NO_SHELL": "true", "SCR_COMMAND": "ls"},
+			want: map[string]string{"no-shell": "false", "command": ""},
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"SCR_TIMEOUT": "120"},
+			wantErr: `SCR_TIMEOUT: time: missing unit in duration "120"`,
+		},
+		{
+			name:    "invalid int",
+			env:     map[string]string{"SCR_PORT": "http"},
+			wantErr: `SCR_PORT: strconv.ParseInt: parsing "http": invalid syntax`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cmd := NewRootCommand()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			require.NoError(t, applyConfigFile(cmd.Flags(), ".scr.yaml", []byte(tt.file), bytes.NewBuffer(nil)))
+
+			err := applyEnv(cmd.Flags())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for name, want := range tt.want {
+				assert.Equal(t, want, cmd.Flags().Lookup(name).Value.String(), name)
+			}
+		})
+	}
+}
+
+func TestRootCommand_Env(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile(defaultConfigFile, []byte("interval: 250ms\n"), 0o644))
+	other := filepath.Join(dir, "other.yaml")
+	require.NoError(t, os.WriteFile(other, []byte("interval: 2s\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		want     string
+		wantErr  string
+		wantCode int
+	}{
+		{name: "env beats the config file", env: map[string]string{"SCR_INTERVAL": "750ms"}, want: "Interval: 750ms\n"},
+		{name: "flag beats env", env: map[string]string{"SCR_INTERVAL": "750ms"}, args: []string{"-i", "1s"}, want: "Interval: 1s\n"},
+		{name: "SCR_CONFIG picks the config file", env: map[string]string{"SCR_CONFIG": other}, want: "Interval: 2s\n"},
+		{name: "--config beats SCR_CONFIG", env: map[string]string{"SCR_CONFIG": filepath.Join(dir, "missing.yaml")}, args: []string{"--config", other}, want: "Interval: 2s\n"},
+		{name: "invalid value", env: map[string]string{"SCR_INTERVAL": "fast"}, wantErr: "SCR_INTERVAL: ", wantCode: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(append(tt.args, "--storyboard", "bash", "Enter"))
+			cmd.SetOut(&out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, tt.wantCode, exitCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestRootCommand_EnvAndConfigAreSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		file     string
+		args     []string
+		wantErr  string
+		wantCode int
+	}{
+		{
+			name:     "SCR_PORT conflicts with --attach-url",
+			env:      map[string]string{"SCR_PORT": "9000"},
+			args:     []string{"--attach-url", "http://localhost:7681"},
+			wantErr:  "cannot use --attach-url with -p/--port",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "config file parallel needs --matrix",
+			file:     "parallel: 2\n",
+			wantErr:  "--parallel and --fail-fast need --matrix",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "SCR_FAIL_FAST needs --matrix",
+			env:      map[string]string{"SCR_FAIL_FAST": "true"},
+			wantErr:  "--parallel and --fail-fast need --matrix",
+			wantCode: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if tt.file != "" {
+				require.NoError(t, os.WriteFile(defaultConfigFile, []byte(tt.file), 0o644))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			command := "bash"
+			if slices.Contains(tt.args, "--attach-url") {
+				command = ""
+			}
+			cmd := NewRootCommand()
+			cmd.SetArgs(append(tt.args, "--dry-run", command, "Enter"))
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, tt.wantCode, exitCode(err))
+		})
+	}
+}
+
+func TestAutoPort(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		file string
+		args []string
+		want bool
+	}{
+		{name: "default is any free port", want: true},
+		{name: "SCR_PORT pins the port", env: map[string]string{"SCR_PORT": "9000"}},
+		{name: "config file pins the port", file: "port: 9000\n"},
+		{name: "flag pins the port", args: []string{"-p", "9000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if tt.file != "" {
+				require.NoError(t, os.WriteFile(defaultConfigFile, []byte(tt.file), 0o644))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cmd := NewRootCommand()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			require.NoError(t, loadConfigFile(cmd))
+			require.NoError(t, applyEnv(cmd.Flags()))
+
+			assert.Equal(t, tt.want, autoPort(cmd.Flags()))
+		})
+	}
+}
+
+func TestNewRootCommand_HelpNamesEnv(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"--help"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "Directory to save screenshots ($SCR_OUT)")
+	assert.Contains(t, buf.String(), "($SCR_CONFIG)")
+	assert.NotContains(t, buf.String(), "SCR_NO_SHELL")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// newEstimateCommand creates the `scr estimate` command, which reports how
+// long a script takes without running it.
+func newEstimateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "estimate [SCRIPT]",
+		Short: "Print the minimum duration and expected frame count of a script",
+		Long: `Print the minimum time a script's actions take and the number of frames a
+capture of it is expected to write, without starting ttyd or the browser.
+
+The duration counts sleeps, typing and post-action delays. Startup, key
+round-trips and Wait actions, which may match at once, are not included, so
+a real run takes longer; use it to budget --timeout and CI job time.
+
+SCRIPT may also be the path of an existing script file.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runEstimate,
+	}
+
+	cmd.Flags().StringP("file", "f", "", "Read the script from a file")
+	cmd.Flags().DurationP("interval", "i", 500*time.Millisecond, "Interval between screenshots (0 disables interval screenshots)")
+	cmd.Flags().Bool("json", false, "Print the estimate as JSON")
+	cmd.Flags().StringArray("param", nil, "Value for a script Param, as NAME=VALUE (repeatable)")
+
+	return cmd
+}
+
+// estimateJSON is the --json output of `scr estimate`.
+type estimateJSON struct {
+	Actions    int    `json:"actions"`
+	Duration   string `json:"duration"`
+	DurationMS int64  `json:"durationMs"`
+	Frames     int    `json:"frames"`
+}
+
+// runEstimate parses the script and prints its estimate.
+func runEstimate(cmd *cobra.Command, args []string) error {
+	scriptFile, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return fmt.Errorf("get file flag: %w", err)
+	}
+
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return fmt.Errorf("get interval flag: %w", err)
+	}
+	if interval < 0 {
+		return fmt.Errorf("--interval must be >= 0, got %v", interval)
+	}
+
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("get json flag: %w", err)
+	}
+
+	var scriptStr string
+	switch {
+	case scriptFile != "" && len(args) > 0:
+		return fmt.Errorf("cannot use both --file and a SCRIPT argument")
+	case len(args) > 0 && script.IsFile(args[0]):
+		scriptFile = args[0]
+	case len(args) > 0:
+		scriptStr = args[0]
+	case scriptFile == "":
+		return fmt.Errorf("SCRIPT or --file is required (e.g., 'scr estimate -f demo.tape')")
+	}
+	if scriptFile != "" {
+		scriptStr, err = script.ReadFile(scriptFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	params, err := paramValues(cmd)
+	if err != nil {
+		return err
+	}
+	parsed, err := script.ParseScript(scriptStr, params)
+	if err != nil {
+		return parseScriptError(err, scriptStr)
+	}
+	est := script.Estimate(parsed.Actions, script.EstimateOptions{Interval: interval})
+
+	out := cmd.OutOrStdout()
+	if asJSON {
+		data, err := json.MarshalIndent(estimateJSON{
+			Actions:    est.Actions,
+			Duration:   est.Duration.String(),
+			DurationMS: est.Duration.Milliseconds(),
+			Frames:     est.Frames,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode estimate: %w", err)
+		}
+		fmt.Fprintf(out, "%s\n", data)
+		return nil
+	}
+
+	fmt.Fprintf(out, "Actions: %d\n", est.Actions)
+	fmt.Fprintf(out, "Duration: at least %v\n", est.Duration.Round(time.Millisecond))
+	fmt.Fprintf(out, "Frames: %d\n", est.Frames)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCommand(t *testing.T) {
+	tapeFile := filepath.Join(t.TempDir(), "demo.tape")
+	require.NoError(t, os.WriteFile(tapeFile, []byte("Type@100ms 'ls' Enter\nSleep 1s\nScreenshot\n"), 0o644))
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       string
+		errContain string
+	}{
+		{
+			name: "inline script",
+			args: []string{"estimate", "-i", "0", "Sleep 2s Enter@500ms"},
+			want: "Actions: 2\nDuration: at least 2.5s\nFrames: 2\n",
+		},
+		{
+			name: "script file with interval",
+			args: []string{"estimate", "-f", tapeFile},
+			want: "Actions: 4\nDuration: at least 1.2s\nFrames: 5\n",
+		},
+		{
+			name: "script path argument as JSON",
+			args: []string{"estimate", "--json", tapeFile},
+			want: "{\n  \"actions\": 4,\n  \"duration\": \"1.2s\",\n  \"durationMs\": 1200,\n  \"frames\": 5\n}\n",
+		},
+		{
+			name:       "no script",
+			args:       []string{"estimate"},
+			errContain: "SCRIPT or --file is required",
+		},
+		{
+			name:       "parse error",
+			args:       []string{"estimate", "Sleep 500"},
+			errContain: "did you mean 500ms?",
+		},
+		{
+			name:       "negative interval",
+			args:       []string{"estimate", "-i", "-1s", "Enter"},
+			errContain: "--interval must be >= 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
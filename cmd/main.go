@@ -2,20 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/yarlson/scr/internal/capture"
 	"github.com/yarlson/scr/internal/config"
 	"github.com/yarlson/scr/internal/input"
 	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
 )
 
 // NewRootCommand creates and returns the root Cobra command for scr.
@@ -28,21 +35,125 @@ func NewRootCommand() *cobra.Command {
 Usage:
   scr [flags] COMMAND
   scr [flags] COMMAND SCRIPT
+  scr [flags] -f FILE COMMAND
+  scr [flags] --actions-json FILE COMMAND
+  scr [flags] --attach-url URL "" [SCRIPT]
+  scr [flags] --no-shell [SCRIPT] -- PROGRAM [ARGS...]
+  scr [flags] --tmux-layout FILE [SCRIPT]
+
+SCRIPT may also be the path of an existing script file. With --attach-url,
+scr drives an already running ttyd instead of starting one, so COMMAND must
+be empty. COMMAND runs in bash unless --shell picks another shell; with
+--no-shell, PROGRAM and its ARGS after -- run directly, without a shell.
+With --tmux-layout, a tmux session with the panes in FILE runs instead of
+COMMAND, and Pane actions in SCRIPT switch between them.
+
+Flags not given on the command line are read from the environment variable
+shown with each, such as SCR_OUT for --out, then from .scr.yaml or the
+--config file.
 
 Examples:
   scr "ls -la"
   scr bash "Type 'echo hello' Enter"
-  scr "seq 100 | fzf" "Down 5 Enter"`,
-		Args: cobra.RangeArgs(0, 2),
+  scr "seq 100 | fzf" "Down 5 Enter"
+  scr -f demo.tape bash
+  scr --no-shell "Sleep 2s Type 'q'" -- htop -d 10`,
+		Args: validateArgs,
 		RunE: runCommand,
 	}
 
 	// New short flags
 	cmd.Flags().StringP("out", "o", "./screenshots", "Directory to save screenshots")
-	cmd.Flags().DurationP("interval", "i", 500*time.Millisecond, "Interval between screenshots")
+	cmd.Flags().DurationP("interval", "i", 500*time.Millisecond, "Interval between screenshots (0 disables interval screenshots)")
 	cmd.Flags().DurationP("timeout", "t", 60*time.Second, "Timeout for the entire operation")
-	cmd.Flags().IntP("port", "p", 7681, "Port for ttyd server")
+	cmd.Flags().IntP("port", "p", 0, "Port for ttyd server (default: a free port)")
 	cmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging")
+	cmd.Flags().StringP("file", "f", "", "Read the script from a file")
+	cmd.Flags().String("actions-json", "", "Read the actions from a JSON file, an array of objects such as {\"kind\": \"type\", \"text\": \"ls\"}, instead of a script")
+	cmd.Flags().String("name", config.DefaultNameTemplate, "File name template for screenshots: {prefix} (program name), {n}, {time} (ms), {action}; {n:03} zero-pads to at least 3 digits (alias --template)")
+	cmd.Flags().Bool("out-tmp", false, "Write frames to a temp directory and move them into --out when done, so the command never sees them")
+	cmd.Flags().String("shell", config.Shells[0], fmt.Sprintf("Shell that runs COMMAND (%s)", strings.Join(config.Shells, ", ")))
+	cmd.Flags().Bool("no-shell", false, "Run the program given after -- directly, without a shell")
+	cmd.Flags().String("attach-url", "", "Drive an already running ttyd at this URL instead of starting one (alias --url)")
+	cmd.Flags().String("chrome-path", "", "Chrome or Chromium executable to use instead of searching the usual locations")
+	cmd.Flags().StringArray("chrome-flag", nil, "Extra Chrome command-line flag, e.g. --chrome-flag=--no-sandbox (repeatable)")
+	cmd.Flags().String("chrome-profile", "", "Chrome profile directory reused across runs for faster startup (created on first use; \"tmp\" uses a throwaway profile)")
+	cmd.Flags().Int("width", config.DefaultWidth, "Browser viewport width in pixels")
+	cmd.Flags().Int("height", config.DefaultHeight, "Browser viewport height in pixels")
+	cmd.Flags().Int("cols", 0, "Terminal width in columns (0 fits the viewport)")
+	cmd.Flags().Int("rows", 0, "Terminal height in rows (0 fits the viewport)")
+	cmd.Flags().String("theme", "", fmt.Sprintf("Terminal theme: a built-in name (%s) or a JSON theme file", strings.Join(theme.Names(), ", ")))
+	cmd.Flags().String("log", "", "Write ttyd output and the Chrome DevTools trace to this file")
+	cmd.Flags().Int("log-max-size", 10, "Rotate the --log file when it reaches this many MiB")
+	cmd.Flags().Int("log-keep", 3, "Number of rotated --log files to keep (0 discards old output)")
+	cmd.Flags().Bool("stats", false, "Print startup phases, frame/action timings and per-frame change after the run")
+	cmd.Flags().String("format", capture.DefaultFormat, fmt.Sprintf("Output format (%s)", strings.Join(capture.EncoderNames(), ", ")))
+	cmd.Flags().Duration("gif-delay", 0, "Fixed delay between GIF frames (0 uses real capture timing)")
+	cmd.Flags().Int("target-frames", 0, "Leave interval frames out of the GIF to bring it to about this many frames, keeping the initial, final, Screenshot and Burst frames (0 keeps every frame)")
+	cmd.Flags().Bool("keep-frames", false, "Also keep the PNG frames when writing an animated format")
+	cmd.Flags().Int("quality", 0, fmt.Sprintf("Compression quality of jpeg and webp frames, 1 to 100 (0 uses %d)", config.DefaultQuality))
+	cmd.Flags().String("before", "", "Shell command run on this machine before the capture, with the command's environment; the run stops if it fails")
+	cmd.Flags().String("after", "", "Shell command run on this machine after the capture, even when it failed")
+	cmd.Flags().String("frame-hook", "", "Shell command run for each frame written, with {file}, {index} and {elapsed} (ms) filled in")
+	cmd.Flags().Bool("frame-hook-strict", false, "Fail the run when a --frame-hook command fails")
+	cmd.Flags().Int("font-size", 0, fmt.Sprintf("Terminal font size in CSS pixels, %d to %d (default: ttyd's)", config.MinFontSize, config.MaxFontSize))
+	cmd.Flags().String("font-family", "", "CSS font family for the terminal, e.g. \"JetBrains Mono, monospace\", instead of the embedded Fira Mono; the fonts must be installed")
+	cmd.Flags().Bool("system-fonts", false, "Render with the browser's monospace font instead of the embedded Fira Mono, which looks the same on every machine")
+	cmd.Flags().String("prompt-pattern", "", "Regex the last terminal line matches while the shell shows its prompt, for Wait Prompt (replaces the shell's profile)")
+	cmd.Flags().Bool("dedup", false, "Skip interval screenshots identical to the previous frame")
+	cmd.Flags().Bool("no-capture-while-typing", false, "Skip interval screenshots during Type actions, taking one after each instead")
+	cmd.Flags().StringArray("param", nil, "Value for a script Param, as NAME=VALUE (repeatable)")
+	cmd.Flags().StringArrayP("env", "e", nil, "Environment variable for the command, as KEY=VALUE; overrides scr's TERM and PS1 (repeatable)")
+	cmd.Flags().Bool("no-lock", false, "Allow another run to write to the same --out directory at the same time")
+	cmd.Flags().Bool("skip-version-check", false, "Run with ttyd or Chrome versions older than scr supports, e.g. patched builds")
+	cmd.Flags().Int("progress-fd", 0, "Write newline-delimited JSON progress events to this inherited file descriptor, e.g. 3")
+	cmd.Flags().String("progress-file", "", "Write newline-delimited JSON progress events to this file")
+	cmd.Flags().Int("padding", 0, "Pixels of background to add around every frame")
+	cmd.Flags().String("bg", "", "Color of the --padding and the --window corners, as #rgb or #rrggbb (default: the terminal background)")
+	cmd.Flags().Bool("window", false, "Draw a window with rounded corners and traffic-light dots around every frame")
+	cmd.Flags().String("title", "", "Title to show in the --window title bar")
+	cmd.Flags().String("selector", "", "CSS selector of the element to capture instead of the whole terminal, e.g. \"#terminal-container .xterm-screen\"")
+	cmd.Flags().String("crop", "", "Cut every frame down to the pixel rectangle x,y,w,h of the captured element")
+	cmd.Flags().String("ssh", "", "Run ttyd on this remote host, as host or user@host, over ssh and forward its port to this machine")
+	cmd.Flags().String("tmux-layout", "", "Run a tmux session with the panes in this file instead of COMMAND, one 'name [right|below] [size%]: command' per line")
+	cmd.Flags().Bool("no-color-session", false, "Ask the command for monochrome output: NO_COLOR=1, TERM=xterm and no COLORTERM")
+	cmd.Flags().Bool("anonymize", false, "Give the command a neutral user, host and prompt (user@demo), and replace the real ones and the home directory in text files and the manifest")
+	cmd.Flags().Bool("grayscale", false, "Convert frames to grayscale, for commands that print colors anyway")
+	cmd.Flags().Duration("escape-delay", config.DefaultEscapeDelay, "Pause after each Escape keypress so editors such as vim don't read it with the next key as an Alt sequence (0 disables)")
+	cmd.Flags().Int("type-chunk-size", config.DefaultTypeChunkSize, "Send Type text longer than this many characters in chunks of that size, checking each reached the terminal")
+	cmd.Flags().Bool("no-type-verify", false, "Send long Type text in chunks without checking they arrived, for programs that don't echo input")
+	cmd.Flags().Bool("no-probe", false, "Don't type and erase a space to check the terminal takes input before the first action")
+	cmd.Flags().StringSlice("simulate-cvd", nil, fmt.Sprintf("Also write each frame as seen with a color vision deficiency (%s; repeatable)", strings.Join(config.CVDSimulations, ", ")))
+	cmd.Flags().String("video", "", "Also record a .webm or .mp4 video of the run to this file (needs ffmpeg; disables interval screenshots)")
+	cmd.Flags().Bool("exit-on-done", false, "Stop capturing when the command exits; a non-zero exit fails the run with exit code 6")
+	cmd.Flags().Bool("fail-on-error", false, "Fail the run with exit code 6 when the command has exited non-zero by the end of the script")
+	cmd.Flags().Duration("max-action-duration", script.DefaultMaxActionDuration, "Warn when a single Sleep, delay or Type takes longer than this (0 disables the check)")
+	cmd.Flags().Bool("strict", false, "Fail instead of warning when an action exceeds --max-action-duration")
+	cmd.Flags().Bool("dry-run", false, "Parse the script and print the planned actions without capturing")
+	cmd.Flags().Bool("storyboard", false, "Print a Markdown storyboard of the expected frames without capturing (implies --dry-run)")
+	cmd.Flags().String("matrix", "", "Run the script once per entry of this YAML file, each with its own Param values and --out subdirectory")
+	cmd.Flags().Int("parallel", 1, "Run up to this many --matrix entries at once")
+	cmd.Flags().Bool("fail-fast", false, "Stop starting --matrix entries after the first one fails")
+	cmd.Flags().String("config", "", "Read default flag values from this YAML file instead of ./"+defaultConfigFile)
+	cmd.Flags().Bool("json", false, "Print a JSON object describing the result instead of the usual messages; a failure is also printed as JSON on stderr")
+
+	// Bad flags fail with the usage exit status, like bad arguments
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return classify(err, errUsage)
+	})
+
+	cmd.AddCommand(newThemesCommand())
+	cmd.AddCommand(newVersionCommand())
+	cmd.AddCommand(newEstimateCommand())
+	cmd.AddCommand(newDoctorCommand())
+	cmd.AddCommand(newVerifyCommand())
+	cmd.AddCommand(newSelfUpdateCommand())
+	cmd.AddCommand(newCleanupCommand())
+	cmd.CompletionOptions.DisableDefaultCmd = true
+
+	// --output-format is accepted as an alias for --format, and --url for
+	// --attach-url
+	cmd.Flags().SetNormalizeFunc(normalizeFlagName)
 
 	// Hidden deprecated flags (for backward compatibility)
 	cmd.Flags().String("command", "", "Command to execute (deprecated: use positional arg)")
@@ -67,14 +178,82 @@ Examples:
 	_ = cmd.Flags().MarkDeprecated("screenshot-interval", "use -i or --interval instead")
 	_ = cmd.Flags().MarkDeprecated("ttyd-port", "use -p or --port instead")
 
+	// Name each flag's environment variable in --help
+	documentEnv(cmd.Flags())
+
+	// scr batch takes the capture flags, so it comes once they are all here
+	cmd.AddCommand(newBatchCommand(cmd.Flags()))
+
 	return cmd
 }
 
+// normalizeFlagName maps the aliases of flags to their names.
+func normalizeFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	switch name {
+	case "output-format":
+		name = "format"
+	case "url":
+		name = "attach-url"
+	case "template":
+		name = "name"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// validateArgs accepts COMMAND [SCRIPT], or with --no-shell, [SCRIPT] before
+// the program and arguments that follow --.
+func validateArgs(cmd *cobra.Command, args []string) error {
+	if noShell, _ := cmd.Flags().GetBool("no-shell"); noShell {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return classify(fmt.Errorf("--no-shell needs the program after --, e.g. 'scr --no-shell -- htop -d 10'"), errUsage)
+		}
+		if dash > 1 {
+			return classify(fmt.Errorf("with --no-shell, only a SCRIPT may come before --, got %d args", dash), errUsage)
+		}
+		return nil
+	}
+	return classify(cobra.RangeArgs(0, 2)(cmd, args), errUsage)
+}
+
 // runCommand is the RunE function that handles flag parsing and validation.
-func runCommand(cmd *cobra.Command, args []string) error {
+func runCommand(cmd *cobra.Command, args []string) (err error) {
+	// Config file values, then SCR_ environment variables, fill in the
+	// flags not given on the command line
+	if err := loadConfigFile(cmd); err != nil {
+		return err
+	}
+	if err := applyEnv(cmd.Flags()); err != nil {
+		return classify(err, errUsage)
+	}
+
+	// With --json, errors are reported in the result instead of by cobra
+	jsonOut, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return fmt.Errorf("get json flag: %w", err)
+	}
+	var result *runResult
+	if jsonOut {
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		result = &runResult{}
+		defer func() {
+			if writeErr := writeResult(cmd.OutOrStdout(), cmd.ErrOrStderr(), result, err); writeErr != nil && err == nil {
+				err = fmt.Errorf("write result: %w", writeErr)
+			}
+		}()
+	}
+
 	// Check for deprecated flag usage
 	deprecatedFlagsUsed := cmd.Flags().Changed("command") || cmd.Flags().Changed("keypresses") || cmd.Flags().Changed("delays")
 
+	// With --no-shell, the program comes after -- and COMMAND is left empty
+	var commandArgs []string
+	if noShell, _ := cmd.Flags().GetBool("no-shell"); noShell {
+		dash := cmd.ArgsLenAtDash()
+		commandArgs = args[dash:]
+		args = append([]string{""}, args[:dash]...)
+	}
+
 	// Determine command source
 	var command string
 	if len(args) > 0 {
@@ -83,35 +262,136 @@ func runCommand(cmd *cobra.Command, args []string) error {
 
 	// Check for mixing positional args with deprecated flags
 	if len(args) > 0 && deprecatedFlagsUsed {
-		return fmt.Errorf("cannot use both positional arguments and deprecated flags: use either 'scr COMMAND [SCRIPT]' or deprecated flags, not both")
+		return classify(fmt.Errorf("cannot use both positional arguments and deprecated flags: use either 'scr COMMAND [SCRIPT]' or deprecated flags, not both"), errUsage)
+	}
+
+	// Get optional script from args or a script file
+	scriptFile, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return fmt.Errorf("get file flag: %w", err)
+	}
+	if scriptFile != "" && len(args) > 1 {
+		return classify(fmt.Errorf("cannot use both --file and a SCRIPT argument"), errUsage)
+	}
+
+	actionsJSON, err := cmd.Flags().GetString("actions-json")
+	if err != nil {
+		return fmt.Errorf("get actions-json flag: %w", err)
+	}
+	if actionsJSON != "" && (scriptFile != "" || len(args) > 1) {
+		return classify(fmt.Errorf("cannot use --actions-json with --file or a SCRIPT argument"), errUsage)
 	}
 
-	// Get optional script from args
 	var scriptStr string
 	if len(args) > 1 {
 		scriptStr = args[1]
+		if script.IsFile(scriptStr) {
+			scriptFile = scriptStr
+		}
+	}
+	if scriptFile != "" {
+		scriptStr, err = script.ReadFile(scriptFile)
+		if err != nil {
+			return classify(err, errUsage)
+		}
+	}
+	if actionsJSON != "" {
+		scriptStr, err = readActionsJSON(actionsJSON)
+		if err != nil {
+			return classify(err, errUsage)
+		}
+	}
+
+	matrixFile, err := cmd.Flags().GetString("matrix")
+	if err != nil {
+		return fmt.Errorf("get matrix flag: %w", err)
 	}
 
 	// Handle deprecated flag mode
 	if deprecatedFlagsUsed {
+		if jsonOut {
+			return classify(fmt.Errorf("--json needs 'scr COMMAND [SCRIPT]' instead of deprecated flags"), errUsage)
+		}
+		if matrixFile != "" {
+			return classify(fmt.Errorf("--matrix needs 'scr COMMAND [SCRIPT]' instead of deprecated flags"), errUsage)
+		}
+		if actionsJSON != "" {
+			return classify(fmt.Errorf("--actions-json needs 'scr COMMAND' instead of deprecated flags"), errUsage)
+		}
 		return runWithDeprecatedFlags(cmd)
 	}
 
+	if matrixFile != "" {
+		if jsonOut {
+			return classify(fmt.Errorf("cannot use --json with --matrix: each entry is a separate run"), errUsage)
+		}
+		return runMatrix(cmd, matrixFile, command, commandArgs, scriptStr)
+	}
+	if isSet(cmd.Flags(), "parallel") || isSet(cmd.Flags(), "fail-fast") {
+		return classify(fmt.Errorf("--parallel and --fail-fast need --matrix"), errUsage)
+	}
+
 	// Handle new positional arg mode
-	return runWithPositionalArgs(cmd, command, scriptStr)
+	return runWithPositionalArgs(cmd, command, commandArgs, scriptStr, result, nil, nil)
 }
 
 // runWithPositionalArgs handles the new positional argument interface.
-func runWithPositionalArgs(cmd *cobra.Command, command, scriptStr string) error {
-	if command == "" {
+// commandArgs is the program run with --no-shell, in which case command is
+// empty. result, when not nil, collects the outcome for --json, which
+// replaces the messages printed otherwise. entry, when not nil, is the
+// --matrix entry or batch capture being run, and browser, when not nil,
+// the Chrome it opens its page in. Errors before the capture starts are
+// usage errors, unless they say otherwise.
+func runWithPositionalArgs(cmd *cobra.Command, command string, commandArgs []string, scriptStr string, result *runResult, entry *config.MatrixEntry, browser *capture.SharedBrowser) (err error) {
+	started := false
+	defer func() {
+		var classified *classError
+		if err != nil && !started && !errors.As(err, &classified) {
+			err = classify(err, errUsage)
+		}
+	}()
+
+	attachURL, err := cmd.Flags().GetString("attach-url")
+	if err != nil {
+		return fmt.Errorf("get attach-url flag: %w", err)
+	}
+
+	if attachURL != "" && isSet(cmd.Flags(), "port") {
+		return fmt.Errorf("cannot use --attach-url with -p/--port: the attached ttyd already listens on the URL's port")
+	}
+	if attachURL != "" && (command != "" || len(commandArgs) > 0) {
+		return fmt.Errorf("cannot use --attach-url with a COMMAND: the attached ttyd already runs its command (pass \"\" as COMMAND to give a SCRIPT)")
+	}
+	tmuxLayout, err := cmd.Flags().GetString("tmux-layout")
+	if err != nil {
+		return fmt.Errorf("get tmux-layout flag: %w", err)
+	}
+	if tmuxLayout != "" && (command != "" || len(commandArgs) > 0) {
+		return fmt.Errorf("cannot use --tmux-layout with a COMMAND: the layout gives each pane its command (pass \"\" as COMMAND to give a SCRIPT)")
+	}
+	if tmuxLayout != "" && attachURL != "" {
+		return fmt.Errorf("cannot use --tmux-layout with --attach-url: the layout starts its own tmux session")
+	}
+	if command == "" && len(commandArgs) == 0 && attachURL == "" && tmuxLayout == "" {
 		return fmt.Errorf("COMMAND is required (e.g., 'scr bash' or 'scr bash \"Type ...\"')")
 	}
 
+	shell, err := cmd.Flags().GetString("shell")
+	if err != nil {
+		return fmt.Errorf("get shell flag: %w", err)
+	}
+	if len(commandArgs) > 0 && isSet(cmd.Flags(), "shell") {
+		return fmt.Errorf("cannot use both --shell and --no-shell")
+	}
+
 	// Parse new short flags
 	outputDir, err := cmd.Flags().GetString("out")
 	if err != nil {
 		return fmt.Errorf("get out flag: %w", err)
 	}
+	if entry != nil {
+		outputDir = filepath.Join(outputDir, entry.Out)
+	}
 
 	screenshotInterval, err := cmd.Flags().GetDuration("interval")
 	if err != nil {
@@ -133,38 +413,425 @@ func runWithPositionalArgs(cmd *cobra.Command, command, scriptStr string) error
 		return fmt.Errorf("get verbose flag: %w", err)
 	}
 
+	noCaptureWhileTyping, err := cmd.Flags().GetBool("no-capture-while-typing")
+	if err != nil {
+		return fmt.Errorf("get no-capture-while-typing flag: %w", err)
+	}
+
+	dedup, err := cmd.Flags().GetBool("dedup")
+	if err != nil {
+		return fmt.Errorf("get dedup flag: %w", err)
+	}
+
+	logFile, err := cmd.Flags().GetString("log")
+	if err != nil {
+		return fmt.Errorf("get log flag: %w", err)
+	}
+
+	logMaxSize, err := cmd.Flags().GetInt("log-max-size")
+	if err != nil {
+		return fmt.Errorf("get log-max-size flag: %w", err)
+	}
+	if logMaxSize < 1 {
+		return fmt.Errorf("--log-max-size must be >= 1, got %d", logMaxSize)
+	}
+
+	logKeep, err := cmd.Flags().GetInt("log-keep")
+	if err != nil {
+		return fmt.Errorf("get log-keep flag: %w", err)
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("get format flag: %w", err)
+	}
+
+	nameTemplate, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return fmt.Errorf("get name flag: %w", err)
+	}
+
+	outTmp, err := cmd.Flags().GetBool("out-tmp")
+	if err != nil {
+		return fmt.Errorf("get out-tmp flag: %w", err)
+	}
+
+	width, err := cmd.Flags().GetInt("width")
+	if err != nil {
+		return fmt.Errorf("get width flag: %w", err)
+	}
+
+	height, err := cmd.Flags().GetInt("height")
+	if err != nil {
+		return fmt.Errorf("get height flag: %w", err)
+	}
+
+	// Zero means the default size in Config, so reject it here
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("--width and --height must be > 0, got %dx%d", width, height)
+	}
+
+	cols, err := cmd.Flags().GetInt("cols")
+	if err != nil {
+		return fmt.Errorf("get cols flag: %w", err)
+	}
+
+	rows, err := cmd.Flags().GetInt("rows")
+	if err != nil {
+		return fmt.Errorf("get rows flag: %w", err)
+	}
+
+	themeRef, err := cmd.Flags().GetString("theme")
+	if err != nil {
+		return fmt.Errorf("get theme flag: %w", err)
+	}
+
+	frameDelay, err := cmd.Flags().GetDuration("gif-delay")
+	if err != nil {
+		return fmt.Errorf("get gif-delay flag: %w", err)
+	}
+
+	targetFrames, err := cmd.Flags().GetInt("target-frames")
+	if err != nil {
+		return fmt.Errorf("get target-frames flag: %w", err)
+	}
+
+	keepFrames, err := cmd.Flags().GetBool("keep-frames")
+	if err != nil {
+		return fmt.Errorf("get keep-frames flag: %w", err)
+	}
+
+	beforeHook, err := cmd.Flags().GetString("before")
+	if err != nil {
+		return fmt.Errorf("get before flag: %w", err)
+	}
+
+	afterHook, err := cmd.Flags().GetString("after")
+	if err != nil {
+		return fmt.Errorf("get after flag: %w", err)
+	}
+
+	frameHook, err := cmd.Flags().GetString("frame-hook")
+	if err != nil {
+		return fmt.Errorf("get frame-hook flag: %w", err)
+	}
+
+	frameHookStrict, err := cmd.Flags().GetBool("frame-hook-strict")
+	if err != nil {
+		return fmt.Errorf("get frame-hook-strict flag: %w", err)
+	}
+
+	promptPattern, err := cmd.Flags().GetString("prompt-pattern")
+	if err != nil {
+		return fmt.Errorf("get prompt-pattern flag: %w", err)
+	}
+
+	env, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		return fmt.Errorf("get env flag: %w", err)
+	}
+
+	systemFonts, err := cmd.Flags().GetBool("system-fonts")
+	if err != nil {
+		return fmt.Errorf("get system-fonts flag: %w", err)
+	}
+
+	fontSize, err := cmd.Flags().GetInt("font-size")
+	if err != nil {
+		return fmt.Errorf("get font-size flag: %w", err)
+	}
+
+	fontFamily, err := cmd.Flags().GetString("font-family")
+	if err != nil {
+		return fmt.Errorf("get font-family flag: %w", err)
+	}
+
+	showStats, err := cmd.Flags().GetBool("stats")
+	if err != nil {
+		return fmt.Errorf("get stats flag: %w", err)
+	}
+
+	exitOnDone, err := cmd.Flags().GetBool("exit-on-done")
+	if err != nil {
+		return fmt.Errorf("get exit-on-done flag: %w", err)
+	}
+
+	failOnError, err := cmd.Flags().GetBool("fail-on-error")
+	if err != nil {
+		return fmt.Errorf("get fail-on-error flag: %w", err)
+	}
+
+	chromePath, err := cmd.Flags().GetString("chrome-path")
+	if err != nil {
+		return fmt.Errorf("get chrome-path flag: %w", err)
+	}
+
+	chromeFlags, err := cmd.Flags().GetStringArray("chrome-flag")
+	if err != nil {
+		return fmt.Errorf("get chrome-flag flag: %w", err)
+	}
+
+	chromeProfile, err := cmd.Flags().GetString("chrome-profile")
+	if err != nil {
+		return fmt.Errorf("get chrome-profile flag: %w", err)
+	}
+
+	video, err := cmd.Flags().GetString("video")
+	if err != nil {
+		return fmt.Errorf("get video flag: %w", err)
+	}
+
+	padding, err := cmd.Flags().GetInt("padding")
+	if err != nil {
+		return fmt.Errorf("get padding flag: %w", err)
+	}
+
+	background, err := cmd.Flags().GetString("bg")
+	if err != nil {
+		return fmt.Errorf("get bg flag: %w", err)
+	}
+
+	window, err := cmd.Flags().GetBool("window")
+	if err != nil {
+		return fmt.Errorf("get window flag: %w", err)
+	}
+
+	title, err := cmd.Flags().GetString("title")
+	if err != nil {
+		return fmt.Errorf("get title flag: %w", err)
+	}
+
+	escapeDelay, err := cmd.Flags().GetDuration("escape-delay")
+	if err != nil {
+		return fmt.Errorf("get escape-delay flag: %w", err)
+	}
+
+	typeChunkSize, err := cmd.Flags().GetInt("type-chunk-size")
+	if err != nil {
+		return fmt.Errorf("get type-chunk-size flag: %w", err)
+	}
+
+	noTypeVerify, err := cmd.Flags().GetBool("no-type-verify")
+	if err != nil {
+		return fmt.Errorf("get no-type-verify flag: %w", err)
+	}
+
+	noProbe, err := cmd.Flags().GetBool("no-probe")
+	if err != nil {
+		return fmt.Errorf("get no-probe flag: %w", err)
+	}
+
+	quality, err := cmd.Flags().GetInt("quality")
+	if err != nil {
+		return fmt.Errorf("get quality flag: %w", err)
+	}
+
+	selector, err := cmd.Flags().GetString("selector")
+	if err != nil {
+		return fmt.Errorf("get selector flag: %w", err)
+	}
+
+	cropFlag, err := cmd.Flags().GetString("crop")
+	if err != nil {
+		return fmt.Errorf("get crop flag: %w", err)
+	}
+	var crop image.Rectangle
+	if cropFlag != "" {
+		if crop, err = config.ParseCrop(cropFlag); err != nil {
+			return fmt.Errorf("parse --crop: %w", err)
+		}
+	}
+
+	ssh, err := cmd.Flags().GetString("ssh")
+	if err != nil {
+		return fmt.Errorf("get ssh flag: %w", err)
+	}
+
+	// The tmux session takes the place of COMMAND, on its own server
+	var tmuxPanes []config.TmuxPane
+	if tmuxLayout != "" {
+		data, err := os.ReadFile(tmuxLayout)
+		if err != nil {
+			return fmt.Errorf("read --tmux-layout: %w", err)
+		}
+		tmuxPanes, err = config.ParseTmuxLayout(string(data))
+		if err != nil {
+			return fmt.Errorf("parse --tmux-layout %s: %w", tmuxLayout, err)
+		}
+		if _, err := exec.LookPath("tmux"); err != nil && ssh == "" {
+			return classify(fmt.Errorf("tmux not found; install tmux 3.0 or later to use --tmux-layout"), capture.ErrMissingDependency)
+		}
+		command = config.TmuxCommand(fmt.Sprintf("scr-%d", os.Getpid()), tmuxPanes)
+	}
+
+	noColorSession, err := cmd.Flags().GetBool("no-color-session")
+	if err != nil {
+		return fmt.Errorf("get no-color-session flag: %w", err)
+	}
+
+	anonymize, err := cmd.Flags().GetBool("anonymize")
+	if err != nil {
+		return fmt.Errorf("get anonymize flag: %w", err)
+	}
+
+	grayscale, err := cmd.Flags().GetBool("grayscale")
+	if err != nil {
+		return fmt.Errorf("get grayscale flag: %w", err)
+	}
+
+	simulateCVD, err := cmd.Flags().GetStringSlice("simulate-cvd")
+	if err != nil {
+		return fmt.Errorf("get simulate-cvd flag: %w", err)
+	}
+
+	noLock, err := cmd.Flags().GetBool("no-lock")
+	if err != nil {
+		return fmt.Errorf("get no-lock flag: %w", err)
+	}
+
+	skipVersionCheck, err := cmd.Flags().GetBool("skip-version-check")
+	if err != nil {
+		return fmt.Errorf("get skip-version-check flag: %w", err)
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("get dry-run flag: %w", err)
+	}
+
+	storyboard, err := cmd.Flags().GetBool("storyboard")
+	if err != nil {
+		return fmt.Errorf("get storyboard flag: %w", err)
+	}
+	if result != nil && (dryRun || storyboard) {
+		return fmt.Errorf("cannot use --json with --dry-run or --storyboard: they print a plan, not a result")
+	}
+
+	maxActionDuration, err := cmd.Flags().GetDuration("max-action-duration")
+	if err != nil {
+		return fmt.Errorf("get max-action-duration flag: %w", err)
+	}
+
+	strict, err := cmd.Flags().GetBool("strict")
+	if err != nil {
+		return fmt.Errorf("get strict flag: %w", err)
+	}
+
+	params, err := paramValues(cmd)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		for name, value := range entry.Params {
+			params[name] = value
+		}
+	}
+
 	// Parse script if provided
 	var actions []script.Action
+	var resolved []script.Param
 	if scriptStr != "" {
-		parsedActions, err := script.Parse(scriptStr)
+		parsed, err := script.ParseScript(scriptStr, params)
 		if err != nil {
-			return fmt.Errorf("parse script: %w", err)
+			return parseScriptError(err, scriptStr)
 		}
-		actions = parsedActions
+		actions, resolved = parsed.Actions, parsed.Params
+		positions := parsed.Positions
+		printWarnings(cmd.ErrOrStderr(), parsed.Warnings, scriptStr)
+
+		if err := checkDurations(cmd.ErrOrStderr(), actions, positions, scriptStr, maxActionDuration, strict); err != nil {
+			return err
+		}
+	} else if len(params) > 0 {
+		return fmt.Errorf("--param needs a SCRIPT that declares it with Param")
 	}
 
 	// Create config - pass actions directly to capture engine
 	cfg := &config.Config{
 		Command:            command,
+		CommandArgs:        commandArgs,
+		Shell:              shell,
 		OutputDir:          outputDir,
+		NameTemplate:       nameTemplate,
 		ScreenshotInterval: screenshotInterval,
 		TTydPort:           ttydPort,
+		AutoPort:           autoPort(cmd.Flags()),
 		Timeout:            timeout,
 		Verbose:            verbose,
 		Actions:            actions,
 		Script:             scriptStr,
+		Params:             resolved,
+		OutTmp:             outTmp,
+		LogFile:            logFile,
+		LogMaxSize:         int64(logMaxSize) << 20,
+		LogKeep:            logKeep,
+
+		NoCaptureWhileTyping: noCaptureWhileTyping,
+		Dedup:                dedup,
+		FrameDiff:            showStats,
+		Width:                width,
+		Height:               height,
+		Cols:                 cols,
+		Rows:                 rows,
+		Theme:                themeRef,
+		Format:               format,
+		FrameDelay:           frameDelay,
+		KeepFrames:           keepFrames,
+		FrameHook:            frameHook,
+		FrameHookStrict:      frameHookStrict,
+		PromptPattern:        promptPattern,
+		SystemFonts:          systemFonts,
+		Env:                  env,
+		NoColorSession:       noColorSession,
+		Grayscale:            grayscale,
+		FontSize:             fontSize,
+		FontFamily:           fontFamily,
+		Padding:              padding,
+		Background:           background,
+		EscapeDelay:          escapeDelay,
+		TypeChunkSize:        typeChunkSize,
+		NoTypeVerify:         noTypeVerify,
+		Window:               window,
+		Title:                title,
+		Quality:              quality,
+		Selector:             selector,
+		Crop:                 crop,
+		SSH:                  ssh,
+		FailOnError:          failOnError,
+		TerminalURL:          attachURL,
+		ExitOnDone:           exitOnDone,
+		Video:                video,
+		SimulateCVD:          simulateCVD,
+		NoLock:               noLock,
+		SkipVersionCheck:     skipVersionCheck,
+		ChromePath:           chromePath,
+		ChromeFlags:          chromeFlags,
+		ChromeProfile:        chromeProfile,
+		TmuxPanes:            tmuxPanes,
+		Anonymize:            anonymize,
+		BeforeHook:           beforeHook,
+		AfterHook:            afterHook,
+		TargetFrames:         targetFrames,
+		NoProbe:              noProbe,
 	}
 
 	// Validate config (skip keypresses/delays validation if script was used)
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("validate config: %w", err)
 	}
+	if result != nil {
+		result.describe(cfg)
+	}
 
 	// Log success if verbose
 	if cfg.Verbose {
-		logger := log.New(os.Stderr, "", log.LstdFlags)
+		logger := log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
 		logger.Printf("Configuration validated successfully")
-		logger.Printf("Command: %s", cfg.Command)
+		if cfg.TerminalURL != "" {
+			logger.Printf("Attach URL: %s", cfg.TerminalURL)
+		} else {
+			logger.Printf("Command: %s", cfg.CommandLine())
+		}
 		if cfg.Script != "" {
 			logger.Printf("Script: %s", cfg.Script)
 			logger.Printf("Actions: %d", len(cfg.Actions))
@@ -172,13 +839,50 @@ func runWithPositionalArgs(cmd *cobra.Command, command, scriptStr string) error
 			logger.Printf("Keypresses: %v", cfg.Keypresses)
 		}
 		logger.Printf("Output Directory: %s", cfg.OutputDir)
+		logger.Printf("Viewport: %dx%d", cfg.Width, cfg.Height)
+		if cfg.Cols > 0 || cfg.Rows > 0 {
+			logger.Printf("Terminal: %d cols x %d rows", cfg.Cols, cfg.Rows)
+		}
+	}
+
+	// Dry runs stop here, before ttyd or Chrome are started
+	if storyboard {
+		return printStoryboard(cmd.OutOrStdout(), cfg)
+	}
+	if dryRun {
+		return printDryRun(cmd.OutOrStdout(), cfg)
+	}
+
+	// The command runs in our working directory, unless it runs on another
+	// host; frames written below it can show up in its output (e.g. ls)
+	if !cfg.OutTmp && cfg.TerminalURL == "" && cfg.SSH == "" {
+		if inside, err := isWithinDir(cfg.OutputDir, "."); err == nil && inside {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: output directory %s is inside the command's working directory, so frames may appear in the capture; use -o with a path outside it, or --out-tmp\n", cfg.OutputDir)
+		}
+	}
+
+	progress, err := openProgress(cmd)
+	if err != nil {
+		return err
+	}
+	if progress != nil {
+		defer progress.Close()
+		cfg.Progress = progress
 	}
 
 	// Create capturer and execute capture workflow
+	started = true
 	capturer := capture.NewCapturer(cfg)
+	if browser != nil {
+		capturer.ShareBrowser(browser)
+	}
+	if result != nil {
+		// Collected however the run ends, once it has
+		defer result.collect(capturer)
+	}
 
 	// Apply timeout from config
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	ctx, cancel := context.WithTimeoutCause(context.Background(), cfg.Timeout, errTimeout)
 	defer cancel()
 
 	// Set up signal handling for graceful shutdown on Ctrl+C
@@ -192,29 +896,252 @@ func runWithPositionalArgs(cmd *cobra.Command, command, scriptStr string) error
 		runErr <- capturer.Run(ctx)
 	}()
 
-	// Wait for either capture completion or signal
+	// Wait for either capture completion or signal. A command that exited
+	// non-zero still produced complete output, which is reported first.
+	var exitErr *capture.ExitError
 	select {
 	case err := <-runErr:
-		if err != nil {
-			return fmt.Errorf("capture execution: %w", err)
+		if err != nil && !errors.As(err, &exitErr) {
+			return captureFailed(ctx, err)
 		}
 	case sig := <-sigChan:
 		// Cancel context on signal to trigger cleanup
 		cancel()
 		// Wait for capture to finish cleanup
 		if err := <-runErr; err != nil {
-			log.Printf("shutdown error: %v", err)
+			fmt.Fprintf(cmd.ErrOrStderr(), "shutdown error: %v\n", err)
 		}
-		fmt.Fprintf(os.Stderr, "\nReceived signal %s, shutting down gracefully...\n", sig)
-		return fmt.Errorf("interrupted by signal: %s", sig)
+		fmt.Fprintf(cmd.ErrOrStderr(), "\nReceived signal %s, shutting down gracefully...\n", sig)
+		return classify(fmt.Errorf("interrupted by signal: %s", sig), errInterrupted)
+	}
+
+	if showStats {
+		printStats(cmd.ErrOrStderr(), capturer.Stats())
+	}
+
+	if result != nil {
+		if exitErr != nil {
+			return fmt.Errorf("capture execution: %w", exitErr)
+		}
+		return nil
+	}
+
+	if artifact := capturer.Artifact(); artifact != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", artifact)
+	}
+	if video := capturer.Video(); video != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", video)
+	}
+	if cfg.FrameHook != "" {
+		hooks := capturer.FrameHooks()
+		fmt.Fprintf(cmd.OutOrStdout(), "Frame hook: %d succeeded, %d failed\n", hooks.Succeeded, hooks.Failed)
+	}
+
+	if exitErr != nil {
+		return fmt.Errorf("capture execution: %w", exitErr)
 	}
 
 	// Print success message
-	fmt.Printf("Capture completed successfully\n")
+	fmt.Fprintf(cmd.OutOrStdout(), "Capture completed successfully: %s\n", cfg.OutputDir)
+
+	return nil
+}
+
+// printStats writes the run's timings: startup phases with when each began,
+// since ttyd and the browser start together, then every frame and action
+// with its offset from the terminal becoming ready and its wall-clock time.
+// Frames also show how much they changed from the previous frame.
+func printStats(w io.Writer, stats capture.Stats) {
+	fmt.Fprintf(w, "Startup: %v\n", stats.Startup.Round(time.Millisecond))
+	for _, phase := range stats.Phases {
+		fmt.Fprintf(w, "  %-10s +%-10v took %v\n", phase.Name, phase.Start.Sub(stats.Start).Round(time.Millisecond), phase.Duration.Round(time.Millisecond))
+	}
+	duplicates := 0
+	for _, frame := range stats.Frames {
+		if frame.Duplicate {
+			duplicates++
+		}
+	}
+	if duplicates > 0 {
+		fmt.Fprintf(w, "Frames: %d (%d duplicates skipped)\n", len(stats.Frames)-duplicates, duplicates)
+	} else {
+		fmt.Fprintf(w, "Frames: %d\n", len(stats.Frames))
+	}
+	for _, frame := range stats.Frames {
+		name := filepath.Base(frame.Path)
+		if frame.Duplicate {
+			name = "(same as " + name + ")"
+		}
+		change := ""
+		if frame.Compared {
+			change = fmt.Sprintf("  %5.1f%% change", frame.Change)
+		}
+		fmt.Fprintf(w, "  %-24s +%-10v %s%s\n", name, frame.Offset.Round(time.Millisecond), frame.Time.Format("15:04:05.000"), change)
+	}
+	fmt.Fprintf(w, "Actions: %d\n", len(stats.Actions))
+	for _, action := range stats.Actions {
+		fmt.Fprintf(w, "  #%-23d +%-10v %s (took %v)\n", action.Index, action.Offset.Round(time.Millisecond), action.Time.Format("15:04:05.000"), action.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "Total: %v\n", stats.Total.Round(time.Millisecond))
+}
+
+// isWithinDir reports whether path is dir or lies below it, after resolving
+// both to absolute paths and following symlinks where they exist.
+func isWithinDir(path, dir string) (bool, error) {
+	absPath, err := resolvePath(path)
+	if err != nil {
+		return false, err
+	}
+	absDir, err := resolvePath(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// resolvePath makes path absolute and resolves symlinks in its longest
+// existing prefix, so a not-yet-created output directory still compares
+// correctly.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := abs, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// autoPort reports whether ttyd should listen on any free port, which it
+// does unless -p/--port was set on the command line, in a config file or in
+// the environment.
+func autoPort(flags *pflag.FlagSet) bool {
+	return !isSet(flags, "port")
+}
+
+// printDryRun writes the parsed actions and the number of frames the run is
+// expected to take.
+func printDryRun(w io.Writer, cfg *config.Config) error {
+	frames, err := capture.Storyboard(cfg)
+	if err != nil {
+		return fmt.Errorf("plan frames: %w", err)
+	}
+
+	fmt.Fprintf(w, "Actions: %d\n", len(cfg.Actions))
+	for i, action := range cfg.Actions {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, action)
+	}
+	est := script.Estimate(cfg.Actions, script.EstimateOptions{Interval: cfg.ScreenshotInterval})
+	fmt.Fprintf(w, "Duration: at least %v\n", est.Duration.Round(time.Millisecond))
+	last := frames[len(frames)-1]
+	fmt.Fprintf(w, "Frames: %d (last at %v)\n", len(frames), last.Offset.Round(time.Millisecond))
+	return nil
+}
+
+// printStoryboard writes a Markdown storyboard of the frames the run is
+// expected to take, for reviewing a script without capturing it.
+func printStoryboard(w io.Writer, cfg *config.Config) error {
+	frames, err := capture.Storyboard(cfg)
+	if err != nil {
+		return fmt.Errorf("plan frames: %w", err)
+	}
 
+	if cfg.TerminalURL != "" {
+		fmt.Fprintf(w, "# Storyboard: %s\n\n", cfg.TerminalURL)
+	} else {
+		fmt.Fprintf(w, "# Storyboard: `%s`\n\n", cfg.CommandLine())
+	}
+	if cfg.ScreenshotInterval > 0 {
+		fmt.Fprintf(w, "Interval: %v\n\n", cfg.ScreenshotInterval)
+	} else {
+		fmt.Fprintf(w, "Interval: off\n\n")
+	}
+	return capture.WriteStoryboard(w, frames)
+}
+
+// checkDurations warns about actions longer than limit, most likely unit
+// typos such as Sleep 500s, or with strict fails on the first one.
+func checkDurations(w io.Writer, actions []script.Action, positions []int, src string, limit time.Duration, strict bool) error {
+	for _, long := range script.CheckDurations(actions, positions, src, limit) {
+		excerpt := long.Excerpt(src)
+		if excerpt != "" {
+			excerpt = "\n" + excerpt
+		}
+		if strict {
+			return fmt.Errorf("check script: %w%s", long, excerpt)
+		}
+		fmt.Fprintf(w, "Warning: %v%s\n", long, excerpt)
+	}
 	return nil
 }
 
+// printWarnings writes the script's warnings with the lines they are on.
+func printWarnings(w io.Writer, warnings []*script.Warning, src string) {
+	for _, warning := range warnings {
+		excerpt := warning.Excerpt(src)
+		if excerpt != "" {
+			excerpt = "\n" + excerpt
+		}
+		fmt.Fprintf(w, "Warning: %v%s\n", warning, excerpt)
+	}
+}
+
+// paramValues returns the --param NAME=VALUE flags as a map.
+func paramValues(cmd *cobra.Command) (map[string]string, error) {
+	flags, err := cmd.Flags().GetStringArray("param")
+	if err != nil {
+		return nil, fmt.Errorf("get param flag: %w", err)
+	}
+	values := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --param %q; use NAME=VALUE", flag)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// parseScriptError wraps a script parse error, appending the offending line
+// with a caret under the error column when position information is available.
+func parseScriptError(err error, src string) error {
+	var parseErr *script.ParseError
+	if errors.As(err, &parseErr) {
+		if excerpt := parseErr.Excerpt(src); excerpt != "" {
+			return classify(fmt.Errorf("parse script: %w\n%s", err, excerpt), errUsage)
+		}
+	}
+	return classify(fmt.Errorf("parse script: %w", err), errUsage)
+}
+
+// readActionsJSON reads the --actions-json file and writes its actions as
+// script text, so the rest of the run treats them like a SCRIPT.
+func readActionsJSON(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read actions file: %w", err)
+	}
+	actions, err := script.ParseJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("parse actions file %s: %w", path, err)
+	}
+	return script.Format(actions), nil
+}
+
 // runWithDeprecatedFlags handles the old flag-based interface for backward compatibility.
 func runWithDeprecatedFlags(cmd *cobra.Command) error {
 	// Get deprecated flag values
@@ -297,6 +1224,7 @@ func runWithDeprecatedFlags(cmd *cobra.Command) error {
 		OutputDir:          outputDir,
 		ScreenshotInterval: screenshotInterval,
 		TTydPort:           ttydPort,
+		AutoPort:           autoPort(cmd.Flags()),
 		Timeout:            timeout,
 		Verbose:            verbose,
 	}
@@ -319,7 +1247,7 @@ func runWithDeprecatedFlags(cmd *cobra.Command) error {
 	capturer := capture.NewCapturer(cfg)
 
 	// Apply timeout from config
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	ctx, cancel := context.WithTimeoutCause(context.Background(), cfg.Timeout, errTimeout)
 	defer cancel()
 
 	// Set up signal handling for graceful shutdown on Ctrl+C
@@ -337,7 +1265,7 @@ func runWithDeprecatedFlags(cmd *cobra.Command) error {
 	select {
 	case err := <-runErr:
 		if err != nil {
-			return fmt.Errorf("capture execution: %w", err)
+			return captureFailed(ctx, err)
 		}
 	case sig := <-sigChan:
 		// Cancel context on signal to trigger cleanup
@@ -347,11 +1275,11 @@ func runWithDeprecatedFlags(cmd *cobra.Command) error {
 			log.Printf("shutdown error: %v", err)
 		}
 		fmt.Fprintf(os.Stderr, "\nReceived signal %s, shutting down gracefully...\n", sig)
-		return fmt.Errorf("interrupted by signal: %s", sig)
+		return classify(fmt.Errorf("interrupted by signal: %s", sig), errInterrupted)
 	}
 
 	// Print success message
-	fmt.Printf("Capture completed successfully\n")
+	fmt.Printf("Capture completed successfully: %s\n", cfg.OutputDir)
 
 	return nil
 }
@@ -360,6 +1288,78 @@ func runWithDeprecatedFlags(cmd *cobra.Command) error {
 func main() {
 	cmd := NewRootCommand()
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// Exit statuses of a failed run, documented in the README.
+const (
+	exitFailure     = 1
+	exitUsage       = 2
+	exitDependency  = 3
+	exitTimeout     = 4
+	exitInterrupted = 5
+	exitCommand     = 6
+)
+
+// Failure classes that exitCode tells apart, besides a command that exited
+// non-zero and capture.ErrMissingDependency.
+var (
+	// errUsage is a bad flag, argument, script or configuration, found
+	// before anything started.
+	errUsage = errors.New("usage error")
+	// errTimeout is the --timeout running out.
+	errTimeout = errors.New("timed out")
+	// errInterrupted is a run stopped by SIGINT or SIGTERM.
+	errInterrupted = errors.New("interrupted")
+)
+
+// classError gives err a failure class, matched with errors.Is, without
+// changing its message.
+type classError struct {
+	err, class error
+}
+
+func (e *classError) Error() string { return e.err.Error() }
+
+func (e *classError) Unwrap() []error { return []error{e.err, e.class} }
+
+// classify returns err in class, or nil when err is nil.
+func classify(err, class error) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{err: err, class: class}
+}
+
+// captureFailed wraps the error of a capture run on ctx, in errTimeout when
+// the --timeout of ctx ran out.
+func captureFailed(ctx context.Context, err error) error {
+	err = fmt.Errorf("capture execution: %w", err)
+	if errors.Is(context.Cause(ctx), errTimeout) {
+		return classify(err, errTimeout)
+	}
+	return err
+}
+
+// exitCode maps a failed run to the process exit status: 2 for usage
+// errors, 3 when ttyd, Chrome or another program the run needs is missing,
+// 4 when --timeout ran out, 5 when interrupted by a signal, 6 when the
+// captured command exited non-zero (with --exit-on-done or
+// --fail-on-error), and 1 otherwise.
+func exitCode(err error) int {
+	var exitErr *capture.ExitError
+	switch {
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.As(err, &exitErr):
+		return exitCommand
+	case errors.Is(err, capture.ErrMissingDependency):
+		return exitDependency
+	case errors.Is(err, errTimeout):
+		return exitTimeout
+	case errors.Is(err, errUsage):
+		return exitUsage
 	}
+	return exitFailure
 }
@@ -2,11 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/yarlson/scr/internal/capture"
 	"github.com/yarlson/scr/internal/input"
 )
 
@@ -318,3 +327,834 @@ func TestRunCommand_ExecutesCapture(t *testing.T) {
 		})
 	}
 }
+
+// TestRootCommand_ScriptFile tests reading the script from a file via -f or the SCRIPT argument.
+func TestRootCommand_ScriptFile(t *testing.T) {
+	dir := t.TempDir()
+	badScript := filepath.Join(dir, "bad.tape")
+	require.NoError(t, os.WriteFile(badScript, []byte("Type 'ls'\r\nSleep 500\r\n"), 0o644))
+
+	tests := []struct {
+		name       string
+		args       []string
+		errContain []string
+	}{
+		{
+			name:       "parse error via --file reports line and caret",
+			args:       []string{"-f", badScript, "bash"},
+			errContain: []string{"parse error at line 2, column 7", "Sleep 500\n      ^"},
+		},
+		{
+			name:       "SCRIPT argument naming a file is read from disk",
+			args:       []string{"bash", badScript},
+			errContain: []string{"parse error at line 2, column 7"},
+		},
+		{
+			name:       "inline script errors also show a caret",
+			args:       []string{"bash", "Type 'ls' Bogus"},
+			errContain: []string{"line 1, column 11", "Type 'ls' Bogus\n          ^"},
+		},
+		{
+			name:       "--file with SCRIPT argument fails",
+			args:       []string{"-f", badScript, "bash", "Enter"},
+			errContain: []string{"cannot use both --file and a SCRIPT argument"},
+		},
+		{
+			name:       "missing --file fails",
+			args:       []string{"-f", filepath.Join(dir, "missing.tape"), "bash"},
+			errContain: []string{"read script file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.Error(t, err)
+			for _, want := range tt.errContain {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+// TestPrintStats tests the --stats summary lists phases, frames, and actions with both time bases.
+func TestPrintStats(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	origin := start.Add(1500 * time.Millisecond)
+
+	var buf bytes.Buffer
+	printStats(&buf, capture.Stats{
+		Start:   start,
+		Origin:  origin,
+		Startup: 1500 * time.Millisecond,
+		Phases: []capture.Phase{
+			{Name: "ttyd", Start: start, Duration: 200 * time.Millisecond},
+			{Name: "browser", Start: start.Add(5 * time.Millisecond), Duration: 900 * time.Millisecond},
+		},
+		Frames: []capture.FrameStat{
+			{Path: "/tmp/out/screenshot_001.png", Time: origin, Offset: 0},
+			{Path: "/tmp/out/screenshot_002.png", Time: origin.Add(500 * time.Millisecond), Offset: 500 * time.Millisecond},
+		},
+		Actions: []capture.ActionStat{{Index: 0, Time: origin.Add(10 * time.Millisecond), Offset: 10 * time.Millisecond, Duration: 40 * time.Millisecond}},
+		Total:   2 * time.Second,
+	})
+
+	output := buf.String()
+	assert.Contains(t, output, "Startup: 1.5s")
+	assert.Contains(t, output, "ttyd       +0s         took 200ms")
+	assert.Contains(t, output, "browser    +5ms        took 900ms")
+	assert.Contains(t, output, "Frames: 2")
+	assert.Contains(t, output, "screenshot_002.png")
+	assert.Contains(t, output, "+500ms")
+	assert.Contains(t, output, "03:04:07.000")
+	assert.Contains(t, output, "Actions: 1")
+	assert.Contains(t, output, "took 40ms")
+	assert.Contains(t, output, "Total: 2s")
+}
+
+func TestPrintStats_Duplicates(t *testing.T) {
+	origin := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	printStats(&buf, capture.Stats{
+		Origin: origin,
+		Frames: []capture.FrameStat{
+			{Path: "/tmp/out/screenshot_001.png", Time: origin},
+			{Path: "/tmp/out/screenshot_001.png", Time: origin.Add(500 * time.Millisecond), Offset: 500 * time.Millisecond, Duplicate: true},
+			{Path: "/tmp/out/screenshot_002.png", Time: origin.Add(time.Second), Offset: time.Second},
+		},
+	})
+
+	output := buf.String()
+	assert.Contains(t, output, "Frames: 2 (1 duplicates skipped)")
+	assert.Contains(t, output, "(same as screenshot_001.png) +500ms")
+}
+
+func TestPrintStats_Change(t *testing.T) {
+	origin := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	printStats(&buf, capture.Stats{
+		Origin: origin,
+		Frames: []capture.FrameStat{
+			{Path: "/tmp/out/screenshot_001.png", Time: origin},
+			{Path: "/tmp/out/screenshot_002.png", Time: origin.Add(time.Second), Offset: time.Second, Change: 12.5, Compared: true},
+			{Path: "/tmp/out/screenshot_003.png", Time: origin.Add(2 * time.Second), Offset: 2 * time.Second, Compared: true},
+		},
+	})
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.NotContains(t, lines[2], "change", "the first frame has nothing to compare against")
+	assert.Contains(t, lines[3], " 12.5% change")
+	assert.Contains(t, lines[4], "  0.0% change")
+}
+
+// TestRootCommand_AttachURL tests the --attach-url (--url) argument rules.
+func TestRootCommand_AttachURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		errContain string
+	}{
+		{
+			name:       "rejects a COMMAND",
+			args:       []string{"--attach-url", "http://localhost:7681", "bash"},
+			errContain: "cannot use --attach-url with a COMMAND",
+		},
+		{
+			name:       "url alias rejects a COMMAND",
+			args:       []string{"--url", "http://localhost:7681", "bash"},
+			errContain: "cannot use --attach-url with a COMMAND",
+		},
+		{
+			name:       "rejects a port",
+			args:       []string{"--url", "http://localhost:9999", "-p", "9999", "", "Enter"},
+			errContain: "cannot use --attach-url with -p/--port",
+		},
+		{
+			name:       "rejects a non-http URL",
+			args:       []string{"--attach-url", "localhost:7681", "", "Enter"},
+			errContain: "terminal URL must be an http(s) URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContain)
+		})
+	}
+}
+
+func TestRootCommand_Crop(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		errContain string
+	}{
+		{
+			name:       "rejects a short rectangle",
+			args:       []string{"--dry-run", "--crop", "10,20", "bash", "Enter"},
+			errContain: `parse --crop: crop "10,20" must be x,y,w,h`,
+		},
+		{
+			name:       "rejects an empty rectangle",
+			args:       []string{"--dry-run", "--crop", "0,0,0,10", "bash", "Enter"},
+			errContain: "must have x and y >= 0 and a width and height > 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContain)
+		})
+	}
+}
+
+func TestRootCommand_SSH(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		errContain string
+	}{
+		{
+			name:       "rejects an ssh option",
+			args:       []string{"--dry-run", "--ssh=-oProxyCommand=sh", "bash", "Enter"},
+			errContain: `ssh destination must be host or user@host, got "-oProxyCommand=sh"`,
+		},
+		{
+			name:       "rejects an attach URL",
+			args:       []string{"--dry-run", "--ssh", "devbox", "--url", "http://localhost:9999", "", "Enter"},
+			errContain: "cannot attach to a terminal URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContain)
+		})
+	}
+}
+
+func TestRootCommand_TmuxLayout(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+	layout := filepath.Join(t.TempDir(), "demo.layout")
+	require.NoError(t, os.WriteFile(layout, []byte("server: python3 -m http.server\nclient below 30%:\n"), 0o644))
+	broken := filepath.Join(t.TempDir(), "broken.layout")
+	require.NoError(t, os.WriteFile(broken, []byte("server sideways: top\n"), 0o644))
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       string
+		errContain string
+	}{
+		{
+			name: "runs the script against the panes",
+			args: []string{"--dry-run", "--tmux-layout", layout, "", "Pane 'client' Type 'curl localhost:8000' Enter"},
+			want: "  1. Pane 'client'\n",
+		},
+		{
+			name:       "rejects a COMMAND",
+			args:       []string{"--dry-run", "--tmux-layout", layout, "bash"},
+			errContain: "cannot use --tmux-layout with a COMMAND",
+		},
+		{
+			name:       "rejects an attach URL",
+			args:       []string{"--dry-run", "--tmux-layout", layout, "--url", "http://localhost:9999", ""},
+			errContain: "cannot use --tmux-layout with --attach-url",
+		},
+		{
+			name:       "rejects a broken layout",
+			args:       []string{"--dry-run", "--tmux-layout", broken, ""},
+			errContain: `line 1: pane server: unknown option "sideways"`,
+		},
+		{
+			name:       "rejects a missing layout",
+			args:       []string{"--dry-run", "--tmux-layout", filepath.Join(t.TempDir(), "missing.layout"), ""},
+			errContain: "read --tmux-layout",
+		},
+		{
+			name:       "rejects an unknown pane",
+			args:       []string{"--dry-run", "--tmux-layout", layout, "", "Pane 'logs'"},
+			errContain: `pane "logs" is not in the tmux layout (panes: server, client)`,
+		},
+		{
+			name:       "rejects a pane without a layout",
+			args:       []string{"--dry-run", "bash", "Pane 'client'"},
+			errContain: `pane "client" needs a tmux layout; pass --tmux-layout`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			out := bytes.NewBuffer(nil)
+			cmd.SetOut(out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestRootCommand_ActionsJSON(t *testing.T) {
+	dir := t.TempDir()
+	actions := filepath.Join(dir, "actions.json")
+	require.NoError(t, os.WriteFile(actions, []byte(`[
+		{"kind": "type", "text": "echo hi", "speed": "10ms"},
+		{"kind": "key", "key": "Enter"},
+		{"kind": "sleep", "duration": "500ms"}
+	]`), 0o644))
+	unknown := filepath.Join(dir, "unknown.json")
+	require.NoError(t, os.WriteFile(unknown, []byte(`[{"kind": "jump"}]`), 0o644))
+	negative := filepath.Join(dir, "negative.json")
+	require.NoError(t, os.WriteFile(negative, []byte(`[{"kind": "sleep", "duration": "-1s"}]`), 0o644))
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       string
+		errContain string
+	}{
+		{
+			name: "runs the actions",
+			args: []string{"--dry-run", "--actions-json", actions, "bash"},
+			want: "  1. Type@10ms 'echo hi'\n  2. Enter\n  3. Sleep 500ms\n",
+		},
+		{
+			name:       "rejects an unknown kind",
+			args:       []string{"--dry-run", "--actions-json", unknown, "bash"},
+			errContain: `action 1: unknown action kind "jump"`,
+		},
+		{
+			name:       "rejects a negative duration",
+			args:       []string{"--dry-run", "--actions-json", negative, "bash"},
+			errContain: "action 1: ",
+		},
+		{
+			name:       "rejects a SCRIPT argument",
+			args:       []string{"--dry-run", "--actions-json", actions, "bash", "Enter"},
+			errContain: "cannot use --actions-json with --file or a SCRIPT argument",
+		},
+		{
+			name:       "rejects a missing file",
+			args:       []string{"--dry-run", "--actions-json", filepath.Join(dir, "missing.json"), "bash"},
+			errContain: "read actions file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			out := bytes.NewBuffer(nil)
+			cmd.SetOut(out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				assert.Equal(t, 2, exitCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestRootCommand_DryRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "lists actions and frame count",
+			args: []string{"--dry-run", "-i", "0", "bash", "Type 'ls' Enter Screenshot 'listing'"},
+			want: []string{"Actions: 3\n", "  1. Type 'ls'\n", "  2. Enter\n", "  3. Screenshot 'listing'\n", "Duration: at least 100ms\n", "Frames: 3 (last at 200ms)\n"},
+		},
+		{
+			name: "no script is a static capture",
+			args: []string{"--dry-run", "ls"},
+			want: []string{"Actions: 0\n", "Frames: 2 (last at 100ms)\n"},
+		},
+		{
+			name: "empty script",
+			args: []string{"--dry-run", "ls", ""},
+			want: []string{"Actions: 0\n", "Frames: 2 (last at 100ms)\n"},
+		},
+		{
+			name: "comment-only script",
+			args: []string{"--dry-run", "ls", "# nothing to type"},
+			want: []string{"Actions: 0\n", "Frames: 2 (last at 100ms)\n"},
+		},
+		{
+			name: "settings-only script",
+			args: []string{"--storyboard", "ls", "Set Theme dracula\nSet Width 800"},
+			want: []string{
+				"| 1 | screenshot_001.png | 0s | initial | `Set Theme 'dracula'`, `Set Width 800` |\n",
+				"| 2 | screenshot_002.png | 100ms | final | — |\n",
+			},
+		},
+		{
+			name: "storyboard",
+			args: []string{"--storyboard", "-i", "0", "bash", "Type 'ls' Screenshot 'listing'"},
+			want: []string{"# Storyboard: `bash`\n", "Interval: off\n", "| 2 | listing.png | 100ms | screenshot \"listing\" | `Type 'ls'`, `Screenshot 'listing'` |\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			require.NoError(t, cmd.Execute())
+			for _, want := range tt.want {
+				assert.Contains(t, out.String(), want)
+			}
+			assert.NotContains(t, out.String(), "Capture completed")
+		})
+	}
+}
+
+func TestRootCommand_Params(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       string
+		errContain string
+	}{
+		{
+			name: "default",
+			args: []string{"--dry-run", "bash", "Param WHO default 'world' Type 'hi ${WHO}'"},
+			want: "Type 'hi world'",
+		},
+		{
+			name: "value",
+			args: []string{"--dry-run", "--param", "WHO=Gophers", "bash", "Param WHO default 'world' Type 'hi ${WHO}'"},
+			want: "Type 'hi Gophers'",
+		},
+		{
+			name:       "undeclared",
+			args:       []string{"--dry-run", "--param", "WHAT=x", "bash", "Param WHO default 'world' Type 'hi ${WHO}'"},
+			errContain: `unknown param "WHAT"`,
+		},
+		{
+			name:       "malformed",
+			args:       []string{"--dry-run", "--param", "WHO", "bash", "Enter"},
+			errContain: `invalid --param "WHO"; use NAME=VALUE`,
+		},
+		{
+			name:       "without a script",
+			args:       []string{"--dry-run", "--param", "WHO=x", "bash"},
+			errContain: "--param needs a SCRIPT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestRootCommand_TypeSecret(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--dry-run", "--param", "TOKEN=hunter2", "bash", "Param TOKEN secret\nTypeSecret ${TOKEN} Enter\nType '${TOKEN}'"})
+	cmd.SetOut(&out)
+	cmd.SetErr(&errOut)
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "1. TypeSecret [redacted]")
+	assert.Equal(t, "Warning: line 3, column 1: Type shows secret param ${TOKEN} in logs and frames; use TypeSecret to keep it out of them\nType '${TOKEN}'\n^\n", errOut.String())
+}
+
+func TestRootCommand_MaxActionDuration(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+		errContain string
+	}{
+		{
+			name:       "warns by default",
+			args:       []string{"--dry-run", "-t", "1h", "bash", "Type 'ls'\nSleep 500s"},
+			wantStderr: "Warning: line 2, column 1: Sleep lasts 8m20s, longer than the 1m0s limit for one action\nSleep 500s\n^\n",
+		},
+		{
+			name:       "fails with --strict",
+			args:       []string{"--dry-run", "--strict", "-t", "1h", "bash", "Sleep 500s"},
+			errContain: "check script: line 1, column 1: Sleep lasts 8m20s",
+		},
+		{
+			name: "custom limit",
+			args: []string{"--dry-run", "--strict", "--max-action-duration", "10m", "-t", "1h", "bash", "Sleep 500s"},
+		},
+		{
+			name: "zero disables the check",
+			args: []string{"--dry-run", "--strict", "--max-action-duration", "0", "-t", "1h", "bash", "Sleep 500s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+}
+
+func TestNewRootCommand_ChromeFlags(t *testing.T) {
+	cmd := NewRootCommand()
+	require.NoError(t, cmd.ParseFlags([]string{
+		"--chrome-path", "/usr/bin/chromium-browser",
+		"--chrome-flag=--no-sandbox", "--chrome-flag", "--proxy-server=a,b",
+		"--chrome-profile", "~/.cache/scr/chrome",
+	}))
+
+	path, err := cmd.Flags().GetString("chrome-path")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/chromium-browser", path)
+
+	flags, err := cmd.Flags().GetStringArray("chrome-flag")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--no-sandbox", "--proxy-server=a,b"}, flags)
+
+	profile, err := cmd.Flags().GetString("chrome-profile")
+	require.NoError(t, err)
+	assert.Equal(t, "~/.cache/scr/chrome", profile)
+}
+
+func TestNewRootCommand_GIFFlags(t *testing.T) {
+	cmd := NewRootCommand()
+	require.NoError(t, cmd.ParseFlags([]string{"--output-format", "gif", "--gif-delay", "80ms", "--keep-frames", "--target-frames", "60"}))
+
+	format, err := cmd.Flags().GetString("format")
+	require.NoError(t, err)
+	assert.Equal(t, "gif", format)
+
+	delay, err := cmd.Flags().GetDuration("gif-delay")
+	require.NoError(t, err)
+	assert.Equal(t, 80*time.Millisecond, delay)
+
+	keep, err := cmd.Flags().GetBool("keep-frames")
+	require.NoError(t, err)
+	assert.True(t, keep)
+	target, err := cmd.Flags().GetInt("target-frames")
+	require.NoError(t, err)
+	assert.Equal(t, 60, target)
+}
+
+func TestRootCommand_Geometry(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		errContain string
+	}{
+		{name: "accepts a custom viewport", args: []string{"--dry-run", "--width", "1024", "--height", "600", "--cols", "100", "bash", "Enter"}},
+		{name: "rejects zero width", args: []string{"--dry-run", "--width", "0", "bash", "Enter"}, errContain: "--width and --height must be > 0"},
+		{name: "rejects negative height", args: []string{"--dry-run", "--height", "-1", "bash", "Enter"}, errContain: "--width and --height must be > 0"},
+		{name: "rejects negative cols", args: []string{"--dry-run", "--cols", "-5", "bash", "Enter"}, errContain: "cols and rows must be > 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContain)
+		})
+	}
+}
+
+func TestRootCommand_LogFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		errContain string
+	}{
+		{name: "accepts log limits", args: []string{"--dry-run", "--log", "scr.log", "--log-max-size", "1", "--log-keep", "0", "bash", "Enter"}},
+		{name: "rejects zero max size", args: []string{"--dry-run", "--log-max-size", "0", "bash", "Enter"}, errContain: "--log-max-size must be >= 1"},
+		{name: "rejects negative keep", args: []string{"--dry-run", "--log-keep", "-1", "bash", "Enter"}, errContain: "log max size and keep must be >= 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContain)
+		})
+	}
+}
+
+func TestRootCommand_Shell(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOut    string
+		errContain string
+	}{
+		{name: "picks a shell", args: []string{"--storyboard", "--shell", "sh", "ls", "Enter"}, wantOut: "# Storyboard: `ls`"},
+		{name: "rejects an unknown shell", args: []string{"--dry-run", "--shell", "csh", "ls", "Enter"}, errContain: `unknown shell "csh"`},
+		{name: "runs argv without a shell", args: []string{"--storyboard", "--no-shell", "Enter", "--", "htop", "-d", "10"}, wantOut: "# Storyboard: `htop -d 10`"},
+		{name: "requires a program after --", args: []string{"--dry-run", "--no-shell", "Enter"}, errContain: "--no-shell needs the program after --"},
+		{name: "allows only a script before --", args: []string{"--dry-run", "--no-shell", "ls", "Enter", "--", "htop"}, errContain: "only a SCRIPT may come before --"},
+		{name: "rejects --shell with --no-shell", args: []string{"--dry-run", "--no-shell", "--shell", "zsh", "Enter", "--", "htop"}, errContain: "cannot use both --shell and --no-shell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			out := bytes.NewBuffer(nil)
+			cmd.SetOut(out)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.errContain != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContain)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.wantOut)
+		})
+	}
+}
+
+func TestIsWithinDir(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "work", "shots"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(root, "work"), filepath.Join(root, "link")))
+
+	tests := []struct {
+		name string
+		path string
+		dir  string
+		want bool
+	}{
+		{name: "existing subdirectory", path: filepath.Join(root, "work", "shots"), dir: filepath.Join(root, "work"), want: true},
+		{name: "not yet created subdirectory", path: filepath.Join(root, "work", "new", "shots"), dir: filepath.Join(root, "work"), want: true},
+		{name: "same directory", path: filepath.Join(root, "work"), dir: filepath.Join(root, "work"), want: true},
+		{name: "through a symlink", path: filepath.Join(root, "link", "shots"), dir: filepath.Join(root, "work"), want: true},
+		{name: "sibling directory", path: filepath.Join(root, "other"), dir: filepath.Join(root, "work"), want: false},
+		{name: "sibling with shared prefix", path: filepath.Join(root, "workshots"), dir: filepath.Join(root, "work"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isWithinDir(tt.path, tt.dir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "command exited non-zero", err: fmt.Errorf("capture execution: %w", &capture.ExitError{Code: 2}), want: 6},
+		{name: "other failure", err: errors.New("launch browser: crashed"), want: 1},
+		{name: "usage", err: classify(errors.New("cannot use both --file and a SCRIPT argument"), errUsage), want: 2},
+		{name: "missing dependency", err: fmt.Errorf("capture execution: %w", capture.ErrChromeNotFound), want: 3},
+		{name: "timeout", err: classify(errors.New("capture execution: wait: context deadline exceeded"), errTimeout), want: 4},
+		{name: "interrupted", err: classify(errors.New("interrupted by signal: interrupt"), errInterrupted), want: 5},
+		{name: "wrapped", err: fmt.Errorf("estimate: %w", classify(errors.New("parse script: bad"), errUsage)), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(tt.err))
+		})
+	}
+}
+
+func TestRootCommand_ErrorClasses(t *testing.T) {
+	layout := filepath.Join(t.TempDir(), "demo.layout")
+	require.NoError(t, os.WriteFile(layout, []byte("main:\n"), 0o644))
+
+	tests := []struct {
+		name  string
+		args  []string
+		path  string
+		class error
+		want  int
+	}{
+		{name: "unknown flag", args: []string{"--no-such-flag", "bash"}, class: errUsage, want: 2},
+		{name: "too many arguments", args: []string{"bash", "Enter", "extra"}, class: errUsage, want: 2},
+		{name: "script does not parse", args: []string{"bash", "Type"}, class: errUsage, want: 2},
+		{name: "conflicting flags", args: []string{"--url", "http://localhost:9999", "-p", "8080", ""}, class: errUsage, want: 2},
+		{name: "invalid configuration", args: []string{"-p", "99999", "bash"}, class: errUsage, want: 2},
+		{name: "missing script file", args: []string{"-f", filepath.Join(t.TempDir(), "missing.tape"), "bash"}, class: errUsage, want: 2},
+		{
+			name:  "no Chrome",
+			args:  []string{"-o", t.TempDir(), "--chrome-path", filepath.Join(t.TempDir(), "chromium"), "bash", "Enter"},
+			class: capture.ErrMissingDependency,
+			want:  3,
+		},
+		{
+			name:  "no tmux",
+			args:  []string{"--tmux-layout", layout, ""},
+			path:  t.TempDir(),
+			class: capture.ErrMissingDependency,
+			want:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path != "" {
+				t.Setenv("PATH", tt.path)
+			}
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(bytes.NewBuffer(nil))
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			require.ErrorIs(t, err, tt.class)
+			assert.Equal(t, tt.want, exitCode(err))
+		})
+	}
+}
+
+func TestRootCommand_OutputDirWarning(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var stderr bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"-o", "shots", "--chrome-path", filepath.Join(t.TempDir(), "chromium"), "bash", "Enter"})
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(&stderr)
+
+	require.ErrorIs(t, cmd.Execute(), capture.ErrMissingDependency)
+	assert.Contains(t, stderr.String(), "shots is inside the command's working directory", "written to the command's stderr, which --parallel serializes")
+}
+
+func TestRootCommand_OutputDirWarning_SSH(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	var stderr bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"-o", "shots", "--ssh", "devbox", "bash", "Enter"})
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(&stderr)
+
+	require.ErrorIs(t, cmd.Execute(), capture.ErrMissingDependency)
+	assert.NotContains(t, stderr.String(), "working directory", "the command runs on the remote host")
+}
+
+func TestCaptureFailed(t *testing.T) {
+	timedOut, cancel := context.WithTimeoutCause(context.Background(), time.Nanosecond, errTimeout)
+	defer cancel()
+	<-timedOut.Done()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		timeout bool
+	}{
+		{name: "timeout ran out", ctx: timedOut, timeout: true},
+		{name: "canceled", ctx: canceled},
+		{name: "still running", ctx: context.Background()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := captureFailed(tt.ctx, fmt.Errorf("wait for terminal: %w", context.DeadlineExceeded))
+			assert.EqualError(t, err, "capture execution: wait for terminal: context deadline exceeded")
+			assert.Equal(t, tt.timeout, errors.Is(err, errTimeout))
+			if tt.timeout {
+				assert.Equal(t, 4, exitCode(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yarlson/scr/internal/config"
+)
+
+// matrixRun is the outcome of one --matrix entry or batch capture.
+type matrixRun struct {
+	entry   config.MatrixEntry
+	dir     string
+	err     error
+	skipped bool
+	// took is how long the run took.
+	took time.Duration
+}
+
+// runMatrix runs the script once per entry of the --matrix file, up to
+// --parallel at a time. A failed entry doesn't stop the others unless
+// --fail-fast is set; an interrupted one stops them all.
+func runMatrix(cmd *cobra.Command, matrixFile, command string, commandArgs []string, scriptStr string) error {
+	data, err := os.ReadFile(matrixFile)
+	if err != nil {
+		return classify(fmt.Errorf("read --matrix: %w", err), errUsage)
+	}
+	entries, err := config.ParseMatrix(data)
+	if err != nil {
+		return classify(fmt.Errorf("parse --matrix %s: %w", matrixFile, err), errUsage)
+	}
+
+	parallel, err := cmd.Flags().GetInt("parallel")
+	if err != nil {
+		return fmt.Errorf("get parallel flag: %w", err)
+	}
+	failFast, err := cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		return fmt.Errorf("get fail-fast flag: %w", err)
+	}
+	outputDir, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return fmt.Errorf("get out flag: %w", err)
+	}
+	if err := checkParallel(cmd, parallel, "--matrix", "entries"); err != nil {
+		return err
+	}
+
+	runs := make([]matrixRun, len(entries))
+	for i := range entries {
+		runs[i] = matrixRun{entry: entries[i], dir: filepath.Join(outputDir, entries[i].Out)}
+	}
+	firstErr := runEntries(cmd, runs, parallel, failFast, func(run *matrixRun) error {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Matrix entry %s\n", run.entry.Out)
+		return runWithPositionalArgs(cmd, command, commandArgs, scriptStr, nil, &run.entry, nil)
+	})
+
+	failed := printMatrix(cmd.OutOrStdout(), runs)
+	if err := interrupted(runs); err != nil {
+		return err
+	}
+	if failFast && firstErr != nil {
+		return firstErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d matrix entries failed", failed, len(runs))
+	}
+	return nil
+}
+
+// checkParallel checks the flags that runs of mode, up to parallel at a
+// time, cannot be combined with.
+func checkParallel(cmd *cobra.Command, parallel int, mode, runs string) error {
+	progressFile, err := cmd.Flags().GetString("progress-file")
+	if err != nil {
+		return fmt.Errorf("get progress-file flag: %w", err)
+	}
+	tmuxLayout, err := cmd.Flags().GetString("tmux-layout")
+	if err != nil {
+		return fmt.Errorf("get tmux-layout flag: %w", err)
+	}
+
+	switch {
+	case parallel < 1:
+		return classify(fmt.Errorf("--parallel must be at least 1, got %d", parallel), errUsage)
+	case isSet(cmd.Flags(), "progress-fd") || progressFile != "":
+		return classify(fmt.Errorf("cannot use --progress-fd or --progress-file with %s: each of the %s is a separate run", mode, runs), errUsage)
+	case parallel > 1 && isSet(cmd.Flags(), "port"):
+		return classify(fmt.Errorf("cannot use -p/--port with --parallel: each of the %s needs its own port", runs), errUsage)
+	case parallel > 1 && tmuxLayout != "":
+		return classify(fmt.Errorf("cannot use --tmux-layout with --parallel: the %s would share a tmux session", runs), errUsage)
+	}
+	return nil
+}
+
+// runEntries calls run for each of runs, up to parallel at a time, and
+// returns the first error. A failed run doesn't stop the others unless
+// failFast is set; an interrupted one stops them all. The runs not started
+// are marked skipped.
+func runEntries(cmd *cobra.Command, runs []matrixRun, parallel int, failFast bool, run func(*matrixRun) error) error {
+	// Runs at once share the command's output
+	if parallel > 1 {
+		var outMu sync.Mutex
+		cmd.SetOut(&lockedWriter{mu: &outMu, w: cmd.OutOrStdout()})
+		cmd.SetErr(&lockedWriter{mu: &outMu, w: cmd.ErrOrStderr()})
+	}
+
+	var stop atomic.Bool
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallel)
+	for i := range runs {
+		// Runs start once an earlier one has finished and reported
+		slots <- struct{}{}
+		if stop.Load() {
+			<-slots
+			runs[i].skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *matrixRun) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			started := time.Now()
+			r.err = run(r)
+			r.took = time.Since(started)
+			if r.err == nil {
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			mu.Unlock()
+			if failFast || errors.Is(r.err, errInterrupted) {
+				stop.Store(true)
+			}
+		}(&runs[i])
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// interrupted returns the error of the first of runs that was interrupted,
+// or nil.
+func interrupted(runs []matrixRun) error {
+	for _, run := range runs {
+		if errors.Is(run.err, errInterrupted) {
+			return run.err
+		}
+	}
+	return nil
+}
+
+// lockedWriter serializes writes to w.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// printMatrix writes how each --matrix entry went and returns the number
+// that failed.
+func printMatrix(w io.Writer, runs []matrixRun) int {
+	var ok, failed int
+	for _, run := range runs {
+		switch {
+		case run.skipped:
+			fmt.Fprintf(w, "%-8s  %s\n", "skipped", run.dir)
+		case run.err != nil:
+			failed++
+			fmt.Fprintf(w, "%-8s  %s: %v\n", "failed", run.dir, run.err)
+		default:
+			ok++
+			fmt.Fprintf(w, "%-8s  %s\n", "ok", run.dir)
+		}
+	}
+	fmt.Fprintf(w, "Matrix: %d of %d entries succeeded\n", ok, len(runs))
+	return failed
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCommand_Matrix(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "frames")
+	writeMatrix := func(t *testing.T, text string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "matrix.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+		return path
+	}
+	languages := writeMatrix(t, "- out: en\n  params:\n    GREETING: Hello\n- out: de\n  params:\n    GREETING: Hallo\n")
+	oneBad := writeMatrix(t, "- out: en\n  params:\n    NAME: x\n- out: de\n  params:\n    GREETING: Hallo\n")
+	tape := "Param GREETING default 'Hi'\nType '${GREETING}' Enter"
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		wantErr  string
+		wantCode int
+	}{
+		{
+			name: "runs each entry with its params",
+			args: []string{"--matrix", languages, "--dry-run", "-o", out, "bash", tape},
+			want: []string{
+				"  1. Type 'Hello'\n", "  1. Type 'Hallo'\n",
+				"ok        " + filepath.Join(out, "en") + "\n",
+				"ok        " + filepath.Join(out, "de") + "\n",
+				"Matrix: 2 of 2 entries succeeded\n",
+			},
+		},
+		{
+			name: "entry params replace --param",
+			args: []string{"--matrix", languages, "--dry-run", "--param", "GREETING=Hey", "bash", tape},
+			want: []string{"  1. Type 'Hello'\n", "  1. Type 'Hallo'\n"},
+		},
+		{
+			name:     "a failed entry doesn't stop the others",
+			args:     []string{"--matrix", oneBad, "--dry-run", "-o", out, "bash", tape},
+			want:     []string{"failed    " + filepath.Join(out, "en") + ": ", "  1. Type 'Hallo'\n", "Matrix: 1 of 2 entries succeeded\n"},
+			wantErr:  "1 of 2 matrix entries failed",
+			wantCode: exitFailure,
+		},
+		{
+			name:     "--fail-fast skips the rest",
+			args:     []string{"--matrix", oneBad, "--fail-fast", "--dry-run", "-o", out, "bash", tape},
+			want:     []string{"skipped   " + filepath.Join(out, "de") + "\n", "Matrix: 0 of 2 entries succeeded\n"},
+			wantErr:  `unknown param "NAME"`,
+			wantCode: exitUsage,
+		},
+		{
+			name:     "missing matrix file",
+			args:     []string{"--matrix", filepath.Join(dir, "missing.yaml"), "bash"},
+			wantErr:  "read --matrix",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "parallel below 1",
+			args:     []string{"--matrix", languages, "--parallel", "0", "bash"},
+			wantErr:  "--parallel must be at least 1, got 0",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "parallel with a fixed port",
+			args:     []string{"--matrix", languages, "--parallel", "2", "-p", "9000", "bash"},
+			wantErr:  "cannot use -p/--port with --parallel",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "parallel without matrix",
+			args:     []string{"--parallel", "2", "bash"},
+			wantErr:  "--parallel and --fail-fast need --matrix",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "json",
+			args:     []string{"--matrix", languages, "--json", "bash"},
+			wantErr:  "cannot use --json with --matrix",
+			wantCode: exitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(&stdout)
+			cmd.SetErr(bytes.NewBuffer(nil))
+
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Equal(t, tt.wantCode, exitCode(err))
+			} else {
+				require.NoError(t, err)
+			}
+			for _, want := range tt.want {
+				assert.Contains(t, stdout.String(), want)
+			}
+		})
+	}
+}
+
+func TestRootCommand_MatrixParallel(t *testing.T) {
+	matrix := filepath.Join(t.TempDir(), "matrix.yaml")
+	require.NoError(t, os.WriteFile(matrix, []byte("- out: a\n- out: b\n- out: c\n"), 0o644))
+
+	var stdout bytes.Buffer
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--matrix", matrix, "--parallel", "2", "--storyboard", "bash", "Enter"})
+	cmd.SetOut(&stdout)
+	cmd.SetErr(bytes.NewBuffer(nil))
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, 3, bytes.Count(stdout.Bytes(), []byte("# Storyboard: `bash`")))
+	assert.Contains(t, stdout.String(), "Matrix: 3 of 3 entries succeeded\n")
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// nopWriteCloser leaves the wrapped stdout or stderr open on Close.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// openProgress returns the destination of --progress-fd or --progress-file,
+// or nil when neither is set. The caller closes it after the run.
+func openProgress(cmd *cobra.Command) (io.WriteCloser, error) {
+	fd, err := cmd.Flags().GetInt("progress-fd")
+	if err != nil {
+		return nil, fmt.Errorf("get progress-fd flag: %w", err)
+	}
+	path, err := cmd.Flags().GetString("progress-file")
+	if err != nil {
+		return nil, fmt.Errorf("get progress-file flag: %w", err)
+	}
+
+	switch {
+	case isSet(cmd.Flags(), "progress-fd") && path != "":
+		return nil, fmt.Errorf("cannot use both --progress-fd and --progress-file")
+	case path != "":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("open progress file: %w", err)
+		}
+		return f, nil
+	case !isSet(cmd.Flags(), "progress-fd"):
+		return nil, nil
+	case fd == 1:
+		return nopWriteCloser{os.Stdout}, nil
+	case fd == 2:
+		return nopWriteCloser{os.Stderr}, nil
+	case fd < 3:
+		return nil, fmt.Errorf("--progress-fd must be 1, 2 or an inherited descriptor >= 3, got %d", fd)
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("--progress-fd %d is not an open file descriptor", fd)
+	}
+	return f, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenProgress(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantNil bool
+		wantErr string
+	}{
+		{name: "off", args: nil, wantNil: true},
+		{name: "file", args: []string{"--progress-file", file}},
+		{name: "stdout", args: []string{"--progress-fd", "1"}},
+		{name: "stdout from SCR_PROGRESS_FD", env: map[string]string{"SCR_PROGRESS_FD": "1"}},
+		{name: "SCR_PROGRESS_FD and a file", env: map[string]string{"SCR_PROGRESS_FD": "1"}, args: []string{"--progress-file", file}, wantErr: "cannot use both --progress-fd and --progress-file"},
+		{name: "stdin", args: []string{"--progress-fd", "0"}, wantErr: "--progress-fd must be 1, 2 or an inherited descriptor >= 3, got 0"},
+		{name: "closed descriptor", args: []string{"--progress-fd", "987"}, wantErr: "--progress-fd 987 is not an open file descriptor"},
+		{name: "both", args: []string{"--progress-fd", "3", "--progress-file", file}, wantErr: "cannot use both --progress-fd and --progress-file"},
+		{name: "unwritable file", args: []string{"--progress-file", filepath.Join(file, "missing", "x")}, wantErr: "open progress file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cmd := NewRootCommand()
+			require.NoError(t, cmd.ParseFlags(tt.args))
+			require.NoError(t, applyEnv(cmd.Flags()))
+
+			w, err := openProgress(cmd)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, w)
+				return
+			}
+			require.NotNil(t, w)
+			require.NoError(t, w.Close())
+		})
+	}
+
+	// Closing stdout's wrapper leaves stdout usable
+	_, err := os.Stdout.Stat()
+	assert.NoError(t, err)
+}
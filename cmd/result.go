@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/yarlson/scr/internal/capture"
+	"github.com/yarlson/scr/internal/config"
+)
+
+// Result statuses.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// runResult is the JSON object --json prints on stdout at the end of a run,
+// in place of the messages printed otherwise. Fields the run did not get to
+// are left empty.
+type runResult struct {
+	Status  string `json:"status"`
+	Command string `json:"command,omitempty"`
+	// URL is the terminal page when attaching to an existing ttyd.
+	URL       string `json:"url,omitempty"`
+	Actions   int    `json:"actions"`
+	OutputDir string `json:"outputDir,omitempty"`
+	// Screenshots are the frame files written, and Frames counts the frames
+	// captured, including those that went into Artifact.
+	Screenshots []string     `json:"screenshots"`
+	Artifact    string       `json:"artifact,omitempty"`
+	Video       string       `json:"video,omitempty"`
+	Frames      int          `json:"frames"`
+	DurationMS  int64        `json:"durationMs"`
+	Error       *resultError `json:"error,omitempty"`
+}
+
+// resultError describes why a run failed.
+type resultError struct {
+	Message string `json:"message"`
+	// ExitCode is the exit status scr fails with, and CommandExitCode the
+	// non-zero code of the captured command when that was the failure.
+	ExitCode        int `json:"exitCode"`
+	CommandExitCode int `json:"commandExitCode,omitempty"`
+}
+
+// describe records what the run was asked to do.
+func (r *runResult) describe(cfg *config.Config) {
+	if cfg.TerminalURL != "" {
+		r.URL = cfg.TerminalURL
+	} else {
+		r.Command = cfg.CommandLine()
+	}
+	r.Actions = len(cfg.Actions)
+	r.OutputDir = cfg.OutputDir
+}
+
+// collect records what the capture produced.
+func (r *runResult) collect(capturer *capture.Capturer) {
+	res := capturer.Result()
+	r.Screenshots = res.Screenshots
+	r.Artifact = res.Artifact
+	r.Video = res.Video
+	r.Frames = res.Frames
+	r.DurationMS = res.Duration.Milliseconds()
+}
+
+// writeResult prints result on stdout, as a success when err is nil. A
+// failure is also printed on stderr, as an object with just its error.
+func writeResult(stdout, stderr io.Writer, result *runResult, err error) error {
+	result.Status = resultSuccess
+	if result.Screenshots == nil {
+		result.Screenshots = []string{}
+	}
+	if err != nil {
+		result.Status = resultFailure
+		result.Error = &resultError{Message: err.Error(), ExitCode: exitCode(err)}
+		var exitErr *capture.ExitError
+		if errors.As(err, &exitErr) {
+			result.Error.CommandExitCode = exitErr.Code
+		}
+		if encErr := json.NewEncoder(stderr).Encode(struct {
+			Error *resultError `json:"error"`
+		}{result.Error}); encErr != nil {
+			return encErr
+		}
+	}
+	return json.NewEncoder(stdout).Encode(result)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+func TestWriteResult(t *testing.T) {
+	run := func() *runResult {
+		return &runResult{
+			Command:     "bash",
+			Actions:     2,
+			OutputDir:   "shots",
+			Screenshots: []string{"shots/screenshot_001.png", "shots/screenshot_002.png"},
+			Frames:      2,
+			DurationMS:  1500,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		result     *runResult
+		err        error
+		wantStdout map[string]any
+		wantStderr map[string]any
+	}{
+		{
+			name:   "success",
+			result: run(),
+			wantStdout: map[string]any{
+				"status":      "success",
+				"command":     "bash",
+				"actions":     float64(2),
+				"outputDir":   "shots",
+				"screenshots": []any{"shots/screenshot_001.png", "shots/screenshot_002.png"},
+				"frames":      float64(2),
+				"durationMs":  float64(1500),
+			},
+		},
+		{
+			name:   "failure before the capture",
+			result: &runResult{},
+			err:    errors.New("validate config: port must be between 1 and 65535"),
+			wantStdout: map[string]any{
+				"status":      "failure",
+				"actions":     float64(0),
+				"screenshots": []any{},
+				"frames":      float64(0),
+				"durationMs":  float64(0),
+				"error":       map[string]any{"message": "validate config: port must be between 1 and 65535", "exitCode": float64(1)},
+			},
+			wantStderr: map[string]any{
+				"error": map[string]any{"message": "validate config: port must be between 1 and 65535", "exitCode": float64(1)},
+			},
+		},
+		{
+			name:   "command exited non-zero",
+			result: run(),
+			err:    fmt.Errorf("capture execution: %w", &capture.ExitError{Code: 2}),
+			wantStdout: map[string]any{
+				"status":      "failure",
+				"command":     "bash",
+				"actions":     float64(2),
+				"outputDir":   "shots",
+				"screenshots": []any{"shots/screenshot_001.png", "shots/screenshot_002.png"},
+				"frames":      float64(2),
+				"durationMs":  float64(1500),
+				"error":       map[string]any{"message": "capture execution: command exited with code 2", "exitCode": float64(6), "commandExitCode": float64(2)},
+			},
+			wantStderr: map[string]any{
+				"error": map[string]any{"message": "capture execution: command exited with code 2", "exitCode": float64(6), "commandExitCode": float64(2)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			require.NoError(t, writeResult(&stdout, &stderr, tt.result, tt.err))
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &got))
+			assert.Equal(t, tt.wantStdout, got)
+
+			if tt.wantStderr == nil {
+				assert.Empty(t, stderr.String())
+				return
+			}
+			var gotErr map[string]any
+			require.NoError(t, json.Unmarshal(stderr.Bytes(), &gotErr))
+			assert.Equal(t, tt.wantStderr, gotErr)
+		})
+	}
+}
+
+func TestRootCommand_JSON_Failure(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantMessage string
+	}{
+		{
+			name:        "script does not parse",
+			args:        []string{"--json", "bash", "Type"},
+			wantMessage: "Type",
+		},
+		{
+			name:        "dry run",
+			args:        []string{"--json", "--dry-run", "bash", "Enter"},
+			wantMessage: "cannot use --json with --dry-run or --storyboard",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+
+			err := cmd.Execute()
+			require.Error(t, err)
+
+			var got runResult
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &got), "stdout is one JSON object: %s", stdout.String())
+			assert.Equal(t, "failure", got.Status)
+			require.NotNil(t, got.Error)
+			assert.Contains(t, got.Error.Message, tt.wantMessage)
+			assert.Equal(t, 2, got.Error.ExitCode, "a usage error")
+			assert.NotNil(t, got.Screenshots)
+
+			// stderr holds the error as JSON, and nothing from cobra
+			var gotErr struct {
+				Error resultError `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(stderr.Bytes(), &gotErr), "stderr is one JSON object: %s", stderr.String())
+			assert.Equal(t, *got.Error, gotErr.Error)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/update"
+)
+
+// selfUpdateTimeout bounds the release lookup and download.
+const selfUpdateTimeout = 5 * time.Minute
+
+// newSelfUpdateCommand creates the `scr self-update` command.
+func newSelfUpdateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Replace scr with the latest release from GitHub",
+		Long: `Look up the latest scr release on GitHub and, when it is newer than this
+one, download the archive for this OS and architecture, check it against the
+release's checksums.txt and replace the scr executable with it. The previous
+executable is kept beside the new one with an .old suffix.
+
+scr never checks for updates on its own; only this command does. With
+--check, it only reports whether a newer release exists. Requests go through
+the proxy in HTTPS_PROXY, HTTP_PROXY and NO_PROXY, and GITHUB_TOKEN, when
+set, raises GitHub's rate limit.`,
+		Args: cobra.NoArgs,
+		RunE: runSelfUpdate,
+	}
+
+	cmd.Flags().Bool("check", false, "Only report whether a newer release exists")
+
+	return cmd
+}
+
+// runSelfUpdate checks for a newer release and installs it.
+func runSelfUpdate(cmd *cobra.Command, _ []string) error {
+	check, err := cmd.Flags().GetBool("check")
+	if err != nil {
+		return fmt.Errorf("get check flag: %w", err)
+	}
+
+	updater := &update.Updater{
+		Token:  os.Getenv("GITHUB_TOKEN"),
+		GOOS:   runtime.GOOS,
+		GOARCH: runtime.GOARCH,
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), selfUpdateTimeout)
+	defer cancel()
+	return selfUpdate(ctx, cmd, updater, check)
+}
+
+// selfUpdate reports on or installs the latest release found by updater.
+func selfUpdate(ctx context.Context, cmd *cobra.Command, updater *update.Updater, check bool) error {
+	out := cmd.OutOrStdout()
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	newer, err := update.Newer(Version, release.Version)
+	if err != nil {
+		return err
+	}
+	if !newer {
+		fmt.Fprintf(out, "scr %s is up to date (latest release: %s)\n", Version, release.Version)
+		return nil
+	}
+	if check {
+		fmt.Fprintf(out, "scr %s is available (current: %s); run 'scr self-update' to install it\n", release.Version, Version)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+
+	data, err := updater.Download(ctx, release)
+	if err != nil {
+		return err
+	}
+	backup, err := update.Replace(exe, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Updated %s from %s to %s; the previous version is at %s\n", exe, Version, release.Version, backup)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/update"
+)
+
+func TestSelfUpdate_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"tag_name": "v1.4.0", "assets": []}`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "newer release", version: "1.3.2", want: "scr 1.4.0 is available (current: 1.3.2); run 'scr self-update' to install it\n"},
+		{name: "up to date", version: "1.4.0", want: "scr 1.4.0 is up to date (latest release: 1.4.0)\n"},
+		{name: "dev build", version: "dev", want: "scr 1.4.0 is available (current: dev)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := Version
+			Version = tt.version
+			defer func() { Version = old }()
+
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+			updater := &update.Updater{APIURL: srv.URL, GOOS: "linux", GOARCH: "amd64"}
+
+			require.NoError(t, selfUpdate(context.Background(), cmd, updater, true))
+			assert.Contains(t, out.String(), tt.want)
+		})
+	}
+}
+
+func TestSelfUpdateCommand_NoArgs(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"self-update", "now"})
+	cmd.SetOut(bytes.NewBuffer(nil))
+	cmd.SetErr(bytes.NewBuffer(nil))
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown command "now"`)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/theme"
+)
+
+// newThemesCommand creates the `scr themes` command, which lists the
+// built-in themes and optionally renders a preview of each.
+func newThemesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "themes [THEME|FILE...]",
+		Short: "List built-in themes, or validate and preview themes",
+		Long: `List the built-in terminal themes for --theme and Set Theme.
+
+With arguments, only the given themes are shown; a FILE is loaded as a
+custom JSON theme and validated. With --preview DIR, a color-test PNG of
+each theme is written to DIR.`,
+		RunE: runThemes,
+	}
+
+	cmd.Flags().String("preview", "", "Write a preview PNG of each theme to this directory")
+
+	return cmd
+}
+
+// runThemes lists themes and writes previews when requested.
+func runThemes(cmd *cobra.Command, args []string) error {
+	previewDir, err := cmd.Flags().GetString("preview")
+	if err != nil {
+		return fmt.Errorf("get preview flag: %w", err)
+	}
+
+	refs := args
+	if len(refs) == 0 {
+		refs = theme.Names()
+	}
+
+	if previewDir != "" {
+		if err := os.MkdirAll(previewDir, 0o755); err != nil {
+			return fmt.Errorf("preview directory: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	for _, ref := range refs {
+		t, err := theme.Resolve(ref)
+		if err != nil {
+			return err
+		}
+
+		line := fmt.Sprintf("%-16s background %s  foreground %s", t.Name, t.Background, t.Foreground)
+		if previewDir != "" {
+			path := filepath.Join(previewDir, t.Name+".png")
+			if err := writePreview(path, t); err != nil {
+				return err
+			}
+			line += "  " + path
+		}
+		fmt.Fprintln(out, line)
+	}
+
+	return nil
+}
+
+// writePreview renders t's preview to a PNG file.
+func writePreview(path string, t theme.Theme) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create preview: %w", err)
+	}
+	if err := png.Encode(file, t.Preview()); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("encode preview: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write preview: %w", err)
+	}
+	return nil
+}
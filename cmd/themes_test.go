@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/theme"
+)
+
+func TestThemesCommand(t *testing.T) {
+	t.Run("lists built-in themes", func(t *testing.T) {
+		var out bytes.Buffer
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"themes"})
+		cmd.SetOut(&out)
+
+		require.NoError(t, cmd.Execute())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, len(theme.Names()))
+		assert.True(t, strings.HasPrefix(lines[0], "dracula "))
+		assert.Contains(t, lines[0], "background #282a36")
+	})
+
+	t.Run("writes previews", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "previews")
+		var out bytes.Buffer
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"themes", "--preview", dir, "nord"})
+		cmd.SetOut(&out)
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, out.String(), filepath.Join(dir, "nord.png"))
+		info, err := os.Stat(filepath.Join(dir, "nord.png"))
+		require.NoError(t, err)
+		assert.Positive(t, info.Size())
+	})
+
+	t.Run("validates a custom theme file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "broken.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"background": "#000", "foreground": "white"}`), 0o644))
+
+		cmd := NewRootCommand()
+		cmd.SetArgs([]string{"themes", file})
+		cmd.SetOut(bytes.NewBuffer(nil))
+		cmd.SetErr(bytes.NewBuffer(nil))
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid theme")
+	})
+}
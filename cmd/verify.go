@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+// newVerifyCommand creates the `scr verify` command, which checks the files
+// of a run against the SHA256SUMS written with them.
+func newVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify DIR",
+		Short: "Check that the files of a run are unchanged since it wrote them",
+		Long: `Recompute the SHA-256 of every file listed in DIR/SHA256SUMS, which a
+successful run writes into its output directory, and compare it with the
+one recorded there. Each file prints ok, modified or missing; the command
+fails when any file is modified or missing. Files that are not listed, such
+as those of other runs, are not checked.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runVerify,
+	}
+}
+
+// runVerify checks the directory and fails when any file did not match.
+func runVerify(cmd *cobra.Command, args []string) error {
+	results, err := capture.VerifyChecksums(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Mismatches are reported by printChecksums, not by usage
+	cmd.SilenceUsage = true
+	if failed := printChecksums(cmd.OutOrStdout(), results); failed > 0 {
+		return fmt.Errorf("%d of %d files modified or missing", failed, len(results))
+	}
+	return nil
+}
+
+// printChecksums prints a line for each checked file and returns how many
+// did not match.
+func printChecksums(w io.Writer, results []capture.ChecksumResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status != capture.ChecksumOK {
+			failed++
+		}
+		fmt.Fprintf(w, "%-8s  %s\n", result.Status, result.File)
+	}
+	return failed
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+func TestVerifyCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(dir string)
+		want    string
+		wantErr string
+	}{
+		{
+			name: "unchanged",
+			want: "ok        manifest.json\nok        release 01.png\n",
+		},
+		{
+			name:    "missing",
+			change:  func(dir string) { _ = os.Remove(filepath.Join(dir, "manifest.json")) },
+			want:    "missing   manifest.json\nok        release 01.png\n",
+			wantErr: "1 of 2 files modified or missing",
+		},
+		{
+			name:    "modified",
+			change:  func(dir string) { _ = os.WriteFile(filepath.Join(dir, "release 01.png"), []byte("edited"), 0o644) },
+			want:    "ok        manifest.json\nmodified  release 01.png\n",
+			wantErr: "1 of 2 files modified or missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var sums bytes.Buffer
+			for _, name := range []string{"manifest.json", "release 01.png"} {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+				sum := sha256.Sum256([]byte(name))
+				fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+			}
+			require.NoError(t, os.WriteFile(filepath.Join(dir, capture.ChecksumsFilename), sums.Bytes(), 0o644))
+			if tt.change != nil {
+				tt.change(dir)
+			}
+
+			var out bytes.Buffer
+			cmd := NewRootCommand()
+			cmd.SetOut(&out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs([]string{"verify", dir})
+
+			err := cmd.Execute()
+			assert.Equal(t, tt.want, out.String())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyCommand_NoChecksums(t *testing.T) {
+	cmd := NewRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"verify", t.TempDir()})
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read checksums")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+// Version is the scr release, set at build time with -ldflags "-X main.Version=...".
+var Version = "dev"
+
+// versionProbeTimeout bounds how long `scr version --verbose` waits for
+// the browser.
+const versionProbeTimeout = 30 * time.Second
+
+// newVersionCommand creates the `scr version` command.
+func newVersionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the scr version, and with --verbose the ttyd and browser versions",
+		Long: `Print the scr version.
+
+With --verbose, also report the installed ttyd version and start the browser
+headless to report its version, user agent and default viewport: the same
+details every capture records in manifest.json.`,
+		Args: cobra.NoArgs,
+		RunE: runVersion,
+	}
+
+	cmd.Flags().BoolP("verbose", "v", false, "Also report the ttyd and browser versions")
+
+	return cmd
+}
+
+// runVersion prints the version and, when verbose, the probed environment.
+func runVersion(cmd *cobra.Command, _ []string) error {
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return fmt.Errorf("get verbose flag: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "scr %s\n", Version)
+	if !verbose {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+	env, probeErr := capture.ProbeEnvironment(ctx)
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode environment: %w", err)
+	}
+	fmt.Fprintf(out, "%s\n", data)
+	return probeErr
+}
@@ -2,310 +2,506 @@ package capture
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/yarlson/scr/internal/config"
 	"github.com/yarlson/scr/internal/script"
 )
 
-func TestCapturer_ExecuteActions_TypeAction(t *testing.T) {
+// recordKeys replaces c.sendKey and c.insertText with fakes that record
+// what was sent, in order, returning failErr for failKey.
+func recordKeys(c *Capturer, failKey string, failErr error) func() []string {
+	var mu sync.Mutex
+	var keys []string
+	c.sendKey = func(_ context.Context, key string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, key)
+		if key == failKey {
+			return failErr
+		}
+		return nil
+	}
+	c.insertText = func(_ context.Context, text string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, "insert:"+text)
+		return nil
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), keys...)
+	}
+}
+
+func TestCapturer_executeActions(t *testing.T) {
 	tests := []struct {
-		name    string
-		actions []script.Action
-		wantErr bool
-		errMsg  string
+		name     string
+		actions  []script.Action
+		wantKeys []string
+		// minDuration is the least time the actions take, from sleeps,
+		// typing speed and post-action delays.
+		minDuration time.Duration
 	}{
 		{
-			name: "type action with per-char speed",
+			name:        "type presses each character at its speed",
+			actions:     []script.Action{{Kind: script.ActionType, Text: "hi", Speed: 40 * time.Millisecond}},
+			wantKeys:    []string{"h", "i"},
+			minDuration: 80 * time.Millisecond,
+		},
+		{
+			name: "consecutive type actions",
+			actions: []script.Action{
+				{Kind: script.ActionType, Text: "a", Speed: 10 * time.Millisecond},
+				{Kind: script.ActionType, Text: "b", Speed: 10 * time.Millisecond},
+			},
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:     "type delay",
+			actions:  []script.Action{{Kind: script.ActionType, Text: "x", Delay: 50 * time.Millisecond}},
+			wantKeys: []string{"x"},
+			// Speed 0 types at once; only the delay counts
+			minDuration: 50 * time.Millisecond,
+		},
+		{
+			name:        "key repeat and delay",
+			actions:     []script.Action{{Kind: script.ActionKey, Key: "enter", Repeat: 3, Delay: 30 * time.Millisecond}},
+			wantKeys:    []string{"enter", "enter", "enter"},
+			minDuration: 30 * time.Millisecond,
+		},
+		{
+			name:     "key repeat defaults to once",
+			actions:  []script.Action{{Kind: script.ActionKey, Key: "tab"}},
+			wantKeys: []string{"tab"},
+		},
+		{
+			name:     "key with modifiers",
+			actions:  []script.Action{{Kind: script.ActionKey, Key: "Tab", Modifiers: script.ModShift, Repeat: 1}},
+			wantKeys: []string{"Shift+Tab"},
+		},
+		{
+			name: "ctrl keys",
 			actions: []script.Action{
-				{
-					Kind:  script.ActionType,
-					Text:  "hi",
-					Speed: 10 * time.Millisecond,
-				},
+				{Kind: script.ActionCtrl, Key: "c"},
+				{Kind: script.ActionCtrl, Key: "d"},
 			},
-			wantErr: false,
+			wantKeys: []string{"ctrl+c", "ctrl+d"},
+		},
+		{
+			name:        "sleep sends nothing",
+			actions:     []script.Action{{Kind: script.ActionSleep, Duration: 40 * time.Millisecond}},
+			minDuration: 40 * time.Millisecond,
 		},
 		{
-			name: "multiple type actions",
+			name: "mixed sequence in order",
 			actions: []script.Action{
-				{
-					Kind:  script.ActionType,
-					Text:  "a",
-					Speed: 10 * time.Millisecond,
-				},
-				{
-					Kind:  script.ActionType,
-					Text:  "b",
-					Speed: 10 * time.Millisecond,
-				},
+				{Kind: script.ActionType, Text: "echo hi", Speed: 0},
+				{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+				{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+				{Kind: script.ActionCtrl, Key: "c"},
 			},
-			wantErr: false,
+			wantKeys:    []string{"insert:echo hi", "enter", "ctrl+c"},
+			minDuration: 20 * time.Millisecond,
+		},
+		{
+			name:    "no actions",
+			actions: nil,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &config.Config{
-				Command:            "echo test",
-				TTydPort:           8080,
-				OutputDir:          t.TempDir(),
-				ScreenshotInterval: 500 * time.Millisecond,
-				Timeout:            30 * time.Second,
-				Actions:            tt.actions,
-				Script:             "test script",
+			c := newFakeCapturer(t, &config.Config{Actions: tt.actions})
+			keys := recordKeys(c, "", nil)
+
+			ctx := context.Background()
+			start := time.Now()
+			require.NoError(t, c.executeActions(ctx, ctx))
+			assert.GreaterOrEqual(t, time.Since(start), tt.minDuration)
+			assert.Equal(t, tt.wantKeys, keys())
+
+			stats := c.Stats()
+			assert.Len(t, stats.Actions, len(tt.actions), "every action is timed")
+		})
+	}
+}
+
+func TestPlayer_key_EscapeDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		action script.Action
+		delay  time.Duration
+		// wantGap is whether each key waits the delay before the next.
+		wantGap bool
+	}{
+		{name: "escape pauses", action: script.Action{Kind: script.ActionKey, Key: "Escape", Repeat: 2}, delay: 60 * time.Millisecond, wantGap: true},
+		{name: "any case", action: script.Action{Kind: script.ActionKey, Key: "escape", Repeat: 2}, delay: 60 * time.Millisecond, wantGap: true},
+		{name: "zero delay", action: script.Action{Kind: script.ActionKey, Key: "Escape", Repeat: 2}},
+		{name: "other keys do not pause", action: script.Action{Kind: script.ActionKey, Key: "Enter", Repeat: 2}, delay: 60 * time.Millisecond},
+		{name: "modified escape does not pause", action: script.Action{Kind: script.ActionKey, Key: "Escape", Modifiers: script.ModAlt, Repeat: 2}, delay: 60 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{EscapeDelay: tt.delay})
+			var sent []time.Time
+			c.sendKey = func(context.Context, string) error {
+				sent = append(sent, time.Now())
+				return nil
 			}
 
-			capturer := NewCapturer(cfg)
-			assert.NotNil(t, capturer)
-			assert.Equal(t, tt.actions, capturer.config.Actions)
-			assert.Equal(t, "test script", capturer.config.Script)
+			start := time.Now()
+			require.NoError(t, c.player(context.Background()).do(context.Background(), tt.action, 0))
+			require.Len(t, sent, 2)
+			if tt.wantGap {
+				assert.GreaterOrEqual(t, sent[1].Sub(sent[0]), tt.delay)
+				assert.GreaterOrEqual(t, time.Since(start), 2*tt.delay, "the last Escape settles too")
+			} else {
+				assert.Less(t, time.Since(start), 50*time.Millisecond)
+			}
 		})
 	}
 }
 
-func TestCapturer_ExecuteActions_SleepAction(t *testing.T) {
+func TestCapturer_executeActions_Errors(t *testing.T) {
+	sendErr := errors.New("browser went away")
+
 	tests := []struct {
-		name    string
-		actions []script.Action
-		wantErr bool
+		name     string
+		actions  []script.Action
+		failKey  string
+		errMsg   string
+		wantKeys []string
 	}{
 		{
-			name: "sleep action with context",
+			name: "key failure stops the repeat and the script",
 			actions: []script.Action{
-				{
-					Kind:     script.ActionSleep,
-					Duration: 10 * time.Millisecond,
-				},
+				{Kind: script.ActionKey, Key: "down", Repeat: 3},
+				{Kind: script.ActionKey, Key: "enter", Repeat: 1},
 			},
-			wantErr: false,
+			failKey:  "down",
+			errMsg:   `send key "down" (repeat 1): browser went away`,
+			wantKeys: []string{"down"},
 		},
 		{
-			name: "sleep action respects context cancellation",
+			name: "ctrl failure",
 			actions: []script.Action{
-				{
-					Kind:     script.ActionSleep,
-					Duration: 1 * time.Second,
-				},
+				{Kind: script.ActionType, Text: "a", Speed: 0},
+				{Kind: script.ActionCtrl, Key: "c"},
+				{Kind: script.ActionType, Text: "b", Speed: 0},
 			},
-			wantErr: false,
+			failKey:  "ctrl+c",
+			errMsg:   "send Ctrl+c: browser went away",
+			wantKeys: []string{"a", "ctrl+c"},
+		},
+		{
+			name:     "type failure",
+			actions:  []script.Action{{Kind: script.ActionType, Text: "ab", Speed: script.DefaultTypeSpeed}},
+			failKey:  "a",
+			errMsg:   "send character 'a': browser went away",
+			wantKeys: []string{"a"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &config.Config{
-				Command:            "echo test",
-				TTydPort:           8080,
-				OutputDir:          t.TempDir(),
-				ScreenshotInterval: 500 * time.Millisecond,
-				Timeout:            30 * time.Second,
-				Actions:            tt.actions,
-				Script:             "test script",
-			}
+			c := newFakeCapturer(t, &config.Config{Actions: tt.actions})
+			keys := recordKeys(c, tt.failKey, sendErr)
 
-			capturer := NewCapturer(cfg)
-			assert.NotNil(t, capturer)
+			ctx := context.Background()
+			err := c.executeActions(ctx, ctx)
+			require.ErrorIs(t, err, sendErr)
+			assert.EqualError(t, err, tt.errMsg)
+			assert.Equal(t, tt.wantKeys, keys())
 		})
 	}
 }
 
-func TestCapturer_ExecuteActions_KeyAction(t *testing.T) {
+func TestCapturer_executeActions_ContextCancellation(t *testing.T) {
 	tests := []struct {
 		name    string
 		actions []script.Action
-		wantErr bool
 	}{
-		{
-			name: "key action with repeat",
-			actions: []script.Action{
-				{
-					Kind:   script.ActionKey,
-					Key:    "enter",
-					Repeat: 3,
-					Delay:  10 * time.Millisecond,
-				},
-			},
-			wantErr: false,
-		},
-		{
-			name: "key action without delay",
-			actions: []script.Action{
-				{
-					Kind:   script.ActionKey,
-					Key:    "tab",
-					Repeat: 1,
-					Delay:  0,
-				},
-			},
-			wantErr: false,
-		},
+		{name: "sleep", actions: []script.Action{{Kind: script.ActionSleep, Duration: 5 * time.Second}}},
+		{name: "type delay", actions: []script.Action{{Kind: script.ActionType, Text: "x", Delay: 5 * time.Second}}},
+		{name: "typing speed", actions: []script.Action{{Kind: script.ActionType, Text: "xyz", Speed: 5 * time.Second}}},
+		{name: "key delay", actions: []script.Action{{Kind: script.ActionKey, Key: "enter", Repeat: 1, Delay: 5 * time.Second}}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &config.Config{
-				Command:            "echo test",
-				TTydPort:           8080,
-				OutputDir:          t.TempDir(),
-				ScreenshotInterval: 500 * time.Millisecond,
-				Timeout:            30 * time.Second,
-				Actions:            tt.actions,
-				Script:             "test script",
-			}
+			c := newFakeCapturer(t, &config.Config{
+				Actions: append(tt.actions, script.Action{Kind: script.ActionKey, Key: "q", Repeat: 1}),
+			})
+			keys := recordKeys(c, "", nil)
 
-			capturer := NewCapturer(cfg)
-			assert.NotNil(t, capturer)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := c.executeActions(ctx, context.Background())
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), time.Second, "cancellation interrupts the wait")
+			assert.NotContains(t, keys(), "q", "later actions do not run")
 		})
 	}
 }
 
-func TestCapturer_ExecuteActions_CtrlAction(t *testing.T) {
+func TestCapturer_executeActions_Keypresses(t *testing.T) {
+	// Without Actions, the legacy keypresses are sent with Delays between
+	c := newFakeCapturer(t, &config.Config{
+		Keypresses: []string{"a", "enter", "q"},
+		Delays:     []time.Duration{20 * time.Millisecond, 20 * time.Millisecond},
+	})
+	keys := recordKeys(c, "", nil)
+
+	ctx := context.Background()
+	start := time.Now()
+	require.NoError(t, c.executeActions(ctx, ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, []string{"a", "enter", "q"}, keys())
+	assert.Len(t, c.Stats().Actions, 3)
+}
+
+func TestScreenshotName(t *testing.T) {
 	tests := []struct {
 		name    string
-		actions []script.Action
-		wantErr bool
+		label   string
+		format  string
+		want    string
+		wantErr string
 	}{
-		{
-			name: "ctrl+c action",
-			actions: []script.Action{
-				{
-					Kind: script.ActionCtrl,
-					Key:  "c",
-				},
-			},
-			wantErr: false,
-		},
-		{
-			name: "ctrl+d action",
-			actions: []script.Action{
-				{
-					Kind: script.ActionCtrl,
-					Key:  "d",
-				},
-			},
-			wantErr: false,
-		},
+		{name: "simple label", label: "after-login", want: "after-login.png"},
+		{name: "jpeg extension", label: "after-login", format: "jpeg", want: "after-login.jpg"},
+		{name: "keeps jpeg extension", label: "menu.JPEG", format: "jpeg", want: "menu.JPEG"},
+		{name: "replaces png extension", label: "menu.png", format: "webp", want: "menu.webp"},
+		{name: "keeps png extension", label: "menu.png", want: "menu.png"},
+		{name: "replaces unsafe characters", label: "step 1/2: done?", want: "step_1_2__done_.png"},
+		{name: "path traversal is flattened", label: "../../etc/passwd", want: "_.._etc_passwd.png"},
+		{name: "leading dots dropped", label: ".hidden", want: "hidden.png"},
+		{name: "non-ASCII replaced", label: "héllo", want: "h_llo.png"},
+		{name: "no usable characters", label: "///", wantErr: "has no usable characters"},
+		{name: "collides with sequential names", label: "screenshot_003", wantErr: "collides with sequential screenshot names"},
+		{name: "collides case-insensitively", label: "Screenshot_010.PNG", wantErr: "collides with sequential screenshot names"},
+		{name: "collides in another format", label: "screenshot_003", format: "webp", wantErr: "collides with sequential screenshot names"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &config.Config{
-				Command:            "echo test",
-				TTydPort:           8080,
-				OutputDir:          t.TempDir(),
-				ScreenshotInterval: 500 * time.Millisecond,
-				Timeout:            30 * time.Second,
-				Actions:            tt.actions,
-				Script:             "test script",
+			format := tt.format
+			if format == "" {
+				format = "png"
 			}
-
-			capturer := NewCapturer(cfg)
-			assert.NotNil(t, capturer)
+			got, err := screenshotName(tt.label, format)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestCapturer_ExecuteActions_Mixed(t *testing.T) {
+func TestValidateScreenshotNames(t *testing.T) {
 	tests := []struct {
 		name    string
 		actions []script.Action
-		wantErr bool
+		wantErr string
 	}{
 		{
-			name: "mixed actions sequence",
+			name: "unnamed and distinct names",
+			actions: []script.Action{
+				{Kind: script.ActionScreenshot},
+				{Kind: script.ActionScreenshot},
+				{Kind: script.ActionScreenshot, Name: "a"},
+				{Kind: script.ActionScreenshot, Name: "b"},
+			},
+		},
+		{
+			name: "duplicate after sanitizing",
+			actions: []script.Action{
+				{Kind: script.ActionScreenshot, Name: "step 1"},
+				{Kind: script.ActionScreenshot, Name: "step_1"},
+			},
+			wantErr: `screenshot action 1: name "step_1" collides with "step 1"`,
+		},
+		{
+			name:    "sequential collision",
+			actions: []script.Action{{Kind: script.ActionScreenshot, Name: "screenshot_001"}},
+			wantErr: "screenshot action 0",
+		},
+		{
+			name: "same name in different scenes",
 			actions: []script.Action{
-				{
-					Kind:  script.ActionType,
-					Text:  "echo hello",
-					Speed: 10 * time.Millisecond,
-				},
-				{
-					Kind:   script.ActionKey,
-					Key:    "enter",
-					Repeat: 1,
-				},
-				{
-					Kind:     script.ActionSleep,
-					Duration: 100 * time.Millisecond,
-				},
-				{
-					Kind: script.ActionCtrl,
-					Key:  "c",
-				},
+				{Kind: script.ActionScreenshot, Name: "menu"},
+				{Kind: script.ActionScene, Name: "one"},
+				{Kind: script.ActionScreenshot, Name: "menu"},
+				{Kind: script.ActionScene, Name: "two"},
+				{Kind: script.ActionScreenshot, Name: "menu"},
 			},
-			wantErr: false,
+		},
+		{
+			name: "duplicate within a scene",
+			actions: []script.Action{
+				{Kind: script.ActionScene, Name: "one"},
+				{Kind: script.ActionScreenshot, Name: "menu"},
+				{Kind: script.ActionScreenshot, Name: "Menu"},
+			},
+			wantErr: `screenshot action 2: name "Menu" collides with "menu"`,
+		},
+		{
+			name: "duplicate scene",
+			actions: []script.Action{
+				{Kind: script.ActionScene, Name: "Step 1"},
+				{Kind: script.ActionScene, Name: "step.1"},
+			},
+			wantErr: `scene action 1: name "step.1" collides with "Step 1"`,
+		},
+		{
+			name:    "unusable scene name",
+			actions: []script.Action{{Kind: script.ActionScene, Name: "..."}},
+			wantErr: `scene action 0: scene name "..." has no usable characters`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg := &config.Config{
-				Command:            "echo test",
-				TTydPort:           8080,
-				OutputDir:          t.TempDir(),
-				ScreenshotInterval: 500 * time.Millisecond,
-				Timeout:            30 * time.Second,
-				Actions:            tt.actions,
-				Script:             "test script",
+			err := validateScreenshotNames(tt.actions, "png", config.NameTemplate{}.Pattern("bash"))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
 			}
-
-			capturer := NewCapturer(cfg)
-			assert.NotNil(t, capturer)
+			assert.NoError(t, err)
 		})
 	}
 }
 
-func TestCapturer_ExecuteActions_ContextCancellation(t *testing.T) {
-	actions := []script.Action{
-		{
-			Kind:     script.ActionSleep,
-			Duration: 5 * time.Second,
+func TestValidateScreenshotNames_NameTemplate(t *testing.T) {
+	names, err := config.ParseNameTemplate("login_{n:02}.png")
+	require.NoError(t, err)
+
+	err = validateScreenshotNames([]script.Action{{Kind: script.ActionScreenshot, Name: "login_1"}}, "png", names.Pattern("bash"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `screenshot action 0: name "login_1" collides with sequential screenshot names`)
+
+	// Names the template cannot produce are free
+	err = validateScreenshotNames([]script.Action{{Kind: script.ActionScreenshot, Name: "login"}}, "png", names.Pattern("bash"))
+	assert.NoError(t, err)
+}
+
+func TestCapturer_runSession_ScreenshotAction(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		ScreenshotInterval: 0,
+		Actions: []script.Action{
+			{Kind: script.ActionScreenshot},
+			{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+			{Kind: script.ActionScreenshot, Name: "after enter"},
 		},
-	}
+	})
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
 
-	cfg := &config.Config{
-		Command:            "echo test",
-		TTydPort:           8080,
-		OutputDir:          t.TempDir(),
-		ScreenshotInterval: 500 * time.Millisecond,
-		Timeout:            30 * time.Second,
-		Actions:            actions,
-		Script:             "test script",
+	var got []string
+	for _, f := range enc.frames {
+		got = append(got, filepath.Base(f.Path))
 	}
+	assert.Equal(t, []string{
+		"screenshot_001.png", // initial
+		"screenshot_002.png", // Screenshot
+		"after_enter.png",    // Screenshot "after enter"
+		"screenshot_003.png", // final
+	}, got, "interval disabled: only initial, explicit, and final frames")
+}
 
-	capturer := NewCapturer(cfg)
+func TestCapturer_runSession_BurstAction(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		ScreenshotInterval: time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+			{Kind: script.ActionBurst, Repeat: 4, Duration: 10 * time.Millisecond},
+			{Kind: script.ActionBurst, Repeat: 2, Duration: 10 * time.Millisecond},
+		},
+	})
+	enc := &recordingEncoder{}
+	c.encoder = enc
 
-	// Create a context that we'll cancel
-	_, cancel := context.WithCancel(context.Background())
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
 
-	// Cancel the context immediately to test cancellation
-	cancel()
+	// Interval frames pause during bursts, so each burst is numbered
+	// contiguously
+	var bursts [][]int
+	for i, f := range c.Stats().Frames {
+		if f.Kind != FrameBurst {
+			assert.Zero(t, f.Burst)
+			continue
+		}
+		if len(bursts) < f.Burst {
+			bursts = append(bursts, nil)
+		}
+		var n int
+		_, err := fmt.Sscanf(filepath.Base(f.Path), "screenshot_%03d.png", &n)
+		require.NoError(t, err)
+		bursts[f.Burst-1] = append(bursts[f.Burst-1], n)
+		assert.Equal(t, f.Burst, enc.frames[i].Burst)
+	}
+	require.Len(t, bursts, 2)
+	for _, burst := range bursts {
+		for i := 1; i < len(burst); i++ {
+			assert.Equal(t, burst[i-1]+1, burst[i], "burst numbered %v", burst)
+		}
+	}
+	assert.Len(t, bursts[0], 4)
+	assert.Len(t, bursts[1], 2)
 
-	// The Run method should handle context cancellation gracefully
-	// Since we can't actually run Chrome in tests, we just verify the
-	// config is set up correctly with Actions
-	assert.NotNil(t, capturer)
-	assert.Equal(t, actions, capturer.config.Actions)
+	grouped := 0
+	for _, f := range c.manifest().Frames {
+		if f.Burst != 0 {
+			grouped++
+			assert.Equal(t, FrameBurst, f.Kind)
+		}
+	}
+	assert.Equal(t, 6, grouped, "the manifest groups every burst frame")
 }
 
-func TestCapturer_ExecuteActions_EmptyActions(t *testing.T) {
-	cfg := &config.Config{
-		Command:            "echo test",
-		TTydPort:           8080,
-		OutputDir:          t.TempDir(),
-		ScreenshotInterval: 500 * time.Millisecond,
-		Timeout:            30 * time.Second,
-		Actions:            []script.Action{},
-		Script:             "test script",
-		Keypresses:         []string{},
-		Delays:             []time.Duration{},
+func TestCapturer_executeBurstAction_UsesClock(t *testing.T) {
+	clock := newFakeClock()
+	c := newFakeCapturer(t, &config.Config{})
+	c.now = clock.Now
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	// Each capture takes as long as the burst's spacing on the clock, so no
+	// frame has to wait for the next to be due
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		clock.Advance(time.Hour)
+		return []byte("png"), nil
 	}
 
-	capturer := NewCapturer(cfg)
-	assert.NotNil(t, capturer)
-	assert.Empty(t, capturer.config.Actions)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.executeBurstAction(context.Background(), context.Background(), script.Action{Kind: script.ActionBurst, Repeat: 3, Duration: time.Hour}, 0)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("burst waited on the wall clock")
+	}
+	assert.Len(t, enc.frames, 3)
 }
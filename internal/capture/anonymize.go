@@ -0,0 +1,111 @@
+package capture
+
+import (
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// The placeholders Config.Anonymize shows instead of the real user name,
+// host name and home directory.
+const (
+	anonymousUser = "user"
+	anonymousHost = "demo"
+	anonymousHome = "/home/user"
+)
+
+// anonymousEnv gives the command the placeholder user and host, and a
+// prompt made of them. A shell that sets its own prompt in a startup file,
+// as an interactive bash does from ~/.bashrc, still shows that one.
+var anonymousEnv = []string{
+	"USER=" + anonymousUser,
+	"LOGNAME=" + anonymousUser,
+	"HOSTNAME=" + anonymousHost,
+	"PS1=" + anonymousUser + "@" + anonymousHost + "$ ",
+}
+
+// anonymizeEnv returns anonymousEnv when cfg asks for it.
+func anonymizeEnv(cfg *config.Config) []string {
+	if !cfg.Anonymize {
+		return nil
+	}
+	return anonymousEnv
+}
+
+// anonymizer replaces the real user name, host name and home directory in
+// text with the placeholders. A nil anonymizer leaves text as is.
+type anonymizer struct {
+	home  *regexp.Regexp
+	names *regexp.Regexp
+	// placeholders maps each name names matches to its placeholder.
+	placeholders map[string]string
+}
+
+// newAnonymizer returns an anonymizer for the given user name, host name
+// and home directory; empty ones are left alone. The host name is also
+// replaced without its domain. Names are only replaced as whole words, so
+// a user named "ed" leaves "edit" alone.
+func newAnonymizer(userName, hostName, home string) *anonymizer {
+	a := &anonymizer{placeholders: map[string]string{}}
+	if home = strings.TrimRight(home, `/\`); home != "" {
+		a.home = regexp.MustCompile(regexp.QuoteMeta(home) + `\b`)
+	}
+	add := func(name, placeholder string) {
+		if name != "" && name != placeholder {
+			a.placeholders[name] = placeholder
+		}
+	}
+	add(userName, anonymousUser)
+	add(hostName, anonymousHost)
+	if short, _, ok := strings.Cut(hostName, "."); ok {
+		add(short, anonymousHost)
+	}
+
+	names := make([]string, 0, len(a.placeholders))
+	for name := range a.placeholders {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	if len(names) > 0 {
+		// Longest first, so a full host name wins over its short form
+		sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+		a.names = regexp.MustCompile(`\b(?:` + strings.Join(names, "|") + `)\b`)
+	}
+	return a
+}
+
+// currentAnonymizer returns an anonymizer for the user running scr and the
+// machine it runs on.
+func currentAnonymizer() *anonymizer {
+	var userName string
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+		// Windows user names include the domain, as DOMAIN\name
+		if i := strings.LastIndex(userName, `\`); i >= 0 {
+			userName = userName[i+1:]
+		}
+	}
+	hostName, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+	return newAnonymizer(userName, hostName, home)
+}
+
+// replace returns text with the home directory and the names replaced by
+// their placeholders.
+func (a *anonymizer) replace(text string) string {
+	if a == nil {
+		return text
+	}
+	if a.home != nil {
+		text = a.home.ReplaceAllLiteralString(text, anonymousHome)
+	}
+	if a.names != nil {
+		text = a.names.ReplaceAllStringFunc(text, func(name string) string {
+			return a.placeholders[name]
+		})
+	}
+	return text
+}
@@ -0,0 +1,87 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestAnonymizer_replace(t *testing.T) {
+	a := newAnonymizer("alice", "laptop.corp.example", "/home/alice")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "prompt", text: "alice@laptop:~$ ", want: "user@demo:~$ "},
+		{name: "full host name", text: "ssh alice@laptop.corp.example", want: "ssh user@demo"},
+		{name: "home directory", text: "$ pwd\n/home/alice/src/app\n", want: "$ pwd\n/home/user/src/app\n"},
+		{name: "whole words only", text: "malice in laptops", want: "malice in laptops"},
+		{name: "home directory prefix", text: "/home/alicexyz", want: "/home/alicexyz"},
+		{name: "nothing to replace", text: "make test", want: "make test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, a.replace(tt.text))
+		})
+	}
+}
+
+func TestAnonymizer_replace_MacHome(t *testing.T) {
+	a := newAnonymizer("bob", "bobs-mac", "/Users/bob/")
+	assert.Equal(t, "cd /home/user/code on demo as user", a.replace("cd /Users/bob/code on bobs-mac as bob"))
+}
+
+func TestAnonymizer_replace_Empty(t *testing.T) {
+	var nilAnonymizer *anonymizer
+	assert.Equal(t, "alice@laptop", nilAnonymizer.replace("alice@laptop"), "a nil anonymizer leaves text as is")
+	assert.Equal(t, "alice@laptop /root", newAnonymizer("", "", "").replace("alice@laptop /root"))
+	assert.Equal(t, "user@demo", newAnonymizer("user", "demo", "").replace("user@demo"))
+}
+
+func TestNewCapturer_AnonymizeEnv(t *testing.T) {
+	cfg := &config.Config{Command: "bash", Anonymize: true, Env: []string{"USER=me"}}
+
+	c := NewCapturer(cfg)
+
+	want := append(slices.Clone(anonymousEnv), "USER=me")
+	assert.Equal(t, want, c.ttyd.Env, "the user's variables still win")
+	assert.Contains(t, c.ttyd.Env, "PS1=user@demo$ ")
+	assert.NotNil(t, c.anonymizer)
+	assert.Nil(t, NewCapturer(&config.Config{Command: "bash"}).anonymizer)
+}
+
+func TestCapturer_Anonymize(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Command:   "cat /home/alice/notes.txt",
+		Script:    "Type 'whoami' Enter",
+		Anonymize: true,
+	})
+	c.anonymizer = newAnonymizer("alice", "laptop", "/home/alice")
+	c.readText = func(context.Context) (string, error) { return "alice@laptop:~$ whoami\nalice\n", nil }
+
+	c.captureFailure(context.Background(), errors.New("wait /done/ in /home/alice: timed out"))
+	text, err := os.ReadFile(filepath.Join(c.config.OutputDir, FailureTextFilename))
+	require.NoError(t, err)
+	assert.Equal(t, "error: wait /done/ in /home/user: timed out\n\nuser@demo:~$ whoami\nuser\n", string(text))
+
+	require.NoError(t, c.writeManifest())
+	data, err := os.ReadFile(filepath.Join(c.config.OutputDir, ManifestFilename))
+	require.NoError(t, err)
+	var m Manifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "cat /home/user/notes.txt", m.Command)
+	assert.True(t, m.Anonymized)
+	assert.NotContains(t, string(data), "alice")
+}
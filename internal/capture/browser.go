@@ -0,0 +1,172 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// chromeLocations are the executable names and paths searched for Chrome
+// when no path is configured, in order. They match chromedp's own search.
+func chromeLocations() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		return []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Google\Chrome\Application\chrome.exe`),
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Chromium\Application\chrome.exe`),
+		}
+	default:
+		return []string{
+			"headless_shell",
+			"headless-shell",
+			"chromium",
+			"chromium-browser",
+			"google-chrome",
+			"google-chrome-stable",
+			"google-chrome-beta",
+			"google-chrome-unstable",
+			"/usr/bin/google-chrome",
+			"/usr/local/bin/chrome",
+			"/snap/bin/chromium",
+			"chrome",
+		}
+	}
+}
+
+// ErrMissingDependency matches the errors Run returns when a program the
+// capture needs, such as ttyd, Chrome, ssh or ffmpeg, is not installed.
+var ErrMissingDependency = errors.New("missing dependency")
+
+// dependencyError reports a program that is not installed. It matches
+// ErrMissingDependency.
+type dependencyError struct {
+	msg string
+}
+
+func (e *dependencyError) Error() string { return e.msg }
+
+func (e *dependencyError) Is(target error) bool { return target == ErrMissingDependency }
+
+// missingDependency returns a dependencyError with the formatted message.
+func missingDependency(format string, args ...any) error {
+	return &dependencyError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrChromeNotFound is returned by Run when no usable Chrome or Chromium
+// executable exists. It matches ErrMissingDependency.
+var ErrChromeNotFound error = &dependencyError{msg: "no Chrome/Chromium found"}
+
+// findChrome returns the Chrome executable to launch: path if set, which
+// must exist, or the first of chromeLocations found.
+func findChrome(path string) (string, error) {
+	if path != "" {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("%w at --chrome-path %q: %v", ErrChromeNotFound, path, err)
+		}
+		return found, nil
+	}
+
+	locations := chromeLocations()
+	for _, location := range locations {
+		if found, err := exec.LookPath(location); err == nil {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("%w (searched %s); install one or pass --chrome-path", ErrChromeNotFound, strings.Join(locations, ", "))
+}
+
+// parseChromeFlag splits a command-line flag such as "--no-sandbox" or
+// "--proxy-server=host:8080" into the name and value chromedp.Flag takes:
+// true for a bare flag, the string after = otherwise.
+func parseChromeFlag(flag string) (name string, value any, err error) {
+	trimmed := strings.TrimLeft(flag, "-")
+	name, val, hasValue := strings.Cut(trimmed, "=")
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid chrome flag %q", flag)
+	}
+	if !hasValue {
+		return name, true, nil
+	}
+	return name, val, nil
+}
+
+// allocatorOptions returns chromedp's default options for launching
+// headless Chrome at execPath, with the persistent profileDir if set,
+// followed by cfg.ChromeFlags, which override defaults of the same name.
+func allocatorOptions(cfg *config.Config, execPath, profileDir string) ([]chromedp.ExecAllocatorOption, error) {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	opts = append(opts, chromedp.ExecPath(execPath))
+	if profileDir != "" {
+		opts = append(opts, chromedp.UserDataDir(profileDir))
+	}
+	for _, flag := range cfg.ChromeFlags {
+		name, value, err := parseChromeFlag(flag)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+	return opts, nil
+}
+
+// launchChrome starts Chrome with allocOpts and returns the context of its
+// first tab. Chrome does not inherit ctx, so that it outlives a deadline
+// long enough to capture the failure; ctx ending only abandons the launch,
+// and Chrome is closed as soon as it has started. The returned function
+// terminates Chrome and may be called more than once.
+func launchChrome(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.WithoutCancel(ctx), allocOpts...)
+	browserCtx, cancel := chromedp.NewContext(allocCtx, browserOpts...)
+	// chromedp.Cancel() explicitly terminates the Chrome process,
+	// distinct from context cancel which only closes the connection
+	closeBrowser := sync.OnceFunc(func() {
+		_ = chromedp.Cancel(browserCtx)
+		cancel()
+		cancelAlloc()
+	})
+
+	launched := make(chan error, 1)
+	go func() { launched <- chromedp.Run(browserCtx) }()
+	select {
+	case err := <-launched:
+		if err != nil {
+			closeBrowser()
+			return nil, nil, err
+		}
+		return browserCtx, closeBrowser, nil
+	case <-ctx.Done():
+		// Cancelling the tab while chromedp starts Chrome can leave it
+		// waiting forever for Chrome to exit, so Chrome is closed once the
+		// launch is over instead
+		go func() {
+			<-launched
+			closeBrowser()
+		}()
+		return nil, nil, ctx.Err()
+	}
+}
+
+// navigatePage loads url in the browser and waits for the page to load.
+func navigatePage(ctx context.Context, url string) error {
+	return chromedp.Run(ctx, chromedp.Navigate(url))
+}
@@ -0,0 +1,176 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestFindChrome(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake browser")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "chromium-browser")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\n"), 0o755))
+
+	t.Run("explicit path", func(t *testing.T) {
+		got, err := findChrome(fake)
+		require.NoError(t, err)
+		assert.Equal(t, fake, got)
+	})
+
+	t.Run("explicit path that does not exist", func(t *testing.T) {
+		_, err := findChrome(filepath.Join(dir, "nope"))
+		require.ErrorIs(t, err, ErrChromeNotFound)
+		require.ErrorIs(t, err, ErrMissingDependency)
+		assert.Contains(t, err.Error(), `no Chrome/Chromium found at --chrome-path "`+filepath.Join(dir, "nope")+`"`)
+	})
+
+	t.Run("found on PATH", func(t *testing.T) {
+		if runtime.GOOS == "darwin" {
+			t.Skip("macOS searches application bundles only")
+		}
+		t.Setenv("PATH", dir)
+		got, err := findChrome("")
+		require.NoError(t, err)
+		assert.Equal(t, fake, got)
+	})
+
+	t.Run("not found names the locations searched", func(t *testing.T) {
+		if runtime.GOOS == "darwin" {
+			t.Skip("macOS searches application bundles only")
+		}
+		t.Setenv("PATH", t.TempDir())
+		_, err := findChrome("")
+		require.ErrorIs(t, err, ErrChromeNotFound)
+		assert.Contains(t, err.Error(), "no Chrome/Chromium found (searched headless_shell, headless-shell, chromium")
+		assert.Contains(t, err.Error(), "/snap/bin/chromium, chrome); install one or pass --chrome-path")
+	})
+}
+
+func TestParseChromeFlag(t *testing.T) {
+	tests := []struct {
+		flag      string
+		wantName  string
+		wantValue any
+		wantErr   bool
+	}{
+		{flag: "--no-sandbox", wantName: "no-sandbox", wantValue: true},
+		{flag: "disable-gpu", wantName: "disable-gpu", wantValue: true},
+		{flag: "--proxy-server=host:8080", wantName: "proxy-server", wantValue: "host:8080"},
+		{flag: "--lang=", wantName: "lang", wantValue: ""},
+		{flag: "--", wantErr: true},
+		{flag: "--=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			name, value, err := parseChromeFlag(tt.flag)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
+
+func TestAllocatorOptions(t *testing.T) {
+	defaults := len(chromedp.DefaultExecAllocatorOptions)
+
+	opts, err := allocatorOptions(&config.Config{}, "/usr/bin/chromium", "")
+	require.NoError(t, err)
+	assert.Len(t, opts, defaults+1, "defaults plus the executable")
+
+	opts, err = allocatorOptions(&config.Config{ChromeFlags: []string{"--no-sandbox", "--window-size=800,600"}}, "/usr/bin/chromium", "")
+	require.NoError(t, err)
+	assert.Len(t, opts, defaults+3, "flags come after the defaults")
+
+	_, err = allocatorOptions(&config.Config{ChromeFlags: []string{"--"}}, "/usr/bin/chromium", "")
+	assert.ErrorContains(t, err, `invalid chrome flag "--"`)
+}
+
+func TestCapturer_Run_ChromeNotFound(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS searches application bundles only")
+	}
+	// A ttyd on PATH proves the browser is checked before ttyd starts: it
+	// leaves a file behind if it is run at all, even for --version
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ttyd"), []byte("#!/bin/sh\n: > "+ran+"\nsleep 30\n"), 0o755))
+	t.Setenv("PATH", dir)
+
+	c := NewCapturer(&config.Config{Command: "bash", TTydPort: 8080, OutputDir: t.TempDir()})
+	err := c.Run(context.Background())
+	require.ErrorIs(t, err, ErrChromeNotFound)
+	assert.Contains(t, err.Error(), "searched headless_shell, ")
+	assert.Contains(t, err.Error(), "pass --chrome-path")
+	assert.Empty(t, c.Stats().Phases, "nothing was started")
+	assert.NoFileExists(t, ran, "ttyd was never run")
+}
+
+func TestCapturer_Run_ChromeFailsToStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as the fake ttyd and browser")
+	}
+	sleep, err := exec.LookPath("sleep")
+	require.NoError(t, err)
+
+	// A ttyd that never answers, so Run is still waiting for it when the
+	// browser fails, and that records its pid so the test can check it is
+	// gone
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "ttyd.pid")
+	script := "#!/bin/sh\ncase \"$1\" in\n" +
+		"--help) printf '%s\\n' '" + helpWithWritable + "'; exit 1 ;;\n" +
+		"--version) echo 'ttyd version 1.7.7' ;;\n" +
+		"*) echo $$ > " + pidFile + "; exec sleep 30 ;;\nesac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ttyd"), []byte(script), 0o755))
+	t.Setenv("PATH", dir+string(filepath.ListSeparator)+filepath.Dir(sleep))
+
+	// A browser that exits as soon as ttyd has started
+	chrome := filepath.Join(t.TempDir(), "chromium")
+	require.NoError(t, os.WriteFile(chrome, []byte("#!/bin/sh\nwhile [ ! -s "+pidFile+" ]; do sleep 0.01; done\nexit 1\n"), 0o755))
+
+	c := NewCapturer(&config.Config{Command: "bash", TTydPort: 8080, AutoPort: true, OutputDir: t.TempDir(), ChromePath: chrome})
+	start := time.Now()
+	err = c.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "launch browser "+chrome)
+	assert.Contains(t, err.Error(), "--chrome-path")
+	assert.Less(t, time.Since(start), 5*time.Second, "Run does not wait for ttyd's health check")
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err, "ttyd was started")
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	proc, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	assert.Error(t, proc.Signal(syscall.Signal(0)), "no ttyd is left behind")
+}
+
+// fakeChrome writes an executable that passes for Chrome in findChrome and
+// returns its path, for tests of Run that stop before the browser starts.
+func fakeChrome(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chromium")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755))
+	return path
+}
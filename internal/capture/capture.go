@@ -2,19 +2,23 @@ package capture
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/chromedp/cdproto/input"
 	"github.com/chromedp/chromedp"
 
 	"github.com/yarlson/scr/internal/config"
-	inputpkg "github.com/yarlson/scr/internal/input"
 	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
 )
 
 // Capturer orchestrates the TUI interaction workflow, connecting ttyd,
@@ -24,21 +28,299 @@ type Capturer struct {
 	ttyd            *TTydServer
 	screenshotCount int
 	mu              sync.Mutex
+	interval        *intervalCapturer
+
+	// runID marks the processes and throwaway Chrome profile of the
+	// current Run; see RunIDEnv.
+	runID string
+
+	// anonymizer scrubs the text files and manifest scr writes; nil
+	// without Config.Anonymize.
+	anonymizer *anonymizer
+
+	// names and namePrefix name sequential screenshots; see
+	// config.NameTemplate.
+	names      config.NameTemplate
+	namePrefix string
+
+	// scene is the directory of the current Scene, "" before the first
+	// one, and scenes are the scenes started so far; both are guarded by
+	// mu.
+	scene  string
+	scenes []scene
+
+	// encoder receives every captured frame; encMu serializes captures
+	// and calls to it from the main flow and the interval goroutine, and
+	// guards lastFrame.
+	encoder   Encoder
+	encMu     sync.Mutex
+	lastFrame lastFrame
+
+	// secretShown is set from a TypeSecret until the next Enter or Ctrl+L,
+	// while its text may be on screen, and holds back every frame; it is
+	// guarded by encMu. typingSecret is set while a TypeSecret is typed,
+	// and keeps the browser's protocol trace out of the session log.
+	secretShown  bool
+	typingSecret atomic.Bool
+
+	// hidden is set between a Hide and the next Show, and holds back every
+	// frame; it is guarded by encMu.
+	hidden bool
+
+	// burst counts the Burst actions started; FrameBurst frames belong to
+	// the latest. It is guarded by encMu.
+	burst int
+
+	// stageDir is the temporary directory frames are written to with
+	// OutTmp; empty otherwise.
+	stageDir string
+
+	// terminalSelector is the CSS selector that matched the terminal
+	// element; see terminalSelectors.
+	terminalSelector string
+
+	// width and height are the current viewport size; Set Width and Set
+	// Height change them mid-run.
+	width, height int
+
+	// sendKey, insertText, captureFrame, readText, readPrompt,
+	// readAltScreen, readScrollback, readCursor, applyTheme, applyFont,
+	// setFont, setViewport and resizeTerminal perform the browser-side work
+	// of sending a keypress, typing a run of text at once, grabbing the
+	// terminal image, reading the terminal text, prompt marks, which screen
+	// buffer is active, how far output has scrolled and where the cursor
+	// is, changing its colors, switching it to the embedded font or another
+	// font family and size, and changing its size. They default to the
+	// chromedp implementations and are replaced in tests.
+	sendKey        func(ctx context.Context, key string) error
+	insertText     func(ctx context.Context, text string) error
+	captureFrame   func(ctx context.Context) ([]byte, error)
+	readText       func(ctx context.Context) (string, error)
+	readPrompt     func(ctx context.Context) (promptMarks, error)
+	readAltScreen  func(ctx context.Context) (bool, error)
+	readScrollback func(ctx context.Context) (int, error)
+	readCursor     func(ctx context.Context) (Cursor, error)
+	applyTheme     func(ctx context.Context, t theme.Theme) error
+	applyFont      func(ctx context.Context) error
+	setFont        func(ctx context.Context, family string, size int) error
+	setViewport    func(ctx context.Context, width, height int) error
+	resizeTerminal func(ctx context.Context, cols, rows int) error
+
+	// startBrowser launches Chrome and returns a context for its page and a
+	// function that terminates it. checkBrowser, navigate, findTerminal,
+	// findElement and probeBrowser check its version, load the terminal
+	// page, look once for the terminal element or whether the element of a
+	// selector is visible, and read the browser setup. They default to
+	// chromedp and are replaced in tests.
+	startBrowser func(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error)
+	checkBrowser func(ctx context.Context) error
+	navigate     func(ctx context.Context, url string) error
+	findTerminal func(ctx context.Context) (string, error)
+	findElement  func(ctx context.Context, selector string) (bool, error)
+	probeBrowser func(ctx context.Context) (Environment, error)
+
+	// shared is the browser the run opens its page in, when it shares one;
+	// see ShareBrowser.
+	shared *SharedBrowser
+
+	// screencast starts streaming page frames and encodeVideo turns the
+	// recorded frames into a video file, for Config.Video. They default to
+	// the DevTools screencast and ffmpeg and are replaced in tests. video
+	// is the file written by the last run.
+	screencast  func(ctx context.Context, onFrame func(data []byte)) (stop func(), err error)
+	encodeVideo func(ctx context.Context, list, out string) error
+	video       string
+
+	// progress streams events to Config.Progress during a run; nil when
+	// it is not set.
+	progress *progressWriter
+
+	// onFrame, if set, is called with every frame written; see OnFrame.
+	onFrame func(Frame)
+
+	// signal delivers a signal to the captured command's processes, for
+	// Signal actions.
+	signal func(name string) error
+
+	// command reports when the captured command exits; it is ttyd, or nil
+	// when attaching to an existing terminal. With ExitOnDone, its exit
+	// ends the capture early; with FailOnError, a non-zero exit fails it.
+	command commandExit
+
+	// hooks runs Config.FrameHook for the frames of the current session;
+	// nil without one. runHook runs a single hook command and is replaced
+	// in tests. hookStats are the counts from the last session.
+	hooks     *frameHooks
+	runHook   func(ctx context.Context, command string) ([]byte, error)
+	hookStats HookStats
+
+	// env is the ttyd and browser setup of the current run, recorded in
+	// the manifest.
+	env Environment
+
+	// scrollback is the most lines that scrolled off the top of the
+	// terminal in the last session, recorded in the manifest.
+	scrollback int
+
+	// sums are the checksums of the files written by the current run.
+	sums *checksums
+
+	// now is the clock used for all recorded timings; timeline holds them.
+	now      func() time.Time
+	timeline *timeline
 }
 
 // NewCapturer creates and returns a new Capturer with the provided config.
-// It initializes ttyd with cfg.Command and cfg.TTydPort.
+// It initializes ttyd with cfg.Command (or cfg.CommandArgs) and
+// cfg.TTydPort, on cfg.SSH if set, unless cfg.TerminalURL attaches to an
+// existing ttyd, in which case ttyd is nil.
 // It does NOT start ttyd yet (that happens in Run()).
 // It does NOT validate config (caller has already done so).
 func NewCapturer(cfg *config.Config) *Capturer {
 	if cfg == nil {
 		panic("NewCapturer: config must not be nil")
 	}
-	return &Capturer{
+	c := &Capturer{
 		config:          cfg,
-		ttyd:            NewTTydServer(cfg.Command, cfg.TTydPort),
 		screenshotCount: 0,
+		encoder:         &fileEncoder{},
+		sums:            newChecksums(),
+	}
+	if cfg.TerminalURL == "" {
+		c.ttyd = NewTTydServer(cfg.Command, cfg.TTydPort)
+		c.ttyd.Args = cfg.CommandArgs
+		c.ttyd.Shell = cfg.Shell
+		c.ttyd.AutoPort = cfg.AutoPort
+		c.ttyd.NeedsInput = needsInput(cfg)
+		// The user's variables come last, so they override scr's own
+		c.ttyd.Env = slices.Concat(promptEnv(cfg), anonymizeEnv(cfg), cfg.Env)
+		c.ttyd.NoColor = cfg.NoColorSession
+		c.ttyd.SSH = cfg.SSH
+		c.command = c.ttyd
+		c.signal = c.ttyd.SignalCommand
+	} else {
+		c.signal = func(string) error { return errNoCommand }
+	}
+	if cfg.Anonymize {
+		c.anonymizer = currentAnonymizer()
+	}
+	c.sendKey = c.sendKeypress
+	c.insertText = insertTerminalText
+	c.captureFrame = c.captureTerminal
+	c.readText = TerminalReader{}.Text
+	c.readPrompt = watchPrompt
+	c.readAltScreen = TerminalReader{}.altScreen
+	c.readScrollback = TerminalReader{}.scrollback
+	c.readCursor = TerminalReader{}.CursorPosition
+	c.applyTheme = applyTerminalTheme
+	c.applyFont = applyEmbeddedFont
+	c.setFont = setTerminalFont
+	c.setViewport = setBrowserViewport
+	c.resizeTerminal = resizeTerminal
+	c.startBrowser = launchChrome
+	c.checkBrowser = checkBrowser
+	c.navigate = navigatePage
+	c.findTerminal = findTerminalElement
+	c.findElement = elementVisible
+	c.probeBrowser = probeBrowser
+	c.screencast = startScreencast
+	c.encodeVideo = runFFmpeg
+	c.runHook = runHookCommand
+	// An invalid template is reported by Run
+	if names, err := config.ParseNameTemplate(cfg.NameTemplate); err == nil {
+		c.names = names.ForFormat(cfg.ImageFormat())
+	}
+	c.namePrefix = cfg.NamePrefix()
+	c.width, c.height = viewportSize(cfg)
+	c.now = time.Now
+	c.timeline = newTimeline(c.now)
+	return c
+}
+
+// needsInput reports whether the run sends any keys to the terminal.
+func needsInput(cfg *config.Config) bool {
+	if len(cfg.Keypresses) > 0 {
+		return true
+	}
+	for _, action := range cfg.Actions {
+		switch action.Kind {
+		case script.ActionType, script.ActionKey, script.ActionCtrl:
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the timings recorded by the most recent Run. Frame and
+// action offsets are relative to the moment the terminal became ready.
+func (c *Capturer) Stats() Stats {
+	return c.timeline.stats()
+}
+
+// Artifact returns the single file written by the output encoder, such as
+// an animated GIF, or "" when the format writes one file per frame.
+func (c *Capturer) Artifact() string {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	if a, ok := c.encoder.(Artifact); ok && a.Artifact() != "" {
+		return c.finalPath(a.Artifact())
+	}
+	return ""
+}
+
+// Screenshots returns the frame files written by the last run, in capture
+// order, at their final location. Frames skipped by Dedup are not included,
+// and formats that write a single Artifact list no frames unless KeepFrames
+// is set.
+func (c *Capturer) Screenshots() []string {
+	c.encMu.Lock()
+	_, single := c.encoder.(Artifact)
+	c.encMu.Unlock()
+	if single && !c.config.KeepFrames {
+		return nil
+	}
+
+	var paths []string
+	for _, frame := range c.Stats().Frames {
+		if !frame.Duplicate {
+			paths = append(paths, c.finalPath(frame.Path))
+		}
+	}
+	return paths
+}
+
+// Result summarizes the last run for callers that report on it.
+type Result struct {
+	// Screenshots are the frame files written, as returned by Screenshots.
+	Screenshots []string
+	// Artifact and Video are the files returned by Artifact and Video, or
+	// "".
+	Artifact string
+	Video    string
+	// Frames counts the frames captured, not counting those skipped by
+	// Dedup, also when they went into a single Artifact.
+	Frames int
+	// Duration is the time from the start of the run until its last
+	// recorded event.
+	Duration time.Duration
+}
+
+// Result returns the outcome of the last run. It is filled in as far as the
+// run got, so a failed run reports the frames it wrote before failing.
+func (c *Capturer) Result() Result {
+	stats := c.Stats()
+	r := Result{
+		Screenshots: c.Screenshots(),
+		Artifact:    c.Artifact(),
+		Video:       c.Video(),
+		Duration:    stats.Total,
+	}
+	for _, frame := range stats.Frames {
+		if !frame.Duplicate {
+			r.Frames++
+		}
 	}
+	return r
 }
 
 // Validate checks that the Capturer configuration is valid.
@@ -52,303 +334,734 @@ func (c *Capturer) Validate() error {
 
 // Run orchestrates the TUI capture workflow:
 // 1. Creates output directory
-// 2. Starts ttyd process
-// 3. Launches Chrome browser
+// 2. Starts ttyd process (or waits for an attached one)
+// 3. Launches Chrome browser while ttyd starts
 // 4. Navigates to ttyd URL
 // 5. Captures initial screenshot
 // 6. Sends keypresses with configured delays
 // 7. Captures screenshots at specified intervals
 // 8. Captures final screenshot
 // All cleanup defers execute even on error.
-func (c *Capturer) Run(ctx context.Context) error {
-	// Create output directory
-	if err := os.MkdirAll(c.config.OutputDir, 0o755); err != nil {
-		return fmt.Errorf("output directory: %w", err)
+func (c *Capturer) Run(ctx context.Context) (err error) {
+	c.timeline = newTimeline(c.now)
+	c.runID = newRunID(c.now())
+	if c.ttyd != nil {
+		c.ttyd.RunID = c.runID
 	}
+	c.env = Environment{}
+	c.video = ""
+	c.scene, c.scenes = "", nil
+	c.sums = newChecksums()
+	endProgress := c.startProgress()
+	defer func() { endProgress(err) }()
 
-	// Start ttyd process
-	if err := c.ttyd.Start(ctx); err != nil {
-		return fmt.Errorf("start ttyd: %w", err)
+	// Reject unusable screenshot names before starting anything
+	names, err := config.ParseNameTemplate(c.config.NameTemplate)
+	if err != nil {
+		return fmt.Errorf("name: %w", err)
 	}
-	defer c.ttyd.Stop()
-
-	// Launch Chrome browser
-	browserCtx, cancel := chromedp.NewContext(ctx)
-	defer cancel()
-	// chromedp.Cancel() explicitly terminates the Chrome process,
-	// distinct from context cancel which only closes the connection
-	defer chromedp.Cancel(browserCtx)
-
-	// Navigate to ttyd URL
-	if err := chromedp.Run(browserCtx, chromedp.Navigate(c.ttyd.URL())); err != nil {
-		return fmt.Errorf("navigate to ttyd: %w", err)
+	c.names = names.ForFormat(c.config.ImageFormat())
+	if err := validateScreenshotNames(c.config.Actions, c.config.ImageFormat(), c.names.Pattern(c.namePrefix)); err != nil {
+		return err
 	}
-
-	// Set viewport size for consistent screenshots
-	if err := chromedp.Run(browserCtx, chromedp.EmulateViewport(1280, 720)); err != nil {
-		return fmt.Errorf("set viewport: %w", err)
+	if c.config.Video != "" {
+		if _, err := lookFFmpeg(); err != nil {
+			return err
+		}
+	}
+	if err := validateThemes(c.config); err != nil {
+		return err
 	}
 
-	// Wait for xterm terminal to be ready
-	if err := chromedp.Run(browserCtx,
-		chromedp.WaitVisible(".xterm-screen", chromedp.ByQuery),
-	); err != nil {
-		return fmt.Errorf("wait for terminal: %w", err)
+	// Resolve the output format before starting anything
+	encoder, err := NewEncoder(c.config.Format)
+	if err != nil {
+		return fmt.Errorf("output format: %w", err)
+	}
+	c.encoder = encoder
+	if _, single := encoder.(Artifact); single && c.config.FrameHook != "" && !c.config.KeepFrames {
+		return fmt.Errorf("frame hook needs frame files, which format %s does not keep; add --keep-frames", c.config.Format)
 	}
 
-	// Capture initial screenshot at t=0
-	if c.config.Verbose {
-		fmt.Fprintf(os.Stderr, "Capturing initial screenshot\n")
+	// Claim the output directory; the lock goes only after the staged
+	// files have been moved into it
+	releaseOutput, err := c.lockOutput()
+	if err != nil {
+		return err
 	}
-	if err := c.captureScreenshot(browserCtx, c.getScreenshotFilename()); err != nil {
-		return fmt.Errorf("initial screenshot: %w", err)
+	defer releaseOutput()
+
+	// Create output directory, or a staging directory with OutTmp whose
+	// files are moved into place once the terminal and browser are gone
+	if err := c.prepareOutput(); err != nil {
+		return err
 	}
+	defer func() {
+		if pubErr := c.publishOutput(); pubErr != nil && err == nil {
+			err = pubErr
+		}
+	}()
 
-	// Start interval-based screenshot capture
-	var intervalStopChan chan struct{}
-	var wg sync.WaitGroup
-	if c.config.ScreenshotInterval > 0 {
-		intervalStopChan = make(chan struct{})
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			c.captureIntervalScreenshots(browserCtx, intervalStopChan)
+	// The after hook is deferred before the before hook runs, so that it
+	// cleans up whatever happens from here on, a failed before hook too
+	if c.config.AfterHook != "" {
+		defer func() {
+			if hookErr := c.runAfterHook(ctx); hookErr != nil {
+				if err == nil {
+					err = hookErr
+				} else {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", hookErr)
+				}
+			}
 		}()
 	}
+	if c.config.BeforeHook != "" {
+		done := c.timeline.beginPhase("before")
+		err := c.runShellHook(ctx, "before", c.config.BeforeHook)
+		done()
+		if err != nil {
+			return err
+		}
+	}
 
-	// Execute actions directly
-	if err := c.executeActions(ctx, browserCtx, intervalStopChan, &wg); err != nil {
+	// Open the debug log, if any, before anything starts writing to it
+	logw, err := c.openLog()
+	if err != nil {
 		return err
 	}
+	var browserOpts []chromedp.ContextOption
+	if logw != nil {
+		defer logw.Close()
+		// Secret param values are redacted from everything logged
+		logOut := redactSecrets(logw, c.config)
+		if c.ttyd != nil {
+			c.ttyd.Log = logOut
+		}
+		browserOpts = browserLogOptions(logOut, c.typingSecret.Load)
+	}
 
-	// Stop interval-based screenshots
-	if intervalStopChan != nil {
-		close(intervalStopChan)
-		wg.Wait()
+	// Like ttyd, the browser must exist before anything starts; otherwise
+	// the run would only fail once the launch times out
+	chromePath, err := findChrome(c.config.ChromePath)
+	if err != nil {
+		return err
 	}
+	// A shared browser has a profile of its own
+	var profileDir string
+	if c.shared == nil {
+		var releaseProfile func()
+		profileDir, releaseProfile, err = c.chromeProfile()
+		if err != nil {
+			return err
+		}
+		defer releaseProfile()
+		if profileDir == "" {
+			var removeProfile func()
+			profileDir, removeProfile, err = c.tempProfile()
+			if err != nil {
+				return err
+			}
+			defer removeProfile()
+		}
+	}
+	allocOpts, err := allocatorOptions(c.config, chromePath, profileDir)
+	if err != nil {
+		return err
+	}
+	allocOpts = append(allocOpts, chromedp.Env(RunIDEnv+"="+c.runID))
 
-	// Small delay to ensure final state is rendered
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(100 * time.Millisecond):
-		// continue
+	// Start ttyd process, or wait for the existing instance we attach to,
+	// while the browser launches; neither needs the other until the page
+	// is opened. An attached ttyd is not ours, so it is never stopped.
+	// ttyd lives on termCtx, which is only cancelled early when the browser
+	// fails, so that a starting ttyd is torn down rather than left behind.
+	termCtx, cancelTerm := context.WithCancel(ctx)
+	defer cancelTerm()
+	type terminal struct {
+		url string
+		err error
 	}
+	started := make(chan terminal, 1)
+	go func() {
+		url, err := c.startTerminal(termCtx)
+		started <- terminal{url: url, err: err}
+	}()
 
-	// Capture final screenshot
+	// Launch Chrome browser. It does not inherit ctx, so that it is still
+	// there to capture a failure caused by the deadline; until the page has
+	// loaded, ctx ending tears it down instead.
 	if c.config.Verbose {
-		fmt.Fprintf(os.Stderr, "Capturing final screenshot\n")
+		fmt.Fprintf(os.Stderr, "Launching %s\n", chromePath)
 	}
-	if err := c.captureScreenshot(browserCtx, c.getScreenshotFilename()); err != nil {
-		return fmt.Errorf("final screenshot: %w", err)
+	done := c.timeline.beginPhase("browser")
+	browserCtx, closeBrowser, launchErr := c.startBrowser(ctx, allocOpts, browserOpts)
+	done()
+	if launchErr != nil {
+		cancelTerm()
 	}
 
-	return nil
-}
+	// After a browser failure, ttyd's error only says it was stopped; when
+	// ctx ended, though, both failed and ttyd's error says where the run was
+	term := <-started
+	if term.err == nil && c.ttyd != nil {
+		defer c.ttyd.Stop()
+	}
+	if term.err != nil && (launchErr == nil || ctx.Err() != nil) {
+		if launchErr == nil {
+			closeBrowser()
+		}
+		return term.err
+	}
+	if launchErr != nil {
+		return fmt.Errorf("launch browser %s: %w; check that it starts, or pass --chrome-path with another browser", chromePath, launchErr)
+	}
+	defer closeBrowser()
+	stopLaunchWatch := context.AfterFunc(ctx, closeBrowser)
 
-// sendKeypress sends a keypress to the browser using CDP Input.dispatchKeyEvent.
-// It handles both regular keys and special keys (including Ctrl combinations).
-func (c *Capturer) sendKeypress(ctx context.Context, key string) error {
-	// Check if this is a Ctrl key combination
-	if c.isCtrlKey(key) {
-		return c.sendCtrlKeypress(ctx, key)
+	if !c.config.SkipVersionCheck {
+		if err := c.checkBrowser(browserCtx); err != nil {
+			return err
+		}
 	}
 
-	keyCode, err := inputpkg.KeyToKeyCode(key)
+	// Navigate to ttyd URL
+	url := term.url
+	done = c.timeline.beginPhase("navigate")
+	err = c.navigate(browserCtx, url)
+	done()
+	if err == nil && !stopLaunchWatch() {
+		err = ctx.Err()
+	}
 	if err != nil {
-		return fmt.Errorf("lookup key code for %q: %w", key, err)
+		return fmt.Errorf("navigate to ttyd: %w", err)
 	}
 
-	// For regular keys, use chromedp.KeyEvent
-	return chromedp.Run(ctx, chromedp.KeyEvent(keyCode))
-}
+	// From here on, ctx ending only interrupts browser calls, and any
+	// failure leaves a screenshot and the terminal text behind
+	browserCtx, stop := context.WithCancel(browserCtx)
+	defer stop()
+	defer context.AfterFunc(ctx, stop)()
+	defer func() {
+		var exitErr *ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			c.captureFailure(context.WithoutCancel(browserCtx), err)
+		}
+	}()
+
+	// Set viewport size for consistent screenshots
+	c.width, c.height = viewportSize(c.config)
+	if err := c.setViewport(browserCtx, c.width, c.height); err != nil {
+		return fmt.Errorf("set viewport: %w", err)
+	}
 
-// sendCtrlKeypress sends a Ctrl+key combination using chromedp.KeyEvent with modifiers.
-func (c *Capturer) sendCtrlKeypress(ctx context.Context, key string) error {
-	lowerKey := strings.ToLower(key)
-	if !strings.HasPrefix(lowerKey, "ctrl+") || len(lowerKey) != 6 {
-		return fmt.Errorf("invalid Ctrl key format: %s", key)
+	// Wait for xterm terminal to be ready, finding its element among the
+	// page layouts of different ttyd versions
+	done = c.timeline.beginPhase("terminal")
+	err = c.waitForTerminal(browserCtx)
+	done()
+	if err != nil {
+		return fmt.Errorf("wait for terminal: %w", err)
 	}
-	keyChar := lowerKey[5:] // Extract the character after "ctrl+"
 
-	// Use chromedp.KeyEvent with Ctrl modifier
-	return chromedp.Run(ctx, chromedp.KeyEvent(keyChar, chromedp.KeyModifiers(input.ModifierCtrl)))
+	// Record what the frames are rendered with; a failed probe leaves the
+	// fields empty rather than failing the capture
+	env, err := c.probeBrowser(browserCtx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: read browser version: %v\n", err)
+	}
+	env.TTyd = c.env.TTyd
+	env.Font = EmbeddedFontName
+	if c.config.SystemFonts {
+		env.Font = SystemFontName
+	}
+	if c.config.FontFamily != "" {
+		env.Font = c.config.FontFamily
+	}
+	env.FontSize = c.config.FontSize
+	c.env = env
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Environment: %s\n", c.env)
+	}
+
+	// Make sure the command takes input before any action types into it
+	done = c.timeline.beginPhase("probe")
+	err = c.probeInput(browserCtx)
+	done()
+	if err != nil {
+		return fmt.Errorf("probe terminal: %w", err)
+	}
+
+	err = c.runSession(ctx, browserCtx)
+	if manErr := c.writeManifest(); manErr != nil && err == nil {
+		err = manErr
+	}
+	if err == nil {
+		err = c.writeChecksums()
+	}
+	return err
 }
 
-// isCtrlKey checks if a key is a Ctrl key combination.
-func (c *Capturer) isCtrlKey(key string) bool {
-	lowerKey := strings.ToLower(key)
-	return strings.HasPrefix(lowerKey, "ctrl+") && len(lowerKey) == 6
+// OnFrame makes Run call fn with every frame written, in capture order, as
+// soon as it is written. Frame.Path is where the file ends up once the run
+// has finished. Captures wait while fn runs, and fn must not modify Data,
+// which the encoder may still hold.
+func (c *Capturer) OnFrame(fn func(Frame)) {
+	c.onFrame = fn
 }
 
-// executeActions executes the configured actions in sequence.
-// It handles ActionType, ActionSleep, ActionKey, and ActionCtrl.
-// All blocking operations respect ctx.Done() for graceful shutdown.
-func (c *Capturer) executeActions(ctx, browserCtx context.Context, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
-	// Determine which action set to use
-	actions := c.config.Actions
-	useActions := len(actions) > 0
+// Environment returns the ttyd and browser setup of the last run; it is
+// empty until the terminal page has loaded.
+func (c *Capturer) Environment() Environment {
+	return c.env
+}
 
-	if !useActions {
-		// Fall back to legacy keypresses/delays for backward compatibility
-		return c.executeKeypresses(ctx, browserCtx, intervalStopChan, wg)
+// startTerminal makes the terminal page available and returns its URL. It
+// starts ttyd unless the config attaches to an existing instance, in which
+// case it only waits for that instance to answer.
+func (c *Capturer) startTerminal(ctx context.Context) (string, error) {
+	if c.config.TerminalURL != "" {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Attaching to %s\n", c.config.TerminalURL)
+		}
+		done := c.timeline.beginPhase("attach")
+		err := waitForHTTP(ctx, c.config.TerminalURL, 5*time.Second)
+		done()
+		if errors.Is(err, errNotReady) {
+			return "", fmt.Errorf("attach to %s: no response after 5 seconds", c.config.TerminalURL)
+		}
+		if err != nil {
+			return "", fmt.Errorf("attach to %s: %w", c.config.TerminalURL, err)
+		}
+		return c.config.TerminalURL, nil
 	}
 
-	for i, action := range actions {
-		if err := c.executeSingleAction(ctx, browserCtx, action, i, intervalStopChan, wg); err != nil {
-			return err
+	// An old ttyd fails in confusing ways, such as a terminal that never
+	// renders, so it is turned away before starting. Over ssh, reading the
+	// remote version also checks that the login works and ttyd is there
+	if c.config.SSH != "" {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Connecting to %s\n", c.config.SSH)
+		}
+		version, err := remoteTTydVersion(ctx, c.config.SSH)
+		if err != nil {
+			return "", err
+		}
+		c.env.TTyd = version
+	} else {
+		c.env.TTyd = ttydVersion()
+	}
+	if !c.config.SkipVersionCheck {
+		if err := checkTTydVersion(c.env.TTyd); err != nil {
+			return "", err
 		}
 	}
 
-	return nil
-}
+	if c.config.Verbose {
+		for _, entry := range c.config.Env {
+			fmt.Fprintf(os.Stderr, "Setting %s for the command\n", entry)
+		}
+	}
 
-// executeSingleAction executes a single action based on its kind.
-func (c *Capturer) executeSingleAction(ctx, browserCtx context.Context, action script.Action, index int, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
-	switch action.Kind {
-	case script.ActionType:
-		return c.executeTypeAction(ctx, browserCtx, action, index, intervalStopChan, wg)
-	case script.ActionSleep:
-		return c.executeSleepAction(ctx, action, index)
-	case script.ActionKey:
-		return c.executeKeyAction(ctx, browserCtx, action, index, intervalStopChan, wg)
-	case script.ActionCtrl:
-		return c.executeCtrlAction(ctx, browserCtx, action, index, intervalStopChan, wg)
-	default:
-		return fmt.Errorf("unknown action kind: %v", action.Kind)
+	done := c.timeline.beginPhase("ttyd")
+	err := c.ttyd.Start(ctx)
+	done()
+	if err != nil {
+		return "", fmt.Errorf("start ttyd: %w", err)
 	}
+	if c.config.Verbose {
+		if c.config.SSH != "" {
+			fmt.Fprintf(os.Stderr, "ttyd %s on %s forwarded to port %d\n", c.env.TTyd, c.config.SSH, c.ttyd.Port)
+		} else {
+			fmt.Fprintf(os.Stderr, "ttyd %s listening on port %d\n", c.env.TTyd, c.ttyd.Port)
+		}
+	}
+	return c.ttyd.URL(), nil
 }
 
-// executeTypeAction executes a type action by sending each character with per-char delay.
-func (c *Capturer) executeTypeAction(ctx, browserCtx context.Context, action script.Action, index int, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
-	for _, char := range action.Text {
-		// Check for context cancellation before each character
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+// runSession runs the part of the workflow that happens once the terminal is
+// ready: initial screenshot, actions with interval capture, final screenshot.
+// The encoder is finished even when the session fails, but only its error on
+// the success path is reported.
+func (c *Capturer) runSession(ctx, browserCtx context.Context) (err error) {
+	// The terminal is ready: this is t=0 for all frame and action offsets
+	c.timeline.markReady()
+	c.lastFrame = lastFrame{}
+
+	if err := c.encoder.Begin(Meta{
+		OutputDir: c.outputDir(),
+		Command:   c.config.CommandLine(),
+		Script:    c.config.Script,
+		Interval:  c.config.ScreenshotInterval,
+
+		FrameDelay:   c.config.FrameDelay,
+		KeepFrames:   c.config.KeepFrames,
+		TargetFrames: c.config.TargetFrames,
+	}); err != nil {
+		return fmt.Errorf("begin output: %w", err)
+	}
+	defer func() {
+		if endErr := c.encoder.End(); endErr != nil && err == nil {
+			err = fmt.Errorf("finish output: %w", endErr)
 		}
+	}()
 
+	// Frame hooks finish before the output is finished and published
+	c.hooks = c.startFrameHooks(ctx)
+	defer func() {
+		stats, hookErr := c.hooks.wait(c.config.FrameHookStrict)
+		c.hookStats, c.hooks = stats, nil
+		if hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}()
+
+	// The font changes the cell size, so it goes before the terminal is
+	// resized to fixed cols and rows
+	if !c.config.SystemFonts && c.config.FontFamily == "" {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Switching terminal to the embedded %s font\n", EmbeddedFontName)
+		}
+		if err := c.applyFont(browserCtx); err != nil {
+			return fmt.Errorf("apply font: %w", err)
+		}
+	}
+	if c.config.FontFamily != "" || c.config.FontSize > 0 {
 		if c.config.Verbose {
-			fmt.Fprintf(os.Stderr, "Sending character: %s\n", string(char))
+			fmt.Fprintf(os.Stderr, "Setting terminal font to %q, size %d\n", c.config.FontFamily, c.config.FontSize)
+		}
+		if err := c.setFont(browserCtx, c.config.FontFamily, c.config.FontSize); err != nil {
+			return fmt.Errorf("set font: %w", err)
 		}
+	}
 
-		if err := c.sendKeypress(browserCtx, string(char)); err != nil {
-			if intervalStopChan != nil {
-				close(intervalStopChan)
-				wg.Wait()
-			}
-			return fmt.Errorf("send character %q: %w", char, err)
+	if c.config.Cols > 0 || c.config.Rows > 0 {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Resizing terminal to %dx%d cells\n", c.config.Cols, c.config.Rows)
 		}
+		if err := c.resizeTerminal(browserCtx, c.config.Cols, c.config.Rows); err != nil {
+			return fmt.Errorf("resize terminal: %w", err)
+		}
+	}
 
-		// Sleep for per-character speed
-		if action.Speed > 0 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(action.Speed):
-				// continue
-			}
+	if c.config.Theme != "" {
+		if err := c.setTheme(browserCtx, c.config.Theme); err != nil {
+			return err
+		}
+	}
+
+	// Start counting prompt markers before the shell prints its first
+	// prompt, if possible; Wait Prompt falls back to the profile's pattern
+	// until one is seen
+	if c.config.PromptPattern == "" && waitsForPrompt(c.config.Actions) {
+		if _, err := c.readPrompt(browserCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: watch for prompt markers: %v\n", err)
 		}
 	}
 
-	// Apply post-action delay if specified
-	if action.Delay > 0 {
+	c.watchScrollback(browserCtx)
+
+	// Without interactive actions, the capture is static: Set actions
+	// configure the session before the initial frame, and nothing runs
+	// between it and the final frame
+	static := script.SettingsOnly(c.config.Actions) && len(c.config.Keypresses) == 0
+	if static {
 		if c.config.Verbose {
-			fmt.Fprintf(os.Stderr, "Waiting %v after type action %d\n", action.Delay, index)
+			fmt.Fprintf(os.Stderr, "No interactive actions found; capturing the initial and final frames\n")
 		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(action.Delay):
-			// continue
+		if err := c.executeActions(ctx, browserCtx); err != nil {
+			return err
 		}
 	}
 
-	return nil
-}
-
-// executeSleepAction executes a sleep action with context-aware cancellation.
-func (c *Capturer) executeSleepAction(ctx context.Context, action script.Action, index int) error {
+	// Capture initial screenshot at t=0
 	if c.config.Verbose {
-		fmt.Fprintf(os.Stderr, "Sleeping for %v (action %d)\n", action.Duration, index)
+		fmt.Fprintf(os.Stderr, "Capturing initial screenshot\n")
+	}
+	if err := c.captureScreenshot(browserCtx, "", FrameInitial); err != nil {
+		return fmt.Errorf("initial screenshot: %w", err)
+	}
+
+	// Start interval-based screenshot capture; Stop is idempotent, so the
+	// deferred call is a no-op on the success path.
+	c.interval = c.startIntervalCapture(browserCtx)
+	defer c.interval.Stop()
+
+	// With Video, the screencast records everything from here to the
+	// final frame instead
+	video, err := c.startVideo(browserCtx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if video != nil {
+			_ = c.finishVideo(ctx, video, false)
+		}
+	}()
+
+	// Execute actions directly; with ExitOnDone, the command exiting
+	// stops them early and the final frame is taken right away
+	var exitErr error
+	if c.config.ExitOnDone {
+		actx, exited, cancel := c.untilExit(ctx)
+		var err error
+		if !static {
+			err = c.executeActions(actx, browserCtx)
+		}
+		cancel()
+		if exited() {
+			exitErr = c.exitResult()
+		} else if err != nil {
+			return err
+		}
+	} else if !static {
+		if err := c.executeActions(ctx, browserCtx); err != nil {
+			return err
+		}
 	}
 
+	// Stop interval-based screenshots
+	c.interval.Stop()
+
+	// Small delay to ensure final state is rendered
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(action.Duration):
+	case <-time.After(100 * time.Millisecond):
 		// continue
 	}
 
-	return nil
+	// Capture final screenshot
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Capturing final screenshot\n")
+	}
+	if err := c.captureScreenshot(browserCtx, "", FrameFinal); err != nil {
+		return fmt.Errorf("final screenshot: %w", err)
+	}
+
+	recorded := video
+	video = nil
+	if err := c.finishVideo(ctx, recorded, true); err != nil {
+		return err
+	}
+	c.checkScrollback(browserCtx)
+
+	// With FailOnError, a command that has exited non-zero by now fails
+	// the capture too, although it did not end it
+	if exitErr == nil && c.config.FailOnError {
+		exitErr = c.commandFailure()
+	}
+	return exitErr
 }
 
-// executeKeyAction executes a key action with optional delay and repeat count.
-func (c *Capturer) executeKeyAction(ctx, browserCtx context.Context, action script.Action, index int, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
-	// Determine repeat count (defaults to 1)
-	repeat := action.Repeat
-	if repeat <= 0 {
-		repeat = 1
+// sendKeypress sends a keypress to the browser using CDP Input.dispatchKeyEvent.
+// It handles regular keys, special keys and keys with modifiers such as
+// Ctrl+C, Alt+X or Shift+Tab.
+func (c *Capturer) sendKeypress(ctx context.Context, key string) error {
+	return ChromeDriver{}.SendKey(ctx, key)
+}
+
+// executeActions executes the configured actions in sequence with the
+// Capturer's Player, or the legacy keypresses without any actions.
+// All blocking operations respect ctx.Done() for graceful shutdown.
+func (c *Capturer) executeActions(ctx, browserCtx context.Context) error {
+	if len(c.config.Actions) == 0 {
+		// Fall back to legacy keypresses/delays for backward compatibility
+		return c.executeKeypresses(ctx, browserCtx)
 	}
+	return c.player(browserCtx).Play(ctx, c.config.Actions)
+}
 
-	for i := 0; i < repeat; i++ {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+// player returns the Player that executes the actions of a session on the
+// page of browserCtx. It records each action in the timeline and progress
+// events, holds back frames while a secret is typed, and performs the
+// actions that take or shape frames.
+func (c *Capturer) player(browserCtx context.Context) *Player {
+	p := &Player{
+		Driver:        pageDriver{c: c, ctx: browserCtx},
+		EscapeDelay:   c.config.EscapeDelay,
+		TypeChunkSize: c.config.TypeChunkSize,
+		NoTypeVerify:  c.config.NoTypeVerify,
+		Signal:        c.signal,
+		panes:         c.config.TmuxPanes,
+		prompt:        c.promptWait,
+	}
+	if c.config.Verbose {
+		p.Log = os.Stderr
+	}
+	p.Perform = func(ctx context.Context, action script.Action, index int) error {
+		return c.performAction(ctx, browserCtx, action, index)
+	}
 
-		if c.config.Verbose {
-			fmt.Fprintf(os.Stderr, "Sending keypress: %s (repeat %d/%d)\n", action.Key, i+1, repeat)
+	var start time.Time
+	var pausedInterval bool
+	p.Before = func(action script.Action, index int) {
+		start = c.now()
+		c.timeline.beginAction(index)
+		c.emit(ProgressEvent{Event: EventActionStart, Action: &index, Text: action.String()})
+		if action.Kind != script.ActionType {
+			return
 		}
-
-		if err := c.sendKeypress(browserCtx, action.Key); err != nil {
-			if intervalStopChan != nil {
-				close(intervalStopChan)
-				wg.Wait()
-			}
-			return fmt.Errorf("send key %q (repeat %d): %w", action.Key, i+1, err)
+		// With NoCaptureWhileTyping, interval frames are paused for the
+		// whole action and a single frame is taken once the post-action
+		// delay has elapsed
+		pausedInterval = c.config.NoCaptureWhileTyping && c.interval != nil
+		if pausedInterval {
+			c.interval.Pause()
+		}
+		if action.Secret {
+			c.showSecret(true)
+			c.typingSecret.Store(true)
 		}
 	}
-
-	// Apply post-action delay if specified
-	if action.Delay > 0 {
-		if c.config.Verbose {
-			fmt.Fprintf(os.Stderr, "Waiting %v after key action %d\n", action.Delay, index)
+	p.After = func(action script.Action, index int, err error) error {
+		if action.Kind == script.ActionType {
+			c.typingSecret.Store(false)
+			if pausedInterval {
+				if err == nil {
+					err = c.captureAfterType(browserCtx, index)
+				}
+				c.interval.Resume()
+				pausedInterval = false
+			}
 		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(action.Delay):
-			// continue
+		if err != nil {
+			c.emit(ProgressEvent{Event: EventActionEnd, Action: &index, Text: action.String(), Error: err.Error()})
+			return err
 		}
+		if clearsSecret(action) {
+			c.showSecret(false)
+		}
+		c.timeline.addAction(index, start)
+		c.emit(ProgressEvent{Event: EventActionEnd, Action: &index, Text: action.String()})
+		return nil
+	}
+	return p
+}
+
+// pageDriver is the Driver of a Capturer's terminal page. It goes through
+// the Capturer's browser functions in the page's context, which outlives
+// the contexts the Player passes, so tests that replace those functions
+// replace the driver too.
+type pageDriver struct {
+	c   *Capturer
+	ctx context.Context
+}
+
+func (d pageDriver) SendKey(_ context.Context, key string) error {
+	return d.c.sendKey(d.ctx, key)
+}
+
+func (d pageDriver) InsertText(_ context.Context, text string) error {
+	return d.c.insertText(d.ctx, text)
+}
+
+func (d pageDriver) ReadText(_ context.Context) (string, error) {
+	return d.c.readText(d.ctx)
+}
+
+func (d pageDriver) ReadAltScreen(_ context.Context) (bool, error) {
+	return d.c.readAltScreen(d.ctx)
+}
+
+// performAction executes an action that takes or shapes frames, for the
+// Player.
+func (c *Capturer) performAction(ctx, browserCtx context.Context, action script.Action, index int) error {
+	switch action.Kind {
+	case script.ActionScreenshot:
+		return c.executeScreenshotAction(browserCtx, action, index)
+	case script.ActionSet:
+		return c.executeSetAction(browserCtx, action, index)
+	case script.ActionScene:
+		return c.executeSceneAction(action, index)
+	case script.ActionBurst:
+		return c.executeBurstAction(ctx, browserCtx, action, index)
+	case script.ActionHide, script.ActionShow:
+		return c.executeHideAction(action, index)
+	default:
+		return fmt.Errorf("unknown action kind: %v", action.Kind)
 	}
+}
 
+// captureAfterType takes the frame of a type action whose interval frames
+// were paused with NoCaptureWhileTyping.
+func (c *Capturer) captureAfterType(browserCtx context.Context, index int) error {
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Capturing screenshot after type action %d\n", index)
+	}
+	if err := c.captureScreenshot(browserCtx, "", FrameInterval); err != nil {
+		return fmt.Errorf("screenshot after type action %d: %w", index, err)
+	}
 	return nil
 }
 
-// executeCtrlAction executes a control key combination action.
-func (c *Capturer) executeCtrlAction(ctx, browserCtx context.Context, action script.Action, index int, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
+// executeBurstAction captures action.Repeat frames action.Duration apart,
+// the first at once. Interval frames are paused meanwhile, so the burst is
+// numbered contiguously, and each frame is due at its place in the burst
+// however long the ones before took to capture.
+func (c *Capturer) executeBurstAction(ctx, browserCtx context.Context, action script.Action, index int) error {
 	if c.config.Verbose {
-		fmt.Fprintf(os.Stderr, "Sending Ctrl+%s (action %d)\n", action.Key, index)
+		fmt.Fprintf(os.Stderr, "Capturing %d frames %v apart (action %d)\n", action.Repeat, action.Duration, index)
+	}
+	if c.interval != nil {
+		c.interval.Pause()
+		defer c.interval.Resume()
+	}
+	c.encMu.Lock()
+	c.burst++
+	c.encMu.Unlock()
+
+	start := c.now()
+	for i := range action.Repeat {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(start.Add(time.Duration(i) * action.Duration).Sub(c.now())):
+				// continue
+			}
+		}
+		if err := c.captureScreenshot(browserCtx, "", FrameBurst); err != nil {
+			return fmt.Errorf("burst action %d, frame %d: %w", index, i+1, err)
+		}
 	}
+	return nil
+}
 
-	if err := c.sendCtrlKeypress(browserCtx, "ctrl+"+action.Key); err != nil {
-		if intervalStopChan != nil {
-			close(intervalStopChan)
-			wg.Wait()
+// executeScreenshotAction captures a screenshot immediately, named after the
+// action's label if it has one or with the next sequential name otherwise.
+func (c *Capturer) executeScreenshotAction(browserCtx context.Context, action script.Action, index int) error {
+	var filename string
+	if action.Name != "" {
+		name, err := screenshotName(action.Name, c.config.ImageFormat())
+		if err != nil {
+			return fmt.Errorf("screenshot action %d: %w", index, err)
+		}
+		c.mu.Lock()
+		filename = filepath.Join(c.frameDirLocked(), name)
+		c.mu.Unlock()
+	}
+
+	if c.config.Verbose {
+		if filename != "" {
+			fmt.Fprintf(os.Stderr, "Capturing screenshot %s (action %d)\n", filename, index)
+		} else {
+			fmt.Fprintf(os.Stderr, "Capturing screenshot (action %d)\n", index)
 		}
-		return fmt.Errorf("send Ctrl+%s: %w", action.Key, err)
+	}
+
+	if err := c.captureScreenshot(browserCtx, filename, FrameExplicit); err != nil {
+		return fmt.Errorf("screenshot action %d: %w", index, err)
 	}
 
 	return nil
 }
 
 // executeKeypresses executes the legacy keypresses/delays configuration.
-func (c *Capturer) executeKeypresses(ctx, browserCtx context.Context, intervalStopChan chan struct{}, wg *sync.WaitGroup) error {
+func (c *Capturer) executeKeypresses(ctx, browserCtx context.Context) error {
 	for i, key := range c.config.Keypresses {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// Wait for delay before sending key (except for first key)
 		if i > 0 && i-1 < len(c.config.Delays) {
 			delay := c.config.Delays[i-1]
@@ -367,75 +1080,251 @@ func (c *Capturer) executeKeypresses(ctx, browserCtx context.Context, intervalSt
 			fmt.Fprintf(os.Stderr, "Sending keypress: %s\n", key)
 		}
 
-		if err := c.sendKeypress(browserCtx, key); err != nil {
-			if intervalStopChan != nil {
-				close(intervalStopChan)
-				wg.Wait()
-			}
-			return fmt.Errorf("send keypress %d (%s): %w", i, key, err)
+		start := c.now()
+		c.timeline.beginAction(i)
+		c.emit(ProgressEvent{Event: EventActionStart, Action: &i, Text: key})
+		if err := c.sendKey(browserCtx, key); err != nil {
+			err = fmt.Errorf("send keypress %d (%s): %w", i, key, err)
+			c.emit(ProgressEvent{Event: EventActionEnd, Action: &i, Text: key, Error: err.Error()})
+			return err
 		}
+		c.timeline.addAction(i, start)
+		c.emit(ProgressEvent{Event: EventActionEnd, Action: &i, Text: key})
 	}
 
 	return nil
 }
 
-// getScreenshotFilename returns the filename for the next screenshot
-// with sequential naming (screenshot_001.png, etc.).
-func (c *Capturer) getScreenshotFilename() string {
+// getScreenshotFilename returns the filename for the next screenshot,
+// captured at the given time, with sequential naming from the name template
+// (screenshot_001.png, etc. by default, with the extension of the frame
+// format), in the current scene's directory once a Scene has started.
+// Callers hold c.encMu, so numbers increase in the order frames are written
+// even with interval capture running alongside.
+func (c *Capturer) getScreenshotFilename(at time.Time) string {
+	fields := config.NameFields{Prefix: c.namePrefix}
+	if c.timeline != nil {
+		fields.Time = c.timeline.offset(at)
+		fields.Action = c.timeline.currentAction() + 1
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.screenshotCount++
-	return filepath.Join(c.config.OutputDir, fmt.Sprintf("screenshot_%03d.png", c.screenshotCount))
+	fields.N = c.screenshotCount
+	return filepath.Join(c.frameDirLocked(), c.names.Format(fields))
 }
 
-// captureIntervalScreenshots captures screenshots at the configured interval
-// until the stop channel is closed.
-func (c *Capturer) captureIntervalScreenshots(ctx context.Context, stopChan chan struct{}) {
-	if c.config.ScreenshotInterval <= 0 {
-		return
-	}
+// sequentialName matches the default names produced by
+// getScreenshotFilename; they are never available to Screenshot actions.
+var sequentialName = regexp.MustCompile(`^screenshot_\d+\.(png|jpe?g|webp)$`)
 
-	ticker := time.NewTicker(c.config.ScreenshotInterval)
-	defer ticker.Stop()
+// screenshotName turns a user-supplied screenshot label into a safe file
+// name for a frame in the image format: characters outside [A-Za-z0-9._-]
+// become underscores, leading dots are dropped so the file is never hidden
+// or a path component, and the extension is made the format's, as
+// config.WithImageExt does. Names that would collide with sequential
+// screenshots are rejected.
+func screenshotName(label, format string) (string, error) {
+	var sb strings.Builder
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	name := strings.TrimLeft(sb.String(), ".")
+	if strings.Trim(name, "_.") == "" {
+		return "", fmt.Errorf("screenshot name %q has no usable characters", label)
+	}
+	name = config.WithImageExt(name, format)
+	if sequentialName.MatchString(strings.ToLower(name)) {
+		return "", fmt.Errorf("screenshot name %q collides with sequential screenshot names", label)
+	}
+	return name, nil
+}
 
-	for {
-		select {
-		case <-stopChan:
-			return
-		case <-ticker.C:
-			if c.config.Verbose {
-				fmt.Fprintf(os.Stderr, "Capturing interval screenshot %d\n", c.screenshotCount+1)
+// validateScreenshotNames checks every named Screenshot and Scene action up
+// front so a bad or duplicate name, or one that sequential matches, fails
+// the run before ttyd and Chrome are started. Screenshot names, in the image
+// format, need only be unique within their scene.
+func validateScreenshotNames(actions []script.Action, format string, sequential *regexp.Regexp) error {
+	seen := make(map[string]string)
+	scenes := make(map[string]string)
+	dir := ""
+	for i, action := range actions {
+		if action.Kind == script.ActionScene {
+			var err error
+			dir, err = sceneDirName(action.Name)
+			if err != nil {
+				return fmt.Errorf("scene action %d: %w", i, err)
 			}
-			// We need to create a new context for each screenshot since the
-			// parent context might be cancelled
-			if err := c.captureScreenshot(ctx, c.getScreenshotFilename()); err != nil {
-				// Log error but don't stop - interval screenshots are best effort
-				if c.config.Verbose {
-					fmt.Fprintf(os.Stderr, "Failed to capture interval screenshot: %v\n", err)
-				}
+			key := strings.ToLower(dir)
+			if prev, ok := scenes[key]; ok {
+				return fmt.Errorf("scene action %d: name %q collides with %q", i, action.Name, prev)
 			}
+			scenes[key] = action.Name
+			continue
+		}
+		if action.Kind != script.ActionScreenshot || action.Name == "" {
+			continue
+		}
+		name, err := screenshotName(action.Name, format)
+		if err != nil {
+			return fmt.Errorf("screenshot action %d: %w", i, err)
+		}
+		if sequential.MatchString(name) {
+			return fmt.Errorf("screenshot action %d: name %q collides with sequential screenshot names", i, action.Name)
+		}
+		key := strings.ToLower(path.Join(dir, name))
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("screenshot action %d: name %q collides with %q", i, action.Name, prev)
 		}
+		seen[key] = action.Name
 	}
+	return nil
 }
 
-// captureScreenshot captures the terminal and saves it as PNG.
-// Returns an error if the capture or save fails.
-func (c *Capturer) captureScreenshot(ctx context.Context, filename string) error {
-	var buf []byte
-
-	// Capture the terminal container element
-	err := chromedp.Run(ctx,
-		chromedp.Screenshot("#terminal-container", &buf, chromedp.NodeVisible, chromedp.ByID),
-	)
+// captureScreenshot captures the terminal and hands it to the encoder under
+// filename, or the next sequential name when filename is empty, recording
+// it as a frame of the given kind. Returns an error if the capture or
+// encoding fails.
+func (c *Capturer) captureScreenshot(ctx context.Context, filename string, kind FrameKind) error {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	if c.framesHeldLocked(kind) {
+		return nil
+	}
+	buf, at, err := c.captureFrameLocked(ctx)
 	if err != nil {
-		return fmt.Errorf("capture screenshot: %w", err)
+		return err
+	}
+	if filename != "" {
+		_, err := c.writeFrameLocked(filename, buf, at, kind)
+		return err
 	}
+	return c.writeSequentialLocked(buf, at, kind)
+}
 
-	// Write the captured screenshot data to file
-	err = os.WriteFile(filename, buf, 0o644)
+// captureFrameLocked grabs the terminal image and the time it was taken.
+// Callers hold c.encMu for the capture and the write that follows, so
+// frames are captured one at a time: an interval frame cannot be taken
+// alongside an action's frame and then be written after it, and sequential
+// numbers, frame times and the manifest's order always agree. Crop,
+// Window, Padding and Grayscale are applied here, in that order and before
+// a PNG frame is converted to jpeg, so Dedup compares what is written.
+func (c *Capturer) captureFrameLocked(ctx context.Context) ([]byte, time.Time, error) {
+	buf, err := c.captureFrame(ctx)
 	if err != nil {
-		return fmt.Errorf("write screenshot: %w", err)
+		return nil, time.Time{}, fmt.Errorf("capture screenshot: %w", err)
+	}
+	at := c.now()
+	if !c.config.Crop.Empty() {
+		if buf, err = cropPNG(buf, c.config.Crop); err != nil {
+			return nil, time.Time{}, fmt.Errorf("crop frame: %w", err)
+		}
+	}
+	if c.config.Window {
+		if buf, err = c.windowFrame(buf); err != nil {
+			return nil, time.Time{}, fmt.Errorf("draw window: %w", err)
+		}
 	}
+	if c.config.Padding > 0 {
+		if buf, err = c.padFrame(buf); err != nil {
+			return nil, time.Time{}, fmt.Errorf("pad frame: %w", err)
+		}
+	}
+	if c.config.Grayscale {
+		if buf, err = grayscale(buf); err != nil {
+			return nil, time.Time{}, fmt.Errorf("grayscale: %w", err)
+		}
+	}
+	if c.config.ImageFormat() == "jpeg" && !c.nativeFrames() {
+		if buf, err = encodeJPEG(buf, c.quality()); err != nil {
+			return nil, time.Time{}, fmt.Errorf("encode jpeg: %w", err)
+		}
+	}
+	return buf, at, nil
+}
 
-	return nil
+// writeSequentialLocked writes a frame under the next sequential name. The
+// number is only taken when the frame is written, so a failed write leaves
+// no gap in the numbering; a frame written before a later step failed
+// keeps its number, so the next frame does not overwrite it. Callers hold
+// c.encMu.
+func (c *Capturer) writeSequentialLocked(buf []byte, at time.Time, kind FrameKind) error {
+	filename := c.getScreenshotFilename(at)
+	if c.config.Verbose && kind == FrameInterval {
+		fmt.Fprintf(os.Stderr, "Capturing interval screenshot %s\n", filename)
+	}
+	written, err := c.writeFrameLocked(filename, buf, at, kind)
+	if !written {
+		c.mu.Lock()
+		c.screenshotCount--
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// writeFrameLocked hands a captured frame to the encoder and records it,
+// along with how much it changed from the previous frame when that is
+// measured. written reports whether the encoder took the frame, also when
+// a step after it failed. Callers hold c.encMu.
+func (c *Capturer) writeFrameLocked(filename string, buf []byte, at time.Time, kind FrameKind) (written bool, err error) {
+	if c.config.FrameHookStrict {
+		if err := c.hooks.failed(); err != nil {
+			return false, fmt.Errorf("frame hook failed for %w", err)
+		}
+	}
+	sceneDir := c.sceneOf(filename)
+	offset := c.timeline.offset(at)
+	burst := 0
+	if kind == FrameBurst {
+		burst = c.burst
+	}
+	frame := Frame{Path: filename, Data: buf, Kind: kind, Time: at, Offset: offset, Scene: sceneDir, Burst: burst}
+	if err := c.encoder.Frame(frame); err != nil {
+		return false, err
+	}
+	c.hashFrame(filename, buf)
+	if err := c.writeSimulations(filename, buf); err != nil {
+		return true, err
+	}
+	c.hooks.submit(filename, offset)
+	change, compared := c.trackFrameLocked(filename, buf)
+	if compared {
+		c.logChange(filename, change)
+	}
+	c.timeline.addFrame(FrameStat{Path: filename, Kind: kind, Scene: sceneDir, Burst: burst, Time: at, Change: change, Compared: compared})
+	c.emit(ProgressEvent{Event: EventScreenshot, Path: c.finalPath(filename), Kind: kind})
+	if c.onFrame != nil {
+		frame.Path = c.finalPath(filename)
+		c.onFrame(frame)
+	}
+	return true, nil
+}
+
+// captureTerminal grabs the terminal element found by waitForTerminal, or
+// the Config.Selector element, as PNG bytes, or as jpeg or webp when
+// nativeFrames says so.
+func (c *Capturer) captureTerminal(ctx context.Context) ([]byte, error) {
+	selector := c.config.Selector
+	if selector == "" {
+		selector = c.terminalSelector
+	}
+	if selector == "" {
+		selector = terminalSelectors[0]
+	}
+	if c.nativeFrames() {
+		return captureElement(ctx, selector, c.config.ImageFormat(), c.quality())
+	}
+	var buf []byte
+	err := chromedp.Run(ctx,
+		chromedp.Screenshot(selector, &buf, chromedp.NodeVisible, chromedp.ByQuery),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
 }
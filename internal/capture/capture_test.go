@@ -2,15 +2,25 @@ package capture
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	cdpinput "github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/yarlson/scr/internal/config"
 	"github.com/yarlson/scr/internal/input"
+	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/testutil"
+	"github.com/yarlson/scr/internal/theme"
 )
 
 func TestNewCapturer(t *testing.T) {
@@ -296,6 +306,7 @@ func TestCapturer_getScreenshotFilename(t *testing.T) {
 	tests := []struct {
 		name         string
 		outputDir    string
+		template     string
 		counter      int
 		wantFilename string
 	}{
@@ -317,56 +328,77 @@ func TestCapturer_getScreenshotFilename(t *testing.T) {
 			counter:      99,
 			wantFilename: "/tmp/output/screenshot_100.png",
 		},
+		{
+			name:         "name template",
+			outputDir:    "/tmp/output",
+			template:     "login_{n:02}.png",
+			counter:      4,
+			wantFilename: "/tmp/output/login_05.png",
+		},
+		{
+			name:         "name template with prefix, time and action",
+			outputDir:    "/tmp/output",
+			template:     "{prefix}_{action}_{time:05}_{n}",
+			counter:      0,
+			wantFilename: "/tmp/output/htop_0_00000_1.png",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			capturer := &Capturer{
-				config: &config.Config{
-					OutputDir: tt.outputDir,
-				},
-				screenshotCount: tt.counter,
-			}
-
-			got := capturer.getScreenshotFilename()
+			capturer := NewCapturer(&config.Config{
+				Command:      "htop",
+				OutputDir:    tt.outputDir,
+				NameTemplate: tt.template,
+			})
+			capturer.screenshotCount = tt.counter
+
+			got := capturer.getScreenshotFilename(time.Now())
 			assert.Equal(t, tt.wantFilename, got)
 		})
 	}
 }
 
-func TestCapturer_isCtrlKey(t *testing.T) {
+func TestKeyEvent(t *testing.T) {
 	tests := []struct {
 		name     string
 		key      string
-		expected bool
+		wantText string
+		wantMods []cdpinput.Modifier
+		wantErr  bool
 	}{
+		{name: "letter", key: "a", wantText: "a"},
+		{name: "space is typed as a space", key: " ", wantText: " "},
+		{name: "named key is encoded, not typed", key: "enter", wantText: kb.Enter},
+		{name: "arrow key", key: "Down", wantText: kb.ArrowDown},
+		{name: "ctrl+c", key: "ctrl+c", wantText: "c", wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl}},
+		{name: "ctrl+d", key: "ctrl+d", wantText: "d", wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl}},
+		{name: "ctrl+left", key: "Ctrl+Left", wantText: kb.ArrowLeft, wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl}},
+		{name: "ctrl+right", key: "Ctrl+Right", wantText: kb.ArrowRight, wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl}},
+		{name: "ctrl+backspace", key: "ctrl+backspace", wantText: kb.Backspace, wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl}},
+		{name: "alt letter", key: "Alt+X", wantText: "x", wantMods: []cdpinput.Modifier{cdpinput.ModifierAlt}},
+		{name: "shift tab", key: "Shift+Tab", wantText: kb.Tab, wantMods: []cdpinput.Modifier{cdpinput.ModifierShift}},
 		{
-			name:     "ctrl+c is a control key",
-			key:      "ctrl+c",
-			expected: true,
-		},
-		{
-			name:     "ctrl+d is a control key",
-			key:      "ctrl+d",
-			expected: true,
-		},
-		{
-			name:     "enter is not a control key",
-			key:      "enter",
-			expected: false,
-		},
-		{
-			name:     "a is not a control key",
-			key:      "a",
-			expected: false,
+			name:     "chained modifiers",
+			key:      "Ctrl+Shift+c",
+			wantText: "C",
+			wantMods: []cdpinput.Modifier{cdpinput.ModifierCtrl, cdpinput.ModifierShift},
 		},
+		{name: "unknown modifier", key: "Hyper+a", wantErr: true},
+		{name: "unknown key", key: "invalidkey123", wantErr: true},
+		{name: "ctrl with unknown key", key: "Ctrl+NotAKey", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			capturer := &Capturer{}
-			got := capturer.isCtrlKey(tt.key)
-			assert.Equal(t, tt.expected, got)
+			text, mods, err := keyEvent(tt.key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantMods, mods)
 		})
 	}
 }
@@ -382,3 +414,280 @@ func TestChromeCleanup_WiredUp(t *testing.T) {
 		assert.True(t, true, "Chrome cleanup via chromedp.Cancel() is configured")
 	})
 }
+
+func TestCapturer_AttachURL(t *testing.T) {
+	t.Run("does not create a ttyd server", func(t *testing.T) {
+		capturer := NewCapturer(&config.Config{
+			TerminalURL: "http://localhost:7681",
+			OutputDir:   t.TempDir(),
+		})
+		assert.Nil(t, capturer.ttyd)
+	})
+
+	t.Run("waits for the attached URL instead of starting ttyd", func(t *testing.T) {
+		capturer := NewCapturer(&config.Config{
+			TerminalURL: "http://127.0.0.1:1",
+			OutputDir:   t.TempDir(),
+			ChromePath:  fakeChrome(t),
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := capturer.Run(ctx)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "attach to http://127.0.0.1:1")
+		assert.NotContains(t, err.Error(), "ttyd binary not found")
+		// The browser launches meanwhile, and fails on the same ctx
+		assert.ElementsMatch(t, []string{"attach", "browser"}, phaseNames(capturer.Stats().Phases))
+	})
+}
+
+// phaseNames returns the names of the given phases in order.
+func phaseNames(phases []Phase) []string {
+	names := make([]string, 0, len(phases))
+	for _, p := range phases {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// newHarnessCapturer returns a Capturer whose Run attaches to a fake ttyd
+// and drives a fake browser, so the whole run can be tested without either.
+// The output directory is one that does not exist yet.
+func newHarnessCapturer(t testing.TB, cfg *config.Config) (*Capturer, *testutil.Browser, *testutil.TTyd) {
+	t.Helper()
+	ttyd := testutil.NewTTyd(t)
+	b := testutil.NewBrowser()
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = filepath.Join(t.TempDir(), "out", "run")
+	}
+	cfg.TerminalURL = ttyd.URL()
+	cfg.ChromePath = testutil.ChromePath(t)
+
+	c := NewCapturer(cfg)
+	c.startBrowser = b.Launch
+	c.checkBrowser = b.CheckVersion
+	c.navigate = b.Navigate
+	c.findTerminal = b.FindTerminal
+	c.probeBrowser = func(ctx context.Context) (Environment, error) {
+		product, err := b.Product(ctx)
+		return Environment{Browser: product}, err
+	}
+	c.setViewport = b.SetViewport
+	c.resizeTerminal = b.Resize
+	c.captureFrame = b.Screenshot
+	c.readText = b.Text
+	c.sendKey = b.SendKey
+	c.insertText = b.InsertText
+	c.readPrompt = func(context.Context) (promptMarks, error) { return promptMarks{}, nil }
+	c.readAltScreen = func(context.Context) (bool, error) { return false, nil }
+	c.readScrollback = func(context.Context) (int, error) { return 0, nil }
+	c.readCursor = func(context.Context) (Cursor, error) {
+		lines := strings.Split(b.Screen(), "\n")
+		last := lines[len(lines)-1]
+		return Cursor{Col: utf8.RuneCountInString(last), Row: len(lines) - 1}, nil
+	}
+	c.applyTheme = func(context.Context, theme.Theme) error { return nil }
+	c.applyFont = func(context.Context) error { return nil }
+	c.setFont = func(context.Context, string, int) error { return nil }
+	return c, b, ttyd
+}
+
+func TestCapturer_Run_Harness(t *testing.T) {
+	c, b, ttyd := newHarnessCapturer(t, &config.Config{
+		ScreenshotInterval: 20 * time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionType, Text: "echo hi", Speed: 5 * time.Millisecond},
+			{Kind: script.ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: script.ActionSleep, Duration: 60 * time.Millisecond},
+			{Kind: script.ActionScreenshot, Name: "done"},
+		},
+	})
+
+	require.NoError(t, c.Run(context.Background()))
+
+	dir := c.config.OutputDir
+	assert.DirExists(t, dir)
+	assert.Contains(t, ttyd.Requests(), "/")
+	assert.Equal(t, []string{
+		"launch",
+		"version",
+		"navigate " + ttyd.URL(),
+		"viewport 1280x720",
+		"terminal",
+		"probe",
+		"key",
+		"key Backspace",
+		"screenshot",
+	}, b.Calls()[:9], "the terminal is probed with a space that is erased again")
+	assert.True(t, b.Closed(), "the browser is closed after the run")
+	assert.Equal(t, "echo hi\n", b.Screen())
+	assert.Equal(t, testutil.BrowserProduct, c.Environment().Browser)
+	assert.Equal(t, EmbeddedFontName, c.Environment().Font)
+	assert.Equal(t, "#terminal-container", c.terminalSelector)
+
+	frames := c.Stats().Frames
+	require.GreaterOrEqual(t, len(frames), 4)
+	assert.Equal(t, FrameInitial, frames[0].Kind)
+	assert.Equal(t, FrameFinal, frames[len(frames)-1].Kind)
+	kinds := map[FrameKind]int{}
+	for i, f := range frames {
+		kinds[f.Kind]++
+		assert.FileExists(t, f.Path)
+		if i > 0 {
+			assert.False(t, f.Time.Before(frames[i-1].Time), "frames are in capture order")
+		}
+	}
+	assert.Equal(t, 1, kinds[FrameExplicit])
+	assert.NotZero(t, kinds[FrameInterval], "interval frames are captured during the actions")
+	assert.FileExists(t, filepath.Join(dir, "done.png"))
+	assert.FileExists(t, filepath.Join(dir, ManifestFilename))
+	assert.NoFileExists(t, filepath.Join(dir, FailureScreenshotFilename))
+	assert.NoFileExists(t, c.outputLockPath())
+}
+
+func TestCapturer_Run_HarnessWaitsForTTyd(t *testing.T) {
+	c, _, ttyd := newHarnessCapturer(t, &config.Config{})
+	ttyd.NotReadyFor(2)
+
+	require.NoError(t, c.Run(context.Background()))
+	assert.Equal(t, []string{"/", "/", "/"}, ttyd.Requests()[:3], "readiness is polled until ttyd answers")
+}
+
+func TestCapturer_Run_LaunchesBrowserWhileTTydStarts(t *testing.T) {
+	c, _, ttyd := newHarnessCapturer(t, &config.Config{})
+	ttyd.NotReadyFor(3)
+
+	require.NoError(t, c.Run(context.Background()))
+
+	phases := map[string]Phase{}
+	for _, p := range c.Stats().Phases {
+		phases[p.Name] = p
+	}
+	attach, browser, navigate := phases["attach"], phases["browser"], phases["navigate"]
+	attachEnd := attach.Start.Add(attach.Duration)
+	assert.True(t, browser.Start.Before(attachEnd), "the browser launches before ttyd is ready")
+	assert.False(t, navigate.Start.Before(attachEnd), "the page opens once ttyd is ready")
+	assert.False(t, navigate.Start.Before(browser.Start.Add(browser.Duration)), "the page opens once the browser is up")
+}
+
+// BenchmarkCapturer_Run_Startup measures the time until the terminal is
+// ready when the browser takes 50ms to launch and ttyd answers its fourth
+// poll, as a cold start does on a CI machine, only faster.
+func BenchmarkCapturer_Run_Startup(b *testing.B) {
+	var startup time.Duration
+	for b.Loop() {
+		c, browser, ttyd := newHarnessCapturer(b, &config.Config{})
+		ttyd.NotReadyFor(3)
+		c.startBrowser = func(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+			time.Sleep(50 * time.Millisecond)
+			return browser.Launch(ctx, allocOpts, browserOpts)
+		}
+		if err := c.Run(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+		startup += c.Stats().Startup
+	}
+	b.ReportMetric(float64(startup.Milliseconds())/float64(b.N), "startup-ms/op")
+}
+
+func TestCapturer_Run_HarnessSelector(t *testing.T) {
+	c, _, _ := newHarnessCapturer(t, &config.Config{
+		Selector: "#terminal-container .xterm-screen",
+		Actions:  []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+	})
+	var checked []string
+	c.findElement = func(_ context.Context, selector string) (bool, error) {
+		checked = append(checked, selector)
+		return len(checked) > 1, nil
+	}
+
+	require.NoError(t, c.Run(context.Background()))
+
+	assert.Equal(t, []string{"#terminal-container .xterm-screen", "#terminal-container .xterm-screen"}, checked,
+		"frames are only taken once the element is visible")
+	assert.NotEmpty(t, c.Stats().Frames)
+}
+
+func TestCapturer_Run_HarnessFailures(t *testing.T) {
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name string
+		step string
+		n    int
+		// wantErr is empty when the failure is only a warning.
+		wantErr string
+		// launched is whether the browser was running when the step failed.
+		launched bool
+		// failureFiles is whether the terminal was saved at the failure.
+		failureFiles bool
+	}{
+		{name: "launch", step: testutil.StepLaunch, wantErr: "launch browser", launched: false},
+		{name: "version check", step: testutil.StepVersion, wantErr: "boom", launched: true},
+		{name: "navigate", step: testutil.StepNavigate, wantErr: "navigate to ttyd", launched: true},
+		{name: "viewport", step: testutil.StepViewport, wantErr: "set viewport", launched: true, failureFiles: true},
+		{name: "terminal", step: testutil.StepTerminal, wantErr: "wait for terminal", launched: true, failureFiles: true},
+		{name: "probe", step: testutil.StepProbe, launched: true},
+		{name: "initial screenshot", step: testutil.StepScreenshot, n: 1, wantErr: "initial screenshot", launched: true, failureFiles: true},
+		{name: "input probe", step: testutil.StepKey, n: 1, wantErr: "probe terminal", launched: true, failureFiles: true},
+		{name: "action", step: testutil.StepKey, n: 3, wantErr: "send key", launched: true, failureFiles: true},
+		{name: "final screenshot", step: testutil.StepScreenshot, n: 2, wantErr: "final screenshot", launched: true, failureFiles: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, b, _ := newHarnessCapturer(t, &config.Config{
+				Actions: []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+			})
+			b.Fail(tt.step, tt.n, boom)
+
+			err := c.Run(context.Background())
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.ErrorIs(t, err, boom)
+			}
+
+			assert.Equal(t, tt.launched, b.Closed(), "a launched browser is always closed")
+			assert.NoFileExists(t, c.outputLockPath(), "the output lock is released")
+			dir := c.config.OutputDir
+			if tt.failureFiles {
+				assert.FileExists(t, filepath.Join(dir, FailureScreenshotFilename))
+				assert.FileExists(t, filepath.Join(dir, FailureTextFilename))
+			} else {
+				assert.NoFileExists(t, filepath.Join(dir, FailureScreenshotFilename))
+			}
+		})
+	}
+}
+
+func TestCapturer_runSession_OnFrame(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{{Kind: script.ActionScreenshot, Name: "menu"}},
+	})
+	var frames []Frame
+	c.OnFrame(func(f Frame) { frames = append(frames, f) })
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	dir := c.config.OutputDir
+	require.Len(t, frames, 3)
+	for i, want := range []struct {
+		name string
+		kind FrameKind
+	}{
+		{"screenshot_001.png", FrameInitial},
+		{"menu.png", FrameExplicit},
+		{"screenshot_002.png", FrameFinal},
+	} {
+		assert.Equal(t, filepath.Join(dir, want.name), frames[i].Path)
+		assert.Equal(t, want.kind, frames[i].Kind)
+		assert.Equal(t, []byte("png"), frames[i].Data)
+		assert.Equal(t, c.Stats().Frames[i].Time, frames[i].Time)
+	}
+}
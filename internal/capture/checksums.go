@@ -0,0 +1,244 @@
+package capture
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChecksumsFilename is the file in the output directory that lists the
+// SHA-256 of every file a successful run produced, in the format of
+// sha256sum, so `sha256sum -c` can check it as well as VerifyChecksums.
+const ChecksumsFilename = "SHA256SUMS"
+
+// checksums collects the SHA-256 of the files a run writes, by path
+// relative to the output directory with forward slashes. It is safe for
+// concurrent use.
+type checksums struct {
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+func newChecksums() *checksums {
+	return &checksums{sums: map[string]string{}}
+}
+
+// add records the checksum of data, just written to rel.
+func (s *checksums) add(rel string, data []byte) {
+	sum := sha256.Sum256(data)
+	s.mu.Lock()
+	s.sums[rel] = hex.EncodeToString(sum[:])
+	s.mu.Unlock()
+}
+
+// has reports whether rel has a checksum.
+func (s *checksums) has(rel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.sums[rel]
+	return ok
+}
+
+// addFile records the checksum of the file at path, known as rel.
+func (s *checksums) addFile(rel, path string) error {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sums[rel] = sum
+	s.mu.Unlock()
+	return nil
+}
+
+// write writes the checksums to the ChecksumsFilename in dir, sorted by
+// path.
+func (s *checksums) write(dir string) error {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.sums))
+	for name := range s.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", s.sums[name], name)
+	}
+	s.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(dir, ChecksumsFilename), []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write checksums: %w", err)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// framesWritten reports whether the encoder writes each frame's data
+// unchanged to its path, so its checksum can be taken from memory.
+func (c *Capturer) framesWritten() bool {
+	switch enc := c.encoder.(type) {
+	case *fileEncoder:
+		return true
+	case *gifEncoder:
+		return enc.meta.KeepFrames
+	}
+	return false
+}
+
+// hashFrame records the checksum of a frame just written to path, unless a
+// frame hook may still change the file; writeChecksums reads those back.
+func (c *Capturer) hashFrame(path string, data []byte) {
+	if c.config.FrameHook == "" && c.framesWritten() {
+		c.sums.add(c.manifestFile(path), data)
+	}
+}
+
+// writeChecksums writes the ChecksumsFilename for a finished run. Files
+// whose data the Capturer never held, such as an animated GIF, a video in
+// the output directory or frames changed by a hook, are read back once the
+// run has finished writing them.
+func (c *Capturer) writeChecksums() error {
+	dir := c.outputDir()
+	var files []string
+	if c.framesWritten() {
+		for _, frame := range c.Stats().Frames {
+			if !frame.Duplicate {
+				files = append(files, frame.Path)
+			}
+		}
+	}
+	c.encMu.Lock()
+	if a, ok := c.encoder.(Artifact); ok && a.Artifact() != "" {
+		files = append(files, a.Artifact())
+	}
+	_, gif := c.encoder.(*gifEncoder)
+	c.encMu.Unlock()
+	if gif {
+		c.mu.Lock()
+		for _, s := range c.scenes {
+			files = append(files, filepath.Join(dir, s.Dir, GIFFilename))
+		}
+		c.mu.Unlock()
+	}
+
+	for _, path := range files {
+		rel := c.manifestFile(path)
+		if c.sums.has(rel) {
+			continue
+		}
+		err := c.sums.addFile(rel, path)
+		if errors.Is(err, fs.ErrNotExist) && filepath.Base(path) == GIFFilename {
+			// A scene without frames has no animation
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("checksum %s: %w", rel, err)
+		}
+	}
+
+	// The video is written straight to where it goes, which may be
+	// outside the output directory
+	if c.video != "" {
+		rel, err := filepath.Rel(c.config.OutputDir, c.video)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			if err := c.sums.addFile(filepath.ToSlash(rel), c.video); err != nil {
+				return fmt.Errorf("checksum %s: %w", rel, err)
+			}
+		}
+	}
+	return c.sums.write(dir)
+}
+
+// ChecksumResult is the outcome of checking one file listed in a
+// ChecksumsFilename.
+type ChecksumResult struct {
+	// File is the path listed, relative to the directory checked.
+	File string
+	// Status is ChecksumOK, ChecksumModified or ChecksumMissing.
+	Status string
+}
+
+// Checksum statuses.
+const (
+	ChecksumOK       = "ok"
+	ChecksumModified = "modified"
+	ChecksumMissing  = "missing"
+)
+
+// VerifyChecksums recomputes the checksum of every file listed in the
+// ChecksumsFilename in dir and compares it with the one recorded, in the
+// order listed. Files in dir that are not listed are not checked.
+func VerifyChecksums(dir string) ([]ChecksumResult, error) {
+	f, err := os.Open(filepath.Join(dir, ChecksumsFilename))
+	if err != nil {
+		return nil, fmt.Errorf("read checksums: %w", err)
+	}
+	defer f.Close()
+
+	var results []ChecksumResult
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		want, name, err := parseChecksumLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", ChecksumsFilename, line, err)
+		}
+		result := ChecksumResult{File: name, Status: ChecksumOK}
+		got, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(name)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			result.Status = ChecksumMissing
+		case err != nil:
+			return nil, fmt.Errorf("checksum %s: %w", name, err)
+		case got != want:
+			result.Status = ChecksumModified
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read checksums: %w", err)
+	}
+	return results, nil
+}
+
+// parseChecksumLine splits a sha256sum line, "hex  name" or, for binary
+// mode, "hex *name", into its checksum and file name. The name may contain
+// spaces, but must stay inside the directory checked.
+func parseChecksumLine(line string) (sum, name string, err error) {
+	sum, name, ok := strings.Cut(line, " ")
+	if !ok || len(sum) != sha256.Size*2 || (!strings.HasPrefix(name, " ") && !strings.HasPrefix(name, "*")) {
+		return "", "", fmt.Errorf("expected a SHA-256 checksum, two spaces and a file name, got %q", line)
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", "", fmt.Errorf("checksum %q is not hexadecimal", sum)
+	}
+	name = name[1:]
+	if name == "" || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", "", fmt.Errorf("file name %q is not inside the directory", name)
+	}
+	return strings.ToLower(sum), name, nil
+}
@@ -0,0 +1,197 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// checksumFiles returns the files listed in the ChecksumsFilename in dir.
+func checksumFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, ChecksumsFilename))
+	require.NoError(t, err)
+	var files []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		_, name, err := parseChecksumLine(line)
+		require.NoError(t, err)
+		files = append(files, name)
+	}
+	return files
+}
+
+func TestCapturer_Run_Checksums(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{
+			name: "frames with a name template",
+			cfg:  config.Config{NameTemplate: "demo shot {n}"},
+			want: []string{"demo shot 1.png", "demo shot 2.png", ManifestFilename},
+		},
+		{
+			name: "staged frames and color vision simulations",
+			cfg:  config.Config{OutTmp: true, SimulateCVD: []string{"deuteranopia"}},
+			want: []string{ManifestFilename, "screenshot_001-deuteranopia.png", "screenshot_001.png", "screenshot_002-deuteranopia.png", "screenshot_002.png"},
+		},
+		{
+			name: "frames changed by a hook",
+			cfg:  config.Config{FrameHook: "printf watermark >> {file}"},
+			want: []string{ManifestFilename, "screenshot_001.png", "screenshot_002.png"},
+		},
+		{
+			name: "gif with a scene",
+			cfg: config.Config{Format: "gif", Actions: []script.Action{
+				{Kind: script.ActionScene, Name: "intro"},
+				{Kind: script.ActionScreenshot, Name: "hello"},
+			}},
+			want: []string{GIFFilename, "intro/" + GIFFilename, ManifestFilename},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			c, _, _ := newHarnessCapturer(t, &cfg)
+			require.NoError(t, c.Run(context.Background()))
+			dir := cfg.OutputDir
+
+			assert.Equal(t, tt.want, checksumFiles(t, dir))
+			results, err := VerifyChecksums(dir)
+			require.NoError(t, err)
+			for _, r := range results {
+				assert.Equal(t, ChecksumOK, r.Status, r.File)
+			}
+
+			// The file is in the format sha256sum checks
+			if _, err := exec.LookPath("sha256sum"); err == nil {
+				cmd := exec.Command("sha256sum", "--check", "--strict", ChecksumsFilename)
+				cmd.Dir = dir
+				out, err := cmd.CombinedOutput()
+				assert.NoError(t, err, string(out))
+			}
+		})
+	}
+}
+
+func TestCapturer_writeChecksums_Video(t *testing.T) {
+	tests := []struct {
+		name   string
+		inside bool
+	}{
+		{name: "in the output directory", inside: true},
+		{name: "elsewhere"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			video := filepath.Join(t.TempDir(), "demo.webm")
+			if tt.inside {
+				video = filepath.Join(dir, "videos", "demo.webm")
+			}
+			c := newFakeCapturer(t, &config.Config{OutputDir: dir, Video: video})
+			c.screencast = func(_ context.Context, onFrame func([]byte)) (func(), error) {
+				onFrame([]byte("jpeg"))
+				return func() {}, nil
+			}
+			c.encodeVideo = func(_ context.Context, _, out string) error {
+				return os.WriteFile(out, []byte("video"), 0o644)
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+			require.NoError(t, c.writeChecksums())
+
+			files := checksumFiles(t, dir)
+			if tt.inside {
+				assert.Contains(t, files, "videos/demo.webm")
+			} else {
+				assert.Equal(t, []string{"screenshot_001.png", "screenshot_002.png"}, files)
+			}
+		})
+	}
+}
+
+func TestCapturer_Run_Checksums_Failure(t *testing.T) {
+	c, _, _ := newHarnessCapturer(t, &config.Config{
+		Actions: []script.Action{{Kind: script.ActionWait, Pattern: "never", Timeout: 10}},
+	})
+
+	require.Error(t, c.Run(context.Background()))
+
+	assert.NoFileExists(t, filepath.Join(c.config.OutputDir, ChecksumsFilename), "a failed run is not vouched for")
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644))
+	}
+	sums := newChecksums()
+	for _, name := range []string{"login 001.png", "intro/screenshot_001.png", "gone.png", "manifest.json"} {
+		write(name, name)
+		sums.add(name, []byte(name))
+	}
+	require.NoError(t, sums.write(dir))
+	write("intro/screenshot_001.png", "retouched")
+	require.NoError(t, os.Remove(filepath.Join(dir, "gone.png")))
+	write("extra.png", "not listed")
+
+	results, err := VerifyChecksums(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []ChecksumResult{
+		{File: "gone.png", Status: ChecksumMissing},
+		{File: "intro/screenshot_001.png", Status: ChecksumModified},
+		{File: "login 001.png", Status: ChecksumOK},
+		{File: "manifest.json", Status: ChecksumOK},
+	}, results)
+}
+
+func TestVerifyChecksums_Errors(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		sums    string
+		wantErr string
+	}{
+		{name: "no checksums file", wantErr: "read checksums"},
+		{name: "not a checksum", sums: "hello  a.png\n", wantErr: "SHA256SUMS line 1: expected a SHA-256 checksum"},
+		{name: "not hexadecimal", sums: strings.Repeat("zz", 32) + "  a.png\n", wantErr: "is not hexadecimal"},
+		{name: "outside the directory", sums: sum + "  ../etc/passwd\n", wantErr: `file name "../etc/passwd" is not inside the directory`},
+		{name: "absolute path", sums: sum + "  /etc/passwd\n", wantErr: "is not inside the directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.sums != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, ChecksumsFilename), []byte(tt.sums), 0o644))
+			}
+			_, err := VerifyChecksums(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseChecksumLine(t *testing.T) {
+	sum := strings.Repeat("AB", 32)
+	got, name, err := parseChecksumLine(sum + " *intro/shot  1.png")
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToLower(sum), got)
+	assert.Equal(t, "intro/shot  1.png", name, "binary mode, and spaces in the name")
+}
@@ -0,0 +1,33 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// cropPNG returns the part of the PNG data inside r, which must lie within
+// the image.
+func cropPNG(data []byte, r image.Rectangle) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+
+	bounds := src.Bounds()
+	if !r.Add(bounds.Min).In(bounds) {
+		return nil, fmt.Errorf("crop %s does not fit in the %dx%d frame", config.FormatCrop(r), bounds.Dx(), bounds.Dy())
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, r.Min.Add(bounds.Min), draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// coordinatePNG is a width x height frame whose pixel at x, y has red x and
+// green y, so a crop shows where it was taken from.
+func coordinatePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	return framePNG(t, width, height, func(x, y int) color.Color {
+		return color.NRGBA{R: uint8(x), G: uint8(y), A: 0xff}
+	})
+}
+
+func TestCropPNG(t *testing.T) {
+	frame := coordinatePNG(t, 40, 20)
+
+	tests := []struct {
+		name    string
+		crop    image.Rectangle
+		wantErr string
+	}{
+		{name: "inner region", crop: image.Rect(5, 3, 25, 13)},
+		{name: "whole frame", crop: image.Rect(0, 0, 40, 20)},
+		{name: "single pixel in the corner", crop: image.Rect(39, 19, 40, 20)},
+		{name: "too wide", crop: image.Rect(30, 0, 41, 10), wantErr: "crop 30,0,11,10 does not fit in the 40x20 frame"},
+		{name: "too tall", crop: image.Rect(0, 15, 10, 25), wantErr: "does not fit in the 40x20 frame"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cropPNG(frame, tt.crop)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(got))
+			require.NoError(t, err)
+			require.Equal(t, image.Rect(0, 0, tt.crop.Dx(), tt.crop.Dy()), img.Bounds())
+			for y := range tt.crop.Dy() {
+				for x := range tt.crop.Dx() {
+					want := color.NRGBA{R: uint8(tt.crop.Min.X + x), G: uint8(tt.crop.Min.Y + y), A: 0xff}
+					require.Equal(t, want, color.NRGBAModel.Convert(img.At(x, y)), "pixel %d,%d", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestCapturer_Crop(t *testing.T) {
+	frame := coordinatePNG(t, 40, 20)
+
+	tests := []struct {
+		name    string
+		padding int
+		// want is the size of every frame written, and corner the color of
+		// its top-left pixel.
+		want   image.Rectangle
+		corner color.NRGBA
+	}{
+		{name: "crop", want: image.Rect(0, 0, 10, 5), corner: color.NRGBA{R: 8, G: 4, A: 0xff}},
+		{name: "crop then padding", padding: 3, want: image.Rect(0, 0, 16, 11), corner: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.Config{OutputDir: dir, Crop: image.Rect(8, 4, 18, 9), Padding: tt.padding}
+			if tt.padding > 0 {
+				cfg.Background = "#fff"
+			}
+			c := newFakeCapturer(t, cfg)
+			c.captureFrame = func(context.Context) ([]byte, error) { return frame, nil }
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			for _, name := range []string{"screenshot_001.png", "screenshot_002.png"} {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				img, err := png.Decode(bytes.NewReader(data))
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, img.Bounds(), name)
+				assert.Equal(t, tt.corner, pixelOf(t, data), name)
+				if tt.padding > 0 {
+					assert.Equal(t, color.NRGBA{R: 8, G: 4, A: 0xff}, color.NRGBAModel.Convert(img.At(tt.padding, tt.padding)), "the crop sits inside the padding")
+				}
+			}
+		})
+	}
+}
+
+func TestCapturer_Crop_OutsideFrame(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{Crop: image.Rect(0, 0, 100, 10)})
+	c.captureFrame = func(context.Context) ([]byte, error) { return coordinatePNG(t, 40, 20), nil }
+
+	ctx := context.Background()
+	err := c.runSession(ctx, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crop frame: crop 0,0,100,10 does not fit in the 40x20 frame")
+}
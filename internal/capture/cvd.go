@@ -0,0 +1,125 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cvdMatrices simulate full-severity color vision deficiencies on linear
+// RGB (Machado, Oliveira and Fernandes, 2009).
+var cvdMatrices = map[string][3][3]float64{
+	"protanopia": {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	"deuteranopia": {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	"tritanopia": {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// linearLevels is the resolution of the linear-to-sRGB lookup table.
+const linearLevels = 4096
+
+var (
+	// toLinear maps an 8-bit sRGB channel to linear light.
+	toLinear [256]float64
+	// toSRGB maps linear light, quantized to linearLevels, back to sRGB.
+	toSRGB [linearLevels + 1]uint8
+)
+
+func init() {
+	for i := range toLinear {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			toLinear[i] = v / 12.92
+		} else {
+			toLinear[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	for i := range toSRGB {
+		v := float64(i) / linearLevels
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		toSRGB[i] = uint8(math.Round(v * 255))
+	}
+}
+
+// simulateCVD returns the PNG or JPEG frame data as seen with the named
+// color vision deficiency, one of config.CVDSimulations, as a PNG.
+func simulateCVD(data []byte, kind string) ([]byte, error) {
+	m, ok := cvdMatrices[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown color vision deficiency %q", kind)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			r, g, b := toLinear[c.R], toLinear[c.G], toLinear[c.B]
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: linearToSRGB(m[0][0]*r + m[0][1]*g + m[0][2]*b),
+				G: linearToSRGB(m[1][0]*r + m[1][1]*g + m[1][2]*b),
+				B: linearToSRGB(m[2][0]*r + m[2][1]*g + m[2][2]*b),
+				A: c.A,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// linearToSRGB clamps a linear channel to [0, 1] and converts it to sRGB.
+func linearToSRGB(v float64) uint8 {
+	return toSRGB[int(math.Round(min(max(v, 0), 1)*linearLevels))]
+}
+
+// cvdPath returns where the kind simulation of the frame at path is
+// written: the same name with a -kind suffix, e.g. frame-0001-tritanopia.png.
+func cvdPath(path, kind string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + kind + ".png"
+}
+
+// writeSimulations writes a copy of the frame for each of
+// Config.SimulateCVD next to path.
+func (c *Capturer) writeSimulations(path string, data []byte) error {
+	for _, kind := range c.config.SimulateCVD {
+		sim, err := simulateCVD(data, kind)
+		if err != nil {
+			return fmt.Errorf("simulate %s: %w", kind, err)
+		}
+		if err := os.WriteFile(cvdPath(path, kind), sim, 0o644); err != nil {
+			return fmt.Errorf("write %s simulation: %w", kind, err)
+		}
+		c.sums.add(c.manifestFile(cvdPath(path, kind)), sim)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// pixelOf decodes PNG data and returns its top-left pixel.
+func pixelOf(t *testing.T, data []byte) color.NRGBA {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+}
+
+func TestSimulateCVD(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	gray := color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+
+	for _, kind := range config.CVDSimulations {
+		t.Run(kind, func(t *testing.T) {
+			sim, err := simulateCVD(testPNG(t, gray), kind)
+			require.NoError(t, err)
+			got := pixelOf(t, sim)
+			assert.InDelta(t, 0x80, int(got.R), 2, "grays are unaffected")
+			assert.InDelta(t, 0x80, int(got.G), 2)
+			assert.InDelta(t, 0x80, int(got.B), 2)
+
+			sim, err = simulateCVD(testPNG(t, red), kind)
+			require.NoError(t, err)
+			assert.NotEqual(t, red, pixelOf(t, sim), "red is shifted")
+		})
+	}
+
+	// Protanopes see pure red as a dark brown
+	sim, err := simulateCVD(testPNG(t, red), "protanopia")
+	require.NoError(t, err)
+	got := pixelOf(t, sim)
+	assert.Less(t, got.R, uint8(0x80))
+	assert.Less(t, got.B, got.G)
+}
+
+func TestSimulateCVD_Errors(t *testing.T) {
+	_, err := simulateCVD(testPNG(t, color.Black), "achromatopsia")
+	assert.ErrorContains(t, err, `unknown color vision deficiency "achromatopsia"`)
+
+	_, err = simulateCVD([]byte("not a png"), "protanopia")
+	assert.ErrorContains(t, err, "decode frame")
+}
+
+func TestCVDPath(t *testing.T) {
+	assert.Equal(t, "/out/frame-0001-tritanopia.png", cvdPath("/out/frame-0001.png", "tritanopia"))
+	assert.Equal(t, "/out/listing-protanopia.png", cvdPath("/out/listing.png", "protanopia"))
+}
+
+func TestCapturer_runSession_SimulateCVD(t *testing.T) {
+	dir := t.TempDir()
+	c := newFakeCapturer(t, &config.Config{
+		OutputDir:   dir,
+		SimulateCVD: []string{"protanopia", "deuteranopia"},
+	})
+	frame := testPNG(t, color.NRGBA{R: 0xff, G: 0x40, A: 0xff})
+	c.captureFrame = func(context.Context) ([]byte, error) { return frame, nil }
+	c.encoder = &fileEncoder{}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{
+		"screenshot_001-deuteranopia.png", "screenshot_001-protanopia.png", "screenshot_001.png",
+		"screenshot_002-deuteranopia.png", "screenshot_002-protanopia.png", "screenshot_002.png",
+	}, names)
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		_, err = png.Decode(bytes.NewReader(data))
+		assert.NoError(t, err, e.Name())
+	}
+}
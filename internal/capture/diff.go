@@ -0,0 +1,124 @@
+package capture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// lastFrame identifies the most recently written frame. Dedup compares new
+// interval frames against its hash; change measurement compares against its
+// decoded image.
+type lastFrame struct {
+	path string
+	sum  [sha256.Size]byte
+	img  image.Image
+}
+
+// measuresChange reports whether frames are compared with their predecessor,
+// which costs an image decode per frame.
+func (c *Capturer) measuresChange() bool {
+	return c.config.Verbose || c.config.FrameDiff
+}
+
+// trackFrameLocked remembers a written frame for Dedup and change
+// measurement, and returns how much it differs from the previous frame.
+// compared is false for the first frame or when a frame cannot be decoded,
+// as webp frames cannot.
+// Callers hold c.encMu.
+func (c *Capturer) trackFrameLocked(filename string, buf []byte) (change float64, compared bool) {
+	if !c.config.Dedup && !c.measuresChange() {
+		return 0, false
+	}
+	prev := c.lastFrame
+	next := lastFrame{path: filename, sum: sha256.Sum256(buf)}
+	defer func() { c.lastFrame = next }()
+	if !c.measuresChange() {
+		return 0, false
+	}
+
+	if prev.img != nil && prev.sum == next.sum {
+		next.img = prev.img
+		return 0, true
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return 0, false
+	}
+	next.img = img
+	if prev.img == nil {
+		return 0, false
+	}
+	return changedPercent(prev.img, img), true
+}
+
+// logChange reports a frame's change from its predecessor in verbose mode.
+// A frame with no change usually means the preceding action's timing is off.
+func (c *Capturer) logChange(filename string, change float64) {
+	if !c.config.Verbose {
+		return
+	}
+	if change == 0 {
+		fmt.Fprintf(os.Stderr, "Frame %s: 0.0%% change (same as previous frame; check the preceding Sleep or delay)\n", filepath.Base(filename))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Frame %s: %.1f%% change\n", filepath.Base(filename), change)
+}
+
+// changedPercent returns the percentage of pixels that differ between a and
+// b. Images of different sizes are 100% changed.
+func changedPercent(a, b image.Image) float64 {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return 100
+	}
+	total := ab.Dx() * ab.Dy()
+	if total == 0 {
+		return 0
+	}
+
+	changed := 0
+	if pa, pb, ok := pixels(a, b); ok {
+		for i := 0; i < len(pa); i += 4 {
+			if pa[i] != pb[i] || pa[i+1] != pb[i+1] || pa[i+2] != pb[i+2] || pa[i+3] != pb[i+3] {
+				changed++
+			}
+		}
+	} else {
+		for y := 0; y < ab.Dy(); y++ {
+			for x := 0; x < ab.Dx(); x++ {
+				r1, g1, b1, a1 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+				r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+				if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+					changed++
+				}
+			}
+		}
+	}
+	return float64(changed) * 100 / float64(total)
+}
+
+// pixels returns the raw 4-byte-per-pixel data of a and b when both use the
+// same packed, unpadded layout, so they can be compared byte by byte.
+func pixels(a, b image.Image) ([]byte, []byte, bool) {
+	switch a := a.(type) {
+	case *image.NRGBA:
+		if b, ok := b.(*image.NRGBA); ok && packed(a.Stride, a.Rect, len(a.Pix)) && packed(b.Stride, b.Rect, len(b.Pix)) {
+			return a.Pix, b.Pix, true
+		}
+	case *image.RGBA:
+		if b, ok := b.(*image.RGBA); ok && packed(a.Stride, a.Rect, len(a.Pix)) && packed(b.Stride, b.Rect, len(b.Pix)) {
+			return a.Pix, b.Pix, true
+		}
+	}
+	return nil, nil, false
+}
+
+// packed reports whether a 4-byte-per-pixel buffer holds exactly rect's
+// pixels with no row padding.
+func packed(stride int, rect image.Rectangle, n int) bool {
+	return stride == 4*rect.Dx() && n == stride*rect.Dy()
+}
@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestChangedPercent(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+
+	fill := func(img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}, c color.Color) image.Image {
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	quarterBlue := fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red).(*image.RGBA)
+	quarterBlue.Set(0, 0, blue)
+	quarterBlue.Set(1, 0, blue)
+
+	tests := []struct {
+		name string
+		a, b image.Image
+		want float64
+	}{
+		{
+			name: "identical",
+			a:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red),
+			b:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red),
+			want: 0,
+		},
+		{
+			name: "every pixel",
+			a:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red),
+			b:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), blue),
+			want: 100,
+		},
+		{
+			name: "some pixels",
+			a:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red),
+			b:    quarterBlue,
+			want: 25,
+		},
+		{
+			name: "mixed image types",
+			a:    fill(image.NewNRGBA(image.Rect(0, 0, 4, 2)), red),
+			b:    quarterBlue,
+			want: 25,
+		},
+		{
+			name: "different sizes",
+			a:    fill(image.NewRGBA(image.Rect(0, 0, 4, 2)), red),
+			b:    fill(image.NewRGBA(image.Rect(0, 0, 2, 2)), red),
+			want: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, changedPercent(tt.a, tt.b), 0.001)
+		})
+	}
+}
+
+func TestCapturer_FrameChange(t *testing.T) {
+	red := testPNG(t, color.RGBA{R: 0xff, A: 0xff})
+	blue := testPNG(t, color.RGBA{B: 0xff, A: 0xff})
+
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		frames      [][]byte
+		wantChanges []float64
+		wantCompare []bool
+	}{
+		{
+			name:        "measures change from the previous frame",
+			cfg:         &config.Config{FrameDiff: true},
+			frames:      [][]byte{red, blue, blue},
+			wantChanges: []float64{0, 100, 0},
+			wantCompare: []bool{false, true, true},
+		},
+		{
+			name:        "off by default",
+			cfg:         &config.Config{},
+			frames:      [][]byte{red, blue},
+			wantChanges: []float64{0, 0},
+			wantCompare: []bool{false, false},
+		},
+		{
+			name:        "undecodable frames are not compared",
+			cfg:         &config.Config{FrameDiff: true},
+			frames:      [][]byte{red, []byte("png"), blue},
+			wantChanges: []float64{0, 0, 0},
+			wantCompare: []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, tt.cfg)
+			c.encoder = &recordingEncoder{}
+			next := 0
+			c.captureFrame = func(context.Context) ([]byte, error) {
+				buf := tt.frames[next]
+				next++
+				return buf, nil
+			}
+
+			for range tt.frames {
+				require.NoError(t, c.captureScreenshot(context.Background(), "", FrameInterval))
+			}
+
+			frames := c.Stats().Frames
+			require.Len(t, frames, len(tt.frames))
+			for i, f := range frames {
+				assert.InDelta(t, tt.wantChanges[i], f.Change, 0.001, "frame %d", i)
+				assert.Equal(t, tt.wantCompare[i], f.Compared, "frame %d", i)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// CheckStatus is the outcome of a doctor Check.
+type CheckStatus string
+
+const (
+	// CheckPass means the requirement is met.
+	CheckPass CheckStatus = "pass"
+	// CheckWarn means captures work, but maybe not as configured.
+	CheckWarn CheckStatus = "warn"
+	// CheckFail means captures cannot work until it is fixed.
+	CheckFail CheckStatus = "fail"
+)
+
+// CheckResult is what a Check found.
+type CheckResult struct {
+	Status CheckStatus
+	// Detail says what was found, such as a version or a path.
+	Detail string
+	// Hint says how to fix a failure or warning; empty when it passed.
+	Hint string
+}
+
+// Check is one thing `scr doctor` verifies. Run must not leave anything
+// behind.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) CheckResult
+}
+
+// smokeTimeout bounds the smoke capture, startup included.
+const smokeTimeout = 30 * time.Second
+
+// DoctorChecks returns the checks `scr doctor` runs, in order, for the
+// ttyd port, Chrome path and output directory of cfg. With smoke, a last
+// check captures `echo ok`.
+func DoctorChecks(cfg *config.Config, smoke bool) []Check {
+	checks := []Check{
+		{Name: "ttyd", Run: func(context.Context) CheckResult { return checkTTyd() }},
+		{Name: "Chrome", Run: func(context.Context) CheckResult { return checkChrome(cfg.ChromePath) }},
+		{Name: "port", Run: func(context.Context) CheckResult { return checkPort(cfg.TTydPort) }},
+		{Name: "output directory", Run: func(context.Context) CheckResult { return checkOutputDir(cfg.OutputDir) }},
+	}
+	if smoke {
+		checks = append(checks, Check{Name: "smoke capture", Run: func(ctx context.Context) CheckResult {
+			return checkSmoke(ctx, cfg.TTydPort, cfg.ChromePath)
+		}})
+	}
+	return checks
+}
+
+// checkTTyd checks that ttyd is in PATH and new enough.
+func checkTTyd() CheckResult {
+	path, err := exec.LookPath("ttyd")
+	if err != nil {
+		return CheckResult{
+			Status: CheckFail,
+			Detail: "ttyd not found in PATH",
+			Hint:   "install ttyd (brew install ttyd, apt install ttyd, or see https://github.com/tsl0922/ttyd) and make sure it is in PATH",
+		}
+	}
+	version := ttydVersion()
+	if err := checkTTydVersion(version); err != nil {
+		return CheckResult{Status: CheckFail, Detail: err.Error(), Hint: "upgrade ttyd"}
+	}
+	return CheckResult{Status: CheckPass, Detail: fmt.Sprintf("ttyd %s at %s", version, path)}
+}
+
+// checkChrome checks that a browser is found where a capture looks for
+// one.
+func checkChrome(chromePath string) CheckResult {
+	path, err := findChrome(chromePath)
+	if err != nil {
+		return CheckResult{
+			Status: CheckFail,
+			Detail: err.Error(),
+			Hint:   "install Google Chrome or Chromium, or pass --chrome-path with its executable",
+		}
+	}
+	return CheckResult{Status: CheckPass, Detail: path}
+}
+
+// checkPort checks that ttyd's port is free. A busy port only warns, since
+// a capture without -p picks a free one instead.
+func checkPort(port int) CheckResult {
+	if portFree(port) {
+		return CheckResult{Status: CheckPass, Detail: fmt.Sprintf("port %d is free", port)}
+	}
+	return CheckResult{
+		Status: CheckWarn,
+		Detail: fmt.Sprintf("port %d is in use", port),
+		Hint:   "captures pick a free port unless -p is given; stop what listens there to use it",
+	}
+}
+
+// checkOutputDir checks that files can be created in dir, or, when it does
+// not exist yet, in the directory it would be created in.
+func checkOutputDir(dir string) CheckResult {
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return CheckResult{Status: CheckFail, Detail: fmt.Sprintf("%s is not a directory", existing), Hint: "pass a different -o"}
+			}
+			break
+		}
+		parent := filepath.Dir(existing)
+		if !errors.Is(err, fs.ErrNotExist) || parent == existing {
+			return CheckResult{Status: CheckFail, Detail: err.Error(), Hint: "pass a different -o"}
+		}
+		existing = parent
+	}
+
+	f, err := os.CreateTemp(existing, ".scr-doctor-*")
+	if err != nil {
+		return CheckResult{
+			Status: CheckFail,
+			Detail: fmt.Sprintf("cannot write to %s: %v", existing, err),
+			Hint:   "fix its permissions or pass a different -o",
+		}
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+
+	if existing != dir {
+		return CheckResult{Status: CheckPass, Detail: fmt.Sprintf("%s will be created in %s", dir, existing)}
+	}
+	return CheckResult{Status: CheckPass, Detail: dir + " is writable"}
+}
+
+// checkSmoke captures `echo ok` into a temporary directory, which is
+// removed afterwards, with ttyd on port or a free one.
+func checkSmoke(ctx context.Context, port int, chromePath string) CheckResult {
+	dir, err := os.MkdirTemp("", "scr-doctor-")
+	if err != nil {
+		return CheckResult{Status: CheckFail, Detail: err.Error(), Hint: "check that the temporary directory is writable"}
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, smokeTimeout)
+	defer cancel()
+	c := NewCapturer(&config.Config{
+		Command:    "echo ok",
+		OutputDir:  dir,
+		TTydPort:   port,
+		AutoPort:   true,
+		ChromePath: chromePath,
+		Actions:    []script.Action{{Kind: script.ActionWait, Pattern: "ok", Timeout: 2 * time.Second}},
+	})
+	if err := c.Run(ctx); err != nil {
+		return CheckResult{
+			Status: CheckFail,
+			Detail: err.Error(),
+			Hint:   "rerun a capture with --verbose --log scr.log to see where it stops",
+		}
+	}
+	stats := c.Stats()
+	return CheckResult{Status: CheckPass, Detail: fmt.Sprintf("captured %d frames in %v", len(stats.Frames), stats.Total.Round(time.Millisecond))}
+}
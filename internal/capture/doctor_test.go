@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestDoctorChecks(t *testing.T) {
+	names := func(checks []Check) []string {
+		var out []string
+		for _, c := range checks {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+
+	cfg := &config.Config{OutputDir: t.TempDir(), TTydPort: 7681}
+	assert.Equal(t, []string{"ttyd", "Chrome", "port", "output directory"}, names(DoctorChecks(cfg, false)))
+	assert.Equal(t, []string{"ttyd", "Chrome", "port", "output directory", "smoke capture"}, names(DoctorChecks(cfg, true)))
+}
+
+func TestCheckTTyd(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		want       CheckStatus
+		wantDetail string
+	}{
+		{name: "supported", version: "1.7.4-68c0ddb", want: CheckPass, wantDetail: "ttyd 1.7.4-68c0ddb at "},
+		{name: "too old", version: "1.5.2", want: CheckFail, wantDetail: "ttyd 1.5.2 found, 1.7.2 required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubTTyd(t, helpWithWritable, tt.version)
+			result := checkTTyd()
+			assert.Equal(t, tt.want, result.Status)
+			assert.Contains(t, result.Detail, tt.wantDetail)
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		result := checkTTyd()
+		assert.Equal(t, CheckFail, result.Status)
+		assert.Equal(t, "ttyd not found in PATH", result.Detail)
+		assert.Contains(t, result.Hint, "install ttyd")
+	})
+}
+
+func TestCheckChrome(t *testing.T) {
+	path := fakeChrome(t)
+	assert.Equal(t, CheckResult{Status: CheckPass, Detail: path}, checkChrome(path))
+
+	result := checkChrome(filepath.Join(t.TempDir(), "chromium"))
+	assert.Equal(t, CheckFail, result.Status)
+	assert.Contains(t, result.Detail, ErrChromeNotFound.Error())
+	assert.Contains(t, result.Hint, "--chrome-path")
+}
+
+func TestCheckPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	result := checkPort(port)
+	assert.Equal(t, CheckWarn, result.Status, "a busy port only warns")
+	assert.Contains(t, result.Hint, "-p")
+
+	require.NoError(t, ln.Close())
+	assert.Equal(t, CheckPass, checkPort(port).Status)
+}
+
+func TestCheckOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	tests := []struct {
+		name       string
+		dir        string
+		want       CheckStatus
+		wantDetail string
+	}{
+		{name: "existing", dir: dir, want: CheckPass, wantDetail: dir + " is writable"},
+		{name: "created on first run", dir: filepath.Join(dir, "a", "b"), want: CheckPass, wantDetail: "will be created in " + dir},
+		{name: "a file", dir: file, want: CheckFail, wantDetail: "is not a directory"},
+		{name: "below a file", dir: filepath.Join(file, "out"), want: CheckFail, wantDetail: "not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkOutputDir(tt.dir)
+			assert.Equal(t, tt.want, result.Status)
+			assert.Contains(t, result.Detail, tt.wantDetail)
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "nothing is left behind")
+}
+
+func TestCheckSmoke_NoChrome(t *testing.T) {
+	result := checkSmoke(context.Background(), 7681, filepath.Join(t.TempDir(), "chromium"))
+	assert.Equal(t, CheckFail, result.Status)
+	assert.Contains(t, result.Detail, ErrChromeNotFound.Error())
+}
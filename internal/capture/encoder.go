@@ -0,0 +1,140 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFormat is the output format used when none is configured.
+const DefaultFormat = "png"
+
+// Meta describes a capture run to an Encoder.
+type Meta struct {
+	OutputDir string
+	Command   string
+	Script    string
+	Interval  time.Duration
+	// FrameDelay fixes the delay between frames in animated formats,
+	// except within a burst; zero means the real time between captures.
+	FrameDelay time.Duration
+	// KeepFrames asks animated formats to also write each frame as a PNG.
+	KeepFrames bool
+	// TargetFrames, when positive, asks animated formats to leave out
+	// interval frames to come to about this many; see sampleFrames.
+	TargetFrames int
+}
+
+// Frame is a single captured terminal image.
+type Frame struct {
+	// Path is the file path the Capturer assigned to this frame. Encoders
+	// that write one file per frame use it; others may ignore it.
+	Path string
+	// Data is the encoded image: PNG, or JPEG or WebP when Config.Format
+	// selects one.
+	Data []byte
+	// Kind says what the frame was captured for.
+	Kind FrameKind
+	// Time is the wall-clock time the frame was captured.
+	Time time.Time
+	// Offset is the capture time relative to the terminal becoming ready.
+	Offset time.Duration
+	// Scene is the directory of the Scene the frame belongs to, relative
+	// to Meta.OutputDir, or "" for frames before the first Scene.
+	Scene string
+	// Burst numbers the Burst action the frame belongs to, from 1, or is 0
+	// for frames outside a burst.
+	Burst int
+}
+
+// Encoder turns captured frames into an output artifact. The Capturer calls
+// Begin once, Frame for every captured image, and End once when the run
+// finishes. Calls are serialized, so implementations need no locking.
+type Encoder interface {
+	Begin(meta Meta) error
+	Frame(f Frame) error
+	End() error
+}
+
+// Artifact is implemented by encoders that write a single output file, so
+// callers can report where it went.
+type Artifact interface {
+	// Artifact returns the path written by End, or "" if nothing was written.
+	Artifact() string
+}
+
+// Sampler is implemented by encoders that may leave frames out of their
+// output, so the manifest can mark them.
+type Sampler interface {
+	// Dropped returns the paths of the frames End left out.
+	Dropped() []string
+}
+
+// EncoderFactory creates a fresh Encoder for a single run.
+type EncoderFactory func() Encoder
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFactory{
+		DefaultFormat: func() Encoder { return &fileEncoder{} },
+		"jpeg":        func() Encoder { return &fileEncoder{} },
+		"webp":        func() Encoder { return &fileEncoder{} },
+		"gif":         func() Encoder { return &gifEncoder{} },
+	}
+)
+
+// RegisterEncoder makes an output format available under name, replacing any
+// existing registration. Names are case-insensitive.
+func RegisterEncoder(name string, factory EncoderFactory) {
+	if name == "" || factory == nil {
+		panic("RegisterEncoder: name and factory must be set")
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[strings.ToLower(name)] = factory
+}
+
+// NewEncoder returns a new Encoder for the named format. An empty name
+// selects DefaultFormat.
+func NewEncoder(name string) (Encoder, error) {
+	if name == "" {
+		name = DefaultFormat
+	}
+	encodersMu.RLock()
+	factory, ok := encoders[strings.ToLower(name)]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(EncoderNames(), ", "))
+	}
+	return factory(), nil
+}
+
+// EncoderNames returns the registered format names in sorted order.
+func EncoderNames() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileEncoder writes every frame as its own image file at Frame.Path, in
+// the format it was captured in.
+type fileEncoder struct{}
+
+func (e *fileEncoder) Begin(Meta) error { return nil }
+
+func (e *fileEncoder) Frame(f Frame) error {
+	if err := os.WriteFile(f.Path, f.Data, 0o644); err != nil {
+		return fmt.Errorf("write screenshot: %w", err)
+	}
+	return nil
+}
+
+func (e *fileEncoder) End() error { return nil }
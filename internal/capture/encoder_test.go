@@ -0,0 +1,204 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// framePNG encodes a width x height frame whose pixel at x, y is
+// pixel(x, y), for tests of the steps that redraw frames.
+func framePNG(t *testing.T, width, height int, pixel func(x, y int) color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, pixel(x, y))
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// recordingEncoder records the calls it receives.
+type recordingEncoder struct {
+	meta   Meta
+	frames []Frame
+	begun  bool
+	ended  bool
+}
+
+func (e *recordingEncoder) Begin(meta Meta) error {
+	e.meta = meta
+	e.begun = true
+	return nil
+}
+
+func (e *recordingEncoder) Frame(f Frame) error {
+	e.frames = append(e.frames, f)
+	return nil
+}
+
+func (e *recordingEncoder) End() error {
+	e.ended = true
+	return nil
+}
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    Encoder
+		wantErr string
+	}{
+		{name: "empty name selects png", format: "", want: &fileEncoder{}},
+		{name: "png", format: "png", want: &fileEncoder{}},
+		{name: "case-insensitive", format: "PNG", want: &fileEncoder{}},
+		{name: "jpeg", format: "jpeg", want: &fileEncoder{}},
+		{name: "webp", format: "webp", want: &fileEncoder{}},
+		{name: "unknown format", format: "bmp", wantErr: `unknown format "bmp" (available: `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEncoder(tt.format)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("Recording", func() Encoder { return &recordingEncoder{} })
+	t.Cleanup(func() {
+		encodersMu.Lock()
+		delete(encoders, "recording")
+		encodersMu.Unlock()
+	})
+
+	assert.Contains(t, EncoderNames(), "recording")
+	assert.Contains(t, EncoderNames(), "png")
+
+	enc, err := NewEncoder("recording")
+	require.NoError(t, err)
+	assert.IsType(t, &recordingEncoder{}, enc)
+
+	assert.Panics(t, func() { RegisterEncoder("", nil) })
+}
+
+func TestPNGEncoder_Frame(t *testing.T) {
+	dir := t.TempDir()
+	enc := &fileEncoder{}
+
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir}))
+	path := filepath.Join(dir, "screenshot_001.png")
+	require.NoError(t, enc.Frame(Frame{Path: path, Data: []byte("png"), Time: time.Now()}))
+	require.NoError(t, enc.End())
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), got)
+
+	err = enc.Frame(Frame{Path: filepath.Join(dir, "missing", "x.png"), Data: []byte("png")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "write screenshot")
+}
+
+func TestCapturer_runSession_UsesEncoder(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Command: "bash",
+		Script:  "Type 'a'",
+		Actions: []script.Action{{Kind: script.ActionType, Text: "a"}},
+	})
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	assert.True(t, enc.begun)
+	assert.True(t, enc.ended)
+	assert.Equal(t, "bash", enc.meta.Command)
+	assert.Equal(t, "Type 'a'", enc.meta.Script)
+	require.Len(t, enc.frames, 2, "initial and final frames")
+	assert.Equal(t, filepath.Join(c.config.OutputDir, "screenshot_001.png"), enc.frames[0].Path)
+	assert.Equal(t, filepath.Join(c.config.OutputDir, "screenshot_002.png"), enc.frames[1].Path)
+	assert.Equal(t, []byte("png"), enc.frames[0].Data)
+}
+
+func TestCapturer_Run_UnknownFormat(t *testing.T) {
+	c := NewCapturer(&config.Config{
+		Command:   "bash",
+		TTydPort:  8080,
+		OutputDir: t.TempDir(),
+		Format:    "bmp",
+	})
+
+	err := c.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output format")
+}
+
+func TestCapturer_Screenshots(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		encoder    Encoder
+		wantFrames bool
+	}{
+		{name: "per-frame format", cfg: &config.Config{}, encoder: &fileEncoder{}, wantFrames: true},
+		{name: "single artifact", cfg: &config.Config{}, encoder: &gifEncoder{}, wantFrames: false},
+		{name: "single artifact keeping frames", cfg: &config.Config{KeepFrames: true}, encoder: &gifEncoder{}, wantFrames: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, tt.cfg)
+			c.encoder = &recordingEncoder{}
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+			c.encoder = tt.encoder
+
+			if !tt.wantFrames {
+				assert.Empty(t, c.Screenshots())
+				return
+			}
+			assert.Equal(t, []string{
+				filepath.Join(c.config.OutputDir, "screenshot_001.png"),
+				filepath.Join(c.config.OutputDir, "screenshot_002.png"),
+			}, c.Screenshots())
+		})
+	}
+}
+
+func TestCapturer_Result(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	c.encoder = &recordingEncoder{}
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	c.encoder = &gifEncoder{}
+
+	got := c.Result()
+	assert.Empty(t, got.Screenshots, "the frames went into the GIF")
+	assert.Equal(t, 2, got.Frames)
+	assert.Equal(t, c.Stats().Total, got.Duration)
+	assert.Empty(t, got.Video)
+}
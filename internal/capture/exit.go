@@ -0,0 +1,168 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// ExitError reports that the captured command exited with a non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// commandExit reports when the captured command has exited. TTydServer
+// implements it; tests substitute their own.
+type commandExit interface {
+	Done() <-chan struct{}
+	ExitCode() (code int, ok bool)
+}
+
+// untilExit returns a context that is canceled when ctx is, or when the
+// command exits. exited reports whether the latter happened.
+func (c *Capturer) untilExit(ctx context.Context) (actx context.Context, exited func() bool, cancel context.CancelFunc) {
+	actx, cancel = context.WithCancel(ctx)
+	if c.command == nil {
+		return actx, func() bool { return false }, cancel
+	}
+	done := c.command.Done()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-actx.Done():
+		}
+	}()
+	return actx, func() bool {
+		select {
+		case <-done:
+			return ctx.Err() == nil
+		default:
+			return false
+		}
+	}, cancel
+}
+
+// exitResult logs how the command exited and returns an ExitError for a
+// non-zero code.
+func (c *Capturer) exitResult() error {
+	code, ok := c.command.ExitCode()
+	if c.config.Verbose {
+		if ok {
+			fmt.Fprintf(os.Stderr, "Command exited with code %d, stopping capture\n", code)
+		} else {
+			fmt.Fprintf(os.Stderr, "ttyd exited, stopping capture\n")
+		}
+	}
+	if ok && code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// commandFailure returns an ExitError when the command has exited with a
+// non-zero code, and nil while it runs, after it succeeded, or when ttyd went
+// away without reporting a code.
+func (c *Capturer) commandFailure() error {
+	if c.command == nil {
+		return nil
+	}
+	code, ok := c.command.ExitCode()
+	if !ok || code == 0 {
+		return nil
+	}
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Command exited with code %d\n", code)
+	}
+	return &ExitError{Code: code}
+}
+
+// processExitRegex matches the notice ttyd logs when the command it runs
+// for a client exits. ttyd itself keeps running afterwards.
+var processExitRegex = regexp.MustCompile(`process exited with code (-?\d+)`)
+
+// exitState records when the command, or ttyd itself, has exited. The zero
+// value is not usable; create one with newExitState.
+type exitState struct {
+	done chan struct{}
+	once sync.Once
+
+	mu    sync.Mutex
+	code  int
+	known bool
+}
+
+func newExitState() *exitState {
+	return &exitState{done: make(chan struct{})}
+}
+
+// exit records the exit; only the first call has effect. known is false
+// when ttyd went away without reporting a code.
+func (e *exitState) exit(code int, known bool) {
+	e.once.Do(func() {
+		e.mu.Lock()
+		e.code, e.known = code, known
+		e.mu.Unlock()
+		close(e.done)
+	})
+}
+
+// exitWatcher scans ttyd's log output line by line for the process exit
+// notice.
+type exitWatcher struct {
+	state *exitState
+	line  []byte
+}
+
+func (w *exitWatcher) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		if m := processExitRegex.FindSubmatch(w.line[:i]); m != nil {
+			code, _ := strconv.Atoi(string(m[1]))
+			w.state.exit(code, true)
+		}
+		w.line = w.line[i+1:]
+	}
+	// Only a partial line is kept; an overlong one is not a notice
+	if len(w.line) > 4096 {
+		w.line = w.line[:0]
+	}
+	return len(p), nil
+}
+
+// Done returns a channel that is closed once the command has exited, or
+// ttyd itself has stopped. Before Start it returns nil, which never fires.
+func (s *TTydServer) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exit == nil {
+		return nil
+	}
+	return s.exit.done
+}
+
+// ExitCode returns the command's exit code once Done is closed. ok is false
+// while the command runs, or when ttyd stopped without reporting a code.
+func (s *TTydServer) ExitCode() (code int, ok bool) {
+	s.mu.Lock()
+	exit := s.exit
+	s.mu.Unlock()
+	if exit == nil {
+		return 0, false
+	}
+	exit.mu.Lock()
+	defer exit.mu.Unlock()
+	return exit.code, exit.known
+}
@@ -0,0 +1,207 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestExitWatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		writes    []string
+		wantDone  bool
+		wantCode  int
+		wantKnown bool
+	}{
+		{
+			name:     "no notice",
+			writes:   []string{"[2024/01/01 10:00:00:0000] N: client connected\n"},
+			wantDone: false,
+		},
+		{
+			name:      "zero exit",
+			writes:    []string{"N: process exited with code 0, pid: 1234\n"},
+			wantDone:  true,
+			wantCode:  0,
+			wantKnown: true,
+		},
+		{
+			name:      "notice split across writes",
+			writes:    []string{"N: process exited wi", "th code 2", "7, pid: 1234\n"},
+			wantDone:  true,
+			wantCode:  27,
+			wantKnown: true,
+		},
+		{
+			name:     "incomplete line is not matched",
+			writes:   []string{"N: process exited with code 1"},
+			wantDone: false,
+		},
+		{
+			name:      "first notice wins",
+			writes:    []string{"process exited with code 2\nprocess exited with code 0\n"},
+			wantDone:  true,
+			wantCode:  2,
+			wantKnown: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TTydServer{exit: newExitState()}
+			w := &exitWatcher{state: s.exit}
+			for _, p := range tt.writes {
+				n, err := w.Write([]byte(p))
+				require.NoError(t, err)
+				assert.Equal(t, len(p), n)
+			}
+
+			select {
+			case <-s.Done():
+				assert.True(t, tt.wantDone, "Done closed")
+			default:
+				assert.False(t, tt.wantDone, "Done not closed")
+			}
+			code, known := s.ExitCode()
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantKnown, known)
+		})
+	}
+}
+
+func TestTTydServer_DoneBeforeStart(t *testing.T) {
+	s := NewTTydServer("bash", 7681)
+	assert.Nil(t, s.Done())
+	_, ok := s.ExitCode()
+	assert.False(t, ok)
+}
+
+// fakeCommand is a commandExit that exits when told to.
+type fakeCommand struct {
+	state *exitState
+}
+
+func (f *fakeCommand) Done() <-chan struct{} { return f.state.done }
+func (f *fakeCommand) ExitCode() (int, bool) { return f.state.code, f.state.known }
+
+func TestCapturer_runSession_ExitOnDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		known   bool
+		wantErr *ExitError
+	}{
+		{name: "zero exit ends the capture", code: 0, known: true},
+		{name: "non-zero exit is reported", code: 3, known: true, wantErr: &ExitError{Code: 3}},
+		{name: "ttyd gone without a code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{
+				ExitOnDone: true,
+				Actions: []script.Action{
+					{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+					{Kind: script.ActionSleep, Duration: time.Minute},
+					{Kind: script.ActionKey, Key: "q", Repeat: 1},
+				},
+			})
+			var keys []string
+			c.sendKey = func(_ context.Context, key string) error {
+				keys = append(keys, key)
+				return nil
+			}
+			cmd := &fakeCommand{state: newExitState()}
+			c.command = cmd
+			enc := &recordingEncoder{}
+			c.encoder = enc
+
+			time.AfterFunc(20*time.Millisecond, func() { cmd.state.exit(tt.code, tt.known) })
+
+			ctx := context.Background()
+			start := time.Now()
+			err := c.runSession(ctx, ctx)
+			assert.Less(t, time.Since(start), 10*time.Second, "the Sleep was cut short")
+
+			if tt.wantErr != nil {
+				var exitErr *ExitError
+				require.True(t, errors.As(err, &exitErr), "got %v", err)
+				assert.Equal(t, tt.wantErr, exitErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, []string{"enter"}, keys, "actions after the exit are skipped")
+			assert.Len(t, enc.frames, 2, "initial and final frames")
+		})
+	}
+}
+
+func TestCapturer_runSession_ExitIgnoredWithoutExitOnDone(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionSleep, Duration: 30 * time.Millisecond},
+		},
+	})
+	cmd := &fakeCommand{state: newExitState()}
+	cmd.state.exit(1, true)
+	c.command = cmd
+
+	ctx := context.Background()
+	assert.NoError(t, c.runSession(ctx, ctx))
+}
+
+func TestCapturer_runSession_FailOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		exit    func(*exitState)
+		wantErr *ExitError
+	}{
+		{name: "command exited 1", exit: func(s *exitState) { s.exit(1, true) }, wantErr: &ExitError{Code: 1}},
+		{name: "command succeeded", exit: func(s *exitState) { s.exit(0, true) }},
+		{name: "command still running", exit: func(*exitState) {}},
+		{name: "ttyd gone without a code", exit: func(s *exitState) { s.exit(0, false) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{
+				FailOnError: true,
+				Actions: []script.Action{
+					{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+					{Kind: script.ActionSleep, Duration: 30 * time.Millisecond},
+					{Kind: script.ActionKey, Key: "q", Repeat: 1},
+				},
+			})
+			var keys []string
+			c.sendKey = func(_ context.Context, key string) error {
+				keys = append(keys, key)
+				return nil
+			}
+			cmd := &fakeCommand{state: newExitState()}
+			tt.exit(cmd.state)
+			c.command = cmd
+			enc := &recordingEncoder{}
+			c.encoder = enc
+
+			ctx := context.Background()
+			err := c.runSession(ctx, ctx)
+
+			if tt.wantErr != nil {
+				var exitErr *ExitError
+				require.True(t, errors.As(err, &exitErr), "got %v", err)
+				assert.Equal(t, tt.wantErr, exitErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, []string{"enter", "q"}, keys, "the exit does not cut the script short")
+			assert.Len(t, enc.frames, 2, "initial and final frames")
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// FailureScreenshotFilename and FailureTextFilename are written to the
+// output directory when a run fails after the terminal page has loaded:
+// the terminal as it looked, and its text.
+const (
+	FailureScreenshotFilename = "failure.png"
+	FailureTextFilename       = "failure.txt"
+)
+
+// failureCaptureTimeout bounds the failure capture, so a browser that is
+// no longer responding cannot hold up shutdown.
+const failureCaptureTimeout = 5 * time.Second
+
+// captureFailure saves a last screenshot and the terminal text after a run
+// failed with runErr. It is best effort: problems are only reported on
+// stderr, and the run's error is returned unchanged by the caller.
+func (c *Capturer) captureFailure(ctx context.Context, runErr error) {
+	ctx, cancel := context.WithTimeout(ctx, failureCaptureTimeout)
+	defer cancel()
+
+	// The screenshot is as captured: in the frame format when the browser
+	// writes it, and PNG otherwise
+	name := FailureScreenshotFilename
+	if c.nativeFrames() {
+		name = config.WithImageExt(name, c.config.ImageFormat())
+	}
+	var saved []string
+	if buf, err := c.captureFrame(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failure screenshot: %v\n", err)
+	} else if path, err := c.writeFailureFile(name, buf); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failure screenshot: %v\n", err)
+	} else {
+		saved = append(saved, path)
+	}
+
+	if text, err := c.readText(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failure terminal text: %v\n", err)
+	} else {
+		dump := fmt.Sprintf("error: %v\n\n%s\n", runErr, strings.TrimRight(text, "\n"))
+		dump = c.anonymizer.replace(dump)
+		if path, err := c.writeFailureFile(FailureTextFilename, []byte(dump)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failure terminal text: %v\n", err)
+		} else {
+			saved = append(saved, path)
+		}
+	}
+
+	if len(saved) > 0 {
+		fmt.Fprintf(os.Stderr, "Saved the terminal at the time of failure to %s\n", strings.Join(saved, " and "))
+	}
+}
+
+// writeFailureFile writes data to name in the output directory and returns
+// where the file ends up.
+func (c *Capturer) writeFailureFile(name string, data []byte) (string, error) {
+	path := filepath.Join(c.outputDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return c.finalPath(path), nil
+}
@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestCapturer_captureFailure(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	c.readText = func(context.Context) (string, error) { return "$ make\nerror: boom\n", nil }
+
+	c.captureFailure(context.Background(), errors.New("send keypress 12: context deadline exceeded"))
+
+	png, err := os.ReadFile(filepath.Join(c.config.OutputDir, FailureScreenshotFilename))
+	require.NoError(t, err)
+	assert.Equal(t, "png", string(png))
+
+	text, err := os.ReadFile(filepath.Join(c.config.OutputDir, FailureTextFilename))
+	require.NoError(t, err)
+	assert.Equal(t, "error: send keypress 12: context deadline exceeded\n\n$ make\nerror: boom\n", string(text))
+}
+
+func TestCapturer_captureFailure_Bounded(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	var deadlines []time.Duration
+	hang := func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "failure capture must have a deadline")
+		deadlines = append(deadlines, time.Until(deadline))
+		return errors.New("browser gone")
+	}
+	c.captureFrame = func(ctx context.Context) ([]byte, error) { return nil, hang(ctx) }
+	c.readText = func(ctx context.Context) (string, error) { return "", hang(ctx) }
+
+	c.captureFailure(context.Background(), errors.New("boom"))
+
+	require.Len(t, deadlines, 2)
+	for _, d := range deadlines {
+		assert.LessOrEqual(t, d, failureCaptureTimeout)
+	}
+	entries, err := os.ReadDir(c.config.OutputDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing is written when the browser does not answer")
+}
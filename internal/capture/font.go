@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// embeddedFont is Fira Mono Regular, under the SIL Open Font License in
+// fonts/OFL.txt. Served to the page, it renders the same glyphs on every
+// machine, whatever monospace fonts are installed; the browser derives
+// bold from it.
+//
+//go:embed fonts/FiraMono-Regular.woff2
+var embeddedFont []byte
+
+// EmbeddedFontName and SystemFontName are the fonts recorded in the
+// manifest: the embedded font, or whatever monospace font the browser picks
+// with Config.SystemFonts.
+const (
+	EmbeddedFontName = "Fira Mono"
+	SystemFontName   = "system"
+)
+
+// embeddedFontFamily is the CSS font family the terminal is switched to. It
+// is named apart from an installed Fira Mono, which may be another version.
+const embeddedFontFamily = `"scr Fira Mono", monospace`
+
+// applyFontJS adds css, an @font-face rule, to the page, waits for the font
+// to load and switches the xterm.js terminal on window.term to family.
+// Resizing the window makes ttyd refit the terminal to the new cell size.
+// It evaluates to false when the page has no window.term.
+const applyFontJS = `(async (css, family) => {
+	if (!window.term) return false;
+	const style = document.createElement("style");
+	style.textContent = css;
+	document.head.appendChild(style);
+	await document.fonts.load("16px " + family);
+	window.term.options.fontFamily = family;
+	window.dispatchEvent(new Event("resize"));
+	return true;
+})(%s, %s)`
+
+// setFontJS sets the font family, unless empty, and the font size, unless
+// zero, of the xterm.js terminal on window.term, once the family has
+// loaded. Like applyFontJS, it resizes the window so ttyd refits the
+// terminal, and evaluates to false when the page has no window.term.
+const setFontJS = `(async (family, size) => {
+	if (!window.term) return false;
+	if (family) {
+		await document.fonts.load((size || window.term.options.fontSize) + "px " + family);
+		window.term.options.fontFamily = family;
+	}
+	if (size) window.term.options.fontSize = size;
+	window.dispatchEvent(new Event("resize"));
+	return true;
+})(%s, %d)`
+
+// fontFaceCSS returns the @font-face rule that serves the embedded font
+// from a data URL.
+func fontFaceCSS() string {
+	return fmt.Sprintf(`@font-face { font-family: "scr Fira Mono"; src: url(data:font/woff2;base64,%s) format("woff2"); }`,
+		base64.StdEncoding.EncodeToString(embeddedFont))
+}
+
+// applyEmbeddedFont switches the terminal in the page to the embedded font.
+func applyEmbeddedFont(ctx context.Context) error {
+	css, err := json.Marshal(fontFaceCSS())
+	if err != nil {
+		return fmt.Errorf("encode font: %w", err)
+	}
+	family, err := json.Marshal(embeddedFontFamily)
+	if err != nil {
+		return fmt.Errorf("encode font: %w", err)
+	}
+	var ok bool
+	awaitPromise := func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(applyFontJS, css, family), &ok, awaitPromise)); err != nil {
+		return err
+	}
+	if !ok {
+		return errNoTerm
+	}
+	return nil
+}
+
+// setTerminalFont switches the terminal in the page to the font family,
+// unless empty, and size, unless zero.
+func setTerminalFont(ctx context.Context, family string, size int) error {
+	data, err := json.Marshal(family)
+	if err != nil {
+		return fmt.Errorf("encode font: %w", err)
+	}
+	var ok bool
+	awaitPromise := func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(setFontJS, data, size), &ok, awaitPromise)); err != nil {
+		return err
+	}
+	if !ok {
+		return errNoTerm
+	}
+	return nil
+}
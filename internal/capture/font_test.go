@@ -0,0 +1,77 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestEmbeddedFont(t *testing.T) {
+	require.True(t, strings.HasPrefix(string(embeddedFont), "wOF2"), "the embedded font is WOFF2")
+
+	css := fontFaceCSS()
+	assert.True(t, strings.HasPrefix(css, `@font-face { font-family: "scr Fira Mono"; src: url(data:font/woff2;base64,d09GMg`))
+	assert.True(t, strings.HasSuffix(css, `) format("woff2"); }`))
+}
+
+func TestCapturer_runSession_Font(t *testing.T) {
+	tests := []struct {
+		name        string
+		systemFonts bool
+		fontFamily  string
+		fontSize    int
+		want        []string
+	}{
+		{name: "embedded font before resizing", want: []string{"font", "resize"}},
+		{name: "system fonts", systemFonts: true, want: []string{"resize"}},
+		{name: "embedded font at a size", fontSize: 18, want: []string{"font", `set "" 18`, "resize"}},
+		{name: "font family", fontFamily: "Menlo, monospace", want: []string{`set "Menlo, monospace" 0`, "resize"}},
+		{name: "system fonts at a size", systemFonts: true, fontSize: 9, want: []string{`set "" 9`, "resize"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Cols: 80, SystemFonts: tt.systemFonts, FontFamily: tt.fontFamily, FontSize: tt.fontSize})
+			var calls []string
+			c.applyFont = func(context.Context) error {
+				calls = append(calls, "font")
+				return nil
+			}
+			c.setFont = func(_ context.Context, family string, size int) error {
+				calls = append(calls, fmt.Sprintf("set %q %d", family, size))
+				return nil
+			}
+			c.resizeTerminal = func(context.Context, int, int) error {
+				calls = append(calls, "resize")
+				return nil
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+			assert.Equal(t, tt.want, calls)
+		})
+	}
+}
+
+func TestCapturer_runSession_FontFailure(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	c.applyFont = func(context.Context) error { return errors.New("no window.term") }
+
+	ctx := context.Background()
+	err := c.runSession(ctx, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "apply font: no window.term")
+
+	c = newFakeCapturer(t, &config.Config{FontSize: 18})
+	c.setFont = func(context.Context, string, int) error { return errors.New("no window.term") }
+	err = c.runSession(ctx, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "set font: no window.term")
+}
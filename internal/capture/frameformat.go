@@ -0,0 +1,93 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// elementRectJS returns the box of the first element matching a selector,
+// in page coordinates, or null when nothing matches.
+const elementRectJS = `((sel) => {
+	const el = document.querySelector(sel);
+	if (!el) return null;
+	const r = el.getBoundingClientRect();
+	return {x: r.x + window.scrollX, y: r.y + window.scrollY, width: r.width, height: r.height};
+})(%s)`
+
+// nativeFrames reports whether the browser captures frames directly in the
+// jpeg or webp frame format, which saves decoding and encoding every
+// frame. Otherwise frames are captured as PNG, which Crop, Window, Padding
+// and Grayscale work on, and jpeg frames are converted once they have.
+func (c *Capturer) nativeFrames() bool {
+	return c.config.ImageFormat() != "png" && c.config.Crop.Empty() && !c.config.Window && c.config.Padding == 0 && !c.config.Grayscale
+}
+
+// quality returns the compression quality of jpeg and webp frames.
+func (c *Capturer) quality() int {
+	if c.config.Quality == 0 {
+		return config.DefaultQuality
+	}
+	return c.config.Quality
+}
+
+// captureElement grabs the element matching selector in the image format,
+// one of config.ImageFormats, compressed with quality unless it is png.
+func captureElement(ctx context.Context, selector, format string, quality int) ([]byte, error) {
+	sel, err := json.Marshal(selector)
+	if err != nil {
+		return nil, fmt.Errorf("encode selector: %w", err)
+	}
+	var rect *page.Viewport
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(elementRectJS, sel), &rect)); err != nil {
+		return nil, err
+	}
+	if rect == nil {
+		return nil, fmt.Errorf("selector %q did not return any nodes", selector)
+	}
+
+	// Whole pixels, as chromedp.Screenshot uses: fractional clips are not
+	// captured properly
+	x, y := math.Round(rect.X), math.Round(rect.Y)
+	clip := &page.Viewport{X: x, Y: y, Width: math.Round(rect.Width + rect.X - x), Height: math.Round(rect.Height + rect.Y - y), Scale: 1}
+	params := page.CaptureScreenshot().
+		WithFormat(page.CaptureScreenshotFormat(format)).
+		WithCaptureBeyondViewport(true).
+		WithFromSurface(true).
+		WithClip(clip)
+	if format != "png" {
+		params = params.WithQuality(int64(quality))
+	}
+	var buf []byte
+	err = chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, err = params.Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// encodeJPEG converts a PNG frame to JPEG with quality.
+func encodeJPEG(data []byte, quality int) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestCapturer_FrameFormat(t *testing.T) {
+	frame := tinyTerminalPNG(t)
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		captured []byte
+		// want are the files written; same is whether they hold the
+		// captured bytes unchanged, and otherwise they are JPEG.
+		want []string
+		same bool
+	}{
+		{name: "png", captured: frame, want: []string{"screenshot_001.png", "screenshot_002.png"}, same: true},
+		{
+			name:     "jpeg from the browser",
+			cfg:      config.Config{Format: "jpeg"},
+			captured: []byte("jpeg"),
+			want:     []string{"screenshot_001.jpg", "screenshot_002.jpg"},
+			same:     true,
+		},
+		{
+			name:     "webp from the browser",
+			cfg:      config.Config{Format: "WEBP"},
+			captured: []byte("webp"),
+			want:     []string{"screenshot_001.webp", "screenshot_002.webp"},
+			same:     true,
+		},
+		{
+			name:     "jpeg converted after padding",
+			cfg:      config.Config{Format: "jpeg", Padding: 2, Quality: 50},
+			captured: frame,
+			want:     []string{"screenshot_001.jpg", "screenshot_002.jpg"},
+		},
+		{
+			name:     "template extension follows the format",
+			cfg:      config.Config{Format: "jpeg", NameTemplate: "shot_{n}.png"},
+			captured: []byte("jpeg"),
+			want:     []string{"shot_1.jpg", "shot_2.jpg"},
+			same:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.OutputDir = t.TempDir()
+			c := newFakeCapturer(t, &cfg)
+			c.captureFrame = func(context.Context) ([]byte, error) { return tt.captured, nil }
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			entries, err := os.ReadDir(cfg.OutputDir)
+			require.NoError(t, err)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			assert.Equal(t, tt.want, names)
+
+			for _, name := range tt.want {
+				data, err := os.ReadFile(filepath.Join(cfg.OutputDir, name))
+				require.NoError(t, err)
+				if tt.same {
+					assert.Equal(t, tt.captured, data, name)
+					continue
+				}
+				img, err := jpeg.Decode(bytes.NewReader(data))
+				require.NoError(t, err, name)
+				assert.Equal(t, image.Rect(0, 0, 7, 6), img.Bounds(), name)
+			}
+		})
+	}
+}
+
+func TestCapturer_captureFailure_FrameFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{name: "jpeg from the browser", cfg: config.Config{Format: "jpeg"}, want: "failure.jpg"},
+		{name: "webp from the browser", cfg: config.Config{Format: "webp"}, want: "failure.webp"},
+		{name: "png before jpeg conversion", cfg: config.Config{Format: "jpeg", Grayscale: true}, want: FailureScreenshotFilename},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			c := newFakeCapturer(t, &cfg)
+
+			c.captureFailure(context.Background(), errors.New("boom"))
+
+			assert.FileExists(t, filepath.Join(cfg.OutputDir, tt.want))
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// GIFFilename is the name of the animation written by the gif format.
+const GIFFilename = "animation.gif"
+
+// gifFinalHold is how long the last frame stays on screen before looping.
+const gifFinalHold = time.Second
+
+// gifEncoder collects frames during the run and writes an animated GIF when
+// it ends, and another of each scene's frames in the scene's directory.
+// Frame delays follow the real time between captures unless Meta.FrameDelay
+// fixes them; frames of a burst always keep their real spacing.
+//
+// Memory use does not grow with the length of the run: frames are spilled
+// to a temporary file in the output directory as they arrive (or read back
+// from their PNGs with KeepFrames), and End decodes, quantizes and writes
+// them one at a time, holding a single decoded frame. See
+// BenchmarkGIFEncoder_1000Frames.
+type gifEncoder struct {
+	meta Meta
+	// frames indexes the captured frames; their data is in spill, or at
+	// their Path with KeepFrames.
+	frames []spilledFrame
+	spill  *os.File
+	size   int64
+	path   string
+	// dropped are the paths of the frames sampling left out.
+	dropped []string
+}
+
+// spilledFrame locates a captured frame's PNG data.
+type spilledFrame struct {
+	path   string
+	kind   FrameKind
+	scene  string
+	offset time.Duration
+	burst  int
+	// at and n are the data's position and length in the spill file.
+	at, n int64
+}
+
+// gifSpillPattern names the temporary file frames are spilled to.
+const gifSpillPattern = ".scr-frames-*.tmp"
+
+func (e *gifEncoder) Begin(meta Meta) error {
+	e.meta = meta
+	e.frames = nil
+	e.spill = nil
+	e.size = 0
+	e.path = ""
+	e.dropped = nil
+	return nil
+}
+
+func (e *gifEncoder) Frame(f Frame) error {
+	frame := spilledFrame{path: f.Path, kind: f.Kind, scene: f.Scene, offset: f.Offset, burst: f.Burst}
+	if e.meta.KeepFrames {
+		if err := (&fileEncoder{}).Frame(f); err != nil {
+			return err
+		}
+	} else {
+		if e.spill == nil {
+			spill, err := os.CreateTemp(e.meta.OutputDir, gifSpillPattern)
+			if err != nil {
+				return fmt.Errorf("create frame spill file: %w", err)
+			}
+			e.spill = spill
+		}
+		if _, err := e.spill.Write(f.Data); err != nil {
+			return fmt.Errorf("spill frame %s: %w", filepath.Base(f.Path), err)
+		}
+		frame.at, frame.n = e.size, int64(len(f.Data))
+		e.size += frame.n
+	}
+	e.frames = append(e.frames, frame)
+	return nil
+}
+
+func (e *gifEncoder) End() error {
+	if e.spill != nil {
+		defer func() {
+			_ = e.spill.Close()
+			_ = os.Remove(e.spill.Name())
+		}()
+	}
+	if len(e.frames) == 0 {
+		return nil
+	}
+
+	// Interval and action frames are captured concurrently, so they may
+	// arrive slightly out of order.
+	sort.SliceStable(e.frames, func(i, j int) bool {
+		return e.frames[i].offset < e.frames[j].offset
+	})
+	e.frames, e.dropped = sampleFrames(e.frames, e.meta.TargetFrames)
+
+	// Every frame goes into the animation of the whole run and, in a
+	// scene, into the scene's own; each is decoded once for both
+	path := filepath.Join(e.meta.OutputDir, GIFFilename)
+	all, err := createGIF(path)
+	if err != nil {
+		return err
+	}
+	writers := []*gifWriter{all}
+	defer func() {
+		for _, w := range writers {
+			if w != nil {
+				w.Abort()
+			}
+		}
+	}()
+
+	// Each scene's animation has its own delays, as its frames are spread
+	// further apart
+	var scenes []string
+	byScene := map[string][]spilledFrame{}
+	for _, f := range e.frames {
+		if f.scene == "" {
+			continue
+		}
+		if _, ok := byScene[f.scene]; !ok {
+			scenes = append(scenes, f.scene)
+		}
+		byScene[f.scene] = append(byScene[f.scene], f)
+	}
+	type sceneGIF struct {
+		w      *gifWriter
+		delays []time.Duration
+		next   int
+	}
+	sceneGIFs := map[string]*sceneGIF{}
+	for _, scene := range scenes {
+		w, err := createGIF(filepath.Join(e.meta.OutputDir, scene, GIFFilename))
+		if err != nil {
+			return fmt.Errorf("scene %s: %w", scene, err)
+		}
+		writers = append(writers, w)
+		sceneGIFs[scene] = &sceneGIF{w: w, delays: e.frameDelays(byScene[scene])}
+	}
+
+	delays := e.frameDelays(e.frames)
+	var data []byte
+	for i, f := range e.frames {
+		data, err = e.readFrame(f, data)
+		if err != nil {
+			return err
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("decode frame %s: %w", filepath.Base(f.path), err)
+		}
+		pm := quantize(img)
+		if err := all.Frame(pm, gifDelay(delays[i])); err != nil {
+			return err
+		}
+		if sg := sceneGIFs[f.scene]; sg != nil {
+			if err := sg.w.Frame(pm, gifDelay(sg.delays[sg.next])); err != nil {
+				return fmt.Errorf("scene %s: %w", f.scene, err)
+			}
+			sg.next++
+		}
+	}
+
+	for i, w := range writers {
+		writers[i] = nil
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	e.path = path
+	return nil
+}
+
+func (e *gifEncoder) Artifact() string { return e.path }
+
+func (e *gifEncoder) Dropped() []string { return e.dropped }
+
+// sampleFrames thins out the interval frames of frames, in order, to bring
+// them to about target, and returns the frames kept and the paths of those
+// dropped. The initial, final, Screenshot and Burst frames are all kept,
+// and interval frames at even spacing in time, except close to one of
+// those, so the animation moves at about the same pace throughout; each
+// kept frame stays on screen until the next, so timing is kept too. A
+// target of zero, or one the frames already fit, keeps them all.
+func sampleFrames(frames []spilledFrame, target int) (kept []spilledFrame, dropped []string) {
+	if target <= 0 || len(frames) <= target {
+		return frames, nil
+	}
+	step := (frames[len(frames)-1].offset - frames[0].offset) / time.Duration(max(target-1, 1))
+	if step <= 0 {
+		return frames, nil
+	}
+
+	// An interval frame just before one that is always kept is not needed
+	nextKept := make([]time.Duration, len(frames))
+	upcoming := frames[len(frames)-1].offset + step
+	for i := len(frames) - 1; i >= 0; i-- {
+		nextKept[i] = upcoming
+		if frames[i].kind != FrameInterval {
+			upcoming = frames[i].offset
+		}
+	}
+
+	kept = make([]spilledFrame, 0, target)
+	due := frames[0].offset
+	for i, f := range frames {
+		pinned := f.kind != FrameInterval
+		if !pinned && (f.offset < due || nextKept[i]-f.offset < step/2) {
+			dropped = append(dropped, f.path)
+			continue
+		}
+		kept = append(kept, f)
+		// Interval frames are due every step from the first frame, so the
+		// spacing does not drift with the capture interval, but not right
+		// after a frame that is always kept
+		for due <= f.offset {
+			due += step
+		}
+		if pinned {
+			due = max(due, f.offset+step/2)
+		}
+	}
+	return kept, dropped
+}
+
+// readFrame returns the PNG data of f, reusing buf when it is large enough.
+func (e *gifEncoder) readFrame(f spilledFrame, buf []byte) ([]byte, error) {
+	if e.meta.KeepFrames {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("read frame %s: %w", filepath.Base(f.path), err)
+		}
+		return data, nil
+	}
+	if int64(cap(buf)) < f.n {
+		buf = make([]byte, f.n)
+	}
+	buf = buf[:f.n]
+	if _, err := e.spill.ReadAt(buf, f.at); err != nil {
+		return nil, fmt.Errorf("read frame %s: %w", filepath.Base(f.path), err)
+	}
+	return buf, nil
+}
+
+// frameDelays returns how long each of frames, in order, stays on screen.
+// A fixed FrameDelay does not apply within a burst, whose frames keep
+// their real spacing.
+func (e *gifEncoder) frameDelays(frames []spilledFrame) []time.Duration {
+	delays := make([]time.Duration, len(frames))
+	for i := range frames {
+		inBurst := i+1 < len(frames) && frames[i].burst != 0 && frames[i+1].burst == frames[i].burst
+		switch {
+		case e.meta.FrameDelay > 0 && !inBurst:
+			delays[i] = e.meta.FrameDelay
+		case i == len(frames)-1:
+			delays[i] = gifFinalHold
+		default:
+			delays[i] = frames[i+1].offset - frames[i].offset
+		}
+	}
+	return delays
+}
+
+// gifDelay converts d to GIF delay units (1/100 s). Delays below 2 units
+// are raised to 2, as most viewers slow faster frames down to 10.
+func gifDelay(d time.Duration) int {
+	return max(int((d+5*time.Millisecond)/(10*time.Millisecond)), 2)
+}
+
+// quantize reduces img to at most 256 colors. Images that already fit are
+// kept exact; otherwise colors are grouped into 15-bit buckets and the 256
+// most common buckets, averaged, form the palette. Terminal screenshots are
+// dominated by a few background and text colors, so this keeps text crisp
+// without dithering. Mostly transparent pixels, such as the corners outside
+// Config.Window, share a transparent palette entry; the rest are made
+// opaque.
+func quantize(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := map[uint16]*bucket{}
+	exact := map[color.RGBA]struct{}{}
+	transparent := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := opaque(img.At(x, y))
+			if !ok {
+				transparent = true
+				continue
+			}
+			if len(exact) <= 256 {
+				exact[c] = struct{}{}
+			}
+			key := uint16(c.R>>3)<<10 | uint16(c.G>>3)<<5 | uint16(c.B>>3)
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += int(c.R)
+			bk.g += int(c.G)
+			bk.b += int(c.B)
+		}
+	}
+
+	// The transparent entry, if any, takes one of the 256
+	limit := 256
+	if transparent {
+		limit--
+	}
+	var pal color.Palette
+	if len(exact) <= limit {
+		for c := range exact {
+			pal = append(pal, c)
+		}
+		sort.Slice(pal, func(i, j int) bool {
+			ci, cj := pal[i].(color.RGBA), pal[j].(color.RGBA)
+			return uint32(ci.R)<<16|uint32(ci.G)<<8|uint32(ci.B) < uint32(cj.R)<<16|uint32(cj.G)<<8|uint32(cj.B)
+		})
+	} else {
+		keys := make([]uint16, 0, len(buckets))
+		for k := range buckets {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ci, cj := buckets[keys[i]].count, buckets[keys[j]].count
+			if ci != cj {
+				return ci > cj
+			}
+			return keys[i] < keys[j]
+		})
+		if len(keys) > limit {
+			keys = keys[:limit]
+		}
+		for _, k := range keys {
+			bk := buckets[k]
+			pal = append(pal, color.RGBA{
+				R: uint8(bk.r / bk.count),
+				G: uint8(bk.g / bk.count),
+				B: uint8(bk.b / bk.count),
+				A: 0xff,
+			})
+		}
+	}
+
+	if transparent {
+		pal = append(pal, color.RGBA{})
+	}
+
+	// Map pixels to the nearest palette entry, caching lookups since the
+	// same few colors repeat across the whole image.
+	out := image.NewPaletted(bounds, pal)
+	index := map[color.RGBA]uint8{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := opaque(img.At(x, y))
+			if !ok {
+				out.SetColorIndex(x, y, uint8(len(pal)-1))
+				continue
+			}
+			i, ok := index[c]
+			if !ok {
+				i = uint8(pal.Index(c))
+				index[c] = i
+			}
+			out.SetColorIndex(x, y, i)
+		}
+	}
+	return out
+}
+
+// opaque returns c without its transparency, or false when it is mostly
+// transparent.
+func opaque(c color.Color) (color.RGBA, bool) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A < 0x80 {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{R: n.R, G: n.G, B: n.B, A: 0xff}, true
+}
@@ -0,0 +1,374 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPNG returns a small PNG filled with c.
+func testPNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	return framePNG(t, 4, 2, func(int, int) color.Color { return c })
+}
+
+func TestGIFEncoder(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+
+	tests := []struct {
+		name       string
+		meta       Meta
+		wantDelays []int
+		wantFrames []string
+	}{
+		{
+			name:       "delays follow capture timing",
+			wantDelays: []int{50, 120, 100},
+		},
+		{
+			name:       "fixed frame delay",
+			meta:       Meta{FrameDelay: 80 * time.Millisecond},
+			wantDelays: []int{8, 8, 8},
+		},
+		{
+			name:       "keeps PNG frames",
+			meta:       Meta{KeepFrames: true},
+			wantDelays: []int{50, 120, 100},
+			wantFrames: []string{"screenshot_001.png", "screenshot_002.png", "screenshot_003.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			meta := tt.meta
+			meta.OutputDir = dir
+
+			enc := &gifEncoder{}
+			require.NoError(t, enc.Begin(meta))
+			// The second frame arrives late; the encoder orders by offset.
+			frames := []Frame{
+				{Path: filepath.Join(dir, "screenshot_001.png"), Data: testPNG(t, red), Offset: 0},
+				{Path: filepath.Join(dir, "screenshot_003.png"), Data: testPNG(t, red), Offset: 1700 * time.Millisecond},
+				{Path: filepath.Join(dir, "screenshot_002.png"), Data: testPNG(t, blue), Offset: 500 * time.Millisecond},
+			}
+			for _, f := range frames {
+				require.NoError(t, enc.Frame(f))
+			}
+			require.NoError(t, enc.End())
+
+			path := filepath.Join(dir, GIFFilename)
+			assert.Equal(t, path, enc.Artifact())
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+			defer file.Close()
+			anim, err := gif.DecodeAll(file)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantDelays, anim.Delay)
+			require.Len(t, anim.Image, 3)
+			r, g, b, _ := anim.Image[1].At(0, 0).RGBA()
+			assert.Equal(t, [3]uint32{0, 0, 0xffff}, [3]uint32{r, g, b})
+
+			for _, name := range []string{"screenshot_001.png", "screenshot_002.png", "screenshot_003.png"} {
+				_, err := os.Stat(filepath.Join(dir, name))
+				if slices.Contains(tt.wantFrames, name) {
+					assert.NoError(t, err)
+				} else {
+					assert.True(t, os.IsNotExist(err), "%s should not be written", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGIFEncoder_BurstKeepsRealTiming(t *testing.T) {
+	dir := t.TempDir()
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir, FrameDelay: 500 * time.Millisecond}))
+	frames := []Frame{
+		{Path: filepath.Join(dir, "screenshot_001.png"), Offset: 0},
+		{Path: filepath.Join(dir, "screenshot_002.png"), Offset: time.Second, Burst: 1},
+		{Path: filepath.Join(dir, "screenshot_003.png"), Offset: 1030 * time.Millisecond, Burst: 1},
+		{Path: filepath.Join(dir, "screenshot_004.png"), Offset: 1060 * time.Millisecond, Burst: 1},
+		{Path: filepath.Join(dir, "screenshot_005.png"), Offset: 1100 * time.Millisecond, Burst: 2},
+		{Path: filepath.Join(dir, "screenshot_006.png"), Offset: 1150 * time.Millisecond, Burst: 2},
+		{Path: filepath.Join(dir, "screenshot_007.png"), Offset: 3 * time.Second},
+	}
+	for _, f := range frames {
+		f.Data = testPNG(t, color.White)
+		require.NoError(t, enc.Frame(f))
+	}
+	require.NoError(t, enc.End())
+
+	file, err := os.Open(enc.Artifact())
+	require.NoError(t, err)
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	require.NoError(t, err)
+	// Within each burst the real spacing is kept; the last frame of a
+	// burst and the frames outside one get the fixed delay
+	assert.Equal(t, []int{50, 3, 3, 50, 5, 50, 50}, anim.Delay)
+}
+
+func TestGIFEncoder_RemovesSpillFile(t *testing.T) {
+	dir := t.TempDir()
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir}))
+	require.NoError(t, enc.Frame(Frame{Path: filepath.Join(dir, "screenshot_001.png"), Data: testPNG(t, color.White)}))
+
+	spilled, err := filepath.Glob(filepath.Join(dir, gifSpillPattern))
+	require.NoError(t, err)
+	assert.Len(t, spilled, 1, "frames are spilled to the output directory")
+
+	require.NoError(t, enc.End())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, GIFFilename, entries[0].Name())
+}
+
+func TestGIFEncoder_LargerFrame(t *testing.T) {
+	dir := t.TempDir()
+	small := testPNG(t, color.White)
+	var large bytes.Buffer
+	require.NoError(t, png.Encode(&large, image.NewRGBA(image.Rect(0, 0, 8, 2))))
+
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir}))
+	require.NoError(t, enc.Frame(Frame{Path: filepath.Join(dir, "a.png"), Data: small}))
+	require.NoError(t, enc.Frame(Frame{Path: filepath.Join(dir, "b.png"), Data: large.Bytes(), Offset: time.Second}))
+
+	err := enc.End()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "frame of 8x2 is larger than the first frame's 4x2")
+	_, statErr := os.Stat(filepath.Join(dir, GIFFilename))
+	assert.True(t, os.IsNotExist(statErr), "a failed GIF is removed")
+}
+
+func TestGIFEncoder_NoFrames(t *testing.T) {
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: t.TempDir()}))
+	require.NoError(t, enc.End())
+	assert.Empty(t, enc.Artifact())
+}
+
+func TestSampleFrames(t *testing.T) {
+	// A three-minute capture with a frame every 500ms
+	long := []spilledFrame{{path: "initial", kind: FrameInitial}}
+	for at := 500 * time.Millisecond; at < 3*time.Minute; at += 500 * time.Millisecond {
+		long = append(long, spilledFrame{path: at.String(), kind: FrameInterval, offset: at})
+	}
+	long = append(long, spilledFrame{path: "final", kind: FrameFinal, offset: 3 * time.Minute})
+
+	// The same with a Screenshot and a Burst in the middle
+	marked := slices.Clone(long)
+	marked[200].kind = FrameExplicit
+	for i := 300; i < 305; i++ {
+		marked[i].kind, marked[i].burst = FrameBurst, 1
+	}
+
+	tests := []struct {
+		name     string
+		frames   []spilledFrame
+		target   int
+		wantKept int
+	}{
+		{name: "no target", frames: long, target: 0, wantKept: len(long)},
+		{name: "fewer frames than the target", frames: long, target: 500, wantKept: len(long)},
+		{name: "long capture", frames: long, target: 60, wantKept: 60},
+		{name: "screenshot and burst frames", frames: marked, target: 60, wantKept: 64},
+		{name: "target below the frames that must be kept", frames: marked, target: 3, wantKept: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := sampleFrames(slices.Clone(tt.frames), tt.target)
+
+			assert.InDelta(t, tt.wantKept, len(kept), 1)
+			assert.Len(t, dropped, len(tt.frames)-len(kept))
+			for _, f := range tt.frames {
+				if f.kind != FrameInterval {
+					assert.Contains(t, kept, f, "%s frames are always kept", f.kind)
+				}
+			}
+			for i := 1; i < len(kept); i++ {
+				assert.Less(t, kept[i-1].offset, kept[i].offset, "kept frames stay in order")
+			}
+			for _, path := range dropped {
+				assert.NotContains(t, path, "initial")
+			}
+		})
+	}
+}
+
+func TestGIFEncoder_TargetFrames(t *testing.T) {
+	dir := t.TempDir()
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir, TargetFrames: 3}))
+	frames := []Frame{
+		{Path: "screenshot_001.png", Kind: FrameInitial},
+		{Path: "screenshot_002.png", Kind: FrameInterval, Offset: 500 * time.Millisecond},
+		{Path: "screenshot_003.png", Kind: FrameInterval, Offset: time.Second},
+		{Path: "screenshot_004.png", Kind: FrameInterval, Offset: 1500 * time.Millisecond},
+		{Path: "screenshot_005.png", Kind: FrameFinal, Offset: 2 * time.Second},
+	}
+	for _, f := range frames {
+		f.Data = testPNG(t, color.White)
+		require.NoError(t, enc.Frame(f))
+	}
+	require.NoError(t, enc.End())
+
+	file, err := os.Open(enc.Artifact())
+	require.NoError(t, err)
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	require.NoError(t, err)
+	// Each kept frame stays on screen until the next one was captured
+	assert.Equal(t, []int{100, 100, 100}, anim.Delay)
+	assert.Equal(t, []string{"screenshot_002.png", "screenshot_004.png"}, enc.Dropped())
+
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir}))
+	assert.Empty(t, enc.Dropped(), "Begin starts over")
+}
+
+func TestGIFDelay(t *testing.T) {
+	assert.Equal(t, 2, gifDelay(0))
+	assert.Equal(t, 2, gifDelay(12*time.Millisecond))
+	assert.Equal(t, 50, gifDelay(500*time.Millisecond))
+	assert.Equal(t, 51, gifDelay(505*time.Millisecond))
+}
+
+func TestQuantize(t *testing.T) {
+	t.Run("keeps few colors exact", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, color.RGBA{R: 10, G: 20, B: 30, A: 0xff})
+		img.Set(1, 0, color.RGBA{R: 200, G: 210, B: 220, A: 0xff})
+
+		got := quantize(img)
+		assert.Len(t, got.Palette, 2)
+		assert.Equal(t, color.RGBA{R: 200, G: 210, B: 220, A: 0xff}, got.At(1, 0))
+	})
+
+	t.Run("limits many colors to 256", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 128, A: 0xff})
+			}
+		}
+
+		got := quantize(img)
+		assert.LessOrEqual(t, len(got.Palette), 256)
+	})
+
+	t.Run("keeps transparent pixels transparent", func(t *testing.T) {
+		img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: 128, A: 0xff})
+			}
+		}
+		img.SetNRGBA(0, 0, color.NRGBA{})
+		img.SetNRGBA(1, 0, color.NRGBA{R: 0xff, A: 0x40})
+		img.SetNRGBA(2, 0, color.NRGBA{R: 0xff, A: 0xc0})
+
+		got := quantize(img)
+		assert.LessOrEqual(t, len(got.Palette), 256)
+		assert.Equal(t, color.RGBA{}, got.At(0, 0))
+		assert.Equal(t, color.RGBA{}, got.At(1, 0), "mostly transparent")
+		_, _, _, a := got.At(2, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), a, "mostly opaque")
+	})
+}
+
+// BenchmarkGIFEncoder_1000Frames encodes 1000 synthetic 800x480 terminal
+// frames and reports the peak heap in use while doing so. The peak stays
+// near the size of a single decoded frame and its palette lookups (about
+// 5 MB here), however many frames there are; PNG data waits in a spill
+// file. Holding every paletted frame would take over 370 MB.
+func BenchmarkGIFEncoder_1000Frames(b *testing.B) {
+	const frames = 1000
+	data := make([][]byte, 8)
+	for i := range data {
+		img := image.NewRGBA(image.Rect(0, 0, 800, 480))
+		for y := 0; y < 480; y++ {
+			for x := 0; x < 800; x++ {
+				// Background with a few lines of "text" that change per frame
+				c := color.RGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff}
+				if y%24 < 16 && (x/8+y/24+i)%5 == 0 {
+					c = color.RGBA{R: 0xcd, G: 0xd6, B: 0xf4, A: 0xff}
+				}
+				img.Set(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			b.Fatal(err)
+		}
+		data[i] = buf.Bytes()
+	}
+
+	var peak uint64
+	for n := 0; n < b.N; n++ {
+		dir := b.TempDir()
+		runtime.GC()
+		var base runtime.MemStats
+		runtime.ReadMemStats(&base)
+
+		stop := make(chan struct{})
+		sampled := make(chan uint64)
+		go func() {
+			var high uint64
+			var m runtime.MemStats
+			for {
+				runtime.ReadMemStats(&m)
+				high = max(high, m.HeapInuse)
+				select {
+				case <-stop:
+					sampled <- high
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}()
+
+		enc := &gifEncoder{}
+		if err := enc.Begin(Meta{OutputDir: dir, FrameDelay: 100 * time.Millisecond}); err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < frames; i++ {
+			f := Frame{
+				Path:   filepath.Join(dir, fmt.Sprintf("screenshot_%03d.png", i+1)),
+				Data:   data[i%len(data)],
+				Offset: time.Duration(i) * 100 * time.Millisecond,
+			}
+			if err := enc.Frame(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := enc.End(); err != nil {
+			b.Fatal(err)
+		}
+
+		close(stop)
+		if m := <-sampled; m > base.HeapInuse && m-base.HeapInuse > peak {
+			peak = m - base.HeapInuse
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
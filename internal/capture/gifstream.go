@@ -0,0 +1,175 @@
+package capture
+
+import (
+	"bufio"
+	"compress/lzw"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"os"
+)
+
+// gifWriter writes a looping animated GIF one frame at a time. Unlike
+// gif.EncodeAll, which needs every frame in memory at once, it only holds
+// the frame being written. Each frame carries its own color table, as
+// quantize picks a palette per frame.
+type gifWriter struct {
+	file *os.File
+	w    *bufio.Writer
+	// width and height are the logical screen size, set by the first
+	// frame; later frames must fit in it.
+	width, height int
+	// block buffers one data sub-block: a length byte, then up to 255
+	// bytes.
+	block [256]byte
+	err   error
+}
+
+// createGIF creates the file at path for a gifWriter. Nothing is written
+// until the first frame.
+func createGIF(path string) (*gifWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create gif: %w", err)
+	}
+	return &gifWriter{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Frame appends img, shown for delay hundredths of a second.
+func (g *gifWriter) Frame(img *image.Paletted, delay int) error {
+	b := img.Bounds()
+	if g.width == 0 {
+		if b.Dx() >= 1<<16 || b.Dy() >= 1<<16 {
+			return fmt.Errorf("encode gif: frame of %dx%d is too large", b.Dx(), b.Dy())
+		}
+		g.width, g.height = b.Dx(), b.Dy()
+		g.writeHeader()
+	}
+	if b.Dx() > g.width || b.Dy() > g.height {
+		return fmt.Errorf("encode gif: frame of %dx%d is larger than the first frame's %dx%d", b.Dx(), b.Dy(), g.width, g.height)
+	}
+	if len(img.Palette) == 0 {
+		return fmt.Errorf("encode gif: frame has an empty palette")
+	}
+
+	// Graphic Control Extension with the delay
+	var buf [10]byte
+	buf[0], buf[1], buf[2], buf[3] = 0x21, 0xf9, 0x04, 0x00
+	binary.LittleEndian.PutUint16(buf[4:6], uint16(delay))
+	buf[6], buf[7] = 0x00, 0x00
+	g.write(buf[:8])
+
+	// Image Descriptor, always at the top left, with a local color table
+	// of 2^(size+1) entries
+	size := 0
+	for 2<<size < len(img.Palette) {
+		size++
+	}
+	buf[0] = 0x2c
+	binary.LittleEndian.PutUint16(buf[1:3], 0)
+	binary.LittleEndian.PutUint16(buf[3:5], 0)
+	binary.LittleEndian.PutUint16(buf[5:7], uint16(b.Dx()))
+	binary.LittleEndian.PutUint16(buf[7:9], uint16(b.Dy()))
+	buf[9] = 0x80 | uint8(size)
+	g.write(buf[:10])
+
+	table := make([]byte, 3*(2<<size))
+	for i, c := range img.Palette {
+		r, gr, bl, _ := c.RGBA()
+		table[3*i], table[3*i+1], table[3*i+2] = uint8(r>>8), uint8(gr>>8), uint8(bl>>8)
+	}
+	g.write(table)
+
+	// LZW-compressed pixels, split into sub-blocks
+	litWidth := max(size+1, 2)
+	g.write([]byte{uint8(litWidth)})
+	g.block[0] = 0
+	lzww := lzw.NewWriter(blockWriter{g}, lzw.LSB, litWidth)
+	for y := b.Min.Y; y < b.Max.Y && g.err == nil; y++ {
+		i := img.PixOffset(b.Min.X, y)
+		if _, err := lzww.Write(img.Pix[i : i+b.Dx()]); err != nil && g.err == nil {
+			g.err = err
+		}
+	}
+	if err := lzww.Close(); err != nil && g.err == nil {
+		g.err = err
+	}
+	n := int(g.block[0])
+	g.block[n+1] = 0 // block terminator
+	g.write(g.block[:n+2])
+
+	if g.err != nil {
+		return fmt.Errorf("encode gif: %w", g.err)
+	}
+	return nil
+}
+
+// Close finishes the file. It needs at least one frame.
+func (g *gifWriter) Close() error {
+	g.write([]byte{0x3b}) // trailer
+	if g.err == nil {
+		g.err = g.w.Flush()
+	}
+	if err := g.file.Close(); err != nil && g.err == nil {
+		g.err = err
+	}
+	if g.err != nil {
+		return fmt.Errorf("write gif: %w", g.err)
+	}
+	return nil
+}
+
+// Abort closes and removes the partly written file.
+func (g *gifWriter) Abort() {
+	_ = g.file.Close()
+	_ = os.Remove(g.file.Name())
+}
+
+// writeHeader writes the signature, the logical screen without a global
+// color table, and the extension that makes the animation loop forever.
+func (g *gifWriter) writeHeader() {
+	var buf [13]byte
+	copy(buf[:6], "GIF89a")
+	binary.LittleEndian.PutUint16(buf[6:8], uint16(g.width))
+	binary.LittleEndian.PutUint16(buf[8:10], uint16(g.height))
+	g.write(buf[:])
+	g.write([]byte{0x21, 0xff, 0x0b})
+	g.write([]byte("NETSCAPE2.0"))
+	g.write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+}
+
+func (g *gifWriter) write(p []byte) {
+	if g.err == nil {
+		_, g.err = g.w.Write(p)
+	}
+}
+
+// blockWriter splits the LZW stream into the length-prefixed sub-blocks of
+// GIF image data, buffering in gifWriter.block.
+type blockWriter struct{ g *gifWriter }
+
+var _ io.ByteWriter = blockWriter{}
+
+func (b blockWriter) WriteByte(c byte) error {
+	g := b.g
+	if g.err != nil {
+		return g.err
+	}
+	g.block[0]++
+	g.block[g.block[0]] = c
+	if g.block[0] == 255 {
+		g.write(g.block[:])
+		g.block[0] = 0
+	}
+	return g.err
+}
+
+func (b blockWriter) Write(p []byte) (int, error) {
+	for i, c := range p {
+		if err := b.WriteByte(c); err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,35 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// grayscale returns the PNG data with every pixel replaced by its
+// luminance, computed in linear light with the Rec. 709 weights the sRGB
+// primaries use.
+func grayscale(data []byte) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			l := linearToSRGB(0.2126*toLinear[c.R] + 0.7152*toLinear[c.G] + 0.0722*toLinear[c.B])
+			dst.SetNRGBA(x, y, color.NRGBA{R: l, G: l, B: l, A: c.A})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,59 @@
+package capture
+
+import (
+	"context"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestGrayscale(t *testing.T) {
+	tests := []struct {
+		name  string
+		color color.NRGBA
+		want  uint8
+	}{
+		{name: "white", color: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, want: 0xff},
+		{name: "black", color: color.NRGBA{A: 0xff}, want: 0},
+		{name: "gray", color: color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}, want: 0x80},
+		// Green carries most of the luminance, blue the least
+		{name: "green", color: color.NRGBA{G: 0xff, A: 0xff}, want: 0xdc},
+		{name: "blue", color: color.NRGBA{B: 0xff, A: 0xff}, want: 0x4c},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gray, err := grayscale(testPNG(t, tt.color))
+			require.NoError(t, err)
+			assert.Equal(t, color.NRGBA{R: tt.want, G: tt.want, B: tt.want, A: 0xff}, pixelOf(t, gray))
+		})
+	}
+
+	_, err := grayscale([]byte("not a png"))
+	assert.ErrorContains(t, err, "decode frame")
+}
+
+func TestCapturer_runSession_Grayscale(t *testing.T) {
+	dir := t.TempDir()
+	c := newFakeCapturer(t, &config.Config{OutputDir: dir, Grayscale: true})
+	frame := testPNG(t, color.NRGBA{R: 0xff, G: 0x40, A: 0xff})
+	c.captureFrame = func(context.Context) ([]byte, error) { return frame, nil }
+	c.encoder = &fileEncoder{}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	for _, name := range []string{"screenshot_001.png", "screenshot_002.png"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		got := pixelOf(t, data)
+		assert.Equal(t, got.R, got.G, name)
+		assert.Equal(t, got.R, got.B, name)
+	}
+}
@@ -0,0 +1,39 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// executeHideAction stops or, for a Show, resumes taking frames. Actions
+// run as usual while frames are hidden. Hiding twice, or showing while
+// nothing is hidden, changes nothing.
+func (c *Capturer) executeHideAction(action script.Action, index int) error {
+	hide := action.Kind == script.ActionHide
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	if c.config.Verbose && c.hidden != hide {
+		if hide {
+			fmt.Fprintf(os.Stderr, "Hiding frames (action %d)\n", index)
+		} else {
+			fmt.Fprintf(os.Stderr, "Showing frames again (action %d)\n", index)
+		}
+	}
+	c.hidden = hide
+	return nil
+}
+
+// framesHeldLocked reports whether a frame of the given kind must not be
+// taken now: after a Hide, or while a TypeSecret may be on screen. Callers
+// hold c.encMu.
+func (c *Capturer) framesHeldLocked(kind FrameKind) bool {
+	if c.hidden {
+		if c.config.Verbose && kind != FrameInterval {
+			fmt.Fprintf(os.Stderr, "Skipping %s screenshot while hidden\n", kind)
+		}
+		return true
+	}
+	return c.secretHeldLocked(kind)
+}
@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestCapturer_runSession_HideAndShow(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		ScreenshotInterval: time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionSleep, Duration: 5 * time.Millisecond},
+			{Kind: script.ActionHide},
+			{Kind: script.ActionScreenshot, Name: "hidden"},
+			{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+			{Kind: script.ActionShow},
+			{Kind: script.ActionShow},
+			{Kind: script.ActionHide},
+			{Kind: script.ActionHide},
+			{Kind: script.ActionShow},
+			{Kind: script.ActionScreenshot, Name: "shown"},
+			{Kind: script.ActionSleep, Duration: 5 * time.Millisecond},
+		},
+	})
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	var names []string
+	var final bool
+	for _, f := range c.Stats().Frames {
+		switch f.Action {
+		case 2, 3, 7:
+			t.Errorf("frame %s taken while hidden (action %d)", f.Path, f.Action)
+		}
+		if f.Kind == FrameExplicit {
+			names = append(names, filepath.Base(f.Path))
+		}
+		final = final || f.Kind == FrameFinal
+	}
+	assert.Equal(t, []string{"shown.png"}, names)
+	assert.True(t, final, "frames are shown again at the end")
+	assert.NoFileExists(t, filepath.Join(c.config.OutputDir, "hidden.png"))
+}
+
+func TestCapturer_runSession_HiddenAtEnd(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionHide},
+			{Kind: script.ActionType, Text: "clear"},
+		},
+	})
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	frames := c.Stats().Frames
+	require.Len(t, frames, 1)
+	assert.Equal(t, FrameInitial, frames[0].Kind)
+}
@@ -0,0 +1,197 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// frameHookWorkers bounds how many frame hook commands run at once.
+const frameHookWorkers = 4
+
+// HookStats counts the frame hook commands run by the last Run.
+type HookStats struct {
+	Succeeded int
+	Failed    int
+}
+
+// frameHooks runs Config.FrameHook for every frame written. Commands run in
+// the background, at most frameHookWorkers at a time, so a slow hook does
+// not hold up capture.
+type frameHooks struct {
+	ctx      context.Context
+	template string
+	run      func(ctx context.Context, command string) ([]byte, error)
+	verbose  bool
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+	// frames counts the frames submitted; callers of submit hold c.encMu.
+	frames int
+
+	mu    sync.Mutex
+	stats HookStats
+	first error
+}
+
+// startFrameHooks returns the hook runner for a session, or nil when no
+// FrameHook is configured. Hooks are stopped when ctx ends.
+func (c *Capturer) startFrameHooks(ctx context.Context) *frameHooks {
+	c.hookStats = HookStats{}
+	if c.config.FrameHook == "" {
+		return nil
+	}
+	return &frameHooks{
+		ctx:      ctx,
+		template: c.config.FrameHook,
+		run:      c.runHook,
+		verbose:  c.config.Verbose,
+		sem:      make(chan struct{}, frameHookWorkers),
+	}
+}
+
+// submit starts the hook for the frame written to path, captured offset
+// after the terminal became ready. It does not wait for a free worker.
+func (h *frameHooks) submit(path string, offset time.Duration) {
+	if h == nil {
+		return
+	}
+	h.frames++
+	command := expandFrameHook(h.template, path, h.frames, offset)
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.sem <- struct{}{}
+		defer func() { <-h.sem }()
+
+		out, err := h.run(h.ctx, command)
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if err != nil {
+			h.stats.Failed++
+			if h.first == nil {
+				h.first = fmt.Errorf("%s: %w", path, err)
+			}
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: frame hook failed for %s: %v\n", path, err)
+			return
+		}
+		h.stats.Succeeded++
+		if h.verbose {
+			fmt.Fprintf(os.Stderr, "Frame hook done for %s\n", path)
+		}
+	}()
+}
+
+// failed returns the first hook failure so far, or nil.
+func (h *frameHooks) failed() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.first
+}
+
+// wait blocks until every submitted hook has finished and returns the
+// counts, and with strict an error when any of them failed.
+func (h *frameHooks) wait(strict bool) (HookStats, error) {
+	if h == nil {
+		return HookStats{}, nil
+	}
+	h.wg.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if strict && h.first != nil {
+		return h.stats, fmt.Errorf("frame hook failed for %d of %d frames, first %w", h.stats.Failed, h.frames, h.first)
+	}
+	return h.stats, nil
+}
+
+// expandFrameHook fills in the {file}, {index} and {elapsed} placeholders
+// of a hook command. The path is quoted for the shell.
+func expandFrameHook(template, path string, index int, offset time.Duration) string {
+	return strings.NewReplacer(
+		"{file}", config.ShellQuote(path),
+		"{index}", strconv.Itoa(index),
+		"{elapsed}", strconv.FormatInt(offset.Milliseconds(), 10),
+	).Replace(template)
+}
+
+// runHookCommand runs a frame hook command with sh and returns its combined
+// output.
+func runHookCommand(ctx context.Context, command string) ([]byte, error) {
+	return exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+}
+
+// FrameHooks returns how many frame hook commands succeeded and failed in
+// the last Run.
+func (c *Capturer) FrameHooks() HookStats {
+	return c.hookStats
+}
+
+// hookGracePeriod bounds the after hook when the run's context has already
+// ended, so cleanup still happens after a timeout or an interrupt.
+const hookGracePeriod = 10 * time.Second
+
+// hookWaitDelay bounds how long a hook's output is read after it was
+// stopped, in case a process it started still holds the output open.
+const hookWaitDelay = time.Second
+
+// runAfterHook runs Config.AfterHook within ctx or, when ctx has already
+// ended, within hookGracePeriod.
+func (c *Capturer) runAfterHook(ctx context.Context) error {
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), hookGracePeriod)
+		defer cancel()
+	}
+	return c.runShellHook(ctx, "after", c.config.AfterHook)
+}
+
+// runShellHook runs a before or after hook command with sh, in the working
+// directory and with the environment the captured command gets. Its output
+// goes to stderr with Verbose, and is otherwise added to the error when the
+// command fails.
+func (c *Capturer) runShellHook(ctx context.Context, which, command string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = c.hookEnv()
+	cmd.WaitDelay = hookWaitDelay
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Running %s hook: %s\n", which, command)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s hook: %w", which, context.Cause(ctx))
+		}
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return fmt.Errorf("%s hook failed: %w", which, err)
+	}
+	return nil
+}
+
+// hookEnv returns the environment of the before and after hooks: that of
+// the captured command, or scr's own with Config.Env when attaching to a
+// terminal scr does not start.
+func (c *Capturer) hookEnv() []string {
+	if c.ttyd != nil {
+		return c.ttyd.environ(os.Environ())
+	}
+	return append(os.Environ(), c.config.Env...)
+}
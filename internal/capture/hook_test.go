@@ -0,0 +1,218 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestExpandFrameHook(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		want     string
+	}{
+		{
+			name:     "all placeholders",
+			template: "stamp {file} --n {index} --at {elapsed}",
+			path:     "/out/screenshot_003.png",
+			want:     "stamp /out/screenshot_003.png --n 3 --at 1500",
+		},
+		{
+			name:     "path is quoted for the shell",
+			template: "cp {file} /backup/",
+			path:     "/my shots/it's.png",
+			want:     `cp '/my shots/it'\''s.png' /backup/`,
+		},
+		{
+			name:     "other braces are left alone",
+			template: "echo ${HOME} {file}",
+			path:     "/out/a.png",
+			want:     "echo ${HOME} /out/a.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, expandFrameHook(tt.template, tt.path, 3, 1500*time.Millisecond))
+		})
+	}
+}
+
+// recordHooks makes c record the hook commands it runs instead of running
+// them, failing those fail returns true for.
+func recordHooks(c *Capturer, fail func(command string) bool) func() []string {
+	var mu sync.Mutex
+	var commands []string
+	c.runHook = func(_ context.Context, command string) ([]byte, error) {
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		if fail != nil && fail(command) {
+			return []byte("watermark: no such font"), errors.New("exit status 1")
+		}
+		return nil, nil
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sorted := append([]string(nil), commands...)
+		sort.Strings(sorted)
+		return sorted
+	}
+}
+
+func TestCapturer_runSession_FrameHook(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		FrameHook: "hook {index} {file}",
+		Actions:   []script.Action{{Kind: script.ActionScreenshot, Name: "menu"}},
+	})
+	commands := recordHooks(c, nil)
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	dir := c.config.OutputDir
+	assert.Equal(t, []string{
+		"hook 1 " + filepath.Join(dir, "screenshot_001.png"),
+		"hook 2 " + filepath.Join(dir, "menu.png"),
+		"hook 3 " + filepath.Join(dir, "screenshot_002.png"),
+	}, commands())
+	assert.Equal(t, HookStats{Succeeded: 3}, c.FrameHooks())
+}
+
+func TestCapturer_runSession_FrameHookFailures(t *testing.T) {
+	failSecond := func(command string) bool { return command == "hook 2" }
+
+	t.Run("failures are counted but not fatal", func(t *testing.T) {
+		c := newFakeCapturer(t, &config.Config{
+			FrameHook: "hook {index}",
+			Actions:   []script.Action{{Kind: script.ActionScreenshot}},
+		})
+		recordHooks(c, failSecond)
+		ctx := context.Background()
+		require.NoError(t, c.runSession(ctx, ctx))
+		assert.Equal(t, HookStats{Succeeded: 2, Failed: 1}, c.FrameHooks())
+	})
+
+	t.Run("strict fails the run", func(t *testing.T) {
+		c := newFakeCapturer(t, &config.Config{
+			FrameHook:       "hook {index}",
+			FrameHookStrict: true,
+			Actions:         []script.Action{{Kind: script.ActionScreenshot}},
+		})
+		recordHooks(c, failSecond)
+		ctx := context.Background()
+		err := c.runSession(ctx, ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "frame hook failed for")
+		assert.Contains(t, err.Error(), "screenshot_002.png: exit status 1")
+		assert.Equal(t, 1, c.FrameHooks().Failed)
+	})
+}
+
+func TestCapturer_runSession_NoFrameHook(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	c.runHook = func(context.Context, string) ([]byte, error) {
+		t.Fatal("hook run without a FrameHook")
+		return nil, nil
+	}
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	assert.Equal(t, HookStats{}, c.FrameHooks())
+}
+
+func TestCapturer_Run_FrameHookNeedsFrameFiles(t *testing.T) {
+	c := NewCapturer(&config.Config{
+		Command:   "true",
+		OutputDir: t.TempDir(),
+		Format:    "gif",
+		FrameHook: "echo {file}",
+	})
+	err := c.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "frame hook needs frame files")
+}
+
+// hookCapturer returns a Capturer whose Run gets past the hooks and then
+// fails, as it finds no browser.
+func hookCapturer(t *testing.T, before, after string) *Capturer {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run with sh")
+	}
+	return NewCapturer(&config.Config{
+		Command:    "bash",
+		TTydPort:   8080,
+		OutputDir:  t.TempDir(),
+		ChromePath: filepath.Join(t.TempDir(), "no-chrome"),
+		Env:        []string{"SEED=42"},
+		BeforeHook: before,
+		AfterHook:  after,
+	})
+}
+
+func TestCapturer_Run_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "hooks.log")
+	c := hookCapturer(t, "echo before $SEED $TERM >> "+log, "echo after >> "+log)
+
+	err := c.Run(context.Background())
+	require.ErrorIs(t, err, ErrChromeNotFound, "the run's own error is kept")
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "before 42 xterm-256color\nafter\n", string(data), "hooks get the command's environment")
+	require.NotEmpty(t, c.Stats().Phases)
+	assert.Equal(t, "before", c.Stats().Phases[0].Name)
+}
+
+func TestCapturer_Run_BeforeHookFails(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "after")
+	c := hookCapturer(t, "echo seeding; echo no database >&2; false", "touch "+marker)
+
+	err := c.Run(context.Background())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrChromeNotFound, "the run stops at the hook")
+	assert.Equal(t, "before hook failed: exit status 1: seeding\nno database", err.Error())
+	assert.FileExists(t, marker, "the after hook still runs")
+}
+
+func TestCapturer_Run_AfterHookFails(t *testing.T) {
+	c := hookCapturer(t, "true", "false")
+	err := c.Run(context.Background())
+	require.ErrorIs(t, err, ErrChromeNotFound, "an after hook failure does not replace the run's error")
+
+	c = hookCapturer(t, "", "exit 3")
+	assert.EqualError(t, c.runAfterHook(context.Background()), "after hook failed: exit status 3")
+}
+
+func TestCapturer_runShellHook_Context(t *testing.T) {
+	c := hookCapturer(t, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.runShellHook(ctx, "before", "sleep 30")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "before hook: ")
+	assert.Less(t, time.Since(start), 5*time.Second, "the hook is bounded by the run's context")
+
+	// Cleanup still runs once the run has timed out
+	marker := filepath.Join(t.TempDir(), "cleaned")
+	c.config.AfterHook = "touch " + marker
+	require.NoError(t, c.runAfterHook(ctx))
+	assert.FileExists(t, marker)
+}
@@ -0,0 +1,125 @@
+package capture
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// intervalCapturer owns the background goroutine that takes screenshots at
+// the configured interval. It is the single place that starts and stops
+// interval capture; Stop may be called any number of times.
+type intervalCapturer struct {
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+
+	// mu is held for the duration of each interval capture, so Pause
+	// returns only once any in-flight frame has been written.
+	mu     sync.Mutex
+	paused bool
+}
+
+// startIntervalCapture starts interval screenshots if an interval is
+// configured and no video is being recorded. The returned intervalCapturer
+// is always non-nil.
+func (c *Capturer) startIntervalCapture(ctx context.Context) *intervalCapturer {
+	ic := &intervalCapturer{stop: make(chan struct{})}
+	if c.config.ScreenshotInterval <= 0 || c.config.Video != "" {
+		return ic
+	}
+
+	ic.wg.Add(1)
+	go func() {
+		defer ic.wg.Done()
+		c.captureIntervalScreenshots(ctx, ic)
+	}()
+	return ic
+}
+
+// Pause suppresses interval frames until Resume is called. It blocks until
+// a capture already in progress has finished.
+func (ic *intervalCapturer) Pause() {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.paused = true
+}
+
+// Resume re-enables interval frames after Pause.
+func (ic *intervalCapturer) Resume() {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.paused = false
+}
+
+// Stop signals the interval goroutine to exit and waits for it to finish.
+// It is safe to call more than once.
+func (ic *intervalCapturer) Stop() {
+	ic.once.Do(func() {
+		close(ic.stop)
+	})
+	ic.wg.Wait()
+}
+
+// captureIntervalScreenshots captures screenshots at the configured interval
+// until ic is stopped. Ticks that arrive while ic is paused are skipped.
+func (c *Capturer) captureIntervalScreenshots(ctx context.Context, ic *intervalCapturer) {
+	if c.config.ScreenshotInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.config.ScreenshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ic.stop:
+			return
+		case <-ticker.C:
+			ic.mu.Lock()
+			if ic.paused {
+				ic.mu.Unlock()
+				continue
+			}
+			if err := c.captureIntervalFrame(ctx); err != nil {
+				// Log error but don't stop - interval screenshots are best effort
+				if c.config.Verbose {
+					fmt.Fprintf(os.Stderr, "Failed to capture interval screenshot: %v\n", err)
+				}
+			}
+			ic.mu.Unlock()
+		}
+	}
+}
+
+// captureIntervalFrame takes one interval screenshot. With Dedup, a frame
+// byte-identical to the previously written one is not written; it is only
+// recorded in the run's stats, so timing can still be reconstructed.
+func (c *Capturer) captureIntervalFrame(ctx context.Context) error {
+	if !c.config.Dedup {
+		return c.captureScreenshot(ctx, "", FrameInterval)
+	}
+
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	if c.framesHeldLocked(FrameInterval) {
+		return nil
+	}
+	buf, at, err := c.captureFrameLocked(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.lastFrame.path != "" && c.lastFrame.sum == sha256.Sum256(buf) {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Skipping interval screenshot identical to %s\n", c.lastFrame.path)
+		}
+		c.timeline.addFrame(FrameStat{Path: c.lastFrame.path, Kind: FrameInterval, Scene: c.sceneOf(c.lastFrame.path), Time: at, Duplicate: true, Compared: true})
+		return nil
+	}
+
+	return c.writeSequentialLocked(buf, at, FrameInterval)
+}
@@ -0,0 +1,399 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
+)
+
+// newFakeCapturer returns a Capturer whose browser operations are replaced
+// with in-memory fakes, so the session workflow runs without ttyd or Chrome.
+func newFakeCapturer(t *testing.T, cfg *config.Config) *Capturer {
+	t.Helper()
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = t.TempDir()
+	}
+	c := NewCapturer(cfg)
+	c.sendKey = func(context.Context, string) error { return nil }
+	c.insertText = func(context.Context, string) error { return nil }
+	c.captureFrame = func(context.Context) ([]byte, error) { return []byte("png"), nil }
+	c.readText = func(context.Context) (string, error) { return "", nil }
+	c.readPrompt = func(context.Context) (promptMarks, error) { return promptMarks{}, nil }
+	c.readAltScreen = func(context.Context) (bool, error) { return false, nil }
+	c.readScrollback = func(context.Context) (int, error) { return 0, nil }
+	c.readCursor = func(context.Context) (Cursor, error) { return Cursor{}, errNoTerm }
+	c.applyTheme = func(context.Context, theme.Theme) error { return nil }
+	c.applyFont = func(context.Context) error { return nil }
+	c.setFont = func(context.Context, string, int) error { return nil }
+	c.setViewport = func(context.Context, int, int) error { return nil }
+	c.resizeTerminal = func(context.Context, int, int) error { return nil }
+	return c
+}
+
+func TestIntervalCapturer_StopIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{name: "with interval goroutine running", interval: 5 * time.Millisecond},
+		{name: "with interval disabled", interval: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{ScreenshotInterval: tt.interval})
+
+			ic := c.startIntervalCapture(context.Background())
+			assert.NotPanics(t, func() {
+				ic.Stop()
+				ic.Stop()
+			})
+		})
+	}
+}
+
+func TestCapturer_runSession_ActionFailureWithInterval(t *testing.T) {
+	sendErr := errors.New("browser went away")
+
+	tests := []struct {
+		name    string
+		actions []script.Action
+		errMsg  string
+	}{
+		{
+			name:    "type action fails",
+			actions: []script.Action{{Kind: script.ActionType, Text: "ls", Speed: script.DefaultTypeSpeed}},
+			errMsg:  `send character 'l'`,
+		},
+		{
+			name:    "key action fails",
+			actions: []script.Action{{Kind: script.ActionKey, Key: "enter", Repeat: 1}},
+			errMsg:  `send key "enter"`,
+		},
+		{
+			name:    "ctrl action fails",
+			actions: []script.Action{{Kind: script.ActionCtrl, Key: "c"}},
+			errMsg:  "send Ctrl+c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{
+				ScreenshotInterval: time.Millisecond,
+				Actions:            tt.actions,
+			})
+			c.sendKey = func(context.Context, string) error {
+				// Let a few interval frames race with the failure.
+				time.Sleep(5 * time.Millisecond)
+				return sendErr
+			}
+
+			ctx := context.Background()
+			var err error
+			require.NotPanics(t, func() {
+				err = c.runSession(ctx, ctx)
+			})
+			require.Error(t, err)
+			assert.ErrorIs(t, err, sendErr)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestCapturer_runSession_NoCaptureWhileTyping(t *testing.T) {
+	const delay = 10 * time.Millisecond
+
+	tests := []struct {
+		name                 string
+		noCaptureWhileTyping bool
+		wantFramesInWindow   bool
+	}{
+		{name: "interval frames suppressed while typing", noCaptureWhileTyping: true, wantFramesInWindow: false},
+		{name: "interval frames taken while typing by default", noCaptureWhileTyping: false, wantFramesInWindow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{
+				ScreenshotInterval:   time.Millisecond,
+				NoCaptureWhileTyping: tt.noCaptureWhileTyping,
+				Actions: []script.Action{
+					// Slow enough that every character is its own key press
+					{Kind: script.ActionType, Text: "abc", Speed: typeChunkInterval, Delay: delay},
+					{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+					{Kind: script.ActionType, Text: "xyz", Speed: typeChunkInterval, Delay: delay},
+				},
+			})
+
+			var mu sync.Mutex
+			var frames []time.Time
+			keys := map[string]time.Time{}
+			c.sendKey = func(_ context.Context, key string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				keys[key] = time.Now()
+				return nil
+			}
+			c.captureFrame = func(context.Context) ([]byte, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				frames = append(frames, time.Now())
+				return []byte("png"), nil
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			windows := [][2]time.Time{
+				{keys["a"], keys["c"].Add(delay)},
+				{keys["x"], keys["z"].Add(delay)},
+			}
+			inWindow := 0
+			for _, f := range frames {
+				for _, w := range windows {
+					if f.After(w[0]) && f.Before(w[1]) {
+						inWindow++
+					}
+				}
+			}
+
+			if tt.wantFramesInWindow {
+				assert.Positive(t, inWindow)
+			} else {
+				assert.Zero(t, inWindow, "no frame may be taken inside a typing window")
+			}
+		})
+	}
+}
+
+func TestCapturer_captureIntervalFrame_Dedup(t *testing.T) {
+	tests := []struct {
+		name        string
+		dedup       bool
+		frames      []string
+		wantWritten []string
+		wantDups    int
+	}{
+		{
+			name:        "writes every frame without dedup",
+			frames:      []string{"A", "A", "A", "B"},
+			wantWritten: []string{"A", "A", "A", "B"},
+		},
+		{
+			name:        "skips frames identical to the previous one",
+			dedup:       true,
+			frames:      []string{"A", "A", "A", "B", "B", "A"},
+			wantWritten: []string{"A", "B", "A"},
+			wantDups:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Dedup: tt.dedup})
+			enc := &recordingEncoder{}
+			c.encoder = enc
+
+			next := 0
+			c.captureFrame = func(context.Context) ([]byte, error) {
+				buf := []byte(tt.frames[next])
+				next++
+				return buf, nil
+			}
+
+			// The first frame stands in for the initial screenshot
+			require.NoError(t, c.captureScreenshot(context.Background(), "", FrameInterval))
+			for range tt.frames[1:] {
+				require.NoError(t, c.captureIntervalFrame(context.Background()))
+			}
+
+			var written []string
+			for _, f := range enc.frames {
+				written = append(written, string(f.Data))
+			}
+			assert.Equal(t, tt.wantWritten, written)
+			assert.Equal(t, len(tt.wantWritten), c.screenshotCount, "skipped frames do not use up sequence numbers")
+
+			stats := c.Stats()
+			require.Len(t, stats.Frames, len(tt.frames), "skipped frames are still recorded")
+			dups := 0
+			for _, f := range stats.Frames {
+				if f.Duplicate {
+					dups++
+					assert.NotEmpty(t, f.Path)
+				}
+			}
+			assert.Equal(t, tt.wantDups, dups)
+		})
+	}
+}
+
+func TestCapturer_runSession_DedupKeepsFinalFrame(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{Dedup: true})
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	require.Len(t, enc.frames, 2, "the final frame is written even when identical")
+}
+
+func TestCapturer_runSession_NameTemplateNumbersFollowWriteOrder(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		NameTemplate:       "frame_{n}.png",
+		ScreenshotInterval: time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionScreenshot},
+			{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+			{Kind: script.ActionScreenshot},
+		},
+	})
+	enc := &recordingEncoder{}
+	c.encoder = enc
+	// A slow capture widens the window in which interval and action frames
+	// race for the encoder
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		time.Sleep(time.Millisecond)
+		return []byte("png"), nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	require.Greater(t, len(enc.frames), 4)
+	for i, f := range enc.frames {
+		assert.Equal(t, fmt.Sprintf("frame_%d.png", i+1), filepath.Base(f.Path))
+	}
+}
+
+// failingEncoder fails the Frame calls listed in fail, counted from 1, and
+// records the others.
+type failingEncoder struct {
+	recordingEncoder
+	calls int
+	fail  map[int]bool
+}
+
+func (e *failingEncoder) Frame(f Frame) error {
+	e.calls++
+	if e.fail[e.calls] {
+		return errors.New("disk full")
+	}
+	return e.recordingEncoder.Frame(f)
+}
+
+func TestCapturer_captureIntervalFrame_FailuresKeepNumbersContiguous(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dedup %v", dedup), func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Dedup: dedup})
+			enc := &failingEncoder{fail: map[int]bool{2: true, 3: true}}
+			c.encoder = enc
+			captures := 0
+			c.captureFrame = func(context.Context) ([]byte, error) {
+				captures++
+				if captures%3 == 0 {
+					return nil, errors.New("target closed")
+				}
+				return []byte(fmt.Sprintf("png %d", captures)), nil
+			}
+
+			failed := 0
+			for range 9 {
+				if err := c.captureIntervalFrame(context.Background()); err != nil {
+					failed++
+				}
+			}
+
+			assert.Equal(t, 5, failed, "three captures and two writes fail")
+			require.Len(t, enc.frames, 4)
+			for i, f := range enc.frames {
+				assert.Equal(t, fmt.Sprintf("screenshot_%03d.png", i+1), filepath.Base(f.Path))
+			}
+		})
+	}
+}
+
+func TestCapturer_captureIntervalFrame_WrittenFrameKeepsNumber(t *testing.T) {
+	// The frames are not PNGs, so the simulation after each write fails
+	c := newFakeCapturer(t, &config.Config{SimulateCVD: []string{"protanopia"}})
+	enc := &recordingEncoder{}
+	c.encoder = enc
+	captures := 0
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		captures++
+		return []byte(fmt.Sprintf("png %d", captures)), nil
+	}
+
+	for range 2 {
+		assert.ErrorContains(t, c.captureIntervalFrame(context.Background()), "simulate protanopia")
+	}
+
+	require.Len(t, enc.frames, 2)
+	assert.Equal(t, "screenshot_001.png", filepath.Base(enc.frames[0].Path))
+	assert.Equal(t, "screenshot_002.png", filepath.Base(enc.frames[1].Path), "the written frame is not overwritten")
+	assert.Equal(t, 2, c.screenshotCount)
+}
+
+func TestCapturer_runSession_FramesAreCapturedOneAtATime(t *testing.T) {
+	clock := newFakeClock()
+	c := newFakeCapturer(t, &config.Config{
+		ScreenshotInterval: time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionScreenshot},
+			{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+			{Kind: script.ActionScreenshot, Name: "middle"},
+			{Kind: script.ActionSleep, Duration: 20 * time.Millisecond},
+		},
+	})
+	c.now = clock.Now
+	c.timeline = newTimeline(clock.Now)
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	// Each capture takes a millisecond of real time, in which the other
+	// flow may try to capture too, and ends a millisecond later on the
+	// clock
+	var inFlight, overlaps atomic.Int32
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		if inFlight.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer inFlight.Add(-1)
+		time.Sleep(time.Millisecond)
+		clock.Advance(time.Millisecond)
+		return []byte("png"), nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	assert.Zero(t, overlaps.Load(), "captures overlapped")
+	require.Greater(t, len(enc.frames), 4)
+	n := 0
+	for i, f := range enc.frames {
+		if i > 0 {
+			assert.True(t, f.Time.After(enc.frames[i-1].Time), "frame %d (%s) is not later than the one before", i, f.Path)
+		}
+		if filepath.Base(f.Path) != "middle.png" {
+			n++
+			assert.Equal(t, fmt.Sprintf("screenshot_%03d.png", n), filepath.Base(f.Path))
+		}
+	}
+
+	frames := c.Stats().Frames
+	require.Len(t, frames, len(enc.frames))
+	assert.Equal(t, FrameFinal, frames[len(frames)-1].Kind, "the final frame is last")
+}
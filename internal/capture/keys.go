@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"fmt"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp/kb"
+
+	inputpkg "github.com/yarlson/scr/internal/input"
+)
+
+// namedKeys maps the CDP key codes of special keys to the characters
+// chromedp.KeyEvent encodes as those keys. Without the mapping, a code such
+// as "Enter" would be typed letter by letter.
+var namedKeys = map[string]string{
+	"Enter":      kb.Enter,
+	"Escape":     kb.Escape,
+	"Tab":        kb.Tab,
+	"Space":      " ",
+	"ArrowUp":    kb.ArrowUp,
+	"ArrowDown":  kb.ArrowDown,
+	"ArrowLeft":  kb.ArrowLeft,
+	"ArrowRight": kb.ArrowRight,
+	"Home":       kb.Home,
+	"End":        kb.End,
+	"PageUp":     kb.PageUp,
+	"PageDown":   kb.PageDown,
+	"Backspace":  kb.Backspace,
+	"Delete":     kb.Delete,
+}
+
+// cdpModifiers maps key modifiers to their CDP flags, in a fixed order.
+var cdpModifiers = []struct {
+	mod  inputpkg.Modifier
+	flag input.Modifier
+}{
+	{inputpkg.ModCtrl, input.ModifierCtrl},
+	{inputpkg.ModAlt, input.ModifierAlt},
+	{inputpkg.ModShift, input.ModifierShift},
+}
+
+// keyEvent resolves a key such as "enter", "q" or "Ctrl+Shift+Tab" into
+// the text chromedp.KeyEvent takes and the modifiers to hold while it is
+// pressed.
+func keyEvent(key string) (text string, mods []input.Modifier, err error) {
+	code, modifiers, err := inputpkg.ParseKey(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("lookup key code for %q: %w", key, err)
+	}
+	text = code
+	if named, ok := namedKeys[code]; ok {
+		text = named
+	}
+	for _, m := range cdpModifiers {
+		if modifiers&m.mod != 0 {
+			mods = append(mods, m.flag)
+		}
+	}
+	return text, mods, nil
+}
@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockHeldError reports that a live run holds a lock file.
+type lockHeldError struct {
+	path  string
+	pid   int
+	since time.Time // zero if the lock file does not record it
+}
+
+func (e *lockHeldError) Error() string {
+	return fmt.Sprintf("%s is held by pid %d", e.path, e.pid)
+}
+
+// holder describes the process holding the lock, for messages.
+func (e *lockHeldError) holder() string {
+	if e.since.IsZero() {
+		return fmt.Sprintf("pid %d", e.pid)
+	}
+	return fmt.Sprintf("pid %d, started %s", e.pid, e.since.Format(time.DateTime))
+}
+
+// heldLocks counts the locks this process holds by the token of the run
+// that took them, so that a lock holding this process's PID can be told
+// from one left by an earlier process that had the same PID.
+var heldLocks = struct {
+	sync.Mutex
+	tokens map[string]int
+}{tokens: map[string]int{}}
+
+// acquireLock creates the lock file path holding this process's PID, the
+// time now and token, which names the run taking the lock, failing if it
+// exists. A *lockHeldError means a live run holds it: one in another live
+// process, or one in this process whose token still holds a lock. A lock
+// left behind by a process that is gone, or with a token of no live run in
+// this process, is taken over. The returned function removes the lock.
+func acquireLock(path, token string, now time.Time) (release func(), err error) {
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, werr := fmt.Fprintf(f, "%d\n%s\n%s\n", os.Getpid(), now.Format(time.RFC3339), token)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				_ = os.Remove(path)
+				return nil, werr
+			}
+			holdLock(token)
+			return sync.OnceFunc(func() {
+				_ = os.Remove(path)
+				releaseLock(token)
+			}), nil
+		}
+		if !errors.Is(err, os.ErrExist) || attempt > 0 {
+			return nil, err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.SplitN(string(data), "\n", 3)
+		lines = append(lines, "", "")
+		pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+		if err == nil && lockLive(pid, strings.TrimSpace(lines[2])) {
+			since, _ := time.Parse(time.RFC3339, strings.TrimSpace(lines[1]))
+			return nil, &lockHeldError{path: path, pid: pid, since: since}
+		}
+		// Stale lock from a run that did not clean up
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// lockLive reports whether the run that wrote a lock with pid and token is
+// still going.
+func lockLive(pid int, token string) bool {
+	if pid != os.Getpid() {
+		return processAlive(pid)
+	}
+	if token == "" {
+		return false
+	}
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+	return heldLocks.tokens[token] > 0
+}
+
+// holdLock records that the run with token holds one more lock.
+func holdLock(token string) {
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+	heldLocks.tokens[token]++
+}
+
+// releaseLock records that the run with token let go of one of its locks.
+func releaseLock(token string) {
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+	if heldLocks.tokens[token]--; heldLocks.tokens[token] <= 0 {
+		delete(heldLocks.tokens, token)
+	}
+}
@@ -0,0 +1,106 @@
+package capture
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// liveProcess starts a process that outlives the test, standing in for
+// another scr run, and returns its PID.
+func liveProcess(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	return cmd.Process.Pid
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	release, err := acquireLock(path, "run-1", now)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n2025-01-02T10:00:00Z\nrun-1\n", string(data))
+
+	release()
+	assert.NoFileExists(t, path)
+}
+
+func TestAcquireLock_Held(t *testing.T) {
+	pid := liveProcess(t)
+	path := filepath.Join(t.TempDir(), "test.lock")
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n2025-01-02T10:00:00Z\n"), 0o600))
+
+	_, err := acquireLock(path, "run", time.Now())
+	var held *lockHeldError
+	require.True(t, errors.As(err, &held), "got %v", err)
+	assert.Equal(t, pid, held.pid)
+	assert.Equal(t, "pid "+strconv.Itoa(pid)+", started 2025-01-02 10:00:00", held.holder())
+	assert.FileExists(t, path, "another run's lock is left alone")
+}
+
+func TestAcquireLock_Stale(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		lock string
+	}{
+		{name: "exited process", lock: strconv.Itoa(cmd.Process.Pid)},
+		{name: "our own pid", lock: strconv.Itoa(os.Getpid())},
+		{name: "our own pid and a finished run", lock: strconv.Itoa(os.Getpid()) + "\n2025-01-02T10:00:00Z\nrun-0\n"},
+		{name: "garbage", lock: "not a pid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.lock")
+			require.NoError(t, os.WriteFile(path, []byte(tt.lock), 0o600))
+
+			release, err := acquireLock(path, "run", time.Now())
+			require.NoError(t, err)
+			defer release()
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(data), strconv.Itoa(os.Getpid())+"\n"))
+		})
+	}
+}
+
+func TestAcquireLock_HeldInThisProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	release, err := acquireLock(path, "run-1", time.Now())
+	require.NoError(t, err)
+
+	_, err = acquireLock(path, "run-2", time.Now())
+	var held *lockHeldError
+	require.True(t, errors.As(err, &held), "got %v", err)
+	assert.Equal(t, os.Getpid(), held.pid)
+	assert.FileExists(t, path, "the other run's lock is left alone")
+
+	release()
+	release()
+	release, err = acquireLock(path, "run-2", time.Now())
+	require.NoError(t, err)
+	release()
+}
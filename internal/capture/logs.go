@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/logfile"
+)
+
+// openLog opens the configured log file, or returns nil when logging is off.
+func (c *Capturer) openLog() (*logfile.Writer, error) {
+	if c.config.LogFile == "" {
+		return nil, nil
+	}
+	w, err := logfile.Open(c.config.LogFile, c.config.LogMaxSize, c.config.LogKeep)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	return w, nil
+}
+
+// browserLogOptions sends chromedp's log, error and protocol trace output
+// to w. The trace, which holds every key sent, is dropped while hush
+// returns true. With no log, chromedp keeps its defaults.
+func browserLogOptions(w io.Writer, hush func() bool) []chromedp.ContextOption {
+	if w == nil {
+		return nil
+	}
+	trace := logPrintf(w, "cdp trace")
+	return []chromedp.ContextOption{
+		chromedp.WithLogf(logPrintf(w, "cdp")),
+		chromedp.WithErrorf(logPrintf(w, "cdp error")),
+		chromedp.WithDebugf(func(format string, args ...any) {
+			if !hush() {
+				trace(format, args...)
+			}
+		}),
+	}
+}
+
+// logPrintf returns a printf-style function that writes timestamped,
+// prefixed lines to w.
+func logPrintf(w io.Writer, prefix string) func(string, ...any) {
+	return func(format string, args ...any) {
+		fmt.Fprintf(w, "%s %s: %s\n", time.Now().Format("15:04:05.000"), prefix, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,179 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFilename is the file in the output directory that describes the
+// run which produced the frames.
+const ManifestFilename = "manifest.json"
+
+// ManifestVersion is the format version of Manifest. It changes only when
+// fields are removed or change meaning; new fields may be added freely.
+const ManifestVersion = 1
+
+// Manifest is the content of ManifestFilename.
+type Manifest struct {
+	Version int    `json:"version"`
+	Command string `json:"command,omitempty"`
+	// URL is the terminal page when attaching to an existing ttyd.
+	URL    string `json:"url,omitempty"`
+	Script string `json:"script,omitempty"`
+	// Params are the resolved values of the script's parameters, except
+	// secret ones, so the run can be repeated.
+	Params map[string]string `json:"params,omitempty"`
+	// Viewport is the requested browser viewport, in CSS pixels.
+	Viewport ManifestViewport `json:"viewport"`
+	// Interval is the periodic capture interval, e.g. "500ms"; "0s" when
+	// periodic capture is off.
+	Interval string `json:"interval"`
+	// NoColor is set when the command was asked for monochrome output, and
+	// Grayscale when the frames were converted to grayscale.
+	NoColor   bool `json:"noColor,omitempty"`
+	Grayscale bool `json:"grayscale,omitempty"`
+	// Anonymized is set when the real user name, host name and home
+	// directory were replaced by placeholders in the manifest.
+	Anonymized bool `json:"anonymized,omitempty"`
+	// Start is the wall-clock time the terminal became ready, the origin
+	// of every frame's OffsetMS.
+	Start time.Time `json:"start"`
+	// Environment is the ttyd and browser setup the frames were rendered
+	// with.
+	Environment Environment `json:"environment"`
+	// Scrollback is the most lines of output that scrolled off the top of
+	// the terminal during the run, out of every later frame.
+	Scrollback int `json:"scrollback"`
+	// Frames lists every frame of the run, including those of Scenes, in
+	// the order they were captured. Frames are captured one at a time, so
+	// their times increase and sequential names are numbered in this order.
+	Frames []ManifestFrame `json:"frames"`
+	// Scenes are the scenes started by Scene actions, in order, each with
+	// its own frames.
+	Scenes []ManifestScene `json:"scenes,omitempty"`
+}
+
+// ManifestScene describes a scene and the frames captured during it.
+type ManifestScene struct {
+	Name string `json:"name"`
+	// Dir is the scene's directory within the output directory.
+	Dir    string          `json:"dir"`
+	Frames []ManifestFrame `json:"frames"`
+}
+
+// ManifestViewport is the viewport size requested for the run.
+type ManifestViewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ManifestFrame describes one captured frame.
+type ManifestFrame struct {
+	// File is the frame's path within the output directory, with forward
+	// slashes, such as "intro/screenshot_001.png" for a frame of a scene.
+	// For a Duplicate it is the earlier, identical frame.
+	File string    `json:"file"`
+	Kind FrameKind `json:"kind"`
+	// OffsetMS is the capture time in milliseconds after Start.
+	OffsetMS int64     `json:"offsetMs"`
+	Time     time.Time `json:"time"`
+	// Action is the index of the script action in progress or last run
+	// when the frame was captured, or -1 before the first one.
+	Action int `json:"action"`
+	// Duplicate marks interval frames skipped by --dedup.
+	Duplicate bool `json:"duplicate,omitempty"`
+	// Burst numbers the Burst action a burst frame belongs to, from 1, so
+	// the frames of one burst can be grouped.
+	Burst int `json:"burst,omitempty"`
+	// Dropped marks interval frames that --target-frames left out of the
+	// animation.
+	Dropped bool `json:"dropped,omitempty"`
+}
+
+// manifest builds the manifest for the current run.
+func (c *Capturer) manifest() Manifest {
+	stats := c.Stats()
+	m := Manifest{
+		Version:     ManifestVersion,
+		Command:     c.config.CommandLine(),
+		URL:         c.config.TerminalURL,
+		Script:      c.config.Script,
+		Viewport:    ManifestViewport{Width: c.width, Height: c.height},
+		Interval:    c.config.ScreenshotInterval.String(),
+		NoColor:     c.config.NoColorSession,
+		Grayscale:   c.config.Grayscale,
+		Anonymized:  c.config.Anonymize,
+		Start:       stats.Origin,
+		Environment: c.env,
+		Scrollback:  c.scrollback,
+		Frames:      make([]ManifestFrame, 0, len(stats.Frames)),
+	}
+	for _, p := range c.config.Params {
+		if p.Secret {
+			continue
+		}
+		if m.Params == nil {
+			m.Params = map[string]string{}
+		}
+		m.Params[p.Name] = p.Value
+	}
+	c.mu.Lock()
+	for _, s := range c.scenes {
+		m.Scenes = append(m.Scenes, ManifestScene{Name: s.Name, Dir: s.Dir, Frames: []ManifestFrame{}})
+	}
+	c.mu.Unlock()
+	dropped := map[string]bool{}
+	if s, ok := c.encoder.(Sampler); ok {
+		for _, path := range s.Dropped() {
+			dropped[path] = true
+		}
+	}
+	for _, f := range stats.Frames {
+		frame := ManifestFrame{
+			File:      c.manifestFile(f.Path),
+			Kind:      f.Kind,
+			OffsetMS:  f.Offset.Milliseconds(),
+			Time:      f.Time,
+			Action:    f.Action,
+			Duplicate: f.Duplicate,
+			Burst:     f.Burst,
+			Dropped:   !f.Duplicate && dropped[f.Path],
+		}
+		m.Frames = append(m.Frames, frame)
+		for i := range m.Scenes {
+			if f.Scene != "" && m.Scenes[i].Dir == f.Scene {
+				m.Scenes[i].Frames = append(m.Scenes[i].Frames, frame)
+			}
+		}
+	}
+	return m
+}
+
+// manifestFile returns the path of a frame file relative to the output
+// directory, as recorded in the manifest.
+func (c *Capturer) manifestFile(path string) string {
+	rel, err := filepath.Rel(c.outputDir(), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeManifest writes the run's manifest to the output directory.
+func (c *Capturer) writeManifest() error {
+	data, err := json.MarshalIndent(c.manifest(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	data = append([]byte(c.anonymizer.replace(string(data))), '\n')
+	path := filepath.Join(c.outputDir(), ManifestFilename)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	c.sums.add(ManifestFilename, data)
+	return nil
+}
@@ -0,0 +1,129 @@
+package capture
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// update rewrites golden files with the current output.
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestCapturer_writeManifest_Golden(t *testing.T) {
+	clock := newFakeClock()
+	c := newFakeCapturer(t, &config.Config{
+		Command: "vim notes.txt",
+		Script:  `Param FILE default 'notes.txt' Param TOKEN secret Enter Screenshot "after enter" Enter`,
+		Params: []script.Param{
+			{Name: "FILE", Default: "notes.txt", HasDefault: true, Value: "todo.txt"},
+			{Name: "TOKEN", Secret: true, Value: "hunter2"},
+		},
+		ScreenshotInterval: 0,
+		Width:              1280,
+		Height:             720,
+		Actions: []script.Action{
+			{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+			{Kind: script.ActionScreenshot, Name: "after enter"},
+			{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+		},
+	})
+	c.now = clock.Now
+	c.timeline = newTimeline(clock.Now)
+	c.width, c.height = viewportSize(c.config)
+	c.env = Environment{
+		TTyd:      "1.7.4-68c0ddb",
+		Browser:   "HeadlessChrome/120.0.6099.109",
+		Protocol:  "1.3",
+		Revision:  "@3c3a5f8",
+		UserAgent: "Mozilla/5.0 HeadlessChrome/120.0.6099.109",
+		Viewport:  PageViewport{Width: 1280, Height: 720, DeviceScaleFactor: 2},
+	}
+	c.sendKey = func(context.Context, string) error {
+		clock.Advance(100 * time.Millisecond)
+		return nil
+	}
+
+	// Interval frames run on a real ticker, so they are off to keep the
+	// output deterministic
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	require.NoError(t, c.writeManifest())
+	got, err := os.ReadFile(filepath.Join(c.config.OutputDir, ManifestFilename))
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "manifest.golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, got, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "rerun with -update to accept changes")
+}
+
+// samplingEncoder is an Encoder that drops the given frames.
+type samplingEncoder struct {
+	fileEncoder
+	dropped []string
+}
+
+func (e *samplingEncoder) Dropped() []string { return e.dropped }
+
+func TestCapturer_manifest_Dropped(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{Command: "top"})
+	dir := c.config.OutputDir
+	for i, kind := range []FrameKind{FrameInitial, FrameInterval, FrameInterval, FrameFinal} {
+		c.timeline.addFrame(FrameStat{Path: filepath.Join(dir, fmt.Sprintf("screenshot_%03d.png", i+1)), Kind: kind})
+	}
+	// A duplicate names the frame it repeats, but never reached the
+	// encoder itself
+	c.timeline.addFrame(FrameStat{Path: filepath.Join(dir, "screenshot_002.png"), Kind: FrameInterval, Duplicate: true})
+	c.encoder = &samplingEncoder{dropped: []string{filepath.Join(dir, "screenshot_002.png")}}
+
+	var dropped []bool
+	for _, f := range c.manifest().Frames {
+		dropped = append(dropped, f.Dropped)
+	}
+	assert.Equal(t, []bool{false, true, false, false, false}, dropped)
+}
+
+func TestEnvironment_String(t *testing.T) {
+	tests := []struct {
+		name string
+		env  Environment
+		want string
+	}{
+		{
+			name: "started ttyd",
+			env: Environment{
+				TTyd: "1.7.4", Browser: "HeadlessChrome/120.0", Protocol: "1.3", UserAgent: "UA",
+				Viewport: PageViewport{Width: 1280, Height: 720, DeviceScaleFactor: 1},
+			},
+			want: `ttyd 1.7.4, HeadlessChrome/120.0 (protocol 1.3), viewport 1280x720@1x, user agent "UA"`,
+		},
+		{
+			name: "attached ttyd",
+			env: Environment{
+				Browser: "HeadlessChrome/120.0", Protocol: "1.3", UserAgent: "UA",
+				Viewport: PageViewport{Width: 800, Height: 600, DeviceScaleFactor: 1.5},
+			},
+			want: `ttyd n/a, HeadlessChrome/120.0 (protocol 1.3), viewport 800x600@1.5x, user agent "UA"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.env.String())
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/yarlson/scr/internal/theme"
+)
+
+// padFrame surrounds the PNG data with Config.Padding pixels of
+// Config.Background, or of the terminal background when none is set.
+func (c *Capturer) padFrame(data []byte) ([]byte, error) {
+	var bg color.Color
+	if c.config.Background != "" {
+		rgba, err := theme.ParseColor(c.config.Background)
+		if err != nil {
+			return nil, fmt.Errorf("bg: %w", err)
+		}
+		bg = rgba
+	}
+	return padPNG(data, c.config.Padding, bg)
+}
+
+// padPNG returns the PNG data on a canvas padding pixels larger on every
+// side, filled with bg. A nil bg takes the color of the image's top-left
+// pixel, which for a terminal screenshot is the terminal background.
+func padPNG(data []byte, padding int, bg color.Color) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+
+	bounds := src.Bounds()
+	if bg == nil {
+		bg = src.At(bounds.Min.X, bounds.Min.Y)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx()+2*padding, bounds.Dy()+2*padding))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	draw.Draw(dst, bounds.Sub(bounds.Min).Add(image.Pt(padding, padding)), src, bounds.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// tinyTerminalPNG is a 3x2 frame: a dark background with a light and a red
+// "glyph" pixel, so the padding around it is easy to see.
+func tinyTerminalPNG(t *testing.T) []byte {
+	t.Helper()
+	return framePNG(t, 3, 2, func(x, y int) color.Color {
+		switch {
+		case x == 1 && y == 0:
+			return color.NRGBA{R: 0xf8, G: 0xf8, B: 0xf2, A: 0xff}
+		case x == 2 && y == 1:
+			return color.NRGBA{R: 0xff, G: 0x55, B: 0x55, A: 0xff}
+		}
+		return color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}
+	})
+}
+
+func TestPadPNG_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		bg     color.Color
+		golden string
+	}{
+		{name: "background color", bg: color.RGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff}, golden: "padded_bg.golden.png"},
+		{name: "terminal background", golden: "padded_terminal.golden.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := padPNG(tinyTerminalPNG(t), 2, tt.bg)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "rerun with -update to accept changes")
+
+			img, err := png.Decode(bytes.NewReader(got))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, 7, 6), img.Bounds())
+		})
+	}
+}
+
+func TestPadPNG_Pixels(t *testing.T) {
+	got, err := padPNG(tinyTerminalPNG(t), 2, color.RGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff})
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(got))
+	require.NoError(t, err)
+
+	at := func(x, y int) color.NRGBA { return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA) }
+	assert.Equal(t, color.NRGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff}, at(0, 0), "padding")
+	assert.Equal(t, color.NRGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff}, at(6, 5), "padding")
+	assert.Equal(t, color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}, at(2, 2), "frame moved in by the padding")
+	assert.Equal(t, color.NRGBA{R: 0xf8, G: 0xf8, B: 0xf2, A: 0xff}, at(3, 2))
+	assert.Equal(t, color.NRGBA{R: 0xff, G: 0x55, B: 0x55, A: 0xff}, at(4, 3))
+
+	_, err = padPNG([]byte("not a png"), 2, nil)
+	assert.ErrorContains(t, err, "decode frame")
+}
+
+func TestCapturer_runSession_Padding(t *testing.T) {
+	frame := tinyTerminalPNG(t)
+
+	tests := []struct {
+		name    string
+		padding int
+		bg      string
+		// want is the top-left pixel of every frame written.
+		want color.NRGBA
+		// same is whether frames are written exactly as captured.
+		same bool
+	}{
+		{name: "no padding keeps frames byte for byte", want: color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}, same: true},
+		{name: "padding in the terminal background", padding: 4, want: color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}},
+		{name: "padding in a color", padding: 4, bg: "#fff", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := newFakeCapturer(t, &config.Config{OutputDir: dir, Padding: tt.padding, Background: tt.bg})
+			c.captureFrame = func(context.Context) ([]byte, error) { return frame, nil }
+			c.encoder = &fileEncoder{}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			for _, name := range []string{"screenshot_001.png", "screenshot_002.png"} {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				if tt.same {
+					assert.Equal(t, frame, data, name)
+				}
+				img, err := png.Decode(bytes.NewReader(data))
+				require.NoError(t, err)
+				assert.Equal(t, image.Rect(0, 0, 3+2*tt.padding, 2+2*tt.padding), img.Bounds(), name)
+				assert.Equal(t, tt.want, pixelOf(t, data), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// Driver is the terminal a Player acts on: it sends keys and text to it
+// and reads back what it shows.
+type Driver interface {
+	// SendKey presses a key, such as "a", "enter" or "ctrl+c"; see the
+	// keys Key actions accept.
+	SendKey(ctx context.Context, key string) error
+	// InsertText types text in one go, as an input method would commit it.
+	InsertText(ctx context.Context, text string) error
+	// ReadText returns the terminal's text, one line per row.
+	ReadText(ctx context.Context) (string, error)
+	// ReadAltScreen reports whether the terminal shows its alternate
+	// screen.
+	ReadAltScreen(ctx context.Context) (bool, error)
+}
+
+// ChromeDriver is the Driver of the ttyd page in a chromedp context: the
+// contexts passed to it must come from chromedp.NewContext, with the page
+// showing the terminal.
+type ChromeDriver struct{}
+
+// SendKey dispatches key with CDP Input.dispatchKeyEvent.
+func (ChromeDriver) SendKey(ctx context.Context, key string) error {
+	text, mods, err := keyEvent(key)
+	if err != nil {
+		return err
+	}
+	return chromedp.Run(ctx, chromedp.KeyEvent(text, chromedp.KeyModifiers(mods...)))
+}
+
+// InsertText types text with CDP Input.insertText.
+func (ChromeDriver) InsertText(ctx context.Context, text string) error {
+	return insertTerminalText(ctx, text)
+}
+
+// ReadText reads the text of the xterm.js buffer with TerminalReader.
+func (ChromeDriver) ReadText(ctx context.Context) (string, error) {
+	return TerminalReader{}.Text(ctx)
+}
+
+// ReadAltScreen reads which xterm.js buffer is active with TerminalReader.
+func (ChromeDriver) ReadAltScreen(ctx context.Context) (bool, error) {
+	return TerminalReader{}.altScreen(ctx)
+}
+
+// Player executes script actions against a terminal with the timing of a
+// capture: it types, presses keys, sleeps, waits for output and sends
+// signals, and stops as soon as its context is done. It takes no
+// screenshots; Capturer composes one with its frame machinery.
+type Player struct {
+	// Driver is the terminal the actions go to.
+	Driver Driver
+
+	// EscapeDelay is the pause after each lone Escape keypress; see
+	// Config.EscapeDelay.
+	EscapeDelay time.Duration
+	// TypeChunkSize is the length above which Type text is typed with flow
+	// control; zero uses config.DefaultTypeChunkSize. NoTypeVerify turns
+	// flow control off; see Config.NoTypeVerify.
+	TypeChunkSize int
+	NoTypeVerify  bool
+	// PromptPattern matches the last non-blank terminal line while the
+	// shell shows its prompt, for Wait Prompt; empty matches a line ending
+	// in $, #, % or >.
+	PromptPattern string
+
+	// Signal delivers the signals of Signal actions; nil fails them.
+	Signal func(name string) error
+
+	// Log, if set, receives a line for every step, as --verbose prints.
+	Log io.Writer
+
+	// Perform executes the actions that concern the capture rather than
+	// the terminal: Screenshot, Burst, Scene, Hide, Show and Set. Nil skips
+	// them.
+	Perform func(ctx context.Context, action script.Action, index int) error
+
+	// Before and After, if set, are called around each action; After gets
+	// the action's error and returns the one Play reports.
+	Before func(action script.Action, index int)
+	After  func(action script.Action, index int, err error) error
+
+	// panes are the tmux panes Pane actions select from, and prompt, if
+	// set, builds the check of a Wait Prompt in place of PromptPattern.
+	// Capturer sets them from its config.
+	panes  []config.TmuxPane
+	prompt func() (ready func(ctx context.Context, text string) (bool, error), what string, err error)
+}
+
+// NewPlayer returns a Player for driver with the command line's defaults.
+func NewPlayer(driver Driver) *Player {
+	return &Player{Driver: driver, EscapeDelay: config.DefaultEscapeDelay}
+}
+
+// defaultPromptPattern is the PromptPattern used when it is empty.
+const defaultPromptPattern = `[$#%>]$`
+
+// Play executes actions in order and returns the first error. All waits
+// end when ctx is done, with its error.
+func (p *Player) Play(ctx context.Context, actions []script.Action) error {
+	for i, action := range actions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p.Before != nil {
+			p.Before(action, i)
+		}
+		err := p.do(ctx, action, i)
+		if p.After != nil {
+			err = p.After(action, i, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do executes a single action based on its kind.
+func (p *Player) do(ctx context.Context, action script.Action, index int) error {
+	switch action.Kind {
+	case script.ActionType:
+		return p.typeText(ctx, action, index)
+	case script.ActionSleep:
+		return p.sleep(ctx, action, index)
+	case script.ActionKey:
+		return p.key(ctx, action, index)
+	case script.ActionCtrl:
+		return p.ctrl(ctx, action, index)
+	case script.ActionWait:
+		return p.wait(ctx, action, index)
+	case script.ActionSignal:
+		return p.signal(action, index)
+	case script.ActionPane:
+		return p.pane(ctx, action, index)
+	case script.ActionScreenshot, script.ActionBurst, script.ActionScene,
+		script.ActionHide, script.ActionShow, script.ActionSet:
+		if p.Perform == nil {
+			p.logf("Skipping %s (action %d)\n", action, index)
+			return nil
+		}
+		return p.Perform(ctx, action, index)
+	default:
+		return fmt.Errorf("unknown action kind: %v", action.Kind)
+	}
+}
+
+// logf writes a line to Log, if set.
+func (p *Player) logf(format string, args ...any) {
+	if p.Log != nil {
+		fmt.Fprintf(p.Log, format, args...)
+	}
+}
+
+// pause waits for d, or until ctx is done.
+func pause(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// typeText executes a type action by sending each character with per-char delay.
+func (p *Player) typeText(ctx context.Context, action script.Action, index int) error {
+	if action.Secret {
+		return p.typeSecret(ctx, action, index)
+	}
+
+	speed := action.CharDelay()
+	limit, verify := 0, false
+	if utf8.RuneCountInString(action.Text) > p.typeChunkSize() {
+		limit, verify = p.typeChunkSize(), !p.NoTypeVerify
+	}
+	for _, chunk := range typeChunks(action.Text, speed, limit) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// A single character is a real key press; longer chunks are
+		// inserted in one round trip
+		n := utf8.RuneCountInString(chunk)
+		if n == 1 {
+			p.logf("Sending character: %s\n", chunk)
+			if err := p.Driver.SendKey(ctx, chunk); err != nil {
+				char, _ := utf8.DecodeRuneInString(chunk)
+				return fmt.Errorf("send character %q: %w", char, err)
+			}
+		} else {
+			p.logf("Inserting text: %s\n", chunk)
+			insert := p.Driver.InsertText
+			if verify {
+				insert = p.insertVerified
+			}
+			if err := insert(ctx, chunk); err != nil {
+				return fmt.Errorf("insert text %q: %w", chunk, err)
+			}
+		}
+
+		// Sleep for per-character speed
+		if speed > 0 {
+			if err := pause(ctx, time.Duration(n)*speed); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Apply post-action delay if specified
+	if action.Delay > 0 {
+		p.logf("Waiting %v after type action %d\n", action.Delay, index)
+		return pause(ctx, action.Delay)
+	}
+	return nil
+}
+
+// typeSecret types a TypeSecret action without showing its text anywhere:
+// neither the log nor errors name the characters typed.
+func (p *Player) typeSecret(ctx context.Context, action script.Action, index int) error {
+	p.logf("Typing secret (action %d)\n", index)
+
+	speed := action.CharDelay()
+	for _, chunk := range typeChunks(action.Text, speed, 0) {
+		n := utf8.RuneCountInString(chunk)
+		send := p.Driver.InsertText
+		if n == 1 {
+			send = p.Driver.SendKey
+		}
+		if err := send(ctx, chunk); err != nil {
+			return fmt.Errorf("type secret (action %d): %w", index, err)
+		}
+
+		if speed > 0 {
+			if err := pause(ctx, time.Duration(n)*speed); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	if action.Delay > 0 {
+		return pause(ctx, action.Delay)
+	}
+	return nil
+}
+
+// sleep executes a sleep action with context-aware cancellation.
+func (p *Player) sleep(ctx context.Context, action script.Action, index int) error {
+	p.logf("Sleeping for %v (action %d)\n", action.Duration, index)
+	return pause(ctx, action.Duration)
+}
+
+// key executes a key action with optional delay and repeat count.
+func (p *Player) key(ctx context.Context, action script.Action, index int) error {
+	// Determine repeat count (defaults to 1)
+	repeat := action.Repeat
+	if repeat <= 0 {
+		repeat = 1
+	}
+
+	key := action.KeyName()
+	escape := action.Modifiers == 0 && strings.EqualFold(action.Key, "escape")
+	for i := 0; i < repeat; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p.logf("Sending keypress: %s (repeat %d/%d)\n", key, i+1, repeat)
+		if err := p.Driver.SendKey(ctx, key); err != nil {
+			return fmt.Errorf("send key %q (repeat %d): %w", key, i+1, err)
+		}
+
+		// Let the program take a lone Escape before the next key arrives
+		if escape && p.EscapeDelay > 0 {
+			if err := pause(ctx, p.EscapeDelay); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Apply post-action delay if specified
+	if action.Delay > 0 {
+		p.logf("Waiting %v after key action %d\n", action.Delay, index)
+		return pause(ctx, action.Delay)
+	}
+	return nil
+}
+
+// ctrl executes a control key combination action.
+func (p *Player) ctrl(ctx context.Context, action script.Action, index int) error {
+	p.logf("Sending Ctrl+%s (action %d)\n", action.Key, index)
+	if err := p.Driver.SendKey(ctx, "ctrl+"+action.Key); err != nil {
+		return fmt.Errorf("send Ctrl+%s: %w", action.Key, err)
+	}
+	return nil
+}
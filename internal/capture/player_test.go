@@ -0,0 +1,217 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// fakeDriver is a terminal that echoes what is typed and records the calls
+// made to it.
+type fakeDriver struct {
+	mu     sync.Mutex
+	calls  []string
+	screen strings.Builder
+	alt    bool
+	err    error
+}
+
+func (d *fakeDriver) SendKey(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, "key "+key)
+	if strings.EqualFold(key, "enter") {
+		d.screen.WriteString("\n$ ")
+	} else if len(key) == 1 {
+		d.screen.WriteString(key)
+	}
+	return d.err
+}
+
+func (d *fakeDriver) InsertText(_ context.Context, text string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, "insert "+text)
+	d.screen.WriteString(text)
+	return d.err
+}
+
+func (d *fakeDriver) ReadText(context.Context) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.screen.String(), nil
+}
+
+func (d *fakeDriver) ReadAltScreen(context.Context) (bool, error) {
+	return d.alt, nil
+}
+
+func TestPlayer_Play(t *testing.T) {
+	actions, err := script.Parse("Type@0 'ls' Enter Sleep 10ms Ctrl+C Wait /ls/ 1s Wait Prompt 1s Screenshot Escape")
+	require.NoError(t, err)
+
+	driver := &fakeDriver{}
+	p := NewPlayer(driver)
+	p.EscapeDelay = 0
+	var log bytes.Buffer
+	p.Log = &log
+
+	require.NoError(t, p.Play(context.Background(), actions))
+	assert.Equal(t, []string{"insert ls", "key Enter", "key ctrl+c", "key Escape"}, driver.calls)
+	assert.Contains(t, log.String(), "Skipping Screenshot")
+}
+
+func TestPlayer_Play_Perform(t *testing.T) {
+	actions, err := script.Parse("Screenshot 'a' Type@0 'x' Burst 2 @10ms Hide Show Scene 'b'")
+	require.NoError(t, err)
+
+	driver := &fakeDriver{}
+	p := NewPlayer(driver)
+	var performed []script.ActionKind
+	p.Perform = func(_ context.Context, action script.Action, index int) error {
+		performed = append(performed, action.Kind)
+		return nil
+	}
+
+	require.NoError(t, p.Play(context.Background(), actions))
+	assert.Equal(t, []script.ActionKind{script.ActionScreenshot, script.ActionBurst, script.ActionHide, script.ActionShow, script.ActionScene}, performed)
+	assert.Equal(t, []string{"key x"}, driver.calls)
+}
+
+func TestPlayer_Play_Hooks(t *testing.T) {
+	actions, err := script.Parse("Type@0 'a' Enter Type@0 'b'")
+	require.NoError(t, err)
+
+	driver := &fakeDriver{}
+	p := NewPlayer(driver)
+	var events []string
+	p.Before = func(action script.Action, index int) {
+		events = append(events, fmt.Sprintf("before %d", index))
+	}
+	p.After = func(action script.Action, index int, err error) error {
+		events = append(events, fmt.Sprintf("after %d", index))
+		if action.Kind == script.ActionKey {
+			return errors.New("stop here")
+		}
+		return err
+	}
+
+	err = p.Play(context.Background(), actions)
+	assert.EqualError(t, err, "stop here")
+	assert.Equal(t, []string{"before 0", "after 0", "before 1", "after 1"}, events)
+	assert.Equal(t, []string{"key a", "key Enter"}, driver.calls, "actions after a failed one are not executed")
+}
+
+func TestPlayer_Play_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		driver  *fakeDriver
+		wantErr string
+	}{
+		{
+			name:    "driver error",
+			script:  "Enter",
+			driver:  &fakeDriver{err: errors.New("page closed")},
+			wantErr: `send key "Enter" (repeat 1): page closed`,
+		},
+		{
+			name:    "signal without a command",
+			script:  "Signal INT",
+			driver:  &fakeDriver{},
+			wantErr: "signal INT: " + errNoCommand.Error(),
+		},
+		{
+			name:    "pane without a layout",
+			script:  "Pane 'logs'",
+			driver:  &fakeDriver{},
+			wantErr: `pane action 0: pane "logs" is not in the tmux layout`,
+		},
+		{
+			name:    "wait timeout",
+			script:  "Wait AltScreen 150ms",
+			driver:  &fakeDriver{},
+			wantErr: "wait action 0: alternate screen did not appear within 150ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := script.Parse(tt.script)
+			require.NoError(t, err)
+			err = NewPlayer(tt.driver).Play(context.Background(), actions)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestPlayer_Play_Signal(t *testing.T) {
+	actions, err := script.Parse("Signal TERM")
+	require.NoError(t, err)
+
+	var got []string
+	p := NewPlayer(&fakeDriver{})
+	p.Signal = func(name string) error {
+		got = append(got, name)
+		return nil
+	}
+	require.NoError(t, p.Play(context.Background(), actions))
+	assert.Equal(t, []string{"TERM"}, got)
+}
+
+func TestPlayer_Play_PromptPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		screen  string
+		wantErr string
+	}{
+		{name: "default pattern", screen: "done\n$ "},
+		{name: "custom pattern", pattern: `^λ$`, screen: "done\nλ"},
+		{name: "not showing", pattern: `^λ$`, screen: "done\n$ ", wantErr: "prompt /^λ$/ did not appear"},
+		{name: "invalid pattern", pattern: `(`, wantErr: "invalid prompt pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &fakeDriver{}
+			driver.screen.WriteString(tt.screen)
+			p := NewPlayer(driver)
+			p.PromptPattern = tt.pattern
+
+			err := p.Play(context.Background(), []script.Action{{Kind: script.ActionWait, Prompt: true, Timeout: 150 * time.Millisecond}})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlayer_Play_Cancel(t *testing.T) {
+	actions, err := script.Parse("Sleep 1m Enter")
+	require.NoError(t, err)
+
+	driver := &fakeDriver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = NewPlayer(driver).Play(ctx, actions)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, driver.calls)
+}
@@ -0,0 +1,59 @@
+package capture
+
+import (
+	"fmt"
+	"sync"
+)
+
+// portTries bounds how often a portPool asks the kernel for a port before
+// giving up because every one it got was reserved.
+const portTries = 32
+
+// ttydPorts hands out the ports of the ttyd servers of this process that
+// were not given one.
+var ttydPorts = newPortPool()
+
+// portPool hands out free loopback ports. The kernel reports a port as
+// free until a server binds it, so a port is reserved from the moment it
+// is handed out until it is released, once ttyd has bound it or failed to;
+// until then, runs sharing the pool never get it again. Another process
+// may still take it first, which ttyd reports as a failed bind.
+type portPool struct {
+	mu       sync.Mutex
+	reserved map[int]bool
+
+	// listen returns a port the kernel considers free; it defaults to
+	// freePort and is replaced in tests.
+	listen func() (int, error)
+}
+
+// newPortPool returns a pool with no ports reserved.
+func newPortPool() *portPool {
+	return &portPool{reserved: map[int]bool{}, listen: freePort}
+}
+
+// acquire reserves a free port and returns it, with a function that
+// releases it again. The function may be called more than once.
+func (p *portPool) acquire() (port int, release func(), err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for range portTries {
+		port, err := p.listen()
+		if err != nil {
+			return 0, nil, fmt.Errorf("find a free port: %w", err)
+		}
+		if p.reserved[port] {
+			continue
+		}
+		p.reserved[port] = true
+		return port, sync.OnceFunc(func() { p.release(port) }), nil
+	}
+	return 0, nil, fmt.Errorf("no free port after %d tries: every port found is reserved by another run", portTries)
+}
+
+// release makes port available to acquire again.
+func (p *portPool) release(port int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.reserved, port)
+}
@@ -0,0 +1,103 @@
+package capture
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cyclingPorts returns a listen func that hands out ports in turn, the way
+// the kernel may offer a port again that nothing has bound yet.
+func cyclingPorts(ports ...int) func() (int, error) {
+	var mu sync.Mutex
+	var next int
+	return func() (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		port := ports[next%len(ports)]
+		next++
+		return port, nil
+	}
+}
+
+func TestPortPool_acquire(t *testing.T) {
+	p := newPortPool()
+
+	first, releaseFirst, err := p.acquire()
+	require.NoError(t, err)
+	second, releaseSecond, err := p.acquire()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.True(t, portFree(first), "the port is left for ttyd to bind")
+
+	releaseFirst()
+	releaseFirst()
+	releaseSecond()
+	assert.Empty(t, p.reserved)
+}
+
+func TestPortPool_acquire_Reuse(t *testing.T) {
+	p := newPortPool()
+	p.listen = cyclingPorts(9001, 9001, 9002)
+
+	first, release, err := p.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, 9001, first)
+
+	second, _, err := p.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, 9002, second, "a reserved port is skipped")
+
+	release()
+	third, _, err := p.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, 9001, third, "a released port is handed out again")
+}
+
+func TestPortPool_acquire_Concurrent(t *testing.T) {
+	const runs = 50
+	ports := make([]int, 8)
+	for i := range ports {
+		ports[i] = 9001 + i
+	}
+	p := newPortPool()
+	p.listen = cyclingPorts(ports...)
+
+	var mu sync.Mutex
+	got := map[int]int{}
+	var failed int
+	var wg sync.WaitGroup
+	for range runs {
+		wg.Go(func() {
+			port, _, err := p.acquire()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				assert.ErrorContains(t, err, "no free port after 32 tries")
+				failed++
+				return
+			}
+			got[port]++
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, got, len(ports), "every port is handed out")
+	for port, n := range got {
+		assert.Equal(t, 1, n, "port %d is handed out once", port)
+	}
+	assert.Equal(t, runs-len(ports), failed, "the pool is exhausted")
+}
+
+func TestPortPool_acquire_ListenError(t *testing.T) {
+	p := newPortPool()
+	boom := errors.New("boom")
+	p.listen = func() (int, error) { return 0, boom }
+
+	_, _, err := p.acquire()
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorContains(t, err, "find a free port")
+}
@@ -0,0 +1,92 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// probeTimeout is how long the input probe waits for the terminal to
+	// echo a space.
+	probeTimeout = 3 * time.Second
+	// probePollInterval is how often the input probe re-reads the cursor.
+	probePollInterval = 50 * time.Millisecond
+)
+
+// probeInput checks that the command reads what is typed before any
+// action runs: it types a space and waits for the cursor to move, then
+// erases it again. A command that has exited, or never echoes the space,
+// fails the run right away with the terminal text, instead of each action
+// going nowhere. Full-screen programs have drawn their screen by now and
+// are not probed, since a space may do something in them.
+func (c *Capturer) probeInput(ctx context.Context) error {
+	if c.config.NoProbe || !needsInput(c.config) {
+		return nil
+	}
+	if alt, err := c.readAltScreen(ctx); err == nil && alt {
+		return nil
+	}
+	before, err := c.readCursor(ctx)
+	if errors.Is(err, errNoTerm) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read cursor: %w", err)
+	}
+
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Checking that the terminal echoes input\n")
+	}
+	if err := c.sendKey(ctx, " "); err != nil {
+		return fmt.Errorf("send probe key: %w", err)
+	}
+
+	var exited <-chan struct{}
+	if c.command != nil {
+		exited = c.command.Done()
+	}
+	timer := time.NewTimer(probeTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(probePollInterval)
+	defer ticker.Stop()
+	for {
+		pos, err := c.readCursor(ctx)
+		if err != nil {
+			return fmt.Errorf("read cursor: %w", err)
+		}
+		if pos != before {
+			if err := c.sendKey(ctx, "Backspace"); err != nil {
+				return fmt.Errorf("send probe key: %w", err)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-exited:
+			what := "the command exited"
+			if code, ok := c.command.ExitCode(); ok {
+				what = fmt.Sprintf("the command exited with code %d", code)
+			}
+			return c.probeFailed(ctx, what+" before the terminal became interactive")
+		case <-timer.C:
+			return c.probeFailed(ctx, fmt.Sprintf("the terminal did not echo a typed space within %v; "+
+				"the command may not be reading input (pass --no-probe if it does not echo what is typed)", probeTimeout))
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeFailed returns the error of a failed input probe, quoting the last
+// lines of the terminal.
+func (c *Capturer) probeFailed(ctx context.Context, what string) error {
+	text, err := c.readText(ctx)
+	if err != nil {
+		return fmt.Errorf("%s; read terminal: %w", what, err)
+	}
+	return fmt.Errorf("%s; terminal output:\n%s", what, tail(text, waitTailLines))
+}
@@ -0,0 +1,92 @@
+package capture
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestCapturer_probeInput(t *testing.T) {
+	typing := []script.Action{{Kind: script.ActionType, Text: "ls"}}
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		alt      bool
+		noCursor bool
+		echo     bool
+		exit     bool
+		wantKeys []string
+		wantErr  []string
+	}{
+		{name: "echoed space is erased", cfg: &config.Config{Actions: typing}, echo: true, wantKeys: []string{" ", "Backspace"}},
+		{name: "keypresses are probed too", cfg: &config.Config{Keypresses: []string{"q"}}, echo: true, wantKeys: []string{" ", "Backspace"}},
+		{name: "nothing typed", cfg: &config.Config{Actions: []script.Action{{Kind: script.ActionSleep, Duration: time.Second}}}},
+		{name: "no probe", cfg: &config.Config{Actions: typing, NoProbe: true}},
+		{name: "full-screen program", cfg: &config.Config{Actions: typing}, alt: true},
+		{name: "page without window.term", cfg: &config.Config{Actions: typing}, noCursor: true},
+		{
+			name:     "command exited",
+			cfg:      &config.Config{Actions: typing},
+			exit:     true,
+			wantKeys: []string{" "},
+			wantErr:  []string{"the command exited with code 127 before the terminal became interactive", "bash: nosuch: command not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, tt.cfg)
+			var keys []string
+			var pos Cursor
+			c.sendKey = func(_ context.Context, key string) error {
+				keys = append(keys, key)
+				if tt.echo && key == " " {
+					pos.Col++
+				}
+				return nil
+			}
+			c.readCursor = func(context.Context) (Cursor, error) {
+				if tt.noCursor {
+					return Cursor{}, errNoTerm
+				}
+				return pos, nil
+			}
+			c.readAltScreen = func(context.Context) (bool, error) { return tt.alt, nil }
+			c.readText = func(context.Context) (string, error) { return "$ nosuch\nbash: nosuch: command not found\n\n", nil }
+			if tt.exit {
+				cmd := &fakeCommand{state: newExitState()}
+				cmd.state.exit(127, true)
+				c.command = cmd
+			}
+
+			err := c.probeInput(context.Background())
+			assert.Equal(t, tt.wantKeys, keys)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCapturer_probeInput_NoEcho(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{Actions: []script.Action{{Kind: script.ActionType, Text: "ls"}}})
+	c.readCursor = func(context.Context) (Cursor, error) { return Cursor{Col: 2}, nil }
+	c.readText = func(context.Context) (string, error) { return "$ sleep 100", nil }
+
+	err := c.probeInput(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did not echo a typed space within 3s")
+	assert.Contains(t, err.Error(), "--no-probe")
+	assert.Contains(t, err.Error(), "$ sleep 100")
+}
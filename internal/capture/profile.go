@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TempProfile is the ChromeProfile value that keeps the default throwaway
+// profile, as an empty one does.
+const TempProfile = "tmp"
+
+// profileLockFilename marks a persistent profile as in use by a run; it
+// holds the PID of the scr process using it.
+const profileLockFilename = "scr.lock"
+
+// profileInUseError reports that another running scr holds the profile.
+type profileInUseError struct {
+	dir  string
+	held *lockHeldError
+}
+
+func (e *profileInUseError) Error() string {
+	return fmt.Sprintf("Chrome profile %s is in use by another scr run (%s)", e.dir, e.held.holder())
+}
+
+// acquireProfile prepares the persistent Chrome profile dir, creating it on
+// first use, and locks it for the run with token. It returns "" for an
+// empty dir or TempProfile. A *profileInUseError means a live run holds
+// the lock.
+func acquireProfile(dir, token string, now time.Time) (path string, release func(), err error) {
+	if dir == "" || dir == TempProfile {
+		return "", func() {}, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("create Chrome profile: %w", err)
+	}
+
+	release, err = acquireLock(filepath.Join(dir, profileLockFilename), token, now)
+	var held *lockHeldError
+	if errors.As(err, &held) {
+		return "", nil, &profileInUseError{dir: dir, held: held}
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("lock Chrome profile: %w", err)
+	}
+	return dir, release, nil
+}
+
+// chromeProfile locks Config.ChromeProfile for this run. When another run
+// holds it, the run falls back to a throwaway profile with a warning.
+func (c *Capturer) chromeProfile() (dir string, release func(), err error) {
+	dir, release, err = acquireProfile(c.config.ChromeProfile, c.runID, c.now())
+	var inUse *profileInUseError
+	if errors.As(err, &inUse) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using a temporary profile\n", err)
+		return "", func() {}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if dir != "" && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Using Chrome profile %s\n", dir)
+	}
+	return dir, release, nil
+}
@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestAcquireProfile_Temp(t *testing.T) {
+	for _, dir := range []string{"", TempProfile} {
+		path, release, err := acquireProfile(dir, "run", time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, path)
+		release()
+	}
+}
+
+func TestAcquireProfile_CreatesAndLocks(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles", "chrome")
+
+	path, release, err := acquireProfile(dir, "run", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, dir, path)
+	assert.DirExists(t, dir)
+	assert.FileExists(t, filepath.Join(dir, profileLockFilename))
+
+	release()
+	assert.NoFileExists(t, filepath.Join(dir, profileLockFilename))
+
+	// Reused on the next run
+	_, release, err = acquireProfile(dir, "run", time.Now())
+	require.NoError(t, err)
+	release()
+}
+
+func TestAcquireProfile_InUse(t *testing.T) {
+	pid := liveProcess(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, profileLockFilename), []byte(strconv.Itoa(pid)), 0o600))
+
+	_, _, err := acquireProfile(dir, "run", time.Now())
+	var inUse *profileInUseError
+	require.True(t, errors.As(err, &inUse), "got %v", err)
+	assert.EqualError(t, err, "Chrome profile "+dir+" is in use by another scr run (pid "+strconv.Itoa(pid)+")")
+}
+
+func TestAcquireProfile_InUseInThisProcess(t *testing.T) {
+	dir := t.TempDir()
+	_, release, err := acquireProfile(dir, "run-1", time.Now())
+	require.NoError(t, err)
+	defer release()
+
+	_, _, err = acquireProfile(dir, "run-2", time.Now())
+	var inUse *profileInUseError
+	require.True(t, errors.As(err, &inUse), "got %v", err)
+}
+
+func TestCapturer_chromeProfile_FallsBackWhenInUse(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, profileLockFilename), []byte(strconv.Itoa(liveProcess(t))), 0o600))
+
+	c := newFakeCapturer(t, &config.Config{ChromeProfile: dir})
+	path, release, err := c.chromeProfile()
+	require.NoError(t, err)
+	release()
+	assert.Empty(t, path, "uses a temporary profile")
+}
+
+func TestAllocatorOptions_Profile(t *testing.T) {
+	without, err := allocatorOptions(&config.Config{}, "/usr/bin/chromium", "")
+	require.NoError(t, err)
+	with, err := allocatorOptions(&config.Config{}, "/usr/bin/chromium", "/tmp/profile")
+	require.NoError(t, err)
+	assert.Len(t, with, len(without)+1)
+}
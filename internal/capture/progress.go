@@ -0,0 +1,151 @@
+package capture
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressBuffer is how many events may wait to be written before new ones
+// are dropped.
+const progressBuffer = 256
+
+// progressCloseTimeout bounds how long the end of a run waits for a stalled
+// consumer to take the remaining events.
+const progressCloseTimeout = time.Second
+
+// Progress event names.
+const (
+	EventRunStart    = "run.start"
+	EventActionStart = "action.start"
+	EventActionEnd   = "action.end"
+	EventScreenshot  = "screenshot"
+	EventRunEnd      = "run.end"
+)
+
+// ProgressEvent is one line of the Config.Progress stream. Fields that do
+// not apply to an event are omitted.
+type ProgressEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// OffsetMS is milliseconds since the terminal became ready; it is
+	// omitted before then.
+	OffsetMS *int64 `json:"offsetMs,omitempty"`
+
+	// Command and Actions describe the run, on run.start.
+	Command string `json:"command,omitempty"`
+	Actions int    `json:"actions,omitempty"`
+
+	// Action is the action's index and Text its script form, on
+	// action.start and action.end.
+	Action *int   `json:"action,omitempty"`
+	Text   string `json:"text,omitempty"`
+
+	// Path and Kind describe the frame written, on screenshot.
+	Path string    `json:"path,omitempty"`
+	Kind FrameKind `json:"kind,omitempty"`
+
+	// Error is why an action or the run failed; on run.end, Dropped counts
+	// the events lost because the consumer fell behind.
+	Error   string `json:"error,omitempty"`
+	Dropped int    `json:"dropped,omitempty"`
+}
+
+// progressWriter writes events to a consumer as JSON lines from its own
+// goroutine, so a slow consumer never holds up the capture: once
+// progressBuffer events are waiting, new ones are dropped and counted.
+type progressWriter struct {
+	events chan ProgressEvent
+	done   chan struct{}
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// newProgressWriter starts writing events to w.
+func newProgressWriter(w io.Writer) *progressWriter {
+	p := &progressWriter{
+		events: make(chan ProgressEvent, progressBuffer),
+		done:   make(chan struct{}),
+	}
+	go p.write(w)
+	return p
+}
+
+// send queues ev without blocking and reports false when it was dropped.
+func (p *progressWriter) send(ev ProgressEvent) bool {
+	select {
+	case p.events <- ev:
+		return true
+	default:
+		p.mu.Lock()
+		p.dropped++
+		p.mu.Unlock()
+		return false
+	}
+}
+
+// droppedCount returns how many events have been dropped so far.
+func (p *progressWriter) droppedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+// write encodes queued events until the channel is closed. After a write
+// error it keeps draining so send never blocks.
+func (p *progressWriter) write(w io.Writer) {
+	defer close(p.done)
+	enc := json.NewEncoder(w)
+	failed := false
+	for ev := range p.events {
+		if !failed && enc.Encode(ev) != nil {
+			failed = true
+		}
+	}
+}
+
+// close stops accepting events and waits, up to progressCloseTimeout, for
+// the queued ones to be written.
+func (p *progressWriter) close() {
+	close(p.events)
+	select {
+	case <-p.done:
+	case <-time.After(progressCloseTimeout):
+	}
+}
+
+// emit sends a progress event stamped with the current time, if
+// Config.Progress is set.
+func (c *Capturer) emit(ev ProgressEvent) {
+	if c.progress == nil {
+		return
+	}
+	ev.Time = c.now()
+	if c.timeline != nil && c.timeline.ready() {
+		ms := c.timeline.offset(ev.Time).Milliseconds()
+		ev.OffsetMS = &ms
+	}
+	c.progress.send(ev)
+}
+
+// startProgress opens the progress stream for a run and returns the
+// function that ends it with run.end, reporting err.
+func (c *Capturer) startProgress() (end func(err error)) {
+	if c.config.Progress == nil {
+		c.progress = nil
+		return func(error) {}
+	}
+	c.progress = newProgressWriter(c.config.Progress)
+	c.emit(ProgressEvent{Event: EventRunStart, Command: c.config.CommandLine(), Actions: len(c.config.Actions)})
+	return func(err error) {
+		ev := ProgressEvent{Event: EventRunEnd, Dropped: c.progress.droppedCount()}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		c.emit(ev)
+		c.progress.close()
+		c.progress = nil
+	}
+}
@@ -0,0 +1,140 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// syncBuffer is a bytes.Buffer safe for the progress goroutine to write
+// while the test reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// events decodes the JSON lines written so far.
+func (b *syncBuffer) events(t *testing.T) []ProgressEvent {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []ProgressEvent
+	sc := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for sc.Scan() {
+		var ev ProgressEvent
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &ev), sc.Text())
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestProgressWriter_DropsWhenFull(t *testing.T) {
+	// A writer whose goroutine never runs: the buffer fills, then events
+	// are dropped instead of blocking the capture
+	p := &progressWriter{events: make(chan ProgressEvent, progressBuffer), done: make(chan struct{})}
+	for range progressBuffer {
+		require.True(t, p.send(ProgressEvent{Event: EventScreenshot}))
+	}
+	assert.False(t, p.send(ProgressEvent{Event: EventScreenshot}))
+	assert.Equal(t, 1, p.droppedCount())
+}
+
+func TestCapturer_Progress(t *testing.T) {
+	out := &syncBuffer{}
+	c := newFakeCapturer(t, &config.Config{
+		Command:  "bash",
+		Progress: out,
+		Actions: []script.Action{
+			{Kind: script.ActionType, Text: "ls"},
+			{Kind: script.ActionKey, Key: "Enter", Repeat: 1},
+		},
+	})
+	c.sendKey = func(_ context.Context, key string) error {
+		if key == "Enter" {
+			return errors.New("browser went away")
+		}
+		return nil
+	}
+
+	end := c.startProgress()
+	ctx := context.Background()
+	err := c.runSession(ctx, ctx)
+	require.Error(t, err)
+	end(err)
+
+	events := out.events(t)
+	var names []string
+	for _, ev := range events {
+		names = append(names, ev.Event)
+		assert.False(t, ev.Time.IsZero(), ev.Event)
+	}
+	assert.Equal(t, []string{
+		EventRunStart, EventScreenshot,
+		EventActionStart, EventActionEnd,
+		EventActionStart, EventActionEnd,
+		EventRunEnd,
+	}, names)
+
+	assert.Equal(t, "bash", events[0].Command)
+	assert.Equal(t, 2, events[0].Actions)
+	assert.Nil(t, events[0].OffsetMS, "the terminal is not ready yet")
+	assert.Equal(t, FrameInitial, events[1].Kind)
+	assert.NotEmpty(t, events[1].Path)
+	require.NotNil(t, events[1].OffsetMS)
+	require.NotNil(t, events[2].Action)
+	assert.Equal(t, 0, *events[2].Action)
+	assert.Contains(t, events[2].Text, "'ls'")
+	assert.Empty(t, events[3].Error)
+	assert.Equal(t, 1, *events[5].Action)
+	assert.Contains(t, events[5].Error, "browser went away")
+	assert.Contains(t, events[6].Error, "browser went away")
+	assert.Nil(t, c.progress, "closed at the end of the run")
+}
+
+func TestCapturer_ProgressOff(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	end := c.startProgress()
+	c.emit(ProgressEvent{Event: EventScreenshot})
+	end(nil)
+	assert.Nil(t, c.progress)
+}
+
+// blockingWriter never returns from Write, like a consumer that stopped
+// reading a full pipe.
+type blockingWriter struct{ block chan struct{} }
+
+func (w blockingWriter) Write([]byte) (int, error) {
+	<-w.block
+	return 0, errors.New("closed")
+}
+
+func TestProgressWriter_CloseDoesNotWaitForStalledConsumer(t *testing.T) {
+	w := blockingWriter{block: make(chan struct{})}
+	defer close(w.block)
+	p := newProgressWriter(w)
+	for range progressBuffer * 2 {
+		p.send(ProgressEvent{Event: EventScreenshot})
+	}
+	assert.Positive(t, p.droppedCount())
+
+	start := time.Now()
+	p.close()
+	assert.Less(t, time.Since(start), progressCloseTimeout+time.Second)
+}
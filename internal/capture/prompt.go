@@ -0,0 +1,158 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// promptProfile says how Wait Prompt recognizes that a shell is showing its
+// prompt.
+type promptProfile struct {
+	// Pattern matches the last non-blank terminal line while the prompt is
+	// showing. It is used until the shell has printed a promptMarker.
+	Pattern string
+	// Env, if set, is added to the command's environment so the shell
+	// prints a promptMarker before each prompt.
+	Env []string
+}
+
+// promptMarker is the FinalTerm "prompt start" sequence, OSC 133;A, that a
+// shell prints before each prompt. xterm.js consumes OSC sequences without
+// drawing anything, and the handler installed by promptWatchJS swallows
+// this one, so it never shows up in a frame. Shells such as fish 4 print it
+// on their own.
+const promptMarker = "\x1b]133;A\x07"
+
+// promptProfiles are keyed by the names in config.Shells. Patterns accept
+// "> " as well, the PS1 scr gives the command.
+var promptProfiles = map[string]promptProfile{
+	"bash": {
+		Pattern: `[$#>]$`,
+		Env:     []string{`PROMPT_COMMAND=printf '\033]133;A\007'`},
+	},
+	"sh":   {Pattern: `[$#>]$`},
+	"zsh":  {Pattern: `[%#$>]$`},
+	"fish": {Pattern: `[>$#]$`},
+}
+
+// promptShell returns the shell whose prompt Wait Prompt waits for: the
+// command itself when it runs one of config.Shells, as in "scr zsh ...",
+// and otherwise the shell that wraps it.
+func promptShell(cfg *config.Config) string {
+	if program := cfg.NamePrefix(); slices.Contains(config.Shells, program) {
+		return program
+	}
+	if cfg.Shell != "" {
+		return cfg.Shell
+	}
+	return config.Shells[0]
+}
+
+// waitsForPrompt reports whether any action is a Wait Prompt.
+func waitsForPrompt(actions []script.Action) bool {
+	for _, action := range actions {
+		if action.Kind == script.ActionWait && action.Prompt {
+			return true
+		}
+	}
+	return false
+}
+
+// promptEnv returns the environment that makes the command's shell mark
+// its prompts, when the script waits for them and no PromptPattern
+// replaces the profile.
+func promptEnv(cfg *config.Config) []string {
+	if cfg.PromptPattern != "" || !waitsForPrompt(cfg.Actions) {
+		return nil
+	}
+	return promptProfiles[promptShell(cfg)].Env
+}
+
+// promptMarks are the counters kept by promptWatchJS. Seq increases with
+// every prompt marker and every input sent to the terminal; Prompt and
+// Input hold its value at the latest of each.
+type promptMarks struct {
+	Seen   bool `json:"seen"`
+	Prompt int  `json:"prompt"`
+	Input  int  `json:"input"`
+}
+
+// showing reports whether a prompt has been printed since the last input.
+func (m promptMarks) showing() bool {
+	return m.Seen && m.Prompt > m.Input
+}
+
+// promptWatchJS installs, once, an OSC 133 handler on the xterm.js terminal
+// ttyd exposes as window.term, and returns the counters it keeps.
+const promptWatchJS = `(() => {
+	const term = window.term;
+	if (!window.__scrPrompt && term && term.parser) {
+		const marks = window.__scrPrompt = {seen: false, seq: 0, prompt: 0, input: 0};
+		term.parser.registerOscHandler(133, (data) => {
+			if (data === "A" || data.startsWith("A;")) {
+				marks.seen = true;
+				marks.prompt = ++marks.seq;
+			}
+			return true;
+		});
+		term.onData(() => { marks.input = ++marks.seq; });
+	}
+	return window.__scrPrompt || {seen: false, prompt: 0, input: 0};
+})()`
+
+// watchPrompt installs the prompt marker handler if needed and reads its
+// counters.
+func watchPrompt(ctx context.Context) (promptMarks, error) {
+	var marks promptMarks
+	if err := chromedp.Run(ctx, chromedp.Evaluate(promptWatchJS, &marks)); err != nil {
+		return promptMarks{}, err
+	}
+	return marks, nil
+}
+
+// promptWait builds the check a Wait Prompt polls, and describes what it
+// waits for. Marked prompts are used once the shell has printed a marker;
+// until then, and always with PromptPattern, the last non-blank line of the
+// terminal must match the pattern.
+func (c *Capturer) promptWait() (ready func(ctx context.Context, text string) (bool, error), what string, err error) {
+	shell := promptShell(c.config)
+	pattern := promptProfiles[shell].Pattern
+	what = shell + " prompt"
+	if c.config.PromptPattern != "" {
+		pattern = c.config.PromptPattern
+		what = fmt.Sprintf("prompt /%s/", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid prompt pattern: %w", err)
+	}
+	marked := c.config.PromptPattern == ""
+
+	return func(ctx context.Context, text string) (bool, error) {
+		if marked {
+			marks, err := c.readPrompt(ctx)
+			if err != nil {
+				return false, fmt.Errorf("read prompt marks: %w", err)
+			}
+			if marks.Seen {
+				return marks.showing(), nil
+			}
+		}
+		return re.MatchString(lastLine(text)), nil
+	}, what, nil
+}
+
+// lastLine returns the last non-blank line of text, without trailing
+// spaces.
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimRight(text, " \n"), "\n")
+	return strings.TrimRight(lines[len(lines)-1], " ")
+}
@@ -0,0 +1,137 @@
+package capture
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestPromptShell(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{name: "default shell", cfg: config.Config{Command: "make test"}, want: "bash"},
+		{name: "configured shell", cfg: config.Config{Command: "make test", Shell: "sh"}, want: "sh"},
+		{name: "command is a shell", cfg: config.Config{Command: "zsh", Shell: "bash"}, want: "zsh"},
+		{name: "shell run directly", cfg: config.Config{CommandArgs: []string{"/usr/bin/fish", "-l"}}, want: "fish"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, promptShell(&tt.cfg))
+		})
+	}
+}
+
+func TestPromptEnv(t *testing.T) {
+	waitPrompt := []script.Action{{Kind: script.ActionWait, Prompt: true, Timeout: time.Second}}
+
+	assert.Equal(t, promptProfiles["bash"].Env, promptEnv(&config.Config{Command: "bash", Actions: waitPrompt}))
+	assert.Nil(t, promptEnv(&config.Config{Command: "bash"}), "no Wait Prompt, no marker")
+	assert.Nil(t, promptEnv(&config.Config{Command: "bash", Actions: waitPrompt, PromptPattern: `❯$`}), "the override needs no marker")
+	assert.Nil(t, promptEnv(&config.Config{Command: "zsh", Actions: waitPrompt}), "zsh has no profile environment")
+}
+
+func TestNewCapturer_Env(t *testing.T) {
+	waitPrompt := []script.Action{{Kind: script.ActionWait, Prompt: true, Timeout: time.Second}}
+	cfg := &config.Config{Command: "bash", Actions: waitPrompt, Env: []string{"PROMPT_COMMAND=", "LANG=C"}}
+
+	c := NewCapturer(cfg)
+
+	want := append(slices.Clone(promptProfiles["bash"].Env), "PROMPT_COMMAND=", "LANG=C")
+	assert.Equal(t, want, c.ttyd.Env, "the user's variables come after scr's, so they win")
+	assert.Len(t, promptProfiles["bash"].Env, 1, "the profile is not modified")
+}
+
+func TestPlayer_wait_Prompt(t *testing.T) {
+	action := script.Action{Kind: script.ActionWait, Prompt: true, Timeout: 250 * time.Millisecond}
+
+	tests := []struct {
+		name          string
+		cfg           *config.Config
+		outputs       []string
+		marks         []promptMarks
+		wantErr       string
+		wantMarksRead bool
+	}{
+		{
+			name:    "profile pattern before any marker",
+			cfg:     &config.Config{Command: "bash"},
+			outputs: []string{"> make\ncompiling", "> make\ncompiling\ndone\nuser@host:~$  \n\n"},
+		},
+		{
+			name:          "marker after the last input",
+			cfg:           &config.Config{Command: "bash"},
+			outputs:       []string{"$ make\ncompiling\ndone $"},
+			marks:         []promptMarks{{Seen: true, Prompt: 1, Input: 2}, {Seen: true, Prompt: 3, Input: 2}},
+			wantMarksRead: true,
+		},
+		{
+			name:          "markers replace the pattern once seen",
+			cfg:           &config.Config{Command: "bash"},
+			outputs:       []string{"$ make\ndone $"},
+			marks:         []promptMarks{{Seen: true, Prompt: 1, Input: 2}},
+			wantErr:       "wait action 0: bash prompt did not appear within 250ms; last terminal output:\n$ make\ndone $",
+			wantMarksRead: true,
+		},
+		{
+			name:    "prompt pattern override",
+			cfg:     &config.Config{Command: "bash", PromptPattern: `❯$`},
+			outputs: []string{"~/src ❯ make", "~/src ❯ make\ndone\n~/src ❯"},
+		},
+		{
+			name:    "times out with last output",
+			cfg:     &config.Config{Command: "zsh"},
+			outputs: []string{"% make\ncompiling"},
+			wantErr: "wait action 0: zsh prompt did not appear within 250ms; last terminal output:\n% make\ncompiling",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, tt.cfg)
+			reads := 0
+			c.readText = func(context.Context) (string, error) {
+				out := tt.outputs[min(reads, len(tt.outputs)-1)]
+				reads++
+				return out, nil
+			}
+			marksRead := 0
+			c.readPrompt = func(context.Context) (promptMarks, error) {
+				if tt.cfg.PromptPattern != "" {
+					t.Error("prompt marks read with a prompt pattern override")
+				}
+				marksRead++
+				if len(tt.marks) == 0 {
+					return promptMarks{}, nil
+				}
+				return tt.marks[min(marksRead-1, len(tt.marks)-1)], nil
+			}
+
+			err := c.player(context.Background()).do(context.Background(), action, 0)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.wantMarksRead {
+				assert.Positive(t, marksRead)
+			}
+		})
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	assert.Equal(t, "user@host:~$", lastLine("ls\nfile\nuser@host:~$   \n\n  \n"))
+	assert.Equal(t, "", lastLine(""))
+}
@@ -0,0 +1,92 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// Environment records the software and page settings a capture ran with,
+// so rendering differences between machines can be traced back to them.
+type Environment struct {
+	// TTyd is the version reported by `ttyd --version`; empty when
+	// attaching to a ttyd that scr did not start.
+	TTyd string `json:"ttyd,omitempty"`
+	// Browser is the browser product, e.g. "HeadlessChrome/120.0.6099.109",
+	// and Protocol the DevTools protocol version it speaks.
+	Browser   string `json:"browser"`
+	Protocol  string `json:"protocol"`
+	Revision  string `json:"revision"`
+	UserAgent string `json:"userAgent"`
+	// Viewport is the page size in effect, as the page itself reports it.
+	Viewport PageViewport `json:"viewport"`
+	// Font is EmbeddedFontName, SystemFontName when the terminal keeps
+	// the browser's monospace font, or the font family it was set to.
+	Font string `json:"font,omitempty"`
+	// FontSize is the font size set for the terminal, in CSS pixels; zero
+	// when it kept ttyd's default.
+	FontSize int `json:"fontSize,omitempty"`
+}
+
+// PageViewport is the size of the page's viewport in CSS pixels and the
+// device pixel ratio screenshots are taken at.
+type PageViewport struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"deviceScaleFactor"`
+}
+
+// viewportJS evaluates to the viewport the page sees.
+const viewportJS = `({width: window.innerWidth, height: window.innerHeight, deviceScaleFactor: window.devicePixelRatio})`
+
+// probeBrowser queries the browser version over CDP and reads the user
+// agent and viewport in effect on the current page.
+func probeBrowser(ctx context.Context) (Environment, error) {
+	var env Environment
+	err := chromedp.Run(ctx,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			protocol, product, revision, userAgent, _, err := browser.GetVersion().Do(ctx)
+			if err != nil {
+				return fmt.Errorf("get browser version: %w", err)
+			}
+			env.Protocol, env.Browser, env.Revision = protocol, product, revision
+			// The page may report a different user agent than the browser
+			// default when it is overridden; the browser's is the fallback
+			env.UserAgent = userAgent
+			return nil
+		}),
+		chromedp.Evaluate(`navigator.userAgent`, &env.UserAgent),
+		chromedp.Evaluate(viewportJS, &env.Viewport),
+	)
+	if err != nil {
+		return Environment{}, err
+	}
+	return env, nil
+}
+
+// String formats the environment on one line for logs and error messages.
+func (e Environment) String() string {
+	ttyd := e.TTyd
+	if ttyd == "" {
+		ttyd = "n/a"
+	}
+	return fmt.Sprintf("ttyd %s, %s (protocol %s), viewport %dx%d@%gx, user agent %q",
+		ttyd, e.Browser, e.Protocol, e.Viewport.Width, e.Viewport.Height, e.Viewport.DeviceScaleFactor, e.UserAgent)
+}
+
+// ProbeEnvironment reports the installed ttyd's version and the browser a
+// capture would use, by starting it headless on a blank page.
+func ProbeEnvironment(ctx context.Context) (Environment, error) {
+	browserCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+	defer chromedp.Cancel(browserCtx)
+
+	env, err := probeBrowser(browserCtx)
+	env.TTyd = ttydVersion()
+	if err != nil {
+		return env, fmt.Errorf("launch browser: %w", err)
+	}
+	return env, nil
+}
@@ -0,0 +1,203 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// errNoTerm is returned by a TerminalReader when the terminal page does
+// not expose the xterm.js terminal as window.term.
+var errNoTerm = errors.New("terminal page does not expose window.term")
+
+// terminalTextJS returns the text of the xterm.js buffer that ttyd exposes as
+// window.term, falling back to the rendered rows for other frontends, whose
+// blank cells are non-breaking spaces.
+const terminalTextJS = `(() => {
+	const term = window.term;
+	if (term && term.buffer) {
+		const buf = term.buffer.active;
+		const lines = [];
+		for (let i = 0; i < buf.length; i++) {
+			const line = buf.getLine(i);
+			lines.push(line ? line.translateToString(true) : "");
+		}
+		return lines.join("\n");
+	}
+	const rows = document.querySelector(".xterm-rows");
+	return rows ? rows.innerText.replace(/\u00a0/g, " ") : "";
+})()`
+
+// terminalLineJS returns row %d of the screen of the xterm.js terminal on
+// window.term, with the number of rows, or null on pages without it.
+const terminalLineJS = `((n) => {
+	const term = window.term;
+	if (!term || !term.buffer) return null;
+	const buf = term.buffer.active;
+	const line = n >= 0 && n < term.rows ? buf.getLine(buf.baseY + n) : undefined;
+	return {rows: term.rows, text: line ? line.translateToString(true) : ""};
+})(%d)`
+
+// terminalSizeJS returns the columns and rows of the xterm.js terminal on
+// window.term, or null on pages without it.
+const terminalSizeJS = `(() => {
+	const term = window.term;
+	if (!term) return null;
+	return [term.cols, term.rows];
+})()`
+
+// cursorJS returns the cursor position on the screen of the xterm.js
+// terminal on window.term, or null on pages without it.
+const cursorJS = `(() => {
+	const term = window.term;
+	if (!term || !term.buffer) return null;
+	const buf = term.buffer.active;
+	return [buf.cursorX, buf.cursorY];
+})()`
+
+// altScreenJS reports whether the xterm.js terminal on window.term shows
+// its alternate screen buffer, which full-screen programs switch to on
+// start and leave on exit. It is null when the page has no window.term.
+const altScreenJS = `(() => {
+	const term = window.term;
+	if (!term || !term.buffer) return null;
+	return term.buffer.active.type === "alternate";
+})()`
+
+// scrollbackJS evaluates to the most lines the normal buffer of the
+// xterm.js terminal on window.term has held above its viewport, scrolled
+// off the top, since it was first evaluated; the first evaluation starts
+// watching for scrolls. The alternate screen has no scrollback, so
+// full-screen programs do not count. It is null when the page has no
+// window.term.
+const scrollbackJS = `(() => {
+	const term = window.term;
+	if (!term || !term.buffer) return null;
+	const seen = () => {
+		window.__scrScrollback = Math.max(window.__scrScrollback || 0, term.buffer.normal.baseY);
+	};
+	if (window.__scrScrollback === undefined) term.onScroll(seen);
+	seen();
+	return window.__scrScrollback;
+})()`
+
+// Cursor is where the cursor is on the terminal screen, in cells from the
+// top left, which is column 0 of row 0.
+type Cursor struct {
+	Col, Row int
+}
+
+// TerminalReader reads what the terminal of a ttyd page shows: its text,
+// a single line of the screen, its size and where its cursor is. Like
+// ChromeDriver, it must be passed contexts from chromedp.NewContext whose
+// page shows the terminal; the zero value is ready to use.
+//
+// Text and lines read the same whichever way the page is read: a wide
+// character, such as 漢, fills two cells of the screen but is one rune of
+// the text, and the blank cells at the end of each line are left out. The
+// cursor, by contrast, counts cells, so after 漢 it is in column 2.
+type TerminalReader struct {
+	// evaluate runs a JavaScript expression on the page and stores its
+	// result in res; nil runs it with chromedp. It is replaced in tests.
+	evaluate func(ctx context.Context, expr string, res any) error
+}
+
+// Text returns the terminal's text, one line per row, from the top of its
+// scrollback to the bottom of the screen. Pages with another frontend than
+// ttyd's xterm.js are read from the rendered rows, without scrollback.
+func (r TerminalReader) Text(ctx context.Context) (string, error) {
+	var text string
+	if err := r.eval(ctx, terminalTextJS, &text); err != nil {
+		return "", err
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = trimLine(line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Line returns row n of the screen, counting from 0 at the top, without
+// the scrollback above it.
+func (r TerminalReader) Line(ctx context.Context, n int) (string, error) {
+	var line *struct {
+		Rows int    `json:"rows"`
+		Text string `json:"text"`
+	}
+	if err := r.eval(ctx, fmt.Sprintf(terminalLineJS, n), &line); err != nil {
+		return "", err
+	}
+	if line == nil {
+		return "", errNoTerm
+	}
+	if n < 0 || n >= line.Rows {
+		return "", fmt.Errorf("line %d is outside the screen's %d rows", n, line.Rows)
+	}
+	return trimLine(line.Text), nil
+}
+
+// Size returns the number of columns and rows of the terminal screen.
+func (r TerminalReader) Size(ctx context.Context) (cols, rows int, err error) {
+	var size []int
+	if err := r.eval(ctx, terminalSizeJS, &size); err != nil {
+		return 0, 0, err
+	}
+	if len(size) != 2 {
+		return 0, 0, errNoTerm
+	}
+	return size[0], size[1], nil
+}
+
+// CursorPosition returns where the cursor is on the terminal screen.
+func (r TerminalReader) CursorPosition(ctx context.Context) (Cursor, error) {
+	var pos []int
+	if err := r.eval(ctx, cursorJS, &pos); err != nil {
+		return Cursor{}, err
+	}
+	if len(pos) != 2 {
+		return Cursor{}, errNoTerm
+	}
+	return Cursor{Col: pos[0], Row: pos[1]}, nil
+}
+
+// altScreen reports whether the terminal shows its alternate screen.
+func (r TerminalReader) altScreen(ctx context.Context) (bool, error) {
+	var alt *bool
+	if err := r.eval(ctx, altScreenJS, &alt); err != nil {
+		return false, err
+	}
+	if alt == nil {
+		return false, errNoTerm
+	}
+	return *alt, nil
+}
+
+// scrollback returns the peak number of lines scrolled off the top of the
+// terminal since its first call on the page.
+func (r TerminalReader) scrollback(ctx context.Context) (int, error) {
+	var lines *int
+	if err := r.eval(ctx, scrollbackJS, &lines); err != nil {
+		return 0, err
+	}
+	if lines == nil {
+		return 0, errNoTerm
+	}
+	return *lines, nil
+}
+
+// eval runs expr on the page with r.evaluate, or with chromedp.
+func (r TerminalReader) eval(ctx context.Context, expr string, res any) error {
+	if r.evaluate != nil {
+		return r.evaluate(ctx, expr, res)
+	}
+	return chromedp.Run(ctx, chromedp.Evaluate(expr, res))
+}
+
+// trimLine drops the blank cells at the end of a terminal line, which the
+// rendered rows keep.
+func trimLine(line string) string {
+	return strings.TrimRight(line, " ")
+}
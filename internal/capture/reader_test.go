@@ -0,0 +1,133 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePage returns a TerminalReader whose page answers every expression
+// with result, as JSON, and records the expressions.
+func fakePage(result string, exprs *[]string) TerminalReader {
+	return TerminalReader{evaluate: func(_ context.Context, expr string, res any) error {
+		if exprs != nil {
+			*exprs = append(*exprs, expr)
+		}
+		return json.Unmarshal([]byte(result), res)
+	}}
+}
+
+func TestTerminalReader_Text(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{name: "xterm.js buffer", page: `"$ ls\nfile\n$"`, want: "$ ls\nfile\n$"},
+		{name: "rendered rows keep blank cells", page: `"$ ls   \nfile  \n$ "`, want: "$ ls\nfile\n$"},
+		{name: "wide characters", page: `"漢字  \nok"`, want: "漢字\nok"},
+		{name: "empty", page: `""`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fakePage(tt.page, nil).Text(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTerminalReader_Line(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		page    string
+		want    string
+		wantErr string
+	}{
+		{name: "first row", n: 0, page: `{"rows": 24, "text": "$ ls"}`, want: "$ ls"},
+		{name: "blank cells are trimmed", n: 3, page: `{"rows": 24, "text": "done   "}`, want: "done"},
+		{name: "last row", n: 23, page: `{"rows": 24, "text": ""}`, want: ""},
+		{name: "below the screen", n: 24, page: `{"rows": 24, "text": ""}`, wantErr: "line 24 is outside the screen's 24 rows"},
+		{name: "negative", n: -1, page: `{"rows": 24, "text": ""}`, wantErr: "line -1 is outside the screen's 24 rows"},
+		{name: "page without window.term", page: `null`, wantErr: "does not expose window.term"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exprs []string
+			got, err := fakePage(tt.page, &exprs).Line(context.Background(), tt.n)
+			require.Len(t, exprs, 1)
+			assert.True(t, strings.HasSuffix(exprs[0], "})("+strconv.Itoa(tt.n)+")"), "the row is passed to the page")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTerminalReader_Size(t *testing.T) {
+	cols, rows, err := fakePage(`[80, 24]`, nil).Size(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 80, cols)
+	assert.Equal(t, 24, rows)
+
+	_, _, err = fakePage(`null`, nil).Size(context.Background())
+	assert.ErrorIs(t, err, errNoTerm)
+}
+
+func TestTerminalReader_CursorPosition(t *testing.T) {
+	got, err := fakePage(`[2, 5]`, nil).CursorPosition(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{Col: 2, Row: 5}, got)
+
+	_, err = fakePage(`null`, nil).CursorPosition(context.Background())
+	assert.ErrorIs(t, err, errNoTerm)
+}
+
+func TestTerminalReader_altScreen(t *testing.T) {
+	alt, err := fakePage(`true`, nil).altScreen(context.Background())
+	require.NoError(t, err)
+	assert.True(t, alt)
+
+	_, err = fakePage(`null`, nil).altScreen(context.Background())
+	assert.ErrorIs(t, err, errNoTerm)
+}
+
+func TestTerminalReader_scrollback(t *testing.T) {
+	lines, err := fakePage(`12`, nil).scrollback(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 12, lines)
+
+	_, err = fakePage(`null`, nil).scrollback(context.Background())
+	assert.ErrorIs(t, err, errNoTerm)
+}
+
+func TestTerminalReader_EvaluateError(t *testing.T) {
+	boom := errors.New("boom")
+	r := TerminalReader{evaluate: func(context.Context, string, any) error { return boom }}
+	ctx := context.Background()
+
+	_, err := r.Text(ctx)
+	assert.ErrorIs(t, err, boom)
+	_, err = r.Line(ctx, 0)
+	assert.ErrorIs(t, err, boom)
+	_, _, err = r.Size(ctx)
+	assert.ErrorIs(t, err, boom)
+	_, err = r.CursorPosition(ctx)
+	assert.ErrorIs(t, err, boom)
+	_, err = r.altScreen(ctx)
+	assert.ErrorIs(t, err, boom)
+	_, err = r.scrollback(ctx)
+	assert.ErrorIs(t, err, boom)
+}
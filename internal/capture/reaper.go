@@ -0,0 +1,193 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunIDEnv is the environment variable that marks the ttyd and Chrome
+// processes of a run, and everything they start, with the run's ID. The
+// ID starts with the PID of the scr process, so processes left behind by
+// an scr that is gone can be told from those of a run still going.
+const RunIDEnv = "SCR_RUN_ID"
+
+// chromeProfilePrefix starts the name of a run's throwaway Chrome profile
+// in the temp directory; the run's ID follows it.
+const chromeProfilePrefix = "scr-chrome-"
+
+// errProcessesUnsupported is returned by listMarkedProcesses where
+// processes cannot be searched for their environment.
+var errProcessesUnsupported = errors.New("searching for processes is only supported on Linux")
+
+// reapGracePeriod is how long stale processes get to exit after SIGTERM
+// before they are killed.
+const reapGracePeriod = 2 * time.Second
+
+// newRunID returns the ID of a run of this process started at now.
+func newRunID(now time.Time) string {
+	return fmt.Sprintf("%d-%x", os.Getpid(), now.UnixNano())
+}
+
+// runOwner returns the PID of the scr process in a run ID.
+func runOwner(id string) (int, bool) {
+	pid, _, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(pid)
+	return n, err == nil && n > 0
+}
+
+// ownerGone reports whether the scr process of a run ID is no longer
+// running. IDs that name no process are never stale.
+func ownerGone(id string) bool {
+	pid, ok := runOwner(id)
+	return ok && pid != os.Getpid() && !processAlive(pid)
+}
+
+// StaleProcess is a process left behind by a run whose scr is gone.
+type StaleProcess struct {
+	PID     int
+	RunID   string
+	Started time.Time
+	// Args is the process's command line.
+	Args []string
+}
+
+// StaleDir is a throwaway Chrome profile left behind by a run whose scr is
+// gone.
+type StaleDir struct {
+	Path     string
+	RunID    string
+	Modified time.Time
+}
+
+// Leftovers are the processes and directories of runs whose scr is gone.
+type Leftovers struct {
+	Processes []StaleProcess
+	Dirs      []StaleDir
+}
+
+// FindLeftovers returns the processes and Chrome profiles marked with the
+// ID of a run whose scr process is no longer running, and that are at
+// least olderThan old at now. Unmarked processes and directories are never
+// returned. Where processes cannot be searched, the directories are still
+// returned, along with errProcessesUnsupported.
+func FindLeftovers(olderThan time.Duration, now time.Time) (Leftovers, error) {
+	var l Leftovers
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), chromeProfilePrefix+"*"))
+	if err != nil {
+		return Leftovers{}, err
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		id := strings.TrimPrefix(filepath.Base(dir), chromeProfilePrefix)
+		if ownerGone(id) && oldEnough(info.ModTime(), now, olderThan) {
+			l.Dirs = append(l.Dirs, StaleDir{Path: dir, RunID: id, Modified: info.ModTime()})
+		}
+	}
+
+	procs, err := listMarkedProcesses()
+	for _, p := range procs {
+		if ownerGone(p.RunID) && oldEnough(p.Started, now, olderThan) {
+			l.Processes = append(l.Processes, p)
+		}
+	}
+	return l, err
+}
+
+// oldEnough reports whether something from t is at least olderThan old at
+// now. Any age will do for zero, since start times read from the system
+// can be rounded to a time after now.
+func oldEnough(t, now time.Time, olderThan time.Duration) bool {
+	return olderThan <= 0 || now.Sub(t) >= olderThan
+}
+
+// Reap terminates the processes, giving them reapGracePeriod to exit
+// before killing them, then removes the directories. It carries on past
+// failures and returns them joined.
+func Reap(l Leftovers) error {
+	pids := make([]int, 0, len(l.Processes))
+	for _, p := range l.Processes {
+		pids = append(pids, p.PID)
+	}
+	errs := []error{terminateProcesses(pids, reapGracePeriod)}
+	for _, dir := range l.Dirs {
+		if err := os.RemoveAll(dir.Path); err != nil {
+			errs = append(errs, fmt.Errorf("remove %s: %w", dir.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reapPortHolder stops a ttyd left behind on port by a run whose scr is
+// gone, along with the rest of that run, and reports whether it did. It is
+// best effort: anything that goes wrong leaves the port as it was.
+func reapPortHolder(port int) bool {
+	l, _ := FindLeftovers(0, time.Now())
+	var runs []string
+	for _, p := range l.Processes {
+		if ttydOnPort(p.Args, port) {
+			runs = append(runs, p.RunID)
+		}
+	}
+	if len(runs) == 0 {
+		return false
+	}
+
+	var run Leftovers
+	for _, p := range l.Processes {
+		if slices.Contains(runs, p.RunID) {
+			run.Processes = append(run.Processes, p)
+		}
+	}
+	for _, d := range l.Dirs {
+		if slices.Contains(runs, d.RunID) {
+			run.Dirs = append(run.Dirs, d)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Port %d is held by a ttyd left behind by an earlier scr run; stopping %d of its processes\n", port, len(run.Processes))
+	if err := Reap(run); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clean up earlier run: %v\n", err)
+	}
+	return true
+}
+
+// ttydOnPort reports whether args are those of a ttyd told to listen on
+// port.
+func ttydOnPort(args []string, port int) bool {
+	if len(args) == 0 || filepath.Base(args[0]) != "ttyd" {
+		return false
+	}
+	for i := 1; i+1 < len(args); i++ {
+		if (args[i] == "-p" || args[i] == "--port") && args[i+1] == strconv.Itoa(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// tempProfile creates the throwaway Chrome profile of the run, named after
+// its ID so that scr cleanup can remove it if scr is killed; chromedp's own
+// temp profile would be left behind without a trace of whose it is.
+func (c *Capturer) tempProfile() (dir string, remove func(), err error) {
+	return newTempProfile(c.runID)
+}
+
+// newTempProfile creates a throwaway Chrome profile for the run with ID id.
+func newTempProfile(id string) (dir string, remove func(), err error) {
+	dir = filepath.Join(os.TempDir(), chromeProfilePrefix+id)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("create Chrome profile: %w", err)
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }, nil
+}
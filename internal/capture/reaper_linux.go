@@ -0,0 +1,143 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// listMarkedProcesses returns the processes with RunIDEnv in their
+// environment, other than this one, as /proc shows them. Processes whose
+// environment cannot be read, such as those of other users, are skipped.
+func listMarkedProcesses() ([]StaleProcess, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+	var procs []StaleProcess
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		environ, err := os.ReadFile(filepath.Join(dir, "environ"))
+		if err != nil {
+			continue
+		}
+		id, ok := runIDFromEnviron(environ)
+		if !ok {
+			continue
+		}
+		started, err := processStarted(dir, boot)
+		if err != nil {
+			continue
+		}
+		cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+		procs = append(procs, StaleProcess{
+			PID:     pid,
+			RunID:   id,
+			Started: started,
+			Args:    strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"),
+		})
+	}
+	return procs, nil
+}
+
+// clockTicks is the unit of the times in /proc/PID/stat, USER_HZ, which
+// is 100 on every Linux architecture.
+const clockTicks = 100
+
+// bootTime returns when the system booted, from the btime line of
+// /proc/stat.
+func bootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("parse boot time: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("no boot time in /proc/stat")
+}
+
+// processStarted returns when the process of a /proc/PID directory
+// started, from the starttime field of its stat file.
+func processStarted(dir string, boot time.Time) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// The command name in parentheses may hold spaces, so fields are
+	// counted from the last ")": state is field 3, starttime field 22
+	i := strings.LastIndex(string(data), ") ")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("parse %s/stat", dir)
+	}
+	fields := strings.Fields(string(data)[i+2:])
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("parse %s/stat", dir)
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse %s/stat: %w", dir, err)
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+// runIDFromEnviron returns the value of RunIDEnv in a NUL-separated
+// environment, as /proc/PID/environ holds it.
+func runIDFromEnviron(environ []byte) (string, bool) {
+	prefix := []byte(RunIDEnv + "=")
+	for _, kv := range bytes.Split(environ, []byte{0}) {
+		if id, ok := bytes.CutPrefix(kv, prefix); ok && len(id) > 0 {
+			return string(id), true
+		}
+	}
+	return "", false
+}
+
+// terminateProcesses sends SIGTERM to each process and SIGKILL to those
+// still running after grace. Processes that are already gone are skipped.
+func terminateProcesses(pids []int, grace time.Duration) error {
+	var errs []error
+	var running []int
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			if !errors.Is(err, syscall.ESRCH) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		running = append(running, pid)
+	}
+
+	deadline := time.Now().Add(grace)
+	for len(running) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		running = slices.DeleteFunc(running, func(pid int) bool { return !processAlive(pid) })
+	}
+	for _, pid := range running {
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
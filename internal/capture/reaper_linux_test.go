@@ -0,0 +1,119 @@
+package capture
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// markedProcess starts a process with args and RunIDEnv set to runID, or
+// unmarked for an empty runID, and returns a channel closed once it has
+// exited.
+func markedProcess(t *testing.T, runID string, path string, args ...string) (*exec.Cmd, <-chan struct{}) {
+	t.Helper()
+	cmd := exec.Command(path)
+	cmd.Args = args
+	cmd.Env = os.Environ()
+	if runID != "" {
+		cmd.Env = append(cmd.Env, RunIDEnv+"="+runID)
+	}
+	require.NoError(t, cmd.Start())
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		<-exited
+	})
+	return cmd, exited
+}
+
+func TestFindLeftovers_Processes(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+	staleID := strconv.Itoa(deadProcess(t)) + "-1"
+	stale, exited := markedProcess(t, staleID, sleep, "sleep", "30")
+	live, _ := markedProcess(t, strconv.Itoa(liveProcess(t))+"-2", sleep, "sleep", "30")
+	unmarked, _ := markedProcess(t, "", sleep, "sleep", "30")
+
+	l, err := FindLeftovers(0, time.Now())
+	require.NoError(t, err)
+	var pids []int
+	for _, p := range l.Processes {
+		pids = append(pids, p.PID)
+	}
+	require.Contains(t, pids, stale.Process.Pid)
+	assert.NotContains(t, pids, live.Process.Pid, "the run is still going")
+	assert.NotContains(t, pids, unmarked.Process.Pid, "unmarked processes are never touched")
+	for _, p := range l.Processes {
+		if p.PID == stale.Process.Pid {
+			assert.Equal(t, staleID, p.RunID)
+			assert.Equal(t, []string{"sleep", "30"}, p.Args)
+		}
+	}
+
+	l, err = FindLeftovers(time.Hour, time.Now())
+	require.NoError(t, err)
+	for _, p := range l.Processes {
+		assert.NotEqual(t, stale.Process.Pid, p.PID, "too recent")
+	}
+
+	require.NoError(t, Reap(Leftovers{Processes: []StaleProcess{{PID: stale.Process.Pid}}}))
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stale process is still running")
+	}
+	assert.True(t, processAlive(live.Process.Pid))
+	assert.True(t, processAlive(unmarked.Process.Pid))
+}
+
+func TestTTydServer_selectPort_ReapsLeftover(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	// The interpreter itself, not a wrapper script that would replace the
+	// ttyd name given below
+	out, err := exec.Command("python3", "-c", "import sys; print(sys.executable)").Output()
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	python := strings.TrimSpace(string(out))
+	port, err := freePort()
+	require.NoError(t, err)
+
+	// A process named ttyd that listens on the port, as a ttyd of a killed
+	// run would
+	listen := "import socket, sys, time\ns = socket.socket()\ns.bind(('127.0.0.1', int(sys.argv[2])))\ns.listen()\ntime.sleep(30)"
+	_, exited := markedProcess(t, strconv.Itoa(deadProcess(t))+"-1", python, "ttyd", "-c", listen, "-p", strconv.Itoa(port))
+	require.Eventually(t, func() bool { return !portFree(port) }, 5*time.Second, 20*time.Millisecond)
+
+	s := NewTTydServer("bash", port)
+	release, err := s.selectPort()
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, port, s.Port, "the port was freed rather than replaced")
+	<-exited
+}
+
+func TestTTydServer_selectPort_LeavesOthersAlone(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := NewTTydServer("bash", port)
+	_, err = s.selectPort()
+	require.Error(t, err, "a port held by a process scr did not start is left alone")
+	assert.Contains(t, err.Error(), "already in use")
+}
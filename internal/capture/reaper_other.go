@@ -0,0 +1,20 @@
+//go:build !linux
+
+package capture
+
+import "time"
+
+// listMarkedProcesses is only implemented on Linux, where /proc shows the
+// environment of other processes.
+func listMarkedProcesses() ([]StaleProcess, error) {
+	return nil, errProcessesUnsupported
+}
+
+// terminateProcesses is only implemented on Linux; listMarkedProcesses
+// finds no processes to terminate elsewhere.
+func terminateProcesses(pids []int, _ time.Duration) error {
+	if len(pids) > 0 {
+		return errProcessesUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package capture
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// deadProcess returns the PID of a process that has exited.
+func deadProcess(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestRunOwner(t *testing.T) {
+	id := newRunID(time.Unix(0, 255))
+	pid, ok := runOwner(id)
+	require.True(t, ok)
+	assert.Equal(t, os.Getpid(), pid)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"-ff", id)
+
+	for _, bad := range []string{"", "123", "x-1", "-1", "0-1"} {
+		_, ok := runOwner(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestOwnerGone(t *testing.T) {
+	assert.True(t, ownerGone(strconv.Itoa(deadProcess(t))+"-1"))
+	assert.False(t, ownerGone(strconv.Itoa(liveProcess(t))+"-1"), "the run is still going")
+	assert.False(t, ownerGone(newRunID(time.Now())), "this process's own runs")
+	assert.False(t, ownerGone("unrelated"))
+}
+
+func TestFindLeftovers_Dirs(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	dead := strconv.Itoa(deadProcess(t))
+	stale := filepath.Join(tmp, chromeProfilePrefix+dead+"-1")
+	for _, dir := range []string{
+		stale,
+		filepath.Join(tmp, chromeProfilePrefix+strconv.Itoa(liveProcess(t))+"-2"),
+		filepath.Join(tmp, chromeProfilePrefix+"mine"),
+		filepath.Join(tmp, "chromedp-runner123"),
+	} {
+		require.NoError(t, os.Mkdir(dir, 0o700))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, chromeProfilePrefix+dead+"-3"), nil, 0o600))
+
+	now := time.Now()
+	l, _ := FindLeftovers(0, now)
+	require.Len(t, l.Dirs, 1)
+	assert.Equal(t, stale, l.Dirs[0].Path)
+	assert.Equal(t, dead+"-1", l.Dirs[0].RunID)
+
+	l, _ = FindLeftovers(time.Hour, now)
+	assert.Empty(t, l.Dirs, "too recent")
+
+	require.NoError(t, Reap(Leftovers{Dirs: []StaleDir{{Path: stale}}}))
+	assert.NoDirExists(t, stale)
+	assert.DirExists(t, filepath.Join(tmp, "chromedp-runner123"))
+}
+
+func TestTTydOnPort(t *testing.T) {
+	assert.True(t, ttydOnPort([]string{"/usr/bin/ttyd", "-p", "7681", "--interface", "127.0.0.1"}, 7681))
+	assert.True(t, ttydOnPort([]string{"ttyd", "--writable", "--port", "7681"}, 7681))
+	assert.False(t, ttydOnPort([]string{"ttyd", "-p", "7682"}, 7681))
+	assert.False(t, ttydOnPort([]string{"bash", "-p", "7681"}, 7681))
+	assert.False(t, ttydOnPort([]string{"ttyd", "-p"}, 7681))
+	assert.False(t, ttydOnPort(nil, 7681))
+}
+
+func TestCapturer_tempProfile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	c := newFakeCapturer(t, &config.Config{})
+	c.runID = newRunID(time.Now())
+
+	dir, remove, err := c.tempProfile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, chromeProfilePrefix+c.runID), dir)
+	assert.DirExists(t, dir)
+	l, _ := FindLeftovers(0, time.Now())
+	assert.Empty(t, l.Dirs, "the profile of a run still going is left alone")
+
+	remove()
+	assert.NoDirExists(t, dir)
+}
@@ -0,0 +1,54 @@
+package capture
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultRingSize is the capacity of a ringBuffer with no explicit size.
+const defaultRingSize = 64 << 10
+
+// ringBuffer keeps the last size bytes written to it, so output from a
+// long-running process can be held in memory without growing unbounded.
+// The zero value holds defaultRingSize bytes. It is safe for concurrent use.
+type ringBuffer struct {
+	size int
+
+	mu      sync.Mutex
+	buf     []byte
+	dropped int64
+}
+
+// Write appends p, discarding the oldest bytes beyond the buffer's size.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := r.size
+	if size <= 0 {
+		size = defaultRingSize
+	}
+	r.buf = append(r.buf, p...)
+	if over := len(r.buf) - size; over > 0 {
+		r.dropped += int64(over)
+		r.buf = append(r.buf[:0], r.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Reset discards the retained output.
+func (r *ringBuffer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf, r.dropped = r.buf[:0], 0
+}
+
+// String returns the retained output, prefixed with a note when earlier
+// output was discarded.
+func (r *ringBuffer) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dropped > 0 {
+		return fmt.Sprintf("[%d earlier bytes truncated]\n%s", r.dropped, r.buf)
+	}
+	return string(r.buf)
+}
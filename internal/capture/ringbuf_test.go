@@ -0,0 +1,50 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes []string
+		want   string
+	}{
+		{name: "under capacity", size: 8, writes: []string{"abc", "de"}, want: "abcde"},
+		{name: "exactly full", size: 5, writes: []string{"abc", "de"}, want: "abcde"},
+		{name: "keeps the tail", size: 4, writes: []string{"abc", "def"}, want: "[2 earlier bytes truncated]\ncdef"},
+		{name: "single oversized write", size: 3, writes: []string{"abcdefgh"}, want: "[5 earlier bytes truncated]\nfgh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ringBuffer{size: tt.size}
+			for _, s := range tt.writes {
+				n, err := r.Write([]byte(s))
+				assert.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			assert.Equal(t, tt.want, r.String())
+		})
+	}
+}
+
+func TestRingBuffer_DefaultSize(t *testing.T) {
+	var r ringBuffer
+	_, _ = r.Write([]byte(strings.Repeat("x", defaultRingSize+10)))
+	assert.True(t, strings.HasPrefix(r.String(), "[10 earlier bytes truncated]\n"))
+}
+
+func TestRingBuffer_Reset(t *testing.T) {
+	r := &ringBuffer{size: 4}
+	_, _ = r.Write([]byte("abcdef"))
+	r.Reset()
+	assert.Empty(t, r.String())
+
+	_, _ = r.Write([]byte("gh"))
+	assert.Equal(t, "gh", r.String())
+}
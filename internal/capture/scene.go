@@ -0,0 +1,93 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// scene is a Scene started during a run.
+type scene struct {
+	// Name is the name given in the script.
+	Name string
+	// Dir is the scene's directory within the output directory; see
+	// sceneDirName.
+	Dir string
+}
+
+// sceneDirName turns a scene name into the name of its directory:
+// characters outside [A-Za-z0-9_-] become underscores. Dots are replaced
+// too, so a scene directory never hides or clashes with an output file such
+// as manifest.json.
+func sceneDirName(name string) (string, error) {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	dir := sb.String()
+	if strings.Trim(dir, "_") == "" {
+		return "", fmt.Errorf("scene name %q has no usable characters", name)
+	}
+	return dir, nil
+}
+
+// executeSceneAction starts a scene: frames captured from now on go into
+// its directory and are numbered from 1 again.
+func (c *Capturer) executeSceneAction(action script.Action, index int) error {
+	dir, err := sceneDirName(action.Name)
+	if err != nil {
+		return fmt.Errorf("scene action %d: %w", index, err)
+	}
+	if err := os.MkdirAll(filepath.Join(c.outputDir(), dir), 0o755); err != nil {
+		return fmt.Errorf("scene action %d: create scene directory: %w", index, err)
+	}
+
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Starting scene %q in %s (action %d)\n", action.Name, dir, index)
+	}
+
+	c.mu.Lock()
+	c.scene = dir
+	c.scenes = append(c.scenes, scene{Name: action.Name, Dir: dir})
+	c.screenshotCount = 0
+	c.mu.Unlock()
+
+	// Dedup never points the first frame of a scene back at the previous
+	// one, so every scene has its own opening frame
+	c.encMu.Lock()
+	c.lastFrame = lastFrame{}
+	c.encMu.Unlock()
+	return nil
+}
+
+// frameDirLocked returns the directory new frames are written to: the
+// current scene's, or the output directory before the first Scene. Callers
+// hold c.mu.
+func (c *Capturer) frameDirLocked() string {
+	return filepath.Join(c.outputDir(), c.scene)
+}
+
+// sceneOf returns the directory of the scene a frame written to path
+// belongs to, or "" when it is outside any scene.
+func (c *Capturer) sceneOf(path string) string {
+	rel, err := filepath.Rel(c.outputDir(), filepath.Dir(path))
+	if err != nil || rel == "." {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.scenes {
+		if s.Dir == rel {
+			return s.Dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package capture
+
+import (
+	"context"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestSceneDirName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "intro", want: "intro"},
+		{name: "Step 2: build", want: "Step_2__build"},
+		{name: "../etc", want: "___etc"},
+		{name: "manifest.json", want: "manifest_json"},
+		{name: " . ", wantErr: "no usable characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sceneDirName(tt.name)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCapturer_runSession_Scenes(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionScreenshot},
+			{Kind: script.ActionScene, Name: "intro"},
+			{Kind: script.ActionScreenshot, Name: "menu"},
+			{Kind: script.ActionScreenshot},
+			{Kind: script.ActionScene, Name: "step 2"},
+			{Kind: script.ActionScreenshot},
+		},
+	})
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	var files []string
+	for _, f := range c.manifest().Frames {
+		files = append(files, f.File)
+		_, err := os.Stat(filepath.Join(c.config.OutputDir, filepath.FromSlash(f.File)))
+		assert.NoError(t, err, f.File)
+	}
+	assert.Equal(t, []string{
+		"screenshot_001.png", // initial
+		"screenshot_002.png",
+		"intro/menu.png",
+		"intro/screenshot_001.png",
+		"step_2/screenshot_001.png",
+		"step_2/screenshot_002.png", // final
+	}, files)
+
+	scenes := c.manifest().Scenes
+	require.Len(t, scenes, 2)
+	assert.Equal(t, "intro", scenes[0].Name)
+	assert.Equal(t, "intro", scenes[0].Dir)
+	require.Len(t, scenes[0].Frames, 2)
+	assert.Equal(t, "intro/menu.png", scenes[0].Frames[0].File)
+	assert.Equal(t, "step 2", scenes[1].Name)
+	assert.Equal(t, "step_2", scenes[1].Dir)
+	require.Len(t, scenes[1].Frames, 2)
+	assert.Equal(t, FrameFinal, scenes[1].Frames[1].Kind)
+}
+
+func TestCapturer_runSession_NoScenes(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	m := c.manifest()
+	assert.Nil(t, m.Scenes)
+	require.Len(t, m.Frames, 2)
+	assert.Equal(t, "screenshot_001.png", m.Frames[0].File)
+}
+
+func TestGIFEncoder_Scenes(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "intro"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "outro"), 0o755))
+
+	enc := &gifEncoder{}
+	require.NoError(t, enc.Begin(Meta{OutputDir: dir}))
+	frames := []Frame{
+		{Path: filepath.Join(dir, "screenshot_001.png"), Data: testPNG(t, red), Offset: 0},
+		{Path: filepath.Join(dir, "intro", "screenshot_001.png"), Data: testPNG(t, red), Offset: 100 * time.Millisecond, Scene: "intro"},
+		{Path: filepath.Join(dir, "intro", "screenshot_002.png"), Data: testPNG(t, red), Offset: 300 * time.Millisecond, Scene: "intro"},
+		{Path: filepath.Join(dir, "outro", "screenshot_001.png"), Data: testPNG(t, red), Offset: 600 * time.Millisecond, Scene: "outro"},
+	}
+	for _, f := range frames {
+		require.NoError(t, enc.Frame(f))
+	}
+	require.NoError(t, enc.End())
+	assert.Equal(t, filepath.Join(dir, GIFFilename), enc.Artifact())
+
+	for path, wantDelays := range map[string][]int{
+		GIFFilename:                         {10, 20, 30, 100},
+		filepath.Join("intro", GIFFilename): {20, 100},
+		filepath.Join("outro", GIFFilename): {100},
+	} {
+		file, err := os.Open(filepath.Join(dir, path))
+		require.NoError(t, err, path)
+		anim, err := gif.DecodeAll(file)
+		_ = file.Close()
+		require.NoError(t, err, path)
+		assert.Equal(t, wantDelays, anim.Delay, path)
+	}
+}
+
+func TestCapturer_OutTmp_MergesSceneDirectories(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(outputDir, "intro"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "intro", "notes.txt"), []byte("keep"), 0o644))
+
+	c := newFakeCapturer(t, &config.Config{
+		OutputDir: outputDir,
+		OutTmp:    true,
+		Actions:   []script.Action{{Kind: script.ActionScene, Name: "intro"}},
+	})
+	require.NoError(t, c.prepareOutput())
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	require.NoError(t, c.publishOutput())
+
+	for _, name := range []string{"screenshot_001.png", "intro/screenshot_001.png", "intro/notes.txt"} {
+		_, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(name)))
+		assert.NoError(t, err, name)
+	}
+}
@@ -0,0 +1,42 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// overflowWarning is printed after a run in which lines scrolled off the
+// top of the terminal, where no frame taken afterwards shows them.
+func overflowWarning(lines int) string {
+	noun := "lines"
+	if lines == 1 {
+		noun = "line"
+	}
+	return fmt.Sprintf("Warning: %d %s of output scrolled off the top of the terminal, so later frames do not show them; pass a larger --rows, or page the output with less", lines, noun)
+}
+
+// watchScrollback starts counting the lines that scroll off the top of the
+// terminal. A page that cannot count them only loses the overflow warning.
+func (c *Capturer) watchScrollback(ctx context.Context) {
+	c.scrollback = 0
+	if _, err := c.readScrollback(ctx); err != nil && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Warning: watch terminal scrollback: %v\n", err)
+	}
+}
+
+// checkScrollback records the peak scrollback of the run for the manifest
+// and warns when output scrolled off the top of the terminal.
+func (c *Capturer) checkScrollback(ctx context.Context) {
+	lines, err := c.readScrollback(ctx)
+	if err != nil {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Warning: read terminal scrollback: %v\n", err)
+		}
+		return
+	}
+	c.scrollback = lines
+	if lines > 0 {
+		fmt.Fprintln(os.Stderr, overflowWarning(lines))
+	}
+}
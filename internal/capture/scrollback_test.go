@@ -0,0 +1,65 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestOverflowWarning(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+		want  string
+	}{
+		{name: "one line", lines: 1, want: "Warning: 1 line of output scrolled off the top of the terminal"},
+		{name: "many lines", lines: 42, want: "Warning: 42 lines of output scrolled off the top of the terminal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := overflowWarning(tt.lines)
+			assert.Contains(t, got, tt.want)
+			assert.Contains(t, got, "--rows")
+		})
+	}
+}
+
+func TestCapturer_Scrollback(t *testing.T) {
+	tests := []struct {
+		name string
+		// reads are the results of each readScrollback call, in order.
+		reads []int
+		err   error
+		want  int
+	}{
+		{name: "no overflow", reads: []int{0, 0}},
+		{name: "overflow during the run", reads: []int{0, 37}, want: 37},
+		{name: "unreadable", err: errors.New("terminal page does not expose window.term")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{})
+			calls := 0
+			c.readScrollback = func(context.Context) (int, error) {
+				defer func() { calls++ }()
+				if tt.err != nil {
+					return 0, tt.err
+				}
+				return tt.reads[calls], nil
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx), "scrollback never fails the capture")
+
+			assert.Equal(t, 2, calls, "watched from the start and read at the end")
+			assert.Equal(t, tt.want, c.manifest().Scrollback)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// redacted replaces secret values in the session log.
+const redacted = "[redacted]"
+
+// secretValues returns the values of the secret params, longest first so a
+// value containing another is redacted whole.
+func secretValues(cfg *config.Config) []string {
+	var values []string
+	for _, p := range cfg.Params {
+		if p.Secret && p.Value != "" {
+			values = append(values, p.Value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
+
+// redactWriter writes to w with every secret value replaced by redacted.
+type redactWriter struct {
+	w        io.Writer
+	replacer *strings.Replacer
+}
+
+// redactSecrets wraps w so the values of cfg's secret params never reach
+// it. Without any, w is returned as is.
+func redactSecrets(w io.Writer, cfg *config.Config) io.Writer {
+	values := secretValues(cfg)
+	if len(values) == 0 {
+		return w
+	}
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, redacted)
+	}
+	return &redactWriter{w: w, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, r.replacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// showSecret holds back frames from a TypeSecret on, or lets them through
+// again once Enter or Ctrl+L has taken its text off the screen.
+func (c *Capturer) showSecret(shown bool) {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+	if c.secretShown != shown && c.config.Verbose {
+		if shown {
+			fmt.Fprintf(os.Stderr, "Holding back frames while a secret is on screen\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Resuming frames\n")
+		}
+	}
+	c.secretShown = shown
+}
+
+// secretHeldLocked reports whether a frame of the given kind is held back
+// because a TypeSecret may be on screen, and warns when the frame was
+// asked for. Callers hold c.encMu.
+func (c *Capturer) secretHeldLocked(kind FrameKind) bool {
+	if !c.secretShown {
+		return false
+	}
+	if kind == FrameExplicit || kind == FrameFinal {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s screenshot while a secret may be on screen; frames resume after Enter or Ctrl+L\n", kind)
+	}
+	return true
+}
+
+// clearsSecret reports whether action takes a TypeSecret's text off the
+// screen: Enter submits it and Ctrl+L clears the screen.
+func clearsSecret(action script.Action) bool {
+	switch action.Kind {
+	case script.ActionKey:
+		return action.Modifiers == 0 && strings.EqualFold(action.Key, "enter")
+	case script.ActionCtrl:
+		return strings.EqualFold(action.Key, "l")
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	cfg := &config.Config{Params: []script.Param{
+		{Name: "USER", Value: "ann"},
+		{Name: "TOKEN", Secret: true, Value: "s3cret"},
+		{Name: "LONG", Secret: true, Value: "s3cret-and-more"},
+		{Name: "EMPTY", Secret: true},
+	}}
+
+	var buf bytes.Buffer
+	w := redactSecrets(&buf, cfg)
+	line := `Input.insertText {"text":"s3cret-and-more s3cret"} for ann`
+	n, err := w.Write([]byte(line))
+	require.NoError(t, err)
+	assert.Equal(t, len(line), n, "the length written is that of the input")
+	assert.Equal(t, `Input.insertText {"text":"[redacted] [redacted]"} for ann`, buf.String())
+
+	assert.Same(t, &buf, redactSecrets(&buf, &config.Config{}), "nothing to redact")
+}
+
+func TestClearsSecret(t *testing.T) {
+	tests := []struct {
+		action script.Action
+		want   bool
+	}{
+		{action: script.Action{Kind: script.ActionKey, Key: "Enter"}, want: true},
+		{action: script.Action{Kind: script.ActionKey, Key: "enter", Repeat: 2}, want: true},
+		{action: script.Action{Kind: script.ActionCtrl, Key: "l"}, want: true},
+		{action: script.Action{Kind: script.ActionKey, Key: "Enter", Modifiers: script.ModAlt}, want: false},
+		{action: script.Action{Kind: script.ActionCtrl, Key: "c"}, want: false},
+		{action: script.Action{Kind: script.ActionKey, Key: "Tab"}, want: false},
+		{action: script.Action{Kind: script.ActionType, Text: "\n"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, clearsSecret(tt.action))
+		})
+	}
+}
+
+func TestCapturer_runSession_TypeSecret(t *testing.T) {
+	secret := script.Action{Kind: script.ActionType, Text: "hunter2", Speed: 40 * time.Millisecond, Secret: true}
+
+	tests := []struct {
+		name    string
+		actions []script.Action
+		want    []string
+	}{
+		{
+			name: "frames resume after Enter",
+			actions: []script.Action{
+				secret,
+				{Kind: script.ActionScreenshot, Name: "typed"},
+				{Kind: script.ActionKey, Key: "Enter"},
+				{Kind: script.ActionScreenshot, Name: "submitted"},
+			},
+			want: []string{"screenshot_001.png", "submitted.png", "screenshot_002.png"},
+		},
+		{
+			name: "frames resume after Ctrl+L",
+			actions: []script.Action{
+				secret,
+				{Kind: script.ActionCtrl, Key: "l"},
+				{Kind: script.ActionScreenshot, Name: "cleared"},
+			},
+			want: []string{"screenshot_001.png", "cleared.png", "screenshot_002.png"},
+		},
+		{
+			name: "secret still on screen at the end",
+			actions: []script.Action{
+				{Kind: script.ActionScreenshot, Name: "before"},
+				secret,
+				{Kind: script.ActionKey, Key: "Tab"},
+			},
+			want: []string{"screenshot_001.png", "before.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Actions: tt.actions, NoCaptureWhileTyping: true})
+			enc := &recordingEncoder{}
+			c.encoder = enc
+			var mu sync.Mutex
+			var typed strings.Builder
+			c.sendKey = func(_ context.Context, key string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if len(key) == 1 {
+					typed.WriteString(key)
+				}
+				if c.typingSecret.Load() != (len(key) == 1) {
+					t.Errorf("key %q sent with typingSecret %v", key, c.typingSecret.Load())
+				}
+				return nil
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			var got []string
+			for _, f := range enc.frames {
+				got = append(got, filepath.Base(f.Path))
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, "hunter2", typed.String(), "the secret is still typed")
+			assert.False(t, c.typingSecret.Load())
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// terminalSelectors are CSS selectors for the element that is screenshotted,
+// most preferred first. ttyd wraps xterm.js in #terminal-container; pages
+// that were restructured between ttyd versions are matched by the later
+// entries, down to the xterm.js root element itself.
+var terminalSelectors = []string{"#terminal-container", ".terminal-container", "#terminal", ".xterm"}
+
+// screenSelectors match xterm.js elements that only become visible once the
+// terminal has rendered, across renderer types.
+var screenSelectors = []string{".xterm-screen", ".xterm canvas", ".xterm-rows"}
+
+// terminalWaitTimeout bounds how long to wait for the terminal to render,
+// and selectorWaitTimeout how long after that for the Config.Selector
+// element to show.
+const (
+	terminalWaitTimeout = 30 * time.Second
+	selectorWaitTimeout = 5 * time.Second
+)
+
+// DOMDumpFilename is written to the output directory when no terminal
+// element can be found, to show what the page contained instead.
+const DOMDumpFilename = "terminal-dom.txt"
+
+// findTerminalJS evaluates to the first visible selector of the first list
+// once any selector of the second list is visible, and to "" before that.
+const findTerminalJS = `((containers, screens) => {
+	const visible = (sel) => {
+		const el = document.querySelector(sel);
+		if (!el) return false;
+		const r = el.getBoundingClientRect();
+		return r.width > 0 && r.height > 0;
+	};
+	if (!screens.some(visible)) return "";
+	return containers.find(visible) || "";
+})(%s, %s)`
+
+// elementVisibleJS evaluates to whether the first element matching a
+// selector has a size, as findTerminalJS checks.
+const elementVisibleJS = `((sel) => {
+	const el = document.querySelector(sel);
+	if (!el) return false;
+	const r = el.getBoundingClientRect();
+	return r.width > 0 && r.height > 0;
+})(%s)`
+
+// domOutlineJS evaluates to an indented outline of the page's elements,
+// tag#id.class per line, a few levels deep.
+const domOutlineJS = `(() => {
+	const lines = [];
+	const walk = (el, depth) => {
+		let s = el.tagName.toLowerCase();
+		if (el.id) s += "#" + el.id;
+		if (el.classList.length) s += "." + [...el.classList].join(".");
+		lines.push("  ".repeat(depth) + s);
+		if (depth < 5) for (const child of el.children) walk(child, depth + 1);
+	};
+	walk(document.documentElement, 0);
+	return lines.join("\n");
+})()`
+
+// waitForTerminal polls the page until the terminal has rendered and
+// remembers which selector matched its element, then waits for the
+// Config.Selector element, if any. When nothing matches in time, the page
+// outline is written to DOMDumpFilename for debugging.
+func (c *Capturer) waitForTerminal(ctx context.Context) error {
+	timer := time.NewTimer(terminalWaitTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		selector, err := c.findTerminal(ctx)
+		if err != nil {
+			return err
+		}
+		if selector != "" {
+			c.terminalSelector = selector
+			if c.config.Verbose {
+				fmt.Fprintf(os.Stderr, "Terminal element matched %s\n", selector)
+			}
+			if c.config.Selector != "" {
+				return c.waitForSelector(ctx, c.config.Selector, selectorWaitTimeout)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return c.terminalNotFound(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForSelector polls the page until the element of selector is visible,
+// for at most timeout.
+func (c *Capturer) waitForSelector(ctx context.Context, selector string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		visible, err := c.findElement(ctx, selector)
+		if err != nil {
+			return fmt.Errorf("selector %q: %w", selector, err)
+		}
+		if visible {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("selector %q matched no visible element within %v", selector, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// elementVisible runs elementVisibleJS once for selector.
+func elementVisible(ctx context.Context, selector string) (bool, error) {
+	sel, err := json.Marshal(selector)
+	if err != nil {
+		return false, fmt.Errorf("encode selector: %w", err)
+	}
+	var visible bool
+	err = chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(elementVisibleJS, sel), &visible))
+	return visible, err
+}
+
+// findTerminalElement runs findTerminalJS once and returns the selector
+// that matched the rendered terminal, or "" while there is none.
+func findTerminalElement(ctx context.Context) (string, error) {
+	containers, _ := json.Marshal(terminalSelectors)
+	screens, _ := json.Marshal(screenSelectors)
+	var selector string
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(findTerminalJS, containers, screens), &selector))
+	return selector, err
+}
+
+// terminalNotFound writes the page outline to the output directory and
+// returns an error describing what was tried.
+func (c *Capturer) terminalNotFound(ctx context.Context) error {
+	msg := fmt.Sprintf("no terminal element rendered within %v (tried %s with %s)",
+		terminalWaitTimeout, strings.Join(terminalSelectors, ", "), strings.Join(screenSelectors, ", "))
+	if c.ttyd != nil {
+		msg += "; ttyd " + c.env.TTyd
+	}
+
+	var outline string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(domOutlineJS, &outline)); err != nil {
+		return fmt.Errorf("%s; read page structure: %w", msg, err)
+	}
+	path := filepath.Join(c.outputDir(), DOMDumpFilename)
+	if err := os.WriteFile(path, []byte(outline+"\n"), 0o644); err != nil {
+		return fmt.Errorf("%s; write page structure: %w", msg, err)
+	}
+	return fmt.Errorf("%s; page structure written to %s", msg, c.finalPath(path))
+}
+
+// ttydVersion returns the version reported by the ttyd binary, or "unknown".
+func ttydVersion() string {
+	out, err := exec.Command("ttyd", "--version").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return parseTTydVersion(string(out))
+}
+
+// parseTTydVersion extracts the version from `ttyd --version` output such as
+// "ttyd version 1.7.4-68c0ddb".
+func parseTTydVersion(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return fields[len(fields)-1]
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+func TestParseTTydVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{name: "release build", out: "ttyd version 1.7.4-68c0ddb\n", want: "1.7.4-68c0ddb"},
+		{name: "old format", out: "ttyd version 1.6.3", want: "1.6.3"},
+		{name: "empty output", out: "", want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseTTydVersion(tt.out))
+		})
+	}
+}
+
+func TestTerminalSelectors_Order(t *testing.T) {
+	// captureTerminal falls back to the first entry before waitForTerminal runs.
+	assert.Equal(t, "#terminal-container", terminalSelectors[0])
+	assert.Equal(t, ".xterm", terminalSelectors[len(terminalSelectors)-1], "the xterm.js root is the last resort")
+}
+
+func TestCapturer_waitForSelector(t *testing.T) {
+	tests := []struct {
+		name string
+		// visibleAfter is the number of checks before the element shows;
+		// negative never shows it.
+		visibleAfter int
+		timeout      time.Duration
+		checkErr     error
+		wantErr      string
+	}{
+		{name: "visible at once", visibleAfter: 0, timeout: time.Second},
+		{name: "visible after a few polls", visibleAfter: 3, timeout: time.Second},
+		{name: "never visible", visibleAfter: -1, timeout: 50 * time.Millisecond, wantErr: `selector ".status-bar" matched no visible element within 50ms`},
+		{name: "invalid selector", timeout: time.Second, checkErr: errors.New("SyntaxError: '.status-bar' is not a valid selector"), wantErr: `selector ".status-bar": SyntaxError`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{})
+			checks := 0
+			c.findElement = func(_ context.Context, selector string) (bool, error) {
+				assert.Equal(t, ".status-bar", selector)
+				checks++
+				if tt.checkErr != nil {
+					return false, tt.checkErr
+				}
+				return tt.visibleAfter >= 0 && checks > tt.visibleAfter, nil
+			}
+
+			err := c.waitForSelector(context.Background(), ".status-bar", tt.timeout)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.visibleAfter+1, checks)
+		})
+	}
+}
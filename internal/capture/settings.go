@@ -0,0 +1,98 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// resizeTerminalJS resizes the xterm.js terminal on window.term to the given
+// cols and rows; zero keeps the current value. ttyd has no client option for
+// the initial geometry, so the size is set once the terminal is up. ttyd
+// forwards the resize to the PTY. It evaluates to false when the page has
+// no window.term.
+const resizeTerminalJS = `((cols, rows) => {
+	if (!window.term) return false;
+	window.term.resize(cols || window.term.cols, rows || window.term.rows);
+	return true;
+})(%d, %d)`
+
+// setBrowserViewport sets the browser viewport to width x height CSS pixels.
+func setBrowserViewport(ctx context.Context, width, height int) error {
+	return chromedp.Run(ctx, chromedp.EmulateViewport(int64(width), int64(height)))
+}
+
+// resizeTerminal fixes the terminal size in character cells.
+func resizeTerminal(ctx context.Context, cols, rows int) error {
+	var ok bool
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(resizeTerminalJS, cols, rows), &ok)); err != nil {
+		return err
+	}
+	if !ok {
+		return errNoTerm
+	}
+	return nil
+}
+
+// viewportSize returns the configured viewport, falling back to the defaults.
+func viewportSize(cfg *config.Config) (width, height int) {
+	width, height = cfg.Width, cfg.Height
+	if width == 0 {
+		width = config.DefaultWidth
+	}
+	if height == 0 {
+		height = config.DefaultHeight
+	}
+	return width, height
+}
+
+// applyGeometry sets the viewport to the current size and, when Cols or Rows
+// is configured, resizes the terminal to match, since a viewport change
+// refits the terminal to the new size.
+func (c *Capturer) applyGeometry(browserCtx context.Context) error {
+	if err := c.setViewport(browserCtx, c.width, c.height); err != nil {
+		return fmt.Errorf("set viewport: %w", err)
+	}
+	if c.config.Cols > 0 || c.config.Rows > 0 {
+		if err := c.resizeTerminal(browserCtx, c.config.Cols, c.config.Rows); err != nil {
+			return fmt.Errorf("resize terminal: %w", err)
+		}
+	}
+	return nil
+}
+
+// executeSetAction changes a terminal setting mid-script.
+func (c *Capturer) executeSetAction(browserCtx context.Context, action script.Action, index int) error {
+	switch action.Setting {
+	case "theme":
+		if err := c.setTheme(browserCtx, action.Value); err != nil {
+			return fmt.Errorf("set action %d: %w", index, err)
+		}
+		return nil
+	case "width", "height":
+		n, err := strconv.Atoi(action.Value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("set action %d: %s must be a positive number, got %q", index, action.Setting, action.Value)
+		}
+		if action.Setting == "width" {
+			c.width = n
+		} else {
+			c.height = n
+		}
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Setting viewport to %dx%d (action %d)\n", c.width, c.height, index)
+		}
+		if err := c.applyGeometry(browserCtx); err != nil {
+			return fmt.Errorf("set action %d: %w", index, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("set action %d: unknown setting %q", index, action.Setting)
+	}
+}
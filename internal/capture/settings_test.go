@@ -0,0 +1,121 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestViewportSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "defaults", cfg: &config.Config{}, wantWidth: 1280, wantHeight: 720},
+		{name: "configured", cfg: &config.Config{Width: 800, Height: 600}, wantWidth: 800, wantHeight: 600},
+		{name: "width only", cfg: &config.Config{Width: 1920}, wantWidth: 1920, wantHeight: 720},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := viewportSize(tt.cfg)
+			assert.Equal(t, tt.wantWidth, width)
+			assert.Equal(t, tt.wantHeight, height)
+		})
+	}
+}
+
+func TestCapturer_runSession_Geometry(t *testing.T) {
+	enter := script.Action{Kind: script.ActionKey, Key: "enter", Repeat: 1}
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want []string
+	}{
+		{
+			name: "fits terminal to viewport by default",
+			cfg: &config.Config{Actions: []script.Action{
+				{Kind: script.ActionSet, Setting: "width", Value: "1024"},
+				enter,
+			}},
+			want: []string{"frame", "viewport 1024x720", "frame"},
+		},
+		{
+			name: "fixed cols and rows are kept across viewport changes",
+			cfg: &config.Config{Width: 800, Height: 600, Cols: 100, Rows: 30, Actions: []script.Action{
+				{Kind: script.ActionSet, Setting: "height", Value: "400"},
+				enter,
+			}},
+			want: []string{"resize 100x30", "frame", "viewport 800x400", "resize 100x30", "frame"},
+		},
+		{
+			name: "settings-only scripts apply before the initial frame",
+			cfg: &config.Config{Actions: []script.Action{
+				{Kind: script.ActionSet, Setting: "width", Value: "1024"},
+				{Kind: script.ActionSet, Setting: "height", Value: "600"},
+			}},
+			want: []string{"viewport 1024x720", "viewport 1024x600", "frame", "frame"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, tt.cfg)
+
+			var events []string
+			c.setViewport = func(_ context.Context, width, height int) error {
+				events = append(events, fmt.Sprintf("viewport %dx%d", width, height))
+				return nil
+			}
+			c.resizeTerminal = func(_ context.Context, cols, rows int) error {
+				events = append(events, fmt.Sprintf("resize %dx%d", cols, rows))
+				return nil
+			}
+			c.captureFrame = func(context.Context) ([]byte, error) {
+				events = append(events, "frame")
+				return []byte("png"), nil
+			}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+			assert.Equal(t, tt.want, events)
+		})
+	}
+}
+
+func TestCapturer_runSession_Static(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []script.Action
+	}{
+		{name: "no actions"},
+		{name: "settings only", actions: []script.Action{{Kind: script.ActionSet, Setting: "theme", Value: "dracula"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Actions: tt.actions})
+			keys := recordKeys(c, "", nil)
+			enc := &recordingEncoder{}
+			c.encoder = enc
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+			assert.Empty(t, keys())
+
+			var kinds []FrameKind
+			for _, f := range c.Stats().Frames {
+				kinds = append(kinds, f.Kind)
+			}
+			assert.Equal(t, []FrameKind{FrameInitial, FrameFinal}, kinds)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package capture
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// SharedBrowser is a Chrome that several runs open their pages in, each in
+// a tab with a browser context of its own, so that no cookies or storage
+// carry over, instead of each run paying for Chrome to start. Chrome is
+// started by the first run that needs it, with that run's options, and
+// again if it has died since; it lasts until Close.
+type SharedBrowser struct {
+	mu         sync.Mutex
+	runID      string
+	browserCtx context.Context
+	close      func()
+
+	// launch starts Chrome and openTab opens a tab in it. They default to
+	// chromedp and are replaced in tests.
+	launch  func(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error)
+	openTab func(browserCtx context.Context) (context.Context, context.CancelFunc, error)
+}
+
+// NewSharedBrowser returns a SharedBrowser that has not started Chrome yet.
+func NewSharedBrowser() *SharedBrowser {
+	return &SharedBrowser{
+		runID:   newRunID(time.Now()),
+		launch:  launchChrome,
+		openTab: openChromeTab,
+	}
+}
+
+// ShareBrowser makes Run open its page in a tab of b instead of starting
+// Chrome. Runs with a Chrome profile or a debug log still start their own:
+// the profile is only for their Chrome, and the log records the messages
+// of the whole browser.
+func (c *Capturer) ShareBrowser(b *SharedBrowser) {
+	if c.config.ChromeProfile != "" || c.config.LogFile != "" {
+		return
+	}
+	c.shared = b
+	c.startBrowser = b.newTab
+}
+
+// newTab is the startBrowser of runs sharing b. The function it returns
+// closes the tab, leaving Chrome running.
+func (b *SharedBrowser) newTab(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.browserCtx != nil && b.browserCtx.Err() != nil {
+		b.closeLocked()
+	}
+	if b.browserCtx == nil {
+		dir, removeProfile, err := newTempProfile(b.runID)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Chrome outlives the run whose options it starts with, so it
+		// gets a profile and a run ID of its own
+		allocOpts = append(allocOpts, chromedp.UserDataDir(dir), chromedp.Env(RunIDEnv+"="+b.runID))
+		browserCtx, closeBrowser, err := b.launch(ctx, allocOpts, browserOpts)
+		if err != nil {
+			removeProfile()
+			return nil, nil, err
+		}
+		b.browserCtx = browserCtx
+		b.close = func() {
+			closeBrowser()
+			removeProfile()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	tabCtx, closeTab, err := b.openTab(b.browserCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tabCtx, sync.OnceFunc(closeTab), nil
+}
+
+// Close terminates Chrome, if it was started, and removes its profile.
+func (b *SharedBrowser) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked()
+}
+
+// closeLocked is Close for callers holding b.mu.
+func (b *SharedBrowser) closeLocked() {
+	if b.close != nil {
+		b.close()
+	}
+	b.browserCtx, b.close = nil, nil
+}
+
+// openChromeTab opens a tab in the Chrome of browserCtx, with a browser
+// context of its own that closing the tab disposes of.
+func openChromeTab(browserCtx context.Context) (context.Context, context.CancelFunc, error) {
+	tabCtx, cancel := chromedp.NewContext(browserCtx, chromedp.WithNewBrowserContext())
+	if err := chromedp.Run(tabCtx); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return tabCtx, cancel, nil
+}
@@ -0,0 +1,130 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/testutil"
+)
+
+// newFakeSharedBrowser returns a SharedBrowser that launches b, and the
+// number of tabs it has open.
+func newFakeSharedBrowser(b *testutil.Browser) (*SharedBrowser, *atomic.Int32) {
+	shared := NewSharedBrowser()
+	shared.launch = b.Launch
+	var tabs atomic.Int32
+	shared.openTab = func(browserCtx context.Context) (context.Context, context.CancelFunc, error) {
+		tabs.Add(1)
+		tabCtx, cancel := context.WithCancel(browserCtx)
+		return tabCtx, func() {
+			cancel()
+			tabs.Add(-1)
+		}, nil
+	}
+	return shared, &tabs
+}
+
+func TestCapturer_Run_SharedBrowser(t *testing.T) {
+	first, b, _ := newHarnessCapturer(t, &config.Config{})
+	shared, tabs := newFakeSharedBrowser(b)
+	first.ShareBrowser(shared)
+	require.NoError(t, first.Run(context.Background()))
+
+	// The second run drives the same fake browser
+	second, _, _ := newHarnessCapturer(t, &config.Config{})
+	second.ShareBrowser(shared)
+	second.checkBrowser = b.CheckVersion
+	second.navigate = b.Navigate
+	second.findTerminal = b.FindTerminal
+	second.setViewport = b.SetViewport
+	second.resizeTerminal = b.Resize
+	second.captureFrame = b.Screenshot
+	second.readText = b.Text
+	second.probeBrowser = func(ctx context.Context) (Environment, error) {
+		product, err := b.Product(ctx)
+		return Environment{Browser: product}, err
+	}
+	require.NoError(t, second.Run(context.Background()))
+
+	assert.Equal(t, 1, b.Count(testutil.StepLaunch), "Chrome starts once")
+	assert.Equal(t, 2, b.Count(testutil.StepNavigate))
+	assert.Zero(t, tabs.Load(), "each run closes its tab")
+	assert.False(t, b.Closed(), "Chrome outlives the runs")
+	profile := filepath.Join(os.TempDir(), chromeProfilePrefix+shared.runID)
+	assert.DirExists(t, profile)
+
+	shared.Close()
+	assert.True(t, b.Closed())
+	assert.NoDirExists(t, profile)
+	assert.NotPanics(t, shared.Close)
+}
+
+func TestSharedBrowser_newTab(t *testing.T) {
+	t.Run("relaunches Chrome that died", func(t *testing.T) {
+		b := testutil.NewBrowser()
+		shared, tabs := newFakeSharedBrowser(b)
+		defer shared.Close()
+
+		_, closeTab, err := shared.newTab(context.Background(), nil, nil)
+		require.NoError(t, err)
+		closeTab()
+		closeTab()
+		assert.Zero(t, tabs.Load(), "closing a tab twice is harmless")
+
+		// The browser's context ends when Chrome goes away
+		shared.close()
+		_, closeTab, err = shared.newTab(context.Background(), nil, nil)
+		require.NoError(t, err)
+		closeTab()
+		assert.Equal(t, 2, b.Count(testutil.StepLaunch))
+	})
+
+	t.Run("a failed launch is tried again", func(t *testing.T) {
+		shared := NewSharedBrowser()
+		defer shared.Close()
+		boom := errors.New("boom")
+		var launches int
+		shared.launch = func(ctx context.Context, allocOpts []chromedp.ExecAllocatorOption, browserOpts []chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+			launches++
+			return nil, nil, boom
+		}
+
+		for range 2 {
+			_, _, err := shared.newTab(context.Background(), nil, nil)
+			assert.ErrorIs(t, err, boom)
+		}
+		assert.Equal(t, 2, launches)
+		assert.NoDirExists(t, filepath.Join(os.TempDir(), chromeProfilePrefix+shared.runID), "the profile is removed")
+	})
+}
+
+func TestCapturer_ShareBrowser_OwnChrome(t *testing.T) {
+	shared := NewSharedBrowser()
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{name: "chrome profile", cfg: &config.Config{Command: "bash", ChromeProfile: t.TempDir()}},
+		{name: "debug log", cfg: &config.Config{Command: "bash", LogFile: filepath.Join(t.TempDir(), "scr.log")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCapturer(tt.cfg)
+			c.ShareBrowser(shared)
+			assert.Nil(t, c.shared, "the run starts its own Chrome")
+		})
+	}
+	c := NewCapturer(&config.Config{Command: "bash"})
+	c.ShareBrowser(shared)
+	assert.Same(t, shared, c.shared)
+}
@@ -0,0 +1,25 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// errNoCommand is returned by Signal actions when scr did not start the
+// terminal's command.
+var errNoCommand = errors.New("signals need a command started by scr; not available when attaching to a terminal URL")
+
+// signal delivers a signal to the command's processes, bypassing the
+// terminal.
+func (p *Player) signal(action script.Action, index int) error {
+	p.logf("Sending SIG%s to the command (action %d)\n", action.Signal, index)
+	if p.Signal == nil {
+		return fmt.Errorf("signal %s: %w", action.Signal, errNoCommand)
+	}
+	if err := p.Signal(action.Signal); err != nil {
+		return fmt.Errorf("signal %s: %w", action.Signal, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestCapturer_executeActions_Signal(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionSignal, Signal: "INT"},
+			{Kind: script.ActionSignal, Signal: "WINCH"},
+		},
+	})
+	var sent []string
+	c.signal = func(name string) error {
+		sent = append(sent, name)
+		return nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.executeActions(ctx, ctx))
+	assert.Equal(t, []string{"INT", "WINCH"}, sent)
+}
+
+func TestCapturer_executeActions_Signal_Error(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{{Kind: script.ActionSignal, Signal: "TERM"}},
+	})
+	c.signal = func(string) error { return errors.New("no command process is running under ttyd") }
+
+	ctx := context.Background()
+	err := c.executeActions(ctx, ctx)
+	assert.EqualError(t, err, "signal TERM: no command process is running under ttyd")
+}
+
+func TestNewCapturer_SignalWhenAttached(t *testing.T) {
+	c := NewCapturer(&config.Config{TerminalURL: "http://localhost:7681"})
+	assert.ErrorIs(t, c.signal("INT"), errNoCommand)
+}
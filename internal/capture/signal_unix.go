@@ -0,0 +1,134 @@
+//go:build !windows
+
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalNumbers maps the names in script.Signals to signals.
+var signalNumbers = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"KILL":  syscall.SIGKILL,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"TERM":  syscall.SIGTERM,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"TSTP":  syscall.SIGTSTP,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// SignalCommand delivers the named signal, e.g. "INT", to every process ttyd
+// runs for its clients, and their descendants. ttyd itself is not signaled,
+// and a ttyd run over ssh cannot be reached.
+func (s *TTydServer) SignalCommand(name string) error {
+	sig, ok := signalNumbers[name]
+	if !ok {
+		return fmt.Errorf("unknown signal %q", name)
+	}
+	if s.SSH != "" {
+		return fmt.Errorf("signals cannot reach a command run on %s", s.SSH)
+	}
+	process := s.process()
+	if process == nil {
+		return fmt.Errorf("ttyd is not running")
+	}
+
+	pids, err := descendants(process.Pid)
+	if err != nil {
+		return fmt.Errorf("find command processes: %w", err)
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no command process is running under ttyd")
+	}
+	for _, pid := range pids {
+		// A process may exit in the meantime, which is no failure
+		if err := syscall.Kill(pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("signal process %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// descendants returns the PIDs of all processes below pid, parents first.
+func descendants(pid int) ([]int, error) {
+	var all []int
+	queue := []int{pid}
+	for len(queue) > 0 {
+		children, err := childPIDs(queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue[1:], children...)
+		all = append(all, children...)
+	}
+	return all, nil
+}
+
+// childPIDs returns the direct children of pid, read from /proc on Linux
+// and with pgrep elsewhere.
+func childPIDs(pid int) ([]int, error) {
+	if runtime.GOOS == "linux" {
+		return procChildPIDs("/proc", pid)
+	}
+	out, err := exec.Command("pgrep", "-P", strconv.Itoa(pid)).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// pgrep exits 1 when nothing matched
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pgrep: %w", err)
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(out)) {
+		if child, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, child)
+		}
+	}
+	return pids, nil
+}
+
+// procChildPIDs scans the stat files of a procfs mounted at root for
+// processes whose parent is pid.
+func procChildPIDs(root string, pid int) ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(root, entry.Name(), "stat"))
+		if err != nil {
+			// The process exited while scanning
+			continue
+		}
+		// The command name is in parentheses and may contain spaces, so
+		// the fields are read after its closing parenthesis: state, ppid
+		i := bytes.LastIndexByte(stat, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[i+1:]))
+		if len(fields) >= 2 && fields[1] == strconv.Itoa(pid) {
+			pids = append(pids, child)
+		}
+	}
+	return pids, nil
+}
@@ -0,0 +1,72 @@
+//go:build !windows
+
+package capture
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcChildPIDs(t *testing.T) {
+	root := t.TempDir()
+	writeStat := func(pid, stat string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, pid, "stat"), []byte(stat), 0o644))
+	}
+	writeStat("100", "100 (ttyd) S 1 100 100 0")
+	writeStat("101", "101 (bash) S 100 101 101 0")
+	writeStat("102", "102 (my (odd) prog) R 100 101 101 0")
+	writeStat("103", "103 (htop) S 101 101 101 0")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "self"), 0o755))
+
+	pids, err := procChildPIDs(root, 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{101, 102}, pids)
+
+	pids, err = procChildPIDs(root, 103)
+	require.NoError(t, err)
+	assert.Empty(t, pids)
+}
+
+func TestTTydServer_SignalCommand(t *testing.T) {
+	// sh stands in for ttyd, with a sleep as the command below it
+	parent := exec.Command("sh", "-c", "sleep 30 & wait $!")
+	require.NoError(t, parent.Start())
+	t.Cleanup(func() { _ = parent.Process.Kill() })
+	s := &TTydServer{cmd: parent}
+
+	require.Eventually(t, func() bool {
+		pids, err := descendants(parent.Process.Pid)
+		return err == nil && len(pids) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.SignalCommand("TERM"))
+
+	// The shell's wait returns once the sleep is terminated
+	done := make(chan struct{})
+	go func() {
+		_ = parent.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the command did not exit after SIGTERM")
+	}
+	status := parent.ProcessState.Sys().(syscall.WaitStatus)
+	assert.False(t, status.Signaled(), "ttyd itself is not signaled")
+	assert.Equal(t, 128+int(syscall.SIGTERM), status.ExitStatus())
+}
+
+func TestTTydServer_SignalCommand_Errors(t *testing.T) {
+	s := &TTydServer{}
+	assert.EqualError(t, s.SignalCommand("INT"), "ttyd is not running")
+	assert.EqualError(t, s.SignalCommand("BOGUS"), `unknown signal "BOGUS"`)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package capture
+
+import "errors"
+
+// SignalCommand reports that signals cannot be delivered: Windows has no
+// POSIX signals.
+func (s *TTydServer) SignalCommand(string) error {
+	return errors.New("signals are not supported on Windows")
+}
@@ -0,0 +1,97 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// sshOptions are passed to every ssh run: nobody is there to answer a
+// password or host key prompt, and a port forward that cannot be set up
+// must fail the connection rather than leave the browser nothing to open.
+var sshOptions = []string{
+	"-o", "BatchMode=yes",
+	"-o", "ExitOnForwardFailure=yes",
+	"-o", "ConnectTimeout=10",
+}
+
+// sshArgs returns the ssh arguments that run remote, a command line for the
+// remote shell, on host. With a port, it is forwarded from the local
+// loopback interface to the same port on the remote one, and the command
+// gets a terminal, so that it is hung up on when the connection closes.
+func sshArgs(host string, port int, remote string) []string {
+	args := append([]string(nil), sshOptions...)
+	if port > 0 {
+		p := strconv.Itoa(port)
+		args = append(args, "-tt", "-L", "127.0.0.1:"+p+":127.0.0.1:"+p)
+	}
+	return append(args, host, "--", remote)
+}
+
+// remoteCommand quotes argv, preceded by the env entries, into a command
+// line for the remote shell.
+func remoteCommand(env, argv []string) string {
+	words := make([]string, 0, len(env)+len(argv)+2)
+	words = append(words, "exec")
+	if len(env) > 0 {
+		words = append(words, "env")
+	}
+	for _, word := range append(append([]string(nil), env...), argv...) {
+		words = append(words, config.ShellQuote(word))
+	}
+	return strings.Join(words, " ")
+}
+
+// sshError explains why running ttyd on host over ssh failed, from the
+// exit code of ssh and the tail of its output: the login was refused, the
+// port forward could not be set up, or the remote host has no ttyd. It
+// returns nil when the output names none of them.
+func sshError(host string, port, code int, output string) error {
+	output = strings.TrimSpace(strings.ReplaceAll(output, "\r", ""))
+	switch {
+	case strings.Contains(output, "forwarding failed") || strings.Contains(output, "cannot listen to port") ||
+		strings.Contains(output, "Could not request local forwarding"):
+		return fmt.Errorf("forward port %d to %s over ssh: %s", port, host, lastLine(output))
+	case code == 255 && (strings.Contains(output, "Permission denied") ||
+		strings.Contains(output, "Host key verification failed") ||
+		strings.Contains(output, "Too many authentication failures")):
+		return fmt.Errorf("ssh authentication to %s failed: %s; check that `ssh %s` logs in without a prompt", host, lastLine(output), host)
+	case code == 127 || strings.Contains(output, "ttyd: command not found") || strings.Contains(output, "ttyd: not found"):
+		return missingDependency("ttyd not found on %s; install it there and make sure it is in PATH for non-interactive ssh sessions", host)
+	case code == 255:
+		return fmt.Errorf("ssh to %s failed: %s", host, lastLine(output))
+	}
+	return nil
+}
+
+// exitCode returns the exit code in err from running a command, or -1.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// remoteTTydVersion logs in to host and returns the version of its ttyd.
+// It is also the first connection of a run, so a refused login or a missing
+// ttyd is reported before anything starts.
+func remoteTTydVersion(ctx context.Context, host string) (string, error) {
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return "", missingDependency("ssh binary not found; install an OpenSSH client to capture over ssh")
+	}
+	out, err := exec.CommandContext(ctx, sshPath, sshArgs(host, 0, remoteCommand(nil, []string{"ttyd", "--version"}))...).CombinedOutput()
+	if err != nil {
+		if sshErr := sshError(host, 0, exitCode(err), string(out)); sshErr != nil {
+			return "", sshErr
+		}
+		return "", fmt.Errorf("run ttyd --version on %s: %w: %s", host, err, strings.TrimSpace(string(out)))
+	}
+	return parseTTydVersion(string(out)), nil
+}
@@ -0,0 +1,223 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRemote is the body of a stub ssh that runs the remote command, its
+// last argument, on this machine.
+const runRemote = `for last; do :; done; eval "$last"`
+
+// stubSSH writes an ssh script running body into dir, which must be on
+// PATH. Each run appends its arguments, one per line, to the returned log.
+func stubSSH(t *testing.T, dir, body string) string {
+	t.Helper()
+	log := filepath.Join(t.TempDir(), "ssh.log")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" >> '" + log + "'\n" + body + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o755))
+	return log
+}
+
+func TestSSHArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		port int
+		want []string
+	}{
+		{
+			name: "without a forward",
+			want: []string{"-o", "BatchMode=yes", "-o", "ExitOnForwardFailure=yes", "-o", "ConnectTimeout=10", "me@devbox", "--", "exec ttyd"},
+		},
+		{
+			name: "with a forward",
+			port: 7681,
+			want: []string{
+				"-o", "BatchMode=yes", "-o", "ExitOnForwardFailure=yes", "-o", "ConnectTimeout=10",
+				"-tt", "-L", "127.0.0.1:7681:127.0.0.1:7681", "me@devbox", "--", "exec ttyd",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sshArgs("me@devbox", tt.port, "exec ttyd"))
+		})
+	}
+}
+
+func TestRemoteCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []string
+		argv []string
+		want string
+	}{
+		{name: "argv", argv: []string{"ttyd", "--version"}, want: `exec ttyd --version`},
+		{name: "env", env: []string{"TERM=xterm", "PS1=> "}, argv: []string{"ttyd"}, want: `exec env TERM=xterm 'PS1=> ' ttyd`},
+		{name: "quotes", argv: []string{"bash", "-c", `echo 'it''s' "$HOME"`}, want: `exec bash -c 'echo '\''it'\'''\''s'\'' "$HOME"'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remoteCommand(tt.env, tt.argv)
+			assert.Equal(t, tt.want, got)
+
+			// The remote shell reads back the words that were quoted
+			out, err := exec.Command("sh", "-c", strings.Replace(got, "exec", `printf '%s\n'`, 1)).Output()
+			require.NoError(t, err)
+			assert.Equal(t, strings.Join(slices.Concat(tt.env, tt.argv), "\n")+"\n", strings.Replace(string(out), "env\n", "", 1))
+		})
+	}
+}
+
+func TestSSHError(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		output string
+		want   string
+	}{
+		{
+			name:   "login refused",
+			code:   255,
+			output: "me@devbox: Permission denied (publickey,password).\r\n",
+			want:   "ssh authentication to me@devbox failed: me@devbox: Permission denied (publickey,password).; check that `ssh me@devbox` logs in without a prompt",
+		},
+		{
+			name:   "unknown host key",
+			code:   255,
+			output: "No ED25519 host key is known for devbox and you have requested strict checking.\nHost key verification failed.\n",
+			want:   "ssh authentication to me@devbox failed: Host key verification failed.",
+		},
+		{
+			name:   "local port taken",
+			code:   255,
+			output: "bind [127.0.0.1]:7681: Address already in use\nchannel_setup_fwd_listener_tcpip: cannot listen to port: 7681\nCould not request local forwarding.\n",
+			want:   "forward port 7681 to me@devbox over ssh: Could not request local forwarding.",
+		},
+		{
+			name:   "remote forward refused",
+			code:   255,
+			output: "Error: local port forwarding failed for listen port 7681\n",
+			want:   "forward port 7681 to me@devbox over ssh: Error: local port forwarding failed for listen port 7681",
+		},
+		{name: "no remote ttyd", code: 127, output: "bash: line 1: exec: ttyd: not found\n", want: "ttyd not found on me@devbox"},
+		{name: "no remote ttyd over a terminal", code: 1, output: "sh: 1: exec: ttyd: not found\r\n", want: "ttyd not found on me@devbox"},
+		{
+			name:   "unreachable",
+			code:   255,
+			output: "ssh: connect to host devbox port 22: Connection refused\n",
+			want:   "ssh to me@devbox failed: ssh: connect to host devbox port 22: Connection refused",
+		},
+		{name: "ttyd failed", code: 1, output: "ttyd: unknown option\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sshError("me@devbox", 7681, tt.code, tt.output)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+			assert.Equal(t, strings.HasPrefix(tt.want, "ttyd not found"), errors.Is(err, ErrMissingDependency))
+		})
+	}
+}
+
+func TestRemoteTTydVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ssh     string
+		want    string
+		wantErr string
+	}{
+		{name: "version", ssh: runRemote, want: "1.7.7"},
+		{
+			name:    "login refused",
+			ssh:     `echo 'me@devbox: Permission denied (publickey).' >&2; exit 255`,
+			wantErr: "ssh authentication to me@devbox failed",
+		},
+		{name: "no remote ttyd", ssh: `PATH=/nonexistent; ` + runRemote, wantErr: "ttyd not found on me@devbox"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttyd := stubTTyd(t, helpWithWritable, "1.7.7")
+			log := stubSSH(t, filepath.Dir(ttyd), tt.ssh)
+
+			got, err := remoteTTydVersion(context.Background(), "me@devbox")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			args, err := os.ReadFile(log)
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "BatchMode=yes\n", "ssh never prompts")
+			assert.Contains(t, string(args), "me@devbox\n--\nexec ttyd --version\n")
+		})
+	}
+}
+
+func TestTTydServer_Start_SSH(t *testing.T) {
+	tests := []struct {
+		name    string
+		ssh     string
+		wantErr string
+	}{
+		{
+			name:    "forward fails",
+			ssh:     `case "$*" in *" -L "*) printf 'bind [127.0.0.1]:7681: Address already in use\r\nCould not request local forwarding.\r\n'; exit 255 ;; esac; ` + runRemote,
+			wantErr: "forward port",
+		},
+		{
+			name:    "login refused",
+			ssh:     `echo 'me@devbox: Permission denied (publickey).' >&2; exit 255`,
+			wantErr: "ssh authentication to me@devbox failed",
+		},
+		{name: "ttyd exits", ssh: `PATH="$PATH:/usr/bin:/bin"; ` + runRemote, wantErr: "ttyd exited before it was ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttyd := stubTTyd(t, helpWithWritable, "1.7.7")
+			log := stubSSH(t, filepath.Dir(ttyd), tt.ssh)
+			port, err := getFreePort()
+			require.NoError(t, err)
+			server := NewTTydServer("bash", port)
+			server.SSH = "me@devbox"
+			server.Env = []string{"GREETING=it's me"}
+
+			err = server.Start(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			if tt.name != "ttyd exits" {
+				return
+			}
+			// ttyd ran on the remote side with its environment, and the
+			// port forwarded to it
+			args, err := os.ReadFile(log)
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "-tt\n-L\n127.0.0.1:"+strconv.Itoa(port)+":127.0.0.1:"+strconv.Itoa(port)+"\nme@devbox\n--\n")
+			assert.Contains(t, string(args), `'GREETING=it'\''s me' ttyd -p `+strconv.Itoa(port)+" ")
+			assert.Contains(t, string(args), " --writable ")
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// outputDir returns the directory frames are written to during the run:
+// the staging directory with OutTmp, or the configured OutputDir.
+func (c *Capturer) outputDir() string {
+	if c.stageDir != "" {
+		return c.stageDir
+	}
+	return c.config.OutputDir
+}
+
+// finalPath maps a path written during the run to where it ends up once
+// staged files are moved into OutputDir.
+func (c *Capturer) finalPath(path string) string {
+	if c.stageDir == "" {
+		return path
+	}
+	rel, err := filepath.Rel(c.stageDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.Join(c.config.OutputDir, rel)
+}
+
+// outputLockFilename marks OutputDir as being written by a run, so a second
+// run into the same directory fails instead of interleaving frames.
+const outputLockFilename = ".scr.lock"
+
+// outputLockPath returns where the lock file for OutputDir goes: inside it,
+// or with OutTmp beside it, so the directory still does not appear before
+// the frames are moved in.
+func (c *Capturer) outputLockPath() string {
+	dir := filepath.Clean(c.config.OutputDir)
+	if c.config.OutTmp {
+		return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+outputLockFilename)
+	}
+	return filepath.Join(dir, outputLockFilename)
+}
+
+// lockOutput locks OutputDir for this run, creating the directory the lock
+// file goes in, unless NoLock is set. The returned function removes the
+// lock.
+func (c *Capturer) lockOutput() (release func(), err error) {
+	if c.config.NoLock {
+		return func() {}, nil
+	}
+	path := c.outputLockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("output directory: %w", err)
+	}
+	release, err = acquireLock(path, c.runID, c.now())
+	var held *lockHeldError
+	if errors.As(err, &held) {
+		return nil, fmt.Errorf("output directory %s is in use by another scr run (%s); use a different --out, or --no-lock to write anyway", c.config.OutputDir, held.holder())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock output directory: %w", err)
+	}
+	return release, nil
+}
+
+// prepareOutput creates the directory frames are written to. With OutTmp
+// that is a fresh temporary directory, so the captured command never sees
+// the frames while it runs; otherwise it is OutputDir itself.
+func (c *Capturer) prepareOutput() error {
+	c.stageDir = ""
+	if !c.config.OutTmp {
+		if err := os.MkdirAll(c.config.OutputDir, 0o755); err != nil {
+			return fmt.Errorf("output directory: %w", err)
+		}
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "scr-")
+	if err != nil {
+		return fmt.Errorf("output directory: create staging directory: %w", err)
+	}
+	c.stageDir = dir
+	return nil
+}
+
+// publishOutput moves staged files into OutputDir, replacing files with the
+// same name, and removes the staging directory. Scene directories are merged
+// into existing ones. It is a no-op without OutTmp.
+func (c *Capturer) publishOutput() error {
+	if c.stageDir == "" {
+		return nil
+	}
+	defer os.RemoveAll(c.stageDir)
+
+	if err := os.MkdirAll(c.config.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("output directory: %w", err)
+	}
+	return moveTree(c.stageDir, c.config.OutputDir, "")
+}
+
+// moveTree moves the entries of the directory src/rel into dst/rel,
+// descending into directories so files already in dst are kept.
+func moveTree(src, dst, rel string) error {
+	entries, err := os.ReadDir(filepath.Join(src, rel))
+	if err != nil {
+		return fmt.Errorf("read staging directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := filepath.Join(rel, entry.Name())
+		if entry.IsDir() {
+			if err := os.MkdirAll(filepath.Join(dst, name), 0o755); err != nil {
+				return fmt.Errorf("move %s into output directory: %w", name, err)
+			}
+			if err := moveTree(src, dst, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := moveFile(filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+			return fmt.Errorf("move %s into output directory: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// moveFile renames src to dst, copying instead when they are on different
+// file systems.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,117 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestCapturer_OutTmp(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "shots")
+	c := newFakeCapturer(t, &config.Config{
+		OutputDir: outputDir,
+		OutTmp:    true,
+		Actions:   []script.Action{{Kind: script.ActionScreenshot, Name: "middle"}},
+	})
+
+	// The output directory must not appear while the session runs
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		_, err := os.Stat(outputDir)
+		assert.True(t, os.IsNotExist(err), "output directory exists during the run")
+		return []byte("png"), nil
+	}
+
+	require.NoError(t, c.prepareOutput())
+	stageDir := c.stageDir
+	require.NotEmpty(t, stageDir)
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	require.NoError(t, c.publishOutput())
+
+	for _, name := range []string{"screenshot_001.png", "middle.png", "screenshot_002.png"} {
+		_, err := os.Stat(filepath.Join(outputDir, name))
+		assert.NoError(t, err, name)
+	}
+	_, err := os.Stat(stageDir)
+	assert.True(t, os.IsNotExist(err), "staging directory should be removed")
+}
+
+func TestCapturer_OutTmp_ReplacesExistingFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "screenshot_001.png"), []byte("old"), 0o644))
+
+	c := newFakeCapturer(t, &config.Config{OutputDir: outputDir, OutTmp: true})
+	require.NoError(t, c.prepareOutput())
+	require.NoError(t, os.WriteFile(filepath.Join(c.outputDir(), "screenshot_001.png"), []byte("new"), 0o644))
+	require.NoError(t, c.publishOutput())
+
+	got, err := os.ReadFile(filepath.Join(outputDir, "screenshot_001.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(got))
+}
+
+func TestCapturer_finalPath(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{OutputDir: "/out"})
+	assert.Equal(t, "/tmp/scr-1/animation.gif", c.finalPath("/tmp/scr-1/animation.gif"))
+
+	c.stageDir = "/tmp/scr-1"
+	assert.Equal(t, "/out/animation.gif", c.finalPath("/tmp/scr-1/animation.gif"))
+	assert.Equal(t, "/elsewhere/x.gif", c.finalPath("/elsewhere/x.gif"))
+}
+
+func TestCapturer_lockOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		outTmp   bool
+		wantLock string
+	}{
+		{name: "inside the output directory", wantLock: filepath.Join("shots", outputLockFilename)},
+		{name: "beside it with out-tmp", outTmp: true, wantLock: ".shots" + outputLockFilename},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			outputDir := filepath.Join(root, "shots")
+			cfg := &config.Config{OutputDir: outputDir, OutTmp: tt.outTmp}
+			c := newFakeCapturer(t, cfg)
+
+			release, err := c.lockOutput()
+			require.NoError(t, err)
+			lock := filepath.Join(root, tt.wantLock)
+			assert.FileExists(t, lock)
+			if tt.outTmp {
+				assert.NoDirExists(t, outputDir)
+			}
+
+			// A second run into the same directory fails fast
+			other := newFakeCapturer(t, cfg)
+			pid := liveProcess(t)
+			require.NoError(t, os.WriteFile(lock, []byte(strconv.Itoa(pid)+"\n2025-01-02T10:00:00Z\n"), 0o600))
+			_, err = other.lockOutput()
+			assert.EqualError(t, err, "output directory "+outputDir+" is in use by another scr run (pid "+strconv.Itoa(pid)+", started 2025-01-02 10:00:00); use a different --out, or --no-lock to write anyway")
+
+			require.NoError(t, os.Remove(lock))
+			release()
+		})
+	}
+}
+
+func TestCapturer_lockOutput_NoLock(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "shots")
+	c := newFakeCapturer(t, &config.Config{OutputDir: outputDir, NoLock: true})
+
+	release, err := c.lockOutput()
+	require.NoError(t, err)
+	release()
+	assert.NoDirExists(t, outputDir)
+}
@@ -0,0 +1,210 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// finalFrameDelay is the pause between the last action and the final frame.
+const finalFrameDelay = 100 * time.Millisecond
+
+// PlannedFrame is a screenshot a run is expected to take.
+type PlannedFrame struct {
+	// Name is the file the frame will be written to, within the output
+	// directory; frames of a Scene are in its directory, as in
+	// "intro/screenshot_001.png".
+	Name string
+	// Offset is the expected capture time relative to the terminal becoming ready.
+	Offset time.Duration
+	// Trigger says why the frame is taken: "initial", "interval", "type",
+	// "screenshot", "burst" or "final".
+	Trigger string
+	// Label is the name given by a Screenshot action, if any.
+	Label string
+	// Actions are the actions started since the previous frame.
+	Actions []script.Action
+	// AfterWait is set when a Wait action precedes the frame. Offsets assume
+	// every Wait matches immediately, so the real frame may come later.
+	AfterWait bool
+}
+
+// plannedEvent is a frame in the schedule before names are assigned. after is
+// the number of actions started before the frame.
+type plannedEvent struct {
+	at      time.Duration
+	after   int
+	trigger string
+	label   string
+}
+
+// Storyboard returns the frames a run of cfg is expected to take, derived
+// from cfg.Actions and cfg.ScreenshotInterval without starting ttyd or
+// Chrome. Key round-trips and Wait actions take no time in the plan, and
+// no frames are planned between a Hide and the next Show.
+func Storyboard(cfg *config.Config) ([]PlannedFrame, error) {
+	names, err := config.ParseNameTemplate(cfg.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("name: %w", err)
+	}
+	names = names.ForFormat(cfg.ImageFormat())
+	prefix := cfg.NamePrefix()
+	if err := validateScreenshotNames(cfg.Actions, cfg.ImageFormat(), names.Pattern(prefix)); err != nil {
+		return nil, err
+	}
+
+	// A script of only Set actions applies them before the initial frame
+	initialAfter := 0
+	if script.SettingsOnly(cfg.Actions) {
+		initialAfter = len(cfg.Actions)
+	}
+	events := []plannedEvent{{at: 0, after: initialAfter, trigger: "initial"}}
+
+	// Lay out actions back to back and note the windows during which
+	// interval frames are paused.
+	type window struct{ from, to time.Duration }
+	var paused []window
+	var elapsed time.Duration
+	hiddenFrom := time.Duration(-1)
+	starts := make([]time.Duration, len(cfg.Actions))
+	for i, action := range cfg.Actions {
+		starts[i] = elapsed
+		elapsed += script.ActionDuration(action)
+
+		switch {
+		case action.Kind == script.ActionHide:
+			if hiddenFrom < 0 {
+				hiddenFrom = starts[i]
+			}
+		case action.Kind == script.ActionShow:
+			if hiddenFrom >= 0 {
+				paused = append(paused, window{hiddenFrom, starts[i]})
+				hiddenFrom = -1
+			}
+		case hiddenFrom >= 0:
+		case action.Kind == script.ActionScreenshot:
+			events = append(events, plannedEvent{at: starts[i], after: i + 1, trigger: "screenshot", label: action.Name})
+		case action.Kind == script.ActionType && cfg.NoCaptureWhileTyping:
+			paused = append(paused, window{starts[i], elapsed})
+			events = append(events, plannedEvent{at: elapsed, after: i + 1, trigger: "type"})
+		case action.Kind == script.ActionBurst:
+			paused = append(paused, window{starts[i], elapsed})
+			for n := range action.Repeat {
+				events = append(events, plannedEvent{at: starts[i] + time.Duration(n)*action.Duration, after: i + 1, trigger: "burst"})
+			}
+		}
+	}
+
+	if interval := cfg.ScreenshotInterval; interval > 0 && cfg.Video == "" {
+	ticks:
+		for at := interval; at < elapsed; at += interval {
+			for _, w := range paused {
+				if at >= w.from && at < w.to {
+					continue ticks
+				}
+			}
+			after := sort.Search(len(starts), func(i int) bool { return starts[i] >= at })
+			events = append(events, plannedEvent{at: at, after: after, trigger: "interval"})
+		}
+	}
+
+	if hiddenFrom < 0 {
+		events = append(events, plannedEvent{at: elapsed + finalFrameDelay, after: len(cfg.Actions), trigger: "final"})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].after < events[j].after
+	})
+
+	frames := make([]PlannedFrame, 0, len(events))
+	seq, prev, dir := 0, 0, ""
+	for _, ev := range events {
+		frame := PlannedFrame{Offset: ev.at, Trigger: ev.trigger, Label: ev.label}
+		if ev.after > prev {
+			frame.Actions = cfg.Actions[prev:ev.after]
+			prev = ev.after
+		}
+		// A Scene since the previous frame starts a new directory and
+		// numbering
+		for _, action := range frame.Actions {
+			if action.Kind == script.ActionScene {
+				dir, _ = sceneDirName(action.Name)
+				seq = 0
+			}
+		}
+		if ev.label != "" {
+			name, err := screenshotName(ev.label, cfg.ImageFormat())
+			if err != nil {
+				return nil, err
+			}
+			frame.Name = path.Join(dir, name)
+		} else {
+			seq++
+			frame.Name = path.Join(dir, names.Format(config.NameFields{Prefix: prefix, N: seq, Time: ev.at, Action: ev.after}))
+		}
+		for _, action := range cfg.Actions[:ev.after] {
+			if action.Kind == script.ActionWait {
+				frame.AfterWait = true
+				break
+			}
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// WriteStoryboard renders frames as a Markdown table, one row per frame with
+// its expected time and the actions that led up to it.
+func WriteStoryboard(w io.Writer, frames []PlannedFrame) error {
+	var sb strings.Builder
+	sb.WriteString("| # | Frame | Time | Trigger | Actions |\n")
+	sb.WriteString("| - | ----- | ---- | ------- | ------- |\n")
+
+	approximate := false
+	for i, frame := range frames {
+		at := frame.Offset.Round(time.Millisecond).String()
+		if frame.AfterWait {
+			at = "≥ " + at
+			approximate = true
+		}
+
+		trigger := frame.Trigger
+		if frame.Label != "" {
+			trigger += fmt.Sprintf(" %q", frame.Label)
+		}
+
+		actions := make([]string, len(frame.Actions))
+		for j, action := range frame.Actions {
+			actions[j] = "`" + action.String() + "`"
+		}
+		cell := strings.Join(actions, ", ")
+		if cell == "" {
+			cell = "—"
+		}
+
+		fmt.Fprintf(&sb, "| %d | %s | %s | %s | %s |\n",
+			i+1, frame.Name, at, escapeCell(trigger), escapeCell(cell))
+	}
+
+	if approximate {
+		sb.WriteString("\nTimes marked ≥ follow a Wait and assume it matches immediately.\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// escapeCell escapes pipes so text stays inside a Markdown table cell.
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
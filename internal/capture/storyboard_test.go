@@ -0,0 +1,223 @@
+package capture
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// frameSummary is the part of a PlannedFrame most tests care about.
+type frameSummary struct {
+	Name    string
+	Offset  time.Duration
+	Trigger string
+	Actions int
+}
+
+func summarize(frames []PlannedFrame) []frameSummary {
+	out := make([]frameSummary, len(frames))
+	for i, f := range frames {
+		out[i] = frameSummary{f.Name, f.Offset, f.Trigger, len(f.Actions)}
+	}
+	return out
+}
+
+func TestStoryboard(t *testing.T) {
+	typeHi := script.Action{Kind: script.ActionType, Text: "hi", Speed: 100 * time.Millisecond}
+	sleep := script.Action{Kind: script.ActionSleep, Duration: 300 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want []frameSummary
+	}{
+		{
+			name: "no actions",
+			cfg:  &config.Config{ScreenshotInterval: 500 * time.Millisecond},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 100 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "settings-only script applies before the initial frame",
+			cfg: &config.Config{
+				ScreenshotInterval: 500 * time.Millisecond,
+				Actions: []script.Action{
+					{Kind: script.ActionSet, Setting: "theme", Value: "dracula"},
+					{Kind: script.ActionSet, Setting: "width", Value: "800"},
+				},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 2},
+				{"screenshot_002.png", 100 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "interval frames between actions",
+			cfg: &config.Config{
+				ScreenshotInterval: 250 * time.Millisecond,
+				Actions:            []script.Action{typeHi, sleep},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 250 * time.Millisecond, "interval", 2},
+				{"screenshot_003.png", 600 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "screenshot actions with interval disabled",
+			cfg: &config.Config{
+				Actions: []script.Action{
+					typeHi,
+					{Kind: script.ActionScreenshot, Name: "typed"},
+					sleep,
+					{Kind: script.ActionScreenshot},
+				},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"typed.png", 200 * time.Millisecond, "screenshot", 2},
+				{"screenshot_002.png", 500 * time.Millisecond, "screenshot", 2},
+				{"screenshot_003.png", 600 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "no capture while typing",
+			cfg: &config.Config{
+				ScreenshotInterval:   50 * time.Millisecond,
+				NoCaptureWhileTyping: true,
+				Actions:              []script.Action{typeHi},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 200 * time.Millisecond, "type", 1},
+				{"screenshot_003.png", 300 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "burst pauses interval frames",
+			cfg: &config.Config{
+				ScreenshotInterval: 100 * time.Millisecond,
+				Actions: []script.Action{
+					typeHi,
+					{Kind: script.ActionBurst, Repeat: 3, Duration: 40 * time.Millisecond},
+				},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 100 * time.Millisecond, "interval", 1},
+				{"screenshot_003.png", 200 * time.Millisecond, "burst", 1},
+				{"screenshot_004.png", 240 * time.Millisecond, "burst", 0},
+				{"screenshot_005.png", 280 * time.Millisecond, "burst", 0},
+				{"screenshot_006.png", 380 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "hide suppresses frames until show",
+			cfg: &config.Config{
+				ScreenshotInterval: 100 * time.Millisecond,
+				Actions: []script.Action{
+					{Kind: script.ActionHide},
+					typeHi,
+					{Kind: script.ActionScreenshot},
+					{Kind: script.ActionShow},
+					{Kind: script.ActionScreenshot, Name: "ready"},
+					sleep,
+				},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 200 * time.Millisecond, "interval", 2},
+				{"ready.png", 200 * time.Millisecond, "screenshot", 3},
+				{"screenshot_003.png", 300 * time.Millisecond, "interval", 1},
+				{"screenshot_004.png", 400 * time.Millisecond, "interval", 0},
+				{"screenshot_005.png", 600 * time.Millisecond, "final", 0},
+			},
+		},
+		{
+			name: "scenes restart numbering in their directories",
+			cfg: &config.Config{
+				Actions: []script.Action{
+					{Kind: script.ActionScreenshot},
+					{Kind: script.ActionScene, Name: "intro"},
+					typeHi,
+					{Kind: script.ActionScreenshot, Name: "typed"},
+					{Kind: script.ActionScreenshot},
+					{Kind: script.ActionScene, Name: "step 2"},
+					sleep,
+				},
+			},
+			want: []frameSummary{
+				{"screenshot_001.png", 0, "initial", 0},
+				{"screenshot_002.png", 0, "screenshot", 1},
+				{"intro/typed.png", 200 * time.Millisecond, "screenshot", 3},
+				{"intro/screenshot_001.png", 200 * time.Millisecond, "screenshot", 1},
+				{"step_2/screenshot_001.png", 600 * time.Millisecond, "final", 2},
+			},
+		},
+		{
+			name: "name template",
+			cfg: &config.Config{
+				Command:      "vim",
+				NameTemplate: "{prefix}_{n:02}_{action}_{time}",
+				Actions:      []script.Action{typeHi, {Kind: script.ActionScreenshot}},
+			},
+			want: []frameSummary{
+				{"vim_01_0_0.png", 0, "initial", 0},
+				{"vim_02_2_200.png", 200 * time.Millisecond, "screenshot", 2},
+				{"vim_03_2_300.png", 300 * time.Millisecond, "final", 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := Storyboard(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, summarize(frames))
+		})
+	}
+}
+
+func TestStoryboard_InvalidScreenshotName(t *testing.T) {
+	_, err := Storyboard(&config.Config{
+		Actions: []script.Action{{Kind: script.ActionScreenshot, Name: "screenshot_001"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestStoryboard_InvalidNameTemplate(t *testing.T) {
+	_, err := Storyboard(&config.Config{NameTemplate: "{date}_{n}"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "name: unknown placeholder {date}")
+}
+
+func TestWriteStoryboard(t *testing.T) {
+	frames, err := Storyboard(&config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionType, Text: "make | tee log", Speed: script.DefaultTypeSpeed},
+			{Kind: script.ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: script.ActionWait, Pattern: "done", Timeout: 10 * time.Second},
+			{Kind: script.ActionScreenshot, Name: "built"},
+		},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteStoryboard(&buf, frames))
+
+	want := "| # | Frame | Time | Trigger | Actions |\n" +
+		"| - | ----- | ---- | ------- | ------- |\n" +
+		"| 1 | screenshot_001.png | 0s | initial | — |\n" +
+		"| 2 | built.png | ≥ 700ms | screenshot \"built\" | `Type 'make \\| tee log'`, `Enter`, `Wait /done/ 10s`, `Screenshot 'built'` |\n" +
+		"| 3 | screenshot_002.png | ≥ 800ms | final | — |\n" +
+		"\nTimes marked ≥ follow a Wait and assume it matches immediately.\n"
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
+)
+
+// applyThemeJS sets the xterm.js theme on window.term and paints the page
+// and terminal container in the theme background, so padding matches.
+// It evaluates to false when the page has no window.term.
+const applyThemeJS = `((theme) => {
+	if (!window.term) return false;
+	window.term.options.theme = theme;
+	document.body.style.background = theme.background;
+	const container = document.getElementById("terminal-container");
+	if (container) container.style.background = theme.background;
+	return true;
+})(%s)`
+
+// applyTerminalTheme switches the terminal in the page to t.
+func applyTerminalTheme(ctx context.Context, t theme.Theme) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("encode theme: %w", err)
+	}
+	var ok bool
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(applyThemeJS, data), &ok)); err != nil {
+		return err
+	}
+	if !ok {
+		return errNoTerm
+	}
+	return nil
+}
+
+// validateThemes resolves the configured theme and every Set Theme value,
+// so a typo fails before ttyd and Chrome start.
+func validateThemes(cfg *config.Config) error {
+	if cfg.Theme != "" {
+		if _, err := theme.Resolve(cfg.Theme); err != nil {
+			return fmt.Errorf("theme: %w", err)
+		}
+	}
+	for i, action := range cfg.Actions {
+		if action.Kind == script.ActionSet && action.Setting == "theme" {
+			if _, err := theme.Resolve(action.Value); err != nil {
+				return fmt.Errorf("set action %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setTheme resolves ref and applies it to the terminal.
+func (c *Capturer) setTheme(browserCtx context.Context, ref string) error {
+	t, err := theme.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Applying theme %s\n", t.Name)
+	}
+	if err := c.applyTheme(browserCtx, t); err != nil {
+		return fmt.Errorf("apply theme %s: %w", t.Name, err)
+	}
+	return nil
+}
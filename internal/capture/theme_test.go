@@ -0,0 +1,70 @@
+package capture
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
+)
+
+func TestCapturer_runSession_Themes(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Theme: "nord",
+		Actions: []script.Action{
+			{Kind: script.ActionType, Text: "a"},
+			{Kind: script.ActionSet, Setting: "theme", Value: "dracula"},
+		},
+	})
+
+	// Record the theme in effect when each frame is captured
+	var events []string
+	c.applyTheme = func(_ context.Context, th theme.Theme) error {
+		events = append(events, "theme "+th.Name)
+		return nil
+	}
+	c.captureFrame = func(context.Context) ([]byte, error) {
+		events = append(events, "frame")
+		return []byte("png"), nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+	assert.Equal(t, []string{"theme nord", "frame", "theme dracula", "frame"}, events)
+}
+
+func TestValidateThemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr string
+	}{
+		{name: "no themes", cfg: &config.Config{}},
+		{name: "built-in theme", cfg: &config.Config{Theme: "gruvbox"}},
+		{name: "unknown theme", cfg: &config.Config{Theme: "monokai"}, wantErr: `theme: unknown theme "monokai"`},
+		{
+			name: "unknown Set Theme value",
+			cfg: &config.Config{Actions: []script.Action{
+				{Kind: script.ActionSet, Setting: "theme", Value: "nord"},
+				{Kind: script.ActionSet, Setting: "theme", Value: "missing.json"},
+			}},
+			wantErr: "set action 1: read theme file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateThemes(tt.cfg)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
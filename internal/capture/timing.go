@@ -0,0 +1,216 @@
+package capture
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase is a named startup step and how long it took.
+type Phase struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// FrameKind says why a frame was captured.
+type FrameKind string
+
+const (
+	// FrameInitial is the frame taken once the terminal is ready.
+	FrameInitial FrameKind = "initial"
+	// FrameInterval is a periodic frame, or with NoCaptureWhileTyping the
+	// frame taken after a Type action in place of periodic ones.
+	FrameInterval FrameKind = "interval"
+	// FrameFinal is the frame taken after the last action.
+	FrameFinal FrameKind = "final"
+	// FrameExplicit is a frame requested by a Screenshot action.
+	FrameExplicit FrameKind = "explicit"
+	// FrameBurst is one of the frames of a Burst action.
+	FrameBurst FrameKind = "burst"
+)
+
+// FrameStat records when a frame was captured.
+type FrameStat struct {
+	// Path is the file the frame was written to. For a Duplicate it is the
+	// earlier, identical frame that was written instead.
+	Path string
+	Kind FrameKind
+	// Scene is the directory of the Scene the frame was captured in,
+	// relative to the output directory, or "" before the first one.
+	Scene string
+	// Action is the index of the most recently started action when the
+	// frame was captured, or -1 before the first one.
+	Action int
+	// Duplicate is set for interval frames skipped by Dedup because they
+	// matched the previous frame.
+	Duplicate bool
+	// Burst numbers the Burst action a FrameBurst frame belongs to, from
+	// 1; it is 0 for other frames.
+	Burst int
+	// Change is the percentage of pixels that differ from the previous
+	// frame. It is only meaningful when Compared is set, which requires
+	// Verbose or FrameDiff and a previous frame to compare against.
+	Change   float64
+	Compared bool
+	// Time is the wall-clock capture time.
+	Time time.Time
+	// Offset is the capture time relative to the terminal becoming ready.
+	Offset time.Duration
+}
+
+// ActionStat records when an action ran.
+type ActionStat struct {
+	Index int
+	// Time is the wall-clock time the action started.
+	Time time.Time
+	// Offset is the start time relative to the terminal becoming ready.
+	Offset   time.Duration
+	Duration time.Duration
+}
+
+// Stats summarizes the timing of a run. Offsets are measured from Origin,
+// the moment the terminal became ready, so they do not include the
+// run-to-run variance of ttyd and Chrome startup. Startup time is reported
+// separately in Phases.
+type Stats struct {
+	Start   time.Time
+	Origin  time.Time
+	Startup time.Duration
+	Phases  []Phase
+	Frames  []FrameStat
+	Actions []ActionStat
+	// Total is the time from Start until the last recorded event.
+	Total time.Duration
+}
+
+// timeline collects timestamps during a run. It is safe for concurrent use.
+type timeline struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	origin  time.Time
+	end     time.Time
+	phases  []Phase
+	frames  []FrameStat
+	actions []ActionStat
+	action  int // the action in progress or last run; -1 before any
+}
+
+// newTimeline creates a timeline whose run starts now.
+func newTimeline(now func() time.Time) *timeline {
+	return &timeline{now: now, start: now(), action: -1}
+}
+
+// beginPhase starts timing a startup phase; call the returned func when it ends.
+func (t *timeline) beginPhase(name string) func() {
+	start := t.now()
+	return func() {
+		end := t.now()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.phases = append(t.phases, Phase{Name: name, Start: start, Duration: end.Sub(start)})
+		t.touch(end)
+	}
+}
+
+// markReady sets the origin (t=0) to now. Only the first call has effect.
+func (t *timeline) markReady() {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.origin.IsZero() {
+		t.origin = now
+		t.touch(now)
+	}
+}
+
+// ready reports whether the origin has been set.
+func (t *timeline) ready() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.origin.IsZero()
+}
+
+// offset converts a wall-clock time into an offset from the origin. Before
+// the origin is set, offsets are zero.
+func (t *timeline) offset(at time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offsetLocked(at)
+}
+
+func (t *timeline) offsetLocked(at time.Time) time.Duration {
+	if t.origin.IsZero() {
+		return 0
+	}
+	return at.Sub(t.origin)
+}
+
+// addFrame records a frame captured at f.Time, filling in its Offset and
+// Action.
+func (t *timeline) addFrame(f FrameStat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f.Offset = t.offsetLocked(f.Time)
+	f.Action = t.action
+	t.frames = append(t.frames, f)
+	t.touch(f.Time)
+}
+
+// beginAction marks the action with the given index as started, so frames
+// captured from now on are attributed to it.
+func (t *timeline) beginAction(index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.action = index
+}
+
+// currentAction returns the index of the most recently started action, or
+// -1 before the first one.
+func (t *timeline) currentAction() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.action
+}
+
+// addAction records an action that started at the given time and has ended now.
+func (t *timeline) addAction(index int, start time.Time) {
+	end := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.actions = append(t.actions, ActionStat{
+		Index:    index,
+		Time:     start,
+		Offset:   t.offsetLocked(start),
+		Duration: end.Sub(start),
+	})
+	t.touch(end)
+}
+
+// touch extends the end of the run to at. Callers hold t.mu.
+func (t *timeline) touch(at time.Time) {
+	if at.After(t.end) {
+		t.end = at
+	}
+}
+
+// stats returns a snapshot of the recorded timings.
+func (t *timeline) stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := Stats{
+		Start:   t.start,
+		Origin:  t.origin,
+		Phases:  append([]Phase(nil), t.phases...),
+		Frames:  append([]FrameStat(nil), t.frames...),
+		Actions: append([]ActionStat(nil), t.actions...),
+	}
+	if !t.origin.IsZero() {
+		s.Startup = t.origin.Sub(t.start)
+	}
+	if !t.end.IsZero() {
+		s.Total = t.end.Sub(t.start)
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package capture
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// fakeClock is a manually advanced clock for deterministic timings.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.t = f.t.Add(d)
+}
+
+func TestTimeline(t *testing.T) {
+	clock := newFakeClock()
+	tl := newTimeline(clock.Now)
+	start := clock.Now()
+
+	done := tl.beginPhase("ttyd")
+	clock.Advance(300 * time.Millisecond)
+	done()
+	done = tl.beginPhase("browser")
+	clock.Advance(700 * time.Millisecond)
+	done()
+
+	// Frames before the origin is set have a zero offset.
+	assert.Equal(t, time.Duration(0), tl.offset(clock.Now()))
+
+	tl.markReady()
+	origin := clock.Now()
+	tl.addFrame(FrameStat{Path: "a.png", Time: clock.Now()})
+
+	actionStart := clock.Now()
+	tl.beginAction(0)
+	clock.Advance(250 * time.Millisecond)
+	tl.addAction(0, actionStart)
+
+	clock.Advance(50 * time.Millisecond)
+	tl.markReady() // later calls do not move the origin
+	tl.addFrame(FrameStat{Path: "b.png", Time: clock.Now()})
+
+	got := tl.stats()
+	assert.Equal(t, start, got.Start)
+	assert.Equal(t, origin, got.Origin)
+	assert.Equal(t, time.Second, got.Startup)
+	assert.Equal(t, []Phase{
+		{Name: "ttyd", Start: start, Duration: 300 * time.Millisecond},
+		{Name: "browser", Start: start.Add(300 * time.Millisecond), Duration: 700 * time.Millisecond},
+	}, got.Phases)
+	assert.Equal(t, []FrameStat{
+		{Path: "a.png", Action: -1, Time: origin, Offset: 0},
+		{Path: "b.png", Action: 0, Time: origin.Add(300 * time.Millisecond), Offset: 300 * time.Millisecond},
+	}, got.Frames)
+	assert.Equal(t, []ActionStat{
+		{Index: 0, Time: origin, Offset: 0, Duration: 250 * time.Millisecond},
+	}, got.Actions)
+	assert.Equal(t, 1300*time.Millisecond, got.Total)
+}
+
+func TestCapturer_runSession_OffsetsFromReady(t *testing.T) {
+	clock := newFakeClock()
+	c := newFakeCapturer(t, &config.Config{
+		Actions: []script.Action{
+			{Kind: script.ActionKey, Key: "enter", Repeat: 1},
+			{Kind: script.ActionKey, Key: "tab", Repeat: 1},
+		},
+	})
+	c.now = clock.Now
+	c.timeline = newTimeline(clock.Now)
+
+	// Simulate slow startup before the terminal is ready.
+	done := c.timeline.beginPhase("ttyd")
+	clock.Advance(2 * time.Second)
+	done()
+
+	c.sendKey = func(context.Context, string) error {
+		clock.Advance(100 * time.Millisecond)
+		return nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	stats := c.Stats()
+	assert.Equal(t, 2*time.Second, stats.Startup)
+	require.Len(t, stats.Frames, 2)
+	assert.Equal(t, time.Duration(0), stats.Frames[0].Offset, "initial frame is t=0")
+	assert.Equal(t, 200*time.Millisecond, stats.Frames[1].Offset)
+	assert.Equal(t, stats.Origin.Add(200*time.Millisecond), stats.Frames[1].Time)
+	assert.Equal(t, FrameInitial, stats.Frames[0].Kind)
+	assert.Equal(t, -1, stats.Frames[0].Action, "initial frame precedes all actions")
+	assert.Equal(t, FrameFinal, stats.Frames[1].Kind)
+	assert.Equal(t, 1, stats.Frames[1].Action)
+	require.Len(t, stats.Actions, 2)
+	assert.Equal(t, time.Duration(0), stats.Actions[0].Offset)
+	assert.Equal(t, 100*time.Millisecond, stats.Actions[1].Offset)
+	assert.Equal(t, 100*time.Millisecond, stats.Actions[1].Duration)
+}
@@ -0,0 +1,30 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// tmuxPrefix is the key tmux waits for before a key binding, such as the
+// pane indexes bound by config.TmuxCommand.
+const tmuxPrefix = "ctrl+b"
+
+// pane selects the tmux pane action.Name by pressing the tmux prefix and
+// the pane's index, so the keys and text that follow go to it.
+func (p *Player) pane(ctx context.Context, action script.Action, index int) error {
+	pane := config.TmuxPaneIndex(p.panes, action.Name)
+	if pane < 0 {
+		return fmt.Errorf("pane action %d: pane %q is not in the tmux layout", index, action.Name)
+	}
+	p.logf("Selecting pane %q (action %d)\n", action.Name, index)
+	for _, key := range []string{tmuxPrefix, strconv.Itoa(pane)} {
+		if err := p.Driver.SendKey(ctx, key); err != nil {
+			return fmt.Errorf("select pane %q: %w", action.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestCapturer_PaneAction(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		TmuxPanes: []config.TmuxPane{{Name: "server"}, {Name: "client"}, {Name: "logs"}},
+		Actions: []script.Action{
+			{Kind: script.ActionPane, Name: "client"},
+			{Kind: script.ActionType, Text: "ls", Speed: 0},
+			{Kind: script.ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: script.ActionPane, Name: "server"},
+			{Kind: script.ActionCtrl, Key: "c"},
+		},
+	})
+	var keys []string
+	c.sendKey = func(_ context.Context, key string) error {
+		keys = append(keys, key)
+		return nil
+	}
+	c.insertText = func(_ context.Context, text string) error {
+		keys = append(keys, "text:"+text)
+		return nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	assert.Equal(t, []string{"ctrl+b", "1", "text:ls", "Enter", "ctrl+b", "0", "ctrl+c"}, keys)
+}
+
+func TestCapturer_PaneAction_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pane    string
+		sendErr error
+		wantErr string
+	}{
+		{name: "unknown pane", pane: "db", wantErr: `pane action 0: pane "db" is not in the tmux layout`},
+		{name: "key fails", pane: "client", sendErr: errors.New("browser gone"), wantErr: `select pane "client": browser gone`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{
+				TmuxPanes: []config.TmuxPane{{Name: "server"}, {Name: "client"}},
+				Actions:   []script.Action{{Kind: script.ActionPane, Name: tt.pane}},
+			})
+			c.sendKey = func(context.Context, string) error { return tt.sendErr }
+
+			ctx := context.Background()
+			err := c.runSession(ctx, ctx)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
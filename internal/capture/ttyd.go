@@ -1,23 +1,48 @@
 package capture
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
-// TTydServer manages the ttyd subprocess lifecycle.
+// TTydServer manages the ttyd subprocess lifecycle. Stop, Done, ExitCode
+// and SignalCommand may be called while Start runs, from other goroutines;
+// the exported fields must not change once Start is called.
 type TTydServer struct {
-	Command string       // the shell command to execute
-	Port    int          // port number for ttyd to listen on
-	cmd     *exec.Cmd    // the running ttyd process
-	stderr  bytes.Buffer // to capture error output
+	Command    string     // the shell command to execute
+	Args       []string   // argv to run without a shell, replacing Command
+	Shell      string     // the shell that runs Command; empty means bash
+	Port       int        // port number for ttyd to listen on
+	AutoPort   bool       // listen on a free port instead of Port
+	NeedsInput bool       // the script sends keys, so ttyd must accept input
+	Log        io.Writer  // also receives ttyd's output, if set
+	Env        []string   // extra environment for the command, as KEY=value; later entries win
+	NoColor    bool       // ask the command for monochrome output
+	SSH        string     // run ttyd on this host over ssh, forwarding Port; empty runs it locally
+	RunID      string     // marks the local ttyd or ssh process as RunIDEnv, if set
+	stderr     ringBuffer // the tail of ttyd's output, for error messages
+
+	// mu guards the fields below, which Start sets for each launch.
+	mu      sync.Mutex
+	abort   context.CancelFunc // cancels a Start that has not launched ttyd yet
+	cmd     *exec.Cmd          // the running ttyd process
+	exit    *exitState         // when the command exited, and with which code
+	waited  chan struct{}      // closed once cmd.Wait has returned
+	waitErr error              // the result of cmd.Wait, once waited is closed
+	stopped chan struct{}      // closed once the first Stop has finished
+	stopErr error              // the result of the first Stop, once stopped is closed
 }
 
 // NewTTydServer creates a TTydServer instance without starting it.
@@ -25,37 +50,212 @@ func NewTTydServer(command string, port int) *TTydServer {
 	return &TTydServer{
 		Command: command,
 		Port:    port,
-		cmd:     nil,
 	}
 }
 
 // Validate checks that the TTydServer configuration is valid.
 func (s *TTydServer) Validate() error {
-	if s.Command == "" {
+	if s.Command == "" && len(s.Args) == 0 {
 		return fmt.Errorf("command must not be empty")
 	}
-	if s.Port < 1 || s.Port > 65535 {
+	if _, ok := shellArgs[s.shell()]; !ok {
+		return fmt.Errorf("unknown shell %q", s.Shell)
+	}
+	if (s.Port < 1 && !s.AutoPort) || s.Port < 0 || s.Port > 65535 {
 		return fmt.Errorf("port must be between 1 and 65535, got %d", s.Port)
 	}
 	return nil
 }
 
+// bindTries bounds how often Start launches ttyd on another free port when
+// another process took the one it was given first.
+const bindTries = 3
+
+// errPortTaken is returned by start when ttyd could not bind its port.
+var errPortTaken = errors.New("port taken")
+
 // Start verifies ttyd binary exists, builds and starts the ttyd subprocess,
-// and polls the health endpoint to verify readiness.
+// and polls the health endpoint to verify readiness. A Stop while Start
+// runs makes it return an error, with ttyd stopped. With AutoPort, ttyd
+// is launched again on another free port when its port is taken between
+// picking it and ttyd binding it.
 func (s *TTydServer) Start(ctx context.Context) error {
 	// Validate configuration
 	if err := s.Validate(); err != nil {
 		return fmt.Errorf("invalid TTydServer configuration: %w", err)
 	}
 
-	// Verify ttyd binary exists in PATH
-	ttydPath, err := exec.LookPath("ttyd")
+	for try := 1; ; try++ {
+		err := s.start(ctx)
+		if !s.AutoPort || !errors.Is(err, errPortTaken) || try == bindTries {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: port %d was taken before ttyd could listen on it; trying another\n", s.Port)
+	}
+}
+
+// start launches ttyd once, on the port selectPort picks.
+func (s *TTydServer) start(ctx context.Context) error {
+	s.stderr.Reset()
+
+	// Until ttyd is launched, Stop aborts the start by cancelling ctx;
+	// afterwards the caller's ctx ending kills ttyd, as before
+	parent := ctx
+	ctx, abort := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.abort, s.cmd, s.exit, s.waited, s.stopped = abort, nil, nil, nil, nil
+	s.mu.Unlock()
+
+	// Verify ttyd binary exists in PATH; over ssh, the remote one is only
+	// found by running it
+	binary := "ttyd"
+	if s.SSH != "" {
+		binary = "ssh"
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		abort()
+		if s.SSH != "" {
+			return missingDependency("ssh binary not found; install an OpenSSH client to capture over ssh")
+		}
+		return missingDependency("ttyd binary not found. Install ttyd and ensure it's in PATH. Visit: https://github.com/tsl0741/ttyd")
+	}
+
+	// Make sure the port is free before launching, so a busy port fails
+	// fast instead of surfacing as a health check timeout; a port from
+	// the pool stays reserved until ttyd has bound it, or failed to
+	release, err := s.selectPort()
 	if err != nil {
-		return fmt.Errorf("ttyd binary not found. Install ttyd and ensure it's in PATH. Visit: https://github.com/tsl0741/ttyd")
+		abort()
+		return err
+	}
+	defer release()
+
+	// Older ttyd versions reject --writable, so only pass it when supported
+	writable, err := s.checkWritable(ctx, path)
+	if err != nil {
+		abort()
+		return err
+	}
+
+	// Over ssh, the environment is set on the remote command, and the
+	// remote terminal merges ttyd's output into ssh's standard output
+	var cmd *exec.Cmd
+	if s.SSH == "" {
+		cmd = exec.CommandContext(ctx, path, s.args(writable)...)
+		cmd.Env = s.environ(os.Environ())
+	} else {
+		remote := remoteCommand(s.environ(nil), append([]string{"ttyd"}, s.args(writable)...))
+		cmd = exec.CommandContext(ctx, path, sshArgs(s.SSH, s.Port, remote)...)
+		cmd.Env = os.Environ()
+	}
+	if s.RunID != "" {
+		cmd.Env = append(cmd.Env, RunIDEnv+"="+s.RunID)
+	}
+
+	// Attach stderr to capture error output; only the tail is kept in
+	// memory, since ttyd logs for as long as it runs. ttyd stays up after
+	// the command exits, so the exit is detected from its log notice.
+	exit := newExitState()
+	stderr := []io.Writer{&s.stderr, &exitWatcher{state: exit}}
+	if s.Log != nil {
+		stderr = append(stderr, s.Log)
+	}
+	cmd.Stderr = io.MultiWriter(stderr...)
+	if s.SSH != "" {
+		cmd.Stdout = cmd.Stderr
+	}
+
+	// Start process; a Stop that came first has cancelled ctx, which
+	// cmd.Start reports
+	s.mu.Lock()
+	if err := cmd.Start(); err != nil {
+		s.mu.Unlock()
+		abort()
+		return fmt.Errorf("start ttyd process: %w", err)
 	}
+	waited := make(chan struct{})
+	s.cmd, s.exit, s.waited = cmd, exit, waited
+	s.mu.Unlock()
 
-	// Build ttyd command with options matching VHS configuration
-	// These client options (-t) are passed to xterm.js for proper terminal emulation
+	// Reap ttyd in the background, so Done also fires if ttyd itself dies
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		s.waitErr = err
+		s.mu.Unlock()
+		close(waited)
+		exit.exit(0, false)
+		abort()
+	}()
+
+	// Poll http://localhost:<port>/ for up to 5 seconds to verify
+	// readiness, giving up early if ttyd exits or is stopped
+	readyCtx, cancelReady := context.WithCancel(ctx)
+	defer cancelReady()
+	go func() {
+		select {
+		case <-waited:
+			cancelReady()
+		case <-readyCtx.Done():
+		}
+	}()
+	if err := waitForHTTP(readyCtx, s.URL(), 5*time.Second); err != nil {
+		if errors.Is(err, errNotReady) {
+			// Timeout occurred, kill the process
+			if err := s.Stop(); err != nil {
+				// Log that Stop failed and attempt direct kill as fallback
+				_ = cmd.Process.Kill()
+			}
+			return fmt.Errorf("ttyd health check timeout after 5 seconds. stderr: %s", s.stderr.String())
+		}
+		s.mu.Lock()
+		stopping := s.stopped != nil
+		s.mu.Unlock()
+		_ = s.Stop() // Clean up process before returning
+		switch {
+		case parent.Err() != nil:
+			return fmt.Errorf("context cancelled while waiting for ttyd to be ready: %w", parent.Err())
+		case stopping:
+			return fmt.Errorf("ttyd was stopped before it was ready")
+		case strings.Contains(s.stderr.String(), "ERROR on binding"):
+			return fmt.Errorf("ttyd could not listen on port %d: %w. stderr: %s", s.Port, errPortTaken, s.stderr.String())
+		case s.SSH != "":
+			s.mu.Lock()
+			code := exitCode(s.waitErr)
+			s.mu.Unlock()
+			if err := sshError(s.SSH, s.Port, code, s.stderr.String()); err != nil {
+				return err
+			}
+			fallthrough
+		default:
+			return fmt.Errorf("ttyd exited before it was ready. stderr: %s", s.stderr.String())
+		}
+	}
+
+	return nil
+}
+
+// environ returns the command's environment: base with the variables for
+// proper terminal emulation on top, then Env. With NoColor, the command is
+// told to print no colors instead: NO_COLOR=1, TERM=xterm and no COLORTERM,
+// whatever base holds.
+func (s *TTydServer) environ(base []string) []string {
+	if !s.NoColor {
+		env := append(slices.Clip(base), "TERM=xterm-256color", "COLORTERM=truecolor", "PS1=> ")
+		return append(env, s.Env...)
+	}
+	env := slices.DeleteFunc(slices.Clone(base), func(kv string) bool {
+		return strings.HasPrefix(kv, "COLORTERM=")
+	})
+	env = append(env, "TERM=xterm", "NO_COLOR=1", "PS1=> ")
+	return append(env, s.Env...)
+}
+
+// args builds the ttyd command line: Args as given, or Command wrapped in
+// the shell. The client options (-t) match VHS and are passed to xterm.js
+// for proper terminal emulation.
+func (s *TTydServer) args(writable bool) []string {
 	args := []string{
 		"-p", strconv.Itoa(s.Port),
 		"--interface", "127.0.0.1",
@@ -63,53 +263,180 @@ func (s *TTydServer) Start(ctx context.Context) error {
 		"-t", "disableResizeOverlay=true",
 		"-t", "enableSixel=true",
 		"-t", "customGlyphs=true",
-		"--writable",
-		"bash", "--norc", "--noprofile", "-c", s.Command,
 	}
+	if writable {
+		args = append(args, "--writable")
+	}
+	if len(s.Args) > 0 {
+		return append(args, s.Args...)
+	}
+	args = append(args, s.shell())
+	args = append(args, shellArgs[s.shell()]...)
+	return append(args, "-c", s.Command)
+}
 
-	s.cmd = exec.CommandContext(ctx, ttydPath, args...)
+// shellArgs are the options each supported shell gets before -c, so the
+// command runs without the user's startup files.
+var shellArgs = map[string][]string{
+	"bash": {"--norc", "--noprofile"},
+	"sh":   nil,
+	"zsh":  {"--no-rcs"},
+	"fish": {"--no-config"},
+}
 
-	// Set environment for proper terminal emulation
-	s.cmd.Env = append(os.Environ(),
-		"TERM=xterm-256color",
-		"COLORTERM=truecolor",
-		"PS1=> ",
-	)
+// shell returns the shell that runs Command.
+func (s *TTydServer) shell() string {
+	if s.Shell == "" {
+		return "bash"
+	}
+	return s.Shell
+}
 
-	// Attach stderr to capture error output
-	s.cmd.Stderr = &s.stderr
+// writableSupport describes how a ttyd binary treats client input.
+type writableSupport int
 
-	// Start process
-	if err := s.cmd.Start(); err != nil {
-		return fmt.Errorf("start ttyd process: %w", err)
+const (
+	// writableFlag: input is off unless --writable is passed (ttyd 1.7+).
+	writableFlag writableSupport = iota
+	// writableByDefault: input is on unless --readonly is passed (older ttyd).
+	writableByDefault
+	// writableUnknown: the help text mentions neither flag.
+	writableUnknown
+)
+
+// checkWritable reports whether --writable must be passed to the ttyd at
+// path, or, with SSH, to the remote ttyd run by the ssh at path. When the
+// binary documents neither --writable nor --readonly it may not accept
+// input: that is an error if NeedsInput is set, and a warning otherwise.
+func (s *TTydServer) checkWritable(ctx context.Context, path string) (bool, error) {
+	// ttyd exits non-zero after printing help in some versions, so only
+	// the output matters, unless ssh itself failed
+	name := "ttyd " + ttydVersion()
+	cmd := exec.CommandContext(ctx, path, "--help")
+	if s.SSH != "" {
+		name = "ttyd on " + s.SSH
+		cmd = exec.CommandContext(ctx, path, sshArgs(s.SSH, 0, remoteCommand(nil, []string{"ttyd", "--help"}))...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil && s.SSH != "" {
+		if sshErr := sshError(s.SSH, s.Port, exitCode(err), string(out)); sshErr != nil {
+			return false, sshErr
+		}
+	}
+	switch parseWritable(string(out)) {
+	case writableFlag:
+		return true, nil
+	case writableByDefault:
+		return false, nil
+	}
+	if s.NeedsInput {
+		return false, fmt.Errorf("%s does not support --writable, so it may not accept input, but the script sends keys: install ttyd 1.7 or later", name)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s does not support --writable; input may not work\n", name)
+	return false, nil
+}
+
+// parseWritable classifies ttyd --help output.
+func parseWritable(help string) writableSupport {
+	switch {
+	case strings.Contains(help, "--writable"):
+		return writableFlag
+	case strings.Contains(help, "--readonly"):
+		return writableByDefault
+	default:
+		return writableUnknown
+	}
+}
+
+// selectPort picks the port ttyd listens on and returns a function that
+// releases it. With AutoPort, Port is replaced by a free port from
+// ttydPorts, reserved until then; otherwise Port must be free.
+func (s *TTydServer) selectPort() (release func(), err error) {
+	if s.AutoPort {
+		port, release, err := ttydPorts.acquire()
+		if err != nil {
+			return nil, err
+		}
+		s.Port = port
+		return release, nil
+	}
+	if portFree(s.Port) {
+		return func() {}, nil
+	}
+	// A ttyd of an earlier run that scr could not stop, because it was
+	// killed, is stopped instead of working around it
+	if s.SSH == "" && reapPortHolder(s.Port) && waitPortFree(s.Port, reapGracePeriod) {
+		return func() {}, nil
+	}
+	return nil, fmt.Errorf("port %d already in use: pass a different -p, or omit -p to pick a free port", s.Port)
+}
+
+// portFree reports whether port can be bound on the loopback interface.
+func portFree(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
+
+// waitPortFree polls until port is free or timeout passes, and reports
+// whether it is free.
+func waitPortFree(port int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !portFree(port) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
+	return true
+}
+
+// freePort asks the kernel for an unused loopback port. The port is released
+// before returning, so another process could take it before ttyd binds it.
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// errNotReady is returned by waitForHTTP when the deadline passes.
+var errNotReady = errors.New("not ready")
+
+// Polls by waitForHTTP start pollMinDelay apart, doubling up to
+// pollMaxDelay: ttyd usually answers within a few milliseconds, and a fixed
+// 100ms poll made every run wait for the first retry.
+const (
+	pollMinDelay = 10 * time.Millisecond
+	pollMaxDelay = 100 * time.Millisecond
+)
 
-	// Poll http://localhost:<port>/ for up to 5 seconds to verify readiness
-	url := s.URL()
-	deadline := time.Now().Add(5 * time.Second)
+// waitForHTTP polls url until it answers with any status other than 404,
+// the timeout elapses (errNotReady), or ctx is done (ctx.Err()).
+func waitForHTTP(ctx context.Context, url string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
 	client := &http.Client{Timeout: 1 * time.Second}
+	delay := pollMinDelay
 
 	for {
 		select {
 		case <-ctx.Done():
-			_ = s.Stop() // Clean up process before returning
-			return fmt.Errorf("context cancelled while waiting for ttyd to be ready: %w", ctx.Err())
+			return ctx.Err()
 		default:
 		}
 
 		if time.Now().After(deadline) {
-			// Timeout occurred, kill the process
-			if err := s.Stop(); err != nil {
-				// Log that Stop failed and attempt direct kill as fallback
-				_ = s.cmd.Process.Kill()
-			}
-			return fmt.Errorf("ttyd health check timeout after 5 seconds. stderr: %s", s.stderr.String())
+			return errNotReady
 		}
 
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
-			time.Sleep(100 * time.Millisecond)
-			continue
+			return fmt.Errorf("build request for %s: %w", url, err)
 		}
 
 		resp, err := client.Do(req)
@@ -121,46 +448,89 @@ func (s *TTydServer) Start(ctx context.Context) error {
 			_ = resp.Body.Close()
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, pollMaxDelay)
 	}
 }
 
 // Stop gracefully terminates the ttyd process.
 // If process is already finished, returns nil.
 // Sends SIGTERM and waits up to 5 seconds, then SIGKILL if needed.
+// Stop is idempotent: later and concurrent calls wait for the first and
+// return its result. Called while Start runs, it aborts the start.
 func (s *TTydServer) Stop() error {
-	if s.cmd == nil || s.cmd.Process == nil {
+	s.mu.Lock()
+	cmd, waited, stopped := s.cmd, s.waited, s.stopped
+	if cmd == nil {
+		abort := s.abort
+		s.mu.Unlock()
+		if abort != nil {
+			abort()
+		}
 		return nil
 	}
+	if stopped != nil {
+		s.mu.Unlock()
+		<-stopped
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.stopErr
+	}
+	stopped = make(chan struct{})
+	s.stopped = stopped
+	s.mu.Unlock()
 
+	err := s.terminate(cmd, waited)
+	s.mu.Lock()
+	s.stopErr = err
+	s.mu.Unlock()
+	close(stopped)
+	return err
+}
+
+// terminate sends SIGTERM to the ttyd process cmd, and SIGKILL if it has
+// not exited within 5 seconds, and returns once waited is closed.
+func (s *TTydServer) terminate(cmd *exec.Cmd, waited <-chan struct{}) error {
 	// Send SIGTERM for graceful shutdown
-	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
 		// Process might already be dead, check if it's still running
 		if err == os.ErrProcessDone {
 			return nil
 		}
 		// Try to kill it
-		return s.cmd.Process.Kill()
+		return cmd.Process.Kill()
 	}
 
 	// Wait up to 5 seconds for graceful shutdown
-	done := make(chan error, 1)
-	go func() {
-		done <- s.cmd.Wait()
-	}()
-
 	select {
 	case <-time.After(5 * time.Second):
 		// Still running after timeout, send SIGKILL
-		if err := s.cmd.Process.Kill(); err != nil && err != os.ErrProcessDone {
+		if err := cmd.Process.Kill(); err != nil && err != os.ErrProcessDone {
 			return fmt.Errorf("kill ttyd process: %w", err)
 		}
 		// Wait for kill to complete
-		<-done
+		<-waited
+		return nil
+	case <-waited:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.waitErr
+	}
+}
+
+// process returns the running ttyd process, or nil before Start has
+// launched it.
+func (s *TTydServer) process() *os.Process {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil {
 		return nil
-	case err := <-done:
-		return err
 	}
+	return s.cmd.Process
 }
 
 // URL returns the localhost address with the configured port.
@@ -3,13 +3,23 @@ package capture
 import (
 	"context"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
 )
 
 // getFreePort allocates and returns an available ephemeral port.
@@ -143,6 +153,7 @@ func TestTTydServer_Start_TTydNotFound(t *testing.T) {
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.ErrorIs(t, err, ErrMissingDependency)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -297,3 +308,423 @@ func TestTTydServer_Stop_GracefulShutdown(t *testing.T) {
 		})
 	}
 }
+
+func TestTTydServer_environ(t *testing.T) {
+	base := []string{"HOME=/home/me", "COLORTERM=truecolor", "TERM=screen"}
+
+	tests := []struct {
+		name    string
+		noColor bool
+		env     []string
+		want    []string
+	}{
+		{
+			name: "color",
+			want: []string{"HOME=/home/me", "COLORTERM=truecolor", "TERM=screen", "TERM=xterm-256color", "COLORTERM=truecolor", "PS1=> "},
+		},
+		{
+			name: "extra env last",
+			env:  []string{"PS1=$ "},
+			want: []string{"HOME=/home/me", "COLORTERM=truecolor", "TERM=screen", "TERM=xterm-256color", "COLORTERM=truecolor", "PS1=> ", "PS1=$ "},
+		},
+		{
+			name:    "no color drops COLORTERM",
+			noColor: true,
+			env:     []string{"LANG=C"},
+			want:    []string{"HOME=/home/me", "TERM=screen", "TERM=xterm", "NO_COLOR=1", "PS1=> ", "LANG=C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TTydServer{NoColor: tt.noColor, Env: tt.env}
+			assert.Equal(t, tt.want, s.environ(base))
+		})
+	}
+	assert.Equal(t, []string{"HOME=/home/me", "COLORTERM=truecolor", "TERM=screen"}, base, "base is not modified")
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	t.Run("returns once the server answers", func(t *testing.T) {
+		var hits atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Report not-found until the third poll, like a ttyd still starting up.
+			if hits.Add(1) < 3 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		err := waitForHTTP(context.Background(), srv.URL, 5*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), hits.Load())
+	})
+
+	t.Run("times out with errNotReady", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		err := waitForHTTP(context.Background(), srv.URL, 150*time.Millisecond)
+		assert.ErrorIs(t, err, errNotReady)
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := waitForHTTP(ctx, "http://127.0.0.1:1", 5*time.Second)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTTydServer_selectPort(t *testing.T) {
+	// Occupy a port the way a second ttyd would find it
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer busy.Close()
+	busyPort := busy.Addr().(*net.TCPAddr).Port
+
+	freePort, err := getFreePort()
+	if err != nil {
+		t.Fatalf("get free port: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		port     int
+		autoPort bool
+		wantErr  string
+		wantSame bool
+	}{
+		{name: "keeps a free port", port: freePort, wantSame: true},
+		{name: "fails fast when busy and explicit", port: busyPort, wantErr: "port " + strconv.Itoa(busyPort) + " already in use"},
+		{name: "auto port picks a port from the pool", port: busyPort, autoPort: true},
+		{name: "auto port without a port", autoPort: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewTTydServer("bash", tt.port)
+			s.AutoPort = tt.autoPort
+
+			release, err := s.selectPort()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, tt.port, s.Port)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantSame {
+				assert.Equal(t, tt.port, s.Port)
+				release()
+				return
+			}
+			assert.NotEqual(t, tt.port, s.Port)
+			assert.True(t, portFree(s.Port))
+			assert.True(t, ttydPorts.reserved[s.Port], "the port stays reserved until ttyd has bound it")
+			release()
+			assert.False(t, ttydPorts.reserved[s.Port])
+		})
+	}
+}
+
+func TestTTydServer_Start_PortTaken(t *testing.T) {
+	// A ttyd that finds its port taken, as when another process binds it
+	// first, and notes each port it was given
+	dir := t.TempDir()
+	ports := filepath.Join(dir, "ports")
+	script := "#!/bin/sh\ncase \"$1\" in\n" +
+		"--help) printf '%s\\n' '" + helpWithWritable + "'; exit 1 ;;\n" +
+		"--version) echo 'ttyd version 1.7.7' ;;\n" +
+		"*) echo \"$2\" >> " + ports + "; echo \"[E] ERROR on binding fd 12 to port $2 (-1 98)\" >&2; exit 1 ;;\nesac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ttyd"), []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name         string
+		autoPort     bool
+		wantLaunches int
+	}{
+		{name: "auto port tries other ports", autoPort: true, wantLaunches: bindTries},
+		{name: "explicit port is not changed", wantLaunches: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.RemoveAll(ports))
+			port, err := getFreePort()
+			require.NoError(t, err)
+			server := NewTTydServer("bash", port)
+			server.AutoPort = tt.autoPort
+
+			startErr := server.Start(context.Background())
+			require.Error(t, startErr)
+			assert.Contains(t, startErr.Error(), "ERROR on binding")
+
+			data, err := os.ReadFile(ports)
+			require.NoError(t, err)
+			launches := strings.Fields(string(data))
+			assert.Len(t, launches, tt.wantLaunches)
+			if tt.autoPort {
+				assert.ErrorIs(t, startErr, errPortTaken)
+				assert.Len(t, slices.Compact(slices.Sorted(slices.Values(launches))), len(launches), "each launch gets another port")
+				assert.Empty(t, ttydPorts.reserved, "the ports are released")
+			}
+		})
+	}
+}
+
+// stubTTyd installs a fake ttyd as the only command in PATH that prints
+// help for --help and version for --version, and returns its path. It uses
+// shell builtins only, since nothing else is in PATH.
+func stubTTyd(t *testing.T, help, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ttyd")
+	script := "#!/bin/sh\ncase \"$1\" in\n" +
+		"--help) printf '%s\\n' '" + help + "'; exit 1 ;;\n" +
+		"--version) echo 'ttyd version " + version + "' ;;\n" +
+		"*) exit 1 ;;\nesac\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+	return path
+}
+
+// startingTTyd puts a ttyd on PATH that logs to stderr until it gets
+// SIGTERM but never serves the terminal, so Start stays in its health
+// check.
+func startingTTyd(t *testing.T) {
+	t.Helper()
+	sleep, err := exec.LookPath("sleep")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n" +
+		"--help) printf '%s\\n' '" + helpWithWritable + "'; exit 1 ;;\n" +
+		"--version) echo 'ttyd version 1.7.7' ;;\n" +
+		"*) trap 'exit 0' TERM; while :; do echo 'ttyd is starting' >&2; " + sleep + " 0.01; done ;;\nesac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ttyd"), []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+}
+
+// launched waits until Start has launched ttyd.
+func launched(t *testing.T, server *TTydServer) {
+	t.Helper()
+	require.Eventually(t, func() bool { return server.Done() != nil }, 5*time.Second, 5*time.Millisecond, "ttyd is launched")
+}
+
+// stopTwice calls Stop from two goroutines at once and returns both
+// results.
+func stopTwice(server *TTydServer) [2]error {
+	var results [2]error
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Go(func() { results[i] = server.Stop() })
+	}
+	wg.Wait()
+	return results
+}
+
+func TestTTydServer_Stop_DuringStart(t *testing.T) {
+	startingTTyd(t)
+	port, err := getFreePort()
+	require.NoError(t, err)
+	server := NewTTydServer("bash", port)
+
+	started := make(chan error, 1)
+	go func() { started <- server.Start(context.Background()) }()
+	launched(t, server)
+
+	results := stopTwice(server)
+	assert.Equal(t, results[0], results[1], "concurrent Stops return the same result")
+	assert.NoError(t, results[0])
+
+	select {
+	case err := <-started:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ttyd was stopped before it was ready")
+	case <-time.After(3 * time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+	assert.NoError(t, server.Stop(), "a later Stop returns the first one's result")
+	select {
+	case <-server.Done():
+	default:
+		t.Error("Done fires once ttyd is stopped")
+	}
+}
+
+func TestTTydServer_Start_CancelDuringStart(t *testing.T) {
+	startingTTyd(t)
+	port, err := getFreePort()
+	require.NoError(t, err)
+	server := NewTTydServer("bash", port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- server.Start(ctx) }()
+	launched(t, server)
+	cancel()
+
+	select {
+	case err := <-started:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "context cancelled while waiting for ttyd to be ready")
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Start did not return after the context was cancelled")
+	}
+
+	results := stopTwice(server)
+	assert.Equal(t, results[0], results[1], "concurrent Stops return the same result")
+	_, known := server.ExitCode()
+	assert.False(t, known, "ttyd was killed before the command reported an exit")
+}
+
+func TestTTydServer_Start_TTydExits(t *testing.T) {
+	stubTTyd(t, helpWithWritable, "1.7.7")
+	port, err := getFreePort()
+	require.NoError(t, err)
+	server := NewTTydServer("bash", port)
+
+	start := time.Now()
+	err = server.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ttyd exited before it was ready")
+	assert.Less(t, time.Since(start), 5*time.Second, "a dead ttyd is noticed before the health check times out")
+}
+
+const (
+	helpWithWritable = "    -W, --writable          Allow clients to write to the TTY (readonly by default)"
+	helpWithReadonly = "    -R, --readonly          Do not allow clients to write to the TTY"
+	helpWithNeither  = "    -p, --port              Port to listen"
+)
+
+func TestParseWritable(t *testing.T) {
+	tests := []struct {
+		name string
+		help string
+		want writableSupport
+	}{
+		{name: "ttyd 1.7 and later", help: helpWithWritable, want: writableFlag},
+		{name: "older ttyd", help: helpWithReadonly, want: writableByDefault},
+		{name: "neither flag", help: helpWithNeither, want: writableUnknown},
+		{name: "no output", help: "", want: writableUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseWritable(tt.help))
+		})
+	}
+}
+
+func TestTTydServer_checkWritable(t *testing.T) {
+	tests := []struct {
+		name         string
+		help         string
+		needsInput   bool
+		wantWritable bool
+		wantErr      string
+	}{
+		{name: "passes --writable when supported", help: helpWithWritable, needsInput: true, wantWritable: true},
+		{name: "omits it when input is on by default", help: helpWithReadonly, needsInput: true},
+		{name: "warns without input", help: helpWithNeither},
+		{name: "errors when the script sends keys", help: helpWithNeither, needsInput: true, wantErr: "ttyd 1.5.2 does not support --writable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := stubTTyd(t, tt.help, "1.5.2")
+			server := NewTTydServer("bash", 7681)
+			server.NeedsInput = tt.needsInput
+
+			writable, err := server.checkWritable(context.Background(), path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWritable, writable)
+		})
+	}
+}
+
+func TestTTydServer_Start_ReadOnlyTTyd(t *testing.T) {
+	stubTTyd(t, helpWithNeither, "1.5.2")
+	port, err := getFreePort()
+	require.NoError(t, err)
+	server := NewTTydServer("bash", port)
+	server.NeedsInput = true
+
+	err = server.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "may not accept input")
+	assert.Nil(t, server.cmd, "ttyd is not launched")
+}
+
+func TestTTydServer_args(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *TTydServer
+		writable bool
+		wantTail []string
+	}{
+		{
+			name:     "bash by default",
+			server:   &TTydServer{Command: "htop", Port: 9000},
+			writable: true,
+			wantTail: []string{"--writable", "bash", "--norc", "--noprofile", "-c", "htop"},
+		},
+		{
+			name:     "sh",
+			server:   &TTydServer{Command: "htop", Shell: "sh", Port: 9000},
+			wantTail: []string{"customGlyphs=true", "sh", "-c", "htop"},
+		},
+		{
+			name:     "zsh",
+			server:   &TTydServer{Command: "htop", Shell: "zsh", Port: 9000},
+			wantTail: []string{"zsh", "--no-rcs", "-c", "htop"},
+		},
+		{
+			name:     "fish",
+			server:   &TTydServer{Command: "htop", Shell: "fish", Port: 9000},
+			wantTail: []string{"fish", "--no-config", "-c", "htop"},
+		},
+		{
+			name:     "argv without a shell",
+			server:   &TTydServer{Args: []string{"htop", "-d", "10"}, Port: 9000},
+			writable: true,
+			wantTail: []string{"customGlyphs=true", "--writable", "htop", "-d", "10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.server.args(tt.writable)
+			assert.Equal(t, []string{"-p", "9000"}, args[:2])
+			require.GreaterOrEqual(t, len(args), len(tt.wantTail))
+			assert.Equal(t, tt.wantTail, args[len(args)-len(tt.wantTail):])
+			assert.Equal(t, tt.writable, slices.Contains(args, "--writable"))
+		})
+	}
+}
+
+func TestTTydServer_Validate_Shell(t *testing.T) {
+	assert.NoError(t, (&TTydServer{Args: []string{"htop"}, Port: 9000}).Validate())
+	err := (&TTydServer{Command: "htop", Shell: "csh", Port: 9000}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown shell "csh"`)
+}
+
+func TestShellArgs_CoverConfigShells(t *testing.T) {
+	for _, shell := range config.Shells {
+		_, ok := shellArgs[shell]
+		assert.True(t, ok, "no arguments for shell %s", shell)
+	}
+}
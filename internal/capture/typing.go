@@ -0,0 +1,157 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// typeChunkInterval is the shortest pause between the round trips of a Type
+// action. Faster typing is sent in chunks of several characters, one
+// Input.insertText call each, that still appear at the typing speed overall.
+const typeChunkInterval = 30 * time.Millisecond
+
+// typeChunks splits text into the pieces a Type action sends with the given
+// per-character delay. Control characters such as newline are their own
+// pieces, to be sent as key presses; runs of other characters are grouped
+// so that each piece covers at least typeChunkInterval, and with no delay
+// they are not split at all. A limit above zero caps the characters in a
+// piece.
+func typeChunks(text string, delay time.Duration, limit int) []string {
+	size := 0 // unlimited
+	if delay > 0 {
+		size = max(1, int((typeChunkInterval+delay-1)/delay))
+	}
+	if limit > 0 && (size == 0 || size > limit) {
+		size = limit
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	n := 0
+	flush := func() {
+		if n > 0 {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			n = 0
+		}
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			flush()
+			chunks = append(chunks, string(r))
+			continue
+		}
+		cur.WriteRune(r)
+		n++
+		if size > 0 && n == size {
+			flush()
+		}
+	}
+	flush()
+	return chunks
+}
+
+// insertTerminalText types text into the focused terminal in one round
+// trip, as an input method would commit it.
+func insertTerminalText(ctx context.Context, text string) error {
+	return chromedp.Run(ctx, input.InsertText(text))
+}
+
+// Long Type text is typed with flow control: after each chunk is inserted,
+// the terminal is read back until the chunk's last typeMarkerChars
+// non-space characters show up once more than before. A chunk that does not
+// show up within typeVerifyTimeout is sent again, up to typeVerifyRetries
+// times, since ttyd's websocket can drop input that outruns it.
+const (
+	typeMarkerChars   = 16
+	typeVerifyTimeout = time.Second
+	typeVerifyPoll    = 10 * time.Millisecond
+	typeVerifyRetries = 2
+)
+
+// typeMarker returns the last typeMarkerChars non-space characters of
+// chunk. Spaces are left out because the terminal text wraps lines and
+// trims trailing spaces.
+func typeMarker(chunk string) string {
+	runes := []rune(stripSpace(chunk))
+	return string(runes[max(0, len(runes)-typeMarkerChars):])
+}
+
+// stripSpace returns s without its white space.
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// typeChunkSize returns the length above which Type text is typed with
+// flow control, and the most characters each of its chunks holds.
+func (p *Player) typeChunkSize() int {
+	if p.TypeChunkSize > 0 {
+		return p.TypeChunkSize
+	}
+	return config.DefaultTypeChunkSize
+}
+
+// countMarker returns how often marker occurs in the terminal text,
+// ignoring white space.
+func (p *Player) countMarker(ctx context.Context, marker string) (int, error) {
+	text, err := p.Driver.ReadText(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read terminal: %w", err)
+	}
+	return strings.Count(stripSpace(text), marker), nil
+}
+
+// insertVerified inserts chunk and waits for it to reach the terminal,
+// sending it again if it does not. It expects the program to echo what is
+// typed; see Config.NoTypeVerify.
+func (p *Player) insertVerified(ctx context.Context, chunk string) error {
+	marker := typeMarker(chunk)
+	if marker == "" {
+		return p.Driver.InsertText(ctx, chunk)
+	}
+	before, err := p.countMarker(ctx, marker)
+	if err != nil {
+		return err
+	}
+
+	for try := 0; try <= typeVerifyRetries; try++ {
+		if try > 0 {
+			p.logf("Resending %d characters that did not reach the terminal (try %d)\n", len([]rune(chunk)), try+1)
+		}
+		if err := p.Driver.InsertText(ctx, chunk); err != nil {
+			return err
+		}
+
+		deadline := time.Now().Add(typeVerifyTimeout)
+		for {
+			n, err := p.countMarker(ctx, marker)
+			if err != nil {
+				return err
+			}
+			if n > before {
+				return nil
+			}
+			if time.Now().After(deadline) {
+				break
+			}
+			if err := pause(ctx, typeVerifyPoll); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("%d characters did not reach the terminal after %d tries; pass --no-type-verify if the program does not echo what is typed",
+		len([]rune(chunk)), typeVerifyRetries+1)
+}
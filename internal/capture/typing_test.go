@@ -0,0 +1,206 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestTypeChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		delay time.Duration
+		limit int
+		want  []string
+	}{
+		{name: "no delay sends everything at once", text: "echo hello", delay: 0, want: []string{"echo hello"}},
+		{name: "slow typing is per character", text: "abc", delay: 50 * time.Millisecond, want: []string{"a", "b", "c"}},
+		{name: "exactly the chunk interval", text: "ab", delay: typeChunkInterval, want: []string{"a", "b"}},
+		{name: "fast typing is chunked", text: "abcdefg", delay: 10 * time.Millisecond, want: []string{"abc", "def", "g"}},
+		{name: "control characters are separate", text: "ls\ncd /\n", delay: 0, want: []string{"ls", "\n", "cd /", "\n"}},
+		{name: "multibyte runes", text: "héllo", delay: 15 * time.Millisecond, want: []string{"hé", "ll", "o"}},
+		{name: "limit splits unchunked text", text: "abcdefg", delay: 0, limit: 3, want: []string{"abc", "def", "g"}},
+		{name: "limit caps chunks", text: "abcdefg", delay: time.Millisecond, limit: 4, want: []string{"abcd", "efg"}},
+		{name: "limit above chunk size", text: "abcdefg", delay: 10 * time.Millisecond, limit: 5, want: []string{"abc", "def", "g"}},
+		{name: "empty", text: "", delay: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, typeChunks(tt.text, tt.delay, tt.limit))
+		})
+	}
+}
+
+// typeCall is a dispatched browser call recorded by TestPlayer_typeText.
+type typeCall struct {
+	kind string // "key" or "insert"
+	text string
+}
+
+func TestPlayer_typeText(t *testing.T) {
+	tests := []struct {
+		name   string
+		action script.Action
+		want   []typeCall
+	}{
+		{
+			name:   "zero speed inserts the whole text",
+			action: script.Action{Kind: script.ActionType, Text: "cat <<EOF", Speed: 0},
+			want:   []typeCall{{"insert", "cat <<EOF"}},
+		},
+		{
+			name:   "default speed presses each key",
+			action: script.Action{Kind: script.ActionType, Text: "ls", Speed: script.DefaultTypeSpeed},
+			want:   []typeCall{{"key", "l"}, {"key", "s"}},
+		},
+		{
+			name:   "fast typing inserts chunks",
+			action: script.Action{Kind: script.ActionType, Text: "hello", Speed: 10 * time.Millisecond},
+			want:   []typeCall{{"insert", "hel"}, {"insert", "lo"}},
+		},
+		{
+			name:   "newlines are key presses",
+			action: script.Action{Kind: script.ActionType, Text: "a\nbc", Speed: 0},
+			want:   []typeCall{{"key", "a"}, {"key", "\n"}, {"insert", "bc"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{Actions: []script.Action{tt.action}})
+			var calls []typeCall
+			c.sendKey = func(_ context.Context, key string) error {
+				calls = append(calls, typeCall{"key", key})
+				return nil
+			}
+			c.insertText = func(_ context.Context, text string) error {
+				calls = append(calls, typeCall{"insert", text})
+				return nil
+			}
+
+			ctx := context.Background()
+			start := time.Now()
+			require.NoError(t, c.player(ctx).do(ctx, tt.action, 0))
+			assert.Equal(t, tt.want, calls)
+			assert.GreaterOrEqual(t, time.Since(start), tt.action.CharDelay()*time.Duration(len([]rune(tt.action.Text))), "typing keeps its cadence")
+		})
+	}
+}
+
+// echoTerminal is a fake terminal that echoes what is typed into it, wraps
+// it at 80 columns like the terminal text does, and drops chosen inserts
+// the way an overrun websocket would.
+type echoTerminal struct {
+	mu      sync.Mutex
+	got     strings.Builder
+	inserts int
+	// drop lists the inserts, counted from 1, that are lost.
+	drop map[int]bool
+}
+
+func (e *echoTerminal) install(c *Capturer) {
+	c.sendKey = func(_ context.Context, key string) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.got.WriteString(key)
+		return nil
+	}
+	c.insertText = func(_ context.Context, text string) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.inserts++
+		if !e.drop[e.inserts] {
+			e.got.WriteString(text)
+		}
+		return nil
+	}
+	c.readText = func(context.Context) (string, error) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		var lines []string
+		for _, line := range strings.Split(e.got.String(), "\n") {
+			for len(line) > 80 {
+				lines = append(lines, strings.TrimRight(line[:80], " "))
+				line = line[80:]
+			}
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+func TestPlayer_typeText_FlowControl(t *testing.T) {
+	// Lines longer than a chunk, several kilobytes in all
+	var sb strings.Builder
+	for i := 0; sb.Len() < 6000; i++ {
+		fmt.Fprintf(&sb, "line %04d:%s\n", i, strings.Repeat(fmt.Sprintf(" %x fox", i*7919), 60))
+	}
+	text := sb.String()
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		drop    map[int]bool
+		resent  int
+		wantErr string
+	}{
+		{name: "every chunk arrives"},
+		{name: "dropped chunks are resent", drop: map[int]bool{3: true, 9: true}, resent: 2},
+		{name: "small chunks", cfg: config.Config{TypeChunkSize: 16}},
+		{name: "a chunk that never arrives fails", drop: map[int]bool{5: true, 6: true, 7: true}, wantErr: "did not reach the terminal after 3 tries"},
+		{name: "without verification drops go unnoticed", cfg: config.Config{NoTypeVerify: true}, drop: map[int]bool{3: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			c := newFakeCapturer(t, &cfg)
+			term := &echoTerminal{drop: tt.drop}
+			term.install(c)
+
+			ctx := context.Background()
+			err := c.player(ctx).do(ctx, script.Action{Kind: script.ActionType, Text: text}, 0)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if cfg.NoTypeVerify {
+				assert.NotEqual(t, text, term.got.String())
+			} else {
+				assert.Equal(t, text, term.got.String(), "every byte arrives once, in order")
+			}
+			chunks := 0
+			for _, chunk := range typeChunks(text, 0, c.player(ctx).typeChunkSize()) {
+				if chunk != "\n" {
+					chunks++
+				}
+			}
+			assert.Greater(t, chunks, 20)
+			assert.Equal(t, chunks+tt.resent, term.inserts)
+		})
+	}
+}
+
+func TestPlayer_typeText_FlowControlReadError(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{TypeChunkSize: 4})
+	c.insertText = func(context.Context, string) error { return nil }
+	c.readText = func(context.Context) (string, error) { return "", errors.New("page closed") }
+
+	ctx := context.Background()
+	err := c.player(ctx).do(ctx, script.Action{Kind: script.ActionType, Text: "echo hello"}, 0)
+	assert.ErrorContains(t, err, "read terminal: page closed")
+}
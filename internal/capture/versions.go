@@ -0,0 +1,128 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// versionRequirement is the oldest version of ttyd or the browser that
+// supports something scr relies on.
+type versionRequirement struct {
+	Min string
+	// Feature is what needs Min, as it appears in the error.
+	Feature string
+}
+
+// ttydRequirements are checked against `ttyd --version` before ttyd starts,
+// newest first, so the error names the feature that needs the most recent
+// version.
+var ttydRequirements = []versionRequirement{
+	{Min: "1.7.2", Feature: "-t enableSixel"},
+	{Min: "1.6.0", Feature: "-t client options"},
+}
+
+// browserRequirements are checked against the product version reported by
+// Browser.getVersion once the browser is up, newest first.
+var browserRequirements = []versionRequirement{
+	{Min: "100", Feature: "the DevTools input and screenshot commands scr uses"},
+}
+
+// checkTTydVersion fails when version, as reported by ttydVersion, is older
+// than a ttydRequirements entry. Versions that cannot be parsed, such as
+// "unknown", pass: a missing ttyd is reported when it is started.
+func checkTTydVersion(version string) error {
+	req, ok := unmetRequirement(version, ttydRequirements)
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("ttyd %s found, %s required for %s; upgrade ttyd, or rerun with --skip-version-check if your build supports it", version, req.Min, req.Feature)
+}
+
+// checkBrowserVersion fails when product, such as
+// "HeadlessChrome/120.0.6099.109", is older than a browserRequirements
+// entry. Products without a parsable version pass.
+func checkBrowserVersion(product string) error {
+	name, version, found := strings.Cut(product, "/")
+	if !found {
+		return nil
+	}
+	req, ok := unmetRequirement(version, browserRequirements)
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("%s %s found, %s required for %s; upgrade it or pass --chrome-path with a newer browser, or rerun with --skip-version-check if your build supports it", name, version, req.Min, req.Feature)
+}
+
+// checkBrowser reads the running browser's version and checks it. A browser
+// that cannot report its version is not failed here.
+func checkBrowser(ctx context.Context) error {
+	var product string
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		_, product, _, _, _, err = browser.GetVersion().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil
+	}
+	return checkBrowserVersion(product)
+}
+
+// unmetRequirement returns the first of reqs that version does not meet.
+func unmetRequirement(version string, reqs []versionRequirement) (versionRequirement, bool) {
+	have, ok := parseVersion(version)
+	if !ok {
+		return versionRequirement{}, false
+	}
+	for _, req := range reqs {
+		need, _ := parseVersion(req.Min)
+		if compareVersions(have, need) < 0 {
+			return req, true
+		}
+	}
+	return versionRequirement{}, false
+}
+
+// parseVersion reads the dot-separated numbers at the start of a version
+// such as "1.7.4-68c0ddb" or "120.0.6099.109".
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if end := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); end >= 0 {
+		s = s[:end]
+	}
+	var parts []int
+	for _, field := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts, len(parts) > 0
+}
+
+// compareVersions compares two parsed versions part by part, treating
+// missing parts as zero.
+func compareVersions(a, b []int) int {
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTTydVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr string
+	}{
+		{version: "1.7.4-68c0ddb"},
+		{version: "1.7.2"},
+		{version: "1.10.0"},
+		{version: "unknown"},
+		{version: "1.7.1", wantErr: "ttyd 1.7.1 found, 1.7.2 required for -t enableSixel; upgrade ttyd, or rerun with --skip-version-check"},
+		{version: "1.6.3", wantErr: "ttyd 1.6.3 found, 1.7.2 required for -t enableSixel"},
+		{version: "1.5.2", wantErr: "ttyd 1.5.2 found, 1.7.2 required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			err := checkTTydVersion(tt.version)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckBrowserVersion(t *testing.T) {
+	tests := []struct {
+		product string
+		wantErr string
+	}{
+		{product: "HeadlessChrome/120.0.6099.109"},
+		{product: "Chrome/100.0.4896.60"},
+		{product: "Chrome"},
+		{product: "HeadlessChrome/dev"},
+		{product: "HeadlessChrome/99.0.4844.51", wantErr: "HeadlessChrome 99.0.4844.51 found, 100 required for the DevTools input and screenshot commands scr uses; upgrade it or pass --chrome-path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.product, func(t *testing.T) {
+			err := checkBrowserVersion(tt.product)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.7.2", b: "1.7.2", want: 0},
+		{a: "1.7", b: "1.7.0", want: 0},
+		{a: "1.10.0", b: "1.9.9", want: 1},
+		{a: "v1.6.3-abc", b: "1.7.2", want: -1},
+		{a: "120.0.6099.109", b: "100", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, ok := parseVersion(tt.a)
+			require.True(t, ok)
+			b, ok := parseVersion(tt.b)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, compareVersions(a, b))
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// videoBuffer is how many screencast frames may wait to be written before
+// new ones are dropped.
+const videoBuffer = 64
+
+// videoMinFrame is the shortest time a frame is shown for, so a frame that
+// arrives right before the end still appears in the video.
+const videoMinFrame = 10 * time.Millisecond
+
+// videoFrame is a screencast frame written to disk for encoding.
+type videoFrame struct {
+	data []byte
+	at   time.Time
+	file string
+}
+
+// videoRecorder writes screencast frames to a temporary directory as they
+// arrive, for ffmpeg to encode when the run ends. Frames are handed over
+// without blocking: when the writer falls behind by videoBuffer frames, new
+// frames are dropped and the previous frame simply stays on screen longer.
+type videoRecorder struct {
+	dir    string
+	frames chan videoFrame
+	done   chan struct{}
+
+	mu      sync.Mutex
+	written []videoFrame
+	dropped int
+	err     error
+}
+
+// newVideoRecorder creates the frame directory and starts the writer.
+func newVideoRecorder() (*videoRecorder, error) {
+	dir, err := os.MkdirTemp("", "scr-video-")
+	if err != nil {
+		return nil, fmt.Errorf("create video frame directory: %w", err)
+	}
+	r := &videoRecorder{
+		dir:    dir,
+		frames: make(chan videoFrame, videoBuffer),
+		done:   make(chan struct{}),
+	}
+	go r.write()
+	return r, nil
+}
+
+// add queues a frame captured at at. It never blocks and reports false
+// when the frame was dropped.
+func (r *videoRecorder) add(data []byte, at time.Time) bool {
+	select {
+	case r.frames <- videoFrame{data: data, at: at}:
+		return true
+	default:
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+		return false
+	}
+}
+
+// write saves queued frames until the channel is closed. After the first
+// error it keeps draining so add never blocks, but writes nothing more.
+func (r *videoRecorder) write() {
+	defer close(r.done)
+	for f := range r.frames {
+		r.mu.Lock()
+		failed := r.err != nil
+		n := len(r.written)
+		r.mu.Unlock()
+		if failed {
+			continue
+		}
+
+		f.file = filepath.Join(r.dir, fmt.Sprintf("frame-%06d.jpg", n+1))
+		err := os.WriteFile(f.file, f.data, 0o644)
+		f.data = nil
+
+		r.mu.Lock()
+		if err != nil {
+			r.err = fmt.Errorf("write video frame: %w", err)
+		} else {
+			r.written = append(r.written, f)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// close stops accepting frames and waits until the queued ones are
+// written. Call it only once screencast frames can no longer arrive.
+func (r *videoRecorder) close() error {
+	close(r.frames)
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// remove deletes the frame directory.
+func (r *videoRecorder) remove() {
+	_ = os.RemoveAll(r.dir)
+}
+
+// writeConcatList writes an ffmpeg concat playlist that shows each frame
+// until the next one arrived, and the last until end, and returns its path.
+func (r *videoRecorder) writeConcatList(end time.Time) (string, error) {
+	r.mu.Lock()
+	frames := r.written
+	r.mu.Unlock()
+	if len(frames) == 0 {
+		return "", fmt.Errorf("no video frames were received")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("ffconcat version 1.0\n")
+	for i, f := range frames {
+		until := end
+		if i+1 < len(frames) {
+			until = frames[i+1].at
+		}
+		fmt.Fprintf(&sb, "file '%s'\nduration %.6f\n", filepath.Base(f.file), max(until.Sub(f.at), videoMinFrame).Seconds())
+	}
+	// The concat demuxer ignores the last entry's duration unless the file
+	// is listed once more
+	fmt.Fprintf(&sb, "file '%s'\n", filepath.Base(frames[len(frames)-1].file))
+
+	path := filepath.Join(r.dir, "frames.txt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return "", fmt.Errorf("write video frame list: %w", err)
+	}
+	return path, nil
+}
+
+// ffmpegArgs returns the ffmpeg arguments that encode the concat playlist
+// list into out, choosing the codec by out's extension.
+func ffmpegArgs(list, out string) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y",
+		"-f", "concat", "-safe", "0", "-i", list,
+		"-fps_mode", "vfr",
+		// yuv420p needs even dimensions
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", "-pix_fmt", "yuv420p"}
+	if strings.EqualFold(filepath.Ext(out), ".mp4") {
+		args = append(args, "-c:v", "libx264", "-movflags", "+faststart")
+	} else {
+		args = append(args, "-c:v", "libvpx-vp9", "-b:v", "0", "-crf", "32")
+	}
+	return append(args, out)
+}
+
+// lookFFmpeg returns the path of the ffmpeg binary, or an error explaining
+// how to get it.
+func lookFFmpeg() (string, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", missingDependency("--video needs ffmpeg to encode the recording, but it was not found on PATH; install it (e.g. 'brew install ffmpeg' or 'apt install ffmpeg')")
+	}
+	return path, nil
+}
+
+// runFFmpeg encodes the concat playlist list into out.
+func runFFmpeg(ctx context.Context, list, out string) error {
+	path, err := lookFFmpeg()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, ffmpegArgs(list, out)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg: %w: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	return nil
+}
+
+// startScreencast starts the DevTools screencast and calls onFrame with
+// every JPEG frame. Frames are acknowledged as soon as they arrive, since
+// the browser sends no more until it has been. The returned function stops
+// the screencast.
+func startScreencast(ctx context.Context, onFrame func(data []byte)) (stop func(), err error) {
+	lctx, cancel := context.WithCancel(ctx)
+	chromedp.ListenTarget(lctx, func(ev any) {
+		e, ok := ev.(*page.EventScreencastFrame)
+		if !ok {
+			return
+		}
+		// Listeners run on the event loop, which the ack needs
+		go func() { _ = chromedp.Run(lctx, page.ScreencastFrameAck(e.SessionID)) }()
+		data, err := base64.StdEncoding.DecodeString(e.Data)
+		if err != nil {
+			return
+		}
+		onFrame(data)
+	})
+
+	err = chromedp.Run(ctx, page.StartScreencast().
+		WithFormat(page.ScreencastFormatJpeg).
+		WithQuality(90).
+		WithEveryNthFrame(1))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return func() {
+		_ = chromedp.Run(ctx, page.StopScreencast())
+		cancel()
+	}, nil
+}
+
+// videoSession is the recording in progress for Config.Video.
+type videoSession struct {
+	rec  *videoRecorder
+	stop func()
+}
+
+// startVideo starts recording the page, if Config.Video is set; otherwise
+// it returns nil.
+func (c *Capturer) startVideo(ctx context.Context) (*videoSession, error) {
+	if c.config.Video == "" {
+		return nil, nil
+	}
+	rec, err := newVideoRecorder()
+	if err != nil {
+		return nil, err
+	}
+	stop, err := c.screencast(ctx, func(data []byte) { rec.add(data, c.now()) })
+	if err != nil {
+		_ = rec.close()
+		rec.remove()
+		return nil, fmt.Errorf("start screencast: %w", err)
+	}
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Recording video to %s\n", c.config.Video)
+	}
+	return &videoSession{rec: rec, stop: stop}, nil
+}
+
+// finishVideo stops the recording and, when encode is set, encodes it to
+// Config.Video. It is safe to call with a nil session.
+func (c *Capturer) finishVideo(ctx context.Context, v *videoSession, encode bool) error {
+	if v == nil {
+		return nil
+	}
+	v.stop()
+	end := c.now()
+	defer v.rec.remove()
+	if err := v.rec.close(); err != nil || !encode {
+		return err
+	}
+
+	v.rec.mu.Lock()
+	frames, dropped := len(v.rec.written), v.rec.dropped
+	v.rec.mu.Unlock()
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: dropped %d of %d video frames that arrived faster than they could be saved\n", dropped, frames+dropped)
+	}
+
+	list, err := v.rec.writeConcatList(end)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.config.Video); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create video directory: %w", err)
+		}
+	}
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Encoding %d video frames to %s\n", frames, c.config.Video)
+	}
+	if err := c.encodeVideo(ctx, list, c.config.Video); err != nil {
+		return fmt.Errorf("encode video: %w", err)
+	}
+	c.video = c.config.Video
+	return nil
+}
+
+// Video returns the video file written by the last run, or "" if none.
+func (c *Capturer) Video() string {
+	return c.video
+}
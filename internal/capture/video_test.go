@@ -0,0 +1,189 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestVideoRecorder_ConcatList(t *testing.T) {
+	rec, err := newVideoRecorder()
+	require.NoError(t, err)
+	defer rec.remove()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.True(t, rec.add([]byte("a"), start))
+	require.True(t, rec.add([]byte("b"), start.Add(40*time.Millisecond)))
+	require.True(t, rec.add([]byte("c"), start.Add(40*time.Millisecond)))
+	require.NoError(t, rec.close())
+
+	list, err := rec.writeConcatList(start.Add(1040 * time.Millisecond))
+	require.NoError(t, err)
+	data, err := os.ReadFile(list)
+	require.NoError(t, err)
+	assert.Equal(t, `ffconcat version 1.0
+file 'frame-000001.jpg'
+duration 0.040000
+file 'frame-000002.jpg'
+duration 0.010000
+file 'frame-000003.jpg'
+duration 1.000000
+file 'frame-000003.jpg'
+`, string(data))
+
+	frame, err := os.ReadFile(filepath.Join(rec.dir, "frame-000002.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(frame))
+}
+
+func TestVideoRecorder_NoFrames(t *testing.T) {
+	rec, err := newVideoRecorder()
+	require.NoError(t, err)
+	defer rec.remove()
+	require.NoError(t, rec.close())
+
+	_, err = rec.writeConcatList(time.Now())
+	assert.ErrorContains(t, err, "no video frames were received")
+}
+
+func TestVideoRecorder_DropsWhenFull(t *testing.T) {
+	// A recorder whose writer never runs: the buffer fills, then frames
+	// are dropped instead of blocking the screencast
+	rec := &videoRecorder{frames: make(chan videoFrame, videoBuffer), done: make(chan struct{})}
+	now := time.Now()
+	for range videoBuffer {
+		require.True(t, rec.add([]byte("x"), now))
+	}
+	assert.False(t, rec.add([]byte("x"), now))
+	assert.False(t, rec.add([]byte("x"), now))
+	assert.Equal(t, 2, rec.dropped)
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		out   string
+		codec string
+	}{
+		{name: "webm", out: "demo.webm", codec: "libvpx-vp9"},
+		{name: "mp4", out: "out/demo.MP4", codec: "libx264"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := ffmpegArgs("/tmp/frames.txt", tt.out)
+			joined := strings.Join(args, " ")
+			assert.Contains(t, joined, "-f concat -safe 0 -i /tmp/frames.txt")
+			assert.Contains(t, joined, "-c:v "+tt.codec)
+			assert.Equal(t, tt.out, args[len(args)-1])
+		})
+	}
+}
+
+func TestCapturer_runSession_Video(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "videos", "demo.webm")
+	c := newFakeCapturer(t, &config.Config{
+		Video:              out,
+		ScreenshotInterval: time.Millisecond,
+		Actions: []script.Action{
+			{Kind: script.ActionSleep, Duration: 30 * time.Millisecond},
+		},
+	})
+
+	var mu sync.Mutex
+	stopped := false
+	c.screencast = func(ctx context.Context, onFrame func([]byte)) (func(), error) {
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ticker := time.NewTicker(5 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					onFrame([]byte("jpeg"))
+				}
+			}
+		}()
+		return func() {
+			close(stop)
+			<-done
+			mu.Lock()
+			stopped = true
+			mu.Unlock()
+		}, nil
+	}
+	var list string
+	var frames []string
+	c.encodeVideo = func(_ context.Context, l, o string) error {
+		list = l
+		data, err := os.ReadFile(l)
+		require.NoError(t, err)
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "file ") {
+				frames = append(frames, line)
+			}
+		}
+		return os.WriteFile(o, []byte("video"), 0o644)
+	}
+	enc := &recordingEncoder{}
+	c.encoder = enc
+
+	ctx := context.Background()
+	require.NoError(t, c.runSession(ctx, ctx))
+
+	mu.Lock()
+	assert.True(t, stopped, "screencast stopped")
+	mu.Unlock()
+	assert.Greater(t, len(frames), 2, "frames were recorded")
+	assert.Equal(t, out, c.Video())
+	assert.FileExists(t, out)
+	assert.NoFileExists(t, list, "frame directory removed")
+	assert.Len(t, enc.frames, 2, "no interval frames while recording")
+}
+
+func TestCapturer_runSession_VideoNotEncodedOnFailure(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{
+		Video: filepath.Join(t.TempDir(), "demo.mp4"),
+		Actions: []script.Action{
+			{Kind: script.ActionKey, Key: "Enter", Repeat: 1},
+		},
+	})
+	c.sendKey = func(context.Context, string) error { return errors.New("browser went away") }
+	c.screencast = func(context.Context, func([]byte)) (func(), error) { return func() {}, nil }
+	encoded := false
+	c.encodeVideo = func(context.Context, string, string) error {
+		encoded = true
+		return nil
+	}
+
+	ctx := context.Background()
+	require.Error(t, c.runSession(ctx, ctx))
+	assert.False(t, encoded)
+	assert.Empty(t, c.Video())
+}
+
+func TestCapturer_runSession_ScreencastError(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{Video: "demo.webm"})
+	c.screencast = func(context.Context, func([]byte)) (func(), error) {
+		return nil, errors.New("not supported")
+	}
+
+	ctx := context.Background()
+	err := c.runSession(ctx, ctx)
+	assert.ErrorContains(t, err, "start screencast: not supported")
+}
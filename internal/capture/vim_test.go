@@ -0,0 +1,62 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+// TestCapturer_Run_Vim runs testdata/vim.tape end to end with a real ttyd,
+// Chrome and vim: it waits for vim's alternate screen, types into it, leaves
+// insert mode with Escape and saves.
+func TestCapturer_Run_Vim(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs ttyd, Chrome and vim")
+	}
+	for _, program := range []string{"ttyd", "vim", "bash"} {
+		if _, err := exec.LookPath(program); err != nil {
+			t.Skipf("%s not found in PATH", program)
+		}
+	}
+	if _, err := findChrome(""); err != nil {
+		t.Skipf("Chrome not found: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "note.txt")
+	src, err := script.ReadFile(filepath.Join("testdata", "vim.tape"))
+	require.NoError(t, err)
+	parsed, err := script.ParseScript(src, map[string]string{"FILE": file})
+	require.NoError(t, err)
+
+	out := t.TempDir()
+	cfg := &config.Config{
+		Command:     "bash --norc --noprofile",
+		Shell:       "bash",
+		OutputDir:   out,
+		TTydPort:    7681,
+		AutoPort:    true,
+		Timeout:     time.Minute,
+		Actions:     parsed.Actions,
+		Script:      src,
+		EscapeDelay: config.DefaultEscapeDelay,
+	}
+	require.NoError(t, cfg.Validate())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	require.NoError(t, NewCapturer(cfg).Run(ctx))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "hello from scr\n", string(data))
+	assert.FileExists(t, filepath.Join(out, "final.png"))
+}
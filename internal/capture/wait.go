@@ -0,0 +1,112 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+// waitPollInterval is how often a Wait action re-reads the terminal text.
+const waitPollInterval = 100 * time.Millisecond
+
+// waitTailLines bounds how much of the terminal is quoted in a Wait timeout error.
+const waitTailLines = 10
+
+// wait polls the terminal text until action.Pattern matches, with
+// action.Prompt until the shell shows its prompt, or with action.AltScreen
+// until the terminal switches to its alternate screen, or action.Timeout
+// elapses. The pattern is matched in multi-line mode, so ^ and $ anchor to
+// terminal lines. On timeout the error quotes the last lines seen, so a
+// failing script shows what the terminal printed instead.
+func (p *Player) wait(ctx context.Context, action script.Action, index int) error {
+	var ready func(ctx context.Context, text string) (bool, error)
+	var what string
+	if action.Prompt {
+		var err error
+		ready, what, err = p.promptWait()
+		if err != nil {
+			return fmt.Errorf("wait action %d: %w", index, err)
+		}
+	} else if action.AltScreen {
+		ready = func(ctx context.Context, _ string) (bool, error) {
+			alt, err := p.Driver.ReadAltScreen(ctx)
+			if err != nil {
+				return false, fmt.Errorf("read screen buffer: %w", err)
+			}
+			return alt, nil
+		}
+		what = "alternate screen"
+	} else {
+		re, err := regexp.Compile("(?m)" + action.Pattern)
+		if err != nil {
+			return fmt.Errorf("wait action %d: invalid pattern: %w", index, err)
+		}
+		ready = func(_ context.Context, text string) (bool, error) { return re.MatchString(text), nil }
+		what = fmt.Sprintf("/%s/", action.Pattern)
+	}
+
+	p.logf("Waiting up to %v for %s (action %d)\n", action.Timeout, what, index)
+
+	timer := time.NewTimer(action.Timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		text, err := p.Driver.ReadText(ctx)
+		if err != nil {
+			return fmt.Errorf("wait action %d: read terminal: %w", index, err)
+		}
+		last = text
+		ok, err := ready(ctx, text)
+		if err != nil {
+			return fmt.Errorf("wait action %d: %w", index, err)
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("wait action %d: %s did not appear within %v; last terminal output:\n%s",
+				index, what, action.Timeout, tail(last, waitTailLines))
+		case <-ticker.C:
+		}
+	}
+}
+
+// promptWait builds the check a Wait Prompt polls: the one set by Capturer,
+// or a match of PromptPattern against the last non-blank line.
+func (p *Player) promptWait() (ready func(ctx context.Context, text string) (bool, error), what string, err error) {
+	if p.prompt != nil {
+		return p.prompt()
+	}
+	pattern, what := defaultPromptPattern, "prompt"
+	if p.PromptPattern != "" {
+		pattern = p.PromptPattern
+		what = fmt.Sprintf("prompt /%s/", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid prompt pattern: %w", err)
+	}
+	return func(_ context.Context, text string) (bool, error) {
+		return re.MatchString(lastLine(text)), nil
+	}, what, nil
+}
+
+// tail returns the last n lines of text, ignoring trailing blank lines.
+func tail(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, " \n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
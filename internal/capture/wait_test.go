@@ -0,0 +1,139 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestPlayer_wait(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []string
+		readErr error
+		action  script.Action
+		wantErr string
+	}{
+		{
+			name:    "matches immediately",
+			outputs: []string{"$ make\nBuild ok\n$ "},
+			action:  script.Action{Kind: script.ActionWait, Pattern: `Build (ok|done)`, Timeout: time.Second},
+		},
+		{
+			name:    "matches after output appears",
+			outputs: []string{"$ make", "$ make\ncompiling", "$ make\ncompiling\ndone"},
+			action:  script.Action{Kind: script.ActionWait, Pattern: `^done$`, Timeout: time.Second},
+		},
+		{
+			name:    "times out with last output",
+			outputs: []string{"$ make\nerror: missing target\n\n"},
+			action:  script.Action{Kind: script.ActionWait, Pattern: `Build ok`, Timeout: 250 * time.Millisecond},
+			wantErr: "wait action 0: /Build ok/ did not appear within 250ms; last terminal output:\n$ make\nerror: missing target",
+		},
+		{
+			name:    "read error",
+			readErr: errors.New("page closed"),
+			action:  script.Action{Kind: script.ActionWait, Pattern: `x`, Timeout: time.Second},
+			wantErr: "wait action 0: read terminal: page closed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{})
+			reads := 0
+			c.readText = func(context.Context) (string, error) {
+				if tt.readErr != nil {
+					return "", tt.readErr
+				}
+				out := tt.outputs[min(reads, len(tt.outputs)-1)]
+				reads++
+				return out, nil
+			}
+
+			err := c.player(context.Background()).do(context.Background(), tt.action, 0)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPlayer_wait_ContextCancelled(t *testing.T) {
+	c := newFakeCapturer(t, &config.Config{})
+	c.readText = func(context.Context) (string, error) { return "", nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := c.player(context.Background()).do(ctx,
+		script.Action{Kind: script.ActionWait, Pattern: "never", Timeout: time.Minute}, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPlayer_wait_AltScreen(t *testing.T) {
+	tests := []struct {
+		name    string
+		screens []bool
+		readErr error
+		wantErr string
+	}{
+		{name: "already on the alternate screen", screens: []bool{true}},
+		{name: "switches after a while", screens: []bool{false, false, true}},
+		{
+			name:    "times out",
+			screens: []bool{false},
+			wantErr: "wait action 2: alternate screen did not appear within 250ms; last terminal output:\n$ vim",
+		},
+		{
+			name:    "read error",
+			readErr: errors.New("page closed"),
+			wantErr: "wait action 2: read screen buffer: page closed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeCapturer(t, &config.Config{})
+			c.readText = func(context.Context) (string, error) { return "$ vim\n", nil }
+			reads := 0
+			c.readAltScreen = func(context.Context) (bool, error) {
+				if tt.readErr != nil {
+					return false, tt.readErr
+				}
+				alt := tt.screens[min(reads, len(tt.screens)-1)]
+				reads++
+				return alt, nil
+			}
+
+			err := c.player(context.Background()).do(context.Background(),
+				script.Action{Kind: script.ActionWait, AltScreen: true, Timeout: 250 * time.Millisecond}, 2)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.screens), reads)
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	text := strings.Repeat("line\n", 20) + "last\n\n  \n"
+	got := tail(text, 3)
+	assert.Equal(t, "line\nline\nlast", got)
+	assert.Equal(t, "only", tail("only", 3))
+}
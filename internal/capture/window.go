@@ -0,0 +1,336 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/yarlson/scr/internal/theme"
+)
+
+// Window chrome geometry, in pixels: the title bar height, the corner
+// radius, and the traffic-light dots' radius, the center of the first and
+// the distance between centers. Title text is drawn from a 5x7 bitmap font
+// scaled by windowTitleScale.
+const (
+	windowBarHeight  = 28
+	windowRadius     = 10
+	windowDotRadius  = 6
+	windowDotStart   = 18
+	windowDotSpacing = 20
+	windowTitleScale = 2
+)
+
+// windowDots are the close, minimize and zoom buttons, left to right.
+var windowDots = []color.NRGBA{
+	{R: 0xff, G: 0x5f, B: 0x57, A: 0xff},
+	{R: 0xfe, G: 0xbc, B: 0x2e, A: 0xff},
+	{R: 0x28, G: 0xc8, B: 0x40, A: 0xff},
+}
+
+// windowFrame draws window chrome, with Config.Title in its bar, around
+// the PNG data. The corners show Config.Background, or are transparent
+// when none is set.
+func (c *Capturer) windowFrame(data []byte) ([]byte, error) {
+	var bg color.Color
+	if c.config.Background != "" {
+		rgba, err := theme.ParseColor(c.config.Background)
+		if err != nil {
+			return nil, fmt.Errorf("bg: %w", err)
+		}
+		bg = rgba
+	}
+	return windowPNG(data, c.config.Title, bg)
+}
+
+// windowPNG returns the PNG data in a window with rounded corners and a
+// title bar holding the traffic-light dots and title. The bar is a shade
+// of the image's top-left pixel, the terminal background, so it suits
+// light and dark themes alike. Outside the rounded corners the image is bg,
+// or transparent when bg is nil.
+func windowPNG(data []byte, title string, bg color.Color) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode frame: %w", err)
+	}
+	if bg == nil {
+		bg = color.Transparent
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()+windowBarHeight
+	terminal := color.NRGBAModel.Convert(src.At(bounds.Min.X, bounds.Min.Y)).(color.NRGBA)
+	bar, ink := windowColors(terminal)
+
+	win := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(win, image.Rect(0, 0, w, windowBarHeight), &image.Uniform{C: bar}, image.Point{}, draw.Src)
+	draw.Draw(win, image.Rect(0, windowBarHeight, w, h), src, bounds.Min, draw.Src)
+	for i, dot := range windowDots {
+		fillCircle(win, windowDotStart+i*windowDotSpacing, windowBarHeight/2, windowDotRadius, dot)
+	}
+	drawTitle(win, title, ink)
+
+	// Round the corners by blending each corner pixel over bg by how much
+	// of it lies inside the window
+	dst := image.NewNRGBA(win.Bounds())
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cover := roundedCoverage(x, y, w, h, windowRadius)
+			if cover == 0 {
+				continue
+			}
+			px := win.NRGBAAt(x, y)
+			if cover < 1 {
+				px = blendOver(px, cover, color.NRGBAModel.Convert(bg).(color.NRGBA))
+			}
+			dst.SetNRGBA(x, y, px)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// windowColors returns the title bar color for a terminal background, a
+// little lighter on dark themes and darker on light ones, and the color of
+// the title drawn on it.
+func windowColors(terminal color.NRGBA) (bar, ink color.NRGBA) {
+	white := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black := color.NRGBA{A: 0xff}
+	luma := 0.2126*float64(terminal.R) + 0.7152*float64(terminal.G) + 0.0722*float64(terminal.B)
+	if luma < 128 {
+		bar = blendOver(white, 0.12, terminal)
+		return bar, blendOver(white, 0.6, bar)
+	}
+	bar = blendOver(black, 0.08, terminal)
+	return bar, blendOver(black, 0.6, bar)
+}
+
+// blendOver returns fg covering a fraction cover of bg.
+func blendOver(fg color.NRGBA, cover float64, bg color.NRGBA) color.NRGBA {
+	fa := cover * float64(fg.A) / 0xff
+	ba := float64(bg.A) / 0xff * (1 - fa)
+	a := fa + ba
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	mix := func(f, b uint8) uint8 {
+		return uint8((float64(f)*fa+float64(b)*ba)/a + 0.5)
+	}
+	return color.NRGBA{R: mix(fg.R, bg.R), G: mix(fg.G, bg.G), B: mix(fg.B, bg.B), A: uint8(a*0xff + 0.5)}
+}
+
+// coverageSamples is how many sub-pixel samples per axis antialias the
+// window's corners and dots.
+const coverageSamples = 4
+
+// roundedCoverage returns how much of pixel (x, y) lies inside a w by h
+// rectangle whose corners are rounded with radius r.
+func roundedCoverage(x, y, w, h, r int) float64 {
+	// Only pixels in a corner square can be partly outside
+	cx, cy := -1, -1
+	switch {
+	case x < r:
+		cx = r
+	case x >= w-r:
+		cx = w - r
+	}
+	switch {
+	case y < r:
+		cy = r
+	case y >= h-r:
+		cy = h - r
+	}
+	if cx < 0 || cy < 0 {
+		return 1
+	}
+	return circleCoverage(x, y, float64(cx), float64(cy), float64(r))
+}
+
+// circleCoverage returns how much of pixel (x, y) lies inside the circle
+// of radius r around (cx, cy).
+func circleCoverage(x, y int, cx, cy, r float64) float64 {
+	inside := 0
+	for sy := range coverageSamples {
+		for sx := range coverageSamples {
+			dx := float64(x) + (float64(sx)+0.5)/coverageSamples - cx
+			dy := float64(y) + (float64(sy)+0.5)/coverageSamples - cy
+			if dx*dx+dy*dy <= r*r {
+				inside++
+			}
+		}
+	}
+	return float64(inside) / (coverageSamples * coverageSamples)
+}
+
+// fillCircle draws an antialiased circle of radius r centered on the
+// middle of pixel (cx, cy).
+func fillCircle(img *image.NRGBA, cx, cy, r int, c color.NRGBA) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+				continue
+			}
+			if cover := circleCoverage(x, y, float64(cx)+0.5, float64(cy)+0.5, float64(r)); cover > 0 {
+				img.SetNRGBA(x, y, blendOver(c, cover, img.NRGBAAt(x, y)))
+			}
+		}
+	}
+}
+
+// drawTitle writes title centered in the window's title bar, in ink. A
+// title too long for the space between the dots and the right edge is cut
+// short with "...".
+func drawTitle(img *image.NRGBA, title string, ink color.NRGBA) {
+	if title == "" {
+		return
+	}
+	advance := (glyphWidth + 1) * windowTitleScale
+	left := windowDotStart + (len(windowDots)-1)*windowDotSpacing + windowDotRadius + advance
+	room := (img.Bounds().Dx() - 2*left) / advance
+	if room <= 0 {
+		return
+	}
+	if len(title) > room {
+		if room > 3 {
+			title = title[:room-3] + "..."
+		} else {
+			title = title[:room]
+		}
+	}
+
+	width := len(title)*advance - windowTitleScale
+	x0 := (img.Bounds().Dx() - width) / 2
+	y0 := (windowBarHeight - glyphHeight*windowTitleScale) / 2
+	for i := 0; i < len(title); i++ {
+		ch := title[i]
+		if ch < ' ' || ch > '~' {
+			ch = '?'
+		}
+		glyph := glyphs[ch-' ']
+		for col, bits := range glyph {
+			for row := range glyphHeight {
+				if bits&(1<<row) == 0 {
+					continue
+				}
+				x := x0 + i*advance + col*windowTitleScale
+				y := y0 + row*windowTitleScale
+				draw.Draw(img, image.Rect(x, y, x+windowTitleScale, y+windowTitleScale), &image.Uniform{C: ink}, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+// glyphWidth and glyphHeight are the size of a glyph in glyphs.
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs is a 5x7 bitmap font for the printable ASCII characters, from ' '
+// to '~'. Each glyph is five columns, left to right, with the top row in
+// the lowest bit.
+var glyphs = [95][glyphWidth]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5f, 0x00, 0x00}, // '!'
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // '"'
+	{0x14, 0x7f, 0x14, 0x7f, 0x14}, // '#'
+	{0x24, 0x2a, 0x7f, 0x2a, 0x12}, // '$'
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // '%'
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // '&'
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '\''
+	{0x00, 0x1c, 0x22, 0x41, 0x00}, // '('
+	{0x00, 0x41, 0x22, 0x1c, 0x00}, // ')'
+	{0x08, 0x2a, 0x1c, 0x2a, 0x08}, // '*'
+	{0x08, 0x08, 0x3e, 0x08, 0x08}, // '+'
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ','
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // '-'
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // '.'
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // '/'
+	{0x3e, 0x51, 0x49, 0x45, 0x3e}, // '0'
+	{0x00, 0x42, 0x7f, 0x40, 0x00}, // '1'
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // '2'
+	{0x21, 0x41, 0x45, 0x4b, 0x31}, // '3'
+	{0x18, 0x14, 0x12, 0x7f, 0x10}, // '4'
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // '5'
+	{0x3c, 0x4a, 0x49, 0x49, 0x30}, // '6'
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // '7'
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // '8'
+	{0x06, 0x49, 0x49, 0x29, 0x1e}, // '9'
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // ':'
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ';'
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // '<'
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // '='
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // '>'
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // '?'
+	{0x32, 0x49, 0x79, 0x41, 0x3e}, // '@'
+	{0x7e, 0x11, 0x11, 0x11, 0x7e}, // 'A'
+	{0x7f, 0x49, 0x49, 0x49, 0x36}, // 'B'
+	{0x3e, 0x41, 0x41, 0x41, 0x22}, // 'C'
+	{0x7f, 0x41, 0x41, 0x22, 0x1c}, // 'D'
+	{0x7f, 0x49, 0x49, 0x49, 0x41}, // 'E'
+	{0x7f, 0x09, 0x09, 0x09, 0x01}, // 'F'
+	{0x3e, 0x41, 0x49, 0x49, 0x7a}, // 'G'
+	{0x7f, 0x08, 0x08, 0x08, 0x7f}, // 'H'
+	{0x00, 0x41, 0x7f, 0x41, 0x00}, // 'I'
+	{0x20, 0x40, 0x41, 0x3f, 0x01}, // 'J'
+	{0x7f, 0x08, 0x14, 0x22, 0x41}, // 'K'
+	{0x7f, 0x40, 0x40, 0x40, 0x40}, // 'L'
+	{0x7f, 0x02, 0x0c, 0x02, 0x7f}, // 'M'
+	{0x7f, 0x04, 0x08, 0x10, 0x7f}, // 'N'
+	{0x3e, 0x41, 0x41, 0x41, 0x3e}, // 'O'
+	{0x7f, 0x09, 0x09, 0x09, 0x06}, // 'P'
+	{0x3e, 0x41, 0x51, 0x21, 0x5e}, // 'Q'
+	{0x7f, 0x09, 0x19, 0x29, 0x46}, // 'R'
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // 'S'
+	{0x01, 0x01, 0x7f, 0x01, 0x01}, // 'T'
+	{0x3f, 0x40, 0x40, 0x40, 0x3f}, // 'U'
+	{0x1f, 0x20, 0x40, 0x20, 0x1f}, // 'V'
+	{0x3f, 0x40, 0x38, 0x40, 0x3f}, // 'W'
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // 'X'
+	{0x07, 0x08, 0x70, 0x08, 0x07}, // 'Y'
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // 'Z'
+	{0x00, 0x7f, 0x41, 0x41, 0x00}, // '['
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // '\\'
+	{0x00, 0x41, 0x41, 0x7f, 0x00}, // ']'
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // '^'
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // '_'
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // '`'
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // 'a'
+	{0x7f, 0x48, 0x44, 0x44, 0x38}, // 'b'
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // 'c'
+	{0x38, 0x44, 0x44, 0x48, 0x7f}, // 'd'
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // 'e'
+	{0x08, 0x7e, 0x09, 0x01, 0x02}, // 'f'
+	{0x0c, 0x52, 0x52, 0x52, 0x3e}, // 'g'
+	{0x7f, 0x08, 0x04, 0x04, 0x78}, // 'h'
+	{0x00, 0x44, 0x7d, 0x40, 0x00}, // 'i'
+	{0x20, 0x40, 0x44, 0x3d, 0x00}, // 'j'
+	{0x7f, 0x10, 0x28, 0x44, 0x00}, // 'k'
+	{0x00, 0x41, 0x7f, 0x40, 0x00}, // 'l'
+	{0x7c, 0x04, 0x18, 0x04, 0x78}, // 'm'
+	{0x7c, 0x08, 0x04, 0x04, 0x78}, // 'n'
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // 'o'
+	{0x7c, 0x14, 0x14, 0x14, 0x08}, // 'p'
+	{0x08, 0x14, 0x14, 0x18, 0x7c}, // 'q'
+	{0x7c, 0x08, 0x04, 0x04, 0x08}, // 'r'
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // 's'
+	{0x04, 0x3f, 0x44, 0x40, 0x20}, // 't'
+	{0x3c, 0x40, 0x40, 0x20, 0x7c}, // 'u'
+	{0x1c, 0x20, 0x40, 0x20, 0x1c}, // 'v'
+	{0x3c, 0x40, 0x30, 0x40, 0x3c}, // 'w'
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // 'x'
+	{0x0c, 0x50, 0x50, 0x50, 0x3c}, // 'y'
+	{0x44, 0x64, 0x54, 0x4c, 0x44}, // 'z'
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // '{'
+	{0x00, 0x00, 0x7f, 0x00, 0x00}, // '|'
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // '}'
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // '~'
+}
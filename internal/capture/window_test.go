@@ -0,0 +1,152 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/config"
+)
+
+// windowTerminalPNG is a 240x40 dark frame with a light "glyph" block, big
+// enough for the dots and a short title.
+func windowTerminalPNG(t *testing.T) []byte {
+	t.Helper()
+	return framePNG(t, 240, 40, func(x, y int) color.Color {
+		if x >= 10 && x < 16 && y >= 10 && y < 20 {
+			return color.NRGBA{R: 0xf8, G: 0xf8, B: 0xf2, A: 0xff}
+		}
+		return color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}
+	})
+}
+
+func TestWindowPNG_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		bg     color.Color
+		golden string
+	}{
+		{name: "transparent corners", golden: "window.golden.png"},
+		{name: "title and background", title: "demo", bg: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, golden: "window_title.golden.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := windowPNG(windowTerminalPNG(t), tt.title, tt.bg)
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "rerun with -update to accept changes")
+
+			img, err := png.Decode(bytes.NewReader(got))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, 240, 40+windowBarHeight), img.Bounds())
+		})
+	}
+}
+
+func TestWindowPNG_Pixels(t *testing.T) {
+	got, err := windowPNG(windowTerminalPNG(t), "", nil)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(got))
+	require.NoError(t, err)
+
+	at := func(x, y int) color.NRGBA { return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA) }
+	bar, _ := windowColors(color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff})
+	assert.Equal(t, color.NRGBA{}, at(0, 0), "corner outside the window")
+	assert.Equal(t, color.NRGBA{}, at(239, 67), "corner outside the window")
+	assert.Equal(t, bar, at(120, 2), "title bar")
+	assert.Equal(t, windowDots[0], at(windowDotStart, windowBarHeight/2), "close button")
+	assert.Equal(t, windowDots[2], at(windowDotStart+2*windowDotSpacing, windowBarHeight/2), "zoom button")
+	assert.Equal(t, color.NRGBA{R: 0xf8, G: 0xf8, B: 0xf2, A: 0xff}, at(12, 12+windowBarHeight), "frame moved below the bar")
+	assert.Equal(t, color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}, at(80, 50))
+
+	_, err = windowPNG([]byte("not a png"), "", nil)
+	assert.ErrorContains(t, err, "decode frame")
+}
+
+func TestWindowColors(t *testing.T) {
+	dark := color.NRGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}
+	bar, ink := windowColors(dark)
+	assert.Greater(t, bar.G, dark.G, "lighter bar on a dark theme")
+	assert.Greater(t, ink.G, bar.G)
+
+	light := color.NRGBA{R: 0xfd, G: 0xf6, B: 0xe3, A: 0xff}
+	bar, ink = windowColors(light)
+	assert.Less(t, bar.G, light.G, "darker bar on a light theme")
+	assert.Less(t, ink.G, bar.G)
+}
+
+func TestDrawTitle_Truncates(t *testing.T) {
+	inked := func(title string) int {
+		img := image.NewNRGBA(image.Rect(0, 0, 200, windowBarHeight))
+		drawTitle(img, title, color.NRGBA{R: 0xff, A: 0xff})
+		n := 0
+		for y := range windowBarHeight {
+			for x := range 200 {
+				if img.NRGBAAt(x, y).A != 0 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	assert.Zero(t, inked(""))
+	assert.Positive(t, inked("demo"))
+	// 200 pixels leave room for four characters beside the dots
+	assert.Equal(t, inked("a..."), inked("a very long title that cannot fit"))
+}
+
+func TestCapturer_runSession_Window(t *testing.T) {
+	frame := windowTerminalPNG(t)
+
+	tests := []struct {
+		name    string
+		padding int
+		bg      string
+		// want is the top-left pixel of every frame written.
+		want color.NRGBA
+	}{
+		{name: "window alone", want: color.NRGBA{}},
+		{name: "window in transparent padding", padding: 8, want: color.NRGBA{}},
+		{name: "window on a background", padding: 8, bg: "#fff", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := newFakeCapturer(t, &config.Config{OutputDir: dir, Window: true, Title: "demo", Padding: tt.padding, Background: tt.bg})
+			c.captureFrame = func(context.Context) ([]byte, error) { return frame, nil }
+			c.encoder = &fileEncoder{}
+
+			ctx := context.Background()
+			require.NoError(t, c.runSession(ctx, ctx))
+
+			for _, name := range []string{"screenshot_001.png", "screenshot_002.png"} {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				img, err := png.Decode(bytes.NewReader(data))
+				require.NoError(t, err)
+				assert.Equal(t, image.Rect(0, 0, 240+2*tt.padding, 40+windowBarHeight+2*tt.padding), img.Bounds(), name)
+				assert.Equal(t, tt.want, color.NRGBAModel.Convert(img.At(0, 0)), name)
+				// The window sits inside the padding
+				assert.Equal(t, windowDots[0], color.NRGBAModel.Convert(img.At(tt.padding+windowDotStart, tt.padding+windowBarHeight/2)), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BatchEntry is one capture of a batch; see ParseBatch.
+type BatchEntry struct {
+	// Command is the command the script runs in; empty for the default.
+	Command string `yaml:"command"`
+	// Script is the path of the script file.
+	Script string `yaml:"script"`
+	// Out is the capture's subdirectory of the output directory; it
+	// defaults to the script file's name without its extension.
+	Out string `yaml:"out"`
+	// Index is the entry's position in the batch, from 0.
+	Index int `yaml:"-"`
+}
+
+// ParseBatch parses a batch manifest: a YAML list of captures, each with
+// the script file it runs, and optionally the command it runs in and the
+// output subdirectory its frames go to:
+//
+//	# demos.yaml
+//	- script: demos/ls.tape
+//	- command: htop
+//	  script: demos/htop.tape
+//	  out: top
+func ParseBatch(data []byte) ([]BatchEntry, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var entries []BatchEntry
+	if err := dec.Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return checkBatch(entries)
+}
+
+// ScriptBatch returns a batch that runs each of the script files in the
+// default command.
+func ScriptBatch(paths []string) ([]BatchEntry, error) {
+	entries := make([]BatchEntry, len(paths))
+	for i, path := range paths {
+		entries[i].Script = path
+	}
+	return checkBatch(entries)
+}
+
+// checkBatch numbers the entries and fills in their output subdirectories,
+// which must be distinct.
+func checkBatch(entries []BatchEntry) ([]BatchEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("batch has no entries")
+	}
+
+	seen := map[string]int{}
+	for i, entry := range entries {
+		entries[i].Index = i
+		if entry.Script == "" {
+			return nil, fmt.Errorf("entry %d: script is required", i+1)
+		}
+		if entry.Out == "" {
+			base := filepath.Base(entry.Script)
+			entries[i].Out = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		if !filepath.IsLocal(entries[i].Out) {
+			return nil, fmt.Errorf("entry %d: out must be a relative path inside the output directory, got %q", i+1, entries[i].Out)
+		}
+		out := filepath.Clean(entries[i].Out)
+		if prev, ok := seen[out]; ok {
+			return nil, fmt.Errorf("entry %d: out %q is already used by entry %d; give one of them another out", i+1, entries[i].Out, prev)
+		}
+		seen[out] = i + 1
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []BatchEntry
+		wantErr  string
+	}{
+		{
+			name:     "out defaults to the script name",
+			manifest: "- script: demos/ls.tape\n- command: htop\n  script: demos/htop.tape\n  out: top\n",
+			want: []BatchEntry{
+				{Script: "demos/ls.tape", Out: "ls"},
+				{Command: "htop", Script: "demos/htop.tape", Out: "top", Index: 1},
+			},
+		},
+		{name: "script without extension", manifest: "- script: demo\n", want: []BatchEntry{{Script: "demo", Out: "demo"}}},
+		{name: "empty", manifest: "", wantErr: "batch has no entries"},
+		{name: "not a list", manifest: "script: ls.tape\n", wantErr: "cannot unmarshal"},
+		{name: "unknown field", manifest: "- script: ls.tape\n  cmd: bash\n", wantErr: "field cmd not found"},
+		{name: "missing script", manifest: "- command: bash\n", wantErr: "entry 1: script is required"},
+		{name: "absolute out", manifest: "- script: ls.tape\n  out: /tmp/ls\n", wantErr: `entry 1: out must be a relative path inside the output directory, got "/tmp/ls"`},
+		{
+			name:     "same script name twice",
+			manifest: "- script: a/demo.tape\n- script: b/demo.tape\n",
+			wantErr:  `entry 2: out "demo" is already used by entry 1; give one of them another out`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBatch([]byte(tt.manifest))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScriptBatch(t *testing.T) {
+	got, err := ScriptBatch([]string{"demos/ls.tape", "demos/git.log.tape"})
+	require.NoError(t, err)
+	assert.Equal(t, []BatchEntry{
+		{Script: "demos/ls.tape", Out: "ls"},
+		{Script: "demos/git.log.tape", Out: "git.log", Index: 1},
+	}, got)
+
+	_, err = ScriptBatch(nil)
+	assert.EqualError(t, err, "batch has no entries")
+}
@@ -2,16 +2,42 @@ package config
 
 import (
 	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/spf13/cobra"
 
 	"github.com/yarlson/scr/internal/script"
+	"github.com/yarlson/scr/internal/theme"
 )
 
+// Default browser viewport size in CSS pixels, used when Width or Height is zero.
+const (
+	DefaultWidth  = 1280
+	DefaultHeight = 720
+)
+
+// Shells are the shells that can wrap Command; the first is the default.
+var Shells = []string{"bash", "sh", "zsh", "fish"}
+
 // Config holds the configuration for the TUI screen capture application.
 type Config struct {
-	Command            string
+	// Command is a shell command line, run with Shell.
+	Command string
+	// CommandArgs runs a program directly, without a shell, as argv. It
+	// replaces Command.
+	CommandArgs []string
+	// Shell wraps Command; empty means the first of Shells.
+	Shell              string
 	Keypresses         []string
 	Delays             []time.Duration
 	OutputDir          string
@@ -21,6 +47,276 @@ type Config struct {
 	Verbose            bool
 	Actions            []script.Action
 	Script             string
+	// Params are the script's declared parameters with their resolved
+	// values, for the manifest.
+	Params []script.Param
+	// NoCaptureWhileTyping suppresses interval screenshots during Type
+	// actions and takes one frame after each action's delay instead.
+	NoCaptureWhileTyping bool
+	// Dedup skips interval frames that are byte-identical to the previous
+	// frame. Skipped frames are still recorded in the run's stats.
+	Dedup bool
+	// FrameDiff measures how much each frame changed from the previous one
+	// and records it in the run's stats. Verbose implies it.
+	FrameDiff bool
+	// OutTmp writes frames to a temporary directory and moves them into
+	// OutputDir when the run ends, so the captured command never sees them.
+	OutTmp bool
+	// Width and Height set the browser viewport in CSS pixels; zero means
+	// DefaultWidth and DefaultHeight.
+	Width  int
+	Height int
+	// Cols and Rows fix the terminal size in character cells; zero fits the
+	// terminal to the viewport.
+	Cols int
+	Rows int
+	// NameTemplate names sequential screenshots; see ParseNameTemplate.
+	// Empty means DefaultNameTemplate.
+	NameTemplate string
+	// Theme is a built-in theme name or the path of a JSON theme file;
+	// empty keeps ttyd's default colors.
+	Theme string
+	// Format names the output encoder; empty means PNG files.
+	Format string
+	// FrameDelay fixes the delay between frames in animated formats,
+	// except within a Burst; zero uses the real time between captures.
+	FrameDelay time.Duration
+	// KeepFrames also writes the individual PNG frames when an animated
+	// format is selected.
+	KeepFrames bool
+	// LogFile writes ttyd's output and the Chrome DevTools Protocol trace to
+	// this file; empty disables it. The file is rotated when it reaches
+	// LogMaxSize bytes (zero means logfile.DefaultMaxSize), keeping LogKeep
+	// rotated files.
+	LogFile    string
+	LogMaxSize int64
+	LogKeep    int
+	// AutoPort has ttyd listen on a free port instead of TTydPort, which
+	// may then be zero. It is set when the port was not chosen explicitly.
+	AutoPort bool
+	// TerminalURL attaches to an already running ttyd instead of starting
+	// one; Command must then be empty.
+	TerminalURL string
+	// ExitOnDone ends the capture as soon as the command exits, taking the
+	// final frame then; a non-zero exit code is reported as an error.
+	ExitOnDone bool
+	// Video records the page continuously with the DevTools screencast and
+	// encodes it to this .webm or .mp4 file with ffmpeg; empty disables it.
+	// Interval screenshots are off while recording.
+	Video string
+	// ChromePath is the Chrome or Chromium executable to launch; empty
+	// searches the usual names and install locations.
+	ChromePath string
+	// ChromeFlags are extra command-line flags for Chrome, such as
+	// "--no-sandbox" or "--proxy-server=host:8080".
+	ChromeFlags []string
+	// ChromeProfile is a persistent Chrome user data directory reused
+	// across runs, created on first use, so Chrome starts faster; empty or
+	// "tmp" uses a throwaway profile.
+	ChromeProfile string
+	// SimulateCVD lists color vision deficiencies, from CVDSimulations, to
+	// simulate: each frame is also written as seen with each of them.
+	SimulateCVD []string
+	// Progress, if set, receives newline-delimited JSON progress events as
+	// the run goes. A consumer that falls behind loses events rather than
+	// slowing the capture down.
+	Progress io.Writer
+	// NoLock skips the lock file that stops two runs from writing to the
+	// same OutputDir at once.
+	NoLock bool
+	// SkipVersionCheck starts ttyd and the browser even when they are older
+	// than the versions scr supports, for patched builds.
+	SkipVersionCheck bool
+	// FrameHook is a shell command run for every frame file written, with
+	// {file}, {index} and {elapsed} replaced by the frame's path, its
+	// number in the run and its offset in milliseconds; empty disables it.
+	// Failures are reported but do not fail the run unless FrameHookStrict
+	// is set.
+	FrameHook       string
+	FrameHookStrict bool
+	// PromptPattern, if set, replaces the shell's prompt profile for Wait
+	// Prompt: the prompt is showing when the last non-blank terminal line
+	// matches it.
+	PromptPattern string
+	// SystemFonts keeps the browser's own monospace font instead of
+	// switching the terminal to the font embedded in scr, which renders the
+	// same on every machine.
+	SystemFonts bool
+	// Env is added to the command's environment, as KEY=VALUE entries,
+	// after the TERM, COLORTERM and PS1 scr sets, so it can override them.
+	Env []string
+	// NoColorSession asks the command for monochrome output: it gets
+	// NO_COLOR=1 and TERM=xterm, and no COLORTERM. scr's own output is
+	// unaffected.
+	NoColorSession bool
+	// Grayscale converts every frame to grayscale before it is written, for
+	// commands that print colors regardless of NoColorSession.
+	Grayscale bool
+	// FontSize is the terminal font size in CSS pixels, from MinFontSize to
+	// MaxFontSize; zero keeps ttyd's default.
+	FontSize int
+	// FontFamily is a CSS font family, such as "JetBrains Mono, monospace",
+	// used instead of the embedded font; the fonts must be installed.
+	FontFamily string
+	// Padding adds that many pixels on every side of each frame, filled
+	// with Background, or with the terminal background when Background is
+	// empty; zero leaves frames as captured.
+	Padding int
+	// Background is the #rgb or #rrggbb color of the Padding, and of the
+	// corners outside a Window.
+	Background string
+	// EscapeDelay is how long to pause after each Escape keypress, so the
+	// program sees the Escape on its own and not as the start of an Alt
+	// sequence with the keys that follow; zero sends them back to back.
+	EscapeDelay time.Duration
+	// TypeChunkSize is the length, in characters, above which Type text is
+	// sent in chunks of at most that many, each checked to have reached the
+	// terminal before the next is sent; zero uses DefaultTypeChunkSize.
+	TypeChunkSize int
+	// NoTypeVerify sends long Type text in chunks without checking that
+	// they arrived, for programs that do not echo what is typed.
+	NoTypeVerify bool
+	// Window draws a window with rounded corners and a title bar with
+	// traffic-light dots around each frame, inside any Padding. Without a
+	// Background, the area outside the window is transparent.
+	Window bool
+	// Title is printed in the Window's title bar; it is limited to
+	// printable ASCII.
+	Title string
+	// Quality is the compression quality of jpeg and webp frames, from 1 to
+	// 100; zero uses DefaultQuality.
+	Quality int
+	// Selector is the CSS selector of the element captured in each frame,
+	// instead of the terminal element; empty captures the terminal.
+	Selector string
+	// Crop cuts each frame down to this rectangle, in pixels from the top
+	// left of the captured element, before Window and Padding are drawn;
+	// the zero rectangle keeps whole frames.
+	Crop image.Rectangle
+	// SSH runs ttyd on this remote host, written as host or user@host, over
+	// ssh, with its port forwarded to the local one the browser opens;
+	// empty runs ttyd locally.
+	SSH string
+	// FailOnError fails the capture with the command's exit code when the
+	// command has exited non-zero by the end of the script, once every
+	// frame is written. Unlike ExitOnDone, the exit does not end the
+	// capture early.
+	FailOnError bool
+	// TmuxPanes are the panes of the tmux session that Command starts, as
+	// laid out by TmuxCommand; Pane actions select them by name. Empty
+	// without a tmux layout.
+	TmuxPanes []TmuxPane
+	// Anonymize gives the command a neutral user, host name and prompt, and
+	// replaces the real user name, host name and home directory in the
+	// terminal text and manifest scr writes.
+	Anonymize bool
+	// BeforeHook is a shell command run on this machine before the
+	// capture starts, with the command's environment and working
+	// directory; when it fails, the run does not start. AfterHook is run
+	// once the capture has ended, whether or not it succeeded.
+	BeforeHook string
+	AfterHook  string
+	// TargetFrames, when positive, thins out the interval frames of an
+	// animated format to bring it to about this many frames. Other frames
+	// are always kept.
+	TargetFrames int
+	// NoProbe skips the check that the terminal echoes a typed space before
+	// the first action, for programs that do something with it.
+	NoProbe bool
+}
+
+// DefaultEscapeDelay is the EscapeDelay the command line uses by default.
+const DefaultEscapeDelay = 50 * time.Millisecond
+
+// DefaultTypeChunkSize is the TypeChunkSize used when none is given.
+const DefaultTypeChunkSize = 256
+
+// DefaultQuality is the Quality used when none is given.
+const DefaultQuality = 90
+
+// MinFontSize and MaxFontSize bound Config.FontSize.
+const (
+	MinFontSize = 6
+	MaxFontSize = 72
+)
+
+// VideoFormats are the file extensions Video may end in.
+var VideoFormats = []string{".webm", ".mp4"}
+
+// CVDSimulations are the color vision deficiencies SimulateCVD accepts.
+var CVDSimulations = []string{"protanopia", "deuteranopia", "tritanopia"}
+
+// validSSHDestination reports whether dest names an ssh host, optionally
+// with a user, and cannot be taken for an ssh option.
+func validSSHDestination(dest string) bool {
+	if strings.HasPrefix(dest, "-") || strings.ContainsFunc(dest, unicode.IsSpace) {
+		return false
+	}
+	user, host, found := strings.Cut(dest, "@")
+	if !found {
+		host = user
+	} else if user == "" {
+		return false
+	}
+	return host != "" && !strings.Contains(host, "@")
+}
+
+// ParseCrop parses a crop rectangle written as x,y,w,h: the pixel offset
+// of its top left corner and its width and height.
+func ParseCrop(s string) (image.Rectangle, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		return image.Rectangle{}, fmt.Errorf("crop %q must be x,y,w,h", s)
+	}
+	var n [4]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("crop %q must be x,y,w,h in whole pixels", s)
+		}
+		n[i] = v
+	}
+	if n[0] < 0 || n[1] < 0 || n[2] <= 0 || n[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("crop %q must have x and y >= 0 and a width and height > 0", s)
+	}
+	return image.Rect(n[0], n[1], n[0]+n[2], n[1]+n[3]), nil
+}
+
+// FormatCrop writes a crop rectangle as x,y,w,h, as ParseCrop reads it.
+func FormatCrop(r image.Rectangle) string {
+	return fmt.Sprintf("%d,%d,%d,%d", r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+}
+
+// formatName returns Format for messages, with the default spelled out.
+func (c *Config) formatName() string {
+	if c.Format == "" {
+		return "png"
+	}
+	return c.Format
+}
+
+// CommandLine returns the command for display: Command, or CommandArgs
+// quoted for a POSIX shell.
+func (c *Config) CommandLine() string {
+	if len(c.CommandArgs) == 0 {
+		return c.Command
+	}
+	quoted := make([]string, len(c.CommandArgs))
+	for i, arg := range c.CommandArgs {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// ShellQuote single-quotes s for a POSIX shell unless it consists only of
+// safe characters.
+func ShellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // ParseConfig extracts configuration from Cobra command flags.
@@ -145,34 +441,280 @@ func ParseConfig(cmd *cobra.Command) (*Config, error) {
 	}, nil
 }
 
-// Validate checks that all configuration fields are valid.
+// ResolveOutputDir expands a leading ~ in dir to the home directory, as a
+// shell would, and returns it as a clean absolute path. ~user is not
+// supported.
+func ResolveOutputDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expand ~: %w", err)
+		}
+		dir = filepath.Join(home, dir[1:])
+	} else if strings.HasPrefix(dir, "~") {
+		return "", fmt.Errorf("cannot expand %s; use ~/ for your home directory, or an absolute path", dir)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", dir, err)
+	}
+	return abs, nil
+}
+
+// Validate checks that all configuration fields are valid. It also resolves
+// OutputDir to a clean absolute path; see ResolveOutputDir.
 func (c *Config) Validate() error {
-	if c.Command == "" {
+	if c.Command != "" && len(c.CommandArgs) > 0 {
+		return fmt.Errorf("command and command args are mutually exclusive")
+	}
+
+	if c.TerminalURL != "" {
+		if c.Command != "" || len(c.CommandArgs) > 0 {
+			return fmt.Errorf("command must be empty when attaching to a terminal URL")
+		}
+		if c.ExitOnDone {
+			return fmt.Errorf("exit-on-done cannot watch a command when attaching to a terminal URL")
+		}
+		if c.FailOnError {
+			return fmt.Errorf("fail-on-error cannot watch a command when attaching to a terminal URL")
+		}
+		for _, action := range c.Actions {
+			if action.Kind == script.ActionSignal {
+				return fmt.Errorf("signal %s cannot reach the command when attaching to a terminal URL", action.Signal)
+			}
+		}
+		if len(c.Env) > 0 {
+			return fmt.Errorf("env cannot reach the command when attaching to a terminal URL")
+		}
+		if c.NoColorSession {
+			return fmt.Errorf("no-color-session cannot reach the command when attaching to a terminal URL")
+		}
+		u, err := url.Parse(c.TerminalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("terminal URL must be an http(s) URL, got %q", c.TerminalURL)
+		}
+	} else if c.Command == "" && len(c.CommandArgs) == 0 {
 		return fmt.Errorf("command must be non-empty")
 	}
 
-	if c.OutputDir == "" {
+	if len(c.TmuxPanes) > 0 && c.TerminalURL != "" {
+		return fmt.Errorf("a tmux layout starts its own session, so it cannot attach to a terminal URL")
+	}
+	for _, action := range c.Actions {
+		if action.Kind != script.ActionPane {
+			continue
+		}
+		if len(c.TmuxPanes) == 0 {
+			return fmt.Errorf("pane %q needs a tmux layout; pass --tmux-layout", action.Name)
+		}
+		if TmuxPaneIndex(c.TmuxPanes, action.Name) < 0 {
+			names := make([]string, len(c.TmuxPanes))
+			for i, pane := range c.TmuxPanes {
+				names[i] = pane.Name
+			}
+			return fmt.Errorf("pane %q is not in the tmux layout (panes: %s)", action.Name, strings.Join(names, ", "))
+		}
+	}
+
+	if c.SSH != "" {
+		if c.TerminalURL != "" {
+			return fmt.Errorf("ssh starts ttyd on the remote host, so it cannot attach to a terminal URL")
+		}
+		if !validSSHDestination(c.SSH) {
+			return fmt.Errorf("ssh destination must be host or user@host, got %q", c.SSH)
+		}
+		for _, action := range c.Actions {
+			if action.Kind == script.ActionSignal {
+				return fmt.Errorf("signal %s cannot reach a command run over ssh", action.Signal)
+			}
+		}
+	}
+
+	if c.Shell != "" && !slices.Contains(Shells, c.Shell) {
+		return fmt.Errorf("unknown shell %q (available: %s)", c.Shell, strings.Join(Shells, ", "))
+	}
+
+	for _, entry := range c.Env {
+		if name, _, ok := strings.Cut(entry, "="); !ok || name == "" {
+			return fmt.Errorf("invalid env %q; use KEY=VALUE", entry)
+		}
+	}
+
+	if c.PromptPattern != "" {
+		if _, err := regexp.Compile(c.PromptPattern); err != nil {
+			return fmt.Errorf("prompt-pattern: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(c.OutputDir) == "" {
 		return fmt.Errorf("output-dir must be non-empty")
 	}
+	outputDir, err := ResolveOutputDir(c.OutputDir)
+	if err != nil {
+		return fmt.Errorf("output-dir: %w", err)
+	}
+	if info, err := os.Stat(outputDir); err == nil && !info.IsDir() {
+		return fmt.Errorf("output-dir %s is a file, not a directory", outputDir)
+	}
+	c.OutputDir = outputDir
+
+	if _, err := ParseNameTemplate(c.NameTemplate); err != nil {
+		return fmt.Errorf("name: %w", err)
+	}
 
-	if c.TTydPort < 1 || c.TTydPort > 65535 {
+	if (c.TTydPort < 1 && !c.AutoPort) || c.TTydPort < 0 || c.TTydPort > 65535 {
 		return fmt.Errorf("ttyd-port must be between 1 and 65535")
 	}
 
-	if c.ScreenshotInterval <= 0 {
-		return fmt.Errorf("screenshot-interval must be > 0")
+	if c.ScreenshotInterval < 0 {
+		return fmt.Errorf("screenshot-interval must be >= 0 (0 disables interval screenshots)")
 	}
 
 	if c.Timeout <= 0 {
 		return fmt.Errorf("timeout must be > 0")
 	}
 
-	// Only validate keypresses/delays if not using script-based interface or Actions
-	if c.Script == "" && len(c.Actions) == 0 {
-		if len(c.Keypresses) == 0 {
-			return fmt.Errorf("keypresses must not be empty")
+	if c.Width < 0 || c.Height < 0 {
+		return fmt.Errorf("width and height must be > 0, got %dx%d", c.Width, c.Height)
+	}
+
+	if c.Cols < 0 || c.Rows < 0 {
+		return fmt.Errorf("cols and rows must be > 0 (or 0 to fit the viewport), got %dx%d", c.Cols, c.Rows)
+	}
+
+	if c.Theme != "" {
+		if _, err := theme.Resolve(c.Theme); err != nil {
+			return fmt.Errorf("theme: %w", err)
+		}
+	}
+
+	if c.Padding < 0 {
+		return fmt.Errorf("padding must be >= 0, got %d", c.Padding)
+	}
+	if c.Background != "" {
+		if c.Padding == 0 && !c.Window {
+			return fmt.Errorf("bg colors the padding, so it needs padding > 0 or window")
+		}
+		if _, err := theme.ParseColor(c.Background); err != nil {
+			return fmt.Errorf("bg: %w", err)
+		}
+	}
+
+	if c.Title != "" {
+		if !c.Window {
+			return fmt.Errorf("title is drawn in the window's title bar, so it needs window")
+		}
+		for _, r := range c.Title {
+			if r < ' ' || r > '~' {
+				return fmt.Errorf("title %q can only use printable ASCII characters", c.Title)
+			}
+		}
+	}
+
+	if c.Quality < 0 || c.Quality > 100 {
+		return fmt.Errorf("quality must be 1 to 100 (0 uses %d), got %d", DefaultQuality, c.Quality)
+	}
+	switch c.ImageFormat() {
+	case "png":
+		if c.Quality != 0 {
+			return fmt.Errorf("quality applies to jpeg and webp frames, not to %s", c.formatName())
+		}
+	case "jpeg":
+		if c.Window && c.Background == "" {
+			return fmt.Errorf("jpeg cannot store the transparent corners around the window; pass bg")
 		}
+	case "webp":
+		if !c.Crop.Empty() || c.Window || c.Padding > 0 || c.Grayscale || len(c.SimulateCVD) > 0 {
+			return fmt.Errorf("crop, window, padding, grayscale and color vision simulation redraw frames, which scr cannot encode as webp; use jpeg or png")
+		}
+	}
+
+	if c.Selector != "" && strings.TrimSpace(c.Selector) == "" {
+		return fmt.Errorf("selector must not be blank")
+	}
+	if c.Crop != (image.Rectangle{}) && (c.Crop.Empty() || c.Crop.Min.X < 0 || c.Crop.Min.Y < 0) {
+		return fmt.Errorf("crop %s must have x and y >= 0 and a width and height > 0", FormatCrop(c.Crop))
+	}
+
+	if c.FontSize != 0 && (c.FontSize < MinFontSize || c.FontSize > MaxFontSize) {
+		return fmt.Errorf("font size must be %d to %d, got %d", MinFontSize, MaxFontSize, c.FontSize)
+	}
+	if c.FontFamily != "" && strings.TrimSpace(c.FontFamily) == "" {
+		return fmt.Errorf("font family must not be blank")
+	}
+	if c.FontFamily != "" && c.SystemFonts {
+		return fmt.Errorf("font-family and system-fonts are mutually exclusive")
+	}
+
+	if c.LogMaxSize < 0 || c.LogKeep < 0 {
+		return fmt.Errorf("log max size and keep must be >= 0, got %d and %d", c.LogMaxSize, c.LogKeep)
+	}
+
+	if c.FrameDelay < 0 {
+		return fmt.Errorf("frame delay must be >= 0 (0 uses real capture timing)")
+	}
+
+	if c.TargetFrames < 0 {
+		return fmt.Errorf("target frames must be >= 0 (0 keeps every frame), got %d", c.TargetFrames)
+	}
+	if c.TargetFrames > 0 && c.Format != "gif" {
+		return fmt.Errorf("target frames applies to gif output, not to %s", c.formatName())
+	}
+
+	if c.TypeChunkSize < 0 {
+		return fmt.Errorf("type chunk size must be >= 0 (0 uses %d), got %d", DefaultTypeChunkSize, c.TypeChunkSize)
+	}
+
+	if c.EscapeDelay < 0 {
+		return fmt.Errorf("escape delay must be >= 0 (0 sends keys after Escape at once)")
+	}
+
+	if c.Video != "" && !slices.Contains(VideoFormats, strings.ToLower(filepath.Ext(c.Video))) {
+		return fmt.Errorf("video %q must end in %s", c.Video, strings.Join(VideoFormats, " or "))
+	}
+	if c.Video != "" {
+		for _, action := range c.Actions {
+			if action.Kind == script.ActionType && action.Secret {
+				return fmt.Errorf("video records the screen continuously, so it cannot leave out a TypeSecret")
+			}
+		}
+		if c.Grayscale {
+			return fmt.Errorf("grayscale applies to frames only, not to video")
+		}
+		if c.Padding > 0 {
+			return fmt.Errorf("padding applies to frames only, not to video")
+		}
+		if c.Window {
+			return fmt.Errorf("window applies to frames only, not to video")
+		}
+		if c.Selector != "" || c.Crop != (image.Rectangle{}) {
+			return fmt.Errorf("selector and crop apply to frames only, not to video")
+		}
+	}
+
+	if c.FrameHookStrict && strings.TrimSpace(c.FrameHook) == "" {
+		return fmt.Errorf("frame-hook-strict needs a frame hook command")
+	}
+
+	for i, kind := range c.SimulateCVD {
+		if !slices.Contains(CVDSimulations, kind) {
+			return fmt.Errorf("unknown color vision deficiency %q (available: %s)", kind, strings.Join(CVDSimulations, ", "))
+		}
+		if slices.Contains(c.SimulateCVD[:i], kind) {
+			return fmt.Errorf("color vision deficiency %q is simulated twice", kind)
+		}
+	}
+
+	// With ExitOnDone, the command's exit may legitimately cut the script short
+	if !c.ExitOnDone {
+		if est := script.Estimate(c.Actions, script.EstimateOptions{}); est.Duration >= c.Timeout {
+			return fmt.Errorf("script takes at least %v, which does not fit in the %v timeout; raise --timeout", est.Duration, c.Timeout)
+		}
+	}
 
+	// Only validate keypresses/delays if not using script-based interface or
+	// Actions; with neither, the capture is static
+	if c.Script == "" && len(c.Actions) == 0 && len(c.Keypresses) > 0 {
 		if len(c.Delays) != len(c.Keypresses)-1 {
 			return fmt.Errorf("delays length must be equal to keypresses length - 1")
 		}
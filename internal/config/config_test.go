@@ -1,11 +1,16 @@
 package config
 
 import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/yarlson/scr/internal/script"
 )
@@ -42,6 +47,93 @@ func TestValidate_EmptyCommand(t *testing.T) {
 	assert.Contains(t, err.Error(), "command must be non-empty")
 }
 
+func TestResolveOutputDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		dir     string
+		want    string
+		wantErr string
+	}{
+		{name: "tilde", dir: "~", want: home},
+		{name: "below tilde", dir: "~/shots/", want: filepath.Join(home, "shots")},
+		{name: "relative", dir: "shots/../frames/", want: filepath.Join(wd, "frames")},
+		{name: "absolute", dir: "/tmp//shots/", want: filepath.Clean("/tmp/shots")},
+		{name: "other user", dir: "~alice/shots", wantErr: "cannot expand ~alice/shots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOutputDir(tt.dir)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_ResolvesOutputDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	cfg := &Config{
+		Command:            "echo hello",
+		Actions:            []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+		Script:             "Enter",
+		OutputDir:          "~/shots/",
+		ScreenshotInterval: 500 * time.Millisecond,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+	}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, filepath.Join(home, "shots"), cfg.OutputDir)
+}
+
+func TestValidate_OutputDirIsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "shots")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          file,
+		ScreenshotInterval: 500 * time.Millisecond,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is a file, not a directory")
+}
+
+func TestValidate_BlankOutputDir(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "  ",
+		ScreenshotInterval: 500 * time.Millisecond,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output-dir must be non-empty")
+}
+
 func TestValidate_EmptyOutputDir(t *testing.T) {
 	cfg := &Config{
 		Command:            "echo hello",
@@ -74,6 +166,20 @@ func TestValidate_InvalidTTydPort_TooLow(t *testing.T) {
 	assert.Contains(t, err.Error(), "ttyd-port must be between 1 and 65535")
 }
 
+func TestValidate_AutoPortWithoutPort(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "/tmp/output",
+		ScreenshotInterval: 500 * time.Millisecond,
+		AutoPort:           true,
+		Timeout:            30 * time.Second,
+	}
+
+	assert.NoError(t, cfg.Validate())
+}
+
 func TestValidate_InvalidTTydPort_TooHigh(t *testing.T) {
 	cfg := &Config{
 		Command:            "echo hello",
@@ -96,14 +202,496 @@ func TestValidate_InvalidScreenshotInterval(t *testing.T) {
 		Keypresses:         []string{"a"},
 		Delays:             []time.Duration{},
 		OutputDir:          "/tmp/output",
+		ScreenshotInterval: -time.Second,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "screenshot-interval must be >= 0")
+}
+
+func TestValidate_ZeroScreenshotIntervalDisablesInterval(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		OutputDir:          "/tmp/output",
 		ScreenshotInterval: 0,
 		TTydPort:           8080,
 		Timeout:            30 * time.Second,
+		Script:             "Screenshot",
+		Actions:            []script.Action{{Kind: script.ActionScreenshot}},
+	}
+
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_NegativeFrameDelay(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "/tmp/output",
+		ScreenshotInterval: time.Second,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+		FrameDelay:         -time.Millisecond,
 	}
 
 	err := cfg.Validate()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "screenshot-interval must be > 0")
+	assert.Contains(t, err.Error(), "frame delay must be >= 0")
+}
+
+func TestValidate_NegativeEscapeDelay(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "/tmp/output",
+		ScreenshotInterval: time.Second,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+		EscapeDelay:        -time.Millisecond,
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "escape delay must be >= 0")
+}
+
+func TestValidate_NegativeTypeChunkSize(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "/tmp/output",
+		ScreenshotInterval: time.Second,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+		TypeChunkSize:      -1,
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "type chunk size must be >= 0")
+}
+
+func TestValidate_NegativeLogLimits(t *testing.T) {
+	cfg := &Config{
+		Command:            "echo hello",
+		Keypresses:         []string{"a"},
+		Delays:             []time.Duration{},
+		OutputDir:          "/tmp/output",
+		ScreenshotInterval: time.Second,
+		TTydPort:           8080,
+		Timeout:            30 * time.Second,
+		LogFile:            "/tmp/scr.log",
+		LogKeep:            -1,
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "log max size and keep must be >= 0")
+}
+
+func TestValidate_Shell(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		commandArgs []string
+		shell       string
+		wantErr     string
+	}{
+		{name: "default shell", command: "ls"},
+		{name: "known shell", command: "ls", shell: "fish"},
+		{name: "unknown shell", command: "ls", shell: "csh", wantErr: `unknown shell "csh" (available: bash, sh, zsh, fish)`},
+		{name: "argv without a shell", commandArgs: []string{"htop", "-d", "10"}},
+		{name: "command and argv", command: "ls", commandArgs: []string{"htop"}, wantErr: "mutually exclusive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:     tt.command,
+				CommandArgs: tt.commandArgs,
+				Shell:       tt.shell,
+				Keypresses:  []string{"a"},
+				OutputDir:   "/tmp/output",
+				TTydPort:    8080,
+				Timeout:     30 * time.Second,
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestConfig_CommandLine(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "shell command", cfg: Config{Command: "ls -la | head"}, want: "ls -la | head"},
+		{name: "plain argv", cfg: Config{CommandArgs: []string{"htop", "-d", "10"}}, want: "htop -d 10"},
+		{name: "argv needing quotes", cfg: Config{CommandArgs: []string{"echo", "it's here", ""}}, want: `echo 'it'\''s here' ''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.CommandLine())
+		})
+	}
+}
+
+func TestValidate_Theme(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   string
+		wantErr string
+	}{
+		{name: "no theme", theme: ""},
+		{name: "built-in theme", theme: "dracula"},
+		{name: "unknown theme", theme: "monokai", wantErr: `theme: unknown theme "monokai"`},
+		{name: "missing theme file", theme: "/nonexistent/theme.json", wantErr: "theme: read theme file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:            "echo hello",
+				Keypresses:         []string{"a"},
+				Delays:             []time.Duration{},
+				OutputDir:          "/tmp/output",
+				ScreenshotInterval: time.Second,
+				TTydPort:           8080,
+				Timeout:            30 * time.Second,
+				Theme:              tt.theme,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_Font(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		family      string
+		systemFonts bool
+		wantErr     string
+	}{
+		{name: "defaults"},
+		{name: "smallest size", size: MinFontSize},
+		{name: "largest size", size: MaxFontSize},
+		{name: "too small", size: 5, wantErr: "font size must be 6 to 72, got 5"},
+		{name: "too large", size: 73, wantErr: "font size must be 6 to 72, got 73"},
+		{name: "family", family: "JetBrains Mono, monospace"},
+		{name: "blank family", family: "  ", wantErr: "font family must not be blank"},
+		{name: "family with system fonts", family: "Menlo", systemFonts: true, wantErr: "mutually exclusive"},
+		{name: "size with system fonts", size: 16, systemFonts: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:     "bash",
+				OutputDir:   "/tmp/output",
+				TTydPort:    8080,
+				Timeout:     10 * time.Second,
+				Actions:     []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				FontSize:    tt.size,
+				FontFamily:  tt.family,
+				SystemFonts: tt.systemFonts,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_Padding(t *testing.T) {
+	tests := []struct {
+		name       string
+		padding    int
+		background string
+		video      string
+		wantErr    string
+	}{
+		{name: "none"},
+		{name: "padding", padding: 24},
+		{name: "padding and bg", padding: 24, background: "#1e1e2e"},
+		{name: "short bg", padding: 24, background: "#fff"},
+		{name: "negative", padding: -1, wantErr: "padding must be >= 0, got -1"},
+		{name: "bg without padding", background: "#1e1e2e", wantErr: "bg colors the padding, so it needs padding > 0 or window"},
+		{name: "bad bg", padding: 24, background: "navy", wantErr: `bg: "navy" is not a hex color`},
+		{name: "padding with video", padding: 24, video: "demo.mp4", wantErr: "padding applies to frames only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:    "bash",
+				OutputDir:  "/tmp/output",
+				TTydPort:   8080,
+				Timeout:    10 * time.Second,
+				Actions:    []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				Padding:    tt.padding,
+				Background: tt.background,
+				Video:      tt.video,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_Window(t *testing.T) {
+	tests := []struct {
+		name       string
+		window     bool
+		title      string
+		background string
+		video      string
+		wantErr    string
+	}{
+		{name: "window", window: true},
+		{name: "window with title", window: true, title: "demo: make test"},
+		{name: "bg colors the corners", window: true, background: "#ffffff"},
+		{name: "title without window", title: "demo", wantErr: "title is drawn in the window's title bar, so it needs window"},
+		{name: "non-ASCII title", window: true, title: "démo", wantErr: `title "démo" can only use printable ASCII characters`},
+		{name: "control character in title", window: true, title: "a\tb", wantErr: "printable ASCII"},
+		{name: "window with video", window: true, video: "demo.mp4", wantErr: "window applies to frames only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:    "bash",
+				OutputDir:  "/tmp/output",
+				TTydPort:   8080,
+				Timeout:    10 * time.Second,
+				Actions:    []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				Window:     tt.window,
+				Title:      tt.title,
+				Background: tt.background,
+				Video:      tt.video,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_ImageFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "jpeg", cfg: Config{Format: "jpeg"}},
+		{name: "jpeg with quality", cfg: Config{Format: "jpeg", Quality: 75}},
+		{name: "webp with quality", cfg: Config{Format: "webp", Quality: 1}},
+		{name: "format in any case", cfg: Config{Format: "JPEG", Quality: 100}},
+		{name: "quality too high", cfg: Config{Format: "jpeg", Quality: 101}, wantErr: "quality must be 1 to 100 (0 uses 90), got 101"},
+		{name: "negative quality", cfg: Config{Format: "webp", Quality: -1}, wantErr: "quality must be 1 to 100"},
+		{name: "quality with default format", cfg: Config{Quality: 80}, wantErr: "quality applies to jpeg and webp frames, not to png"},
+		{name: "quality with gif", cfg: Config{Format: "gif", Quality: 80}, wantErr: "quality applies to jpeg and webp frames, not to gif"},
+		{name: "gif with target frames", cfg: Config{Format: "gif", TargetFrames: 60}},
+		{name: "target frames with png", cfg: Config{TargetFrames: 60}, wantErr: "target frames applies to gif output, not to png"},
+		{name: "negative target frames", cfg: Config{Format: "gif", TargetFrames: -1}, wantErr: "target frames must be >= 0 (0 keeps every frame), got -1"},
+		{name: "jpeg padding", cfg: Config{Format: "jpeg", Padding: 8, Grayscale: true}},
+		{name: "jpeg window with bg", cfg: Config{Format: "jpeg", Window: true, Background: "#000"}},
+		{name: "jpeg window without bg", cfg: Config{Format: "jpeg", Window: true}, wantErr: "jpeg cannot store the transparent corners around the window; pass bg"},
+		{name: "webp padding", cfg: Config{Format: "webp", Padding: 8}, wantErr: "cannot encode as webp"},
+		{name: "webp simulation", cfg: Config{Format: "webp", SimulateCVD: []string{"tritanopia"}}, wantErr: "cannot encode as webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Command = "bash"
+			cfg.OutputDir = "/tmp/output"
+			cfg.TTydPort = 8080
+			cfg.Timeout = 10 * time.Second
+			cfg.Actions = []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseCrop(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    image.Rectangle
+		wantErr string
+	}{
+		{in: "10,20,300,200", want: image.Rect(10, 20, 310, 220)},
+		{in: "0, 0, 80, 24", want: image.Rect(0, 0, 80, 24)},
+		{in: "10,20,300", wantErr: `crop "10,20,300" must be x,y,w,h`},
+		{in: "10,20,300px,200", wantErr: "in whole pixels"},
+		{in: "-1,0,10,10", wantErr: "must have x and y >= 0 and a width and height > 0"},
+		{in: "0,0,0,10", wantErr: "must have x and y >= 0 and a width and height > 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCrop(tt.in)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, strings.ReplaceAll(tt.in, " ", ""), FormatCrop(got))
+		})
+	}
+}
+
+func TestValidate_SelectorAndCrop(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "selector", cfg: Config{Selector: "#terminal-container .xterm-screen"}},
+		{name: "crop", cfg: Config{Crop: image.Rect(0, 0, 10, 10)}},
+		{name: "crop with jpeg", cfg: Config{Format: "jpeg", Crop: image.Rect(0, 0, 10, 10)}},
+		{name: "blank selector", cfg: Config{Selector: "  "}, wantErr: "selector must not be blank"},
+		{name: "negative crop", cfg: Config{Crop: image.Rect(-5, 0, 10, 10)}, wantErr: "crop -5,0,15,10 must have x and y >= 0"},
+		{name: "empty crop", cfg: Config{Crop: image.Rect(5, 5, 5, 10)}, wantErr: "must have x and y >= 0 and a width and height > 0"},
+		{name: "crop with webp", cfg: Config{Format: "webp", Crop: image.Rect(0, 0, 10, 10)}, wantErr: "cannot encode as webp"},
+		{name: "selector with video", cfg: Config{Selector: ".xterm-screen", Video: "demo.mp4"}, wantErr: "selector and crop apply to frames only, not to video"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Command = "bash"
+			cfg.OutputDir = "/tmp/output"
+			cfg.TTydPort = 8080
+			cfg.Timeout = 10 * time.Second
+			cfg.Actions = []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_SSH(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "host", cfg: Config{Command: "bash", SSH: "devbox"}},
+		{name: "user and host", cfg: Config{Command: "bash", SSH: "me@devbox.example.com"}},
+		{name: "option", cfg: Config{Command: "bash", SSH: "-oProxyCommand=sh"}, wantErr: `ssh destination must be host or user@host, got "-oProxyCommand=sh"`},
+		{name: "space", cfg: Config{Command: "bash", SSH: "me@dev box"}, wantErr: "ssh destination must be host or user@host"},
+		{name: "no host", cfg: Config{Command: "bash", SSH: "me@"}, wantErr: "ssh destination must be host or user@host"},
+		{name: "no user", cfg: Config{Command: "bash", SSH: "@devbox"}, wantErr: "ssh destination must be host or user@host"},
+		{name: "terminal URL", cfg: Config{TerminalURL: "http://localhost:7681", SSH: "devbox"}, wantErr: "cannot attach to a terminal URL"},
+		{
+			name:    "signal",
+			cfg:     Config{Command: "bash", SSH: "devbox", Actions: []script.Action{{Kind: script.ActionSignal, Signal: "INT"}}},
+			wantErr: "signal INT cannot reach a command run over ssh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.OutputDir = "/tmp/output"
+			cfg.TTydPort = 8080
+			cfg.Timeout = 10 * time.Second
+			if cfg.Actions == nil {
+				cfg.Actions = []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}}
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_Geometry(t *testing.T) {
+	tests := []struct {
+		name                      string
+		width, height, cols, rows int
+		wantErr                   string
+	}{
+		{name: "zero uses defaults"},
+		{name: "custom sizes", width: 1024, height: 600, cols: 120, rows: 40},
+		{name: "negative width", width: -1, wantErr: "width and height must be > 0, got -1x0"},
+		{name: "negative rows", rows: -2, wantErr: "cols and rows must be > 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:            "echo hello",
+				Keypresses:         []string{"a"},
+				Delays:             []time.Duration{},
+				OutputDir:          "/tmp/output",
+				ScreenshotInterval: time.Second,
+				TTydPort:           8080,
+				Timeout:            30 * time.Second,
+				Width:              tt.width,
+				Height:             tt.height,
+				Cols:               tt.cols,
+				Rows:               tt.rows,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
 }
 
 func TestValidate_InvalidTimeout(t *testing.T) {
@@ -122,7 +710,143 @@ func TestValidate_InvalidTimeout(t *testing.T) {
 	assert.Contains(t, err.Error(), "timeout must be > 0")
 }
 
-func TestValidate_EmptyKeypresses(t *testing.T) {
+func TestValidate_ScriptLongerThanTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		actions    []script.Action
+		exitOnDone bool
+		wantErr    string
+	}{
+		{
+			name:    "fits",
+			actions: []script.Action{{Kind: script.ActionSleep, Duration: 9 * time.Second}},
+		},
+		{
+			name: "sleeps and typing exceed the timeout",
+			actions: []script.Action{
+				{Kind: script.ActionSleep, Duration: 9 * time.Second},
+				{Kind: script.ActionType, Text: "ls", Speed: time.Second},
+			},
+			wantErr: "script takes at least 11s, which does not fit in the 10s timeout",
+		},
+		{
+			name:       "exit-on-done may end the script early",
+			actions:    []script.Action{{Kind: script.ActionSleep, Duration: time.Minute}},
+			exitOnDone: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:    "bash",
+				OutputDir:  "/tmp/output",
+				TTydPort:   8080,
+				Timeout:    10 * time.Second,
+				Actions:    tt.actions,
+				ExitOnDone: tt.exitOnDone,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_Video(t *testing.T) {
+	tests := []struct {
+		name    string
+		video   string
+		wantErr string
+	}{
+		{name: "webm", video: "demo.webm"},
+		{name: "mp4 in any case", video: "out/demo.MP4"},
+		{name: "unsupported extension", video: "demo.gif", wantErr: `video "demo.gif" must end in .webm or .mp4`},
+		{name: "no extension", video: "demo", wantErr: "must end in .webm or .mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:   "bash",
+				OutputDir: "/tmp/output",
+				TTydPort:  8080,
+				Timeout:   10 * time.Second,
+				Actions:   []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				Video:     tt.video,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_FrameHook(t *testing.T) {
+	tests := []struct {
+		name      string
+		frameHook string
+		strict    bool
+		wantErr   string
+	}{
+		{name: "hook", frameHook: "optipng {file}"},
+		{name: "strict hook", frameHook: "optipng {file}", strict: true},
+		{name: "strict without hook", strict: true, wantErr: "frame-hook-strict needs a frame hook command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:         "bash",
+				OutputDir:       "/tmp/output",
+				TTydPort:        8080,
+				Timeout:         10 * time.Second,
+				Actions:         []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				FrameHook:       tt.frameHook,
+				FrameHookStrict: tt.strict,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_PromptPattern(t *testing.T) {
+	cfg := &Config{
+		Command:       "bash",
+		OutputDir:     "/tmp/output",
+		TTydPort:      8080,
+		Timeout:       10 * time.Second,
+		Actions:       []script.Action{{Kind: script.ActionWait, Prompt: true, Timeout: time.Second}},
+		PromptPattern: `❯$`,
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.PromptPattern = `[$`
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prompt-pattern: error parsing regexp")
+}
+
+// TestValidate_NoActions verifies that a config without keypresses or
+// actions is a static capture.
+func TestValidate_NoActions(t *testing.T) {
 	cfg := &Config{
 		Command:            "echo hello",
 		Keypresses:         []string{},
@@ -133,9 +857,7 @@ func TestValidate_EmptyKeypresses(t *testing.T) {
 		Timeout:            30 * time.Second,
 	}
 
-	err := cfg.Validate()
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "keypresses must not be empty")
+	assert.NoError(t, cfg.Validate())
 }
 
 func TestValidate_InvalidDelaysLength(t *testing.T) {
@@ -219,3 +941,159 @@ func TestValidate_ScriptNotEmpty(t *testing.T) {
 	err := cfg.Validate()
 	assert.NoError(t, err, "validation should pass when Script is not empty")
 }
+
+func TestValidate_TerminalURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		terminalURL string
+		exitOnDone  bool
+		failOnError bool
+		actions     []script.Action
+		env         []string
+		noColor     bool
+		wantErr     string
+	}{
+		{name: "attach without command", terminalURL: "http://localhost:7681"},
+		{name: "attach with a signal", terminalURL: "http://localhost:7681", actions: []script.Action{{Kind: script.ActionSignal, Signal: "INT"}}, wantErr: "signal INT cannot reach the command"},
+		{name: "signal with command", command: "bash", actions: []script.Action{{Kind: script.ActionSignal, Signal: "INT"}}},
+		{name: "attach with env", terminalURL: "http://localhost:7681", env: []string{"LANG=C"}, wantErr: "env cannot reach the command"},
+		{name: "attach with no-color-session", terminalURL: "http://localhost:7681", noColor: true, wantErr: "no-color-session cannot reach the command"},
+		{name: "attach with exit-on-done", terminalURL: "http://localhost:7681", exitOnDone: true, wantErr: "exit-on-done cannot watch a command"},
+		{name: "exit-on-done with command", command: "bash", exitOnDone: true},
+		{name: "attach with fail-on-error", terminalURL: "http://localhost:7681", failOnError: true, wantErr: "fail-on-error cannot watch a command"},
+		{name: "fail-on-error with command", command: "bash", failOnError: true},
+		{name: "attach over https", terminalURL: "https://example.com/ttyd/"},
+		{name: "attach with command", command: "bash", terminalURL: "http://localhost:7681", wantErr: "command must be empty when attaching"},
+		{name: "non-http scheme", terminalURL: "ws://localhost:7681", wantErr: "terminal URL must be an http(s) URL"},
+		{name: "missing host", terminalURL: "http://", wantErr: "terminal URL must be an http(s) URL"},
+		{name: "no URL and no command", wantErr: "command must be non-empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:            tt.command,
+				TerminalURL:        tt.terminalURL,
+				ExitOnDone:         tt.exitOnDone,
+				FailOnError:        tt.failOnError,
+				Actions:            tt.actions,
+				Env:                tt.env,
+				NoColorSession:     tt.noColor,
+				OutputDir:          "/tmp/output",
+				ScreenshotInterval: 500 * time.Millisecond,
+				TTydPort:           7681,
+				Timeout:            30 * time.Second,
+				Script:             "Enter",
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_SimulateCVD(t *testing.T) {
+	tests := []struct {
+		name    string
+		kinds   []string
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "all", kinds: []string{"protanopia", "deuteranopia", "tritanopia"}},
+		{name: "unknown", kinds: []string{"achromatopsia"}, wantErr: `unknown color vision deficiency "achromatopsia" (available: protanopia, deuteranopia, tritanopia)`},
+		{name: "twice", kinds: []string{"tritanopia", "tritanopia"}, wantErr: `"tritanopia" is simulated twice`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:     "bash",
+				OutputDir:   "/tmp/output",
+				TTydPort:    8080,
+				Timeout:     10 * time.Second,
+				Actions:     []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				SimulateCVD: tt.kinds,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_TypeSecretWithVideo(t *testing.T) {
+	cfg := &Config{
+		Command:   "bash",
+		OutputDir: "/tmp/output",
+		TTydPort:  8080,
+		Timeout:   10 * time.Second,
+		Actions:   []script.Action{{Kind: script.ActionType, Text: "s3cret", Secret: true}},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Video = "demo.webm"
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot leave out a TypeSecret")
+}
+
+func TestValidate_GrayscaleWithVideo(t *testing.T) {
+	cfg := &Config{
+		Command:   "bash",
+		OutputDir: "/tmp/output",
+		TTydPort:  8080,
+		Timeout:   10 * time.Second,
+		Actions:   []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+		Grayscale: true,
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Video = "demo.webm"
+	assert.ErrorContains(t, cfg.Validate(), "grayscale applies to frames only")
+}
+
+func TestValidate_Env(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []string
+		wantErr string
+	}{
+		{name: "none"},
+		{name: "several", env: []string{"LANG=C.UTF-8", "PS1=$ ", "EMPTY="}},
+		{name: "value with equals", env: []string{"OPTS=a=b"}},
+		{name: "no equals", env: []string{"LANG"}, wantErr: `invalid env "LANG"; use KEY=VALUE`},
+		{name: "no key", env: []string{"=C"}, wantErr: `invalid env "=C"; use KEY=VALUE`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Command:   "bash",
+				OutputDir: "/tmp/output",
+				TTydPort:  8080,
+				Timeout:   10 * time.Second,
+				Actions:   []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}},
+				Env:       tt.env,
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MatrixEntry is one run of a matrix; see ParseMatrix.
+type MatrixEntry struct {
+	// Out is the entry's subdirectory of the output directory.
+	Out string `yaml:"out"`
+	// Params are the script's Param values for this entry; they replace
+	// the ones given with --param.
+	Params map[string]string `yaml:"params"`
+	// Index is the entry's position in the matrix, from 0.
+	Index int `yaml:"-"`
+}
+
+// ParseMatrix parses a matrix file: a YAML list of entries, each with the
+// output subdirectory its frames go to and the Param values it runs with:
+//
+//	# matrix.yaml
+//	- out: en
+//	  params:
+//	    GREETING: Hello
+//	- out: de
+//	  params:
+//	    GREETING: Hallo
+func ParseMatrix(data []byte) ([]MatrixEntry, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var entries []MatrixEntry
+	if err := dec.Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("matrix has no entries")
+	}
+
+	seen := map[string]bool{}
+	for i, entry := range entries {
+		entries[i].Index = i
+		if entry.Out == "" {
+			return nil, fmt.Errorf("entry %d: out is required", i+1)
+		}
+		if !filepath.IsLocal(entry.Out) {
+			return nil, fmt.Errorf("entry %d: out must be a relative path inside the output directory, got %q", i+1, entry.Out)
+		}
+		out := filepath.Clean(entry.Out)
+		if seen[out] {
+			return nil, fmt.Errorf("entry %d: out %q is already used", i+1, entry.Out)
+		}
+		seen[out] = true
+	}
+	return entries, nil
+}
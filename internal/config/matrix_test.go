@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  string
+		want    []MatrixEntry
+		wantErr string
+	}{
+		{
+			name:   "entries with params",
+			matrix: "- out: en\n  params:\n    GREETING: Hello\n- out: de\n  params:\n    GREETING: Hallo\n",
+			want: []MatrixEntry{
+				{Out: "en", Params: map[string]string{"GREETING": "Hello"}},
+				{Out: "de", Params: map[string]string{"GREETING": "Hallo"}, Index: 1},
+			},
+		},
+		{name: "nested out", matrix: "- out: docs/en\n", want: []MatrixEntry{{Out: "docs/en"}}},
+		{name: "empty", matrix: "", wantErr: "matrix has no entries"},
+		{name: "not a list", matrix: "out: en\n", wantErr: "cannot unmarshal"},
+		{name: "unknown field", matrix: "- out: en\n  param:\n    A: b\n", wantErr: "field param not found"},
+		{name: "missing out", matrix: "- params:\n    A: b\n", wantErr: "entry 1: out is required"},
+		{name: "absolute out", matrix: "- out: /tmp/en\n", wantErr: `entry 1: out must be a relative path inside the output directory, got "/tmp/en"`},
+		{name: "out escapes", matrix: "- out: ../en\n", wantErr: "entry 1: out must be a relative path"},
+		{name: "duplicate out", matrix: "- out: en\n- out: ./en\n", wantErr: `entry 2: out "./en" is already used`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMatrix([]byte(tt.matrix))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
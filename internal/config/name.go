@@ -0,0 +1,267 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNameTemplate names sequential screenshots screenshot_001.png,
+// screenshot_002.png and so on.
+const DefaultNameTemplate = "screenshot_{n:03}.png"
+
+// NameTemplate is a parsed file name template for sequential screenshots;
+// see ParseNameTemplate. The zero value is DefaultNameTemplate.
+type NameTemplate struct {
+	parts []namePart
+}
+
+// namePart is literal text, or with field set a placeholder padded with
+// zeros to at least width digits.
+type namePart struct {
+	literal string
+	field   string
+	width   int
+}
+
+// NameFields are the values a NameTemplate fills in.
+type NameFields struct {
+	// Prefix replaces {prefix}; see NamePrefix.
+	Prefix string
+	// N replaces {n}: the frame's number, from 1.
+	N int
+	// Time replaces {time}, in milliseconds since the terminal became ready.
+	Time time.Duration
+	// Action replaces {action}: the number of the most recently started
+	// action, from 1, or 0 before the first.
+	Action int
+}
+
+// nameFields are the placeholders a NameTemplate accepts, and whether they
+// are numbers that take a width.
+var nameFields = map[string]bool{
+	"prefix": false,
+	"n":      true,
+	"time":   true,
+	"action": true,
+}
+
+var defaultNameTemplate = mustParseNameTemplate(DefaultNameTemplate)
+
+func mustParseNameTemplate(s string) NameTemplate {
+	t, err := ParseNameTemplate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseNameTemplate parses a file name template such as
+// "login_{n:03}.png". Placeholders are {prefix}, {n}, {time} and {action};
+// a number placeholder followed by a zero and a width, as in {n:03}, is
+// padded with zeros to at least that many digits and grows wider past
+// them. The template must contain {n} and name a file, not a path; ".png"
+// is appended unless it ends in an extension of ImageFormats, which
+// ForFormat replaces to suit the frames. An empty template is
+// DefaultNameTemplate.
+func ParseNameTemplate(s string) (NameTemplate, error) {
+	if s == "" {
+		return NameTemplate{}, nil
+	}
+	if strings.ContainsAny(s, `/\`) {
+		return NameTemplate{}, fmt.Errorf("name template %q must be a file name, not a path", s)
+	}
+
+	var t NameTemplate
+	hasN := false
+	rest := s
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			t.parts = append(t.parts, namePart{literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return NameTemplate{}, fmt.Errorf("unexpected } in name template %q", s)
+		}
+		if open > 0 {
+			t.parts = append(t.parts, namePart{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return NameTemplate{}, fmt.Errorf("unclosed { in name template %q", s)
+		}
+		part, err := parseNamePlaceholder(rest[open+1:open+end], s)
+		if err != nil {
+			return NameTemplate{}, err
+		}
+		hasN = hasN || part.field == "n"
+		t.parts = append(t.parts, part)
+		rest = rest[open+end+1:]
+	}
+	if !hasN {
+		return NameTemplate{}, fmt.Errorf("name template %q needs {n} so every frame gets its own file", s)
+	}
+	if imageFormatOf(s) == "" {
+		t.parts = append(t.parts, namePart{literal: ".png"})
+	}
+	return t, nil
+}
+
+// ForFormat returns the template with its extension changed to one of the
+// image format's, as WithImageExt does.
+func (t NameTemplate) ForFormat(format string) NameTemplate {
+	if t.parts == nil {
+		t = defaultNameTemplate
+	}
+	parts := slices.Clone(t.parts)
+	last := &parts[len(parts)-1]
+	last.literal = WithImageExt(last.literal, format)
+	return NameTemplate{parts: parts}
+}
+
+// parseNamePlaceholder parses the inside of a {placeholder} of template.
+func parseNamePlaceholder(inner, template string) (namePart, error) {
+	field, spec, hasSpec := strings.Cut(inner, ":")
+	number, ok := nameFields[field]
+	if !ok {
+		return namePart{}, fmt.Errorf("unknown placeholder {%s} in name template %q; use {prefix}, {n}, {time} or {action}", inner, template)
+	}
+	part := namePart{field: field}
+	if !hasSpec {
+		return part, nil
+	}
+	if !number {
+		return namePart{}, fmt.Errorf("placeholder {%s} in name template %q takes no width", field, template)
+	}
+	width, err := strconv.Atoi(spec)
+	if err != nil || len(spec) < 2 || spec[0] != '0' || width < 1 || width > 20 {
+		return namePart{}, fmt.Errorf("placeholder {%s} in name template %q needs a zero-padded width such as {%s:03}", inner, template, field)
+	}
+	part.width = width
+	return part, nil
+}
+
+// Format returns the file name for f.
+func (t NameTemplate) Format(f NameFields) string {
+	if t.parts == nil {
+		t = defaultNameTemplate
+	}
+	var sb strings.Builder
+	for _, p := range t.parts {
+		var n int64
+		switch p.field {
+		case "":
+			sb.WriteString(p.literal)
+			continue
+		case "prefix":
+			sb.WriteString(f.Prefix)
+			continue
+		case "n":
+			n = int64(f.N)
+		case "time":
+			n = f.Time.Milliseconds()
+		case "action":
+			n = int64(f.Action)
+		}
+		fmt.Fprintf(&sb, "%0*d", p.width, n)
+	}
+	return sb.String()
+}
+
+// Pattern matches, ignoring case, every name Format can return for prefix.
+func (t NameTemplate) Pattern(prefix string) *regexp.Regexp {
+	if t.parts == nil {
+		t = defaultNameTemplate
+	}
+	var sb strings.Builder
+	sb.WriteString(`(?i)^`)
+	for _, p := range t.parts {
+		switch p.field {
+		case "":
+			sb.WriteString(regexp.QuoteMeta(p.literal))
+		case "prefix":
+			sb.WriteString(regexp.QuoteMeta(prefix))
+		default:
+			sb.WriteString(`\d+`)
+		}
+	}
+	sb.WriteString(`$`)
+	return regexp.MustCompile(sb.String())
+}
+
+// ImageFormats maps the image formats frames can be written in to their
+// file extensions; the first is the one scr gives to names without one.
+var ImageFormats = map[string][]string{
+	"png":  {".png"},
+	"jpeg": {".jpg", ".jpeg"},
+	"webp": {".webp"},
+}
+
+// ImageFormat returns the format frame files are written in: Format when it
+// is one of ImageFormats, in any case, and otherwise png, the format
+// animated formats keep their frames in.
+func (c *Config) ImageFormat() string {
+	format := strings.ToLower(c.Format)
+	if _, ok := ImageFormats[format]; ok {
+		return format
+	}
+	return "png"
+}
+
+// WithImageExt returns name ending in an extension of the image format: a
+// name that has one is kept, one ending in the extension of another image
+// format has it replaced, and any other gets the format's first extension
+// appended.
+func WithImageExt(name, format string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if slices.Contains(ImageFormats[format], ext) {
+		return name
+	}
+	if imageFormatOf(name) != "" {
+		name = name[:len(name)-len(ext)]
+	}
+	return name + ImageFormats[format][0]
+}
+
+// imageFormatOf returns the image format whose extension name ends in, in
+// any case, or "" for none.
+func imageFormatOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	for format, exts := range ImageFormats {
+		if slices.Contains(exts, ext) {
+			return format
+		}
+	}
+	return ""
+}
+
+// NamePrefix returns what {prefix} stands for: the base name of the program
+// the command runs, such as "htop" for "htop -d 10", with characters
+// outside [A-Za-z0-9._-] replaced by underscores, or "terminal" when there
+// is none, as when attaching to a terminal URL.
+func (c *Config) NamePrefix() string {
+	program := ""
+	if len(c.CommandArgs) > 0 {
+		program = c.CommandArgs[0]
+	} else if fields := strings.Fields(c.Command); len(fields) > 0 {
+		program = fields[0]
+	}
+	if i := strings.LastIndexAny(program, `/\`); i >= 0 {
+		program = program[i+1:]
+	}
+	prefix := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, program)
+	if strings.Trim(prefix, "_.") == "" {
+		return "terminal"
+	}
+	return prefix
+}
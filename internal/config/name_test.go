@@ -0,0 +1,137 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNameTemplate(t *testing.T) {
+	fields := NameFields{Prefix: "htop", N: 7, Time: 1500 * time.Millisecond, Action: 2}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{name: "default", template: "", want: "screenshot_007.png"},
+		{name: "explicit default", template: DefaultNameTemplate, want: "screenshot_007.png"},
+		{name: "zero-padded n", template: "login_{n:03}.png", want: "login_007.png"},
+		{name: "unpadded n", template: "login_{n}.png", want: "login_7.png"},
+		{name: "padding grows past width", template: "{n:01}", want: "7.png"},
+		{name: "all placeholders", template: "{prefix}-{action:02}-{n:04}-{time}ms.png", want: "htop-02-0007-1500ms.png"},
+		{name: "png appended", template: "frame_{n}", want: "frame_7.png"},
+		{name: "png suffix in any case", template: "frame_{n}.PNG", want: "frame_7.PNG"},
+		{name: "unknown placeholder", template: "{date}_{n}.png", wantErr: "unknown placeholder {date}"},
+		{name: "missing n", template: "{prefix}_{time}.png", wantErr: "needs {n}"},
+		{name: "unclosed brace", template: "shot_{n", wantErr: "unclosed {"},
+		{name: "stray closing brace", template: "shot}_{n}", wantErr: "unexpected }"},
+		{name: "width without zero", template: "{n:3}", wantErr: "needs a zero-padded width such as {n:03}"},
+		{name: "width on prefix", template: "{prefix:03}_{n}", wantErr: "{prefix} in name template \"{prefix:03}_{n}\" takes no width"},
+		{name: "path", template: "img/{n}.png", wantErr: "must be a file name, not a path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseNameTemplate(tt.template)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tmpl.Format(fields))
+		})
+	}
+}
+
+func TestNameTemplate_Pattern(t *testing.T) {
+	tests := []struct {
+		template string
+		name     string
+		want     bool
+	}{
+		{template: "", name: "screenshot_001.png", want: true},
+		{template: "", name: "Screenshot_1234.PNG", want: true},
+		{template: "", name: "menu.png", want: false},
+		{template: "{prefix}_{n:03}.png", name: "htop_012.png", want: true},
+		{template: "{prefix}_{n:03}.png", name: "vim_012.png", want: false},
+		{template: "login_{n}.png", name: "login_final.png", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.name, func(t *testing.T) {
+			tmpl, err := ParseNameTemplate(tt.template)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tmpl.Pattern("htop").MatchString(tt.name))
+		})
+	}
+}
+
+func TestNameTemplate_ForFormat(t *testing.T) {
+	fields := NameFields{Prefix: "htop", N: 7}
+
+	tests := []struct {
+		template string
+		format   string
+		want     string
+	}{
+		{template: "", format: "png", want: "screenshot_007.png"},
+		{template: "", format: "jpeg", want: "screenshot_007.jpg"},
+		{template: "", format: "webp", want: "screenshot_007.webp"},
+		{template: "frame_{n}", format: "jpeg", want: "frame_7.jpg"},
+		{template: "frame_{n}.jpeg", format: "jpeg", want: "frame_7.jpeg"},
+		{template: "frame_{n}.JPG", format: "png", want: "frame_7.png"},
+		{template: "frame_{n}.webp", format: "webp", want: "frame_7.webp"},
+		{template: "frame_{n}.txt", format: "webp", want: "frame_7.txt.webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.format, func(t *testing.T) {
+			tmpl, err := ParseNameTemplate(tt.template)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tmpl.ForFormat(tt.format).Format(fields))
+		})
+	}
+}
+
+func TestConfig_ImageFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "", want: "png"},
+		{format: "png", want: "png"},
+		{format: "JPEG", want: "jpeg"},
+		{format: "webp", want: "webp"},
+		{format: "gif", want: "png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&Config{Format: tt.format}).ImageFormat())
+		})
+	}
+}
+
+func TestConfig_NamePrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "command line", cfg: Config{Command: "htop -d 10"}, want: "htop"},
+		{name: "program path", cfg: Config{Command: "/usr/bin/vim README.md"}, want: "vim"},
+		{name: "argv", cfg: Config{CommandArgs: []string{"./bin/my app", "--flag"}}, want: "my_app"},
+		{name: "attached terminal", cfg: Config{TerminalURL: "http://localhost:7681"}, want: "terminal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.NamePrefix())
+		})
+	}
+}
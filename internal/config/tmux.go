@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxTmuxPanes is the most panes a tmux layout may have: Pane actions
+// select them with the tmux prefix and a single digit.
+const MaxTmuxPanes = 10
+
+// TmuxPane is one pane of a tmux layout; see ParseTmuxLayout.
+type TmuxPane struct {
+	// Name is what Pane actions and the pane's border call it.
+	Name string
+	// Below splits the previous pane top and bottom instead of side by
+	// side; it is ignored for the first pane.
+	Below bool
+	// Size is the pane's share of the pane it splits, in percent; zero
+	// halves it.
+	Size int
+	// Command runs in the pane; empty starts the default shell.
+	Command string
+}
+
+// tmuxPaneName is what a pane name may contain, so it is one word in a
+// layout and can be quoted in a Pane action.
+var tmuxPaneName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ParseTmuxLayout parses a tmux layout: one pane per line, written as
+//
+//	name [right|below] [size%]: command
+//
+// Each pane after the first splits the one before it, side by side
+// (right, the default) or top and bottom (below), taking size percent of
+// it. Blank lines and lines starting with # are skipped.
+func ParseTmuxLayout(text string) ([]TmuxPane, error) {
+	var panes []TmuxPane
+	seen := map[string]bool{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pane, err := parseTmuxPane(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if seen[pane.Name] {
+			return nil, fmt.Errorf("line %d: pane %q is already defined", i+1, pane.Name)
+		}
+		seen[pane.Name] = true
+		panes = append(panes, pane)
+	}
+	if len(panes) == 0 {
+		return nil, fmt.Errorf("layout has no panes")
+	}
+	if len(panes) > MaxTmuxPanes {
+		return nil, fmt.Errorf("layout has %d panes; at most %d are supported", len(panes), MaxTmuxPanes)
+	}
+	return panes, nil
+}
+
+// parseTmuxPane parses one line of a tmux layout.
+func parseTmuxPane(line string) (TmuxPane, error) {
+	head, command, found := strings.Cut(line, ":")
+	if !found {
+		return TmuxPane{}, fmt.Errorf("expected name: command, got %q", line)
+	}
+	fields := strings.Fields(head)
+	if len(fields) == 0 || !tmuxPaneName.MatchString(fields[0]) {
+		return TmuxPane{}, fmt.Errorf("pane name must be letters, digits, - and _, got %q", strings.TrimSpace(head))
+	}
+	pane := TmuxPane{Name: fields[0], Command: strings.TrimSpace(command)}
+	for _, field := range fields[1:] {
+		switch {
+		case field == "right":
+			pane.Below = false
+		case field == "below":
+			pane.Below = true
+		case strings.HasSuffix(field, "%"):
+			size, err := strconv.Atoi(strings.TrimSuffix(field, "%"))
+			if err != nil || size < 1 || size > 99 {
+				return TmuxPane{}, fmt.Errorf("pane %s: size must be 1%% to 99%%, got %q", pane.Name, field)
+			}
+			pane.Size = size
+		default:
+			return TmuxPane{}, fmt.Errorf("pane %s: unknown option %q; use right, below or a size such as 30%%", pane.Name, field)
+		}
+	}
+	return pane, nil
+}
+
+// TmuxPaneIndex returns the index of the named pane, which its Pane key
+// selects, or -1.
+func TmuxPaneIndex(panes []TmuxPane, name string) int {
+	for i, pane := range panes {
+		if pane.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// TmuxCommand returns the shell command that starts a tmux session with
+// panes, on the tmux server socket named socket so that the user's own
+// sessions and configuration are left alone, and attaches to it. The
+// status line is hidden, each pane's border shows its name and programs in
+// the panes may use 256 colors. The tmux prefix, Ctrl+B, followed by a
+// pane's index selects it, and the session ends once the terminal
+// detaches, taking its server with it.
+func TmuxCommand(socket string, panes []TmuxPane) string {
+	// The options go before new-session, so the first pane gets them too
+	args := []string{
+		"tmux", "-L", socket, "-f", "/dev/null", "start-server",
+		";", "set", "-g", "status", "off",
+		";", "set", "-g", "default-terminal", "screen-256color",
+		";", "set", "-g", "destroy-unattached", "on",
+		";", "set", "-g", "pane-border-status", "top",
+		";", "set", "-g", "pane-border-format", " #{@name} ",
+		";", "new-session", "-s", "scr",
+	}
+	if len(panes) > 0 && panes[0].Command != "" {
+		args = append(args, panes[0].Command)
+	}
+	for i, pane := range panes {
+		if i > 0 {
+			split := []string{";", "split-window", "-h"}
+			if pane.Below {
+				split[2] = "-v"
+			}
+			if pane.Size > 0 {
+				split = append(split, "-l", strconv.Itoa(pane.Size)+"%")
+			}
+			if pane.Command != "" {
+				split = append(split, pane.Command)
+			}
+			args = append(args, split...)
+		}
+		args = append(args, ";", "set", "-p", "@name", pane.Name)
+	}
+	for i := range panes {
+		args = append(args, ";", "bind-key", strconv.Itoa(i), "select-pane", "-t", ":."+strconv.Itoa(i))
+	}
+	args = append(args, ";", "select-pane", "-t", ":.0")
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == ";" {
+			quoted[i] = `\;`
+			continue
+		}
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
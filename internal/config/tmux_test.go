@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/script"
+)
+
+func TestParseTmuxLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  string
+		want    []TmuxPane
+		wantErr string
+	}{
+		{
+			name:   "two panes side by side",
+			layout: "server: ./server --port 8080\nclient: bash\n",
+			want:   []TmuxPane{{Name: "server", Command: "./server --port 8080"}, {Name: "client", Command: "bash"}},
+		},
+		{
+			name:   "splits, sizes, comments and a shell",
+			layout: "# demo\nlogs: tail -f app.log\n\nclient below 30%:\nhelp right 40%: man ls # manual\n",
+			want: []TmuxPane{
+				{Name: "logs", Command: "tail -f app.log"},
+				{Name: "client", Below: true, Size: 30},
+				{Name: "help", Size: 40, Command: "man ls # manual"},
+			},
+		},
+		{name: "command with a colon", layout: "web: curl http://localhost:8080", want: []TmuxPane{{Name: "web", Command: "curl http://localhost:8080"}}},
+		{name: "empty", layout: "# nothing\n\n", wantErr: "layout has no panes"},
+		{name: "no colon", layout: "server ./server", wantErr: `line 1: expected name: command, got "server ./server"`},
+		{name: "bad name", layout: "my.pane: bash", wantErr: "line 1: pane name must be letters, digits, - and _"},
+		{name: "duplicate", layout: "a: bash\na below: bash", wantErr: `line 2: pane "a" is already defined`},
+		{name: "bad size", layout: "a: bash\nb 100%: bash", wantErr: `line 2: pane b: size must be 1% to 99%, got "100%"`},
+		{name: "unknown option", layout: "a left: bash", wantErr: `pane a: unknown option "left"; use right, below or a size such as 30%`},
+		{name: "too many", layout: tmuxPanes(11), wantErr: "layout has 11 panes; at most 10 are supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTmuxLayout(tt.layout)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// tmuxPanes is a layout of n shell panes named pa, pb and so on.
+func tmuxPanes(n int) string {
+	var b strings.Builder
+	for i := range n {
+		b.WriteString("p" + string(rune('a'+i)) + ": bash\n")
+	}
+	return b.String()
+}
+
+func TestTmuxCommand(t *testing.T) {
+	panes := []TmuxPane{
+		{Name: "server", Command: "./server --port 8080"},
+		{Name: "client", Below: true, Size: 30},
+		{Name: "logs", Command: "tail -f 'app log'"},
+	}
+	got := TmuxCommand("scr-42", panes)
+
+	want := `tmux -L scr-42 -f /dev/null start-server` +
+		` \; set -g status off \; set -g default-terminal screen-256color \; set -g destroy-unattached on` +
+		` \; set -g pane-border-status top \; set -g pane-border-format ' #{@name} '` +
+		` \; new-session -s scr './server --port 8080' \; set -p @name server` +
+		` \; split-window -v -l 30% \; set -p @name client` +
+		` \; split-window -h 'tail -f '\''app log'\''' \; set -p @name logs` +
+		` \; bind-key 0 select-pane -t :.0 \; bind-key 1 select-pane -t :.1 \; bind-key 2 select-pane -t :.2` +
+		` \; select-pane -t :.0`
+	assert.Equal(t, want, got)
+
+	// The shell hands tmux each command as one argument, and ; on its own
+	out, err := exec.Command("sh", "-c", "printf '%s\\n' "+strings.TrimPrefix(got, "tmux ")).Output()
+	require.NoError(t, err)
+	args := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Contains(t, args, "tail -f 'app log'")
+	assert.Contains(t, args, " #{@name} ")
+	assert.Contains(t, args, ";")
+}
+
+func TestValidate_TmuxPanes(t *testing.T) {
+	panes := []TmuxPane{{Name: "server"}, {Name: "client"}}
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "pane in the layout", cfg: Config{Command: "tmux", TmuxPanes: panes, Actions: []script.Action{{Kind: script.ActionPane, Name: "client"}}}},
+		{name: "layout without Pane actions", cfg: Config{Command: "tmux", TmuxPanes: panes}},
+		{
+			name:    "pane not in the layout",
+			cfg:     Config{Command: "tmux", TmuxPanes: panes, Actions: []script.Action{{Kind: script.ActionPane, Name: "db"}}},
+			wantErr: `pane "db" is not in the tmux layout (panes: server, client)`,
+		},
+		{
+			name:    "pane without a layout",
+			cfg:     Config{Command: "bash", Actions: []script.Action{{Kind: script.ActionPane, Name: "client"}}},
+			wantErr: `pane "client" needs a tmux layout; pass --tmux-layout`,
+		},
+		{
+			name:    "layout with a terminal URL",
+			cfg:     Config{TerminalURL: "http://localhost:7681", TmuxPanes: panes},
+			wantErr: "a tmux layout starts its own session, so it cannot attach to a terminal URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.OutputDir = "/tmp/output"
+			cfg.TTydPort = 8080
+			cfg.Timeout = 10 * time.Second
+			if cfg.Actions == nil {
+				cfg.Actions = []script.Action{{Kind: script.ActionKey, Key: "Enter", Repeat: 1}}
+			}
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
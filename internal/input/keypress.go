@@ -20,8 +20,26 @@ var specialKeyCodes = map[string]string{
 	"pagedown":  "PageDown",
 	"backspace": "Backspace",
 	"delete":    "Delete",
-	"ctrl+c":    "c",
-	"ctrl+d":    "d",
+}
+
+// Modifier is a set of modifier keys held while a key is pressed.
+type Modifier uint8
+
+const (
+	// ModCtrl is the Control key.
+	ModCtrl Modifier = 1 << iota
+	// ModAlt is the Alt (Option) key.
+	ModAlt
+	// ModShift is the Shift key.
+	ModShift
+)
+
+// modifierNames maps the lower-case modifier prefixes of a key, as in
+// "ctrl+shift+tab", to their Modifier.
+var modifierNames = map[string]Modifier{
+	"ctrl":  ModCtrl,
+	"alt":   ModAlt,
+	"shift": ModShift,
 }
 
 // isSinglePrintableASCII reports whether key is exactly one printable ASCII character.
@@ -59,34 +77,74 @@ func ParseKeypresses(keypressStr string) ([]string, error) {
 	return result, nil
 }
 
-// IsValidKey checks if a key is valid (either alphanumeric or a recognized special key).
-// Check is case-insensitive for special keys.
+// IsValidKey checks if a key is valid: a single printable character or a
+// recognized special key, optionally prefixed by modifiers such as
+// "Alt+x" or "Ctrl+Shift+Tab". Check is case-insensitive for special keys
+// and modifiers.
 func IsValidKey(key string) bool {
-	if isSinglePrintableASCII(key) {
-		return true
-	}
-	_, ok := specialKeyCodes[strings.ToLower(key)]
-	return ok
+	_, _, err := ParseKey(key)
+	return err == nil
 }
 
 // KeyToKeyCode maps a key name to its Chrome DevTools Protocol key code.
 // Single character keys are returned as-is.
 // Special keys are mapped to their CDP codes.
-// Ctrl+C and Ctrl+D return "c" and "d" respectively.
+// Modifiers are dropped, so Ctrl+C and Ctrl+D return "c" and "d"
+// respectively; use ParseKey to keep them.
 // Returns error if the key is not recognized.
 func KeyToKeyCode(key string) (string, error) {
-	// Single character keys are sent directly, except space which is a named key in CDP.
-	if isSinglePrintableASCII(key) {
-		if key == " " {
-			return "Space", nil
+	code, _, err := ParseKey(key)
+	return code, err
+}
+
+// ParseKey splits a key such as "Shift+Tab" or "ctrl+alt+x" into the key
+// code of its last part, as KeyToKeyCode returns it, and the modifiers
+// before it. Shift with a letter yields the upper-case letter, as typed.
+func ParseKey(key string) (code string, mods Modifier, err error) {
+	if code, ok := baseKeyCode(key); ok {
+		return code, 0, nil
+	}
+
+	parts := strings.Split(key, "+")
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := modifierNames[strings.ToLower(part)]
+		if !ok {
+			return "", 0, fmt.Errorf("key %q has unknown modifier %q (use Ctrl, Alt or Shift)", key, part)
+		}
+		if mods&mod != 0 {
+			return "", 0, fmt.Errorf("key %q repeats modifier %q", key, part)
 		}
-		return key, nil
+		mods |= mod
+	}
+	if mods == 0 {
+		return "", 0, fmt.Errorf("key %q is not recognized", key)
 	}
 
-	lowerKey := strings.ToLower(key)
-	if code, exists := specialKeyCodes[lowerKey]; exists {
-		return code, nil
+	base := parts[len(parts)-1]
+	code, ok := baseKeyCode(base)
+	if !ok {
+		return "", 0, fmt.Errorf("key %q is not recognized", key)
+	}
+	if len(code) == 1 {
+		// Modified letters are sent as their unshifted character, like a
+		// keyboard does, unless Shift is held
+		code = strings.ToLower(code)
+		if mods&ModShift != 0 {
+			code = strings.ToUpper(code)
+		}
 	}
+	return code, mods, nil
+}
 
-	return "", fmt.Errorf("key %q is not recognized", key)
+// baseKeyCode maps a key without modifiers to its CDP key code.
+func baseKeyCode(key string) (string, bool) {
+	// Single character keys are sent directly, except space which is a named key in CDP.
+	if isSinglePrintableASCII(key) {
+		if key == " " {
+			return "Space", true
+		}
+		return key, true
+	}
+	code, ok := specialKeyCodes[strings.ToLower(key)]
+	return code, ok
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseKeypresses_ValidInput(t *testing.T) {
@@ -504,3 +505,52 @@ func TestKeyToKeyCode_InvalidKey(t *testing.T) {
 		})
 	}
 }
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		wantCode string
+		wantMods Modifier
+		wantErr  string
+	}{
+		{name: "plain letter", key: "a", wantCode: "a"},
+		{name: "plus sign is a key", key: "+", wantCode: "+"},
+		{name: "special key", key: "enter", wantCode: "Enter"},
+		{name: "ctrl letter", key: "Ctrl+C", wantCode: "c", wantMods: ModCtrl},
+		{name: "ctrl named key", key: "Ctrl+Left", wantCode: "ArrowLeft", wantMods: ModCtrl},
+		{name: "ctrl backspace", key: "ctrl+backspace", wantCode: "Backspace", wantMods: ModCtrl},
+		{name: "alt letter", key: "alt+x", wantCode: "x", wantMods: ModAlt},
+		{name: "shift tab", key: "Shift+Tab", wantCode: "Tab", wantMods: ModShift},
+		{name: "shift letter is upper case", key: "shift+a", wantCode: "A", wantMods: ModShift},
+		{name: "chained modifiers", key: "Ctrl+Shift+c", wantCode: "C", wantMods: ModCtrl | ModShift},
+		{name: "unknown modifier", key: "Meta+x", wantErr: `unknown modifier "Meta"`},
+		{name: "repeated modifier", key: "alt+Alt+x", wantErr: `repeats modifier "Alt"`},
+		{name: "unknown key", key: "Alt+NotAKey", wantErr: "is not recognized"},
+		{name: "ctrl unknown key", key: "Ctrl+NotAKey", wantErr: "is not recognized"},
+		{name: "not a combination", key: "NotAKey", wantErr: "is not recognized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, mods, err := ParseKey(tt.key)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantMods, mods)
+		})
+	}
+}
+
+func TestParseKeypresses_Modifiers(t *testing.T) {
+	got, err := ParseKeypresses("j,Alt+f,Shift+Tab,Enter")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"j", "Alt+f", "Shift+Tab", "Enter"}, got)
+
+	_, err = ParseKeypresses("j,Super+f")
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+// Package logfile provides a size-capped, rotating log file writer, so long
+// or noisy runs cannot fill the disk with debug output.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize is the size at which a log file is rotated when none is set.
+const DefaultMaxSize = 10 << 20
+
+// Writer appends to a log file and rotates it once it reaches MaxSize:
+// path becomes path.1, path.1 becomes path.2, and so on, keeping at most
+// Keep old files. Every file that follows a rotation starts with a line
+// saying so. It is safe for concurrent use.
+type Writer struct {
+	path    string
+	maxSize int64
+	keep    int
+	now     func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open creates or truncates the log file at path. A maxSize of zero selects
+// DefaultMaxSize; keep is the number of rotated files to retain, and zero
+// discards old output on rotation.
+func Open(path string, maxSize int64, keep int) (*Writer, error) {
+	if maxSize < 0 {
+		return nil, fmt.Errorf("log max size must be >= 0, got %d", maxSize)
+	}
+	if keep < 0 {
+		return nil, fmt.Errorf("log keep must be >= 0, got %d", keep)
+	}
+	if maxSize == 0 {
+		maxSize = DefaultMaxSize
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create log file: %w", err)
+	}
+	return &Writer{path: path, maxSize: maxSize, keep: keep, now: time.Now, file: f}, nil
+}
+
+// Write appends p, rotating first if p would take the file past its maximum
+// size. A single write larger than the maximum keeps only its tail, with a
+// note of how much was dropped.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	n := len(p)
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if room := w.maxSize - w.size; int64(len(p)) > room {
+		// The note for the whole write is at least as long as the final one
+		keep := max(room-int64(len(dropNote(len(p)))), 0)
+		if err := w.writeString(dropNote(len(p) - int(keep))); err != nil {
+			return 0, err
+		}
+		p = p[int64(len(p))-keep:]
+	}
+	written, err := w.file.Write(p)
+	w.size += int64(written)
+	if err != nil {
+		return 0, fmt.Errorf("write log file: %w", err)
+	}
+	return n, nil
+}
+
+// dropNote is the line recorded in place of n bytes that did not fit.
+func dropNote(n int) string {
+	return fmt.Sprintf("--- %d bytes dropped: single write exceeded the log size limit ---\n", n)
+}
+
+// Close closes the current log file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate shifts the existing files up by one, dropping the oldest, and
+// starts a new file with a note about what happened to earlier output.
+// Callers hold w.mu.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	w.file = nil
+
+	var note string
+	if w.keep == 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+		note = fmt.Sprintf("--- log truncated at %s: earlier output discarded ---\n", w.now().Format(time.RFC3339))
+	} else {
+		for i := w.keep - 1; i >= 1; i-- {
+			if err := os.Rename(w.rotated(i), w.rotated(i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("rotate log file: %w", err)
+			}
+		}
+		if err := os.Rename(w.path, w.rotated(1)); err != nil {
+			return fmt.Errorf("rotate log file: %w", err)
+		}
+		note = fmt.Sprintf("--- log rotated at %s: earlier output in %s ---\n", w.now().Format(time.RFC3339), filepath.Base(w.rotated(1)))
+	}
+
+	f, err := os.Create(w.path)
+	if err != nil {
+		return fmt.Errorf("create log file: %w", err)
+	}
+	w.file = f
+	w.size = 0
+	return w.writeString(note)
+}
+
+// rotated returns the name of the i-th rotated file.
+func (w *Writer) rotated(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
+
+// writeString writes s to the current file. Callers hold w.mu.
+func (w *Writer) writeString(s string) error {
+	n, err := w.file.WriteString(s)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write log file: %w", err)
+	}
+	return nil
+}
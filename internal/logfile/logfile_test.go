@@ -0,0 +1,127 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_InvalidLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		keep    int
+		wantErr string
+	}{
+		{name: "negative size", maxSize: -1, wantErr: "log max size must be >= 0"},
+		{name: "negative keep", keep: -1, wantErr: "log keep must be >= 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(filepath.Join(t.TempDir(), "scr.log"), tt.maxSize, tt.keep)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestWriter_Rotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		keep      int
+		writes    []string
+		wantFiles map[string]string
+		wantNote  string
+	}{
+		{
+			name:   "under the limit",
+			keep:   2,
+			writes: []string{"one\n", "two\n"},
+			wantFiles: map[string]string{
+				"scr.log": "one\ntwo\n",
+			},
+		},
+		{
+			name:   "rotates and keeps old files",
+			keep:   2,
+			writes: []string{strings.Repeat("a", 600), strings.Repeat("b", 600), strings.Repeat("c", 600)},
+			wantFiles: map[string]string{
+				"scr.log.1": strings.Repeat("b", 600),
+				"scr.log.2": strings.Repeat("a", 600),
+			},
+			wantNote: "--- log rotated at",
+		},
+		{
+			name:   "drops the oldest file",
+			keep:   1,
+			writes: []string{strings.Repeat("a", 600), strings.Repeat("b", 600), strings.Repeat("c", 600)},
+			wantFiles: map[string]string{
+				"scr.log.1": strings.Repeat("b", 600),
+			},
+			wantNote: "--- log rotated at",
+		},
+		{
+			name:     "keep zero discards old output",
+			keep:     0,
+			writes:   []string{strings.Repeat("a", 600), strings.Repeat("b", 600)},
+			wantNote: "--- log truncated at",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "scr.log")
+			w, err := Open(path, 1024, tt.keep)
+			require.NoError(t, err)
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			require.NoError(t, w.Close())
+
+			for name, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				assert.Contains(t, string(got), want, name)
+			}
+			current, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(current), 1024)
+			if tt.wantNote != "" {
+				assert.True(t, strings.HasPrefix(string(current), tt.wantNote), "current log starts with %q, got %q", tt.wantNote, current)
+			}
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(entries), tt.keep+1)
+		})
+	}
+}
+
+func TestWriter_OversizedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scr.log")
+	w, err := Open(path, 128, 1)
+	require.NoError(t, err)
+
+	big := strings.Repeat("x", 1000) + "END"
+	n, err := w.Write([]byte(big))
+	require.NoError(t, err)
+	assert.Equal(t, len(big), n)
+	require.NoError(t, w.Close())
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(got), 128)
+	assert.Contains(t, string(got), "bytes dropped: single write exceeded the log size limit")
+	assert.True(t, strings.HasSuffix(string(got), "END"), "keeps the tail of the write")
+
+	_, err = w.Write([]byte("late"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
@@ -1,6 +1,13 @@
 package script
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTypeSpeed is the per-character delay of a Type action without @speed.
+const DefaultTypeSpeed = 50 * time.Millisecond
 
 // ActionKind represents the type of action in a tape script.
 type ActionKind int
@@ -14,23 +21,268 @@ const (
 	ActionKey
 	// ActionCtrl presses a control key combination.
 	ActionCtrl
+	// ActionScreenshot captures a screenshot immediately.
+	ActionScreenshot
+	// ActionWait blocks until a pattern appears in the terminal.
+	ActionWait
+	// ActionSet changes a terminal setting such as the theme.
+	ActionSet
+	// ActionSignal delivers a signal to the captured command's processes.
+	ActionSignal
+	// ActionScene starts a named scene; the frames that follow go into a
+	// directory of their own.
+	ActionScene
+	// ActionBurst captures several frames in quick succession.
+	ActionBurst
+	// ActionHide stops taking frames until the next ActionShow; actions
+	// still run.
+	ActionHide
+	// ActionShow resumes taking frames after an ActionHide.
+	ActionShow
+	// ActionPane selects a named pane of the tmux layout; the keys and
+	// text that follow go to it.
+	ActionPane
+)
+
+// kindNames are the names of the action kinds in JSON, indexed by kind.
+var kindNames = []string{
+	ActionType:       "type",
+	ActionSleep:      "sleep",
+	ActionKey:        "key",
+	ActionCtrl:       "ctrl",
+	ActionScreenshot: "screenshot",
+	ActionWait:       "wait",
+	ActionSet:        "set",
+	ActionSignal:     "signal",
+	ActionScene:      "scene",
+	ActionBurst:      "burst",
+	ActionHide:       "hide",
+	ActionShow:       "show",
+	ActionPane:       "pane",
+}
+
+// String returns the kind's name in JSON, such as "type" or "sleep".
+func (k ActionKind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("ActionKind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Modifier is a set of modifier keys held while a key is pressed (for
+// ActionKey).
+type Modifier uint8
+
+const (
+	// ModCtrl is the Control key.
+	ModCtrl Modifier = 1 << iota
+	// ModAlt is the Alt (Option) key.
+	ModAlt
+	// ModShift is the Shift key.
+	ModShift
 )
 
+// modifierNames lists the modifiers in the order they are written.
+var modifierNames = []struct {
+	mod  Modifier
+	name string
+}{
+	{ModCtrl, "Ctrl"},
+	{ModAlt, "Alt"},
+	{ModShift, "Shift"},
+}
+
+// String formats the modifiers as they prefix a key, e.g. "Ctrl+Shift".
+func (m Modifier) String() string {
+	var names []string
+	for _, n := range modifierNames {
+		if m&n.mod != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "+")
+}
+
 // Action represents a single action in a tape script.
 type Action struct {
-	// Kind is the type of action (Type, Sleep, Key, Ctrl).
+	// Kind is the type of action; see ActionKind.
 	Kind ActionKind
 	// Text is the text to type (for ActionType).
 	Text string
 	// Key is the key name (for ActionKey and ActionCtrl).
 	Key string
-	// Duration is the sleep duration (for ActionSleep).
+	// Modifiers are held while Key is pressed (for ActionKey), as in
+	// Alt+X or Shift+Tab. Ctrl with a single character is an ActionCtrl.
+	Modifiers Modifier
+	// Duration is the sleep duration (for ActionSleep), or the time
+	// between frames (for ActionBurst).
 	Duration time.Duration
 	// Speed is the typing speed as a per-character delay (for ActionType).
 	Speed time.Duration
+	// Total is the time to type all of Text in (for ActionType, with
+	// "Type over"); when set, it replaces Speed. See CharDelay.
+	Total time.Duration
+	// Secret marks an ActionType written as TypeSecret: Text holds secret
+	// params, which are typed but never shown in logs or listings, and no
+	// frames are taken until the next Enter or Ctrl+L.
+	Secret bool
 	// Delay is the delay after typing this action (for ActionType, ActionKey, ActionCtrl).
 	Delay time.Duration
+	// Name is the optional screenshot label (for ActionScreenshot), the
+	// scene name (for ActionScene) or the pane name (for ActionPane).
+	Name string
+	// Pattern is the regular expression to wait for (for ActionWait).
+	Pattern string
+	// Prompt makes an ActionWait wait for the shell to show its prompt
+	// instead of for Pattern.
+	Prompt bool
+	// AltScreen makes an ActionWait wait for the terminal to switch to its
+	// alternate screen, as full-screen programs such as vim and less do on
+	// start, instead of for Pattern.
+	AltScreen bool
+	// Timeout is how long to wait for Pattern before failing (for ActionWait).
+	Timeout time.Duration
+	// Setting is the lower-case setting name and Value its new value (for ActionSet).
+	Setting string
+	Value   string
+	// Signal is the signal name without the SIG prefix, e.g. "INT" (for
+	// ActionSignal).
+	Signal string
 	// Repeat is the number of times to repeat the key press (for ActionKey and ActionCtrl).
-	// Defaults to 1.
+	// Defaults to 1. For ActionBurst it is the number of frames.
 	Repeat int
 }
+
+// String formats the action in tape script syntax, for listings such as a
+// dry run. Default modifiers (Type speed, Key repeat of 1) are omitted, and
+// the text of a TypeSecret is redacted.
+func (a Action) String() string {
+	switch a.Kind {
+	case ActionType:
+		if a.Secret {
+			return "TypeSecret [redacted]"
+		}
+		if a.Total > 0 {
+			return fmt.Sprintf("Type over %v %s", a.Total, quote(a.Text))
+		}
+		if a.Speed != DefaultTypeSpeed {
+			return fmt.Sprintf("Type@%v %s", a.Speed, quote(a.Text))
+		}
+		return "Type " + quote(a.Text)
+	case ActionSleep:
+		return fmt.Sprintf("Sleep %v", a.Duration)
+	case ActionKey:
+		s := a.KeyName()
+		if a.Delay > 0 {
+			s += fmt.Sprintf("@%v", a.Delay)
+		}
+		if a.Repeat > 1 {
+			s += fmt.Sprintf(" %d", a.Repeat)
+		}
+		return s
+	case ActionCtrl:
+		return "Ctrl+" + strings.ToUpper(a.Key)
+	case ActionScreenshot:
+		if a.Name != "" {
+			return "Screenshot " + quote(a.Name)
+		}
+		return "Screenshot"
+	case ActionWait:
+		if a.Prompt {
+			return fmt.Sprintf("Wait Prompt %v", a.Timeout)
+		}
+		if a.AltScreen {
+			return fmt.Sprintf("Wait AltScreen %v", a.Timeout)
+		}
+		return fmt.Sprintf("Wait /%s/ %v", strings.ReplaceAll(a.Pattern, "/", `\/`), a.Timeout)
+	case ActionSet:
+		if numericSettings[a.Setting] {
+			return fmt.Sprintf("Set %s %s", settingNames[a.Setting], a.Value)
+		}
+		return fmt.Sprintf("Set %s %s", settingNames[a.Setting], quote(a.Value))
+	case ActionSignal:
+		return "Signal " + a.Signal
+	case ActionScene:
+		return "Scene " + quote(a.Name)
+	case ActionBurst:
+		if a.Duration != DefaultBurstSpacing {
+			return fmt.Sprintf("Burst %d @%v", a.Repeat, a.Duration)
+		}
+		return fmt.Sprintf("Burst %d", a.Repeat)
+	case ActionHide:
+		return "Hide"
+	case ActionShow:
+		return "Show"
+	case ActionPane:
+		return "Pane " + quote(a.Name)
+	default:
+		return fmt.Sprintf("Unknown(%d)", int(a.Kind))
+	}
+}
+
+// KeyName returns the key of an ActionKey with its modifiers, e.g.
+// "Shift+Tab".
+func (a Action) KeyName() string {
+	if a.Modifiers == 0 {
+		return a.Key
+	}
+	return a.Modifiers.String() + "+" + a.Key
+}
+
+// CharDelay returns the delay after each character of a Type action: Speed,
+// or with Total, Total spread evenly over the characters of Text so the
+// whole text takes Total. Empty text types nothing, so it has no delay.
+func (a Action) CharDelay() time.Duration {
+	if a.Total <= 0 {
+		return a.Speed
+	}
+	n := len([]rune(a.Text))
+	if n == 0 {
+		return 0
+	}
+	return a.Total / time.Duration(n)
+}
+
+// SettingsOnly reports whether actions are all Set actions, or none, so a
+// script only configures the session and never interacts with it.
+func SettingsOnly(actions []Action) bool {
+	for _, action := range actions {
+		if action.Kind != ActionSet {
+			return false
+		}
+	}
+	return true
+}
+
+// quote wraps s in single quotes, or double quotes if s contains a single
+// quote. Text with both is written as quoted strings back to back, which
+// the lexer joins, each taking the longer of the runs up to the next single
+// or double quote.
+func quote(s string) string {
+	switch {
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'"
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`
+	}
+
+	var sb strings.Builder
+	for s != "" {
+		mark, end := "'", runEnd(s, '\'')
+		if double := runEnd(s, '"'); double > end {
+			mark, end = `"`, double
+		}
+		sb.WriteString(mark + s[:end] + mark)
+		s = s[end:]
+	}
+	return sb.String()
+}
+
+// runEnd returns the length of the run at the start of s that mark can
+// quote: up to the next mark, or all of s.
+func runEnd(s string, mark byte) int {
+	if i := strings.IndexByte(s, mark); i >= 0 {
+		return i
+	}
+	return len(s)
+}
@@ -1,6 +1,7 @@
 package script
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,11 @@ func TestActionKind_Constants(t *testing.T) {
 	assert.Equal(t, ActionKind(1), ActionSleep)
 	assert.Equal(t, ActionKind(2), ActionKey)
 	assert.Equal(t, ActionKind(3), ActionCtrl)
+	assert.Equal(t, ActionKind(4), ActionScreenshot)
+	assert.Equal(t, ActionKind(5), ActionWait)
+	assert.Equal(t, ActionKind(6), ActionSet)
+	assert.Equal(t, ActionKind(7), ActionSignal)
+	assert.Equal(t, ActionKind(8), ActionScene)
 }
 
 func TestAction_ZeroValues(t *testing.T) {
@@ -47,3 +53,100 @@ func TestAction_FullValues(t *testing.T) {
 	assert.Equal(t, 500*time.Millisecond, action.Delay)
 	assert.Equal(t, 3, action.Repeat)
 }
+
+func TestAction_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   string
+	}{
+		{name: "type", action: Action{Kind: ActionType, Text: "ls -la", Speed: DefaultTypeSpeed}, want: "Type 'ls -la'"},
+		{name: "type with speed", action: Action{Kind: ActionType, Text: "fast", Speed: 10 * time.Millisecond}, want: "Type@10ms 'fast'"},
+		{name: "type over", action: Action{Kind: ActionType, Text: "make", Speed: DefaultTypeSpeed, Total: 2 * time.Second}, want: "Type over 2s 'make'"},
+		{name: "type with single quote", action: Action{Kind: ActionType, Text: "it's", Speed: DefaultTypeSpeed}, want: `Type "it's"`},
+		{name: "sleep", action: Action{Kind: ActionSleep, Duration: 2 * time.Second}, want: "Sleep 2s"},
+		{name: "key", action: Action{Kind: ActionKey, Key: "Enter", Repeat: 1}, want: "Enter"},
+		{name: "key with delay and repeat", action: Action{Kind: ActionKey, Key: "Down", Delay: 200 * time.Millisecond, Repeat: 3}, want: "Down@200ms 3"},
+		{name: "ctrl", action: Action{Kind: ActionCtrl, Key: "c"}, want: "Ctrl+C"},
+		{name: "alt key", action: Action{Kind: ActionKey, Key: "x", Modifiers: ModAlt, Repeat: 1}, want: "Alt+x"},
+		{name: "chained modifiers with repeat", action: Action{Kind: ActionKey, Key: "Tab", Modifiers: ModShift | ModCtrl, Repeat: 2}, want: "Ctrl+Shift+Tab 2"},
+		{name: "screenshot", action: Action{Kind: ActionScreenshot}, want: "Screenshot"},
+		{name: "named screenshot", action: Action{Kind: ActionScreenshot, Name: "menu"}, want: "Screenshot 'menu'"},
+		{name: "set", action: Action{Kind: ActionSet, Setting: "theme", Value: "nord"}, want: "Set Theme 'nord'"},
+		{name: "set width", action: Action{Kind: ActionSet, Setting: "width", Value: "1024"}, want: "Set Width 1024"},
+		{name: "signal", action: Action{Kind: ActionSignal, Signal: "WINCH"}, want: "Signal WINCH"},
+		{name: "scene", action: Action{Kind: ActionScene, Name: "intro"}, want: "Scene 'intro'"},
+		{name: "pane", action: Action{Kind: ActionPane, Name: "client"}, want: "Pane 'client'"},
+		{name: "wait", action: Action{Kind: ActionWait, Pattern: "a/b", Timeout: 5 * time.Second}, want: `Wait /a\/b/ 5s`},
+		{name: "wait prompt", action: Action{Kind: ActionWait, Prompt: true, Timeout: 5 * time.Second}, want: "Wait Prompt 5s"},
+		{name: "wait altscreen", action: Action{Kind: ActionWait, AltScreen: true, Timeout: 5 * time.Second}, want: "Wait AltScreen 5s"},
+		{name: "burst", action: Action{Kind: ActionBurst, Repeat: 10, Duration: DefaultBurstSpacing}, want: "Burst 10"},
+		{name: "burst with spacing", action: Action{Kind: ActionBurst, Repeat: 4, Duration: 20 * time.Millisecond}, want: "Burst 4 @20ms"},
+		{name: "hide", action: Action{Kind: ActionHide}, want: "Hide"},
+		{name: "show", action: Action{Kind: ActionShow}, want: "Show"},
+		{name: "type secret", action: Action{Kind: ActionType, Text: "hunter2", Speed: DefaultTypeSpeed, Secret: true}, want: "TypeSecret [redacted]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.action.String())
+		})
+	}
+}
+
+func TestAction_String_RoundTrip(t *testing.T) {
+	src := `Type@30ms 'echo hi' Type over 1s 'ls' Enter@200ms Down 3 Ctrl+C Shift+Tab Alt+b@50ms 2 Sleep 500ms Screenshot 'done' Wait /\$ $/ 5s Wait Prompt 2s Wait AltScreen 1s Set Theme 'solarized-dark' Set Height 600 Signal INT Scene 'setup' Burst 3 @20ms Burst 2 Hide Show Pane 'client'`
+	actions, err := Parse(src)
+	assert.NoError(t, err)
+
+	parts := make([]string, len(actions))
+	for i, a := range actions {
+		parts[i] = a.String()
+	}
+	reparsed, err := Parse(strings.Join(parts, " "))
+	assert.NoError(t, err)
+	assert.Equal(t, actions, reparsed)
+}
+
+func TestAction_CharDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   time.Duration
+	}{
+		{name: "speed", action: Action{Kind: ActionType, Text: "abc", Speed: 30 * time.Millisecond}, want: 30 * time.Millisecond},
+		{name: "total spread over characters", action: Action{Kind: ActionType, Text: "abcd", Speed: DefaultTypeSpeed, Total: 2 * time.Second}, want: 500 * time.Millisecond},
+		{name: "total counts runes, not bytes", action: Action{Kind: ActionType, Text: "héé", Speed: DefaultTypeSpeed, Total: 300 * time.Millisecond}, want: 100 * time.Millisecond},
+		{name: "total with empty text", action: Action{Kind: ActionType, Speed: DefaultTypeSpeed, Total: 2 * time.Second}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.action.CharDelay())
+		})
+	}
+}
+
+func TestSettingsOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{name: "empty", script: "", want: true},
+		{name: "comments only", script: "# intro\n# nothing typed\n", want: true},
+		{name: "settings only", script: "# demo\nSet Theme dracula\nSet Width 800\n", want: true},
+		{name: "settings then input", script: "Set Theme dracula\nEnter", want: false},
+		{name: "sleep", script: "Sleep 1s", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseScript(tt.script, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, SettingsOnly(parsed.Actions))
+		})
+	}
+}
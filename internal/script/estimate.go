@@ -0,0 +1,70 @@
+package script
+
+import "time"
+
+// EstimateOptions configures Estimate.
+type EstimateOptions struct {
+	// Interval is the periodic screenshot interval; 0 counts no interval
+	// frames.
+	Interval time.Duration
+}
+
+// Estimation is the expected cost of running a script.
+type Estimation struct {
+	// Actions is the number of actions in the script.
+	Actions int
+	// Duration is the least time the actions take: sleeps, typing and
+	// post-action delays. Startup, key round-trips and Waits, which may
+	// match at once, are not included.
+	Duration time.Duration
+	// Frames is the expected number of frames: the initial and final
+	// frames, one per Screenshot action, those of Burst actions and one
+	// per interval tick. Screenshots, bursts and a final frame after a
+	// Hide are not counted.
+	Frames int
+}
+
+// Estimate returns the minimum duration of actions and the frames a run of
+// them is expected to take.
+func Estimate(actions []Action, opts EstimateOptions) Estimation {
+	est := Estimation{Actions: len(actions), Frames: 1}
+	hidden := false
+	for _, action := range actions {
+		est.Duration += ActionDuration(action)
+		switch {
+		case action.Kind == ActionHide || action.Kind == ActionShow:
+			hidden = action.Kind == ActionHide
+		case hidden:
+		case action.Kind == ActionScreenshot:
+			est.Frames++
+		case action.Kind == ActionBurst:
+			est.Frames += action.Repeat
+		}
+	}
+	if !hidden {
+		est.Frames++ // the final frame
+	}
+	if opts.Interval > 0 && est.Duration > 0 {
+		// Ticks fall at every multiple of the interval before the end
+		est.Frames += int((est.Duration - 1) / opts.Interval)
+	}
+	return est
+}
+
+// ActionDuration is the least time an action takes. Key repeats are sent
+// back to back, so only the delay after the last one counts; a Burst takes
+// the spacing between its frames, not the time to capture them.
+func ActionDuration(action Action) time.Duration {
+	switch action.Kind {
+	case ActionType:
+		return time.Duration(len([]rune(action.Text)))*action.CharDelay() + action.Delay
+	case ActionSleep:
+		return action.Duration
+	case ActionKey, ActionCtrl:
+		return action.Delay
+	case ActionBurst:
+		return time.Duration(action.Repeat-1) * action.Duration
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,84 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		opts    EstimateOptions
+		want    Estimation
+	}{
+		{
+			name: "no actions",
+			want: Estimation{Frames: 2},
+		},
+		{
+			name: "sleeps typing and delays",
+			actions: []Action{
+				{Kind: ActionType, Text: "abc", Speed: 100 * time.Millisecond, Delay: 50 * time.Millisecond},
+				{Kind: ActionSleep, Duration: time.Second},
+				{Kind: ActionKey, Key: "Down", Repeat: 3, Delay: 200 * time.Millisecond},
+				{Kind: ActionCtrl, Key: "c", Delay: 10 * time.Millisecond},
+				{Kind: ActionWait, Pattern: "\\$", Timeout: 5 * time.Second},
+			},
+			want: Estimation{Actions: 5, Duration: 1560 * time.Millisecond, Frames: 2},
+		},
+		{
+			name: "type over counts its total",
+			actions: []Action{
+				{Kind: ActionType, Text: "make", Speed: DefaultTypeSpeed, Total: 2 * time.Second},
+			},
+			want: Estimation{Actions: 1, Duration: 2 * time.Second, Frames: 2},
+		},
+		{
+			name: "screenshots and interval ticks",
+			actions: []Action{
+				{Kind: ActionSleep, Duration: time.Second},
+				{Kind: ActionScreenshot},
+				{Kind: ActionSleep, Duration: 500 * time.Millisecond},
+			},
+			opts: EstimateOptions{Interval: 500 * time.Millisecond},
+			// Ticks at 500ms and 1s; the one at 1.5s is the final frame
+			want: Estimation{Actions: 3, Duration: 1500 * time.Millisecond, Frames: 5},
+		},
+		{
+			name: "bursts take their spacing",
+			actions: []Action{
+				{Kind: ActionBurst, Repeat: 5, Duration: 50 * time.Millisecond},
+				{Kind: ActionBurst, Repeat: 1, Duration: time.Second},
+			},
+			want: Estimation{Actions: 2, Duration: 200 * time.Millisecond, Frames: 8},
+		},
+		{
+			name: "hidden frames are not counted",
+			actions: []Action{
+				{Kind: ActionHide},
+				{Kind: ActionScreenshot},
+				{Kind: ActionShow},
+				{Kind: ActionScreenshot},
+				{Kind: ActionHide},
+				{Kind: ActionBurst, Repeat: 3, Duration: 50 * time.Millisecond},
+			},
+			// Initial frame and one screenshot; no final frame while hidden
+			want: Estimation{Actions: 6, Duration: 100 * time.Millisecond, Frames: 2},
+		},
+		{
+			name:    "interval longer than the script",
+			actions: []Action{{Kind: ActionSleep, Duration: 100 * time.Millisecond}},
+			opts:    EstimateOptions{Interval: time.Second},
+			want:    Estimation{Actions: 1, Duration: 100 * time.Millisecond, Frames: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Estimate(tt.actions, tt.opts))
+		})
+	}
+}
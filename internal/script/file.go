@@ -0,0 +1,34 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some editors prepend to UTF-8 files.
+const utf8BOM = "\uFEFF"
+
+// ReadFile reads a tape script from disk. A leading UTF-8 BOM is stripped
+// and CRLF line endings are normalized to LF so reported line and column
+// numbers match what the user sees in an editor. An empty file yields an
+// empty script.
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read script file: %w", err)
+	}
+	return normalize(string(data)), nil
+}
+
+// normalize strips a UTF-8 BOM and converts CRLF line endings to LF.
+func normalize(src string) string {
+	src = strings.TrimPrefix(src, utf8BOM)
+	return strings.ReplaceAll(src, "\r\n", "\n")
+}
+
+// IsFile reports whether path names an existing regular file.
+func IsFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
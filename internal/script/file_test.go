@@ -0,0 +1,75 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "multi-line script", content: "Type 'ls'\nEnter\n", want: "Type 'ls'\nEnter\n"},
+		{name: "CRLF line endings", content: "Type 'ls'\r\nEnter\r\n", want: "Type 'ls'\nEnter\n"},
+		{name: "UTF-8 BOM", content: "\uFEFFType 'ls'", want: "Type 'ls'"},
+		{name: "empty file", content: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "demo.tape")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			got, err := ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ReadFile(filepath.Join(t.TempDir(), "missing.tape"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "read script file")
+	})
+}
+
+func TestReadFile_ParsesLikeInline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "demo.tape")
+	require.NoError(t, os.WriteFile(path, []byte("\uFEFFType 'ls'\r\nSleep 500ms\r\n"), 0o644))
+
+	src, err := ReadFile(path)
+	require.NoError(t, err)
+	got, err := Parse(src)
+	require.NoError(t, err)
+	assert.Equal(t, []Action{
+		{Kind: ActionType, Text: "ls", Speed: 50 * time.Millisecond},
+		{Kind: ActionSleep, Duration: 500 * time.Millisecond},
+	}, got)
+
+	empty := filepath.Join(dir, "empty.tape")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+	src, err = ReadFile(empty)
+	require.NoError(t, err)
+	got, err = Parse(src)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestIsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "demo.tape")
+	require.NoError(t, os.WriteFile(path, []byte("Enter"), 0o644))
+
+	assert.True(t, IsFile(path))
+	assert.False(t, IsFile(dir))
+	assert.False(t, IsFile(filepath.Join(dir, "missing")))
+	assert.False(t, IsFile("Type 'ls' Enter"))
+}
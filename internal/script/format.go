@@ -0,0 +1,128 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format writes actions as a tape script, one action per line, in
+// canonical syntax: text is quoted (see quote), durations take their
+// shortest form, and values Parse fills in by default, such as the Type
+// speed, a Key repeat of 1 and the Wait timeout, are left out. Parsing the
+// result gives back actions equal to the ones Parse returned, with two
+// exceptions: the text of a TypeSecret, which comes from a secret param,
+// is written as [redacted] and fails to parse, and a Wait pattern ending
+// in a backslash cannot be written at all.
+func Format(actions []Action) string {
+	var sb strings.Builder
+	for _, action := range actions {
+		sb.WriteString(formatAction(action))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// formatAction writes a single action in canonical syntax.
+func formatAction(a Action) string {
+	switch a.Kind {
+	case ActionType:
+		if a.Secret {
+			return a.String()
+		}
+		text := quoteParams(a.Text)
+		switch {
+		case a.Total > 0:
+			return fmt.Sprintf("Type over %s %s", formatDuration(a.Total), text)
+		case a.Speed != DefaultTypeSpeed:
+			return fmt.Sprintf("Type@%s %s", formatDuration(a.Speed), text)
+		}
+		return "Type " + text
+	case ActionSleep:
+		return "Sleep " + formatDuration(a.Duration)
+	case ActionKey:
+		s := a.KeyName()
+		if a.Delay > 0 {
+			s += "@" + formatDuration(a.Delay)
+		}
+		if a.Repeat > 1 {
+			s += " " + strconv.Itoa(a.Repeat)
+		}
+		return s
+	case ActionScreenshot:
+		if a.Name != "" {
+			return "Screenshot " + quoteParams(a.Name)
+		}
+		return "Screenshot"
+	case ActionWait:
+		var s string
+		switch {
+		case a.Prompt:
+			s = "Wait Prompt"
+		case a.AltScreen:
+			s = "Wait AltScreen"
+		default:
+			s = "Wait /" + strings.ReplaceAll(a.Pattern, "/", `\/`) + "/"
+		}
+		if a.Timeout != DefaultWaitTimeout {
+			s += " " + formatDuration(a.Timeout)
+		}
+		return s
+	case ActionScene:
+		return "Scene " + quoteParams(a.Name)
+	case ActionBurst:
+		if a.Duration != DefaultBurstSpacing {
+			return fmt.Sprintf("Burst %d @%s", a.Repeat, formatDuration(a.Duration))
+		}
+		return fmt.Sprintf("Burst %d", a.Repeat)
+	case ActionPane:
+		return "Pane " + quoteParams(a.Name)
+	default:
+		// Ctrl, Set, Signal, Hide and Show have a single form
+		return a.String()
+	}
+}
+
+// quoteParams quotes s as a string that params are expanded in, writing
+// each ${ as $${ so it is typed as is.
+func quoteParams(s string) string {
+	return quote(strings.ReplaceAll(s, "${", "$${"))
+}
+
+// durationUnitSizes are the units formatDuration tries, largest first.
+var durationUnitSizes = []struct {
+	name string
+	size time.Duration
+}{
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// formatDuration writes d in its shortest form that parses back to d: a
+// number of at least 1 of a single unit, such as 90s, 1.5s or 500ms, or
+// d.String() when that is shorter. Ties go to the larger unit.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	best := d.String()
+	for _, unit := range durationUnitSizes {
+		n := float64(d) / float64(unit.size)
+		if n < 1 {
+			continue
+		}
+		s := strconv.FormatFloat(n, 'f', -1, 64) + unit.name
+		if parsed, err := time.ParseDuration(s); err != nil || parsed != d {
+			continue
+		}
+		if len(s) < len(best) {
+			best = s
+		}
+	}
+	return best
+}
@@ -0,0 +1,156 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    string
+	}{
+		{
+			name:    "empty",
+			actions: nil,
+			want:    "",
+		},
+		{
+			name: "defaults are left out",
+			actions: []Action{
+				{Kind: ActionType, Text: "ls", Speed: DefaultTypeSpeed},
+				{Kind: ActionKey, Key: "Enter", Repeat: 1},
+				{Kind: ActionWait, Prompt: true, Timeout: DefaultWaitTimeout},
+				{Kind: ActionBurst, Repeat: 5, Duration: DefaultBurstSpacing},
+			},
+			want: "Type 'ls'\nEnter\nWait Prompt\nBurst 5\n",
+		},
+		{
+			name: "modifiers",
+			actions: []Action{
+				{Kind: ActionType, Text: "fast", Speed: 10 * time.Millisecond},
+				{Kind: ActionType, Text: "slow", Speed: DefaultTypeSpeed, Total: 90 * time.Second},
+				{Kind: ActionKey, Key: "Tab", Modifiers: ModShift, Delay: 1500 * time.Millisecond, Repeat: 3},
+				{Kind: ActionCtrl, Key: "c"},
+				{Kind: ActionWait, Pattern: `a/b$`, Timeout: time.Minute},
+				{Kind: ActionBurst, Repeat: 2, Duration: 100 * time.Millisecond},
+			},
+			want: "Type@10ms 'fast'\nType over 90s 'slow'\nShift+Tab@1.5s 3\nCtrl+C\nWait /a\\/b$/ 1m\nBurst 2 @100ms\n",
+		},
+		{
+			name: "quoting",
+			actions: []Action{
+				{Kind: ActionType, Text: `echo "hi"`, Speed: DefaultTypeSpeed},
+				{Kind: ActionType, Text: "it's", Speed: DefaultTypeSpeed},
+				{Kind: ActionType, Text: `say "it's"`, Speed: DefaultTypeSpeed},
+				{Kind: ActionType, Text: `'"`, Speed: DefaultTypeSpeed},
+				{Kind: ActionScreenshot, Name: "cost-${PRICE}"},
+			},
+			want: `Type 'echo "hi"'
+Type "it's"
+Type 'say "it'"'s"'"'
+Type "'"'"'
+Screenshot 'cost-$${PRICE}'
+`,
+		},
+		{
+			name: "single forms",
+			actions: []Action{
+				{Kind: ActionSleep, Duration: 0},
+				{Kind: ActionScreenshot},
+				{Kind: ActionSet, Setting: "theme", Value: "dracula"},
+				{Kind: ActionSet, Setting: "width", Value: "800"},
+				{Kind: ActionSignal, Signal: "INT"},
+				{Kind: ActionScene, Name: "intro"},
+				{Kind: ActionHide},
+				{Kind: ActionShow},
+				{Kind: ActionPane, Name: "client"},
+			},
+			want: "Sleep 0s\nScreenshot\nSet Theme 'dracula'\nSet Width 800\nSignal INT\nScene 'intro'\nHide\nShow\nPane 'client'\n",
+		},
+		{
+			name:    "secret text is redacted",
+			actions: []Action{{Kind: ActionType, Text: "s3cret", Speed: DefaultTypeSpeed, Secret: true}},
+			want:    "TypeSecret [redacted]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.actions))
+		})
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	corpus := map[string]string{
+		"both quotes":       `Type "it's "'"ok"' Type "'"'"' Type ''`,
+		"literal params":    "Type 'echo $${HOME} $$${X}' Screenshot 'a$${b}' Scene '$${c}' Pane '$${d}'",
+		"escaped slashes":   `Wait /https?:\/\/[^\/]+\// 2m Wait /\\\// 1h30m`,
+		"odd durations":     "Sleep 1h0m1s Sleep 1.5m Sleep 1001ms Sleep 3us Sleep 7ns Type@0 'x' Enter@2.25s 4",
+		"params expanded":   "Param NAME default '${x}' Type 'hi ${NAME}' Screenshot 'shot-${NAME}'",
+		"modified keys":     "Ctrl+Shift+c Alt+x Ctrl+Left@20ms 2 Shift+tab Ctrl+1",
+		"snippets":          "Define go { Type 'go' Enter } Use go Use go",
+		"multi-line text":   "Type 'line one\nline two\ttab'",
+		"settings":          "Set Theme dracula Set Theme 'Solarized Dark' Set Height 300",
+		"wait defaults":     "Wait /x/ 10s Wait Prompt 10s Wait AltScreen 250ms",
+		"comment-like text": "Type '# not a comment // nor this'",
+	}
+	for _, tt := range parseTests {
+		if tt.wantErr == "" && len(tt.want) > 0 {
+			corpus[tt.name] = tt.input
+		}
+	}
+
+	for name, input := range corpus {
+		t.Run(name, func(t *testing.T) {
+			actions, err := Parse(input)
+			require.NoError(t, err)
+			for _, action := range actions {
+				if action.Secret {
+					t.Skip("TypeSecret text comes from params")
+				}
+			}
+
+			text := Format(actions)
+			got, err := Parse(text)
+			require.NoError(t, err, "parse formatted script:\n%s", text)
+			assert.Equal(t, actions, got, "formatted script:\n%s", text)
+			assert.Equal(t, text, Format(got), "formatting is canonical")
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{time.Nanosecond, "1ns"},
+		{3 * time.Microsecond, "3us"},
+		{500 * time.Millisecond, "500ms"},
+		{1500 * time.Millisecond, "1.5s"},
+		{1001 * time.Millisecond, "1.001s"},
+		{time.Second, "1s"},
+		{90 * time.Second, "90s"},
+		{time.Minute, "1m"},
+		{150 * time.Minute, "2.5h"},
+		{time.Hour + time.Second, "3601s"},
+		{time.Hour + time.Nanosecond, "3600.000000001s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := formatDuration(tt.d)
+			assert.Equal(t, tt.want, got)
+			parsed, err := parseDuration(got)
+			require.NoError(t, err)
+			assert.Equal(t, tt.d, parsed)
+		})
+	}
+}
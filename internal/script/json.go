@@ -0,0 +1,256 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// actionJSON is the JSON form of an Action. Durations are strings such as
+// "500ms"; fields left out take the defaults the script syntax gives them.
+type actionJSON struct {
+	Kind      string    `json:"kind"`
+	Text      string    `json:"text,omitempty"`
+	Secret    bool      `json:"secret,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Modifiers []string  `json:"modifiers,omitempty"`
+	Duration  *duration `json:"duration,omitempty"`
+	Speed     *duration `json:"speed,omitempty"`
+	Total     *duration `json:"total,omitempty"`
+	Delay     *duration `json:"delay,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Pattern   string    `json:"pattern,omitempty"`
+	Prompt    bool      `json:"prompt,omitempty"`
+	AltScreen bool      `json:"altScreen,omitempty"`
+	Timeout   *duration `json:"timeout,omitempty"`
+	Setting   string    `json:"setting,omitempty"`
+	Value     string    `json:"value,omitempty"`
+	Signal    string    `json:"signal,omitempty"`
+	Repeat    *int      `json:"repeat,omitempty"`
+}
+
+// duration is a time.Duration written in JSON as a string such as "1.5s".
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(formatDuration(time.Duration(d)))
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"500ms\", got %s", data)
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// durationPtr returns d for a JSON field, or nil to leave it out when it
+// is zero.
+func durationPtr(d time.Duration) *duration {
+	if d == 0 {
+		return nil
+	}
+	jd := duration(d)
+	return &jd
+}
+
+// MarshalJSON writes the action as an object with its kind as a string and
+// durations as strings, such as {"kind":"sleep","duration":"500ms"}. The
+// text of a TypeSecret is left out.
+func (a Action) MarshalJSON() ([]byte, error) {
+	if a.Kind < 0 || int(a.Kind) >= len(kindNames) {
+		return nil, fmt.Errorf("unknown action kind %d", int(a.Kind))
+	}
+	v := actionJSON{
+		Kind:      kindNames[a.Kind],
+		Text:      a.Text,
+		Secret:    a.Secret,
+		Key:       a.Key,
+		Duration:  durationPtr(a.Duration),
+		Speed:     durationPtr(a.Speed),
+		Total:     durationPtr(a.Total),
+		Delay:     durationPtr(a.Delay),
+		Name:      a.Name,
+		Pattern:   a.Pattern,
+		Prompt:    a.Prompt,
+		AltScreen: a.AltScreen,
+		Timeout:   durationPtr(a.Timeout),
+		Setting:   a.Setting,
+		Value:     a.Value,
+		Signal:    a.Signal,
+	}
+	if a.Repeat != 0 {
+		v.Repeat = &a.Repeat
+	}
+	for _, n := range modifierNames {
+		if a.Modifiers&n.mod != 0 {
+			v.Modifiers = append(v.Modifiers, strings.ToLower(n.name))
+		}
+	}
+	if a.Secret {
+		v.Text = ""
+	}
+	// Type has a default speed, so no speed is written as 0s
+	if a.Kind == ActionType && a.Speed == 0 && a.Total == 0 {
+		v.Speed = new(duration)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads an action written by MarshalJSON. Fields left out
+// take the defaults of the script syntax: a Type speed of 50ms, a Key
+// repeat of 1, a Wait timeout of 10s and a Burst spacing of 50ms. Unknown
+// kinds, fields and modifiers, negative durations and repeat counts below 1
+// are errors; see ParseJSON for the checks of the script syntax.
+func (a *Action) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var v actionJSON
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	kind := -1
+	for k, name := range kindNames {
+		if strings.EqualFold(v.Kind, name) {
+			kind = k
+		}
+	}
+	if kind < 0 {
+		if v.Kind == "" {
+			return errors.New("action has no kind")
+		}
+		return fmt.Errorf("unknown action kind %q; valid kinds: %s", v.Kind, strings.Join(kindNames, ", "))
+	}
+
+	*a = Action{
+		Kind:      ActionKind(kind),
+		Text:      v.Text,
+		Secret:    v.Secret,
+		Key:       v.Key,
+		Name:      v.Name,
+		Pattern:   v.Pattern,
+		Prompt:    v.Prompt,
+		AltScreen: v.AltScreen,
+		Setting:   v.Setting,
+		Value:     v.Value,
+		Signal:    v.Signal,
+	}
+	if v.Repeat != nil {
+		// A count below 2 is left out of the script syntax, where it
+		// would go unchecked and run as a single press
+		if *v.Repeat < 1 {
+			return fmt.Errorf("repeat count must be at least 1, got %d", *v.Repeat)
+		}
+		a.Repeat = *v.Repeat
+	}
+	for _, name := range v.Modifiers {
+		mod, ok := modifiers[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown modifier %q; use ctrl, alt or shift", name)
+		}
+		a.Modifiers |= mod
+	}
+	for _, field := range []struct {
+		value *duration
+		dst   *time.Duration
+		def   time.Duration
+	}{
+		{v.Duration, &a.Duration, defaultDuration(a.Kind)},
+		{v.Speed, &a.Speed, defaultSpeed(a.Kind)},
+		{v.Total, &a.Total, 0},
+		{v.Delay, &a.Delay, 0},
+		{v.Timeout, &a.Timeout, defaultTimeout(a.Kind)},
+	} {
+		*field.dst = field.def
+		if field.value != nil {
+			*field.dst = time.Duration(*field.value)
+		}
+	}
+	if a.Kind == ActionKey && a.Repeat == 0 {
+		a.Repeat = 1
+	}
+	return nil
+}
+
+// defaultSpeed returns the Speed an action of kind gets when none is given.
+func defaultSpeed(kind ActionKind) time.Duration {
+	if kind == ActionType {
+		return DefaultTypeSpeed
+	}
+	return 0
+}
+
+// defaultDuration returns the Duration an action of kind gets when none is
+// given.
+func defaultDuration(kind ActionKind) time.Duration {
+	if kind == ActionBurst {
+		return DefaultBurstSpacing
+	}
+	return 0
+}
+
+// defaultTimeout returns the Timeout an action of kind gets when none is
+// given.
+func defaultTimeout(kind ActionKind) time.Duration {
+	if kind == ActionWait {
+		return DefaultWaitTimeout
+	}
+	return 0
+}
+
+// ParseJSON reads actions from a JSON array of the objects written by
+// Action.MarshalJSON, such as
+//
+//	[{"kind": "type", "text": "ls"}, {"kind": "key", "key": "Enter"}]
+//
+// Each action is checked as the script syntax checks it, with the same
+// messages, and returned as Parse would return it: key names keep their
+// case, Ctrl keys are lower case and signal names upper case. TypeSecret
+// actions are rejected, since their text comes from secret params that
+// JSON cannot declare.
+func ParseJSON(data []byte) ([]Action, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var raw []json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the array of actions")
+	}
+
+	actions := make([]Action, len(raw))
+	for i := range raw {
+		if err := json.Unmarshal(raw[i], &actions[i]); err != nil {
+			return nil, fmt.Errorf("action %d: %w", i+1, err)
+		}
+		action := actions[i]
+		switch {
+		case action.Secret:
+			return nil, fmt.Errorf("action %d: secret text cannot be given in JSON; type it with TypeSecret and a secret param in a script", i+1)
+		case action.Kind == ActionType && action.Total > 0 && action.Speed != DefaultTypeSpeed:
+			return nil, fmt.Errorf("action %d (type): cannot use both speed and total; total sets the speed from the text length", i+1)
+		}
+		parsed, err := Parse(formatAction(action))
+		if err != nil {
+			var perr *ParseError
+			if errors.As(err, &perr) {
+				err = errors.New(perr.Message)
+			}
+			return nil, fmt.Errorf("action %d (%s): %w", i+1, action.Kind, err)
+		}
+		if len(parsed) != 1 {
+			return nil, fmt.Errorf("action %d (%s): %q is not a single action", i+1, action.Kind, formatAction(action))
+		}
+		actions[i] = parsed[0]
+	}
+	return actions, nil
+}
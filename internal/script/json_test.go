@@ -0,0 +1,267 @@
+package script
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAction_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   string
+	}{
+		{
+			name:   "type with default speed",
+			action: Action{Kind: ActionType, Text: "ls", Speed: DefaultTypeSpeed},
+			want:   `{"kind":"type","text":"ls","speed":"50ms"}`,
+		},
+		{
+			name:   "type instantly",
+			action: Action{Kind: ActionType, Text: "ls"},
+			want:   `{"kind":"type","text":"ls","speed":"0s"}`,
+		},
+		{
+			name:   "secret text is left out",
+			action: Action{Kind: ActionType, Text: "s3cret", Secret: true, Speed: DefaultTypeSpeed},
+			want:   `{"kind":"type","secret":true,"speed":"50ms"}`,
+		},
+		{
+			name:   "key with modifiers",
+			action: Action{Kind: ActionKey, Key: "Tab", Modifiers: ModCtrl | ModShift, Delay: 1500 * time.Millisecond, Repeat: 3},
+			want:   `{"kind":"key","key":"Tab","modifiers":["ctrl","shift"],"delay":"1.5s","repeat":3}`,
+		},
+		{
+			name:   "wait",
+			action: Action{Kind: ActionWait, Pattern: "a/b", Timeout: 90 * time.Second},
+			want:   `{"kind":"wait","pattern":"a/b","timeout":"90s"}`,
+		},
+		{
+			name:   "sleep",
+			action: Action{Kind: ActionSleep, Duration: 500 * time.Millisecond},
+			want:   `{"kind":"sleep","duration":"500ms"}`,
+		},
+		{
+			name:   "hide",
+			action: Action{Kind: ActionHide},
+			want:   `{"kind":"hide"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.action)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestAction_MarshalJSON_UnknownKind(t *testing.T) {
+	_, err := json.Marshal(Action{Kind: ActionKind(99)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown action kind 99")
+}
+
+func TestAction_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Action
+		wantErr string
+	}{
+		{
+			name:  "defaults are filled in",
+			input: `{"kind":"type","text":"ls"}`,
+			want:  Action{Kind: ActionType, Text: "ls", Speed: DefaultTypeSpeed},
+		},
+		{
+			name:  "kind is case-insensitive",
+			input: `{"kind":"Key","key":"Enter"}`,
+			want:  Action{Kind: ActionKey, Key: "Enter", Repeat: 1},
+		},
+		{
+			name:  "wait timeout default",
+			input: `{"kind":"wait","prompt":true}`,
+			want:  Action{Kind: ActionWait, Prompt: true, Timeout: DefaultWaitTimeout},
+		},
+		{
+			name:  "burst spacing default",
+			input: `{"kind":"burst","repeat":4}`,
+			want:  Action{Kind: ActionBurst, Repeat: 4, Duration: DefaultBurstSpacing},
+		},
+		{
+			name:  "modifiers",
+			input: `{"kind":"key","key":"Left","modifiers":["Ctrl","alt"]}`,
+			want:  Action{Kind: ActionKey, Key: "Left", Modifiers: ModCtrl | ModAlt, Repeat: 1},
+		},
+		{
+			name:    "unknown kind",
+			input:   `{"kind":"jump"}`,
+			wantErr: `unknown action kind "jump"; valid kinds: type, sleep`,
+		},
+		{
+			name:    "no kind",
+			input:   `{"text":"ls"}`,
+			wantErr: "action has no kind",
+		},
+		{
+			name:    "negative duration",
+			input:   `{"kind":"sleep","duration":"-1s"}`,
+			wantErr: "negative",
+		},
+		{
+			name:    "duration is not a string",
+			input:   `{"kind":"sleep","duration":500}`,
+			wantErr: `duration must be a string such as "500ms", got 500`,
+		},
+		{
+			name:    "unknown field",
+			input:   `{"kind":"sleep","seconds":"1s"}`,
+			wantErr: `unknown field "seconds"`,
+		},
+		{
+			name:    "unknown modifier",
+			input:   `{"kind":"key","key":"Tab","modifiers":["super"]}`,
+			wantErr: `unknown modifier "super"`,
+		},
+		{
+			name:    "zero repeat",
+			input:   `{"kind":"key","key":"Tab","repeat":0}`,
+			wantErr: "repeat count must be at least 1, got 0",
+		},
+		{
+			name:    "negative repeat",
+			input:   `{"kind":"key","key":"Tab","repeat":-3}`,
+			wantErr: "repeat count must be at least 1, got -3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Action
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Action
+		wantErr string
+	}{
+		{
+			name:  "empty array",
+			input: `[]`,
+			want:  []Action{},
+		},
+		{
+			name:  "actions are normalized as Parse does",
+			input: `[{"kind":"key","key":"c","modifiers":["ctrl"]}, {"kind":"signal","signal":"int"}, {"kind":"set","setting":"theme","value":"dracula"}]`,
+			want: []Action{
+				{Kind: ActionCtrl, Key: "c"},
+				{Kind: ActionSignal, Signal: "INT"},
+				{Kind: ActionSet, Setting: "theme", Value: "dracula"},
+			},
+		},
+		{
+			name:    "error names the action",
+			input:   `[{"kind":"sleep","duration":"1s"}, {"kind":"jump"}]`,
+			wantErr: `action 2: unknown action kind "jump"`,
+		},
+		{
+			name:    "script checks apply",
+			input:   `[{"kind":"key","key":"Bogus"}]`,
+			wantErr: "action 1 (key): ",
+		},
+		{
+			name:    "empty scene name",
+			input:   `[{"kind":"scene"}]`,
+			wantErr: "action 1 (scene): ",
+		},
+		{
+			name:    "key with no name",
+			input:   `[{"kind":"key"}]`,
+			wantErr: `action 1 (key): "" is not a single action`,
+		},
+		{
+			name:    "repeat below 1",
+			input:   `[{"kind":"key","key":"Down","repeat":0}]`,
+			wantErr: "action 1: repeat count must be at least 1, got 0",
+		},
+		{
+			name:    "speed and total",
+			input:   `[{"kind":"type","text":"ls","speed":"10ms","total":"2s"}]`,
+			wantErr: "action 1 (type): cannot use both speed and total",
+		},
+		{
+			name:    "secret",
+			input:   `[{"kind":"type","text":"pw","secret":true}]`,
+			wantErr: "action 1: secret text cannot be given in JSON",
+		},
+		{
+			name:    "not an array",
+			input:   `{"kind":"hide"}`,
+			wantErr: "cannot unmarshal object",
+		},
+		{
+			name:    "trailing data",
+			input:   `[] []`,
+			wantErr: "unexpected data after the array of actions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSON([]byte(tt.input))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseJSON_RoundTrip(t *testing.T) {
+	for _, tt := range parseTests {
+		if tt.wantErr != "" || len(tt.want) == 0 {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := Parse(tt.input)
+			require.NoError(t, err)
+			for _, action := range actions {
+				if action.Secret {
+					t.Skip("TypeSecret text comes from params")
+				}
+			}
+
+			data, err := json.Marshal(actions)
+			require.NoError(t, err)
+
+			var decoded []Action
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, actions, decoded, "json:\n%s", data)
+
+			parsed, err := ParseJSON(data)
+			require.NoError(t, err, "json:\n%s", data)
+			assert.Equal(t, actions, parsed, "json:\n%s", data)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package script
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxActionDuration is how long a single action may take before
+// CheckDurations reports it; anything longer is most likely a unit typo
+// such as Sleep 500s for Sleep 500ms.
+const DefaultMaxActionDuration = time.Minute
+
+// DurationError reports a single action that takes longer than the limit.
+type DurationError struct {
+	// Index is the action's index in the script.
+	Index  int
+	Action Action
+	// What describes the part of the action that is too long, e.g.
+	// "Sleep lasts 8m20s".
+	What  string
+	Limit time.Duration
+	// Line and Column locate the action in the script, 1-based; they are
+	// zero when its position is unknown.
+	Line   int
+	Column int
+}
+
+func (e *DurationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s, longer than the %v limit for one action", e.Line, e.Column, e.What, e.Limit)
+	}
+	return fmt.Sprintf("action %d (%s): %s, longer than the %v limit for one action", e.Index+1, e.Action, e.What, e.Limit)
+}
+
+// Excerpt returns the script line holding the action with a caret under
+// it, like ParseError.Excerpt.
+func (e *DurationError) Excerpt(input string) string {
+	return (&ParseError{Line: e.Line, Column: e.Column}).Excerpt(input)
+}
+
+// CheckDurations returns an error for every sleep, post-action delay,
+// typing time or burst in actions that exceeds limit. positions, as
+// returned by ParseWithPositions for input, locate the errors; they may be
+// nil.
+func CheckDurations(actions []Action, positions []int, input string, limit time.Duration) []*DurationError {
+	if limit <= 0 {
+		return nil
+	}
+
+	var errs []*DurationError
+	for i, action := range actions {
+		var whats []string
+		switch action.Kind {
+		case ActionSleep:
+			if action.Duration > limit {
+				whats = append(whats, fmt.Sprintf("Sleep lasts %v", action.Duration))
+			}
+		case ActionBurst:
+			if burst := ActionDuration(action); burst > limit {
+				whats = append(whats, fmt.Sprintf("Burst lasts %v", burst))
+			}
+		case ActionType:
+			n := len([]rune(action.Text))
+			if typing := time.Duration(n) * action.CharDelay(); typing > limit {
+				chars := "characters"
+				if n == 1 {
+					chars = "character"
+				}
+				whats = append(whats, fmt.Sprintf("typing %d %s at %v each takes %v", n, chars, action.CharDelay(), typing))
+			}
+		}
+		if action.Delay > limit {
+			whats = append(whats, fmt.Sprintf("the delay after it lasts %v", action.Delay))
+		}
+
+		for _, what := range whats {
+			err := &DurationError{Index: i, Action: action, What: what, Limit: limit}
+			if i < len(positions) {
+				err.Line, err.Column = lineColumn(input, positions[i])
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,91 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDurations(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit time.Duration
+		want  []string
+	}{
+		{
+			name:  "within the limit",
+			input: "Sleep 60s Type 'ls' Enter@1s",
+			limit: time.Minute,
+		},
+		{
+			name:  "long sleep",
+			input: "Type 'ls'\n  Sleep 500s",
+			limit: time.Minute,
+			want:  []string{"line 2, column 3: Sleep lasts 8m20s, longer than the 1m0s limit for one action"},
+		},
+		{
+			name:  "long delay",
+			input: "Down@120s 3",
+			limit: time.Minute,
+			want:  []string{"line 1, column 1: the delay after it lasts 2m0s, longer than the 1m0s limit for one action"},
+		},
+		{
+			name:  "slow typing",
+			input: "Type@1s 'hello world'",
+			limit: 10 * time.Second,
+			want:  []string{"line 1, column 1: typing 11 characters at 1s each takes 11s, longer than the 10s limit for one action"},
+		},
+		{
+			name:  "type over",
+			input: "Type over 120s 'x'",
+			limit: time.Minute,
+			want:  []string{"line 1, column 1: typing 1 character at 2m0s each takes 2m0s, longer than the 1m0s limit for one action"},
+		},
+		{
+			name:  "inside a snippet points at its definition",
+			input: "Define wait { Sleep 90s }\nUse wait",
+			limit: time.Minute,
+			want:  []string{"line 1, column 15: Sleep lasts 1m30s, longer than the 1m0s limit for one action"},
+		},
+		{
+			name:  "zero limit disables the check",
+			input: "Sleep 3600s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, positions, err := ParseWithPositions(tt.input)
+			require.NoError(t, err)
+
+			var got []string
+			for _, err := range CheckDurations(actions, positions, tt.input, tt.limit) {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckDurations_WithoutPositions(t *testing.T) {
+	actions := []Action{
+		{Kind: ActionKey, Key: "Enter", Repeat: 1},
+		{Kind: ActionSleep, Duration: 2 * time.Minute},
+	}
+
+	errs := CheckDurations(actions, nil, "", time.Minute)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "action 2 (Sleep 2m0s): Sleep lasts 2m0s, longer than the 1m0s limit for one action", errs[0].Error())
+	assert.Empty(t, errs[0].Excerpt(""))
+}
+
+func TestParseWithPositions(t *testing.T) {
+	input := "Define s { Tab }\nType 'ls' Enter\n  Use s"
+	actions, positions, err := ParseWithPositions(input)
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+	assert.Equal(t, []int{17, 27, 11}, positions)
+}
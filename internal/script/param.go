@@ -0,0 +1,187 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Param is a script parameter declared with `Param NAME [secret] [default
+// 'value']` and referenced as ${NAME} in Type text and Screenshot and Scene
+// names. Secret params are typed with TypeSecret.
+type Param struct {
+	Name string
+	// Default is the value used when none is given; it is only meaningful
+	// when HasDefault is set.
+	Default    string
+	HasDefault bool
+	// Secret params are left out of records of the run, such as the
+	// manifest, and may be typed with TypeSecret.
+	Secret bool
+	// Value is the resolved value: the one given, or Default.
+	Value string
+}
+
+// Parsed is a parsed script.
+type Parsed struct {
+	Actions []Action
+	// Positions are the byte offsets the actions are written at; see
+	// ParseWithPositions.
+	Positions []int
+	// Params are the declared parameters with their resolved values, in
+	// declaration order.
+	Params []Param
+	// Warnings are problems that do not stop the script from running,
+	// such as a secret param typed with Type rather than TypeSecret.
+	Warnings []*Warning
+}
+
+// paramName matches valid parameter names.
+var paramName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseScript parses a script, giving its parameters the values in params.
+// Every name in params must be declared by the script, and every parameter
+// without a default must be given a value.
+func ParseScript(src string, params map[string]string) (*Parsed, error) {
+	l := newLexer(src)
+	p := newParser(l)
+	p.values = params
+
+	parsed := &Parsed{Actions: []Action{}, Positions: []int{}}
+	for p.curToken.kind != tokenEOF {
+		s, err := p.parseStatement()
+		if p.lexErr != nil {
+			return nil, locate(p.lexErr, src)
+		}
+		if err != nil {
+			return nil, locate(err, src)
+		}
+		parsed.Actions = append(parsed.Actions, s.actions...)
+		parsed.Positions = append(parsed.Positions, s.positions...)
+	}
+
+	var unknown []string
+	for name := range params {
+		if _, ok := p.params[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown param %q; declare it in the script with Param %s", unknown[0], unknown[0])
+	}
+
+	for _, name := range p.paramOrder {
+		parsed.Params = append(parsed.Params, *p.params[name])
+	}
+	for _, w := range p.warnings {
+		w.Line, w.Column = lineColumn(src, w.Position)
+	}
+	parsed.Warnings = p.warnings
+	return parsed, nil
+}
+
+// parseParam parses `Param NAME [secret] [default 'value']` and records the
+// parameter with its value.
+func (p *parser) parseParam() error {
+	paramPos := p.curToken.position
+	if p.defining != "" {
+		return &ParseError{
+			Position: paramPos,
+			Message:  fmt.Sprintf("Param cannot appear inside snippet %q", p.defining),
+		}
+	}
+	p.nextToken() // consume 'Param'
+
+	if p.curToken.kind != tokenIdent || !paramName.MatchString(p.curToken.literal) {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected param name after Param, such as NAME or USER_NAME",
+		}
+	}
+	param := &Param{Name: p.curToken.literal}
+	if _, ok := p.params[param.Name]; ok {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("param %q is already declared", param.Name),
+		}
+	}
+	p.nextToken() // consume name
+
+	if p.curToken.kind == tokenIdent && strings.EqualFold(p.curToken.literal, "secret") {
+		param.Secret = true
+		p.nextToken() // consume 'secret'
+	}
+
+	if p.curToken.kind == tokenIdent && strings.EqualFold(p.curToken.literal, "default") {
+		p.nextToken() // consume 'default'
+		if p.curToken.kind != tokenString {
+			return &ParseError{
+				Position: p.curToken.position,
+				Message:  fmt.Sprintf("expected quoted string after Param %s default", param.Name),
+			}
+		}
+		param.Default = p.curToken.literal
+		param.HasDefault = true
+		p.nextToken() // consume value
+	}
+
+	if value, ok := p.values[param.Name]; ok {
+		param.Value = value
+	} else if param.HasDefault {
+		param.Value = param.Default
+	} else {
+		return &ParseError{
+			Position: paramPos,
+			Message:  fmt.Sprintf("param %s has no default, so it needs a value (--param %s=VALUE)", param.Name, param.Name),
+		}
+	}
+
+	if p.params == nil {
+		p.params = map[string]*Param{}
+	}
+	p.params[param.Name] = param
+	p.paramOrder = append(p.paramOrder, param.Name)
+	return nil
+}
+
+// expand replaces ${NAME} in s, a string token at pos, with the value of
+// the declared param NAME; $${ is a literal ${.
+func (p *parser) expand(s string, pos int) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			sb.WriteString(s[:i-1])
+			sb.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		sb.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", &ParseError{Position: pos, Message: "unterminated ${ in string; write $${ for a literal ${"}
+		}
+		name := s[i+2 : i+end]
+		param, ok := p.params[name]
+		if !ok {
+			return "", &ParseError{
+				Position: pos,
+				Message:  fmt.Sprintf("unknown param ${%s}; declare it with Param %s before using it", name, name),
+			}
+		}
+		sb.WriteString(param.Value)
+		p.expanded = append(p.expanded, param)
+		s = s[i+end+1:]
+	}
+}
@@ -0,0 +1,224 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScript_Params(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		values     map[string]string
+		wantText   string
+		wantParams []Param
+	}{
+		{
+			name:     "default",
+			input:    "Param NAME default 'world' Type 'hello ${NAME}'",
+			wantText: "hello world",
+			wantParams: []Param{
+				{Name: "NAME", Default: "world", HasDefault: true, Value: "world"},
+			},
+		},
+		{
+			name:     "value overrides default",
+			input:    "Param NAME default 'world' Type 'hello ${NAME}'",
+			values:   map[string]string{"NAME": "Gophers"},
+			wantText: "hello Gophers",
+			wantParams: []Param{
+				{Name: "NAME", Default: "world", HasDefault: true, Value: "Gophers"},
+			},
+		},
+		{
+			name:     "required and secret",
+			input:    "Param USER\nParam TOKEN secret\nType '${USER}:${TOKEN}'",
+			values:   map[string]string{"USER": "ann", "TOKEN": "s3cret"},
+			wantText: "ann:s3cret",
+			wantParams: []Param{
+				{Name: "USER", Value: "ann"},
+				{Name: "TOKEN", Secret: true, Value: "s3cret"},
+			},
+		},
+		{
+			name:     "keywords are case-insensitive",
+			input:    "param Path SECRET DEFAULT '/tmp' Type 'cd ${Path}'",
+			wantText: "cd /tmp",
+			wantParams: []Param{
+				{Name: "Path", Default: "/tmp", HasDefault: true, Secret: true, Value: "/tmp"},
+			},
+		},
+		{
+			name:     "escaped and repeated",
+			input:    "Param X default 'a' Type 'echo $${HOME} ${X}${X}'",
+			wantText: "echo ${HOME} aa",
+			wantParams: []Param{
+				{Name: "X", Default: "a", HasDefault: true, Value: "a"},
+			},
+		},
+		{
+			name:     "used in a snippet",
+			input:    "Param DIR default 'src' Define go { Type 'cd ${DIR}' } Use go",
+			wantText: "cd src",
+			wantParams: []Param{
+				{Name: "DIR", Default: "src", HasDefault: true, Value: "src"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseScript(tt.input, tt.values)
+			require.NoError(t, err)
+			require.Len(t, parsed.Actions, 1)
+			assert.Equal(t, tt.wantText, parsed.Actions[0].Text)
+			assert.Equal(t, tt.wantParams, parsed.Params)
+			assert.Len(t, parsed.Positions, 1)
+		})
+	}
+}
+
+func TestParseScript_ParamInScreenshotName(t *testing.T) {
+	parsed, err := ParseScript("Param STEP default 'login' Screenshot '${STEP}-done'", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Action{{Kind: ActionScreenshot, Name: "login-done"}}, parsed.Actions)
+}
+
+func TestParseScript_ParamErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		values  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "undeclared value",
+			input:   "Param A default 'x' Type '${A}'",
+			values:  map[string]string{"B": "y"},
+			wantErr: `unknown param "B"; declare it in the script with Param B`,
+		},
+		{
+			name:    "missing required value",
+			input:   "Type 'a'\nParam USER",
+			wantErr: "parse error at line 2, column 1: param USER has no default, so it needs a value (--param USER=VALUE)",
+		},
+		{
+			name:    "unknown reference",
+			input:   "Type 'hi ${WHO}'",
+			wantErr: "unknown param ${WHO}; declare it with Param WHO before using it",
+		},
+		{
+			name:    "used before declared",
+			input:   "Type '${A}' Param A default 'x'",
+			wantErr: "unknown param ${A}",
+		},
+		{
+			name:    "unterminated reference",
+			input:   "Param A default 'x' Type '${A'",
+			wantErr: "unterminated ${ in string",
+		},
+		{
+			name:    "declared twice",
+			input:   "Param A default 'x' Param A default 'y'",
+			wantErr: `param "A" is already declared`,
+		},
+		{
+			name:    "missing name",
+			input:   "Param 'A'",
+			wantErr: "expected param name after Param",
+		},
+		{
+			name:    "default without value",
+			input:   "Param A default Enter",
+			wantErr: "expected quoted string after Param A default",
+		},
+		{
+			name:    "inside a snippet",
+			input:   "Define s { Param A default 'x' }",
+			wantErr: `Param cannot appear inside snippet "s"`,
+		},
+		{
+			name:    "TypeSecret of plain text",
+			input:   "TypeSecret 'hunter2'",
+			wantErr: "TypeSecret needs a secret param such as ${TOKEN}, so the value stays out of the script",
+		},
+		{
+			name:    "TypeSecret of a param that is not secret",
+			input:   "Param USER default 'ann' TypeSecret ${USER}",
+			wantErr: "TypeSecret types param USER, which is not secret; declare it with Param USER secret",
+		},
+		{
+			name:    "TypeSecret without text",
+			input:   "TypeSecret Enter",
+			wantErr: "expected ${NAME} or quoted string after TypeSecret",
+		},
+		{
+			name:    "unterminated bare reference",
+			input:   "Param A secret TypeSecret ${A",
+			values:  map[string]string{"A": "x"},
+			wantErr: "unterminated ${ starting here; add the closing }",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScript(tt.input, tt.values)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParse_ParamsTakeDefaults(t *testing.T) {
+	actions, err := Parse("Param N default 'x' Type@10ms '${N}'")
+	require.NoError(t, err)
+	assert.Equal(t, []Action{{Kind: ActionType, Text: "x", Speed: 10 * time.Millisecond}}, actions)
+}
+
+func TestParseScript_TypeSecret(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Action
+	}{
+		{
+			name:  "bare param",
+			input: "Param TOKEN secret TypeSecret ${TOKEN}",
+			want:  Action{Kind: ActionType, Text: "s3cret", Speed: DefaultTypeSpeed, Secret: true},
+		},
+		{
+			name:  "quoted with speed",
+			input: "Param TOKEN secret TypeSecret@10ms 'Bearer ${TOKEN}'",
+			want:  Action{Kind: ActionType, Text: "Bearer s3cret", Speed: 10 * time.Millisecond, Secret: true},
+		},
+		{
+			name:  "keyword is case-insensitive",
+			input: "Param TOKEN secret typesecret over 1s ${TOKEN}",
+			want:  Action{Kind: ActionType, Text: "s3cret", Speed: DefaultTypeSpeed, Total: time.Second, Secret: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseScript(tt.input, map[string]string{"TOKEN": "s3cret"})
+			require.NoError(t, err)
+			assert.Equal(t, []Action{tt.want}, parsed.Actions)
+			assert.Empty(t, parsed.Warnings)
+		})
+	}
+}
+
+func TestParseScript_SecretTypedInPlainSight(t *testing.T) {
+	src := "Param USER default 'ann'\nParam TOKEN secret\nType '${USER}'\n  Type ${TOKEN} Enter"
+	parsed, err := ParseScript(src, map[string]string{"TOKEN": "s3cret"})
+	require.NoError(t, err)
+
+	require.Len(t, parsed.Warnings, 1)
+	w := parsed.Warnings[0]
+	assert.Equal(t, "line 4, column 3: Type shows secret param ${TOKEN} in logs and frames; use TypeSecret to keep it out of them", w.String())
+	assert.Equal(t, "  Type ${TOKEN} Enter\n  ^", w.Excerpt(src))
+	assert.Equal(t, "s3cret", parsed.Actions[1].Text)
+}
@@ -2,10 +2,13 @@ package script
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 // tokenKind represents the type of a token.
@@ -19,6 +22,10 @@ const (
 	tokenDuration           // 500ms, 2s
 	tokenAt                 // @
 	tokenPlus               // +
+	tokenRegex              // /pattern/
+	tokenLBrace             // {
+	tokenRBrace             // }
+	tokenIllegal            // malformed input; literal is the error message
 )
 
 // token represents a lexical token with its kind, literal value, and position.
@@ -62,10 +69,21 @@ func (l *lexer) peekChar() byte {
 	return l.input[l.readPos]
 }
 
-// skipWhitespace skips spaces, tabs, newlines, and carriage returns.
+// skipWhitespace skips spaces, tabs, newlines, carriage returns, and
+// comments. A comment starts with # or // outside a quoted string or
+// /pattern/ and runs to the end of the line.
 func (l *lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '#' || (l.ch == '/' && l.peekChar() == '/'):
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		default:
+			return
+		}
 	}
 }
 
@@ -82,69 +100,197 @@ func (l *lexer) nextToken() token {
 	case '+':
 		l.readChar()
 		return token{kind: tokenPlus, literal: "+", position: pos}
+	case '{':
+		l.readChar()
+		return token{kind: tokenLBrace, literal: "{", position: pos}
+	case '}':
+		l.readChar()
+		return token{kind: tokenRBrace, literal: "}", position: pos}
 	case '\'':
 		return l.readString('\'')
 	case '"':
 		return l.readString('"')
+	case '/':
+		return l.readRegex()
 	case 0:
 		return token{kind: tokenEOF, literal: "", position: pos}
 	default:
-		if isDigit(l.ch) {
+		if isDigit(l.ch) || (l.ch == '-' && isDigit(l.peekChar())) {
 			return l.readNumberOrDuration()
 		}
 		if isLetter(l.ch) {
 			return l.readIdent()
 		}
-		// Unknown character - skip it and return next token
-		l.readChar()
-		return l.nextToken()
+		if l.ch == '$' && l.peekChar() == '{' {
+			return l.readParamRef()
+		}
+		r, size := utf8.DecodeRuneInString(l.input[l.position:])
+		for range size {
+			l.readChar()
+		}
+		return token{kind: tokenIllegal, literal: fmt.Sprintf("unexpected character %q", r), position: pos}
 	}
 }
 
-// readString reads a quoted string (single or double quotes).
+// readString reads a quoted string (single or double quotes). Quoted
+// strings written back to back join into one, as in a shell, so text with
+// both kinds of quote can be written as "it's "'"ok"'.
 func (l *lexer) readString(quote byte) token {
 	pos := l.position
-	l.readChar() // consume opening quote
 
 	var sb strings.Builder
-	for l.ch != quote && l.ch != 0 {
-		sb.WriteByte(l.ch)
+	for {
+		start := l.position
+		l.readChar() // consume opening quote
+		for l.ch != quote && l.ch != 0 {
+			sb.WriteByte(l.ch)
+			l.readChar()
+		}
+		if l.ch == 0 {
+			return token{kind: tokenIllegal, literal: fmt.Sprintf("unterminated string starting here; add the closing %c", quote), position: start}
+		}
+		l.readChar() // consume closing quote
+
+		if l.ch != '\'' && l.ch != '"' {
+			return token{kind: tokenString, literal: sb.String(), position: pos}
+		}
+		quote = l.ch
+	}
+}
+
+// readParamRef reads an unquoted ${NAME} as a string token, so a param can
+// be given without quotes, as in TypeSecret ${TOKEN}.
+func (l *lexer) readParamRef() token {
+	pos := l.position
+	for l.ch != '}' && l.ch != 0 {
 		l.readChar()
 	}
+	if l.ch == 0 {
+		return token{kind: tokenIllegal, literal: "unterminated ${ starting here; add the closing }", position: pos}
+	}
 
-	l.readChar() // consume closing quote
-	return token{kind: tokenString, literal: sb.String(), position: pos}
+	l.readChar() // consume closing brace
+	return token{kind: tokenString, literal: l.input[pos:l.position], position: pos}
 }
 
-// readNumberOrDuration reads a number, which may be followed by 'ms' or 's' to form a duration.
-func (l *lexer) readNumberOrDuration() token {
+// readRegex reads a /pattern/ literal. A backslash-escaped slash is part of
+// the pattern; other escapes are kept as-is for the regexp package. The
+// closing slash must come before the end of the line.
+func (l *lexer) readRegex() token {
 	pos := l.position
-	var sb strings.Builder
+	l.readChar() // consume opening slash
 
-	for isDigit(l.ch) {
+	var sb strings.Builder
+	for l.ch != '/' && l.ch != 0 && l.ch != '\n' {
+		if l.ch == '\\' && l.peekChar() == '/' {
+			l.readChar() // drop the escaping backslash
+		}
 		sb.WriteByte(l.ch)
 		l.readChar()
 	}
+	if l.ch != '/' {
+		return token{kind: tokenIllegal, literal: "unterminated pattern starting here; add the closing /", position: pos}
+	}
 
-	numStr := sb.String()
+	l.readChar() // consume closing slash
+	return token{kind: tokenRegex, literal: sb.String(), position: pos}
+}
 
-	// Check for duration suffix
-	if l.ch == 'm' && l.peekChar() == 's' {
-		sb.WriteByte(l.ch)
+// durationUnits are the unit suffixes time.ParseDuration accepts, longest
+// first where one is a prefix of another.
+var durationUnits = []string{"ns", "us", "µs", "μs", "ms", "s", "m", "h"}
+
+// readNumberOrDuration reads a number, which may have a sign and a decimal
+// part and be followed by duration units to form a duration such as 500ms,
+// 1.5s or 1h30m. Spaces before the unit, as in "500 ms", are dropped from
+// the literal. A number without a unit, such as a repeat count, is a
+// tokenNumber. Negative values are left for the parser to reject, so the
+// error can name them; a letter that starts no unit, as in 1e3ms, is
+// illegal.
+func (l *lexer) readNumberOrDuration() token {
+	pos := l.position
+	if l.ch == '-' {
 		l.readChar()
-		sb.WriteByte(l.ch)
+	}
+	l.readDecimal()
+	number := l.input[pos:l.position]
+	// A letter right after the digits that starts no unit, as the e of
+	// 1e3ms, is a typo rather than a number followed by a key name
+	if isLetter(l.ch) && l.durationUnit() == "" {
+		charPos := l.position
 		l.readChar()
-		return token{kind: tokenDuration, literal: sb.String(), position: pos}
+		return token{kind: tokenIllegal, literal: fmt.Sprintf("unexpected character %q after number %s", l.input[charPos], number), position: charPos}
+	}
+	for range l.spacedUnit() {
+		l.readChar()
+	}
+	if l.durationUnit() == "" {
+		return token{kind: tokenNumber, literal: number, position: pos}
 	}
 
-	if l.ch == 's' {
-		sb.WriteByte(l.ch)
+	unitPos := l.position
+	for {
+		unit := l.durationUnit()
+		if unit == "" {
+			break
+		}
+		for range len(unit) {
+			l.readChar()
+		}
+		if !isDigit(l.ch) {
+			break
+		}
+		l.readDecimal()
+	}
+	return token{kind: tokenDuration, literal: number + l.input[unitPos:l.position], position: pos}
+}
+
+// readDecimal reads digits with an optional fractional part.
+func (l *lexer) readDecimal() {
+	for isDigit(l.ch) {
 		l.readChar()
-		return token{kind: tokenDuration, literal: sb.String(), position: pos}
 	}
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+}
 
-	// Just a number
-	return token{kind: tokenNumber, literal: numStr, position: pos}
+// durationUnit returns the duration unit at the current position, or "".
+func (l *lexer) durationUnit() string {
+	if l.ch == 0 {
+		return ""
+	}
+	rest := l.input[l.position:]
+	for _, unit := range durationUnits {
+		if strings.HasPrefix(rest, unit) {
+			return unit
+		}
+	}
+	return ""
+}
+
+// spacedUnit returns how many spaces separate the number just read from a
+// duration unit, as in "500 ms", or 0 when none follows them. The unit must
+// end the word, so "3 sleep" stays a number followed by a command.
+func (l *lexer) spacedUnit() int {
+	rest := l.input[l.position:]
+	trimmed := strings.TrimLeft(rest, " \t")
+	if len(trimmed) == len(rest) {
+		return 0
+	}
+	for _, unit := range durationUnits {
+		if !strings.HasPrefix(trimmed, unit) {
+			continue
+		}
+		if after := trimmed[len(unit):]; after != "" && isLetter(after[0]) {
+			return 0
+		}
+		return len(rest) - len(trimmed)
+	}
+	return 0
 }
 
 // readIdent reads an identifier (sequence of letters and digits, case-insensitive).
@@ -160,9 +306,11 @@ func (l *lexer) readIdent() token {
 	return token{kind: tokenIdent, literal: sb.String(), position: pos}
 }
 
-// isLetter checks if a byte is a letter (or underscore).
+// isLetter checks if a byte is an ASCII letter (or underscore). Bytes of
+// multi-byte characters are not letters, so they are reported whole as
+// unexpected characters.
 func isLetter(ch byte) bool {
-	return unicode.IsLetter(rune(ch)) || ch == '_'
+	return ch < utf8.RuneSelf && unicode.IsLetter(rune(ch)) || ch == '_'
 }
 
 // isDigit checks if a byte is a digit.
@@ -175,11 +323,30 @@ type parser struct {
 	l         *lexer
 	curToken  token
 	peekToken token
+
+	// snippets holds the Define blocks seen so far, by name; defining is
+	// the name of the one being parsed, if any.
+	snippets map[string]snippet
+	defining string
+
+	// lexErr is the first malformed token read, which takes precedence
+	// over any error parsing around it.
+	lexErr *ParseError
+
+	// params holds the Param declarations seen so far, by name, in
+	// paramOrder; values are the values given for them.
+	params     map[string]*Param
+	paramOrder []string
+	values     map[string]string
+	// expanded collects the params used by expand, for the caller to
+	// check; warnings are the Warnings found so far.
+	expanded []*Param
+	warnings []*Warning
 }
 
 // newParser creates a new parser for the given lexer.
 func newParser(l *lexer) *parser {
-	p := &parser{l: l}
+	p := &parser{l: l, snippets: map[string]snippet{}}
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
 	p.nextToken()
@@ -190,18 +357,80 @@ func newParser(l *lexer) *parser {
 func (p *parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.nextToken()
+	if p.peekToken.kind == tokenIllegal && p.lexErr == nil {
+		p.lexErr = &ParseError{Position: p.peekToken.position, Message: p.peekToken.literal}
+	}
 }
 
 // ParseError represents a parsing error with position information.
+// Position is a byte offset into the input; Line and Column are 1-based
+// and count characters, and are filled in by Parse.
 type ParseError struct {
 	Position int
+	Line     int
+	Column   int
 	Message  string
 }
 
 func (e *ParseError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("parse error at line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
 	return fmt.Sprintf("parse error at position %d: %s", e.Position, e.Message)
 }
 
+// Warning is a problem in a script that does not stop it from running,
+// located like a ParseError.
+type Warning struct {
+	Position int
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", w.Line, w.Column, w.Message)
+}
+
+// Excerpt returns the script line holding the warning with a caret under
+// it, like ParseError.Excerpt.
+func (w *Warning) Excerpt(input string) string {
+	return (&ParseError{Line: w.Line, Column: w.Column}).Excerpt(input)
+}
+
+// Excerpt returns the line of input containing the error followed by a caret
+// under the offending column, for display to the user.
+func (e *ParseError) Excerpt(input string) string {
+	if e.Line < 1 {
+		return ""
+	}
+	lines := strings.Split(input, "\n")
+	if e.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[e.Line-1], "\r")
+	return line + "\n" + strings.Repeat(" ", e.Column-1) + "^"
+}
+
+// locate fills in Line and Column for a ParseError from its byte Position.
+func locate(err error, input string) error {
+	pe, ok := err.(*ParseError)
+	if !ok {
+		return err
+	}
+	pe.Line, pe.Column = lineColumn(input, pe.Position)
+	return pe
+}
+
+// lineColumn converts a byte offset into input to a 1-based line and
+// character column.
+func lineColumn(input string, pos int) (line, column int) {
+	pos = min(max(pos, 0), len(input))
+	before := input[:pos]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
 // validKeys contains all recognized special key names (case-insensitive).
 var validKeys = map[string]bool{
 	"enter":     true,
@@ -226,35 +455,79 @@ func isValidKey(key string) bool {
 	if validKeys[lowerKey] {
 		return true
 	}
-	// Check for Ctrl combinations
-	if strings.HasPrefix(lowerKey, "ctrl+") && len(lowerKey) == 6 {
-		return true
-	}
 	return false
 }
 
-// parseDuration parses a duration string (e.g., "500ms", "2s").
+// modifiers maps lower-case modifier names to their Modifier.
+var modifiers = map[string]Modifier{
+	"ctrl":  ModCtrl,
+	"alt":   ModAlt,
+	"shift": ModShift,
+}
+
+// parseDuration parses a duration string (e.g., "500ms", "2s"). Negative
+// durations are rejected.
 func parseDuration(s string) (time.Duration, error) {
-	return time.ParseDuration(s)
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	return d, nil
+}
+
+// durationError returns the error for the invalid duration in the current
+// token, with hint saying what is expected. A bare number gets a guess at
+// the unit it lacks instead.
+func (p *parser) durationError(hint string) *ParseError {
+	lit := p.curToken.literal
+	if p.curToken.kind == tokenNumber {
+		if guess := suggestDuration(lit); guess != "" {
+			return &ParseError{
+				Position: p.curToken.position,
+				Message:  fmt.Sprintf("duration %s has no unit; did you mean %s?", lit, guess),
+			}
+		}
+	}
+	return &ParseError{
+		Position: p.curToken.position,
+		Message:  fmt.Sprintf("invalid duration %q; %s", lit, hint),
+	}
+}
+
+// suggestDuration guesses the duration a positive bare number stands for:
+// seconds below 10, as in "Sleep 2", and milliseconds from 10, as in
+// "Type@50". It returns "" for other numbers.
+func suggestDuration(number string) string {
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f <= 0 {
+		return ""
+	}
+	if f < 10 {
+		return number + "s"
+	}
+	return number + "ms"
 }
 
 // Parse converts a tape script string into a slice of Actions.
 // Returns error with position info on parse failure.
 func Parse(script string) ([]Action, error) {
-	l := newLexer(script)
-	p := newParser(l)
-
-	actions := []Action{}
+	actions, _, err := ParseWithPositions(script)
+	return actions, err
+}
 
-	for p.curToken.kind != tokenEOF {
-		action, err := p.parseAction()
-		if err != nil {
-			return nil, err
-		}
-		actions = append(actions, action)
+// ParseWithPositions is Parse that also returns where each action is
+// written: positions[i] is the byte offset of actions[i] in script. Actions
+// from a Use are placed at their definition in the snippet. Params take
+// their defaults; see ParseScript.
+func ParseWithPositions(script string) ([]Action, []int, error) {
+	parsed, err := ParseScript(script, nil)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return actions, nil
+	return parsed.Actions, parsed.Positions, nil
 }
 
 // parseAction parses a single action from the current token.
@@ -268,13 +541,13 @@ func (p *parser) parseAction() (Action, error) {
 
 	ident := strings.ToLower(p.curToken.literal)
 
-	// Check for Ctrl+ combinations first
-	if strings.HasPrefix(ident, "ctrl+") {
-		return p.parseCtrlAction()
+	// Check for modifier combinations such as Ctrl+C or Shift+Tab first
+	if strings.Contains(ident, "+") {
+		return p.parseModifiedKeyAction()
 	}
 
-	// Check for Type command
-	if ident == "type" {
+	// Check for Type and TypeSecret commands
+	if ident == "type" || ident == "typesecret" {
 		return p.parseTypeAction()
 	}
 
@@ -283,13 +556,59 @@ func (p *parser) parseAction() (Action, error) {
 		return p.parseSleepAction()
 	}
 
+	// Check for Screenshot command
+	if ident == "screenshot" {
+		return p.parseScreenshotAction()
+	}
+
+	// Check for Wait command
+	if ident == "wait" {
+		return p.parseWaitAction()
+	}
+
+	// Check for Set command
+	if ident == "set" {
+		return p.parseSetAction()
+	}
+
+	// Check for Signal command
+	if ident == "signal" {
+		return p.parseSignalAction()
+	}
+
+	// Check for Scene command
+	if ident == "scene" {
+		return p.parseSceneAction()
+	}
+
+	// Check for Burst command
+	if ident == "burst" {
+		return p.parseBurstAction()
+	}
+
+	// Check for Pane command
+	if ident == "pane" {
+		return p.parsePaneAction()
+	}
+
+	// Check for Hide and Show commands
+	if ident == "hide" || ident == "show" {
+		p.nextToken() // consume 'Hide' or 'Show'
+		if ident == "hide" {
+			return Action{Kind: ActionHide}, nil
+		}
+		return Action{Kind: ActionShow}, nil
+	}
+
 	// Otherwise, treat as a key press
 	return p.parseKeyAction()
 }
 
-// parseTypeAction parses a Type command with optional speed modifier.
+// parseTypeAction parses a Type or TypeSecret command with an optional
+// @speed modifier or "over" total duration.
 func (p *parser) parseTypeAction() (Action, error) {
-	action := Action{Kind: ActionType, Speed: 50 * time.Millisecond}
+	typePos := p.curToken.position
+	action := Action{Kind: ActionType, Speed: DefaultTypeSpeed, Secret: strings.EqualFold(p.curToken.literal, "typesecret")}
 
 	p.nextToken() // consume 'Type'
 
@@ -305,29 +624,86 @@ func (p *parser) parseTypeAction() (Action, error) {
 
 		duration, err := parseDuration(p.curToken.literal)
 		if err != nil {
+			return Action{}, p.durationError("use '500ms' or '2s'")
+		}
+		action.Speed = duration
+		p.nextToken() // consume duration
+	}
+
+	// Check for "over" total duration
+	if p.curToken.kind == tokenIdent && strings.EqualFold(p.curToken.literal, "over") {
+		if action.Speed != DefaultTypeSpeed {
 			return Action{}, &ParseError{
 				Position: p.curToken.position,
-				Message:  fmt.Sprintf("invalid duration %q; use '500ms' or '2s'", p.curToken.literal),
+				Message:  "cannot use both @speed and over in Type; over sets the speed from the text length",
 			}
 		}
-		action.Speed = duration
+		p.nextToken() // consume 'over'
+		if p.curToken.kind != tokenDuration && p.curToken.kind != tokenNumber {
+			return Action{}, &ParseError{
+				Position: p.curToken.position,
+				Message:  "expected duration after over",
+			}
+		}
+
+		duration, err := parseDuration(p.curToken.literal)
+		if err != nil || duration <= 0 {
+			return Action{}, p.durationError("Type over needs a positive duration such as '2s'")
+		}
+		action.Total = duration
 		p.nextToken() // consume duration
 	}
 
 	// Expect quoted string
 	if p.curToken.kind != tokenString {
-		return Action{}, &ParseError{
-			Position: p.curToken.position,
-			Message:  "expected quoted string after Type",
+		message := "expected quoted string after Type"
+		if action.Secret {
+			message = "expected ${NAME} or quoted string after TypeSecret"
 		}
+		return Action{}, &ParseError{Position: p.curToken.position, Message: message}
 	}
 
-	action.Text = p.curToken.literal
+	p.expanded = nil
+	text, err := p.expand(p.curToken.literal, p.curToken.position)
+	if err != nil {
+		return Action{}, err
+	}
+	if err := p.checkSecrets(action, typePos); err != nil {
+		return Action{}, err
+	}
+	action.Text = text
 	p.nextToken() // consume string
 
 	return action, nil
 }
 
+// checkSecrets checks the params the text of a Type or TypeSecret at pos
+// used. A TypeSecret must type only secret params, so its value never
+// appears in the script; a Type of a secret param gets a warning.
+func (p *parser) checkSecrets(action Action, pos int) error {
+	if action.Secret && len(p.expanded) == 0 {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  "TypeSecret needs a secret param such as ${TOKEN}, so the value stays out of the script",
+		}
+	}
+	for _, param := range p.expanded {
+		switch {
+		case action.Secret && !param.Secret:
+			return &ParseError{
+				Position: p.curToken.position,
+				Message:  fmt.Sprintf("TypeSecret types param %s, which is not secret; declare it with Param %s secret", param.Name, param.Name),
+			}
+		case !action.Secret && param.Secret:
+			p.warnings = append(p.warnings, &Warning{
+				Position: pos,
+				Message:  fmt.Sprintf("Type shows secret param ${%s} in logs and frames; use TypeSecret to keep it out of them", param.Name),
+			})
+		}
+	}
+	return nil
+}
+
 // parseSleepAction parses a Sleep command with duration.
 func (p *parser) parseSleepAction() (Action, error) {
 	action := Action{Kind: ActionSleep}
@@ -344,13 +720,270 @@ func (p *parser) parseSleepAction() (Action, error) {
 
 	duration, err := parseDuration(p.curToken.literal)
 	if err != nil {
+		return Action{}, p.durationError("use '500ms' or '2s'")
+	}
+	action.Duration = duration
+	p.nextToken() // consume duration
+
+	return action, nil
+}
+
+// parseScreenshotAction parses a Screenshot command with an optional quoted name.
+func (p *parser) parseScreenshotAction() (Action, error) {
+	action := Action{Kind: ActionScreenshot}
+
+	p.nextToken() // consume 'Screenshot'
+
+	if p.curToken.kind == tokenString {
+		name, err := p.expand(p.curToken.literal, p.curToken.position)
+		if err != nil {
+			return Action{}, err
+		}
+		if strings.TrimSpace(name) == "" {
+			return Action{}, &ParseError{
+				Position: p.curToken.position,
+				Message:  "screenshot name must not be empty",
+			}
+		}
+		action.Name = name
+		p.nextToken() // consume name
+	}
+
+	return action, nil
+}
+
+// parseSceneAction parses a Scene command with its quoted name.
+func (p *parser) parseSceneAction() (Action, error) {
+	p.nextToken() // consume 'Scene'
+
+	if p.curToken.kind != tokenString {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected quoted scene name after Scene, such as Scene 'intro'",
+		}
+	}
+	name, err := p.expand(p.curToken.literal, p.curToken.position)
+	if err != nil {
+		return Action{}, err
+	}
+	if strings.TrimSpace(name) == "" {
 		return Action{}, &ParseError{
 			Position: p.curToken.position,
-			Message:  fmt.Sprintf("invalid duration %q; use '500ms' or '2s'", p.curToken.literal),
+			Message:  "scene name must not be empty",
 		}
 	}
-	action.Duration = duration
-	p.nextToken() // consume duration
+	p.nextToken() // consume name
+
+	return Action{Kind: ActionScene, Name: name}, nil
+}
+
+// parsePaneAction parses a Pane command with its quoted pane name.
+func (p *parser) parsePaneAction() (Action, error) {
+	p.nextToken() // consume 'Pane'
+
+	if p.curToken.kind != tokenString {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected quoted pane name after Pane, such as Pane 'client'",
+		}
+	}
+	name, err := p.expand(p.curToken.literal, p.curToken.position)
+	if err != nil {
+		return Action{}, err
+	}
+	if strings.TrimSpace(name) == "" {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "pane name must not be empty",
+		}
+	}
+	p.nextToken() // consume name
+
+	return Action{Kind: ActionPane, Name: name}, nil
+}
+
+// settingNames maps the lower-case names accepted by Set to their display form.
+var settingNames = map[string]string{
+	"theme":  "Theme",
+	"width":  "Width",
+	"height": "Height",
+}
+
+// numericSettings are the settings whose value is a positive integer.
+var numericSettings = map[string]bool{
+	"width":  true,
+	"height": true,
+}
+
+// parseSetAction parses a Set command: a setting name and its value, a
+// positive number for Width and Height and a quoted or bare word otherwise.
+func (p *parser) parseSetAction() (Action, error) {
+	p.nextToken() // consume 'Set'
+
+	if p.curToken.kind != tokenIdent {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected setting name after Set",
+		}
+	}
+	setting := strings.ToLower(p.curToken.literal)
+	if _, ok := settingNames[setting]; !ok {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("unknown setting %q; valid settings: Theme, Width, Height", p.curToken.literal),
+		}
+	}
+	p.nextToken() // consume setting name
+
+	if numericSettings[setting] {
+		n, err := strconv.Atoi(p.curToken.literal)
+		if p.curToken.kind != tokenNumber || err != nil || n <= 0 {
+			return Action{}, &ParseError{
+				Position: p.curToken.position,
+				Message:  fmt.Sprintf("expected a positive number after Set %s", settingNames[setting]),
+			}
+		}
+	} else if (p.curToken.kind != tokenString && p.curToken.kind != tokenIdent) || strings.TrimSpace(p.curToken.literal) == "" {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("expected value after Set %s", settingNames[setting]),
+		}
+	}
+	action := Action{Kind: ActionSet, Setting: setting, Value: p.curToken.literal}
+	p.nextToken() // consume value
+
+	return action, nil
+}
+
+// Signals are the signal names accepted by Signal, without the SIG prefix.
+var Signals = []string{"HUP", "INT", "QUIT", "KILL", "USR1", "USR2", "TERM", "CONT", "STOP", "TSTP", "WINCH"}
+
+// parseSignalAction parses a Signal command: a signal name such as INT,
+// with or without the SIG prefix, in any case.
+func (p *parser) parseSignalAction() (Action, error) {
+	p.nextToken() // consume 'Signal'
+
+	if p.curToken.kind != tokenIdent {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected signal name after Signal, e.g. Signal INT",
+		}
+	}
+	name := strings.TrimPrefix(strings.ToUpper(p.curToken.literal), "SIG")
+	if !slices.Contains(Signals, name) {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("unknown signal %q; valid signals: %s", p.curToken.literal, strings.Join(Signals, ", ")),
+		}
+	}
+	p.nextToken() // consume signal name
+
+	return Action{Kind: ActionSignal, Signal: name}, nil
+}
+
+// DefaultBurstSpacing is the time between the frames of a Burst action
+// without @spacing.
+const DefaultBurstSpacing = 50 * time.Millisecond
+
+// maxBurstFrames bounds the frames of one Burst action.
+const maxBurstFrames = 1000
+
+// parseBurstAction parses a Burst command: a frame count and an optional
+// @spacing between the frames.
+func (p *parser) parseBurstAction() (Action, error) {
+	action := Action{Kind: ActionBurst, Duration: DefaultBurstSpacing}
+
+	p.nextToken() // consume 'Burst'
+
+	if p.curToken.kind != tokenNumber {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected frame count after Burst, e.g. Burst 10 @50ms",
+		}
+	}
+	count, err := strconv.Atoi(p.curToken.literal)
+	if err != nil || count < 1 || count > maxBurstFrames {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("burst frame count must be between 1 and %d, got %s", maxBurstFrames, p.curToken.literal),
+		}
+	}
+	action.Repeat = count
+	p.nextToken() // consume count
+
+	if p.curToken.kind == tokenAt {
+		p.nextToken() // consume '@'
+		if p.curToken.kind != tokenDuration && p.curToken.kind != tokenNumber {
+			return Action{}, &ParseError{
+				Position: p.curToken.position,
+				Message:  "expected duration after @",
+			}
+		}
+		spacing, err := parseDuration(p.curToken.literal)
+		if err != nil || spacing <= 0 {
+			return Action{}, p.durationError("Burst needs a positive spacing such as '50ms'")
+		}
+		action.Duration = spacing
+		p.nextToken() // consume duration
+	}
+
+	return action, nil
+}
+
+// DefaultWaitTimeout is how long a Wait action waits when no timeout is given.
+const DefaultWaitTimeout = 10 * time.Second
+
+// parseWaitAction parses a Wait command: a /pattern/ or the word Prompt or
+// AltScreen, and an optional timeout.
+func (p *parser) parseWaitAction() (Action, error) {
+	action := Action{Kind: ActionWait, Timeout: DefaultWaitTimeout}
+
+	p.nextToken() // consume 'Wait'
+
+	if p.curToken.kind == tokenIdent && strings.EqualFold(p.curToken.literal, "prompt") {
+		action.Prompt = true
+		p.nextToken() // consume 'Prompt'
+		return p.parseWaitTimeout(action)
+	}
+	if p.curToken.kind == tokenIdent && strings.EqualFold(p.curToken.literal, "altscreen") {
+		action.AltScreen = true
+		p.nextToken() // consume 'AltScreen'
+		return p.parseWaitTimeout(action)
+	}
+	if p.curToken.kind != tokenRegex {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected /pattern/ after Wait, or Wait Prompt or Wait AltScreen",
+		}
+	}
+	if p.curToken.literal == "" {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "wait pattern must not be empty",
+		}
+	}
+	if _, err := regexp.Compile(p.curToken.literal); err != nil {
+		return Action{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("invalid wait pattern: %v", err),
+		}
+	}
+	action.Pattern = p.curToken.literal
+	p.nextToken() // consume pattern
+
+	return p.parseWaitTimeout(action)
+}
+
+// parseWaitTimeout parses the optional timeout that ends a Wait command.
+func (p *parser) parseWaitTimeout(action Action) (Action, error) {
+	if p.curToken.kind == tokenDuration || p.curToken.kind == tokenNumber {
+		timeout, err := parseDuration(p.curToken.literal)
+		if err != nil || timeout <= 0 {
+			return Action{}, p.durationError("use '500ms' or '2s'")
+		}
+		action.Timeout = timeout
+		p.nextToken() // consume timeout
+	}
 
 	return action, nil
 }
@@ -363,7 +996,7 @@ func (p *parser) parseKeyAction() (Action, error) {
 	if !isValidKey(keyName) {
 		return Action{}, &ParseError{
 			Position: pos,
-			Message:  fmt.Sprintf("unknown key %q; valid keys: Enter, Tab, Escape, Space, Backspace, Delete, Up, Down, Left, Right, Home, End, PageUp, PageDown, Ctrl+C, Ctrl+D, Ctrl+L, Ctrl+Z", keyName),
+			Message:  fmt.Sprintf("unknown key %q; valid keys: Enter, Tab, Escape, Space, Backspace, Delete, Up, Down, Left, Right, Home, End, PageUp, PageDown, or a letter or digit with Ctrl+, Alt+ or Shift+", keyName),
 		}
 	}
 
@@ -375,6 +1008,12 @@ func (p *parser) parseKeyAction() (Action, error) {
 
 	p.nextToken() // consume key name
 
+	return p.parseKeyOptions(action)
+}
+
+// parseKeyOptions parses the optional @delay and repeat count that follow
+// a key.
+func (p *parser) parseKeyOptions(action Action) (Action, error) {
 	// Check for @delay modifier
 	if p.curToken.kind == tokenAt {
 		p.nextToken() // consume '@'
@@ -387,10 +1026,7 @@ func (p *parser) parseKeyAction() (Action, error) {
 
 		duration, err := parseDuration(p.curToken.literal)
 		if err != nil {
-			return Action{}, &ParseError{
-				Position: p.curToken.position,
-				Message:  fmt.Sprintf("invalid duration %q; use '500ms' or '2s'", p.curToken.literal),
-			}
+			return Action{}, p.durationError("use '500ms' or '2s'")
 		}
 		action.Delay = duration
 		p.nextToken() // consume duration
@@ -405,6 +1041,12 @@ func (p *parser) parseKeyAction() (Action, error) {
 				Message:  fmt.Sprintf("invalid repeat count %q", p.curToken.literal),
 			}
 		}
+		if repeat < 1 {
+			return Action{}, &ParseError{
+				Position: p.curToken.position,
+				Message:  fmt.Sprintf("repeat count must be at least 1, got %d", repeat),
+			}
+		}
 		action.Repeat = repeat
 		p.nextToken() // consume number
 	}
@@ -412,16 +1054,52 @@ func (p *parser) parseKeyAction() (Action, error) {
 	return action, nil
 }
 
-// parseCtrlAction parses a Ctrl+ combination.
-func (p *parser) parseCtrlAction() (Action, error) {
-	keyName := p.curToken.literal
+// parseModifiedKeyAction parses a key with modifiers, such as Alt+X,
+// Shift+Tab or Ctrl+Shift+C. Ctrl with a single character is an
+// ActionCtrl; other combinations are an ActionKey with Modifiers, which
+// accepts @delay and a repeat count like any key.
+func (p *parser) parseModifiedKeyAction() (Action, error) {
+	literal := p.curToken.literal
+	pos := p.curToken.position
 
-	action := Action{
-		Kind: ActionCtrl,
-		Key:  strings.ToLower(keyName[5:]), // Extract key after "Ctrl+"
+	parts := strings.Split(literal, "+")
+	var mods Modifier
+	offset := 0
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := modifiers[strings.ToLower(part)]
+		if !ok {
+			return Action{}, &ParseError{
+				Position: pos + offset,
+				Message:  fmt.Sprintf("unknown modifier %q in %q; use Ctrl, Alt or Shift", part, literal),
+			}
+		}
+		if mods&mod != 0 {
+			return Action{}, &ParseError{
+				Position: pos + offset,
+				Message:  fmt.Sprintf("modifier %q repeated in %q", part, literal),
+			}
+		}
+		mods |= mod
+		offset += len(part) + 1
 	}
 
-	p.nextToken() // consume Ctrl+key
+	key := parts[len(parts)-1]
+	single := len(key) == 1 && (isLetter(key[0]) || isDigit(key[0]))
+	if !single && !validKeys[strings.ToLower(key)] {
+		message := fmt.Sprintf("unknown key %q after %s+", key, mods)
+		if key == "" {
+			message = fmt.Sprintf("expected key after %q", literal)
+		}
+		return Action{}, &ParseError{Position: pos + offset, Message: message}
+	}
 
-	return action, nil
+	p.nextToken() // consume the combination
+
+	if single {
+		key = strings.ToLower(key)
+		if mods == ModCtrl {
+			return Action{Kind: ActionCtrl, Key: key}, nil
+		}
+	}
+	return p.parseKeyOptions(Action{Kind: ActionKey, Key: key, Modifiers: mods, Repeat: 1})
 }
@@ -8,302 +8,946 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestParse(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   string
-		want    []Action
-		wantErr string
-	}{
-		{
-			name:  "simple type",
-			input: "Type 'hello'",
-			want:  []Action{{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond}},
+// parseTests are the scripts TestParse checks, and the corpus that
+// TestFormat_RoundTrip formats back.
+var parseTests = []struct {
+	name    string
+	input   string
+	want    []Action
+	wantErr string
+}{
+	{
+		name:  "simple type",
+		input: "Type 'hello'",
+		want:  []Action{{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond}},
+	},
+	{
+		name:  "comments",
+		input: "# open the menu\nType '#tag' // search\nEnter # pick",
+		want: []Action{
+			{Kind: ActionType, Text: "#tag", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
 		},
-		{
-			name:  "type with double quotes",
-			input: `Type "hello"`,
-			want:  []Action{{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond}},
+	},
+	{
+		name:  "type with double quotes",
+		input: `Type "hello"`,
+		want:  []Action{{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond}},
+	},
+	{
+		name:  "adjacent strings join",
+		input: `Type "it's "'"ok"' Enter`,
+		want: []Action{
+			{Kind: ActionType, Text: `it's "ok"`, Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
 		},
-		{
-			name:  "type with speed",
-			input: "Type@30ms 'hello'",
-			want:  []Action{{Kind: ActionType, Text: "hello", Speed: 30 * time.Millisecond}},
+	},
+	{
+		name:    "unterminated joined string",
+		input:   `Type 'a'"b`,
+		wantErr: "unterminated string starting here; add the closing \"",
+	},
+	{
+		name:  "type with speed",
+		input: "Type@30ms 'hello'",
+		want:  []Action{{Kind: ActionType, Text: "hello", Speed: 30 * time.Millisecond}},
+	},
+	{
+		name:  "type over total duration",
+		input: "Type over 2s 'make test'",
+		want:  []Action{{Kind: ActionType, Text: "make test", Speed: 50 * time.Millisecond, Total: 2 * time.Second}},
+	},
+	{
+		name:  "type over is case-insensitive",
+		input: "type OVER 500ms ''",
+		want:  []Action{{Kind: ActionType, Text: "", Speed: 50 * time.Millisecond, Total: 500 * time.Millisecond}},
+	},
+	{
+		name:    "type over with speed",
+		input:   "Type@10ms over 2s 'x'",
+		wantErr: "cannot use both @speed and over",
+	},
+	{
+		name:    "type over without duration",
+		input:   "Type over 'x'",
+		wantErr: "expected duration after over",
+	},
+	{
+		name:    "type over zero",
+		input:   "Type over 0s 'x'",
+		wantErr: "Type over needs a positive duration",
+	},
+	{
+		name:  "type with speed in seconds",
+		input: "Type@1s 'hello'",
+		want:  []Action{{Kind: ActionType, Text: "hello", Speed: 1 * time.Second}},
+	},
+	{
+		name:  "type with spaces in text",
+		input: "Type 'ls -la'",
+		want:  []Action{{Kind: ActionType, Text: "ls -la", Speed: 50 * time.Millisecond}},
+	},
+	{
+		name:  "sleep with ms",
+		input: "Sleep 500ms",
+		want:  []Action{{Kind: ActionSleep, Duration: 500 * time.Millisecond}},
+	},
+	{
+		name:  "sleep with seconds",
+		input: "Sleep 2s",
+		want:  []Action{{Kind: ActionSleep, Duration: 2 * time.Second}},
+	},
+	{
+		name:  "sleep with decimal seconds",
+		input: "Sleep 1.5s",
+		want:  []Action{{Kind: ActionSleep, Duration: 1500 * time.Millisecond}},
+	},
+	{
+		name:  "sleep with minutes, hours and compound units",
+		input: "Sleep 2m Sleep 1h Sleep 1m30s",
+		want: []Action{
+			{Kind: ActionSleep, Duration: 2 * time.Minute},
+			{Kind: ActionSleep, Duration: time.Hour},
+			{Kind: ActionSleep, Duration: 90 * time.Second},
 		},
-		{
-			name:  "type with speed in seconds",
-			input: "Type@1s 'hello'",
-			want:  []Action{{Kind: ActionType, Text: "hello", Speed: 1 * time.Second}},
+	},
+	{
+		name:  "type with microsecond and nanosecond speeds",
+		input: "Type@500us 'a' Type@500µs 'b' Type@10ns 'c'",
+		want: []Action{
+			{Kind: ActionType, Text: "a", Speed: 500 * time.Microsecond},
+			{Kind: ActionType, Text: "b", Speed: 500 * time.Microsecond},
+			{Kind: ActionType, Text: "c", Speed: 10 * time.Nanosecond},
 		},
-		{
-			name:  "type with spaces in text",
-			input: "Type 'ls -la'",
-			want:  []Action{{Kind: ActionType, Text: "ls -la", Speed: 50 * time.Millisecond}},
+	},
+	{
+		name:  "key delay in minutes with repeat",
+		input: "Down@2m 3",
+		want:  []Action{{Kind: ActionKey, Key: "Down", Delay: 2 * time.Minute, Repeat: 3}},
+	},
+	{
+		name:  "key simple - Enter",
+		input: "Enter",
+		want:  []Action{{Kind: ActionKey, Key: "Enter", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Tab (lowercase)",
+		input: "tab",
+		want:  []Action{{Kind: ActionKey, Key: "tab", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Escape",
+		input: "Escape",
+		want:  []Action{{Kind: ActionKey, Key: "Escape", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Space",
+		input: "Space",
+		want:  []Action{{Kind: ActionKey, Key: "Space", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Backspace",
+		input: "Backspace",
+		want:  []Action{{Kind: ActionKey, Key: "Backspace", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Delete",
+		input: "Delete",
+		want:  []Action{{Kind: ActionKey, Key: "Delete", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Up",
+		input: "Up",
+		want:  []Action{{Kind: ActionKey, Key: "Up", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Down",
+		input: "Down",
+		want:  []Action{{Kind: ActionKey, Key: "Down", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Left",
+		input: "Left",
+		want:  []Action{{Kind: ActionKey, Key: "Left", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Right",
+		input: "Right",
+		want:  []Action{{Kind: ActionKey, Key: "Right", Repeat: 1}},
+	},
+	{
+		name:  "key simple - Home",
+		input: "Home",
+		want:  []Action{{Kind: ActionKey, Key: "Home", Repeat: 1}},
+	},
+	{
+		name:  "key simple - End",
+		input: "End",
+		want:  []Action{{Kind: ActionKey, Key: "End", Repeat: 1}},
+	},
+	{
+		name:  "key simple - PageUp",
+		input: "PageUp",
+		want:  []Action{{Kind: ActionKey, Key: "PageUp", Repeat: 1}},
+	},
+	{
+		name:  "key simple - PageDown",
+		input: "PageDown",
+		want:  []Action{{Kind: ActionKey, Key: "PageDown", Repeat: 1}},
+	},
+	{
+		name:  "key repeat",
+		input: "Down 3",
+		want:  []Action{{Kind: ActionKey, Key: "Down", Repeat: 3}},
+	},
+	{
+		name:  "key repeat large",
+		input: "Enter 10",
+		want:  []Action{{Kind: ActionKey, Key: "Enter", Repeat: 10}},
+	},
+	{
+		name:  "key delay",
+		input: "Enter@200ms",
+		want:  []Action{{Kind: ActionKey, Key: "Enter", Delay: 200 * time.Millisecond, Repeat: 1}},
+	},
+	{
+		name:  "key delay in seconds",
+		input: "Tab@1s",
+		want:  []Action{{Kind: ActionKey, Key: "Tab", Delay: 1 * time.Second, Repeat: 1}},
+	},
+	{
+		name:  "key with delay and repeat",
+		input: "Down@500ms 3",
+		want:  []Action{{Kind: ActionKey, Key: "Down", Delay: 500 * time.Millisecond, Repeat: 3}},
+	},
+	{
+		name:  "ctrl combo - Ctrl+C",
+		input: "Ctrl+C",
+		want:  []Action{{Kind: ActionCtrl, Key: "c"}},
+	},
+	{
+		name:  "ctrl combo - Ctrl+D",
+		input: "Ctrl+D",
+		want:  []Action{{Kind: ActionCtrl, Key: "d"}},
+	},
+	{
+		name:  "ctrl combo - Ctrl+L (lowercase)",
+		input: "ctrl+l",
+		want:  []Action{{Kind: ActionCtrl, Key: "l"}},
+	},
+	{
+		name:  "ctrl combo - Ctrl+Z",
+		input: "Ctrl+Z",
+		want:  []Action{{Kind: ActionCtrl, Key: "z"}},
+	},
+	{
+		name:  "alt combo",
+		input: "Alt+X",
+		want:  []Action{{Kind: ActionKey, Key: "x", Modifiers: ModAlt, Repeat: 1}},
+	},
+	{
+		name:  "shift tab",
+		input: "Shift+Tab",
+		want:  []Action{{Kind: ActionKey, Key: "Tab", Modifiers: ModShift, Repeat: 1}},
+	},
+	{
+		name:  "chained modifiers",
+		input: "ctrl+shift+C",
+		want:  []Action{{Kind: ActionKey, Key: "c", Modifiers: ModCtrl | ModShift, Repeat: 1}},
+	},
+	{
+		name:  "ctrl with named key - Ctrl+Left",
+		input: "Ctrl+Left",
+		want:  []Action{{Kind: ActionKey, Key: "Left", Modifiers: ModCtrl, Repeat: 1}},
+	},
+	{
+		name:  "ctrl with named key - Ctrl+Right with repeat",
+		input: "Ctrl+Right 2",
+		want:  []Action{{Kind: ActionKey, Key: "Right", Modifiers: ModCtrl, Repeat: 2}},
+	},
+	{
+		name:  "ctrl with named key - Ctrl+Backspace",
+		input: "ctrl+backspace",
+		want:  []Action{{Kind: ActionKey, Key: "backspace", Modifiers: ModCtrl, Repeat: 1}},
+	},
+	{
+		name:    "ctrl with unknown key",
+		input:   "Ctrl+NotAKey",
+		wantErr: `unknown key "NotAKey" after Ctrl+`,
+	},
+	{
+		name:  "modified key with delay and repeat",
+		input: "Alt+Down@100ms 3",
+		want:  []Action{{Kind: ActionKey, Key: "Down", Modifiers: ModAlt, Delay: 100 * time.Millisecond, Repeat: 3}},
+	},
+	{
+		name:    "unknown modifier",
+		input:   "Meta+X",
+		wantErr: `unknown modifier "Meta"`,
+	},
+	{
+		name:    "repeated modifier",
+		input:   "Alt+alt+X",
+		wantErr: `modifier "alt" repeated`,
+	},
+	{
+		name:    "unknown key after modifier",
+		input:   "Shift+Foo",
+		wantErr: `unknown key "Foo" after Shift+`,
+	},
+	{
+		name:    "missing key after modifier",
+		input:   "Alt+",
+		wantErr: "expected key after",
+	},
+	{
+		name:  "complex script",
+		input: "Sleep 1s Type 'ls -la' Enter Sleep 500ms",
+		want: []Action{
+			{Kind: ActionSleep, Duration: time.Second},
+			{Kind: ActionType, Text: "ls -la", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionSleep, Duration: 500 * time.Millisecond},
 		},
-		{
-			name:  "sleep with ms",
-			input: "Sleep 500ms",
-			want:  []Action{{Kind: ActionSleep, Duration: 500 * time.Millisecond}},
+	},
+	{
+		name:  "type echo and enter",
+		input: "Type 'echo hello' Enter",
+		want: []Action{
+			{Kind: ActionType, Text: "echo hello", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
 		},
-		{
-			name:  "sleep with seconds",
-			input: "Sleep 2s",
-			want:  []Action{{Kind: ActionSleep, Duration: 2 * time.Second}},
+	},
+	{
+		name:  "fzf navigation",
+		input: "Down 5 Enter",
+		want: []Action{
+			{Kind: ActionKey, Key: "Down", Repeat: 5},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
 		},
-		{
-			name:  "key simple - Enter",
-			input: "Enter",
-			want:  []Action{{Kind: ActionKey, Key: "Enter", Repeat: 1}},
+	},
+	{
+		name:  "cat with Ctrl+D",
+		input: "Type 'hello' Enter Sleep 500ms Ctrl+D",
+		want: []Action{
+			{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionSleep, Duration: 500 * time.Millisecond},
+			{Kind: ActionCtrl, Key: "d"},
 		},
-		{
-			name:  "key simple - Tab (lowercase)",
-			input: "tab",
-			want:  []Action{{Kind: ActionKey, Key: "tab", Repeat: 1}},
+	},
+	{
+		name:  "screenshot without name",
+		input: "Type 'ls' Enter Screenshot",
+		want: []Action{
+			{Kind: ActionType, Text: "ls", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionScreenshot},
 		},
-		{
-			name:  "key simple - Escape",
-			input: "Escape",
-			want:  []Action{{Kind: ActionKey, Key: "Escape", Repeat: 1}},
+	},
+	{
+		name:  "screenshot with name",
+		input: `Screenshot "after-login" screenshot 'menu'`,
+		want: []Action{
+			{Kind: ActionScreenshot, Name: "after-login"},
+			{Kind: ActionScreenshot, Name: "menu"},
 		},
-		{
-			name:  "key simple - Space",
-			input: "Space",
-			want:  []Action{{Kind: ActionKey, Key: "Space", Repeat: 1}},
+	},
+	{
+		name:  "wait with timeout",
+		input: "Type 'make' Enter Wait /Build (ok|done)/ 30s",
+		want: []Action{
+			{Kind: ActionType, Text: "make", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionWait, Pattern: "Build (ok|done)", Timeout: 30 * time.Second},
 		},
-		{
-			name:  "key simple - Backspace",
-			input: "Backspace",
-			want:  []Action{{Kind: ActionKey, Key: "Backspace", Repeat: 1}},
+	},
+	{
+		name:  "wait for the prompt",
+		input: "Type 'make' Enter Wait Prompt 30s wait prompt",
+		want: []Action{
+			{Kind: ActionType, Text: "make", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionWait, Prompt: true, Timeout: 30 * time.Second},
+			{Kind: ActionWait, Prompt: true, Timeout: DefaultWaitTimeout},
 		},
-		{
-			name:  "key simple - Delete",
-			input: "Delete",
-			want:  []Action{{Kind: ActionKey, Key: "Delete", Repeat: 1}},
+	},
+	{
+		name:  "wait for the alternate screen",
+		input: "Type 'vim' Enter Wait AltScreen 3s wait altscreen",
+		want: []Action{
+			{Kind: ActionType, Text: "vim", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionWait, AltScreen: true, Timeout: 3 * time.Second},
+			{Kind: ActionWait, AltScreen: true, Timeout: DefaultWaitTimeout},
 		},
-		{
-			name:  "key simple - Up",
-			input: "Up",
-			want:  []Action{{Kind: ActionKey, Key: "Up", Repeat: 1}},
+	},
+	{
+		name:  "wait with default timeout and escaped slash",
+		input: `Wait /src\/main\.go/ Enter`,
+		want: []Action{
+			{Kind: ActionWait, Pattern: `src/main\.go`, Timeout: DefaultWaitTimeout},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
 		},
-		{
-			name:  "key simple - Down",
-			input: "Down",
-			want:  []Action{{Kind: ActionKey, Key: "Down", Repeat: 1}},
+	},
+	{
+		name:  "set theme",
+		input: "Set Theme 'solarized-dark' Set theme nord",
+		want: []Action{
+			{Kind: ActionSet, Setting: "theme", Value: "solarized-dark"},
+			{Kind: ActionSet, Setting: "theme", Value: "nord"},
 		},
-		{
-			name:  "key simple - Left",
-			input: "Left",
-			want:  []Action{{Kind: ActionKey, Key: "Left", Repeat: 1}},
+	},
+	{
+		name:  "signal",
+		input: "Signal INT Signal sigwinch Signal SIGTSTP",
+		want: []Action{
+			{Kind: ActionSignal, Signal: "INT"},
+			{Kind: ActionSignal, Signal: "WINCH"},
+			{Kind: ActionSignal, Signal: "TSTP"},
 		},
-		{
-			name:  "key simple - Right",
-			input: "Right",
-			want:  []Action{{Kind: ActionKey, Key: "Right", Repeat: 1}},
+	},
+	{
+		name:    "signal unknown",
+		input:   "Signal BOGUS",
+		wantErr: `unknown signal "BOGUS"; valid signals: HUP, INT`,
+	},
+	{
+		name:    "signal without name",
+		input:   "Signal",
+		wantErr: "expected signal name after Signal",
+	},
+	{
+		name:  "scene",
+		input: "Scene 'intro' Type 'ls' scene \"step 2\"",
+		want: []Action{
+			{Kind: ActionScene, Name: "intro"},
+			{Kind: ActionType, Text: "ls", Speed: DefaultTypeSpeed},
+			{Kind: ActionScene, Name: "step 2"},
 		},
-		{
-			name:  "key simple - Home",
-			input: "Home",
-			want:  []Action{{Kind: ActionKey, Key: "Home", Repeat: 1}},
+	},
+	{
+		name:  "burst",
+		input: "Enter Burst 10 @20ms burst 3 Burst 2@1s",
+		want: []Action{
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionBurst, Repeat: 10, Duration: 20 * time.Millisecond},
+			{Kind: ActionBurst, Repeat: 3, Duration: DefaultBurstSpacing},
+			{Kind: ActionBurst, Repeat: 2, Duration: time.Second},
 		},
-		{
-			name:  "key simple - End",
-			input: "End",
-			want:  []Action{{Kind: ActionKey, Key: "End", Repeat: 1}},
+	},
+	{
+		name:  "hide and show",
+		input: "Hide Type 'export TOKEN=x' Enter SHOW hide",
+		want: []Action{
+			{Kind: ActionHide},
+			{Kind: ActionType, Text: "export TOKEN=x", Speed: DefaultTypeSpeed},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionShow},
+			{Kind: ActionHide},
 		},
-		{
-			name:  "key simple - PageUp",
-			input: "PageUp",
-			want:  []Action{{Kind: ActionKey, Key: "PageUp", Repeat: 1}},
+	},
+	{
+		name:    "burst without count",
+		input:   "Burst @50ms",
+		wantErr: "expected frame count after Burst, e.g. Burst 10 @50ms",
+	},
+	{
+		name:    "burst of no frames",
+		input:   "Burst 0",
+		wantErr: "burst frame count must be between 1 and 1000, got 0",
+	},
+	{
+		name:    "burst without spacing",
+		input:   "Burst 5 @ Enter",
+		wantErr: "expected duration after @",
+	},
+	{
+		name:    "burst with zero spacing",
+		input:   "Burst 5 @0ms",
+		wantErr: `invalid duration "0ms"; Burst needs a positive spacing such as '50ms'`,
+	},
+	{
+		name:  "pane",
+		input: "Pane 'client' Type 'curl localhost' Enter pane \"server\"",
+		want: []Action{
+			{Kind: ActionPane, Name: "client"},
+			{Kind: ActionType, Text: "curl localhost", Speed: DefaultTypeSpeed},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionPane, Name: "server"},
 		},
-		{
-			name:  "key simple - PageDown",
-			input: "PageDown",
-			want:  []Action{{Kind: ActionKey, Key: "PageDown", Repeat: 1}},
+	},
+	{
+		name:    "pane without name",
+		input:   "Pane client",
+		wantErr: "expected quoted pane name after Pane, such as Pane 'client'",
+	},
+	{
+		name:    "pane with empty name",
+		input:   "Pane ''",
+		wantErr: "pane name must not be empty",
+	},
+	{
+		name:    "scene without name",
+		input:   "Scene intro",
+		wantErr: "expected quoted scene name after Scene",
+	},
+	{
+		name:    "scene with empty name",
+		input:   "Scene ' '",
+		wantErr: "scene name must not be empty",
+	},
+	{
+		name:    "set unknown setting",
+		input:   "Set Shell 'zsh'",
+		wantErr: `unknown setting "Shell"`,
+	},
+	{
+		name:  "set width and height",
+		input: "Set Width 1024 Set height 600",
+		want: []Action{
+			{Kind: ActionSet, Setting: "width", Value: "1024"},
+			{Kind: ActionSet, Setting: "height", Value: "600"},
 		},
-		{
-			name:  "key repeat",
-			input: "Down 3",
-			want:  []Action{{Kind: ActionKey, Key: "Down", Repeat: 3}},
+	},
+	{
+		name:    "set width without number",
+		input:   "Set Width 'wide'",
+		wantErr: "expected a positive number after Set Width",
+	},
+	{
+		name:    "set width zero",
+		input:   "Set Width 0",
+		wantErr: "expected a positive number after Set Width",
+	},
+	{
+		name:    "set without value",
+		input:   "Set Theme",
+		wantErr: "expected value after Set Theme",
+	},
+	{
+		name:    "wait without pattern",
+		input:   "Wait 5s",
+		wantErr: "expected /pattern/ after Wait",
+	},
+	{
+		name:    "wait with invalid pattern",
+		input:   "Wait /(unclosed/",
+		wantErr: "invalid wait pattern",
+	},
+	{
+		name:    "wait with a lone slash",
+		input:   "Wait /",
+		wantErr: "unterminated pattern starting here; add the closing /",
+	},
+	{
+		name:    "wait with a comment instead of a pattern",
+		input:   "Wait // 5s",
+		wantErr: "expected /pattern/ after Wait",
+	},
+	{
+		name:    "screenshot with empty name",
+		input:   "Screenshot ' '",
+		wantErr: "screenshot name must not be empty",
+	},
+	{
+		name:    "missing quote - type without quotes",
+		input:   "Type hello",
+		wantErr: "expected quoted string",
+	},
+	{
+		name:    "unknown key - Foo",
+		input:   "Foo",
+		wantErr: "unknown key",
+	},
+	{
+		name:    "unknown key - random",
+		input:   "Type 'test' Random",
+		wantErr: "unknown key",
+	},
+	{
+		name:    "invalid duration - missing unit",
+		input:   "Sleep 500",
+		wantErr: "duration 500 has no unit; did you mean 500ms?",
+	},
+	{
+		name:    "zero repeat count",
+		input:   "Down 0",
+		wantErr: "repeat count must be at least 1, got 0",
+	},
+	{
+		name:    "negative repeat count",
+		input:   "Down -1",
+		wantErr: "repeat count must be at least 1, got -1",
+	},
+	{
+		name:    "negative duration",
+		input:   "Sleep -1s",
+		wantErr: `invalid duration "-1s"`,
+	},
+	{
+		name:    "stray character",
+		input:   "Type 'ls' $ Enter",
+		wantErr: "unexpected character '$'",
+	},
+	{
+		name:    "stray non-ASCII character",
+		input:   "Enter → Tab",
+		wantErr: "unexpected character '→'",
+	},
+	{
+		name:    "invalid duration - decimal without unit",
+		input:   "Sleep 1.5",
+		wantErr: "duration 1.5 has no unit; did you mean 1.5s?",
+	},
+	{
+		name:    "invalid duration - bad format",
+		input:   "Sleep 1h1",
+		wantErr: `invalid duration "1h1"; use '500ms' or '2s'`,
+	},
+	{
+		name:    "expected duration after at",
+		input:   "Type@ 'hello'",
+		wantErr: "expected duration after @",
+	},
+	{
+		name:    "expected duration after sleep",
+		input:   "Sleep",
+		wantErr: "expected duration",
+	},
+	{
+		name:  "empty input",
+		input: "",
+		want:  []Action{},
+	},
+	{
+		name:  "whitespace only",
+		input: "   \t\n  ",
+		want:  []Action{},
+	},
+	{
+		name:  "type with fast speed",
+		input: "Type@20ms 'fast typing test'",
+		want:  []Action{{Kind: ActionType, Text: "fast typing test", Speed: 20 * time.Millisecond}},
+	},
+	{
+		name:  "enter with delay",
+		input: "Enter@500ms",
+		want:  []Action{{Kind: ActionKey, Key: "Enter", Delay: 500 * time.Millisecond, Repeat: 1}},
+	},
+	{
+		name:  "define and use snippet",
+		input: "Define login { Type 'user' Enter Sleep 200ms }\nUse login Type 'ls' use login",
+		want: []Action{
+			{Kind: ActionType, Text: "user", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionSleep, Duration: 200 * time.Millisecond},
+			{Kind: ActionType, Text: "ls", Speed: 50 * time.Millisecond},
+			{Kind: ActionType, Text: "user", Speed: 50 * time.Millisecond},
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionSleep, Duration: 200 * time.Millisecond},
 		},
-		{
-			name:  "key repeat large",
-			input: "Enter 10",
-			want:  []Action{{Kind: ActionKey, Key: "Enter", Repeat: 10}},
-		},
-		{
-			name:  "key delay",
-			input: "Enter@200ms",
-			want:  []Action{{Kind: ActionKey, Key: "Enter", Delay: 200 * time.Millisecond, Repeat: 1}},
-		},
-		{
-			name:  "key delay in seconds",
-			input: "Tab@1s",
-			want:  []Action{{Kind: ActionKey, Key: "Tab", Delay: 1 * time.Second, Repeat: 1}},
+	},
+	{
+		name:  "define without use adds no actions",
+		input: "define empty {}\nEnter",
+		want:  []Action{{Kind: ActionKey, Key: "Enter", Repeat: 1}},
+	},
+	{
+		name:  "snippet uses an earlier snippet",
+		input: "Define a { Enter } Define b { Use a Tab } Use b",
+		want: []Action{
+			{Kind: ActionKey, Key: "Enter", Repeat: 1},
+			{Kind: ActionKey, Key: "Tab", Repeat: 1},
 		},
+	},
+	{
+		name:    "use before define",
+		input:   "Use login Define login { Enter }",
+		wantErr: `unknown snippet "login"`,
+	},
+	{
+		name:    "snippet uses itself",
+		input:   "Define loop { Enter Use loop }",
+		wantErr: `snippet "loop" cannot use itself`,
+	},
+	{
+		name:    "nested define",
+		input:   "Define a { Define b { Enter } }",
+		wantErr: `Define cannot appear inside snippet "a"`,
+	},
+	{
+		name:    "snippet redefined",
+		input:   "Define a { Enter } Define a { Tab }",
+		wantErr: `snippet "a" is already defined`,
+	},
+	{
+		name:    "define without name",
+		input:   "Define { Enter }",
+		wantErr: "expected snippet name after Define",
+	},
+	{
+		name:    "define without brace",
+		input:   "Define a Enter",
+		wantErr: "expected { after Define a",
+	},
+	{
+		name:    "define without closing brace",
+		input:   "Define a { Enter",
+		wantErr: `snippet "a" is missing its closing }`,
+	},
+	{
+		name:    "use without name",
+		input:   "Use",
+		wantErr: "expected snippet name after Use",
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse_DurationNearMisses(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Action
+		wantErr string
+	}{
+		{name: "space before seconds", input: "Sleep 0.5 s", want: []Action{{Kind: ActionSleep, Duration: 500 * time.Millisecond}}},
+		{name: "space before milliseconds", input: "Sleep 500 ms", want: []Action{{Kind: ActionSleep, Duration: 500 * time.Millisecond}}},
+		{name: "tab before unit", input: "Sleep 2\ts", want: []Action{{Kind: ActionSleep, Duration: 2 * time.Second}}},
+		{name: "space before unit in a speed", input: "Type@50 ms 'x'", want: []Action{{Kind: ActionType, Text: "x", Speed: 50 * time.Millisecond}}},
+		{name: "space before unit in a timeout", input: "Wait /x/ 5 s", want: []Action{{Kind: ActionWait, Pattern: "x", Timeout: 5 * time.Second}}},
 		{
-			name:  "key with delay and repeat",
-			input: "Down@500ms 3",
-			want:  []Action{{Kind: ActionKey, Key: "Down", Delay: 500 * time.Millisecond, Repeat: 3}},
+			name:  "a command after a repeat count is not a unit",
+			input: "Down 3 sleep 1s",
+			want:  []Action{{Kind: ActionKey, Key: "Down", Repeat: 3}, {Kind: ActionSleep, Duration: time.Second}},
 		},
+		{name: "bare seconds", input: "Sleep 2", wantErr: "duration 2 has no unit; did you mean 2s?"},
+		{name: "bare fraction", input: "Sleep 0.5", wantErr: "duration 0.5 has no unit; did you mean 0.5s?"},
+		{name: "bare milliseconds", input: "Sleep 500", wantErr: "duration 500 has no unit; did you mean 500ms?"},
+		{name: "bare type speed", input: "Type@50 'x'", wantErr: "duration 50 has no unit; did you mean 50ms?"},
+		{name: "bare type total", input: "Type over 3 'x'", wantErr: "duration 3 has no unit; did you mean 3s?"},
+		{name: "bare key delay", input: "Enter@200", wantErr: "duration 200 has no unit; did you mean 200ms?"},
+		{name: "bare burst spacing", input: "Burst 3 @20", wantErr: "duration 20 has no unit; did you mean 20ms?"},
+		{name: "bare wait timeout", input: "Wait /x/ 30", wantErr: "duration 30 has no unit; did you mean 30ms?"},
+		{name: "bare zero timeout", input: "Wait Prompt 0", wantErr: `invalid duration "0"; use '500ms' or '2s'`},
+		{name: "unknown unit", input: "Sleep 5 sec", wantErr: "duration 5 has no unit; did you mean 5s?"},
+		{name: "exponent", input: "Sleep 1e3ms", wantErr: `unexpected character 'e' after number 1`},
+		{name: "letter after the digits", input: "Type@50x 'ls'", wantErr: `unexpected character 'x' after number 50`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErr  string
+		position int
+	}{
 		{
-			name:  "ctrl combo - Ctrl+C",
-			input: "Ctrl+C",
-			want:  []Action{{Kind: ActionCtrl, Key: "c"}},
+			name:     "unknown key position",
+			input:    "Foo",
+			wantErr:  "unknown key",
+			position: 0,
 		},
 		{
-			name:  "ctrl combo - Ctrl+D",
-			input: "Ctrl+D",
-			want:  []Action{{Kind: ActionCtrl, Key: "d"}},
+			name:     "unknown key in sequence",
+			input:    "Type 'test' Foo",
+			wantErr:  "unknown key",
+			position: 12,
 		},
 		{
-			name:  "ctrl combo - Ctrl+L (lowercase)",
-			input: "ctrl+l",
-			want:  []Action{{Kind: ActionCtrl, Key: "l"}},
+			name:     "missing quote position",
+			input:    "Type hello",
+			wantErr:  "expected quoted string",
+			position: 5,
 		},
 		{
-			name:  "ctrl combo - Ctrl+Z",
-			input: "Ctrl+Z",
-			want:  []Action{{Kind: ActionCtrl, Key: "z"}},
+			name:     "unknown modifier position",
+			input:    "Enter Ctrl+Hyper+C",
+			wantErr:  "unknown modifier",
+			position: 11,
 		},
 		{
-			name:  "complex script",
-			input: "Sleep 1s Type 'ls -la' Enter Sleep 500ms",
-			want: []Action{
-				{Kind: ActionSleep, Duration: time.Second},
-				{Kind: ActionType, Text: "ls -la", Speed: 50 * time.Millisecond},
-				{Kind: ActionKey, Key: "Enter", Repeat: 1},
-				{Kind: ActionSleep, Duration: 500 * time.Millisecond},
-			},
+			name:     "unknown key after modifier position",
+			input:    "Ctrl+Alt+Nope",
+			wantErr:  "unknown key",
+			position: 9,
 		},
 		{
-			name:  "type echo and enter",
-			input: "Type 'echo hello' Enter",
-			want: []Action{
-				{Kind: ActionType, Text: "echo hello", Speed: 50 * time.Millisecond},
-				{Kind: ActionKey, Key: "Enter", Repeat: 1},
-			},
+			name:     "invalid duration position",
+			input:    "Sleep 500",
+			wantErr:  "has no unit",
+			position: 6,
 		},
 		{
-			name:  "fzf navigation",
-			input: "Down 5 Enter",
-			want: []Action{
-				{Kind: ActionKey, Key: "Down", Repeat: 5},
-				{Kind: ActionKey, Key: "Enter", Repeat: 1},
-			},
+			name:     "error inside snippet points at its definition",
+			input:    "Define a { Sleep 500 }\nUse a",
+			wantErr:  "has no unit",
+			position: 17,
 		},
 		{
-			name:  "cat with Ctrl+D",
-			input: "Type 'hello' Enter Sleep 500ms Ctrl+D",
-			want: []Action{
-				{Kind: ActionType, Text: "hello", Speed: 50 * time.Millisecond},
-				{Kind: ActionKey, Key: "Enter", Repeat: 1},
-				{Kind: ActionSleep, Duration: 500 * time.Millisecond},
-				{Kind: ActionCtrl, Key: "d"},
-			},
+			name:     "unknown snippet points at its name",
+			input:    "Enter Use nope",
+			wantErr:  "unknown snippet",
+			position: 10,
 		},
 		{
-			name:    "missing quote - type without quotes",
-			input:   "Type hello",
-			wantErr: "expected quoted string",
+			name:     "repeat count position",
+			input:    "Enter\nDown 0",
+			wantErr:  "repeat count must be at least 1",
+			position: 11,
 		},
 		{
-			name:    "unknown key - Foo",
-			input:   "Foo",
-			wantErr: "unknown key",
+			name:     "stray character position",
+			input:    "Type 'ls' $ Enter",
+			wantErr:  "unexpected character",
+			position: 10,
 		},
 		{
-			name:    "unknown key - random",
-			input:   "Type 'test' Random",
-			wantErr: "unknown key",
+			name:     "stray character before a parse error",
+			input:    "Type $",
+			wantErr:  "unexpected character",
+			position: 5,
 		},
 		{
-			name:    "invalid duration - missing unit",
-			input:   "Sleep 500",
-			wantErr: "invalid duration",
+			name:     "ampersand between actions",
+			input:    "Type 'ls' & Enter",
+			wantErr:  "unexpected character '&'",
+			position: 10,
 		},
 		{
-			name:    "invalid duration - bad format",
-			input:   "Sleep 500x",
-			wantErr: "invalid duration",
+			name:     "trailing semicolon",
+			input:    "Sleep 500ms;",
+			wantErr:  "unexpected character ';'",
+			position: 11,
 		},
 		{
-			name:    "expected duration after at",
-			input:   "Type@ 'hello'",
-			wantErr: "expected duration after @",
+			name:     "unterminated single quote",
+			input:    "Type 'echo hello",
+			wantErr:  "unterminated string",
+			position: 5,
 		},
 		{
-			name:    "expected duration after sleep",
-			input:   "Sleep",
-			wantErr: "expected duration",
+			name:     "unterminated double quote",
+			input:    "Enter\nType \"echo hello",
+			wantErr:  "unterminated string",
+			position: 11,
 		},
 		{
-			name:  "empty input",
-			input: "",
-			want:  []Action{},
+			name:     "quote as last character",
+			input:    "Enter '",
+			wantErr:  "unterminated string",
+			position: 6,
 		},
 		{
-			name:  "whitespace only",
-			input: "   \t\n  ",
-			want:  []Action{},
+			name:     "pattern ended by a newline",
+			input:    "Wait /abc\nType \"x\"",
+			wantErr:  "unterminated pattern starting here; add the closing /",
+			position: 5,
 		},
 		{
-			name:  "type with fast speed",
-			input: "Type@20ms 'fast typing test'",
-			want:  []Action{{Kind: ActionType, Text: "fast typing test", Speed: 20 * time.Millisecond}},
+			name:     "pattern ending in a backslash",
+			input:    "Enter\nWait /abc\\/",
+			wantErr:  "unterminated pattern starting here; add the closing /",
+			position: 11,
 		},
 		{
-			name:  "enter with delay",
-			input: "Enter@500ms",
-			want:  []Action{{Kind: ActionKey, Key: "Enter", Delay: 500 * time.Millisecond, Repeat: 1}},
+			name:     "pattern at the end of the script",
+			input:    "Wait /abc",
+			wantErr:  "unterminated pattern",
+			position: 5,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := Parse(tt.input)
+			_, err := Parse(tt.input)
+			require.Error(t, err)
 
-			if tt.wantErr != "" {
-				require.Error(t, err)
-				assert.Contains(t, err.Error(), tt.wantErr)
-				return
-			}
+			parseErr, ok := err.(*ParseError)
+			require.True(t, ok, "expected ParseError type")
 
-			require.NoError(t, err)
-			assert.Equal(t, tt.want, got)
+			assert.Contains(t, parseErr.Error(), tt.wantErr)
+			assert.Equal(t, tt.position, parseErr.Position)
 		})
 	}
 }
 
-func TestParseError(t *testing.T) {
+func TestParseError_LineColumn(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		wantErr  string
-		position int
+		name        string
+		input       string
+		wantLine    int
+		wantColumn  int
+		wantMsg     string
+		wantExcerpt string
 	}{
 		{
-			name:     "unknown key position",
-			input:    "Foo",
-			wantErr:  "unknown key",
-			position: 0,
+			name:        "first line",
+			input:       "Sleep 500",
+			wantLine:    1,
+			wantColumn:  7,
+			wantMsg:     "parse error at line 1, column 7: duration 500 has no unit",
+			wantExcerpt: "Sleep 500\n      ^",
 		},
 		{
-			name:     "unknown key in sequence",
-			input:    "Type 'test' Foo",
-			wantErr:  "unknown key",
-			position: 12,
+			name:        "later line",
+			input:       "Type 'ls'\nEnter\n  Bogus",
+			wantLine:    3,
+			wantColumn:  3,
+			wantMsg:     "parse error at line 3, column 3: unknown key",
+			wantExcerpt: "  Bogus\n  ^",
 		},
 		{
-			name:     "missing quote position",
-			input:    "Type hello",
-			wantErr:  "expected quoted string",
-			position: 5,
+			name:        "columns count characters not bytes",
+			input:       "Type 'héllo' Bogus",
+			wantLine:    1,
+			wantColumn:  14,
+			wantExcerpt: "Type 'héllo' Bogus\n             ^",
 		},
 		{
-			name:     "invalid duration position",
-			input:    "Sleep 500",
-			wantErr:  "invalid duration",
-			position: 6,
+			name:        "after comments",
+			input:       "# demo\nType 'a' # type it\n  Bogus // oops",
+			wantLine:    3,
+			wantColumn:  3,
+			wantMsg:     "parse error at line 3, column 3: unknown key",
+			wantExcerpt: "  Bogus // oops\n  ^",
+		},
+		{
+			name:        "error at end of input",
+			input:       "Enter\nType",
+			wantLine:    2,
+			wantColumn:  5,
+			wantExcerpt: "Type\n    ^",
 		},
 	}
 
@@ -312,11 +956,14 @@ func TestParseError(t *testing.T) {
 			_, err := Parse(tt.input)
 			require.Error(t, err)
 
-			parseErr, ok := err.(*ParseError)
-			require.True(t, ok, "expected ParseError type")
-
-			assert.Contains(t, parseErr.Error(), tt.wantErr)
-			assert.Equal(t, tt.position, parseErr.Position)
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tt.wantLine, parseErr.Line)
+			assert.Equal(t, tt.wantColumn, parseErr.Column)
+			if tt.wantMsg != "" {
+				assert.Contains(t, parseErr.Error(), tt.wantMsg)
+			}
+			assert.Equal(t, tt.wantExcerpt, parseErr.Excerpt(tt.input))
 		})
 	}
 }
@@ -403,6 +1050,57 @@ func TestLexer(t *testing.T) {
 				{kind: tokenEOF},
 			},
 		},
+		{
+			name:  "regex token",
+			input: `Wait /a\/b/ 2s`,
+			want: []token{
+				{kind: tokenIdent, literal: "Wait"},
+				{kind: tokenRegex, literal: "a/b"},
+				{kind: tokenDuration, literal: "2s"},
+				{kind: tokenEOF},
+			},
+		},
+		{
+			name:  "leading comments",
+			input: "# setup\n// more setup\nEnter",
+			want: []token{
+				{kind: tokenIdent, literal: "Enter"},
+				{kind: tokenEOF},
+			},
+		},
+		{
+			name:  "trailing comments",
+			input: "Type 'ls' # list files\nEnter // run it",
+			want: []token{
+				{kind: tokenIdent, literal: "Type"},
+				{kind: tokenString, literal: "ls"},
+				{kind: tokenIdent, literal: "Enter"},
+				{kind: tokenEOF},
+			},
+		},
+		{
+			name:  "hash and slashes in strings and patterns",
+			input: `Type 'echo #1 // x' Wait /a#b/`,
+			want: []token{
+				{kind: tokenIdent, literal: "Type"},
+				{kind: tokenString, literal: "echo #1 // x"},
+				{kind: tokenIdent, literal: "Wait"},
+				{kind: tokenRegex, literal: "a#b"},
+				{kind: tokenEOF},
+			},
+		},
+		{
+			name:  "brace tokens",
+			input: "Define a { Enter }",
+			want: []token{
+				{kind: tokenIdent, literal: "Define"},
+				{kind: tokenIdent, literal: "a"},
+				{kind: tokenLBrace, literal: "{"},
+				{kind: tokenIdent, literal: "Enter"},
+				{kind: tokenRBrace, literal: "}"},
+				{kind: tokenEOF},
+			},
+		},
 		{
 			name:  "ctrl token",
 			input: "Ctrl+C",
@@ -411,6 +1109,27 @@ func TestLexer(t *testing.T) {
 				{kind: tokenEOF},
 			},
 		},
+		{
+			name:  "unexpected character is not skipped",
+			input: "Type 'ls' & Enter",
+			want: []token{
+				{kind: tokenIdent, literal: "Type"},
+				{kind: tokenString, literal: "ls"},
+				{kind: tokenIllegal, literal: "unexpected character '&'"},
+				{kind: tokenIdent, literal: "Enter"},
+				{kind: tokenEOF},
+			},
+		},
+		{
+			name:  "trailing semicolon",
+			input: "Sleep 500ms;",
+			want: []token{
+				{kind: tokenIdent, literal: "Sleep"},
+				{kind: tokenDuration, literal: "500ms"},
+				{kind: tokenIllegal, literal: "unexpected character ';'"},
+				{kind: tokenEOF},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,131 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+)
+
+// snippet is a run of actions with the byte offsets they are written at,
+// as parsed from a statement or a Define block.
+type snippet struct {
+	actions   []Action
+	positions []int
+}
+
+func (s *snippet) append(other snippet) {
+	s.actions = append(s.actions, other.actions...)
+	s.positions = append(s.positions, other.positions...)
+}
+
+// parseStatement parses the next action, a Define block or Param
+// declaration, which yield no actions, or a Use, which yields the snippet's
+// actions.
+func (p *parser) parseStatement() (snippet, error) {
+	if p.curToken.kind == tokenIdent {
+		switch strings.ToLower(p.curToken.literal) {
+		case "define":
+			return snippet{}, p.parseDefine()
+		case "use":
+			return p.parseUse()
+		case "param":
+			return snippet{}, p.parseParam()
+		}
+	}
+	pos := p.curToken.position
+	action, err := p.parseAction()
+	if err != nil {
+		return snippet{}, err
+	}
+	return snippet{actions: []Action{action}, positions: []int{pos}}, nil
+}
+
+// parseDefine parses `Define NAME { actions }` and records the snippet.
+// Errors in its actions point into the definition. A snippet can only use
+// snippets defined before it, so snippets cannot recurse.
+func (p *parser) parseDefine() error {
+	definePos := p.curToken.position
+	if p.defining != "" {
+		return &ParseError{
+			Position: definePos,
+			Message:  fmt.Sprintf("Define cannot appear inside snippet %q", p.defining),
+		}
+	}
+	p.nextToken() // consume 'Define'
+
+	if p.curToken.kind != tokenIdent {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected snippet name after Define",
+		}
+	}
+	name := p.curToken.literal
+	if _, ok := p.snippets[name]; ok {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("snippet %q is already defined", name),
+		}
+	}
+	p.nextToken() // consume name
+
+	if p.curToken.kind != tokenLBrace {
+		return &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("expected { after Define %s", name),
+		}
+	}
+	p.nextToken() // consume '{'
+
+	p.defining = name
+	defer func() { p.defining = "" }()
+
+	body := snippet{actions: []Action{}, positions: []int{}}
+	for p.curToken.kind != tokenRBrace {
+		if p.curToken.kind == tokenEOF {
+			return &ParseError{
+				Position: definePos,
+				Message:  fmt.Sprintf("snippet %q is missing its closing }", name),
+			}
+		}
+		parsed, err := p.parseStatement()
+		if err != nil {
+			return err
+		}
+		body.append(parsed)
+	}
+	p.nextToken() // consume '}'
+
+	p.snippets[name] = body
+	return nil
+}
+
+// parseUse parses `Use NAME` and returns a copy of the snippet's actions,
+// positioned at its definition.
+func (p *parser) parseUse() (snippet, error) {
+	p.nextToken() // consume 'Use'
+
+	if p.curToken.kind != tokenIdent {
+		return snippet{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  "expected snippet name after Use",
+		}
+	}
+	name := p.curToken.literal
+	if name == p.defining {
+		return snippet{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("snippet %q cannot use itself", name),
+		}
+	}
+	body, ok := p.snippets[name]
+	if !ok {
+		return snippet{}, &ParseError{
+			Position: p.curToken.position,
+			Message:  fmt.Sprintf("unknown snippet %q; define it with Define %s { ... } before using it", name, name),
+		}
+	}
+	p.nextToken() // consume name
+
+	var used snippet
+	used.append(body)
+	return used, nil
+}
@@ -0,0 +1,307 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Steps of a Browser that Fail can make fail.
+const (
+	StepLaunch     = "launch"
+	StepVersion    = "version"
+	StepNavigate   = "navigate"
+	StepTerminal   = "terminal"
+	StepProbe      = "probe"
+	StepScreenshot = "screenshot"
+	StepText       = "text"
+	StepKey        = "key"
+	StepInsert     = "insert"
+	StepViewport   = "viewport"
+	StepResize     = "resize"
+)
+
+// BrowserProduct is the product a Browser reports.
+const BrowserProduct = "HeadlessChrome/131.0.6778.85"
+
+// ErrBrowserClosed is returned by every step of a Browser after it was
+// closed.
+var ErrBrowserClosed = errors.New("browser closed")
+
+// Browser stands in for Chrome driven over the DevTools protocol. It loads
+// pages over HTTP, finds the terminal on pages shaped like ttyd's, keeps
+// the text typed into it as the screen and renders a distinct image for
+// every screenshot. Its methods match the browser functions of a capture,
+// and each call is recorded.
+type Browser struct {
+	mu       sync.Mutex
+	calls    []string
+	counts   map[string]int
+	failures map[string]failure
+	launched bool
+	closed   bool
+	page     string
+	screen   strings.Builder
+	frames   int
+}
+
+// failure makes call n of a step, or every call when n is 0, return err.
+type failure struct {
+	n   int
+	err error
+}
+
+// NewBrowser returns a Browser that has not been launched.
+func NewBrowser() *Browser {
+	return &Browser{counts: map[string]int{}, failures: map[string]failure{}}
+}
+
+// Fail makes the nth call of step, counting from 1, return err; with n 0
+// every call fails.
+func (b *Browser) Fail(step string, n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures[step] = failure{n: n, err: err}
+}
+
+// Calls returns the steps called so far, in order, with their arguments.
+func (b *Browser) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
+
+// Count returns how many times step was called.
+func (b *Browser) Count(step string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts[step]
+}
+
+// Closed reports whether the browser was launched and has been closed
+// since.
+func (b *Browser) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.launched && b.closed
+}
+
+// Screen returns the text typed into the terminal.
+func (b *Browser) Screen() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.screen.String()
+}
+
+// call records a call of step and returns the error it must fail with, if
+// any. Callers hold b.mu.
+func (b *Browser) call(ctx context.Context, step string, args ...any) error {
+	b.counts[step]++
+	b.calls = append(b.calls, strings.TrimSpace(step+" "+fmt.Sprint(args...)))
+	if step != StepLaunch {
+		if !b.launched || b.closed {
+			return ErrBrowserClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if f, ok := b.failures[step]; ok && (f.n == 0 || f.n == b.counts[step]) {
+		return f.err
+	}
+	return nil
+}
+
+// Launch starts the browser. Like Chrome, it does not end with ctx; the
+// returned function closes it.
+func (b *Browser) Launch(ctx context.Context, _ []chromedp.ExecAllocatorOption, _ []chromedp.ContextOption) (context.Context, context.CancelFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepLaunch); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	b.launched = true
+	browserCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return browserCtx, func() {
+		cancel()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.closed = true
+	}, nil
+}
+
+// CheckVersion checks the browser's version, which always passes.
+func (b *Browser) CheckVersion(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.call(ctx, StepVersion)
+}
+
+// Navigate loads url, which must answer 200 OK.
+func (b *Browser) Navigate(ctx context.Context, url string) error {
+	b.mu.Lock()
+	err := b.call(ctx, StepNavigate, url)
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.page = string(body)
+	return nil
+}
+
+// FindTerminal returns the selector of the terminal element on the loaded
+// page once xterm.js has rendered there, and "" otherwise.
+func (b *Browser) FindTerminal(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepTerminal); err != nil {
+		return "", err
+	}
+	switch {
+	case !strings.Contains(b.page, "xterm-screen"):
+		return "", nil
+	case strings.Contains(b.page, `id="terminal-container"`):
+		return "#terminal-container", nil
+	default:
+		return ".xterm", nil
+	}
+}
+
+// Product returns BrowserProduct.
+func (b *Browser) Product(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepProbe); err != nil {
+		return "", err
+	}
+	return BrowserProduct, nil
+}
+
+// Screenshot returns a small PNG whose color differs from every earlier
+// screenshot's.
+func (b *Browser) Screenshot(ctx context.Context) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepScreenshot); err != nil {
+		return nil, err
+	}
+	b.frames++
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	c := color.RGBA{R: uint8(b.frames), G: uint8(b.frames >> 8), B: 0x80, A: 0xff}
+	for y := range 4 {
+		for x := range 4 {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Text returns the terminal text: everything typed so far.
+func (b *Browser) Text(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepText); err != nil {
+		return "", err
+	}
+	return b.screen.String(), nil
+}
+
+// SendKey presses key. A single character is typed, Enter starts a new
+// line and Backspace erases the last character; other keys change nothing
+// on screen.
+func (b *Browser) SendKey(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepKey, key); err != nil {
+		return err
+	}
+	switch {
+	case strings.EqualFold(key, "enter"):
+		b.screen.WriteByte('\n')
+	case strings.EqualFold(key, "backspace"):
+		text := []rune(b.screen.String())
+		if len(text) > 0 {
+			b.screen.Reset()
+			b.screen.WriteString(string(text[:len(text)-1]))
+		}
+	case utf8.RuneCountInString(key) == 1:
+		b.screen.WriteString(key)
+	}
+	return nil
+}
+
+// InsertText types text at once.
+func (b *Browser) InsertText(ctx context.Context, text string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call(ctx, StepInsert, text); err != nil {
+		return err
+	}
+	b.screen.WriteString(text)
+	return nil
+}
+
+// SetViewport sets the page size in CSS pixels.
+func (b *Browser) SetViewport(ctx context.Context, width, height int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.call(ctx, StepViewport, fmt.Sprintf("%dx%d", width, height))
+}
+
+// Resize sets the terminal size in cells.
+func (b *Browser) Resize(ctx context.Context, cols, rows int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.call(ctx, StepResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// ChromePath returns a path that passes for Chrome's where only the
+// executable's existence is checked: the running test binary.
+func ChromePath(t testing.TB) string {
+	t.Helper()
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatalf("find test executable: %v", err)
+	}
+	return path
+}
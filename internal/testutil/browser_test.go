@@ -0,0 +1,93 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowser(t *testing.T) {
+	ttyd := NewTTyd(t)
+	b := NewBrowser()
+	ctx := context.Background()
+
+	_, err := b.Screenshot(ctx)
+	require.ErrorIs(t, err, ErrBrowserClosed, "not launched yet")
+
+	browserCtx, closeBrowser, err := b.Launch(ctx, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, b.Navigate(browserCtx, ttyd.URL()))
+	selector, err := b.FindTerminal(browserCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "#terminal-container", selector)
+
+	require.NoError(t, b.InsertText(browserCtx, "ls"))
+	require.NoError(t, b.SendKey(browserCtx, "Enter"))
+	require.NoError(t, b.SendKey(browserCtx, "ctrl+c"))
+	text, err := b.Text(browserCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "ls\n", text)
+
+	first, err := b.Screenshot(browserCtx)
+	require.NoError(t, err)
+	second, err := b.Screenshot(browserCtx)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second, "every screenshot differs")
+
+	closeBrowser()
+	assert.True(t, b.Closed())
+	_, err = b.Text(ctx)
+	require.ErrorIs(t, err, ErrBrowserClosed)
+	assert.Equal(t, 2, b.Count(StepText))
+}
+
+func TestBrowser_FindTerminal(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{name: "ttyd", page: TTydPage, want: "#terminal-container"},
+		{name: "bare xterm", page: `<div class="xterm"><div class="xterm-screen"></div></div>`, want: ".xterm"},
+		{name: "not rendered", page: `<div id="terminal-container"></div>`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttyd := NewTTyd(t)
+			ttyd.SetPage(tt.page)
+			b := NewBrowser()
+			ctx, closeBrowser, err := b.Launch(context.Background(), nil, nil)
+			require.NoError(t, err)
+			defer closeBrowser()
+
+			require.NoError(t, b.Navigate(ctx, ttyd.URL()))
+			selector, err := b.FindTerminal(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, selector)
+		})
+	}
+}
+
+func TestBrowser_Fail(t *testing.T) {
+	boom := errors.New("boom")
+	b := NewBrowser()
+	ctx, closeBrowser, err := b.Launch(context.Background(), nil, nil)
+	require.NoError(t, err)
+	defer closeBrowser()
+
+	b.Fail(StepKey, 2, boom)
+	require.NoError(t, b.SendKey(ctx, "a"))
+	require.ErrorIs(t, b.SendKey(ctx, "b"), boom)
+	require.NoError(t, b.SendKey(ctx, "c"))
+
+	b.Fail(StepViewport, 0, boom)
+	require.ErrorIs(t, b.SetViewport(ctx, 800, 600), boom)
+	require.ErrorIs(t, b.SetViewport(ctx, 800, 600), boom)
+
+	assert.Equal(t, []string{"launch", "key a", "key b", "key c", "viewport 800x600", "viewport 800x600"}, b.Calls())
+	assert.Equal(t, "ac", b.Screen(), "a failed key types nothing")
+}
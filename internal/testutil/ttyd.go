@@ -0,0 +1,94 @@
+// Package testutil provides fakes of ttyd and the browser, so that a
+// capture can be run in tests without starting either.
+package testutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// TTydPage is the page a TTyd serves by default: the layout of ttyd's own
+// index page, with xterm.js's elements already rendered.
+const TTydPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>ttyd - Terminal</title></head>
+<body>
+<div id="terminal-container"><div class="terminal xterm"><div class="xterm-screen"></div></div></div>
+</body>
+</html>
+`
+
+// TTyd is an in-process HTTP server that answers like ttyd: its index page
+// on /, an empty auth token on /token, and 404 before it is ready.
+type TTyd struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	page     string
+	notReady int
+	requests []string
+}
+
+// NewTTyd starts a TTyd serving TTydPage. It is closed when the test ends.
+func NewTTyd(t testing.TB) *TTyd {
+	t.Helper()
+	f := &TTyd{page: TTydPage}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// URL returns the address of the terminal page.
+func (f *TTyd) URL() string {
+	return f.server.URL + "/"
+}
+
+// SetPage replaces the index page, as served by a different ttyd version
+// or by something that is not ttyd at all.
+func (f *TTyd) SetPage(html string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.page = html
+}
+
+// NotReadyFor makes the next n requests answer 404, as ttyd does while it
+// is still starting.
+func (f *TTyd) NotReadyFor(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notReady = n
+}
+
+// Requests returns the paths requested so far, in order.
+func (f *TTyd) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func (f *TTyd) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.URL.Path)
+	notReady := f.notReady > 0
+	if notReady {
+		f.notReady--
+	}
+	page := f.page
+	f.mu.Unlock()
+
+	switch {
+	case notReady:
+		http.NotFound(w, r)
+	case r.URL.Path == "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	case r.URL.Path == "/token":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"token": ""}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,42 @@
+package testutil
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestTTyd(t *testing.T) {
+	ttyd := NewTTyd(t)
+	ttyd.NotReadyFor(1)
+
+	status, _ := get(t, ttyd.URL())
+	assert.Equal(t, http.StatusNotFound, status, "not ready yet")
+
+	status, page := get(t, ttyd.URL())
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, TTydPage, page)
+
+	status, token := get(t, ttyd.URL()+"token")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"token": ""}`, token)
+
+	ttyd.SetPage("<p>not a terminal</p>")
+	_, page = get(t, ttyd.URL())
+	assert.Equal(t, "<p>not a terminal</p>", page)
+
+	assert.Equal(t, []string{"/", "/", "/token", "/"}, ttyd.Requests())
+}
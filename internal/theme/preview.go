@@ -0,0 +1,69 @@
+package theme
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+)
+
+// Preview layout, in pixels.
+const (
+	previewMargin = 12
+	swatchSize    = 30
+	swatchGap     = 8
+	barHeight     = 14
+)
+
+// Preview renders the theme's color-test pattern: the eight normal ANSI
+// colors, their bright variants beneath, and a foreground bar ending in a
+// cursor block, all on the theme background.
+func (t Theme) Preview() *image.RGBA {
+	width := 2*previewMargin + 8*swatchSize + 7*swatchGap
+	height := 2*previewMargin + 2*swatchSize + 2*swatchGap + barHeight
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	fill(img, img.Bounds(), t.Background)
+
+	for i, c := range t.ANSI() {
+		x := previewMargin + (i%8)*(swatchSize+swatchGap)
+		y := previewMargin + (i/8)*(swatchSize+swatchGap)
+		fill(img, image.Rect(x, y, x+swatchSize, y+swatchSize), c)
+	}
+
+	y := previewMargin + 2*(swatchSize+swatchGap)
+	barEnd := width - previewMargin - 2*swatchGap
+	fill(img, image.Rect(previewMargin, y, barEnd-swatchGap, y+barHeight), t.Foreground)
+
+	cursor := t.Cursor
+	if cursor == "" {
+		cursor = t.Foreground
+	}
+	fill(img, image.Rect(barEnd, y, barEnd+swatchGap, y+barHeight), cursor)
+
+	return img
+}
+
+// fill paints r with the hex color c.
+func fill(img draw.Image, r image.Rectangle, c string) {
+	draw.Draw(img, r, &image.Uniform{C: parseHex(c)}, image.Point{}, draw.Src)
+}
+
+// ParseColor converts a #rgb or #rrggbb color, as theme files use.
+func ParseColor(s string) (color.RGBA, error) {
+	if !hexColor.MatchString(s) {
+		return color.RGBA{}, fmt.Errorf("%q is not a hex color like #1e1e2e", s)
+	}
+	return parseHex(s), nil
+}
+
+// parseHex converts a validated #rgb or #rrggbb color.
+func parseHex(s string) color.RGBA {
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
@@ -0,0 +1,176 @@
+// Package theme provides terminal color themes: a catalog of built-in
+// themes embedded in the binary and custom themes loaded from JSON files.
+package theme
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed themes/*.json
+var builtinFS embed.FS
+
+// Theme is a terminal color scheme. JSON keys follow xterm.js's ITheme, so a
+// Theme can be handed to the terminal as-is.
+type Theme struct {
+	Name string `json:"-"`
+
+	Background          string `json:"background"`
+	Foreground          string `json:"foreground"`
+	Cursor              string `json:"cursor,omitempty"`
+	SelectionBackground string `json:"selectionBackground,omitempty"`
+
+	Black         string `json:"black"`
+	Red           string `json:"red"`
+	Green         string `json:"green"`
+	Yellow        string `json:"yellow"`
+	Blue          string `json:"blue"`
+	Magenta       string `json:"magenta"`
+	Cyan          string `json:"cyan"`
+	White         string `json:"white"`
+	BrightBlack   string `json:"brightBlack"`
+	BrightRed     string `json:"brightRed"`
+	BrightGreen   string `json:"brightGreen"`
+	BrightYellow  string `json:"brightYellow"`
+	BrightBlue    string `json:"brightBlue"`
+	BrightMagenta string `json:"brightMagenta"`
+	BrightCyan    string `json:"brightCyan"`
+	BrightWhite   string `json:"brightWhite"`
+}
+
+// hexColor matches #rgb and #rrggbb colors.
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ANSI returns the 16 ANSI colors in order: the eight normal colors
+// followed by their bright variants.
+func (t Theme) ANSI() []string {
+	return []string{
+		t.Black, t.Red, t.Green, t.Yellow, t.Blue, t.Magenta, t.Cyan, t.White,
+		t.BrightBlack, t.BrightRed, t.BrightGreen, t.BrightYellow,
+		t.BrightBlue, t.BrightMagenta, t.BrightCyan, t.BrightWhite,
+	}
+}
+
+// field is a theme color with its JSON key.
+type field struct {
+	key      string
+	value    string
+	required bool
+}
+
+// fields lists every color of t with its JSON key, in file order.
+func (t Theme) fields() []field {
+	return []field{
+		{"background", t.Background, true},
+		{"foreground", t.Foreground, true},
+		{"cursor", t.Cursor, false},
+		{"selectionBackground", t.SelectionBackground, false},
+		{"black", t.Black, true},
+		{"red", t.Red, true},
+		{"green", t.Green, true},
+		{"yellow", t.Yellow, true},
+		{"blue", t.Blue, true},
+		{"magenta", t.Magenta, true},
+		{"cyan", t.Cyan, true},
+		{"white", t.White, true},
+		{"brightBlack", t.BrightBlack, true},
+		{"brightRed", t.BrightRed, true},
+		{"brightGreen", t.BrightGreen, true},
+		{"brightYellow", t.BrightYellow, true},
+		{"brightBlue", t.BrightBlue, true},
+		{"brightMagenta", t.BrightMagenta, true},
+		{"brightCyan", t.BrightCyan, true},
+		{"brightWhite", t.BrightWhite, true},
+	}
+}
+
+// Validate checks that every required color is set and that all colors are
+// hex colors.
+func (t Theme) Validate() error {
+	for _, f := range t.fields() {
+		if f.value == "" {
+			if f.required {
+				return fmt.Errorf("missing required key %q", f.key)
+			}
+			continue
+		}
+		if !hexColor.MatchString(f.value) {
+			return fmt.Errorf("%s must be a hex color like #1e1e2e, got %q", f.key, f.value)
+		}
+	}
+	return nil
+}
+
+// Parse decodes and validates a theme from JSON. Unknown keys are rejected
+// so typos do not silently fall back to default colors.
+func Parse(data []byte) (Theme, error) {
+	var t Theme
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return Theme{}, fmt.Errorf("parse theme: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme: %w", err)
+	}
+	return t, nil
+}
+
+// Load reads a custom theme from a JSON file. The theme is named after the
+// file without its extension.
+func Load(file string) (Theme, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Theme{}, fmt.Errorf("read theme file: %w", err)
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", file, err)
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return t, nil
+}
+
+// Names returns the names of the built-in themes in sorted order.
+func Names() []string {
+	entries, _ := builtinFS.ReadDir("themes")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the built-in theme with the given name (case-insensitive).
+func Get(name string) (Theme, error) {
+	data, err := builtinFS.ReadFile("themes/" + strings.ToLower(name) + ".json")
+	if err != nil {
+		return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("built-in theme %s: %w", name, err)
+	}
+	t.Name = strings.ToLower(name)
+	return t, nil
+}
+
+// Resolve returns the built-in theme named ref, or loads ref as a JSON file
+// if it is not a built-in name.
+func Resolve(ref string) (Theme, error) {
+	if t, err := Get(ref); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(strings.ToLower(ref), ".json") || strings.ContainsAny(ref, `/\`) {
+		return Load(ref)
+	}
+	return Get(ref)
+}
@@ -0,0 +1,141 @@
+package theme
+
+import (
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, []string{"dracula", "gruvbox", "nord", "solarized-dark", "solarized-light"}, Names())
+}
+
+func TestBuiltinThemesAreValid(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			th, err := Get(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, th.Name)
+			assert.NoError(t, th.Validate())
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	th, err := Get("Dracula")
+	require.NoError(t, err)
+	assert.Equal(t, "#282a36", th.Background)
+
+	_, err = Get("monokai")
+	assert.EqualError(t, err, `unknown theme "monokai" (available: dracula, gruvbox, nord, solarized-dark, solarized-light)`)
+}
+
+// validJSON returns a complete theme as a JSON object with overrides applied;
+// a nil override value removes the key.
+func validJSON(t *testing.T, overrides map[string]any) []byte {
+	t.Helper()
+	th, err := Get("nord")
+	require.NoError(t, err)
+	data, err := json.Marshal(th)
+	require.NoError(t, err)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	for k, v := range overrides {
+		if v == nil {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+	}
+	data, err = json.Marshal(m)
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]any
+		wantErr   string
+	}{
+		{name: "valid"},
+		{name: "short hex", overrides: map[string]any{"red": "#f00"}},
+		{name: "optional keys may be omitted", overrides: map[string]any{"cursor": nil, "selectionBackground": nil}},
+		{name: "missing required key", overrides: map[string]any{"brightCyan": nil}, wantErr: `invalid theme: missing required key "brightCyan"`},
+		{name: "invalid hex", overrides: map[string]any{"background": "blue"}, wantErr: `invalid theme: background must be a hex color like #1e1e2e, got "blue"`},
+		{name: "invalid optional hex", overrides: map[string]any{"cursor": "#12345"}, wantErr: "cursor must be a hex color"},
+		{name: "unknown key", overrides: map[string]any{"purple": "#800080"}, wantErr: `unknown field "purple"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(validJSON(t, tt.overrides))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "mine.json")
+	require.NoError(t, os.WriteFile(custom, validJSON(t, map[string]any{"background": "#000000"}), 0o644))
+	broken := filepath.Join(dir, "broken.json")
+	require.NoError(t, os.WriteFile(broken, validJSON(t, map[string]any{"red": nil}), 0o644))
+
+	th, err := Resolve("gruvbox")
+	require.NoError(t, err)
+	assert.Equal(t, "gruvbox", th.Name)
+
+	th, err = Resolve(custom)
+	require.NoError(t, err)
+	assert.Equal(t, "mine", th.Name)
+	assert.Equal(t, "#000000", th.Background)
+
+	_, err = Resolve(broken)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `missing required key "red"`)
+
+	_, err = Resolve(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "read theme file")
+
+	_, err = Resolve("monokai")
+	assert.ErrorContains(t, err, "unknown theme")
+}
+
+func TestPreview(t *testing.T) {
+	th, err := Get("dracula")
+	require.NoError(t, err)
+
+	img := th.Preview()
+	bounds := img.Bounds()
+	assert.Equal(t, color.RGBA{R: 0x28, G: 0x2a, B: 0x36, A: 0xff}, img.At(0, 0), "background")
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0x55, B: 0x55, A: 0xff}, img.At(previewMargin+swatchSize+swatchGap+1, previewMargin+1), "red swatch")
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, img.At(bounds.Max.X-previewMargin-swatchSize/2, previewMargin+swatchSize+swatchGap+1), "bright white swatch")
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#1E1e2e")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0x1e, G: 0x1e, B: 0x2e, A: 0xff}, c)
+
+	for _, s := range []string{"", "1e1e2e", "#1e1e2", "#ggg", "red"} {
+		_, err := ParseColor(s)
+		assert.ErrorContains(t, err, "is not a hex color", s)
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0x00, B: 0x11, A: 0xff}, parseHex("#f01"))
+	assert.Equal(t, color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}, parseHex("#123456"))
+}
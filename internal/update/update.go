@@ -0,0 +1,341 @@
+// Package update finds newer scr releases on GitHub and replaces the running
+// executable with one.
+package update
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultAPIURL is the GitHub API that releases are looked up in.
+const DefaultAPIURL = "https://api.github.com"
+
+// Repo is the GitHub repository scr is released from.
+const Repo = "yarlson/scr"
+
+// ChecksumsAsset is the release asset listing the SHA-256 of every archive.
+const ChecksumsAsset = "checksums.txt"
+
+// BackupSuffix is appended to the executable's path for the copy of the
+// previous version left beside it.
+const BackupSuffix = ".old"
+
+// maxDownloadSize bounds a downloaded asset.
+const maxDownloadSize = 200 << 20
+
+// Release is a published scr release.
+type Release struct {
+	// Version is the release tag without its leading v, e.g. 1.4.0.
+	Version string
+	Assets  []Asset
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Updater checks for and installs scr releases.
+type Updater struct {
+	// APIURL is the GitHub API base URL; empty uses DefaultAPIURL.
+	APIURL string
+	// Client makes the requests; nil uses one that honors HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY.
+	Client *http.Client
+	// Token, when set, authenticates API requests, which raises GitHub's
+	// rate limit.
+	Token string
+	// GOOS and GOARCH pick the release archive.
+	GOOS, GOARCH string
+}
+
+// NewClient returns an HTTP client that goes through the proxy named by
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func NewClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	return &http.Client{Transport: transport}
+}
+
+// Latest returns the newest release that is not a draft or pre-release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	apiURL := u.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(apiURL, "/")+"/repos/"+Repo+"/releases/latest", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if u.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.Token)
+	}
+
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get latest release: %s", resp.Status)
+	}
+
+	var body struct {
+		TagName string  `json:"tag_name"`
+		Assets  []Asset `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode latest release: %w", err)
+	}
+	if body.TagName == "" {
+		return nil, fmt.Errorf("latest release has no tag")
+	}
+	return &Release{Version: strings.TrimPrefix(body.TagName, "v"), Assets: body.Assets}, nil
+}
+
+// AssetName returns the name of the release archive for goos and goarch,
+// as .goreleaser.yaml names it, e.g. scr_1.4.0_Linux_x86_64.tar.gz.
+func AssetName(version, goos, goarch string) string {
+	if goarch == "amd64" {
+		goarch = "x86_64"
+	}
+	if goos != "" {
+		goos = strings.ToUpper(goos[:1]) + goos[1:]
+	}
+	return fmt.Sprintf("scr_%s_%s_%s.tar.gz", version, goos, goarch)
+}
+
+// Download fetches the release's archive for u.GOOS and u.GOARCH, checks it
+// against the release's checksums and returns the scr executable inside.
+func (u *Updater) Download(ctx context.Context, release *Release) ([]byte, error) {
+	name := AssetName(release.Version, u.GOOS, u.GOARCH)
+	archive, ok := release.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s for %s/%s", release.Version, name, u.GOOS, u.GOARCH)
+	}
+	sums, ok := release.asset(ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify the download with", release.Version, ChecksumsAsset)
+	}
+
+	sumsData, err := u.get(ctx, sums.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", ChecksumsAsset, err)
+	}
+	want, err := checksumFor(sumsData, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := u.get(ctx, archive.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("%s has SHA-256 %s, but %s lists %s", name, got, ChecksumsAsset, want)
+	}
+	return extractExecutable(data)
+}
+
+// Replace atomically replaces the executable at path with data, keeping
+// the previous one at path+BackupSuffix, which it returns.
+func Replace(path string, data []byte) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("stat executable: %w", err)
+	}
+
+	// Written beside the executable, so the rename below stays on one
+	// file system and is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return "", fmt.Errorf("create new executable: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write new executable: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("write new executable: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("chmod new executable: %w", err)
+	}
+
+	backup := path + BackupSuffix
+	if err := os.Remove(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("remove old backup: %w", err)
+	}
+	if err := os.Link(path, backup); err != nil {
+		if err := copyFile(path, backup, info.Mode().Perm()); err != nil {
+			return "", fmt.Errorf("back up executable: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("replace executable: %w", err)
+	}
+	return backup, nil
+}
+
+// Newer reports whether version latest is newer than current. A current
+// version that is not a release, such as dev, is older than any release.
+func Newer(current, latest string) (bool, error) {
+	l, err := parseVersion(latest)
+	if err != nil {
+		return false, fmt.Errorf("latest version: %w", err)
+	}
+	c, err := parseVersion(current)
+	if err != nil {
+		return true, nil
+	}
+	for i := range c.nums {
+		if c.nums[i] != l.nums[i] {
+			return l.nums[i] > c.nums[i], nil
+		}
+	}
+	// A pre-release comes before its release
+	switch {
+	case c.pre == l.pre:
+		return false, nil
+	case l.pre == "":
+		return true, nil
+	case c.pre == "":
+		return false, nil
+	}
+	return l.pre > c.pre, nil
+}
+
+// version is a parsed MAJOR.MINOR.PATCH[-PRE] version.
+type version struct {
+	nums [3]int
+	pre  string
+}
+
+// parseVersion parses a version, with or without a leading v.
+func parseVersion(s string) (version, error) {
+	core, pre, _ := strings.Cut(strings.TrimPrefix(s, "v"), "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return version{}, fmt.Errorf("invalid version %q; expected MAJOR.MINOR.PATCH", s)
+	}
+	var v version
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, fmt.Errorf("invalid version %q; expected MAJOR.MINOR.PATCH", s)
+		}
+		v.nums[i] = n
+	}
+	v.pre = pre
+	return v, nil
+}
+
+// asset returns the release's asset called name.
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// client returns u.Client, or a proxy-aware client when it is nil.
+func (u *Updater) client() *http.Client {
+	if u.Client != nil {
+		return u.Client
+	}
+	return NewClient()
+}
+
+// get downloads url.
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("larger than %d MiB", maxDownloadSize>>20)
+	}
+	return data, nil
+}
+
+// checksumFor returns the SHA-256 that a checksums file lists for name.
+func checksumFor(data []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read %s: %w", ChecksumsAsset, err)
+	}
+	return "", fmt.Errorf("%s does not list %s", ChecksumsAsset, name)
+}
+
+// extractExecutable returns the scr executable from a release archive.
+func extractExecutable(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("archive has no scr executable")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "scr" {
+			data, err := io.ReadAll(io.LimitReader(tr, maxDownloadSize))
+			if err != nil {
+				return nil, fmt.Errorf("read archive: %w", err)
+			}
+			return data, nil
+		}
+	}
+}
+
+// copyFile copies src to dst, for file systems without hard links.
+func copyFile(src, dst string, perm os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, perm)
+}
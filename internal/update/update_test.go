@@ -0,0 +1,202 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+		wantErr         string
+	}{
+		{current: "1.2.3", latest: "1.2.4", want: true},
+		{current: "1.2.3", latest: "v1.3.0", want: true},
+		{current: "1.9.0", latest: "1.10.0", want: true},
+		{current: "1.2.3", latest: "1.2.3", want: false},
+		{current: "2.0.0", latest: "1.9.9", want: false},
+		{current: "1.2.3-rc1", latest: "1.2.3", want: true},
+		{current: "1.2.3", latest: "1.2.4-rc1", want: true},
+		{current: "1.2.3-rc1", latest: "1.2.3-rc2", want: true},
+		{current: "dev", latest: "1.0.0", want: true},
+		{current: "1.0.0", latest: "latest", wantErr: `latest version: invalid version "latest"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current+" to "+tt.latest, func(t *testing.T) {
+			got, err := Newer(tt.current, tt.latest)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	assert.Equal(t, "scr_1.4.0_Linux_x86_64.tar.gz", AssetName("1.4.0", "linux", "amd64"))
+	assert.Equal(t, "scr_1.4.0_Darwin_arm64.tar.gz", AssetName("1.4.0", "darwin", "arm64"))
+}
+
+func TestNewClient_UsesProxyFromEnvironment(t *testing.T) {
+	transport, ok := NewClient().Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.NotNil(t, transport.Proxy)
+}
+
+// releaseServer serves a GitHub API and release assets for version, with
+// an archive holding exe for linux/amd64.
+type releaseServer struct {
+	*httptest.Server
+	archive   []byte
+	checksums string
+	auth      string
+}
+
+func newReleaseServer(t *testing.T, version string, exe []byte) *releaseServer {
+	t.Helper()
+	s := &releaseServer{archive: tarGz(t, map[string][]byte{"README.md": []byte("readme"), "scr": exe})}
+	name := AssetName(version, "linux", "amd64")
+	sum := sha256.Sum256(s.archive)
+	s.checksums = fmt.Sprintf("%s  %s\n0000  scr_%s_Darwin_arm64.tar.gz\n", hex.EncodeToString(sum[:]), name, version)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/yarlson/scr/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		s.auth = r.Header.Get("Authorization")
+		fmt.Fprintf(w, `{"tag_name": "v%s", "assets": [
+			{"name": %q, "browser_download_url": %q},
+			{"name": "checksums.txt", "browser_download_url": %q}
+		]}`, version, name, s.URL+"/download/"+name, s.URL+"/download/checksums.txt")
+	})
+	mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(s.archive) })
+	mux.HandleFunc("/download/checksums.txt", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, s.checksums) })
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestUpdater_Latest(t *testing.T) {
+	srv := newReleaseServer(t, "1.4.0", []byte("new"))
+	u := &Updater{APIURL: srv.URL, Token: "secret", GOOS: "linux", GOARCH: "amd64"}
+
+	release, err := u.Latest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "1.4.0", release.Version)
+	assert.Len(t, release.Assets, 2)
+	assert.Equal(t, "Bearer secret", srv.auth)
+
+	u.APIURL = srv.URL + "/missing"
+	_, err = u.Latest(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "get latest release: 404 Not Found")
+}
+
+func TestUpdater_Download(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		checksums func() string
+		wantErr   string
+	}{
+		{name: "verified", goos: "linux"},
+		{name: "no asset for the platform", goos: "windows", wantErr: "release 1.4.0 has no scr_1.4.0_Windows_x86_64.tar.gz for windows/amd64"},
+		{
+			name:      "checksum mismatch",
+			goos:      "linux",
+			checksums: func() string { return "abcd  scr_1.4.0_Linux_x86_64.tar.gz\n" },
+			wantErr:   "but checksums.txt lists abcd",
+		},
+		{
+			name:      "not listed",
+			goos:      "linux",
+			checksums: func() string { return "" },
+			wantErr:   "checksums.txt does not list scr_1.4.0_Linux_x86_64.tar.gz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newReleaseServer(t, "1.4.0", []byte("new scr"))
+			if tt.checksums != nil {
+				srv.checksums = tt.checksums()
+			}
+			u := &Updater{APIURL: srv.URL, GOOS: tt.goos, GOARCH: "amd64"}
+			release, err := u.Latest(context.Background())
+			require.NoError(t, err)
+
+			exe, err := u.Download(context.Background(), release)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte("new scr"), exe)
+		})
+	}
+}
+
+func TestExtractExecutable_Missing(t *testing.T) {
+	_, err := extractExecutable(tarGz(t, map[string][]byte{"LICENSE": []byte("MIT")}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archive has no scr executable")
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "scr")
+	require.NoError(t, os.WriteFile(exe, []byte("old scr"), 0o755))
+	require.NoError(t, os.WriteFile(exe+BackupSuffix, []byte("older scr"), 0o755))
+
+	backup, err := Replace(exe, []byte("new scr"))
+	require.NoError(t, err)
+	assert.Equal(t, exe+BackupSuffix, backup)
+
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new scr", string(data))
+	info, err := os.Stat(exe)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	data, err = os.ReadFile(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "old scr", string(data))
+
+	// Nothing else is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,44 @@
+package scr
+
+import "github.com/yarlson/scr/internal/capture"
+
+// Encoder turns the frames of a run into its output. A run calls Begin
+// once, Frame for every captured image and End once when it finishes;
+// calls are serialized, so implementations need no locking. Register one
+// with RegisterEncoder and select it with WithFormat.
+type Encoder = capture.Encoder
+
+// EncoderFactory creates a fresh Encoder for each run.
+type EncoderFactory = capture.EncoderFactory
+
+// EncoderMeta describes a run to Encoder.Begin: its output directory,
+// command, script and frame timing settings.
+type EncoderMeta = capture.Meta
+
+// EncoderFrame is a captured image passed to Encoder.Frame, with the path
+// the run assigned it, why and when it was captured, and the Scene and
+// Burst it belongs to.
+type EncoderFrame = capture.Frame
+
+// FrameKind says why an EncoderFrame was captured.
+type FrameKind = capture.FrameKind
+
+// Frame kinds, as Frame.Trigger spells them too.
+const (
+	FrameInitial  = capture.FrameInitial
+	FrameInterval = capture.FrameInterval
+	FrameExplicit = capture.FrameExplicit
+	FrameBurst    = capture.FrameBurst
+	FrameFinal    = capture.FrameFinal
+)
+
+// ArtifactEncoder is implemented by encoders that write a single output
+// file, which a run reports as Result.Artifact.
+type ArtifactEncoder = capture.Artifact
+
+// RegisterEncoder makes an output format available to WithFormat under
+// name, replacing any format of that name. Names are case-insensitive. It
+// panics if name is empty or factory is nil.
+func RegisterEncoder(name string, factory EncoderFactory) {
+	capture.RegisterEncoder(name, factory)
+}
@@ -0,0 +1,172 @@
+package scr_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/yarlson/scr/pkg/scr"
+)
+
+func Example() {
+	c, err := scr.New(
+		scr.WithCommand("bash"),
+		scr.WithScript("Type 'ls -la' Enter Sleep 1s"),
+		scr.WithOutputDir("./docs/shots"),
+		scr.WithViewport(1024, 600),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := c.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, path := range result.Screenshots {
+		fmt.Println(path)
+	}
+}
+
+func ExampleParse() {
+	actions, err := scr.Parse("Type 'make test' Enter Sleep 2s")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, a := range actions {
+		fmt.Println(a)
+	}
+	// Output:
+	// Type 'make test'
+	// Enter
+	// Sleep 2s
+}
+
+func ExampleFormat() {
+	actions := []scr.Action{
+		{Kind: scr.ActionType, Text: `echo "it's done"`, Speed: 20 * time.Millisecond},
+		{Kind: scr.ActionKey, Key: "Enter", Repeat: 1},
+		{Kind: scr.ActionSleep, Duration: 1500 * time.Millisecond},
+	}
+	fmt.Print(scr.Format(actions))
+	// Output:
+	// Type@20ms 'echo "it'"'s done"'"'
+	// Enter
+	// Sleep 1.5s
+}
+
+func ExampleParseJSON() {
+	actions, err := scr.ParseJSON([]byte(`[
+		{"kind": "type", "text": "make test"},
+		{"kind": "key", "key": "Enter"},
+		{"kind": "wait", "pattern": "PASS|FAIL", "timeout": "2m"}
+	]`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(scr.Format(actions))
+
+	data, err := json.Marshal(actions[2])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(data))
+	// Output:
+	// Type 'make test'
+	// Enter
+	// Wait /PASS|FAIL/ 2m
+	// {"kind":"wait","pattern":"PASS|FAIL","timeout":"2m"}
+}
+
+func ExampleCapturer_Stream() {
+	c, err := scr.New(
+		scr.WithCommand("htop"),
+		scr.WithScript("Sleep 2s"),
+		scr.WithInterval(250*time.Millisecond),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	frames, errs := c.Stream(context.Background())
+	for f := range frames {
+		fmt.Printf("%d %s %s (%d bytes)\n", f.Seq, f.Trigger, f.Path, len(f.Data))
+	}
+	if err := <-errs; err != nil {
+		log.Fatal(err)
+	}
+}
+
+// frameLog writes the capture time and kind of every frame to a text file
+// instead of keeping the images.
+type frameLog struct {
+	f *os.File
+}
+
+func (e *frameLog) Begin(meta scr.EncoderMeta) error {
+	f, err := os.Create(filepath.Join(meta.OutputDir, "frames.log"))
+	e.f = f
+	return err
+}
+
+func (e *frameLog) Frame(frame scr.EncoderFrame) error {
+	_, err := fmt.Fprintf(e.f, "%v %s %d bytes\n", frame.Offset, frame.Kind, len(frame.Data))
+	return err
+}
+
+func (e *frameLog) End() error {
+	return e.f.Close()
+}
+
+func ExampleRegisterEncoder() {
+	scr.RegisterEncoder("framelog", func() scr.Encoder { return &frameLog{} })
+
+	c, err := scr.New(
+		scr.WithCommand("bash"),
+		scr.WithScript("Type 'ls' Enter"),
+		scr.WithFormat("framelog"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := c.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleTerminalReader() {
+	ctx, cancel := chromedp.NewContext(context.Background())
+	defer cancel()
+	if err := chromedp.Run(ctx, chromedp.Navigate("http://localhost:7681")); err != nil {
+		log.Fatal(err)
+	}
+
+	actions, err := scr.Parse("Type 'echo hi' Enter Wait /hi/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := scr.NewPlayer(scr.ChromeDriver{}).Play(ctx, actions); err != nil {
+		log.Fatal(err)
+	}
+
+	var term scr.TerminalReader
+	cols, rows, err := term.Size(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cursor, err := term.CursorPosition(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	line, err := term.Line(ctx, cursor.Row)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%dx%d terminal, cursor on %q\n", cols, rows, line)
+}
@@ -0,0 +1,85 @@
+package scr
+
+import "time"
+
+// Option configures a Capturer.
+type Option func(*options)
+
+// options collects the settings applied by Option values.
+type options struct {
+	command     string
+	outputDir   string
+	port        int
+	portSet     bool
+	interval    time.Duration
+	timeout     time.Duration
+	width       int
+	height      int
+	actions     []Action
+	script      string
+	systemFonts bool
+	format      string
+}
+
+// WithCommand sets the command to run in the terminal.
+func WithCommand(command string) Option {
+	return func(o *options) { o.command = command }
+}
+
+// WithOutputDir sets the directory screenshots are written to. It defaults
+// to ./screenshots.
+func WithOutputDir(dir string) Option {
+	return func(o *options) { o.outputDir = dir }
+}
+
+// WithPort sets the ttyd port. Without it, every run picks a free port.
+func WithPort(port int) Option {
+	return func(o *options) {
+		o.port = port
+		o.portSet = true
+	}
+}
+
+// WithInterval sets the time between interval screenshots; zero disables
+// them. It defaults to 500ms.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) { o.interval = interval }
+}
+
+// WithTimeout bounds the whole run. It defaults to 60s.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.timeout = timeout }
+}
+
+// WithViewport sets the browser viewport in CSS pixels. It defaults to
+// 1280x720.
+func WithViewport(width, height int) Option {
+	return func(o *options) {
+		o.width = width
+		o.height = height
+	}
+}
+
+// WithSystemFonts renders with the browser's own monospace font instead of
+// the font embedded in scr, which looks the same on every machine.
+func WithSystemFonts() Option {
+	return func(o *options) { o.systemFonts = true }
+}
+
+// WithFormat selects the output format by name: png, jpeg, webp, gif or
+// one added with RegisterEncoder. It defaults to png; an unknown name is
+// reported by Run.
+func WithFormat(name string) Option {
+	return func(o *options) { o.format = name }
+}
+
+// WithActions sets the actions to perform, typically from Parse.
+func WithActions(actions ...Action) Option {
+	return func(o *options) { o.actions = append([]Action(nil), actions...) }
+}
+
+// WithScript parses a tape script and sets its actions. A parse error is
+// reported by New.
+func WithScript(src string) Option {
+	return func(o *options) { o.script = src }
+}
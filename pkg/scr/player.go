@@ -0,0 +1,35 @@
+package scr
+
+import "github.com/yarlson/scr/internal/capture"
+
+// Driver is the terminal a Player acts on: it presses keys, types text and
+// reads back what the terminal shows. Implement it to drive something
+// other than a browser, such as a fake terminal in tests.
+type Driver = capture.Driver
+
+// ChromeDriver is the Driver of a ttyd page opened with chromedp: pass
+// Play a context from chromedp.NewContext whose page shows the terminal.
+type ChromeDriver = capture.ChromeDriver
+
+// TerminalReader reads the text, a line, the size and the cursor position
+// of the terminal on a ttyd page opened with chromedp, as ChromeDriver
+// does for a Player, for checks of what the terminal shows. Its zero value
+// is ready to use.
+type TerminalReader = capture.TerminalReader
+
+// Cursor is the cursor position a TerminalReader reads, in cells from the
+// top left of the screen, from 0.
+type Cursor = capture.Cursor
+
+// Player executes actions against a terminal with the same timing and
+// cancellation as a capture, without taking screenshots, for automating a
+// terminal or testing a program through one. Screenshot, Burst, Scene,
+// Hide, Show and Set actions are passed to its Perform function, or skipped
+// without one.
+type Player = capture.Player
+
+// NewPlayer returns a Player that acts on driver, with the command's
+// default settings.
+func NewPlayer(driver Driver) *Player {
+	return capture.NewPlayer(driver)
+}
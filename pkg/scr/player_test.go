@@ -0,0 +1,81 @@
+package scr
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoTerminal is a Driver that shows what is typed, with a prompt after
+// each Enter.
+type echoTerminal struct {
+	keys   []string
+	screen strings.Builder
+}
+
+func (e *echoTerminal) SendKey(_ context.Context, key string) error {
+	e.keys = append(e.keys, key)
+	if strings.EqualFold(key, "enter") {
+		e.screen.WriteString("\nok\n$ ")
+	} else if len(key) == 1 {
+		e.screen.WriteString(key)
+	}
+	return nil
+}
+
+func (e *echoTerminal) InsertText(_ context.Context, text string) error {
+	e.screen.WriteString(text)
+	return nil
+}
+
+func (e *echoTerminal) ReadText(context.Context) (string, error) {
+	return e.screen.String(), nil
+}
+
+func (e *echoTerminal) ReadAltScreen(context.Context) (bool, error) {
+	return false, nil
+}
+
+func TestPlayer_Play(t *testing.T) {
+	actions, err := Parse("Type@0 'make' Enter Wait /ok/ 1s Screenshot Ctrl+L Wait Prompt 1s")
+	require.NoError(t, err)
+
+	term := &echoTerminal{}
+	p := NewPlayer(term)
+	var performed []Action
+	p.Perform = func(_ context.Context, action Action, _ int) error {
+		performed = append(performed, action)
+		return nil
+	}
+
+	require.NoError(t, p.Play(context.Background(), actions))
+	assert.Equal(t, "make\nok\n$ ", term.screen.String())
+	assert.Equal(t, []string{"Enter", "ctrl+l"}, term.keys)
+	require.Len(t, performed, 1)
+	assert.Equal(t, ActionScreenshot, performed[0].Kind)
+}
+
+func TestPlayer_Play_WaitTimeout(t *testing.T) {
+	actions, err := Parse("Type@0 'make' Enter Wait /never/ 200ms")
+	require.NoError(t, err)
+
+	err = NewPlayer(&echoTerminal{}).Play(context.Background(), actions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/never/ did not appear within 200ms; last terminal output:\nmake\nok\n$")
+}
+
+func TestPlayer_Play_Cancel(t *testing.T) {
+	actions, err := Parse("Sleep 1m Type 'never'")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	term := &echoTerminal{}
+	err = NewPlayer(term).Play(ctx, actions)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, term.screen.String())
+}
@@ -0,0 +1,209 @@
+// Package scr captures screenshots of terminal programs from Go code. It is
+// the stable, embeddable surface of the scr command: a Capturer runs a
+// command in ttyd, drives it with tape script actions through headless
+// Chrome, and writes its frames to an output directory: PNG files, or JPEG
+// or WebP files, a GIF or a format of your own when WithFormat selects
+// one. A Player executes the same actions against a terminal without
+// taking screenshots.
+//
+// ttyd and Chrome must be installed, as for the command-line tool.
+package scr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yarlson/scr/internal/capture"
+	"github.com/yarlson/scr/internal/config"
+)
+
+// Default settings used when the corresponding option is not given.
+const (
+	DefaultOutputDir = "./screenshots"
+	// Deprecated: without WithPort, every run picks a free port.
+	DefaultPort     = 7681
+	DefaultInterval = 500 * time.Millisecond
+	DefaultTimeout  = 60 * time.Second
+)
+
+// Capturer runs captures with a fixed configuration. Run may be called more
+// than once, but not concurrently.
+type Capturer struct {
+	config *config.Config
+}
+
+// Result describes a finished run.
+type Result struct {
+	// Screenshots are the frame files written, in capture order.
+	Screenshots []string
+	// Artifact is the single file written by a format such as gif, or
+	// by an ArtifactEncoder, or "".
+	Artifact string
+	// Startup is the time from Run until the terminal was ready; Phases
+	// break it down into ttyd, browser and page steps. ttyd and the browser
+	// start at the same time, so their phases overlap.
+	Startup time.Duration
+	Phases  []Phase
+	// Total is the duration of the whole run.
+	Total time.Duration
+}
+
+// Phase is a named startup step and how long it took.
+type Phase struct {
+	Name string
+	// Offset is when the step began, relative to the start of Run.
+	Offset   time.Duration
+	Duration time.Duration
+}
+
+// New creates a Capturer from opts. It needs a command (WithCommand) and
+// reports invalid settings before anything is started. Without actions
+// (WithActions or WithScript), or with only Set actions, the capture is
+// static: the command's output in an initial and a final frame.
+func New(opts ...Option) (*Capturer, error) {
+	o := options{
+		outputDir: DefaultOutputDir,
+		port:      DefaultPort,
+		interval:  DefaultInterval,
+		timeout:   DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	actions := o.actions
+	if o.script != "" {
+		if len(o.actions) > 0 {
+			return nil, fmt.Errorf("cannot use both WithScript and WithActions")
+		}
+		parsed, err := Parse(o.script)
+		if err != nil {
+			return nil, fmt.Errorf("parse script: %w", err)
+		}
+		actions = parsed
+	}
+
+	cfg := &config.Config{
+		Command:            o.command,
+		OutputDir:          o.outputDir,
+		ScreenshotInterval: o.interval,
+		TTydPort:           o.port,
+		AutoPort:           !o.portSet,
+		Timeout:            o.timeout,
+		Actions:            actions,
+		Script:             o.script,
+		Width:              o.width,
+		Height:             o.height,
+		SystemFonts:        o.systemFonts,
+		Format:             o.format,
+		EscapeDelay:        config.DefaultEscapeDelay,
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	return &Capturer{config: cfg}, nil
+}
+
+// Frame is a screenshot delivered by Stream as soon as it is written.
+type Frame struct {
+	// Seq numbers the frames of a run in capture order, from 1.
+	Seq int
+	// Trigger says what the frame was captured for: "initial", "interval",
+	// "explicit" for a Screenshot action, "burst" or "final".
+	Trigger string
+	// Path is the file the run assigned the frame; formats that write a
+	// single file, such as gif, may not write it.
+	Path string
+	// Data is the encoded image: PNG, or JPEG or WebP when the format
+	// selects one. It is the receiver's to keep or modify.
+	Data []byte
+	// Time is when the frame was captured, and Offset the same time
+	// relative to the terminal becoming ready.
+	Time   time.Time
+	Offset time.Duration
+}
+
+// Run performs one capture. The returned Result is never nil: on failure it
+// describes what was captured before the error.
+func (c *Capturer) Run(ctx context.Context) (*Result, error) {
+	return c.run(ctx, nil)
+}
+
+// Stream performs one capture like Run and sends its frames on the first
+// channel while the run goes on. When the run ends, the frame channel is
+// closed, then the error channel receives the run's error, nil on success,
+// and is closed too; both channels are always closed.
+//
+// The capture waits while a frame is not received, so read frames until
+// the channel is closed. Cancelling ctx, or reaching the timeout, ends the
+// run; frames not received by then are dropped, and the error channel
+// receives the run's error.
+func (c *Capturer) Stream(ctx context.Context) (<-chan Frame, <-chan error) {
+	return stream(ctx, c.config.Timeout, func(ctx context.Context, send func(capture.Frame)) error {
+		_, err := c.run(ctx, send)
+		return err
+	})
+}
+
+// stream runs run in the background, bounded by timeout, and turns the
+// frames it reports into a Frame channel, with the closing order documented
+// on Stream. Frames are dropped instead of sent once the run's context is
+// done.
+func stream(ctx context.Context, timeout time.Duration, run func(ctx context.Context, send func(capture.Frame)) error) (<-chan Frame, <-chan error) {
+	frames := make(chan Frame)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		seq := 0
+		err := run(ctx, func(f capture.Frame) {
+			seq++
+			frame := Frame{
+				Seq:     seq,
+				Trigger: string(f.Kind),
+				Path:    f.Path,
+				Data:    bytes.Clone(f.Data),
+				Time:    f.Time,
+				Offset:  f.Offset,
+			}
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+			}
+		})
+		close(frames)
+		errs <- err
+	}()
+	return frames, errs
+}
+
+// run performs one capture, calling onFrame, if set, with each frame
+// written.
+func (c *Capturer) run(ctx context.Context, onFrame func(capture.Frame)) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	capturer := capture.NewCapturer(c.config)
+	if onFrame != nil {
+		capturer.OnFrame(onFrame)
+	}
+	err := capturer.Run(ctx)
+	return newResult(capturer.Stats(), capturer.Screenshots(), capturer.Artifact()), err
+}
+
+// newResult converts the internal run statistics into a Result.
+func newResult(stats capture.Stats, screenshots []string, artifact string) *Result {
+	r := &Result{
+		Screenshots: screenshots,
+		Artifact:    artifact,
+		Startup:     stats.Startup,
+		Total:       stats.Total,
+	}
+	for _, p := range stats.Phases {
+		r.Phases = append(r.Phases, Phase{Name: p.Name, Offset: p.Start.Sub(stats.Start), Duration: p.Duration})
+	}
+	return r
+}
@@ -0,0 +1,284 @@
+package scr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yarlson/scr/internal/capture"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		check   func(t *testing.T, c *Capturer)
+		wantErr string
+	}{
+		{
+			name: "applies defaults",
+			opts: []Option{WithCommand("bash"), WithScript("Type 'ls' Enter")},
+			check: func(t *testing.T, c *Capturer) {
+				assert.Equal(t, "bash", c.config.Command)
+				wantDir, err := filepath.Abs(DefaultOutputDir)
+				require.NoError(t, err)
+				assert.Equal(t, wantDir, c.config.OutputDir)
+				assert.Equal(t, DefaultPort, c.config.TTydPort)
+				assert.True(t, c.config.AutoPort)
+				assert.Equal(t, DefaultInterval, c.config.ScreenshotInterval)
+				assert.Equal(t, DefaultTimeout, c.config.Timeout)
+				assert.Equal(t, "Type 'ls' Enter", c.config.Script)
+				assert.Len(t, c.config.Actions, 2)
+			},
+		},
+		{
+			name: "applies options",
+			opts: []Option{
+				WithCommand("htop"),
+				WithOutputDir("/tmp/shots"),
+				WithPort(9000),
+				WithInterval(0),
+				WithTimeout(time.Minute),
+				WithViewport(800, 600),
+				WithSystemFonts(),
+				WithFormat("gif"),
+				WithActions(Action{Kind: ActionSleep, Duration: time.Second}),
+			},
+			check: func(t *testing.T, c *Capturer) {
+				assert.Equal(t, "/tmp/shots", c.config.OutputDir)
+				assert.Equal(t, 9000, c.config.TTydPort)
+				assert.False(t, c.config.AutoPort)
+				assert.Equal(t, time.Duration(0), c.config.ScreenshotInterval)
+				assert.Equal(t, time.Minute, c.config.Timeout)
+				assert.Equal(t, 800, c.config.Width)
+				assert.Equal(t, 600, c.config.Height)
+				assert.True(t, c.config.SystemFonts)
+				assert.Equal(t, "gif", c.config.Format)
+				assert.Equal(t, []Action{{Kind: ActionSleep, Duration: time.Second}}, c.config.Actions)
+			},
+		},
+		{
+			name: "no actions captures statically",
+			opts: []Option{WithCommand("ls")},
+			check: func(t *testing.T, c *Capturer) {
+				assert.Empty(t, c.config.Actions)
+			},
+		},
+		{
+			name: "comment-only script",
+			opts: []Option{WithCommand("ls"), WithScript("# nothing to type\n")},
+			check: func(t *testing.T, c *Capturer) {
+				assert.Empty(t, c.config.Actions)
+			},
+		},
+		{
+			name: "settings-only script",
+			opts: []Option{WithCommand("ls"), WithScript("Set Theme dracula\nSet Width 800")},
+			check: func(t *testing.T, c *Capturer) {
+				assert.Len(t, c.config.Actions, 2)
+			},
+		},
+		{
+			name:    "rejects script and actions together",
+			opts:    []Option{WithCommand("bash"), WithScript("Enter"), WithActions(Action{Kind: ActionKey, Key: "enter"})},
+			wantErr: "cannot use both WithScript and WithActions",
+		},
+		{
+			name:    "reports script parse errors",
+			opts:    []Option{WithCommand("bash"), WithScript("Type")},
+			wantErr: "parse script",
+		},
+		{
+			name:    "validates settings",
+			opts:    []Option{WithCommand("bash"), WithScript("Enter"), WithPort(70000)},
+			wantErr: "ttyd-port must be between 1 and 65535",
+		},
+		{
+			name:    "requires a command",
+			opts:    []Option{WithScript("Enter")},
+			wantErr: "command must be non-empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	actions, err := Parse("Type 'hi' Enter")
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, ActionType, actions[0].Kind)
+	assert.Equal(t, "hi", actions[0].Text)
+
+	_, err = Parse("Type")
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 1, parseErr.Line)
+}
+
+func TestNewResult(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := newResult(capture.Stats{
+		Start:   start,
+		Startup: 2 * time.Second,
+		Total:   5 * time.Second,
+		Phases: []capture.Phase{
+			{Name: "ttyd", Start: start.Add(10 * time.Millisecond), Duration: 500 * time.Millisecond},
+			{Name: "browser", Start: start.Add(10 * time.Millisecond), Duration: 1500 * time.Millisecond},
+		},
+	}, []string{"out/screenshot_001.png"}, "out/capture.gif")
+
+	assert.Equal(t, &Result{
+		Screenshots: []string{"out/screenshot_001.png"},
+		Artifact:    "out/capture.gif",
+		Startup:     2 * time.Second,
+		Phases: []Phase{
+			{Name: "ttyd", Offset: 10 * time.Millisecond, Duration: 500 * time.Millisecond},
+			{Name: "browser", Offset: 10 * time.Millisecond, Duration: 1500 * time.Millisecond},
+		},
+		Total: 5 * time.Second,
+	}, got)
+}
+
+// countEncoder counts the frames of a run, as a custom format would.
+type countEncoder struct{ frames int }
+
+func (e *countEncoder) Begin(EncoderMeta) error    { return nil }
+func (e *countEncoder) Frame(f EncoderFrame) error { e.frames++; return nil }
+func (e *countEncoder) End() error                 { return nil }
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("Count", func() Encoder { return &countEncoder{} })
+
+	assert.Contains(t, capture.EncoderNames(), "count")
+	enc, err := capture.NewEncoder("COUNT")
+	require.NoError(t, err)
+	require.NoError(t, enc.Frame(EncoderFrame{Kind: FrameInitial}))
+	assert.Equal(t, 1, enc.(*countEncoder).frames)
+
+	c, err := New(WithCommand("ls"), WithFormat("count"))
+	require.NoError(t, err)
+	assert.Equal(t, "count", c.config.Format)
+}
+
+func TestCapturer_Run_ReturnsResultOnError(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	c, err := New(WithCommand("bash"), WithScript("Enter"), WithOutputDir(t.TempDir()))
+	require.NoError(t, err)
+
+	result, err := c.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no Chrome/Chromium found")
+	require.NotNil(t, result)
+	assert.Empty(t, result.Screenshots)
+}
+
+func TestStream(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	captured := []capture.Frame{
+		{Path: "out/screenshot_001.png", Data: []byte("one"), Kind: capture.FrameInitial, Time: start},
+		{Path: "out/menu.png", Data: []byte("two"), Kind: capture.FrameExplicit, Time: start.Add(time.Second), Offset: time.Second},
+		{Path: "out/screenshot_002.png", Data: []byte("three"), Kind: capture.FrameFinal, Time: start.Add(2 * time.Second), Offset: 2 * time.Second},
+	}
+	runErr := errors.New("browser went away")
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "failure", err: runErr, wantErr: runErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, errs := stream(context.Background(), time.Minute, func(_ context.Context, send func(capture.Frame)) error {
+				for _, f := range captured {
+					f.Data = bytes.Clone(f.Data)
+					send(f)
+					f.Data[0] = 'x' // the sent frame keeps its own copy
+				}
+				return tt.err
+			})
+
+			var got []Frame
+			for f := range frames {
+				got = append(got, f)
+			}
+			assert.Equal(t, []Frame{
+				{Seq: 1, Trigger: "initial", Path: "out/screenshot_001.png", Data: []byte("one"), Time: start},
+				{Seq: 2, Trigger: "explicit", Path: "out/menu.png", Data: []byte("two"), Time: start.Add(time.Second), Offset: time.Second},
+				{Seq: 3, Trigger: "final", Path: "out/screenshot_002.png", Data: []byte("three"), Time: start.Add(2 * time.Second), Offset: 2 * time.Second},
+			}, got)
+
+			err, ok := <-errs
+			require.True(t, ok, "the run's error is sent after the frames")
+			assert.Equal(t, tt.wantErr, err)
+			_, ok = <-errs
+			assert.False(t, ok, "the error channel is closed")
+		})
+	}
+}
+
+func TestStream_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	frames, errs := stream(ctx, time.Minute, func(ctx context.Context, send func(capture.Frame)) error {
+		send(capture.Frame{Kind: capture.FrameInitial})
+		send(capture.Frame{Kind: capture.FrameInterval})
+		return ctx.Err()
+	})
+
+	first := <-frames
+	assert.Equal(t, 1, first.Seq)
+
+	// Nobody reads the second frame; cancelling drops it and ends the run
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	_, ok := <-frames
+	assert.False(t, ok, "the frame channel is closed")
+}
+
+func TestStream_Timeout(t *testing.T) {
+	frames, errs := stream(context.Background(), 10*time.Millisecond, func(ctx context.Context, send func(capture.Frame)) error {
+		send(capture.Frame{Kind: capture.FrameInitial})
+		return ctx.Err()
+	})
+
+	require.ErrorIs(t, <-errs, context.DeadlineExceeded, "an unread frame does not outlast the timeout")
+	_, ok := <-frames
+	assert.False(t, ok)
+}
+
+func TestCapturer_Stream_ReportsErrors(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	c, err := New(WithCommand("bash"), WithScript("Enter"), WithOutputDir(t.TempDir()))
+	require.NoError(t, err)
+
+	frames, errs := c.Stream(context.Background())
+	for range frames {
+		t.Error("no frames are captured without Chrome")
+	}
+	err = <-errs
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no Chrome/Chromium found")
+}
@@ -0,0 +1,69 @@
+package scr
+
+import "github.com/yarlson/scr/internal/script"
+
+// Action is a single step of a tape script, as returned by Parse.
+type Action = script.Action
+
+// ActionKind identifies what an Action does.
+type ActionKind = script.ActionKind
+
+// Action kinds.
+const (
+	ActionType       = script.ActionType
+	ActionSleep      = script.ActionSleep
+	ActionKey        = script.ActionKey
+	ActionCtrl       = script.ActionCtrl
+	ActionScreenshot = script.ActionScreenshot
+	ActionWait       = script.ActionWait
+	ActionSet        = script.ActionSet
+	ActionSignal     = script.ActionSignal
+	ActionScene      = script.ActionScene
+)
+
+// Modifier is a set of modifier keys held while an ActionKey's key is
+// pressed.
+type Modifier = script.Modifier
+
+// Key modifiers.
+const (
+	ModCtrl  = script.ModCtrl
+	ModAlt   = script.ModAlt
+	ModShift = script.ModShift
+)
+
+// ParseError reports where a tape script failed to parse.
+type ParseError = script.ParseError
+
+// Parse converts a tape script, such as "Type 'ls' Enter Sleep 1s", into
+// actions for WithActions. Errors are *ParseError values.
+func Parse(src string) ([]Action, error) {
+	return script.Parse(src)
+}
+
+// Format writes actions as a tape script, one action per line, that Parse
+// reads back into the same actions. The text of a TypeSecret is not
+// written.
+func Format(actions []Action) string {
+	return script.Format(actions)
+}
+
+// ParseJSON reads actions from a JSON array such as
+// [{"kind": "type", "text": "ls"}, {"kind": "key", "key": "Enter"}], as
+// json.Marshal writes them, with durations as strings such as "500ms". Each
+// action is checked as Parse checks a script.
+func ParseJSON(data []byte) ([]Action, error) {
+	return script.ParseJSON(data)
+}
+
+// EstimateOptions configures Estimate.
+type EstimateOptions = script.EstimateOptions
+
+// Estimation is the expected duration and frame count of a script.
+type Estimation = script.Estimation
+
+// Estimate returns the minimum duration of actions and the frames a run of
+// them is expected to take, without running them.
+func Estimate(actions []Action, opts EstimateOptions) Estimation {
+	return script.Estimate(actions, opts)
+}